@@ -0,0 +1,482 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/output"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// apiPrefix is the path prefix of the REST API.
+const apiPrefix = "/api/v1"
+
+// apiParam describes a parameter of a REST API route.
+type apiParam struct {
+	Name        string      // name
+	In          string      // "body" (a property of the JSON request body) or "query"
+	Type        string      // JSON schema type: "string", "integer" or "boolean"
+	Required    bool        // whether the parameter is required
+	Default     interface{} // value used if the parameter is absent, nil for none
+	Internal    bool        // always set to Default and not documented, for arguments only the web UI uses
+	Description string      // description
+}
+
+// apiRoute describes a REST API route, the route table (apiRoutes) is the single source of both the registered
+// handlers and the OpenAPI document.
+type apiRoute struct {
+	Method      string                                       // HTTP method
+	Path        string                                       // path relative to apiPrefix
+	Tag         string                                       // group of the route: files, search, build, git or users
+	Summary     string                                       // summary
+	Params      []*apiParam                                  // parameters
+	Limit       string                                       // rate limit group (see Wide.RateLimits), "" for none
+	HandlerFunc func(w http.ResponseWriter, r *http.Request) // handler
+}
+
+// common parameters
+var (
+	sidParam      = &apiParam{Name: "sid", In: "body", Type: "string", Default: "", Internal: true}
+	pathtypeParam = &apiParam{Name: "pathtype", In: "body", Type: "string", Default: "0",
+		Description: "0: relative to {workspace}/src, 1: Go API source, 2: GOPATH, 3: invited project"}
+	streamParam = &apiParam{Name: "stream", In: "body", Type: "boolean",
+		Description: "responds the output as plain text while the command runs, trailer X-Exit-Code is the exit code"}
+	gitPathParam = &apiParam{Name: "path", In: "body", Type: "string", Required: true,
+		Description: "a file or directory in the repository"}
+)
+
+// apiRoutes is the route table of the REST API.
+var apiRoutes = []*apiRoute{
+	{Method: http.MethodGet, Path: "/files/tree", Tag: "files", Summary: "List a directory",
+		HandlerFunc: file.RefreshDirectoryHandler, Params: []*apiParam{
+			{Name: "path", In: "query", Type: "string", Required: true, Description: "the directory"},
+			{Name: "pathtype", In: "query", Type: "string", Default: "0", Description: pathtypeParam.Description},
+			{Name: "offset", In: "query", Type: "integer", Description: "index of the first child"},
+		}},
+	{Method: http.MethodPost, Path: "/files/read", Tag: "files", Summary: "Read a file",
+		HandlerFunc: file.GetFileHandler, Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "the file"},
+			{Name: "revision", In: "body", Type: "string", Description: "git revision to read the file at"},
+		}},
+	{Method: http.MethodPost, Path: "/files/save", Tag: "files", Summary: "Save a file",
+		HandlerFunc: file.SaveFileHandler, Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "file", In: "body", Type: "string", Required: true, Description: "the file"},
+			{Name: "code", In: "body", Type: "string", Required: true, Description: "content of the file"},
+			{Name: "force", In: "body", Type: "boolean", Default: true,
+				Description: "overwrites changes saved by others since the file was read"},
+		}},
+	{Method: http.MethodPost, Path: "/files/new", Tag: "files", Summary: "Create a file or directory",
+		HandlerFunc: file.NewFileHandler, Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "the file, its parent must exist"},
+			{Name: "fileType", In: "body", Type: "string", Default: "f", Description: "f: file, d: directory"},
+		}},
+	{Method: http.MethodPost, Path: "/files/remove", Tag: "files", Summary: "Remove a file or directory",
+		HandlerFunc: file.RemoveFileHandler, Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "the file or directory"},
+		}},
+	{Method: http.MethodPost, Path: "/files/rename", Tag: "files", Summary: "Rename a file or directory",
+		HandlerFunc: file.RenameFileHandler, Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "oldPath", In: "body", Type: "string", Required: true, Description: "the file or directory"},
+			{Name: "newPath", In: "body", Type: "string", Required: true, Description: "the new path"},
+		}},
+
+	{Method: http.MethodPost, Path: "/search/text", Tag: "search", Summary: "Search text in files",
+		HandlerFunc: file.SearchTextHandler, Limit: "search", Params: []*apiParam{sidParam, pathtypeParam,
+			{Name: "dir", In: "body", Type: "string", Required: true, Description: "the directory to search in"},
+			{Name: "text", In: "body", Type: "string", Required: true, Description: "the text"},
+			{Name: "extension", In: "body", Type: "string", Default: "", Description: "such as \".go\", all files if empty"},
+			{Name: "regex", In: "body", Type: "boolean", Default: false, Description: "the text is a Go regexp"},
+			{Name: "caseSensitive", In: "body", Type: "boolean", Default: false, Description: "case-sensitive"},
+			{Name: "wholeWord", In: "body", Type: "boolean", Default: false, Description: "whole words only"},
+		}},
+	{Method: http.MethodPost, Path: "/search/files", Tag: "search", Summary: "Find files by name",
+		HandlerFunc: file.FindHandler, Limit: "search", Params: []*apiParam{pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "the directory to find in"},
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "name pattern, such as \"*.go\""},
+			{Name: "page", In: "body", Type: "integer", Description: "1-based page"},
+		}},
+	{Method: http.MethodPost, Path: "/search/symbols", Tag: "search", Summary: "Find symbols of the workspace",
+		HandlerFunc: file.FindSymbolHandler, Limit: "search", Params: []*apiParam{
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "such as \"Save\" or \"User.Save\""},
+		}},
+
+	{Method: http.MethodPost, Path: "/build", Tag: "build", Summary: "Build a package",
+		HandlerFunc: output.APIBuildHandler, Limit: "build", Params: []*apiParam{pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "a file or directory of the package"},
+			streamParam,
+		}},
+	{Method: http.MethodPost, Path: "/test", Tag: "build", Summary: "Test a package",
+		HandlerFunc: output.APITestHandler, Limit: "build", Params: []*apiParam{pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "a file or directory of the package"},
+			streamParam,
+		}},
+	{Method: http.MethodPost, Path: "/run", Tag: "build", Summary: "Build a package and run the program",
+		HandlerFunc: output.APIRunHandler, Limit: "build", Params: []*apiParam{pathtypeParam,
+			{Name: "path", In: "body", Type: "string", Required: true, Description: "a file or directory of the package"},
+			streamParam,
+		}},
+
+	{Method: http.MethodPost, Path: "/git/changes", Tag: "git", Summary: "List changes of the repository",
+		HandlerFunc: file.GitChangesHandler, Params: []*apiParam{pathtypeParam, gitPathParam}},
+	{Method: http.MethodPost, Path: "/git/stage", Tag: "git", Summary: "Stage a path or a patch",
+		HandlerFunc: file.GitStageHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "patch", In: "body", Type: "string", Description: "unified diff of the hunks to stage"},
+		}},
+	{Method: http.MethodPost, Path: "/git/unstage", Tag: "git", Summary: "Unstage a path or a patch",
+		HandlerFunc: file.GitUnstageHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "patch", In: "body", Type: "string", Description: "unified diff of the hunks to unstage"},
+		}},
+	{Method: http.MethodPost, Path: "/git/commit", Tag: "git", Summary: "Commit the staged changes",
+		HandlerFunc: file.GitCommitHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "message", In: "body", Type: "string", Required: true, Description: "commit message"},
+			{Name: "all", In: "body", Type: "boolean", Description: "stages all changes before committing"},
+		}},
+	{Method: http.MethodPost, Path: "/git/diff", Tag: "git", Summary: "Diff a file or directory",
+		HandlerFunc: file.GitDiffHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "staged", In: "body", Type: "boolean", Description: "compares the index with HEAD"},
+			{Name: "from", In: "body", Type: "string", Description: "the commit to compare with"},
+			{Name: "to", In: "body", Type: "string", Description: "the commit to compare \"from\" with"},
+			{Name: "context", In: "body", Type: "integer", Description: "lines of context, defaults to 3"},
+		}},
+	{Method: http.MethodPost, Path: "/git/log", Tag: "git", Summary: "List commits",
+		HandlerFunc: file.GitLogHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "revision", In: "body", Type: "string", Description: "branch or commit to start from"},
+			{Name: "page", In: "body", Type: "integer", Description: "1-based page"},
+			{Name: "pageSize", In: "body", Type: "integer", Description: "defaults to 20, at most 100"},
+		}},
+	{Method: http.MethodPost, Path: "/git/branches", Tag: "git", Summary: "List branches",
+		HandlerFunc: file.GitBranchesHandler, Params: []*apiParam{pathtypeParam, gitPathParam}},
+	{Method: http.MethodPost, Path: "/git/branch/new", Tag: "git", Summary: "Create a branch",
+		HandlerFunc: file.GitCreateBranchHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "name of the branch"},
+			{Name: "startPoint", In: "body", Type: "string", Description: "defaults to HEAD"},
+			{Name: "checkout", In: "body", Type: "boolean", Description: "switches to the branch"},
+		}},
+	{Method: http.MethodPost, Path: "/git/branch/switch", Tag: "git", Summary: "Switch to a branch",
+		HandlerFunc: file.GitSwitchBranchHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "name of the branch"},
+			{Name: "force", In: "body", Type: "boolean", Description: "discards local changes"},
+		}},
+	{Method: http.MethodPost, Path: "/git/push", Tag: "git", Summary: "Push to the remote",
+		HandlerFunc: file.GitPushHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "remote", In: "body", Type: "string", Description: "defaults to the upstream"},
+			{Name: "branch", In: "body", Type: "string", Description: "defaults to the current branch"},
+			{Name: "force", In: "body", Type: "boolean", Description: "force push (with lease)"},
+			{Name: "setUpstream", In: "body", Type: "boolean", Description: "sets the upstream of the branch"},
+		}},
+	{Method: http.MethodPost, Path: "/git/pull", Tag: "git", Summary: "Pull from the remote",
+		HandlerFunc: file.GitPullHandler, Params: []*apiParam{pathtypeParam, gitPathParam,
+			{Name: "remote", In: "body", Type: "string", Description: "defaults to the upstream"},
+			{Name: "branch", In: "body", Type: "string", Description: "defaults to the upstream branch"},
+			{Name: "rebase", In: "body", Type: "boolean", Description: "rebases instead of merging"},
+		}},
+
+	{Method: http.MethodGet, Path: "/triggers", Tag: "git", Summary: "List triggers (incoming webhooks of push events)",
+		HandlerFunc: session.TriggersHandler},
+	{Method: http.MethodPost, Path: "/triggers/new", Tag: "git", Summary: "Create a trigger of a project",
+		HandlerFunc: session.NewTriggerHandler, Params: []*apiParam{
+			{Name: "project", In: "body", Type: "string", Required: true,
+				Description: "path of the repository relative to {workspace}/src"},
+			{Name: "branch", In: "body", Type: "string", Description: "branch whose pushes trigger, any branch if empty"},
+			{Name: "job", In: "body", Type: "string", Description: "job run after pulling: build, test or empty"},
+		}},
+	{Method: http.MethodPost, Path: "/triggers/remove", Tag: "git", Summary: "Remove a trigger",
+		HandlerFunc: session.RemoveTriggerHandler, Params: []*apiParam{
+			{Name: "id", In: "body", Type: "string", Required: true, Description: "id of the trigger"},
+		}},
+
+	{Method: http.MethodGet, Path: "/user", Tag: "users", Summary: "Get the current user",
+		HandlerFunc: session.UserHandler},
+	{Method: http.MethodGet, Path: "/users", Tag: "users", Summary: "List users (administrators only)",
+		HandlerFunc: session.UsersHandler},
+	{Method: http.MethodGet, Path: "/tokens", Tag: "users", Summary: "List API tokens of the current user",
+		HandlerFunc: session.APITokensHandler},
+	{Method: http.MethodPost, Path: "/tokens/new", Tag: "users", Summary: "Create an API token",
+		HandlerFunc: session.NewAPITokenHandler, Params: []*apiParam{
+			{Name: "name", In: "body", Type: "string", Required: true, Description: "name of the token, such as \"CI\""},
+		}},
+	{Method: http.MethodPost, Path: "/tokens/revoke", Tag: "users", Summary: "Revoke an API token",
+		HandlerFunc: session.RevokeAPITokenHandler, Params: []*apiParam{
+			{Name: "id", In: "body", Type: "string", Required: true, Description: "id of the token"},
+		}},
+}
+
+// registerAPI registers the routes of the REST API and its OpenAPI document.
+func registerAPI() {
+	for _, route := range apiRoutes {
+		http.HandleFunc(apiPrefix+route.Path, handlerWrapper(apiWrapper(route)))
+	}
+
+	http.HandleFunc(apiPrefix+"/openapi.json", handlerWrapper(openAPIHandler))
+}
+
+// apiWrapper wraps the handler of the specified route with the processes of the REST API:
+//
+//  1. method check
+//  2. authentication with an API token ("Authorization: Bearer {token}") or the HTTP session
+//  3. parameter check, absent parameters are set to their default values
+//  4. rate limiting if the route has a group
+func apiWrapper(route *apiRoute) func(w http.ResponseWriter, r *http.Request) {
+	handler := route.HandlerFunc
+	if "" != route.Limit {
+		handler = rateLimitWrapper(route.Limit, handler)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if route.Method != r.Method {
+			w.Header().Set("Allow", route.Method)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		if "" != r.Header.Get("Authorization") {
+			if !session.AuthenticateAPIToken(r) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wide"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+				return
+			}
+		} else if httpSession, _ := session.HTTPSession.Get(r, session.CookieName); httpSession.IsNew {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wide"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+			return
+		}
+
+		if err := applyAPIParams(r, route.Params); nil != err {
+			http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// apiParamError is returned if a parameter of a request is missing or invalid.
+type apiParamError string
+
+func (e apiParamError) Error() string {
+	return string(e)
+}
+
+// applyAPIParams checks the parameters of the specified request and sets absent parameters to their default values.
+// The JSON body is rewritten so the handlers decode the arguments as usual.
+func applyAPIParams(r *http.Request, params []*apiParam) error {
+	query := r.URL.Query()
+	var args map[string]interface{}
+	for _, param := range params {
+		if "query" == param.In {
+			if "" == query.Get(param.Name) {
+				if param.Required {
+					return apiParamError("missing parameter [" + param.Name + "]")
+				}
+				if nil != param.Default {
+					query.Set(param.Name, toQueryValue(param.Default))
+				}
+			}
+
+			continue
+		}
+
+		if nil == args {
+			args = map[string]interface{}{}
+			data, err := ioutil.ReadAll(r.Body)
+			if nil != err {
+				return err
+			}
+			if 0 < len(bytes.TrimSpace(data)) {
+				if err := json.Unmarshal(data, &args); nil != err || nil == args {
+					return apiParamError("the body must be a JSON object")
+				}
+			}
+		}
+
+		value, ok := args[param.Name]
+		if param.Internal || (!ok && nil != param.Default) {
+			args[param.Name] = param.Default
+
+			continue
+		}
+		if !ok || nil == value {
+			if param.Required {
+				return apiParamError("missing parameter [" + param.Name + "]")
+			}
+
+			continue
+		}
+		if !apiParamTypeOf(param.Type, value) {
+			return apiParamError("parameter [" + param.Name + "] must be " + param.Type)
+		}
+	}
+
+	r.URL.RawQuery = query.Encode()
+	r.Form = nil
+	if nil != args {
+		data, _ := json.Marshal(args)
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		r.ContentLength = int64(len(data))
+	}
+
+	return nil
+}
+
+// apiParamTypeOf determines whether the specified decoded JSON value is of the specified type. Numbers are accepted
+// for string parameters too, as the handlers format arguments such as "pathtype" with fmt.Sprint.
+func apiParamTypeOf(typ string, value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return "string" == typ
+	case float64:
+		return "integer" == typ || "string" == typ
+	case bool:
+		return "boolean" == typ
+	}
+
+	return false
+}
+
+// toQueryValue formats the specified default value of a query parameter.
+func toQueryValue(v interface{}) string {
+	data, _ := json.Marshal(v)
+
+	return strings.Trim(string(data), `"`)
+}
+
+// openAPIHandler responds the OpenAPI document of the REST API.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.Copy(w, bytes.NewReader(openAPIDocument())); nil != err {
+		logger.Warn(err)
+	}
+}
+
+// openAPIDocument generates the OpenAPI document of the REST API from the route table.
+func openAPIDocument() []byte {
+	paths := map[string]map[string]interface{}{}
+	for _, route := range apiRoutes {
+		operation := map[string]interface{}{
+			"tags":        []string{route.Tag},
+			"summary":     route.Summary,
+			"operationId": operationId(route),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{"description": "Result, \"code\" is 0 if succeeded",
+					"content": map[string]interface{}{"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{"$ref": "#/components/schemas/Result"}}}},
+				"400": map[string]interface{}{"description": "Missing or invalid parameters"},
+				"401": map[string]interface{}{"description": "Not authenticated"},
+				"403": map[string]interface{}{"description": "Forbidden"},
+				"429": map[string]interface{}{"description": "Rate limited, retry after the Retry-After seconds"},
+			},
+		}
+
+		var parameters []interface{}
+		properties := map[string]interface{}{}
+		var required []string
+		for _, param := range route.Params {
+			if param.Internal {
+				continue
+			}
+
+			schema := map[string]interface{}{"type": param.Type}
+			if nil != param.Default {
+				schema["default"] = param.Default
+			}
+			if "query" == param.In {
+				parameters = append(parameters, map[string]interface{}{"name": param.Name, "in": "query",
+					"required": param.Required, "description": param.Description, "schema": schema})
+
+				continue
+			}
+
+			schema["description"] = param.Description
+			properties[param.Name] = schema
+			if param.Required {
+				required = append(required, param.Name)
+			}
+		}
+		if 0 < len(parameters) {
+			operation["parameters"] = parameters
+		}
+		if 0 < len(properties) {
+			schema := map[string]interface{}{"type": "object", "properties": properties}
+			if 0 < len(required) {
+				schema["required"] = required
+			}
+			operation["requestBody"] = map[string]interface{}{"required": 0 < len(required),
+				"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}}
+		}
+
+		// routes of the same path are operations of the same path item
+		if nil == paths[route.Path] {
+			paths[route.Path] = map[string]interface{}{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{"title": "Wide", "version": conf.WideVersion,
+			"description": "REST API of Wide, authenticated with API tokens (see /tokens/new) or the session cookie"},
+		"servers": []interface{}{map[string]interface{}{"url": conf.Wide().Server + conf.Wide().Context + apiPrefix}},
+		"security": []interface{}{
+			map[string]interface{}{"token": []string{}},
+			map[string]interface{}{"cookie": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"token":  map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"cookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": session.CookieName},
+			},
+			"schemas": map[string]interface{}{
+				"Result": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"code": map[string]interface{}{"type": "integer"},
+					"msg":  map[string]interface{}{"type": "string"},
+					"data": map[string]interface{}{},
+				}},
+			},
+		},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if nil != err {
+		logger.Error(err)
+	}
+
+	return data
+}
+
+// operationId returns the OpenAPI operation id of the specified route, such as "gitBranchNew" of "/git/branch/new".
+func operationId(route *apiRoute) string {
+	ret := ""
+	for i, part := range strings.Split(strings.Trim(route.Path, "/"), "/") {
+		if 0 < i && "" != part {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+		ret += part
+	}
+
+	return ret
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+// static resources and templates embedded in the binary, served unless Wide.AssetsDir is set.
+//
+//go:embed static views
+var assets embed.FS
+
+// startup time, the modification time of the embedded files which have no one.
+var assetsModTime = time.Now()
+
+// staticHandler serves the static resources (static/) of conf.Assets.
+func staticHandler(w http.ResponseWriter, r *http.Request) {
+	static, err := fs.Sub(conf.Assets(), "static")
+	if nil != err {
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	http.FileServer(http.FS(static)).ServeHTTP(w, r)
+}
+
+// serveSingle serves the file of conf.Assets specified by the given name with the specified pattern.
+func serveSingle(pattern string, name string) {
+	http.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		data, err := fs.ReadFile(conf.Assets(), name)
+		if nil != err {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		http.ServeContent(w, r, path.Base(name), assetsModTime, bytes.NewReader(data))
+	})
+}
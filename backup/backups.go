@@ -0,0 +1,464 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup includes scheduled backups of users' workspaces and configurations, and restoring them.
+//
+// A backup is a zip archive named wide-backup-{yyyyMMdd-HHmmss}.zip, which contains:
+//
+//	manifest.json               workspace paths of users
+//	conf/{wide.json}            the Wide configuration file
+//	users/{userId}.json         users' configuration files
+//	workspaces/{userId}/{i}/... the i-th workspace path of the user, directories "pkg" and "bin" are excluded
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// nameRegexp matches names of backup archives.
+var nameRegexp = regexp.MustCompile(`^wide-backup-(\d{8}-\d{6})\.zip$`)
+
+// nameTimeLayout is the time layout in names of backup archives.
+const nameTimeLayout = "20060102-150405"
+
+// backupMutex serializes backing up and restoring.
+var backupMutex sync.Mutex
+
+// manifest represents the manifest of a backup.
+type manifest struct {
+	Created    int64               `json:"created"`    // create time in unix milliseconds
+	Workspaces map[string][]string `json:"workspaces"` // workspace paths keyed by user id
+}
+
+// Archive represents a backup archive.
+type Archive struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Created int64  `json:"created"` // create time in unix milliseconds
+	Local   bool   `json:"local"`   // whether is in the local backups directory
+	Remote  bool   `json:"remote"`  // whether is in the S3-compatible target
+}
+
+// Load starts the scheduled backups, the schedule follows configurations reloading.
+func Load() {
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		var next time.Time
+		for range time.Tick(time.Minute) {
+			b := conf.Wide().Backup
+			if nil == b || 1 > b.Interval {
+				continue
+			}
+
+			interval := time.Duration(b.Interval) * time.Hour
+			if latest := latestLocal(b); !latest.IsZero() && latest.Add(interval).After(next) {
+				next = latest.Add(interval)
+			}
+			if time.Now().Before(next) {
+				continue
+			}
+
+			if _, err := Create(); nil != err {
+				logger.Errorf("Scheduled backup failed: %s", err)
+
+				// retries later instead of every minute
+				if time.Hour < interval {
+					interval = time.Hour
+				}
+			}
+			next = time.Now().Add(interval)
+		}
+	}()
+}
+
+// backupConf gets the backup configurations, defaults are used if not configured.
+func backupConf() *conf.Backup {
+	if b := conf.Wide().Backup; nil != b {
+		return b
+	}
+
+	return &conf.Backup{}
+}
+
+// s3Client gets the client of the S3-compatible target of the specified backup configurations, returns nil if not
+// configured.
+func s3Client(b *conf.Backup) *util.S3Client {
+	if nil == b.S3 || "" == b.S3.Endpoint || "" == b.S3.Bucket {
+		return nil
+	}
+
+	return &util.S3Client{Endpoint: b.S3.Endpoint, Region: b.S3.Region, Bucket: b.S3.Bucket,
+		AccessKey: b.S3.AccessKey, SecretKey: b.S3.SecretKey, Client: &http.Client{Timeout: time.Hour}}
+}
+
+// Create backs up users' workspaces and configurations, uploads the archive to the S3-compatible target if configured,
+// then removes the outdated archives. Returns the name of the archive.
+func Create() (string, error) {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	b := backupConf()
+	dir := b.BackupDir()
+	if err := os.MkdirAll(dir, 0755); nil != err {
+		return "", err
+	}
+
+	now := time.Now()
+	name := "wide-backup-" + now.Format(nameTimeLayout) + ".zip"
+	path := filepath.Join(dir, name)
+
+	// writes to a temporary file first, so a broken archive is never listed
+	tmp := path + ".tmp"
+	if err := writeArchive(tmp, now); nil != err {
+		os.Remove(tmp)
+
+		return "", err
+	}
+	if err := os.Rename(tmp, path); nil != err {
+		os.Remove(tmp)
+
+		return "", err
+	}
+
+	info, _ := os.Stat(path)
+	logger.Infof("Created backup [%s], %d bytes", path, info.Size())
+
+	if client := s3Client(b); nil != client {
+		f, err := os.Open(path)
+		if nil != err {
+			return name, err
+		}
+		err = client.PutObject(b.S3.Prefix+name, f)
+		f.Close()
+		if nil != err {
+			return name, err
+		}
+
+		logger.Infof("Uploaded backup [%s] to [%s/%s]", name, b.S3.Endpoint, b.S3.Bucket)
+	}
+
+	clean(b)
+
+	return name, nil
+}
+
+// writeArchive writes the backup archive created at the specified time to the specified path.
+func writeArchive(path string, created time.Time) error {
+	f, err := os.Create(path)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	zipWriter := zip.NewWriter(f)
+
+	m := &manifest{Created: created.UnixNano() / int64(time.Millisecond), Workspaces: map[string][]string{}}
+	for _, user := range conf.GetUsers() {
+		workspaces := filepath.SplitList(user.WorkspacePath())
+		m.Workspaces[user.Id] = workspaces
+		for i, workspace := range workspaces {
+			if err := addWorkspace(zipWriter, workspace, "workspaces/"+user.Id+"/"+strconv.Itoa(i)); nil != err {
+				zipWriter.Close()
+
+				return err
+			}
+		}
+	}
+
+	confPath := conf.ConfPath()
+	if err := addFile(zipWriter, confPath, "conf/"+filepath.Base(confPath)); nil != err {
+		zipWriter.Close()
+
+		return err
+	}
+
+	if err := addUsers(zipWriter); nil != err {
+		zipWriter.Close()
+
+		return err
+	}
+
+	entry, err := zipWriter.Create("manifest.json")
+	if nil != err {
+		zipWriter.Close()
+
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(m); nil != err {
+		zipWriter.Close()
+
+		return err
+	}
+
+	if err := zipWriter.Close(); nil != err {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// addUsers adds users' configuration files to the specified zip writer under "users/", users stored in the database
+// are added as JSON files.
+func addUsers(zipWriter *zip.Writer) error {
+	if nil != conf.Wide().Database {
+		for _, user := range conf.GetUsers() {
+			entry, err := zipWriter.Create("users/" + user.Id + ".json")
+			if nil != err {
+				return err
+			}
+
+			data, err := json.MarshalIndent(user, "", "    ")
+			if nil != err {
+				return err
+			}
+			if _, err := entry.Write(data); nil != err {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	usersDir := filepath.Join(conf.Wide().Data, "users")
+	infos, _ := ioutil.ReadDir(usersDir)
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		if err := addFile(zipWriter, filepath.Join(usersDir, info.Name()), "users/"+info.Name()); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addWorkspace adds files of the specified workspace to the specified zip writer under the specified zip path.
+//
+// Directories "pkg" (including the module cache) and "bin" of the workspace are excluded since they can be rebuilt.
+// Files other than regular ones (such as symbolic links) are skipped.
+func addWorkspace(zipWriter *zip.Writer, workspace, zipPath string) error {
+	if !gulu.File.IsDir(workspace) {
+		return nil
+	}
+
+	return filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			logger.Warnf("Backs up [%s] failed: %s", path, err)
+
+			return nil
+		}
+
+		rel, _ := filepath.Rel(workspace, path)
+		if info.IsDir() {
+			if "pkg" == rel || "bin" == rel {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if err := addFile(zipWriter, path, zipPath+"/"+filepath.ToSlash(rel)); nil != err && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// addFile adds the specified file to the specified zip writer as the specified name.
+func addFile(zipWriter *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if nil != err {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if nil != err {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	entry, err := zipWriter.CreateHeader(header)
+	if nil != err {
+		return err
+	}
+
+	// the file may be being written, copies the size at the moment only
+	_, err = io.CopyN(entry, f, info.Size())
+
+	return err
+}
+
+// List lists backup archives in the local backups directory and in the S3-compatible target, the latest first.
+func List() ([]*Archive, error) {
+	b := backupConf()
+
+	archives := map[string]*Archive{}
+	infos, _ := ioutil.ReadDir(b.BackupDir())
+	for _, info := range infos {
+		if created, ok := parseName(info.Name()); ok {
+			archives[info.Name()] = &Archive{Name: info.Name(), Size: info.Size(), Created: created, Local: true}
+		}
+	}
+
+	if client := s3Client(b); nil != client {
+		objects, err := client.ListObjects(b.S3.Prefix + "wide-backup-")
+		if nil != err {
+			return nil, err
+		}
+
+		for _, object := range objects {
+			name := strings.TrimPrefix(object.Key, b.S3.Prefix)
+			created, ok := parseName(name)
+			if !ok {
+				continue
+			}
+
+			if archive := archives[name]; nil != archive {
+				archive.Remote = true
+			} else {
+				archives[name] = &Archive{Name: name, Size: object.Size, Created: created, Remote: true}
+			}
+		}
+	}
+
+	ret := []*Archive{}
+	for _, archive := range archives {
+		ret = append(ret, archive)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name > ret[j].Name })
+
+	return ret, nil
+}
+
+// parseName parses the create time (in unix milliseconds) from the specified archive name.
+func parseName(name string) (int64, bool) {
+	groups := nameRegexp.FindStringSubmatch(name)
+	if nil == groups {
+		return 0, false
+	}
+
+	created, err := time.ParseInLocation(nameTimeLayout, groups[1], time.Local)
+	if nil != err {
+		return 0, false
+	}
+
+	return created.UnixNano() / int64(time.Millisecond), true
+}
+
+// latestLocal gets the create time of the latest archive in the local backups directory of the specified
+// configurations, returns zero time if not found.
+func latestLocal(b *conf.Backup) time.Time {
+	infos, _ := ioutil.ReadDir(b.BackupDir())
+	latest := ""
+	for _, info := range infos {
+		if nameRegexp.MatchString(info.Name()) && info.Name() > latest {
+			latest = info.Name()
+		}
+	}
+
+	if created, ok := parseName(latest); ok {
+		return time.Unix(0, created*int64(time.Millisecond))
+	}
+
+	return time.Time{}
+}
+
+// clean removes archives except the latest ones kept by the retention of the specified configurations, both locally
+// and in the S3-compatible target.
+func clean(b *conf.Backup) {
+	if 1 > b.Retention {
+		return
+	}
+
+	names := []string{}
+	infos, _ := ioutil.ReadDir(b.BackupDir())
+	for _, info := range infos {
+		if nameRegexp.MatchString(info.Name()) {
+			names = append(names, info.Name())
+		}
+	}
+	for _, name := range outdated(names, b.Retention) {
+		if err := os.Remove(filepath.Join(b.BackupDir(), name)); nil != err {
+			logger.Warnf("Removes backup [%s] failed: %s", name, err)
+		}
+	}
+
+	client := s3Client(b)
+	if nil == client {
+		return
+	}
+
+	objects, err := client.ListObjects(b.S3.Prefix + "wide-backup-")
+	if nil != err {
+		logger.Warnf("Lists backups in [%s/%s] failed: %s", b.S3.Endpoint, b.S3.Bucket, err)
+
+		return
+	}
+
+	names = []string{}
+	for _, object := range objects {
+		if name := strings.TrimPrefix(object.Key, b.S3.Prefix); nameRegexp.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range outdated(names, b.Retention) {
+		if err := client.DeleteObject(b.S3.Prefix + name); nil != err {
+			logger.Warnf("Removes backup [%s] from [%s/%s] failed: %s", name, b.S3.Endpoint, b.S3.Bucket, err)
+		}
+	}
+}
+
+// outdated returns the specified archive names except the latest ones of the specified retention.
+func outdated(names []string, retention int) []string {
+	if len(names) <= retention {
+		return nil
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	return names[retention:]
+}
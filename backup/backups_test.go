@@ -0,0 +1,110 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOutdated(t *testing.T) {
+	names := []string{"wide-backup-20200102-000000.zip", "wide-backup-20200103-000000.zip",
+		"wide-backup-20200101-000000.zip"}
+
+	if got := outdated(names, 3); 0 != len(got) {
+		t.Errorf("expected nothing outdated, got %v", got)
+	}
+
+	expected := []string{"wide-backup-20200101-000000.zip"}
+	if got := outdated(names, 2); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestUnder(t *testing.T) {
+	root := filepath.FromSlash("/data/workspaces/admin")
+
+	cases := []struct {
+		rel  string
+		want string
+	}{
+		{"src/main.go", filepath.Join(root, "src", "main.go")},
+		{"../other/src/main.go", ""},
+		{"src/../../admin2/main.go", ""},
+		{"..", ""},
+		{".", ""},
+	}
+
+	for _, c := range cases {
+		if got := under(root, c.rel); c.want != got {
+			t.Errorf("[%s] under [%s]: expected [%s], got [%s]", c.rel, root, c.want, got)
+		}
+	}
+}
+
+func TestRestoreDest(t *testing.T) {
+	usersDir := filepath.FromSlash("/data/users")
+	workspace := filepath.FromSlash("/data/workspaces/admin")
+	m := &manifest{Workspaces: map[string][]string{
+		"admin": {filepath.FromSlash("/etc"), filepath.FromSlash("/root")},
+		"guest": {filepath.FromSlash("/tmp")},
+	}}
+	workspacesOf := func(uid string) []string {
+		if "admin" == uid {
+			return []string{workspace}
+		}
+
+		return nil
+	}
+
+	cases := []struct {
+		name   string
+		userId string
+		want   string
+	}{
+		{"users/admin.json", "", filepath.Join(usersDir, "admin.json")},
+		{"users/admin.json", "guest", ""},
+		{"users/../wide.json", "", ""},
+		{"workspaces/admin/0/src/main.go", "", filepath.Join(workspace, "src", "main.go")},
+		{"workspaces/admin/0/src/main.go", "admin", filepath.Join(workspace, "src", "main.go")},
+		{"workspaces/admin/0/src/main.go", "guest", ""},
+		{"workspaces/admin/0/../../main.go", "", ""},
+		{"workspaces/admin/1/src/main.go", "", ""}, // not an existing workspace
+		{"workspaces/admin/2/src/main.go", "", ""}, // not in the manifest
+		{"workspaces/admin/-1/src/main.go", "", ""},
+		{"workspaces/guest/0/src/main.go", "", ""}, // not an existing user
+		{"workspaces/admin/0", "", ""},
+		{"wide.json", "", ""},
+	}
+
+	for _, c := range cases {
+		if got := restoreDest(c.name, c.userId, m, usersDir, workspacesOf); c.want != got {
+			t.Errorf("[%s] of [%s]: expected [%s], got [%s]", c.name, c.userId, c.want, got)
+		}
+	}
+}
+
+func TestParseName(t *testing.T) {
+	if _, ok := parseName("wide-backup-20200101-120000.zip"); !ok {
+		t.Error("expected a valid name")
+	}
+
+	for _, name := range []string{"wide-backup-20200101-120000.zip.tmp", "wide-backup-2020.zip", "a.zip"} {
+		if _, ok := parseName(name); ok {
+			t.Errorf("expected [%s] invalid", name)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// isAdmin checks whether the request is sent by an administrator, responds 403 if not.
+func isAdmin(w http.ResponseWriter, r *http.Request) bool {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew || !conf.Wide().IsAdmin(httpSession.Values["uid"].(string)) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return false
+	}
+
+	return true
+}
+
+// uid returns the id of the user sending the request.
+func uid(r *http.Request) string {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+
+	return httpSession.Values["uid"].(string)
+}
+
+// ListHandler handles request of listing backup archives, only administrators are allowed.
+func ListHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(w, r) {
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	archives, err := List()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	result.Data = archives
+}
+
+// CreateHandler handles request of backing up now, only administrators are allowed.
+func CreateHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(w, r) {
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	name, err := Create()
+	if nil != err {
+		logger.Errorf("Backup failed: %s", err)
+		result.Code = -1
+		result.Msg = err.Error()
+	}
+
+	result.Data = name
+	if "" != name {
+		conf.AddAudit(uid(r), "backup.create", name)
+	}
+}
+
+// RestoreHandler handles request of restoring from a backup archive, only administrators are allowed.
+//
+// Arguments: "name" of the archive and optional "userId" (restores all users if not specified).
+func RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(w, r) {
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args struct {
+		Name   string
+		UserId string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if err := Restore(args.Name, args.UserId); nil != err {
+		logger.Errorf("Restores backup [%s] failed: %s", args.Name, err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	session.AnnounceReload()
+	conf.AddAudit(uid(r), "backup.restore", args.Name+" "+args.UserId)
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// Restore restores users' workspaces and configurations from the backup archive specified by the given name, only the
+// user specified by the given user id is restored if it is not "". The archive is downloaded from the S3-compatible
+// target if it is not in the local backups directory.
+//
+// Files in the archive overwrite the existing ones, files not in the archive are kept. Workspaces are restored into the
+// current workspace paths of their users, workspaces of users not existing yet are restored by restoring again once
+// the users are restored. The Wide configuration file is not restored, it can be found in the archive. Users'
+// configurations are imported into the database if users are stored in it. Configurations are reloaded after restoring.
+func Restore(name, userId string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.New("invalid backup name [" + name + "]")
+	}
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	b := backupConf()
+	path := filepath.Join(b.BackupDir(), name)
+	if !gulu.File.IsExist(path) {
+		if err := download(b, name, path); nil != err {
+			return err
+		}
+	}
+
+	reader, err := zip.OpenReader(path)
+	if nil != err {
+		return err
+	}
+	defer reader.Close()
+
+	m := &manifest{}
+	for _, f := range reader.File {
+		if "manifest.json" == f.Name {
+			if err := readJSON(f, m); nil != err {
+				return err
+			}
+		}
+	}
+
+	usersDir := filepath.Join(conf.Wide().Data, "users")
+	restored := 0
+	userFiles := []string{}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		dest := restoreDest(f.Name, userId, m, usersDir, userWorkspaces)
+		if "" == dest {
+			continue
+		}
+
+		if err := extract(f, dest); nil != err {
+			return err
+		}
+		if strings.HasPrefix(f.Name, "users/") {
+			userFiles = append(userFiles, dest)
+		}
+		restored++
+	}
+
+	logger.Infof("Restored %d files from backup [%s]", restored, name)
+
+	if err := conf.ImportUserFiles(userFiles); nil != err {
+		return err
+	}
+
+	return conf.Reload()
+}
+
+// restoreDest returns the path the file of the specified name in an archive of the specified manifest restores to, ""
+// if the file isn't restored. Only files of the user specified by the given user id are restored if it's not "".
+//
+// Users' configurations restore to the specified users directory. Workspace files restore to the workspaces (returned
+// by the specified function) of their users, the manifest of the archive is only checked for the number of workspaces
+// so that a tampered archive can't write out of the workspaces.
+func restoreDest(name, userId string, m *manifest, usersDir string, workspacesOf func(uid string) []string) string {
+	switch parts := strings.SplitN(name, "/", 4); parts[0] {
+	case "users":
+		if 2 == len(parts) && ("" == userId || userId == strings.TrimSuffix(parts[1], filepath.Ext(parts[1]))) {
+			return under(usersDir, parts[1])
+		}
+	case "workspaces":
+		if 4 != len(parts) || ("" != userId && userId != parts[1]) {
+			return ""
+		}
+
+		i, err := strconv.Atoi(parts[2])
+		if nil != err || 0 > i || i >= len(m.Workspaces[parts[1]]) {
+			return ""
+		}
+
+		if workspaces := workspacesOf(parts[1]); i < len(workspaces) {
+			return under(workspaces[i], parts[3])
+		}
+	}
+
+	return ""
+}
+
+// userWorkspaces returns the current workspace paths of the user specified by the given user id, nil if the user
+// doesn't exist.
+func userWorkspaces(uid string) []string {
+	user := conf.GetUser(uid)
+	if nil == user {
+		return nil
+	}
+
+	return filepath.SplitList(user.WorkspacePath())
+}
+
+// download downloads the archive specified by the given name from the S3-compatible target to the specified path.
+func download(b *conf.Backup, name, path string) error {
+	client := s3Client(b)
+	if nil == client {
+		return errors.New("backup [" + name + "] not found")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if nil != err {
+		return err
+	}
+
+	err = client.GetObject(b.S3.Prefix+name, f)
+	if closeErr := f.Close(); nil == err {
+		err = closeErr
+	}
+	if nil != err {
+		os.Remove(tmp)
+
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// under returns the path of the specified slash-separated relative path under the specified root, returns "" if the
+// relative path escapes the root.
+func under(root, rel string) string {
+	if "" == root {
+		return ""
+	}
+
+	ret := filepath.Join(root, filepath.FromSlash(rel))
+	if !strings.HasPrefix(ret, filepath.Clean(root)+string(filepath.Separator)) {
+		return ""
+	}
+
+	return ret
+}
+
+// extract extracts the specified zip file to the specified path.
+func extract(f *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	reader, err := f.Open()
+	if nil != err {
+		return err
+	}
+	defer reader.Close()
+
+	perm := f.Mode().Perm()
+	if 0 == perm {
+		perm = 0644
+	}
+
+	writer, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if nil != err {
+		return err
+	}
+
+	_, err = io.Copy(writer, reader)
+	if closeErr := writer.Close(); nil == err {
+		err = closeErr
+	}
+
+	return err
+}
+
+// readJSON reads the specified zip file as JSON into the specified value.
+func readJSON(f *zip.File, v interface{}) error {
+	reader, err := f.Open()
+	if nil != err {
+		return err
+	}
+	defer reader.Close()
+
+	return json.NewDecoder(reader).Decode(v)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the path prefix of the REST API.
+const apiPrefix = "/api/v1"
+
+// client calls the REST API of a Wide server.
+type client struct {
+	server    string            // URL of the server, such as "https://wide.example.com"
+	token     string            // API token
+	transport http.RoundTripper // transport shared by the requests
+}
+
+// result is the response of most API calls.
+type result struct {
+	Code int             `json:"code"` // 0 if succeeded
+	Msg  string          `json:"msg"`  // message
+	Data json.RawMessage `json:"data"` // data
+}
+
+// newClient creates a client of the specified server authenticated with the specified API token.
+func newClient(server, token string) *client {
+	return &client{server: strings.TrimRight(server, "/"), token: token, transport: http.DefaultTransport}
+}
+
+// call calls the API of the specified path with the specified arguments, the data of the result is decoded into the
+// specified data if it's not nil.
+func (c *client) call(path string, args map[string]interface{}, data interface{}) error {
+	resp, err := c.post(path, args, time.Minute)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+
+	ret := &result{}
+	if err := json.NewDecoder(resp.Body).Decode(ret); nil != err {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	if 0 != ret.Code {
+		msg := ret.Msg
+		if "" == msg {
+			msg = "failed"
+		}
+
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+
+	if nil == data || 0 == len(ret.Data) {
+		return nil
+	}
+
+	return json.Unmarshal(ret.Data, data)
+}
+
+// get gets the JSON response of the API of the specified path with the specified query into the specified value.
+func (c *client) get(path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.server+apiPrefix+path+"?"+query.Encode(), nil)
+	if nil != err {
+		return err
+	}
+
+	resp, err := c.do(req, time.Minute)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// stream calls the API of the specified path with the specified arguments and "stream" set, copies the output to the
+// specified writer and returns the exit code of the command.
+func (c *client) stream(path string, args map[string]interface{}, out io.Writer) (int, error) {
+	args["stream"] = true
+	resp, err := c.post(path, args, 0)
+	if nil != err {
+		return -1, err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(out, resp.Body); nil != err {
+		return -1, err
+	}
+
+	exitCode, err := strconv.Atoi(resp.Trailer.Get("X-Exit-Code"))
+	if nil != err {
+		return -1, errors.New(path + ": the command is interrupted")
+	}
+
+	return exitCode, nil
+}
+
+// post posts the specified arguments as JSON to the API of the specified path, the request times out after the
+// specified duration unless it's 0.
+func (c *client) post(path string, args map[string]interface{}, timeout time.Duration) (*http.Response, error) {
+	body, err := json.Marshal(args)
+	if nil != err {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.server+apiPrefix+path, bytes.NewReader(body))
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, timeout)
+}
+
+// do sends the specified request with the API token, responses of error status are turned into errors. The request
+// (including reading the response body) times out after the specified duration unless it's 0.
+func (c *client) do(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := (&http.Client{Transport: c.transport, Timeout: timeout}).Do(req)
+	if nil != err {
+		return nil, err
+	}
+	if http.StatusOK == resp.StatusCode {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	path := req.URL.Path
+	if i := strings.Index(path, apiPrefix); -1 < i { // the server may be served under a sub path
+		path = path[i+len(apiPrefix):]
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return nil, errors.New(path + ": unauthorized, check the API token")
+	case http.StatusTooManyRequests:
+		return nil, errors.New(path + ": rate limited, retry after " + resp.Header.Get("Retry-After") + "s")
+	}
+
+	return nil, fmt.Errorf("%s: %s %s", path, resp.Status, strings.TrimSpace(string(msg)))
+}
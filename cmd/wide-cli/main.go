@@ -0,0 +1,101 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command wide-cli is the command line client of Wide, it talks to the REST API (/api/v1) of a Wide server so files
+// could be edited locally while builds and tests run on the server.
+//
+// Usage:
+//
+//	wide-cli [flags] push <local dir> <remote dir>
+//	wide-cli [flags] pull <remote dir> <local dir>
+//	wide-cli [flags] build <remote path>
+//	wide-cli [flags] test <remote path>
+//
+// Remote paths are relative to {workspace}/src of the user. The server and the API token are specified by flags
+// -server and -token, or environment variables WIDE_SERVER and WIDE_TOKEN.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: wide-cli [flags] <command> [arguments]
+
+Commands:
+  push <local dir> <remote dir>   uploads the files of the local directory into the remote directory
+  pull <remote dir> <local dir>   downloads the files of the remote directory into the local directory
+  build <remote path>             builds the package of the remote file or directory, tails the output
+  test <remote path>              tests the package of the remote file or directory, tails the output
+
+Remote paths are relative to {workspace}/src.
+
+Flags:
+`
+
+func main() {
+	server := flag.String("server", envOr("WIDE_SERVER", "http://127.0.0.1:7070"), "URL of the Wide server, $WIDE_SERVER")
+	token := flag.String("token", os.Getenv("WIDE_TOKEN"), "API token, $WIDE_TOKEN")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if 1 > len(args) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if "" == *token {
+		fatal("API token is required, create one with POST /api/v1/tokens/new")
+	}
+	c := newClient(*server, *token)
+
+	var err error
+	exitCode := 0
+	switch cmd := args[0]; {
+	case "push" == cmd && 3 == len(args):
+		err = c.push(args[1], args[2])
+	case "pull" == cmd && 3 == len(args):
+		err = c.pull(args[1], args[2])
+	case ("build" == cmd || "test" == cmd) && 2 == len(args):
+		exitCode, err = c.run(cmd, args[1], os.Stdout)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if nil != err {
+		fatal(err.Error())
+	}
+	os.Exit(exitCode)
+}
+
+// envOr gets the environment variable of the specified key, returns the specified default value if it's empty.
+func envOr(key, defaultValue string) string {
+	if ret := os.Getenv(key); "" != ret {
+		return ret
+	}
+
+	return defaultValue
+}
+
+// fatal prints the specified message and exits.
+func fatal(msg string) {
+	fmt.Fprintln(os.Stderr, "wide-cli: "+msg)
+	os.Exit(1)
+}
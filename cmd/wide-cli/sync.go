@@ -0,0 +1,249 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// node is a node of the file tree of the server.
+type node struct {
+	Name string `json:"name"` // file name
+	Path string `json:"path"` // path relative to {workspace}/src
+	Type string `json:"type"` // "f": file, "d": directory, "m": marker of more children
+	More int    `json:"more"` // offset of the remaining children if it's a marker
+}
+
+// remotePath cleans the specified remote path, returns "" for {workspace}/src.
+func remotePath(p string) string {
+	return path.Clean("/" + filepath.ToSlash(p))[1:]
+}
+
+// list lists the children of the specified remote directory.
+func (c *client) list(dir string) ([]*node, error) {
+	if "" == dir {
+		dir = "."
+	}
+
+	var ret []*node
+	offset := 0
+	for {
+		var nodes []*node
+		query := url.Values{"path": {dir}, "offset": {strconv.Itoa(offset)}}
+		if err := c.get("/files/tree", query, &nodes); nil != err {
+			return nil, err
+		}
+
+		more := false
+		for _, n := range nodes {
+			if "m" == n.Type {
+				offset, more = n.More, true
+
+				continue
+			}
+
+			n.Path = remotePath(n.Path)
+			ret = append(ret, n)
+		}
+
+		if !more {
+			return ret, nil
+		}
+	}
+}
+
+// walk walks the specified remote directory recursively, the specified function is called for each file and directory.
+func (c *client) walk(dir string, fn func(n *node) error) error {
+	nodes, err := c.list(dir)
+	if nil != err {
+		return err
+	}
+
+	for _, n := range nodes {
+		if err := fn(n); nil != err {
+			return err
+		}
+
+		if "d" == n.Type {
+			if err := c.walk(n.Path, fn); nil != err {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// pull downloads the files of the specified remote directory into the specified local directory. Files can't be
+// opened as text (such as images and binaries) are skipped.
+func (c *client) pull(remote, local string) error {
+	remote = remotePath(remote)
+	if err := os.MkdirAll(local, 0755); nil != err {
+		return err
+	}
+
+	count := 0
+	err := c.walk(remote, func(n *node) error {
+		localPath := filepath.Join(local, filepath.FromSlash(relPath(remote, n.Path)))
+		if "d" == n.Type {
+			return os.MkdirAll(localPath, 0755)
+		}
+
+		data := struct {
+			Content *string `json:"content"`
+		}{}
+		if err := c.call("/files/read", map[string]interface{}{"path": n.Path}, &data); nil != err || nil == data.Content {
+			fmt.Fprintf(os.Stderr, "skipped %s\n", n.Path)
+
+			return nil
+		}
+
+		if err := ioutil.WriteFile(localPath, []byte(*data.Content), 0644); nil != err {
+			return err
+		}
+		count++
+
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+
+	fmt.Printf("pulled %d files\n", count)
+
+	return nil
+}
+
+// push uploads the files of the specified local directory into the specified remote directory, directories are
+// created if not exist. Directories .git and files not in UTF-8 (such as binaries) are skipped.
+func (c *client) push(local, remote string) error {
+	remote = remotePath(remote)
+	dirs := &remoteDirs{client: c, exists: map[string]bool{"": true}, listed: map[string]bool{}}
+
+	count := 0
+	err := filepath.Walk(local, func(localPath string, info os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+
+		rel, err := filepath.Rel(local, localPath)
+		if nil != err {
+			return err
+		}
+		remoteFile := remotePath(path.Join(remote, filepath.ToSlash(rel)))
+
+		if info.IsDir() {
+			if ".git" == info.Name() {
+				return filepath.SkipDir
+			}
+
+			return dirs.mkdirAll(remoteFile)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(localPath)
+		if nil != err {
+			return err
+		}
+		if !utf8.Valid(content) {
+			fmt.Fprintf(os.Stderr, "skipped %s\n", localPath)
+
+			return nil
+		}
+
+		if err := c.call("/files/save", map[string]interface{}{"file": remoteFile, "code": string(content)},
+			nil); nil != err {
+			return err
+		}
+		count++
+
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+
+	fmt.Printf("pushed %d files\n", count)
+
+	return nil
+}
+
+// run builds (if the specified command is "build") or tests the package of the specified remote path, the output is
+// copied to the specified writer while the command runs. Returns the exit code of the command.
+func (c *client) run(cmd, remote string, out io.Writer) (int, error) {
+	return c.stream("/"+cmd, map[string]interface{}{"path": remotePath(remote)}, out)
+}
+
+// relPath returns the specified remote path relative to the specified remote directory.
+func relPath(dir, p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, dir), "/")
+}
+
+// remoteDirs creates remote directories, the directories known to exist are cached.
+type remoteDirs struct {
+	client *client
+	exists map[string]bool // directories known to exist
+	listed map[string]bool // directories whose children have been listed
+}
+
+// mkdirAll creates the specified remote directory along with any necessary parents.
+func (dirs *remoteDirs) mkdirAll(dir string) error {
+	if dirs.exists[dir] {
+		return nil
+	}
+
+	parent := path.Dir(dir)
+	if "." == parent {
+		parent = ""
+	}
+	if err := dirs.mkdirAll(parent); nil != err {
+		return err
+	}
+
+	if !dirs.listed[parent] {
+		nodes, err := dirs.client.list(parent)
+		if nil != err {
+			return err
+		}
+		for _, n := range nodes {
+			if "d" == n.Type {
+				dirs.exists[n.Path] = true
+			}
+		}
+		dirs.listed[parent] = true
+	}
+	if dirs.exists[dir] {
+		return nil
+	}
+
+	if err := dirs.client.call("/files/new", map[string]interface{}{"path": dir, "fileType": "d"}, nil); nil != err {
+		return err
+	}
+	dirs.exists[dir] = true
+	dirs.listed[dir] = true // a new directory is empty
+
+	return nil
+}
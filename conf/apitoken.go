@@ -0,0 +1,134 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APITokenPrefix is the prefix of API tokens, which makes leaked tokens easy to recognize.
+const APITokenPrefix = "wide_"
+
+// apiTokensMutex guards API tokens of users, which are marked used by concurrent requests.
+var apiTokensMutex sync.RWMutex
+
+// APIToken represents a token authenticating requests of the REST API (/api/v1) on behalf of a user.
+//
+// Only the SHA-256 hash of the token is stored, the token itself is shown once on creation.
+type APIToken struct {
+	Id      string // id of the token, used to revoke it
+	Name    string // name given by the user, such as "CI"
+	Hash    string // hex encoded SHA-256 hash of the token
+	Created int64  // create time in unix milliseconds
+	Used    int64  // the latest use time in unix milliseconds, 0 if never used
+}
+
+// NewAPIToken generates an API token with the specified name for the user, returns the token and its id. The user's
+// configurations are not saved.
+func (u *User) NewAPIToken(name string) (token, id string, err error) {
+	secret, err := randomHex(24)
+	if nil != err {
+		return "", "", err
+	}
+	if id, err = randomHex(8); nil != err {
+		return "", "", err
+	}
+
+	token = APITokenPrefix + secret
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+
+	u.APITokens = append(u.APITokens, &APIToken{Id: id, Name: name, Hash: hashAPIToken(token),
+		Created: time.Now().UnixNano() / int64(time.Millisecond)})
+
+	return token, id, nil
+}
+
+// RevokeAPIToken revokes the API token specified by the given id, returns false if not found. The user's
+// configurations are not saved.
+func (u *User) RevokeAPIToken(id string) bool {
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+
+	for i, token := range u.APITokens {
+		if token.Id == id {
+			u.APITokens = append(u.APITokens[:i], u.APITokens[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAPITokens returns copies of the API tokens of the user.
+func (u *User) GetAPITokens() []APIToken {
+	apiTokensMutex.RLock()
+	defer apiTokensMutex.RUnlock()
+
+	ret := []APIToken{}
+	for _, token := range u.APITokens {
+		ret = append(ret, *token)
+	}
+
+	return ret
+}
+
+// GetUserByAPIToken gets the user owning the specified API token and marks the token used, returns nil if the token
+// is not valid.
+func GetUserByAPIToken(token string) *User {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil
+	}
+
+	hash := hashAPIToken(token)
+	apiTokensMutex.Lock()
+	defer apiTokensMutex.Unlock()
+
+	for _, user := range GetUsers() {
+		for _, t := range user.APITokens {
+			if 1 == subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) {
+				t.Used = time.Now().UnixNano() / int64(time.Millisecond)
+
+				return user
+			}
+		}
+	}
+
+	return nil
+}
+
+// hashAPIToken returns the hex encoded SHA-256 hash of the specified API token.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns the hex encoded string of the specified number of cryptographically secure random bytes.
+func randomHex(size int) (string, error) {
+	ret := make([]byte, size)
+	if _, err := rand.Read(ret); nil != err {
+		return "", err
+	}
+
+	return hex.EncodeToString(ret), nil
+}
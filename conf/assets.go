@@ -0,0 +1,42 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"io/fs"
+	"os"
+)
+
+// the static/ and views/ directories embedded in the binary, set by SetAssets.
+var embeddedAssets fs.FS
+
+// SetAssets sets the file system containing the static/ and views/ directories embedded in the binary.
+func SetAssets(assets fs.FS) {
+	embeddedAssets = assets
+}
+
+// Assets gets the file system containing the static/ and views/ directories, the directory Wide.AssetsDir (for
+// development, changes take effect without rebuilding) if set, the embedded ones otherwise.
+func Assets() fs.FS {
+	if c := Wide(); nil != c && "" != c.AssetsDir {
+		return os.DirFS(c.AssetsDir)
+	}
+
+	if nil == embeddedAssets { // such as running tests
+		return os.DirFS(".")
+	}
+
+	return embeddedAssets
+}
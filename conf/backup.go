@@ -0,0 +1,55 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"path/filepath"
+)
+
+// Backup represents the configurations of scheduled backups of users' workspaces and configurations.
+type Backup struct {
+	Interval  int       // interval (in hour) between scheduled backups, 0 disables scheduled backups
+	Dir       string    // directory of backup archives (relative to Data if not absolute), defaults to {Data}/backups
+	Retention int       // number of the latest backups to keep, 0 for unlimited
+	S3        *BackupS3 // S3-compatible target archives are uploaded to, archives are kept locally only if nil
+}
+
+// BackupS3 represents an S3-compatible (AWS S3, MinIO, etc) target of backups.
+type BackupS3 struct {
+	Endpoint  string // such as "https://s3.us-east-1.amazonaws.com"
+	Region    string // such as "us-east-1"
+	Bucket    string
+	Prefix    string // prefix of object keys, such as "wide/"
+	AccessKey string
+	SecretKey string
+}
+
+// BackupDir gets the directory of backup archives.
+func (b *Backup) BackupDir() string {
+	dir := b.Dir
+	if "" == dir {
+		dir = "backups"
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(Wide().Data, dir)
+	}
+
+	return dir
+}
+
+// ConfPath gets the path of the Wide configuration file.
+func ConfPath() string {
+	return loadArgs.path
+}
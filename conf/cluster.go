@@ -0,0 +1,36 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// Cluster represents the configurations of running several Wide instances (replicas) behind a load balancer.
+//
+// All instances should share the same Data directory (such as a NFS or an object store mounted with a FUSE driver)
+// and the same configuration file. Instances without a shared volume should store users in the Database and
+// workspaces in the Storage instead. WebSocket messages and configuration changes are exchanged via the Redis
+// publish/subscribe channel, so requests of a browser tab can be served by any instance without sticky sessions.
+type Cluster struct {
+	Redis    string // address of the Redis server, such as "127.0.0.1:6379"
+	Password string // password of the Redis server, "" if authentication is not required
+	Channel  string // name of the publish/subscribe channel, defaults to "wide"
+}
+
+// ChannelName gets the name of the publish/subscribe channel.
+func (c *Cluster) ChannelName() string {
+	if "" == c.Channel {
+		return "wide"
+	}
+
+	return c.Channel
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// ContainerLabel is the label of images and containers built and run by Wide, the value is the id of the user owning
+// them.
+const ContainerLabel = "wide.user"
+
+// Containers represents the limits of containers run from images built of users' Dockerfiles (feature "docker").
+type Containers struct {
+	BindAddress   string // host address ports of containers are published on, defaults to "127.0.0.1"
+	CPUs          string // CPUs of a container (docker run --cpus), such as "0.5", "" for unlimited
+	Memory        string // memory of a container (docker run --memory), such as "256m", "" for unlimited
+	MaxContainers int    // max running containers per user, 0 for the default 3
+}
+
+// ContainersBindAddress returns the host address ports of containers are published on.
+func (c *conf) ContainersBindAddress() string {
+	if nil == c.Containers || "" == c.Containers.BindAddress {
+		return "127.0.0.1"
+	}
+
+	return c.Containers.BindAddress
+}
+
+// MaxContainers returns the max running containers per user.
+func (c *conf) MaxContainers() int {
+	if nil == c.Containers || 1 > c.Containers.MaxContainers {
+		return 3
+	}
+
+	return c.Containers.MaxContainers
+}
+
+// ContainerRunArgs returns the arguments of docker running a detached container with the specified name of the
+// specified image for the user specified by the given user id, the specified container ports are published on
+// random host ports of the bind address.
+func (c *conf) ContainerRunArgs(name, uid, image string, ports []string) []string {
+	ret := []string{"run", "-d", "--rm", "--name", name, "--label", ContainerLabel + "=" + uid}
+	if nil != c.Containers && "" != c.Containers.CPUs {
+		ret = append(ret, "--cpus", c.Containers.CPUs)
+	}
+	if nil != c.Containers && "" != c.Containers.Memory {
+		ret = append(ret, "--memory", c.Containers.Memory)
+	}
+	for _, port := range ports {
+		ret = append(ret, "-p", c.ContainersBindAddress()+"::"+port)
+	}
+
+	return append(ret, image)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainerRunArgs(t *testing.T) {
+	c := &conf{}
+	expected := []string{"run", "-d", "--rm", "--name", "wide-1-x", "--label", "wide.user=1", "-p",
+		"127.0.0.1::8080", "wide-1/hello:latest"}
+	if got := c.ContainerRunArgs("wide-1-x", "1", "wide-1/hello:latest", []string{"8080"}); !reflect.DeepEqual(expected,
+		got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if 3 != c.MaxContainers() {
+		t.Errorf("expected default max containers [3], got [%d]", c.MaxContainers())
+	}
+
+	c.Containers = &Containers{BindAddress: "0.0.0.0", CPUs: "0.5", Memory: "256m", MaxContainers: 1}
+	expected = []string{"run", "-d", "--rm", "--name", "wide-1-x", "--label", "wide.user=1", "--cpus", "0.5",
+		"--memory", "256m", "-p", "0.0.0.0::8080", "-p", "0.0.0.0::53/udp", "wide-1/hello:latest"}
+	if got := c.ContainerRunArgs("wide-1-x", "1", "wide-1/hello:latest", []string{"8080", "53/udp"}); !reflect.DeepEqual(
+		expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if 1 != c.MaxContainers() {
+		t.Errorf("expected max containers [1], got [%d]", c.MaxContainers())
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import "strings"
+
+// CORS represents the configurations of cross-origin requests to the JSON API, so that SPAs and tools served from
+// other origins could call it.
+type CORS struct {
+	AllowedOrigins   []string // such as "https://app.example.com", "https://*.example.com" or "*" for any origin
+	AllowCredentials bool     // whether to allow requests with cookies (the HTTP session), ignored with "*"
+	MaxAge           int      // seconds preflight results could be cached by browsers, 0 for browsers' default
+}
+
+// AllowsOrigin checks whether the specified origin (such as "https://app.example.com") is allowed.
+func (c *CORS) AllowsOrigin(origin string) bool {
+	if "" == origin {
+		return false
+	}
+
+	for _, allowed := range c.AllowedOrigins {
+		if "*" == allowed || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		if i := strings.Index(allowed, "://*."); -1 < i { // wildcard subdomains
+			scheme, domain := allowed[:i+3], allowed[i+4:]
+			if len(origin) > len(scheme)+len(domain) && strings.EqualFold(origin[:len(scheme)], scheme) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
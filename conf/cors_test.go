@@ -0,0 +1,44 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import "testing"
+
+func TestCORSAllowsOrigin(t *testing.T) {
+	c := &CORS{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"}}
+
+	cases := map[string]bool{
+		"https://app.example.com":    true,
+		"HTTPS://APP.EXAMPLE.COM":    true,
+		"http://app.example.com":     false,
+		"https://a.example.org":      true,
+		"https://a.b.example.org":    true,
+		"https://example.org":        false,
+		"https://.example.org":       false,
+		"http://a.example.org":       false,
+		"https://a.example.org.evil": false,
+		"https://evilexample.org":    false,
+		"":                           false,
+	}
+	for origin, expected := range cases {
+		if allowed := c.AllowsOrigin(origin); expected != allowed {
+			t.Errorf("origin [%s] expected allowed [%v], got [%v]", origin, expected, allowed)
+		}
+	}
+
+	if !(&CORS{AllowedOrigins: []string{"*"}}).AllowsOrigin("http://localhost:3000") {
+		t.Error("any origin should be allowed by \"*\"")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"net"
+	"time"
+)
+
+// DBConsole represents the limits of the database console (feature "dbconsole"), see User.DBConnections.
+//
+// Only database servers of public addresses are connected unless allowed by AllowedHosts (the same as
+// RESTClient.AllowedHosts), so users can't reach internal databases, such as the one of Wide itself.
+type DBConsole struct {
+	MaxRows      int      // max rows responded per query, 0 for the default 500
+	Timeout      int      // max seconds a query runs, 0 for the default 30
+	ReadOnly     bool     // whether queries run in read-only transactions
+	AllowedHosts []string // hosts ("db.staging", "*.svc.local") or networks ("10.0.0.0/8") allowed besides public ones
+}
+
+// DBConsoleMaxRows returns the max rows responded per query of the database console.
+func (c *conf) DBConsoleMaxRows() int {
+	if nil == c.DBConsole || 1 > c.DBConsole.MaxRows {
+		return 500
+	}
+
+	return c.DBConsole.MaxRows
+}
+
+// DBConsoleTimeout returns the max duration a query of the database console runs.
+func (c *conf) DBConsoleTimeout() time.Duration {
+	if nil == c.DBConsole || 1 > c.DBConsole.Timeout {
+		return 30 * time.Second
+	}
+
+	return time.Duration(c.DBConsole.Timeout) * time.Second
+}
+
+// AllowsHost checks whether the specified host name of a database server is allowed by AllowedHosts.
+func (d *DBConsole) AllowsHost(host string) bool {
+	return nil != d && allowsHost(d.AllowedHosts, host)
+}
+
+// AllowsIP checks whether the specified IP of a database server is in the networks of AllowedHosts.
+func (d *DBConsole) AllowsIP(ip net.IP) bool {
+	return nil != d && allowsIP(d.AllowedHosts, ip)
+}
+
+// checkDBConsole checks the allowed hosts of the specified database console.
+func checkDBConsole(d *DBConsole) error {
+	if nil == d {
+		return nil
+	}
+
+	return checkAllowedHosts("DBConsole", d.AllowedHosts)
+}
+
+// DBConnection represents a database connection saved by a user for the database console.
+type DBConnection struct {
+	Id      string // id of the connection
+	Name    string // name given by the user, such as "dev"
+	Driver  string // "postgres", "mysql" or "sqlite3"
+	DSN     string // data source name (encrypted with the vault key, it may contain the password), see GetDSN
+	Created int64  // create time in unix milliseconds
+}
+
+// GetDSN gets the data source name of the connection decrypted from the vault.
+func (c *DBConnection) GetDSN() (string, error) {
+	dsn, err := Open(c.DSN)
+	if nil != err {
+		return "", err
+	}
+
+	return string(dsn), nil
+}
+
+// NewDBConnection saves a database connection with the specified name, driver and data source name for the user. The
+// user's configurations are not saved.
+func (u *User) NewDBConnection(name, driver, dsn string) (*DBConnection, error) {
+	id, err := randomHex(8)
+	if nil != err {
+		return nil, err
+	}
+	sealed, err := Seal([]byte(dsn))
+	if nil != err {
+		return nil, err
+	}
+
+	ret := &DBConnection{Id: id, Name: name, Driver: driver, DSN: sealed,
+		Created: time.Now().UnixNano() / int64(time.Millisecond)}
+	u.DBConnections = append(u.DBConnections, ret)
+
+	return ret, nil
+}
+
+// GetDBConnection gets the database connection specified by the given id, returns nil if not found.
+func (u *User) GetDBConnection(id string) *DBConnection {
+	for _, c := range u.DBConnections {
+		if c.Id == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// RemoveDBConnection removes the database connection specified by the given id, returns false if not found. The
+// user's configurations are not saved.
+func (u *User) RemoveDBConnection(id string) bool {
+	for i, c := range u.DBConnections {
+		if c.Id == id {
+			u.DBConnections = append(u.DBConnections[:i], u.DBConnections[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"hash/fnv"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// Features holds feature flags of experimental subsystems by names, such as "migrate".
+type Features map[string]*Feature
+
+// Feature represents a feature flag, so that an experimental subsystem could be rolled out to subsets of users.
+type Feature struct {
+	Enabled bool     // whether the feature is enabled for all users
+	Users   []string // ids of users the feature is enabled for
+	Percent int      // percentage of users (selected by hashes of user ids) the feature is enabled for, [0, 100]
+}
+
+// FeatureEnabled checks whether the feature specified by the given name is enabled for the user specified by the given
+// user id. The user's own override (User.Features) takes precedence, unknown features are disabled.
+func (c *conf) FeatureEnabled(name, uid string) bool {
+	if user := GetUser(uid); nil != user {
+		if enabled, ok := user.Features[name]; ok {
+			return enabled
+		}
+	}
+
+	f := c.Features[name]
+	if nil == f {
+		return false
+	}
+
+	return f.Enabled || gulu.Str.Contains(uid, f.Users) || ("" != uid && featureBucket(name, uid) < f.Percent)
+}
+
+// UserFeatures gets all the features (of Wide.Features and the user's overrides) and whether they are enabled for the
+// user specified by the given user id.
+func (c *conf) UserFeatures(uid string) map[string]bool {
+	ret := map[string]bool{}
+	for name := range c.Features {
+		ret[name] = c.FeatureEnabled(name, uid)
+	}
+
+	if user := GetUser(uid); nil != user {
+		for name, enabled := range user.Features {
+			ret[name] = enabled
+		}
+	}
+
+	return ret
+}
+
+// featureBucket returns the bucket [0, 100) of the specified user for the specified feature, features are hashed as
+// well so that the same users are not always the first ones getting new features.
+func featureBucket(name, uid string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + uid))
+
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import "testing"
+
+func TestFeatureEnabled(t *testing.T) {
+	usersMutex.Lock()
+	prev := users
+	users = []*User{{Id: "alice", Features: map[string]bool{"terminal": false, "collab": true}}}
+	usersMutex.Unlock()
+	defer func() {
+		usersMutex.Lock()
+		users = prev
+		usersMutex.Unlock()
+	}()
+
+	c := &conf{Features: Features{
+		"terminal": {Enabled: true},
+		"gopls":    {Users: []string{"bob"}},
+		"all":      {Percent: 100},
+		"none":     {Percent: 0},
+	}}
+
+	cases := []struct {
+		name, uid string
+		expected  bool
+	}{
+		{"terminal", "bob", true},
+		{"terminal", "alice", false}, // overridden by the user
+		{"collab", "alice", true},    // enabled for the user only
+		{"collab", "bob", false},
+		{"gopls", "bob", true},
+		{"gopls", "alice", false},
+		{"all", "carol", true},
+		{"none", "carol", false},
+		{"unknown", "bob", false},
+	}
+	for _, tc := range cases {
+		if enabled := c.FeatureEnabled(tc.name, tc.uid); tc.expected != enabled {
+			t.Errorf("feature [%s] of user [%s] expected enabled [%v], got [%v]", tc.name, tc.uid, tc.expected, enabled)
+		}
+	}
+
+	features := c.UserFeatures("alice")
+	if 5 != len(features) || features["terminal"] || !features["collab"] {
+		t.Errorf("unexpected features %v", features)
+	}
+}
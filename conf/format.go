@@ -0,0 +1,355 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// confExts holds the supported extensions of configuration files.
+var confExts = map[string]bool{".json": true, ".yaml": true, ".yml": true, ".toml": true}
+
+// unmarshalConf parses the specified data of the specified configuration file (JSON, YAML or TOML according to the
+// extension) into v.
+//
+// Keys are the same as the JSON ones (field names, case-insensitive) in all formats. Values are validated against
+// the type of v, the error tells the offending key (and line if known), unknown keys are only warned.
+func unmarshalConf(path string, data []byte, v interface{}) error {
+	var value interface{}
+	lines := map[string]int{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		node := &yaml.Node{}
+		if err := yaml.Unmarshal(data, node); nil != err {
+			return fmt.Errorf("parses [%s] failed: %s", path, err)
+		}
+		if 0 < len(node.Content) {
+			value = yamlValue(node.Content[0], "", lines)
+		}
+	case ".toml":
+		m := map[string]interface{}{}
+		if _, err := toml.Decode(string(data), &m); nil != err {
+			return fmt.Errorf("parses [%s] failed: %s", path, err)
+		}
+		value = m
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); nil != err {
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				return fmt.Errorf("parses [%s] failed: line %d: %s", path, lineOf(data, syntaxErr.Offset), err)
+			}
+
+			return fmt.Errorf("parses [%s] failed: %s", path, err)
+		}
+	}
+
+	if nil == value { // empty file
+		value = map[string]interface{}{}
+	}
+
+	for _, problem := range validateConf(value, reflect.TypeOf(v).Elem(), "") {
+		where := ""
+		if line, ok := lines[problem.key]; ok {
+			where = " at line " + strconv.Itoa(line)
+		}
+
+		if problem.unknown {
+			logger.Warnf("Unknown key [%s]%s in [%s]", problem.key, where, path)
+
+			continue
+		}
+
+		return fmt.Errorf("invalid value of key [%s]%s in [%s]: %s", problem.key, where, path, problem.msg)
+	}
+
+	data, err := json.Marshal(value)
+	if nil != err {
+		return fmt.Errorf("parses [%s] failed: %s", path, err)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// marshalConf encodes the specified value in the format of the specified configuration file.
+func marshalConf(path string, v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if nil != err {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ".json" == ext || "" == ext {
+		return data, nil
+	}
+
+	// encodes with the JSON keys, null values are dropped since YAML and TOML readers don't need them
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); nil != err {
+		return nil, err
+	}
+	value = dropNulls(value)
+
+	if ".toml" == ext {
+		buf := &bytes.Buffer{}
+		if err := toml.NewEncoder(buf).Encode(value); nil != err {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	return yaml.Marshal(value)
+}
+
+// confProblem represents a problem of a configuration key.
+type confProblem struct {
+	key     string // key path, such as "Editor.FontSize"
+	msg     string // problem description
+	unknown bool   // whether the key is unknown
+}
+
+// validateConf validates the specified parsed configuration value against the specified type, returns the problems.
+func validateConf(value interface{}, t reflect.Type, key string) []*confProblem {
+	for reflect.Ptr == t.Kind() {
+		t = t.Elem()
+	}
+
+	if nil == value {
+		return nil
+	}
+
+	mismatch := func(expected string) []*confProblem {
+		return []*confProblem{{key: key, msg: fmt.Sprintf("should be %s, but got %v", expected, value)}}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("a string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isInteger(value) {
+			return mismatch("an integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if !isInteger(value) {
+			if _, ok := value.(float64); !ok {
+				return mismatch("a number")
+			}
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch("a list")
+		}
+
+		ret := []*confProblem{}
+		for i, item := range items {
+			ret = append(ret, validateConf(item, t.Elem(), key+"["+strconv.Itoa(i)+"]")...)
+		}
+
+		return ret
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("a map")
+		}
+
+		ret := []*confProblem{}
+		for _, k := range sortedKeys(m) {
+			ret = append(ret, validateConf(m[k], t.Elem(), joinKey(key, k))...)
+		}
+
+		return ret
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("a map")
+		}
+
+		ret := []*confProblem{}
+		for _, k := range sortedKeys(m) {
+			field, ok := jsonField(t, k)
+			if !ok {
+				ret = append(ret, &confProblem{key: joinKey(key, k), unknown: true})
+
+				continue
+			}
+
+			ret = append(ret, validateConf(m[k], field.Type, joinKey(key, k))...)
+		}
+
+		return ret
+	}
+
+	return nil
+}
+
+// jsonField returns the field of the specified struct type decoded from the specified JSON key.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if "" != field.PkgPath { // unexported
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if "-" == name {
+			continue
+		}
+		if "" == name {
+			name = field.Name
+		}
+
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// yamlValue converts the specified YAML node to a value like the one decoded by encoding/json, lines of keys under
+// the specified key are recorded into lines.
+func yamlValue(node *yaml.Node, key string, lines map[string]int) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if 0 < len(node.Content) {
+			return yamlValue(node.Content[0], key, lines)
+		}
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, key, lines)
+	case yaml.MappingNode:
+		ret := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := joinKey(key, node.Content[i].Value)
+			lines[k] = node.Content[i].Line
+			ret[node.Content[i].Value] = yamlValue(node.Content[i+1], k, lines)
+		}
+
+		return ret
+	case yaml.SequenceNode:
+		ret := []interface{}{}
+		for i, item := range node.Content {
+			k := key + "[" + strconv.Itoa(i) + "]"
+			lines[k] = item.Line
+			ret = append(ret, yamlValue(item, k, lines))
+		}
+
+		return ret
+	case yaml.ScalarNode:
+		var ret interface{}
+		if err := node.Decode(&ret); nil != err {
+			return node.Value
+		}
+
+		return ret
+	}
+
+	return nil
+}
+
+// isInteger checks whether the specified parsed value is an integer.
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int, int64, uint64:
+		return true
+	case float64:
+		return v == float64(int64(v))
+	case json.Number:
+		_, err := v.Int64()
+
+		return nil == err
+	}
+
+	return false
+}
+
+// dropNulls removes null values from the specified value decoded by encoding/json.
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if nil == item {
+				delete(v, k)
+
+				continue
+			}
+
+			v[k] = dropNulls(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNulls(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); nil == err {
+			return n
+		}
+		f, _ := v.Float64()
+
+		return f
+	}
+
+	return value
+}
+
+// lineOf returns the line number of the specified offset in the specified data.
+func lineOf(data []byte, offset int64) int {
+	if int64(len(data)) < offset {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// joinKey joins the specified parent key path and key.
+func joinKey(parent, key string) string {
+	if "" == parent {
+		return key
+	}
+
+	return parent + "." + key
+}
+
+// sortedKeys returns the sorted keys of the specified map.
+func sortedKeys(m map[string]interface{}) []string {
+	ret := []string{}
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
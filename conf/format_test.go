@@ -0,0 +1,123 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalConf(t *testing.T) {
+	cases := []struct {
+		path string
+		data string
+	}{
+		{"wide.json", `{"Server": "http://127.0.0.1:7070", "HTTPSessionMaxAge": 86400, "Autocomplete": true,
+			"PreCommitChecks": ["gofmt", "vet"]}`},
+		{"wide.yaml", "server: http://127.0.0.1:7070\nhttpSessionMaxAge: 86400\nautocomplete: true\n" +
+			"preCommitChecks:\n  - gofmt\n  - vet\n"},
+		{"wide.yml", "Server: http://127.0.0.1:7070\nHTTPSessionMaxAge: 86400\nAutocomplete: true\n" +
+			"PreCommitChecks: [gofmt, vet]\n"},
+		{"wide.toml", "Server = \"http://127.0.0.1:7070\"\nHTTPSessionMaxAge = 86400\nAutocomplete = true\n" +
+			"PreCommitChecks = [\"gofmt\", \"vet\"]\n"},
+	}
+
+	for _, c := range cases {
+		v := &conf{}
+		if err := unmarshalConf(c.path, []byte(c.data), v); nil != err {
+			t.Errorf("parses [%s] failed: %s", c.path, err)
+
+			continue
+		}
+
+		if "http://127.0.0.1:7070" != v.Server || 86400 != v.HTTPSessionMaxAge || !v.Autocomplete ||
+			2 != len(v.PreCommitChecks) || "vet" != v.PreCommitChecks[1] {
+			t.Errorf("unexpected configurations %+v parsed from [%s]", *v, c.path)
+		}
+	}
+}
+
+func TestUnmarshalUser(t *testing.T) {
+	data := "name: admin\neditor:\n  fontSize: 13px\n  tabSize: \"4\"\n"
+	user := &User{}
+	if err := unmarshalConf("admin.yaml", []byte(data), user); nil != err {
+		t.Fatalf("parses user failed: %s", err)
+	}
+
+	if "admin" != user.Name || nil == user.Editor || "13px" != user.Editor.FontSize || "4" != user.Editor.TabSize {
+		t.Errorf("unexpected user %+v", *user)
+	}
+}
+
+func TestUnmarshalConfInvalid(t *testing.T) {
+	cases := []struct {
+		path string
+		data string
+		msg  string // expected part of the error message
+	}{
+		{"wide.yaml", "server: a\nhttpSessionMaxAge: forever\n", "[httpSessionMaxAge] at line 2"},
+		{"wide.yaml", "autocomplete: 1\n", "[autocomplete] at line 1"},
+		{"wide.yaml", "preCommitChecks: gofmt\n", "[preCommitChecks]"},
+		{"wide.toml", "HTTPSessionMaxAge = \"1h\"\n", "[HTTPSessionMaxAge]"},
+		{"wide.toml", "Server = \n", "parses [wide.toml] failed"},
+		{"wide.json", "{\n\"Server\": \"a\",\n}", "line 3"},
+		{"wide.json", `{"HTTPSessionMaxAge": 1.5}`, "[HTTPSessionMaxAge]"},
+	}
+
+	for _, c := range cases {
+		err := unmarshalConf(c.path, []byte(c.data), &conf{})
+		if nil == err {
+			t.Errorf("parsing [%s] of [%s] should fail", c.data, c.path)
+
+			continue
+		}
+
+		if !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("expected error containing [%s], got [%s]", c.msg, err)
+		}
+	}
+}
+
+func TestUnmarshalConfUnknownKey(t *testing.T) {
+	v := &conf{}
+	if err := unmarshalConf("wide.yaml", []byte("server: a\nnoSuchKey: 1\n"), v); nil != err {
+		t.Errorf("unknown keys should be only warned, got [%s]", err)
+	}
+	if "a" != v.Server {
+		t.Errorf("expected server [a], got [%s]", v.Server)
+	}
+}
+
+func TestMarshalConf(t *testing.T) {
+	for _, path := range []string{"wide.json", "wide.yaml", "wide.toml"} {
+		data, err := marshalConf(path, &conf{Server: "http://127.0.0.1:7070", HTTPSessionMaxAge: 86400,
+			PreCommitChecks: []string{"gofmt"}})
+		if nil != err {
+			t.Errorf("encodes [%s] failed: %s", path, err)
+
+			continue
+		}
+
+		v := &conf{}
+		if err := unmarshalConf(path, data, v); nil != err {
+			t.Errorf("parses encoded [%s] failed: %s", path, err)
+
+			continue
+		}
+		if "http://127.0.0.1:7070" != v.Server || 86400 != v.HTTPSessionMaxAge || 1 != len(v.PreCommitChecks) {
+			t.Errorf("unexpected configurations %+v round-tripped through [%s]", *v, path)
+		}
+	}
+}
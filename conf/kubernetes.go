@@ -0,0 +1,76 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Kubernetes represents the cluster users deploy demo services to (feature "deploy", see package deploy).
+//
+// Manifests (.yaml, .yml or .json) of a directory are applied as they are, a Go package without manifests is built
+// for linux/amd64, pushed to Registry as an image of BaseImage and deployed with a generated Deployment and Service.
+type Kubernetes struct {
+	Kubeconfig    string // path of the kubeconfig, "" for the in-cluster configuration
+	Context       string // context of the kubeconfig, "" for the current one
+	Namespace     string // namespace deploying to, "{uid}" is replaced with the user id, such as "wide-{uid}"
+	Registry      string // registry images of binaries are pushed to, "" disables deploying binaries
+	BaseImage     string // base image of binaries, defaults to "gcr.io/distroless/static"
+	Port          int    // port binaries listen on (exposed by the generated Service), 0 for the default 8080
+	EventsTimeout int    // seconds events are streamed after applying, 0 for the default 60
+}
+
+// namespaceRegexp matches characters not allowed in namespaces.
+var namespaceRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// UserNamespace returns the namespace the user specified by the given user id deploys to.
+func (k *Kubernetes) UserNamespace(uid string) string {
+	if "" == k.Namespace {
+		return "default"
+	}
+
+	ret := strings.Replace(k.Namespace, "{uid}", strings.ToLower(uid), -1)
+
+	return strings.Trim(namespaceRegexp.ReplaceAllString(ret, "-"), "-")
+}
+
+// GetBaseImage returns the base image of binaries.
+func (k *Kubernetes) GetBaseImage() string {
+	if "" == k.BaseImage {
+		return "gcr.io/distroless/static"
+	}
+
+	return k.BaseImage
+}
+
+// GetPort returns the port binaries listen on.
+func (k *Kubernetes) GetPort() int {
+	if 1 > k.Port {
+		return 8080
+	}
+
+	return k.Port
+}
+
+// EventsDuration returns the duration events are streamed after applying.
+func (k *Kubernetes) EventsDuration() time.Duration {
+	if 1 > k.EventsTimeout {
+		return 60 * time.Second
+	}
+
+	return time.Duration(k.EventsTimeout) * time.Second
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKubernetes(t *testing.T) {
+	k := &Kubernetes{}
+	if "default" != k.UserNamespace("1") {
+		t.Errorf("expected namespace [default], got [%s]", k.UserNamespace("1"))
+	}
+	if "gcr.io/distroless/static" != k.GetBaseImage() || 8080 != k.GetPort() || 60*time.Second != k.EventsDuration() {
+		t.Errorf("unexpected defaults [%s, %d, %s]", k.GetBaseImage(), k.GetPort(), k.EventsDuration())
+	}
+
+	k = &Kubernetes{Namespace: "wide-{uid}", Port: 3000, EventsTimeout: 5}
+	if "wide-alice-b" != k.UserNamespace("Alice_B") {
+		t.Errorf("expected namespace [wide-alice-b], got [%s]", k.UserNamespace("Alice_B"))
+	}
+	if 3000 != k.GetPort() || 5*time.Second != k.EventsDuration() {
+		t.Errorf("unexpected configurations [%d, %s]", k.GetPort(), k.EventsDuration())
+	}
+}
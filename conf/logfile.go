@@ -0,0 +1,216 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// layout of the timestamp in names of rotated log files, such as "wide-20060102-150405.000.log".
+const logBackupLayout = "20060102-150405.000"
+
+// the log file in use, nil if logging to stdout only.
+var logFile *rotatingFile
+
+// rotatingFile represents a log file rotated by size and day, rotated files are kept according to the retention
+// policies.
+type rotatingFile struct {
+	mutex      sync.Mutex
+	path       string    // path of the log file
+	maxSize    int64     // max size (in byte) before rotating, 0 for unlimited
+	daily      bool      // whether to rotate at midnight
+	maxAge     int       // max days to keep rotated files, 0 for unlimited
+	maxBackups int       // max number of rotated files to keep, 0 for unlimited
+	file       *os.File  // the opened file
+	size       int64     // current size of the file
+	opened     time.Time // time of opening the file
+}
+
+// initLogFile directs logging to stdout and the log file configured in the specified configurations.
+func initLogFile(c *conf) {
+	if "" == c.LogFile {
+		if nil != logFile {
+			gulu.Log.SetOutput(os.Stdout)
+			logFile.close()
+			logFile = nil
+		}
+
+		return
+	}
+
+	if nil != logFile && logFile.path == c.LogFile {
+		logFile.setPolicies(c)
+
+		return
+	}
+
+	f := &rotatingFile{path: c.LogFile}
+	f.setPolicies(c)
+	if err := f.open(); nil != err {
+		logger.Errorf("Opens log file [%s] failed: %s", c.LogFile, err)
+
+		return
+	}
+
+	gulu.Log.SetOutput(io.MultiWriter(os.Stdout, f))
+	if nil != logFile {
+		logFile.close()
+	}
+	logFile = f
+
+	logger.Infof("Logging to [%s]", c.LogFile)
+}
+
+// LogFiles returns paths of the log file and the rotated ones (newest first), returns nil if logging to stdout only.
+func LogFiles() []string {
+	if nil == logFile {
+		return nil
+	}
+
+	return append([]string{logFile.path}, logFile.backups()...)
+}
+
+// setPolicies sets the rotation and retention policies of the log file with the specified configurations.
+func (f *rotatingFile) setPolicies(c *conf) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.maxSize = int64(c.LogMaxSize) * 1024 * 1024
+	f.daily = c.LogRotateDaily
+	f.maxAge = c.LogMaxAge
+	f.maxBackups = c.LogMaxBackups
+}
+
+// Write writes the specified data to the log file, rotates the file before writing if needed.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if nil == f.file {
+		return 0, os.ErrClosed
+	}
+
+	now := time.Now()
+	if (0 < f.maxSize && f.maxSize < f.size+int64(len(p)) && 0 < f.size) ||
+		(f.daily && now.Format("20060102") != f.opened.Format("20060102")) {
+		if err := f.rotate(now); nil != err {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// open opens (appends) the log file.
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); nil != err {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if nil != err {
+		return err
+	}
+
+	info, err := file.Stat()
+	if nil != err {
+		file.Close()
+
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = info.ModTime()
+	if 0 == f.size {
+		f.opened = time.Now()
+	}
+
+	return nil
+}
+
+// rotate renames the log file with the specified time, opens a new one and removes the expired rotated files.
+func (f *rotatingFile) rotate(now time.Time) error {
+	f.file.Close()
+	f.file = nil
+
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + now.Format(logBackupLayout) + ext
+	if err := os.Rename(f.path, backup); nil != err {
+		// can't log with logger here since it writes to this file
+		fmt.Fprintf(os.Stderr, "Rotates log file [%s] failed: %s\n", f.path, err)
+
+		return f.open()
+	}
+
+	if err := f.open(); nil != err {
+		return err
+	}
+
+	for i, path := range f.backups() {
+		info, err := os.Stat(path)
+		if nil != err {
+			continue
+		}
+
+		if (0 < f.maxBackups && f.maxBackups <= i) ||
+			(0 < f.maxAge && now.Sub(info.ModTime()) > time.Duration(f.maxAge)*24*time.Hour) {
+			os.Remove(path)
+		}
+	}
+
+	return nil
+}
+
+// backups returns paths of the rotated log files, newest first.
+func (f *rotatingFile) backups() []string {
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+
+	paths, _ := filepath.Glob(prefix + "*" + ext)
+	ret := []string{}
+	for _, path := range paths {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext)
+		if _, err := time.Parse(logBackupLayout, stamp); nil == err {
+			ret = append(ret, path)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ret)))
+
+	return ret
+}
+
+// close closes the log file.
+func (f *rotatingFile) close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if nil != f.file {
+		f.file.Close()
+		f.file = nil
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// flag values of conf fields, <field name, value>.
+var confFlags = map[string]*string{}
+
+// RegisterFlags registers a command line flag for each field of the Wide configurations, for example flag
+// "http_session_max_age" for field HTTPSessionMaxAge. Flags already registered (such as "data") are skipped.
+//
+// It should be called before flag.Parse.
+func RegisterFlags() {
+	t := reflect.TypeOf(conf{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		flagName := snakeCase(name)
+		if nil != flag.Lookup(flagName) || !overridable(t.Field(i).Type) {
+			continue
+		}
+
+		confFlags[name] = flag.String(flagName, "", "this will overwrite Wide."+name+" if specified")
+	}
+}
+
+// applyOverrides overrides fields of the specified configurations with environment variables (named in upper snake
+// case with prefix "WIDE_", for example WIDE_HTTP_SESSION_MAX_AGE) and command line flags, flags take precedence.
+//
+// Values of list fields (such as PreCommitChecks) are separated by commas.
+func applyOverrides(c *conf) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+
+		value, ok := os.LookupEnv("WIDE_" + strings.ToUpper(snakeCase(name)))
+		if f := confFlags[name]; nil != f && "" != *f {
+			value, ok = *f, true
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(v.Field(i), value); nil != err {
+			return fmt.Errorf("overrides [%s] with [%s] failed: %s", name, value, err)
+		}
+		logger.Debugf("Overrode [%s] with [%s]", name, value)
+	}
+
+	return nil
+}
+
+// setField sets the specified field with the specified string value.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if nil != err {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if nil != err {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if !overridable(field.Type()) {
+			return fmt.Errorf("unsupported type [%s]", field.Type())
+		}
+
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); "" != item {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type [%s]", field.Type())
+	}
+
+	return nil
+}
+
+// overridable checks whether fields of the specified type can be overridden with a string value, lists of structs
+// (such as Webhooks) can't.
+func overridable(t reflect.Type) bool {
+	return reflect.Slice != t.Kind() || reflect.String == t.Elem().Kind()
+}
+
+// snakeCase converts the specified field name to snake case, for example "HTTPSessionMaxAge" to
+// "http_session_max_age".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	ret := []rune{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && 0 < i &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			ret = append(ret, '_')
+		}
+		ret = append(ret, unicode.ToLower(r))
+	}
+
+	return string(ret)
+}
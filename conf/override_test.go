@@ -0,0 +1,85 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"Server", "server"},
+		{"LogLevel", "log_level"},
+		{"HTTPSessionMaxAge", "http_session_max_age"},
+		{"TLSCert", "tls_cert"},
+		{"HTTPSAddr", "https_addr"},
+		{"PreCommitChecks", "pre_commit_checks"},
+		{"WebPushSubject", "web_push_subject"},
+	}
+
+	for _, c := range cases {
+		if got := snakeCase(c.name); c.expected != got {
+			t.Errorf("expected [%s] of [%s], got [%s]", c.expected, c.name, got)
+		}
+	}
+}
+
+func TestSetField(t *testing.T) {
+	cases := []struct {
+		field    string
+		value    string
+		expected interface{}
+		fail     bool
+	}{
+		{field: "Server", value: "http://127.0.0.1:7070", expected: "http://127.0.0.1:7070"},
+		{field: "HTTPSessionMaxAge", value: " 3600 ", expected: 3600},
+		{field: "HTTPSessionMaxAge", value: "1h", fail: true},
+		{field: "Autocomplete", value: "true", expected: true},
+		{field: "Autocomplete", value: "yes", fail: true},
+		{field: "PreCommitChecks", value: "gofmt, vet,,", expected: []string{"gofmt", "vet"}},
+		{field: "Admins", value: "", expected: []string{}},
+		{field: "Webhooks", value: "slack", fail: true},
+	}
+
+	for _, c := range cases {
+		v := reflect.ValueOf(&conf{}).Elem().FieldByName(c.field)
+		err := setField(v, c.value)
+		if c.fail {
+			if nil == err {
+				t.Errorf("setting [%s] with [%s] should fail", c.field, c.value)
+			}
+
+			continue
+		}
+
+		if nil != err {
+			t.Errorf("sets [%s] with [%s] failed: %s", c.field, c.value, err)
+
+			continue
+		}
+
+		got := v.Interface()
+		if reflect.Int == v.Kind() {
+			got = int(v.Int())
+		}
+		if !reflect.DeepEqual(c.expected, got) {
+			t.Errorf("expected [%v] of [%s], got [%v]", c.expected, c.field, got)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Plugin represents a plugin, a subprocess extending Wide via JSON-RPC 2.0 over its stdin and stdout (see package
+// plugin). Plugins run with the privileges of Wide, so only administrators configure them.
+type Plugin struct {
+	Name    string   // name of the plugin, such as "protoc", its HTTP handlers are served under /plugins/{name}/
+	Command []string // command line, an executable containing "/" is relative to {Data}/plugins if not absolute
+	Env     []string // extra environment variables of the plugin process, such as "PROTOC=/usr/local/bin/protoc"
+}
+
+// pluginName is the pattern of plugin names.
+var pluginName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Executable returns the executable of the plugin resolved against the specified data directory.
+func (p *Plugin) Executable(data string) string {
+	exe := p.Command[0]
+	if filepath.IsAbs(exe) || !strings.ContainsRune(filepath.ToSlash(exe), '/') {
+		return exe
+	}
+
+	return filepath.Join(data, "plugins", filepath.FromSlash(exe))
+}
+
+// checkPlugins checks the names and commands of the specified plugins.
+func checkPlugins(plugins []*Plugin) error {
+	names := map[string]bool{}
+	for _, p := range plugins {
+		if nil == p || !pluginName.MatchString(p.Name) {
+			return errors.New("plugin names should consist of lowercase letters, digits, '-' and '_'")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicated plugin [%s]", p.Name)
+		}
+		names[p.Name] = true
+
+		if 1 > len(p.Command) || "" == p.Command[0] {
+			return fmt.Errorf("command of plugin [%s] is required", p.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPlugins(t *testing.T) {
+	cases := []struct {
+		plugins []*Plugin
+		valid   bool
+	}{
+		{nil, true},
+		{[]*Plugin{{Name: "protoc-gen", Command: []string{"protoc.sh"}}, {Name: "lint_2", Command: []string{"lint"}}}, true},
+		{[]*Plugin{{Name: "Protoc", Command: []string{"protoc.sh"}}}, false},
+		{[]*Plugin{{Name: "../evil", Command: []string{"evil"}}}, false},
+		{[]*Plugin{{Name: "lint", Command: []string{"a"}}, {Name: "lint", Command: []string{"b"}}}, false},
+		{[]*Plugin{{Name: "lint"}}, false},
+		{[]*Plugin{{Name: "lint", Command: []string{""}}}, false},
+		{[]*Plugin{nil}, false},
+	}
+	for i, c := range cases {
+		if err := checkPlugins(c.plugins); c.valid != (nil == err) {
+			t.Errorf("case %d: expected valid [%v], got error [%v]", i, c.valid, err)
+		}
+	}
+}
+
+func TestPluginExecutable(t *testing.T) {
+	data := filepath.FromSlash("/wide/data")
+
+	cases := map[string]string{
+		"python3":             "python3",
+		"./lint.py":           filepath.Join(data, "plugins", "lint.py"),
+		"lint/bin/lint":       filepath.Join(data, "plugins", "lint", "bin", "lint"),
+		"/usr/local/bin/lint": "/usr/local/bin/lint",
+	}
+	for exe, expected := range cases {
+		p := &Plugin{Name: "lint", Command: []string{exe}}
+		if got := p.Executable(data); filepath.FromSlash(expected) != got {
+			t.Errorf("executable [%s] expected [%s], got [%s]", exe, expected, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// Protoc represents the protoc integration generating code of .proto files.
+//
+// Code is generated next to the .proto file (paths=source_relative) by the configured protoc plugins, such as
+// protoc-gen-go of plugin "go". Plugins are looked up in PATH and the bin directories of the user's workspaces, so
+// users can go install them.
+type Protoc struct {
+	Command  string   // protoc executable, defaults to "protoc"
+	Plugins  []string // plugins generating code, such as ["go", "go-grpc"] for --go_out and --go-grpc_out
+	Includes []string // import paths besides the directory of the file, relative to the directory if not absolute
+}
+
+// Args returns the arguments of protoc generating code of the specified .proto file in the current directory.
+func (p *Protoc) Args(file string) []string {
+	ret := []string{"-I", "."}
+	for _, include := range p.Includes {
+		ret = append(ret, "-I", include)
+	}
+	for _, plugin := range p.Plugins {
+		ret = append(ret, "--"+plugin+"_out=.", "--"+plugin+"_opt=paths=source_relative")
+	}
+
+	return append(ret, file)
+}
+
+// Executable returns the protoc executable.
+func (p *Protoc) Executable() string {
+	if "" == p.Command {
+		return "protoc"
+	}
+
+	return p.Command
+}
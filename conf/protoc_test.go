@@ -0,0 +1,34 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProtocArgs(t *testing.T) {
+	p := &Protoc{Plugins: []string{"go", "go-grpc"}, Includes: []string{"../third_party"}}
+
+	expected := []string{"-I", ".", "-I", "../third_party", "--go_out=.", "--go_opt=paths=source_relative",
+		"--go-grpc_out=.", "--go-grpc_opt=paths=source_relative", "hello.proto"}
+	if got := p.Args("hello.proto"); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if "protoc" != p.Executable() {
+		t.Errorf("executable should default to [protoc], got [%s]", p.Executable())
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// RateLimits holds rate limits by groups of expensive handlers: "search", "build", "clone", "playground", "query" and
+// "request".
+type RateLimits map[string]*RateLimit
+
+// RateLimit represents the rate limit of requests of a group of expensive handlers.
+//
+// Requests are limited per user, anonymous requests (such as the playground ones) are limited per IP.
+type RateLimit struct {
+	PerMinute float64 // requests allowed per minute, 0 disables the limit
+	Burst     int     // max requests allowed at once, defaults to PerMinute
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// reloadMutex serializes configurations reloading.
+var reloadMutex sync.Mutex
+
+// findUser finds the user specified by the given user id in the specified users, returns nil if not found.
+func findUser(users []*User, id string) *User {
+	for _, user := range users {
+		if user.Id == id {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// Reload reloads the Wide configurations from wide.json and users' configurations from users/{userId}.json, then
+// notifies the connected clients with event EvtCodeConfReloaded.
+//
+// The data directory, the server address and the context can't be changed without restarting, changes of them are
+// ignored. The session states (latest session content) of users are kept.
+func Reload() error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	wide, err := loadWide()
+	if nil != err {
+		return err
+	}
+
+	prev := Wide()
+	if wide.Data != prev.Data {
+		logger.Warnf("Changing data directory from [%s] to [%s] requires restarting Wide", prev.Data, wide.Data)
+		wide.Data = prev.Data
+	}
+	if wide.Server != prev.Server {
+		logger.Warnf("Changing server from [%s] to [%s] requires restarting Wide", prev.Server, wide.Server)
+		wide.Server = prev.Server
+	}
+	if wide.Context != prev.Context {
+		logger.Warnf("Changing context from [%s] to [%s] requires restarting Wide", prev.Context, wide.Context)
+		wide.Context = prev.Context
+	}
+	if !reflect.DeepEqual(wide.Database, prev.Database) {
+		logger.Warnf("Changing database requires restarting Wide")
+		wide.Database = prev.Database
+	}
+	if !reflect.DeepEqual(wide.Plugins, prev.Plugins) {
+		logger.Warnf("Changing plugins requires restarting Wide")
+		wide.Plugins = prev.Plugins
+	}
+	wide.StaticResourceVersion = prev.StaticResourceVersion
+
+	loaded, err := loadUsers()
+	if nil != err {
+		return err
+	}
+
+	usersMutex.Lock()
+	reloaded := map[string]bool{}
+	current := []*User{}
+	for _, user := range loaded {
+		reloaded[user.Id] = true
+		if old := findUser(users, user.Id); nil != old {
+			// the old one is referred by sessions and handlers, updates it instead of replacing
+			user.Lived = old.Lived
+			user.LatestSessionContent = old.LatestSessionContent
+			*old = *user
+			user = old
+		}
+		current = append(current, user)
+	}
+	for _, old := range users {
+		// keeps the user whose configuration file exists but is broken
+		if !reloaded[old.Id] && gulu.File.IsExist(old.confPath()) {
+			current = append(current, old)
+		}
+	}
+	users = current
+	usersMutex.Unlock()
+
+	wideConf.Store(wide)
+
+	initLogFile(wide)
+	initWorkspaceDirs()
+	initCustomizedConfs()
+
+	logger.Infof("Reloaded configurations, %d users", len(current))
+
+	event.Publish(&event.Event{Code: event.EvtCodeConfReloaded})
+
+	return nil
+}
+
+// WatchConf watches wide.json and users' configurations, reloads them after they changed.
+//
+// Changes made by Wide itself (such as saving preference) are ignored.
+func WatchConf() {
+	watcher, err := fsnotify.NewWatcher()
+	if nil != err {
+		logger.Error(err)
+
+		return
+	}
+
+	confPath := filepath.Clean(loadArgs.path)
+	usersDir := filepath.Join(Wide().Data, "users")
+	for _, path := range []string{filepath.Dir(confPath), usersDir} {
+		if err := watcher.Add(path); nil != err {
+			logger.Warnf("Watches [%s] failed: %s", path, err)
+		}
+	}
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		var timer *time.Timer
+		for {
+			select {
+			case e := <-watcher.Events:
+				path := filepath.Clean(e.Name)
+				if path != confPath && (usersDir != filepath.Dir(path) || !userConfChanged(path)) {
+					continue
+				}
+
+				// editors may write a file several times, reloads once after they finished
+				if nil != timer {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(time.Second, func() {
+					defer gulu.Panic.Recover(nil)
+
+					if err := Reload(); nil != err {
+						logger.Errorf("Reloads configurations failed: %s", err)
+					}
+				})
+			case err := <-watcher.Errors:
+				if nil != err {
+					logger.Error("Conf watcher ERROR: ", err)
+				}
+			}
+		}
+	}()
+}
+
+// userConfChanged checks whether the specified user configuration file is different from the user's configurations
+// in memory.
+func userConfChanged(path string) bool {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	if strings.HasPrefix(name, ".") || !confExts[strings.ToLower(ext)] {
+		return false
+	}
+
+	user := GetUser(strings.TrimSuffix(name, ext))
+	if nil == user || path != user.confPath() {
+		return true
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return true // removed
+	}
+
+	saved, _ := marshalConf(path, user)
+
+	return !bytes.Equal(data, saved)
+}
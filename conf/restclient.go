@@ -0,0 +1,120 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// RESTClient represents the HTTP request tester (feature "restclient") sending requests described by users from the
+// server's network.
+//
+// Only public addresses are requested unless allowed by AllowedHosts, so users can't reach internal services or cloud
+// metadata endpoints (SSRF) but the ones allowed, such as staging services only reachable from the server.
+type RESTClient struct {
+	AllowedHosts []string // hosts ("api.staging", "*.svc.local") or networks ("10.0.0.0/8") allowed besides public ones
+	Timeout      int      // max seconds a request takes, 0 for the default 30
+	MaxBodySize  int      // max size (in KB) of response bodies responded, 0 for the default 1024
+}
+
+// RESTClientTimeout returns the max duration a request of the HTTP request tester takes.
+func (c *conf) RESTClientTimeout() time.Duration {
+	if nil == c.RESTClient || 1 > c.RESTClient.Timeout {
+		return 30 * time.Second
+	}
+
+	return time.Duration(c.RESTClient.Timeout) * time.Second
+}
+
+// RESTClientMaxBodySize returns the max size (in byte) of response bodies responded by the HTTP request tester.
+func (c *conf) RESTClientMaxBodySize() int {
+	if nil == c.RESTClient || 1 > c.RESTClient.MaxBodySize {
+		return 1024 * 1024
+	}
+
+	return c.RESTClient.MaxBodySize * 1024
+}
+
+// AllowsHost checks whether the specified host name is allowed by AllowedHosts. A pattern "*.{domain}" allows the
+// subdomains of the domain.
+func (r *RESTClient) AllowsHost(host string) bool {
+	return nil != r && allowsHost(r.AllowedHosts, host)
+}
+
+// AllowsIP checks whether the specified IP is in the networks of AllowedHosts.
+func (r *RESTClient) AllowsIP(ip net.IP) bool {
+	return nil != r && allowsIP(r.AllowedHosts, ip)
+}
+
+// checkRESTClient checks the allowed hosts of the specified HTTP request tester.
+func checkRESTClient(r *RESTClient) error {
+	if nil == r {
+		return nil
+	}
+
+	return checkAllowedHosts("RESTClient", r.AllowedHosts)
+}
+
+// allowsHost checks whether the specified host name is allowed by the specified allowed hosts, a pattern "*.{domain}"
+// allows the subdomains of the domain.
+func allowsHost(allowedHosts []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsIP checks whether the specified IP is in the networks of the specified allowed hosts.
+func allowsIP(allowedHosts []string, ip net.IP) bool {
+	for _, allowed := range allowedHosts {
+		if _, network, err := net.ParseCIDR(allowed); nil == err && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAllowedHosts checks the specified allowed hosts of the configuration of the specified name.
+func checkAllowedHosts(name string, allowedHosts []string) error {
+	for _, allowed := range allowedHosts {
+		if strings.Contains(allowed, "/") {
+			if _, _, err := net.ParseCIDR(allowed); nil != err {
+				return fmt.Errorf("allowed network [%s] of %s is invalid: %s", allowed, name, err)
+			}
+
+			continue
+		}
+
+		host := strings.TrimPrefix(allowed, "*.")
+		if "" == host || strings.ContainsAny(host, "*:") {
+			return fmt.Errorf("allowed host [%s] of %s is invalid", allowed, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRESTClientAllows(t *testing.T) {
+	r := &RESTClient{AllowedHosts: []string{"api.staging", "*.svc.local", "10.1.0.0/16"}}
+
+	hosts := []struct {
+		host string
+		want bool
+	}{
+		{"api.staging", true},
+		{"API.Staging.", true},
+		{"db.svc.local", true},
+		{"svc.local", false},
+		{"evilsvc.local", false},
+		{"api.staging.evil.com", false},
+	}
+	for i, c := range hosts {
+		if got := r.AllowsHost(c.host); c.want != got {
+			t.Errorf("host case %d: expected [%v], got [%v]", i, c.want, got)
+		}
+	}
+
+	if !r.AllowsIP(net.ParseIP("10.1.2.3")) || r.AllowsIP(net.ParseIP("10.2.0.1")) {
+		t.Errorf("allows wrong networks")
+	}
+
+	var none *RESTClient
+	if none.AllowsHost("api.staging") || none.AllowsIP(net.ParseIP("10.1.2.3")) {
+		t.Errorf("nil allows hosts")
+	}
+}
+
+func TestCheckRESTClient(t *testing.T) {
+	cases := []struct {
+		hosts []string
+		valid bool
+	}{
+		{nil, true},
+		{[]string{"api.staging", "*.svc.local", "10.0.0.0/8", "fd00::/8"}, true},
+		{[]string{"10.0.0.0/33"}, false},
+		{[]string{"*"}, false},
+		{[]string{"*.*.local"}, false},
+		{[]string{"api.staging:8080"}, false},
+	}
+	for i, c := range cases {
+		if err := checkRESTClient(&RESTClient{AllowedHosts: c.hosts}); c.valid != (nil == err) {
+			t.Errorf("case %d: expected valid [%v], got [%v]", i, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// default max size (in MB) of files opened in editors.
+const defaultMaxFileSize = 5
+
+// Settings represents the instance settings editable by administrators at runtime.
+//
+// Settings are saved in {Data}/settings.json and override the same configurations of wide.json (and the environment
+// variables and flags), nil fields are not overridden.
+type Settings struct {
+	MaxFileSize        *int      `json:",omitempty"`
+	WorkspaceQuota     *int      `json:",omitempty"`
+	SignUpDisabled     *bool     `json:",omitempty"`
+	PlaygroundDisabled *bool     `json:",omitempty"`
+	BuildArgs          *[]string `json:",omitempty"`
+}
+
+// settingsMutex serializes saving of settings.
+var settingsMutex sync.Mutex
+
+// GetSettings returns the effective instance settings.
+func GetSettings() *Settings {
+	c := Wide()
+	buildArgs := append([]string{}, c.BuildArgs...)
+
+	return &Settings{MaxFileSize: &c.MaxFileSize, WorkspaceQuota: &c.WorkspaceQuota,
+		SignUpDisabled: &c.SignUpDisabled, PlaygroundDisabled: &c.PlaygroundDisabled, BuildArgs: &buildArgs}
+}
+
+// SaveSettings validates the non-nil fields of the specified settings, saves them into {Data}/settings.json (merged
+// with the saved ones) and then reloads the configurations to apply them.
+func SaveSettings(settings *Settings) error {
+	if nil != settings.MaxFileSize && 0 > *settings.MaxFileSize {
+		return errors.New("MaxFileSize can't be negative")
+	}
+	if nil != settings.WorkspaceQuota && 0 > *settings.WorkspaceQuota {
+		return errors.New("WorkspaceQuota can't be negative")
+	}
+	if nil != settings.BuildArgs {
+		for _, arg := range *settings.BuildArgs {
+			if "" == strings.TrimSpace(arg) {
+				return errors.New("BuildArgs can't contain empty arguments")
+			}
+		}
+	}
+
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	path := settingsPath(Wide().Data)
+	saved, err := loadSettings(path)
+	if nil != err {
+		return err
+	}
+
+	if nil != settings.MaxFileSize {
+		saved.MaxFileSize = settings.MaxFileSize
+	}
+	if nil != settings.WorkspaceQuota {
+		saved.WorkspaceQuota = settings.WorkspaceQuota
+	}
+	if nil != settings.SignUpDisabled {
+		saved.SignUpDisabled = settings.SignUpDisabled
+	}
+	if nil != settings.PlaygroundDisabled {
+		saved.PlaygroundDisabled = settings.PlaygroundDisabled
+	}
+	if nil != settings.BuildArgs {
+		saved.BuildArgs = settings.BuildArgs
+	}
+
+	bytes, err := marshalConf(path, saved)
+	if nil != err {
+		return err
+	}
+
+	if err := writeFileAtomic(path, bytes); nil != err {
+		return err
+	}
+
+	return Reload()
+}
+
+// settingsPath gets the path of the settings file under the specified data directory.
+func settingsPath(data string) string {
+	return filepath.Join(data, "settings.json")
+}
+
+// loadSettings loads the settings from the specified file, returns empty settings if the file does not exist.
+func loadSettings(path string) (*Settings, error) {
+	ret := &Settings{}
+
+	bytes, err := ioutil.ReadFile(path)
+	if nil != err {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+
+		return nil, err
+	}
+
+	if err := unmarshalConf(path, bytes, ret); nil != err {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// applySettings applies the settings saved in the data directory of the specified configurations.
+func applySettings(c *conf) error {
+	settings, err := loadSettings(settingsPath(c.Data))
+	if nil != err {
+		return err
+	}
+
+	if nil != settings.MaxFileSize {
+		c.MaxFileSize = *settings.MaxFileSize
+	}
+	if nil != settings.WorkspaceQuota {
+		c.WorkspaceQuota = *settings.WorkspaceQuota
+	}
+	if nil != settings.SignUpDisabled {
+		c.SignUpDisabled = *settings.SignUpDisabled
+	}
+	if nil != settings.PlaygroundDisabled {
+		c.PlaygroundDisabled = *settings.PlaygroundDisabled
+	}
+	if nil != settings.BuildArgs {
+		c.BuildArgs = *settings.BuildArgs
+	}
+
+	return nil
+}
+
+// MaxFileSizeBytes gets the max size (in byte) of files opened in editors.
+func (c *conf) MaxFileSizeBytes() int64 {
+	if 0 >= c.MaxFileSize {
+		return defaultMaxFileSize << 20
+	}
+
+	return int64(c.MaxFileSize) << 20
+}
+
+// WorkspaceQuotaBytes gets the max size (in byte) of a user's workspace, 0 for unlimited.
+func (c *conf) WorkspaceQuotaBytes() int64 {
+	return int64(c.WorkspaceQuota) << 20
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestApplySettings(t *testing.T) {
+	data := t.TempDir()
+
+	c := &conf{Data: data, MaxFileSize: 5, SignUpDisabled: true, BuildArgs: []string{"-race"}}
+	if err := applySettings(c); nil != err {
+		t.Fatal(err)
+	}
+	if 5 != c.MaxFileSize || !c.SignUpDisabled {
+		t.Errorf("configurations should be kept without settings, got %+v", c)
+	}
+
+	settings := `{"MaxFileSize": 10, "SignUpDisabled": false, "BuildArgs": ["-trimpath"]}`
+	if err := ioutil.WriteFile(settingsPath(data), []byte(settings), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if err := applySettings(c); nil != err {
+		t.Fatal(err)
+	}
+	if 10 != c.MaxFileSize || c.SignUpDisabled || !reflect.DeepEqual([]string{"-trimpath"}, c.BuildArgs) {
+		t.Errorf("settings should be applied, got %+v", c)
+	}
+	if 10<<20 != c.MaxFileSizeBytes() {
+		t.Errorf("expected max file size [%d], got [%d]", 10<<20, c.MaxFileSizeBytes())
+	}
+
+	if err := ioutil.WriteFile(settingsPath(data), []byte(`{"WorkspaceQuota": "1G"}`), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if err := applySettings(c); nil == err {
+		t.Error("invalid settings should fail")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"time"
+)
+
+// Storage represents an S3-compatible object storage (AWS S3, MinIO, etc) users' workspaces persist in, so that
+// stateless instances (such as autoscaled replicas of a Cluster without a shared volume) can serve any user.
+//
+// Workspace paths of users become local caches of the instances: they are synchronized with the storage when users
+// open Wide, right after files changed and every Interval while users are online. Directories "pkg" and "bin" of
+// workspaces are build caches kept locally only.
+type Storage struct {
+	Endpoint  string // such as "https://s3.us-east-1.amazonaws.com"
+	Region    string // such as "us-east-1"
+	Bucket    string
+	Prefix    string // prefix of object keys, such as "workspaces/", objects are keyed {Prefix}{userId}/{i}/{path}
+	AccessKey string
+	SecretKey string
+	Interval  int // interval (in second) of synchronizing workspaces of online users, 0 for the default 60
+}
+
+// default interval of synchronizing workspaces of online users.
+const defaultStorageInterval = time.Minute
+
+// SyncInterval gets the interval of synchronizing workspaces of online users.
+func (s *Storage) SyncInterval() time.Duration {
+	if 1 > s.Interval {
+		return defaultStorageInterval
+	}
+
+	return time.Duration(s.Interval) * time.Second
+}
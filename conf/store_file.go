@@ -0,0 +1,180 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fileStore stores users' configurations in {Data}/users/{userId}.json (or .yaml, .yml and .toml), playground
+// snippets in {Data}/playground and audit records in {Data}/audit.log (a JSON object per line).
+type fileStore struct {
+	mutex sync.Mutex // serializes writes
+}
+
+func (s *fileStore) LoadUsers() ([]*User, error) {
+	dir := filepath.Join(Wide().Data, "users")
+	f, err := os.Open(dir)
+	if nil != err {
+		return nil, err
+	}
+
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if nil != err {
+		return nil, err
+	}
+
+	users := []*User{}
+	for _, name := range names {
+		if strings.HasPrefix(name, ".") { // hiden files that not be created by Wide
+			continue
+		}
+
+		if !confExts[strings.ToLower(filepath.Ext(name))] { // such as backup (*.json~) not be created by Wide
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		user := &User{}
+
+		bytes, _ := ioutil.ReadFile(path)
+		err := unmarshalConf(path, bytes, user)
+		if err != nil {
+			logger.Errorf("Parses [%s] error: %v, skip loading this user", name, err)
+
+			continue
+		}
+
+		if ".json" != filepath.Ext(name) {
+			user.confFile = path
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// SaveUser writes a temporary file and then renames it, so that concurrent saving and crashes never leave a truncated
+// configuration file.
+func (s *fileStore) SaveUser(user *User) error {
+	path := user.confPath()
+	bytes, err := marshalConf(path, user)
+	if nil != err {
+		return err
+	}
+
+	if "" == string(bytes) {
+		return errors.New("truncated user [" + user.Id + "]")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return writeFileAtomic(path, bytes)
+}
+
+func (s *fileStore) SaveSnippet(name string, code []byte) error {
+	return ioutil.WriteFile(snippetPath(name), code, 0644)
+}
+
+func (s *fileStore) GetSnippet(name string) ([]byte, error) {
+	return ioutil.ReadFile(snippetPath(name))
+}
+
+func (s *fileStore) AddAudit(audit *Audit) error {
+	data, err := json.Marshal(audit)
+	if nil != err {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(Wide().Data, "audit.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if nil != err {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); nil == err {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (s *fileStore) GetAudits(limit int) ([]*Audit, error) {
+	f, err := os.Open(filepath.Join(Wide().Data, "audit.log"))
+	if nil != err {
+		if os.IsNotExist(err) {
+			return []*Audit{}, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	audits := []*Audit{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		audit := &Audit{}
+		if err := json.Unmarshal(scanner.Bytes(), audit); nil != err {
+			continue
+		}
+
+		audits = append(audits, audit)
+		if len(audits) > limit {
+			audits = audits[1:]
+		}
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, err
+	}
+
+	ret := make([]*Audit, len(audits))
+	for i, audit := range audits {
+		ret[len(audits)-1-i] = audit
+	}
+
+	return ret, nil
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// snippetPath gets the path of the playground snippet file specified by the given name.
+func snippetPath(name string) string {
+	return filepath.Join(Wide().Data, "playground", filepath.Base(name))
+}
+
+// writeFileAtomic writes the specified data to a temporary file and then renames it to the specified path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); nil != err {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// statements creating tables, valid in both SQLite and PostgreSQL.
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS wide_user (id VARCHAR(255) PRIMARY KEY, data TEXT NOT NULL, updated BIGINT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS wide_snippet (name VARCHAR(255) PRIMARY KEY, code TEXT NOT NULL,
+		created BIGINT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS wide_audit (time BIGINT NOT NULL, user_id VARCHAR(255) NOT NULL,
+		action VARCHAR(255) NOT NULL, detail TEXT NOT NULL)`,
+	`CREATE INDEX IF NOT EXISTS wide_audit_time ON wide_audit (time)`,
+}
+
+// sqlStore stores users' configurations (as JSON), playground snippets and audit records in a SQLite or PostgreSQL
+// database. A user is saved with a single upsert statement, so concurrent saving never corrupts the configurations.
+type sqlStore struct {
+	db *sql.DB
+}
+
+// openSQLStore opens the SQL store with the specified configurations and creates the tables if not exist. Users'
+// configuration files in {Wide.Data}/users are imported if there is no user in the database.
+func openSQLStore(database *Database) (*sqlStore, error) {
+	if "sqlite3" != database.Driver && "postgres" != database.Driver {
+		return nil, errors.New("unsupported driver [" + database.Driver + "]")
+	}
+
+	db, err := sql.Open(database.Driver, database.DSN)
+	if nil != err {
+		return nil, err
+	}
+	if "sqlite3" == database.Driver {
+		db.SetMaxOpenConns(1) // SQLite allows a writer at a time
+	}
+
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); nil != err {
+			db.Close()
+
+			return nil, err
+		}
+	}
+
+	ret := &sqlStore{db: db}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM wide_user`).Scan(&count); nil != err {
+		db.Close()
+
+		return nil, err
+	}
+	if 0 == count {
+		if err := ret.importUsers(); nil != err {
+			db.Close()
+
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// importUsers imports users' configuration files in {Wide.Data}/users, the existing users are overwritten.
+func (s *sqlStore) importUsers() error {
+	users, err := (&fileStore{}).LoadUsers()
+	if nil != err {
+		return err
+	}
+
+	for _, user := range users {
+		if err := s.SaveUser(user); nil != err {
+			return err
+		}
+	}
+	if 0 < len(users) {
+		logger.Infof("Imported %d users into the database", len(users))
+	}
+
+	return nil
+}
+
+func (s *sqlStore) LoadUsers() ([]*User, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM wide_user`)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); nil != err {
+			return nil, err
+		}
+
+		user := &User{}
+		if err := json.Unmarshal([]byte(data), user); nil != err {
+			logger.Errorf("Parses user [%s] error: %v, skip loading this user", id, err)
+
+			continue
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *sqlStore) SaveUser(user *User) error {
+	data, err := json.Marshal(user)
+	if nil != err {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO wide_user (id, data, updated) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data, updated = excluded.updated`,
+		user.Id, string(data), time.Now().UnixNano())
+
+	return err
+}
+
+func (s *sqlStore) SaveSnippet(name string, code []byte) error {
+	_, err := s.db.Exec(`INSERT INTO wide_snippet (name, code, created) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING`, name, string(code), time.Now().UnixNano())
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(snippetPath(name), code, 0644)
+}
+
+// GetSnippet writes the snippet file if it does not exist, such as the snippet is saved by another instance.
+func (s *sqlStore) GetSnippet(name string) ([]byte, error) {
+	var code string
+	err := s.db.QueryRow(`SELECT code FROM wide_snippet WHERE name = $1`, name).Scan(&code)
+	if sql.ErrNoRows == err {
+		return nil, &os.PathError{Op: "get", Path: name, Err: os.ErrNotExist}
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	if path := snippetPath(name); !gulu.File.IsExist(path) {
+		if err := ioutil.WriteFile(path, []byte(code), 0644); nil != err {
+			logger.Warnf("Writes snippet [%s] failed: %s", path, err)
+		}
+	}
+
+	return []byte(code), nil
+}
+
+func (s *sqlStore) AddAudit(audit *Audit) error {
+	_, err := s.db.Exec(`INSERT INTO wide_audit (time, user_id, action, detail) VALUES ($1, $2, $3, $4)`,
+		audit.Time, audit.UserId, audit.Action, audit.Detail)
+
+	return err
+}
+
+func (s *sqlStore) GetAudits(limit int) ([]*Audit, error) {
+	rows, err := s.db.Query(`SELECT time, user_id, action, detail FROM wide_audit ORDER BY time DESC LIMIT $1`, limit)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []*Audit{}
+	for rows.Next() {
+		audit := &Audit{}
+		if err := rows.Scan(&audit.Time, &audit.UserId, &audit.Action, &audit.Detail); nil != err {
+			return nil, err
+		}
+
+		ret = append(ret, audit)
+	}
+
+	return ret, rows.Err()
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
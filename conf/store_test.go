@@ -0,0 +1,101 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	data := t.TempDir()
+	for _, dir := range []string{"users", "playground"} {
+		if err := os.MkdirAll(filepath.Join(data, dir), 0755); nil != err {
+			t.Fatal(err)
+		}
+	}
+	prev := Wide()
+	wideConf.Store(&conf{Data: data})
+	defer func() {
+		if nil != prev {
+			wideConf.Store(prev)
+		}
+	}()
+
+	// a user saved in a file is imported into the database
+	files := &fileStore{}
+	if err := files.SaveUser(&User{Id: "1", Name: "file", Locale: "en_US"}); nil != err {
+		t.Fatal(err)
+	}
+
+	db, err := openSQLStore(&Database{Driver: "sqlite3", DSN: filepath.Join(data, "wide.db")})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, s := range []Store{files, db} {
+		if err := s.SaveUser(&User{Id: "2", Name: "new", Locale: "zh_CN"}); nil != err {
+			t.Fatal(err)
+		}
+		if err := s.SaveUser(&User{Id: "2", Name: "updated", Locale: "zh_CN"}); nil != err {
+			t.Fatal(err)
+		}
+
+		users, err := s.LoadUsers()
+		if nil != err {
+			t.Fatal(err)
+		}
+		names := map[string]string{}
+		for _, user := range users {
+			names[user.Id] = user.Name
+		}
+		if 2 != len(names) || "file" != names["1"] || "updated" != names["2"] {
+			t.Errorf("unexpected users %v of %T", names, s)
+		}
+
+		if _, err := s.GetSnippet("none.go"); !os.IsNotExist(err) {
+			t.Errorf("expected not found snippet of %T, got %v", s, err)
+		}
+		if err := s.SaveSnippet("a.go", []byte("package main")); nil != err {
+			t.Fatal(err)
+		}
+		os.Remove(snippetPath("a.go"))
+		if err := s.SaveSnippet("a.go", []byte("package main")); nil != err {
+			t.Fatal(err)
+		}
+		if code, err := s.GetSnippet("a.go"); nil != err || "package main" != string(code) {
+			t.Errorf("unexpected snippet [%s] of %T: %v", code, s, err)
+		}
+		if code, _ := ioutil.ReadFile(snippetPath("a.go")); "package main" != string(code) {
+			t.Errorf("snippet file of %T should be written, got [%s]", s, code)
+		}
+
+		for i, action := range []string{"a", "b", "c"} {
+			if err := s.AddAudit(&Audit{Time: int64(i), UserId: "1", Action: action}); nil != err {
+				t.Fatal(err)
+			}
+		}
+		audits, err := s.GetAudits(2)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if 2 != len(audits) || "c" != audits[0].Action || "b" != audits[1].Action {
+			t.Errorf("unexpected audits of %T", s)
+		}
+	}
+}
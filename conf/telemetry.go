@@ -0,0 +1,24 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// Telemetry represents the configurations of the opt-in anonymous usage telemetry.
+//
+// Only aggregate counts (builds, tests, users, errors and events), versions and platform are reported, user ids, file
+// paths and code are never reported. Administrators could preview the report via /admin/telemetry.
+type Telemetry struct {
+	Endpoint string // URL the report is posted (as JSON) to
+	Interval int    // interval (in hour) between reports, defaults to 24
+}
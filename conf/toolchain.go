@@ -0,0 +1,105 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Toolchain represents a toolchain building, running and linting files of a non-Go language, such as Python, Node or
+// C. Go files are always handled by the built-in Go toolchain.
+//
+// Commands are argument lists run in the directory of the file, these placeholders in the arguments are replaced:
+//
+//   - {file}: the file
+//   - {dir}: directory of the file
+//   - {name}: name of the file without the extension, such as "main" of "main.c"
+type Toolchain struct {
+	Name       string   // name of the toolchain, such as "python"
+	Extensions []string // extensions of the files, such as [".py"]
+	Build      []string // build command, such as ["gcc", "-o", "{dir}/{name}", "{file}"], empty if no building
+	Run        []string // run command, such as ["python3", "{file}"]
+	Lint       []string // lint command printing "{file}:{line}[:{column}]: {message}" lines, empty if no linting
+	Image      string   // Docker image running programs if Docker is available, such as "python:3"
+}
+
+// Toolchain returns the toolchain of the specified file by its extension, returns nil if the file is a Go file or no
+// toolchain handles it.
+func (c *conf) Toolchain(path string) *Toolchain {
+	ext := strings.ToLower(filepath.Ext(path))
+	if "" == ext || ".go" == ext {
+		return nil
+	}
+
+	for _, t := range c.Toolchains {
+		for _, e := range t.Extensions {
+			if ext == strings.ToLower(e) {
+				return t
+			}
+		}
+	}
+
+	return nil
+}
+
+// Command returns the specified command of the toolchain with the placeholders replaced for the specified file.
+func (t *Toolchain) Command(command []string, file string) []string {
+	dir := filepath.Dir(file)
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	replacer := strings.NewReplacer("{file}", file, "{dir}", dir, "{name}", name)
+
+	ret := make([]string, len(command))
+	for i, arg := range command {
+		ret[i] = replacer.Replace(arg)
+	}
+
+	return ret
+}
+
+// checkToolchains checks the names, extensions and commands of the specified toolchains.
+func checkToolchains(toolchains []*Toolchain) error {
+	names := map[string]bool{}
+	exts := map[string]string{}
+	for _, t := range toolchains {
+		if nil == t || "" == t.Name {
+			return fmt.Errorf("toolchain name is required")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicated toolchain [%s]", t.Name)
+		}
+		names[t.Name] = true
+
+		if 1 > len(t.Run) || "" == t.Run[0] {
+			return fmt.Errorf("run command of toolchain [%s] is required", t.Name)
+		}
+		if 1 > len(t.Extensions) {
+			return fmt.Errorf("extensions of toolchain [%s] are required", t.Name)
+		}
+		for _, ext := range t.Extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") || ".go" == ext {
+				return fmt.Errorf("extension [%s] of toolchain [%s] is invalid", ext, t.Name)
+			}
+			if other, ok := exts[ext]; ok {
+				return fmt.Errorf("extension [%s] belongs to both toolchain [%s] and [%s]", ext, other, t.Name)
+			}
+			exts[ext] = t.Name
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolchain(t *testing.T) {
+	python := &Toolchain{Name: "python", Extensions: []string{".py"}, Run: []string{"python3", "{file}"}}
+	c := &Toolchain{Name: "c", Extensions: []string{".c", ".H"}, Run: []string{"{dir}/{name}"},
+		Build: []string{"cc", "-o", "{dir}/{name}", "{file}"}}
+	wide := &conf{Toolchains: []*Toolchain{python, c}}
+
+	cases := map[string]*Toolchain{
+		"/ws/src/hello/main.py": python,
+		"/ws/src/hello/MAIN.PY": python,
+		"/ws/src/hello/main.c":  c,
+		"/ws/src/hello/main.h":  c,
+		"/ws/src/hello/main.go": nil,
+		"/ws/src/hello/main.rs": nil,
+		"/ws/src/hello/py":      nil,
+	}
+	for path, expected := range cases {
+		if got := wide.Toolchain(path); expected != got {
+			t.Errorf("toolchain of [%s] expected [%v], got [%v]", path, expected, got)
+		}
+	}
+
+	if got := c.Command(c.Build, "main.c"); !reflect.DeepEqual([]string{"cc", "-o", "./main", "main.c"}, got) {
+		t.Errorf("unexpected build command %v", got)
+	}
+	if got := c.Command(c.Run, "/src/main.c"); !reflect.DeepEqual([]string{"/src/main"}, got) {
+		t.Errorf("unexpected run command %v", got)
+	}
+}
+
+func TestCheckToolchains(t *testing.T) {
+	run := []string{"run"}
+	cases := []struct {
+		toolchains []*Toolchain
+		valid      bool
+	}{
+		{nil, true},
+		{[]*Toolchain{{Name: "python", Extensions: []string{".py"}, Run: run}}, true},
+		{[]*Toolchain{{Extensions: []string{".py"}, Run: run}}, false},
+		{[]*Toolchain{{Name: "python", Extensions: []string{".py"}}}, false},
+		{[]*Toolchain{{Name: "python", Run: run}}, false},
+		{[]*Toolchain{{Name: "python", Extensions: []string{"py"}, Run: run}}, false},
+		{[]*Toolchain{{Name: "gox", Extensions: []string{".GO"}, Run: run}}, false},
+		{[]*Toolchain{{Name: "a", Extensions: []string{".x"}, Run: run}, {Name: "b", Extensions: []string{".X"}, Run: run}}, false},
+		{[]*Toolchain{{Name: "a", Extensions: []string{".x"}, Run: run}, {Name: "a", Extensions: []string{".y"}, Run: run}}, false},
+		{[]*Toolchain{nil}, false},
+	}
+	for i, c := range cases {
+		if err := checkToolchains(c.toolchains); c.valid != (nil == err) {
+			t.Errorf("case %d: expected valid [%v], got error [%v]", i, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// Trigger represents an incoming webhook of a project. A push event of GitHub or GitLab posted to it pulls the
+// repository of the project, and then runs the job of the trigger if any.
+type Trigger struct {
+	Id      string // id of the trigger, the endpoint is /hook/{id}
+	Project string // path of the repository relative to {workspace}/src of the user, in slash form
+	Secret  string // secret (encrypted with the vault key) verifying events, see Verify
+	Branch  string // branch whose pushes trigger, "" for any branch
+	Job     string // job run after pulling: "build", "test" or "" for none
+}
+
+// NewTrigger creates a trigger of the specified project for the user, returns the trigger and its secret which should
+// be configured in the webhook of GitHub/GitLab. The user's configurations are not saved.
+func (u *User) NewTrigger(project, branch, job string) (trigger *Trigger, secret string, err error) {
+	if secret, err = randomHex(20); nil != err {
+		return nil, "", err
+	}
+	id, err := randomHex(12)
+	if nil != err {
+		return nil, "", err
+	}
+	sealed, err := Seal([]byte(secret))
+	if nil != err {
+		return nil, "", err
+	}
+
+	trigger = &Trigger{Id: id, Project: project, Secret: sealed, Branch: branch, Job: job}
+	u.Triggers = append(u.Triggers, trigger)
+
+	return trigger, secret, nil
+}
+
+// RemoveTrigger removes the trigger specified by the given id, returns false if not found. The user's configurations
+// are not saved.
+func (u *User) RemoveTrigger(id string) bool {
+	for i, trigger := range u.Triggers {
+		if trigger.Id == id {
+			u.Triggers = append(u.Triggers[:i], u.Triggers[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetTrigger gets the trigger specified by the given id and the user owning it, returns nil, nil if not found.
+func GetTrigger(id string) (*User, *Trigger) {
+	for _, user := range GetUsers() {
+		for _, trigger := range user.Triggers {
+			if trigger.Id == id {
+				return user, trigger
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Verify verifies an event posted to the trigger with the specified signature (header X-Hub-Signature-256 of GitHub,
+// "sha256={HMAC of the body}") or token (header X-Gitlab-Token of GitLab).
+func (t *Trigger) Verify(signature, token string, body []byte) bool {
+	secret, err := Open(t.Secret)
+	if nil != err {
+		logger.Errorf("Opens secret of trigger [%s] failed: %s", t.Id, err)
+
+		return false
+	}
+
+	if "" != signature {
+		expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+		if nil != err {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+
+		return hmac.Equal(expected, mac.Sum(nil))
+	}
+
+	return "" != token && 1 == subtle.ConstantTimeCompare([]byte(token), secret)
+}
+
+// Accepts determines whether a push to the specified ref (such as "refs/heads/main") triggers.
+func (t *Trigger) Accepts(ref string) bool {
+	if !strings.HasPrefix(ref, "refs/heads/") {
+		return false
+	}
+
+	return "" == t.Branch || "refs/heads/"+t.Branch == ref
+}
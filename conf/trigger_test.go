@@ -0,0 +1,74 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTrigger(t *testing.T) {
+	vaultMutex.Lock()
+	prevKey := vaultKey
+	vaultKey = make([]byte, vaultKeySize)
+	vaultMutex.Unlock()
+	defer func() {
+		vaultMutex.Lock()
+		vaultKey = prevKey
+		vaultMutex.Unlock()
+	}()
+
+	user := &User{Id: "alice"}
+	trigger, secret, err := user.NewTrigger("github.com/alice/hello", "main", "test")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if secret == trigger.Secret {
+		t.Errorf("secret is stored in plaintext")
+	}
+
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	cases := []struct {
+		signature, token string
+		body             []byte
+		want             bool
+	}{
+		{signature, "", body, true},
+		{signature, "", []byte(`{"ref":"refs/heads/evil"}`), false},
+		{"sha256=00", "", body, false},
+		{"", secret, body, true},
+		{"", secret + "x", body, false},
+		{"", "", body, false},
+	}
+	for i, c := range cases {
+		if got := trigger.Verify(c.signature, c.token, c.body); c.want != got {
+			t.Errorf("case %d: expected [%v], got [%v]", i, c.want, got)
+		}
+	}
+
+	if !trigger.Accepts("refs/heads/main") || trigger.Accepts("refs/heads/dev") || trigger.Accepts("refs/tags/main") {
+		t.Errorf("accepts wrong refs of branch [main]")
+	}
+
+	if !user.RemoveTrigger(trigger.Id) || 0 != len(user.Triggers) {
+		t.Errorf("removes trigger [%s] failed", trigger.Id)
+	}
+}
@@ -15,13 +15,13 @@
 package conf
 
 import (
-	"encoding/json"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
 )
 
 // Panel represents a UI panel.
@@ -49,13 +49,20 @@ type LatestSessionContent struct {
 type User struct {
 	Id                    string
 	Name                  string
+	Email                 string // used as the author email of git commits
 	Avatar                string
 	Workspace             string // the GOPATH of this user (maybe contain several paths splitted by os.PathListSeparator)
 	Locale                string
+	TimeZone              string // IANA time zone name, such as "Asia/Shanghai", "" for the time zone of the server
 	GoFormat              string
 	GoBuildArgsForLinux   string
 	GoBuildArgsForWindows string
 	GoBuildArgsForDarwin  string
+	GoProxy               string // GOPROXY of the user's go commands, "" for the default https://goproxy.cn
+	GoPrivate             string // GOPRIVATE, such as "git.corp.example.com"
+	GoNoSumDB             string // GONOSUMDB, modules not checked against the checksum database
+	GoFlags               string // GOFLAGS, such as "-mod=vendor"
+	GoRoot                string // GOROOT of an alternate Go toolchain, "" for the one Wide built with
 	FontFamily            string
 	FontSize              string
 	Theme                 string
@@ -64,7 +71,169 @@ type User struct {
 	Updated               int64  // preference update time in unix nano
 	Lived                 int64  // the latest session activity in unix nano
 	Editor                *editor
+	GitCredential         *GitCredential      `json:",omitempty"` // plaintext credential of old versions, migrated to GitVault
+	GitVault              string              // encrypted credential used by git remote operations, "" if not set
+	ForgeVault            string              // encrypted forge (GitHub/GitLab) API tokens keyed by host, "" if not set
+	TrustedGitHooks       []string            // root directories of repositories whose own git hooks are trusted to run
+	Webhooks              []*Webhook          // webhooks notified when the user's build/test jobs finished
+	PushSubscriptions     []*PushSubscription // Web Push subscriptions of the user's browsers
+	Reviewers             []string            // ids of users allowed to review (read and comment) files of the workspace
+	Features              map[string]bool     // feature flag overrides of the user, see Wide.Features
+	APITokens             []*APIToken         // tokens authenticating the user's REST API requests
+	Triggers              []*Trigger          // incoming webhooks pulling (and building) projects on push events
+	DBConnections         []*DBConnection     // saved connections of the database console
 	LatestSessionContent  *LatestSessionContent
+
+	confFile string // path of the configuration file the user loaded from, "" for {Wide.Data}/users/{userId}.json
+}
+
+// PushSubscription represents a Web Push subscription of a browser.
+type PushSubscription struct {
+	Endpoint string // push service URL
+	P256dh   string // public key (base64url) of the browser
+	Auth     string // authentication secret (base64url)
+}
+
+// GitCredential represents the credential of a user's git remote operations.
+type GitCredential struct {
+	Username string // HTTPS username
+	Password string // HTTPS password or personal access token
+	SSHKey   string // SSH private key (PEM)
+}
+
+// TrustsGitHooks determines whether the user trusts the git hooks of the repository containing the specified path.
+func (u *User) TrustsGitHooks(path string) bool {
+	for _, repo := range u.TrustedGitHooks {
+		if path == repo || strings.HasPrefix(path, repo+PathSeparator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsReviewer determines whether the user specified by the given user id is a reviewer of the user's workspace.
+func (u *User) IsReviewer(uid string) bool {
+	for _, reviewer := range u.Reviewers {
+		if reviewer == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetGitCredential gets the user's git credential decrypted from the vault, returns nil if not set or failed.
+func (u *User) GetGitCredential() *GitCredential {
+	if "" == u.GitVault {
+		return nil
+	}
+
+	ret := &GitCredential{}
+	if err := OpenJSON(u.GitVault, ret); nil != err {
+		logger.Errorf("Opens git credential of user [%s] failed: %s", u.Name, err)
+
+		return nil
+	}
+
+	return ret
+}
+
+// SetGitCredential encrypts the specified git credential into the user's vault, a nil credential revokes the stored
+// one. The user's configurations are not saved.
+func (u *User) SetGitCredential(credential *GitCredential) error {
+	u.GitCredential = nil
+	if nil == credential {
+		u.GitVault = ""
+
+		return nil
+	}
+
+	vault, err := SealJSON(credential)
+	if nil != err {
+		return err
+	}
+	u.GitVault = vault
+
+	return nil
+}
+
+// GetForgeToken gets the user's API token of the forge (GitHub, GitLab, etc) of the specified host, returns "" if
+// not set or failed.
+func (u *User) GetForgeToken(host string) string {
+	if "" == u.ForgeVault {
+		return ""
+	}
+
+	tokens := map[string]string{}
+	if err := OpenJSON(u.ForgeVault, &tokens); nil != err {
+		logger.Errorf("Opens forge tokens of user [%s] failed: %s", u.Name, err)
+
+		return ""
+	}
+
+	return tokens[host]
+}
+
+// SetForgeToken encrypts the specified API token of the forge of the specified host into the user's vault, an empty
+// token revokes the stored one. The user's configurations are not saved.
+func (u *User) SetForgeToken(host, token string) error {
+	tokens := map[string]string{}
+	if "" != u.ForgeVault {
+		if err := OpenJSON(u.ForgeVault, &tokens); nil != err {
+			return err
+		}
+	}
+
+	if "" == token {
+		delete(tokens, host)
+	} else {
+		tokens[host] = token
+	}
+
+	if 0 == len(tokens) {
+		u.ForgeVault = ""
+
+		return nil
+	}
+
+	vault, err := SealJSON(tokens)
+	if nil != err {
+		return err
+	}
+	u.ForgeVault = vault
+
+	return nil
+}
+
+// TimeLayout is the layout of timestamps formatted for users.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Location gets the location of the user's time zone, returns the local location of the server if the user has not
+// configured one or it is invalid.
+func (u *User) Location() *time.Location {
+	if "" == u.TimeZone {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(u.TimeZone)
+	if nil != err {
+		logger.Warnf("Loads time zone [%s] of user [%s] failed: %s", u.TimeZone, u.Name, err)
+
+		return time.Local
+	}
+
+	return loc
+}
+
+// FormatTime formats the specified time with TimeLayout in the user's time zone.
+func (u *User) FormatTime(t time.Time) string {
+	return t.In(u.Location()).Format(TimeLayout)
+}
+
+// FormatUnixMilli formats the specified unix milliseconds with TimeLayout in the user's time zone.
+func (u *User) FormatUnixMilli(millis int64) string {
+	return u.FormatTime(time.Unix(0, millis*int64(time.Millisecond)))
 }
 
 // Editor configuration of a user.
@@ -76,29 +245,28 @@ type editor struct {
 	TabSize    string
 }
 
-// Save saves the user's configurations in conf/users/{userId}.json.
+// Save saves the user's configurations in the store, in conf/users/{userId}.json (or the YAML/TOML file the user
+// loaded from) by default.
 func (u *User) Save() bool {
-	bytes, err := json.MarshalIndent(u, "", "    ")
-
-	if nil != err {
-		logger.Error(err)
-
-		return false
-	}
+	apiTokensMutex.RLock()
+	defer apiTokensMutex.RUnlock()
 
-	if "" == string(bytes) {
-		logger.Error("Truncated user [" + u.Id + "]")
+	if err := store.SaveUser(u); nil != err {
+		logger.Errorf("Saves user [%s] failed: %s", u.Id, err)
 
 		return false
 	}
 
-	if err = ioutil.WriteFile(filepath.Join(Wide.Data, "users", u.Id+".json"), bytes, 0644); nil != err {
-		logger.Error(err)
+	return true
+}
 
-		return false
+// confPath returns the path of the user's configuration file.
+func (u *User) confPath() string {
+	if "" != u.confFile {
+		return u.confFile
 	}
 
-	return true
+	return filepath.Join(Wide().Data, "users", u.Id+".json")
 }
 
 // NewUser creates a user with the specified username and workspace.
@@ -106,7 +274,7 @@ func NewUser(id, name, avatar, workspace string) *User {
 	now := time.Now().UnixNano()
 
 	return &User{Id: id, Name: name, Avatar: avatar, Workspace: workspace,
-		Locale: Wide.Locale, GoFormat: "gofmt",
+		Locale: Wide().Locale, GoFormat: "gofmt",
 		GoBuildArgsForLinux: "-i", GoBuildArgsForWindows: "-i", GoBuildArgsForDarwin: "-i",
 		FontFamily: "Helvetica", FontSize: "13px", Theme: "default",
 		Keymap:  "wide",
@@ -128,6 +296,11 @@ func (u *User) WorkspacePath() string {
 	return filepath.FromSlash(w)
 }
 
+// ScratchesPath gets the path of the user's scratches, throwaway notes and snippets kept out of any workspace.
+func (u *User) ScratchesPath() string {
+	return filepath.Join(Wide().Data, "scratches", u.Id)
+}
+
 // BuildArgs get build args with the specified os.
 func (u *User) BuildArgs(os string) []string {
 	var tmp string
@@ -147,12 +320,12 @@ func (u *User) BuildArgs(os string) []string {
 		ret[idx] = strings.Replace(ret[idx], "\"", "", -1)
 	}
 
-	return ret
+	return append(append([]string{}, Wide().BuildArgs...), ret...)
 }
 
 // GetOwner gets the user the specified path belongs to. Returns "" if not found.
 func GetOwner(path string) string {
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		if strings.HasPrefix(path, user.WorkspacePath()) {
 			return user.Id
 		}
@@ -160,3 +333,29 @@ func GetOwner(path string) string {
 
 	return ""
 }
+
+// GitAuthor gets the name and email used as the author of the user's git commits.
+//
+// Falls back to the GitHub noreply email if the user has not configured an email.
+func (u *User) GitAuthor() (name, email string) {
+	email = u.Email
+	if "" == email {
+		email = u.Id + "+" + u.Name + "@users.noreply.github.com"
+	}
+
+	return u.Name, email
+}
+
+// GoExecutable gets the path of the go command of the user's Go toolchain (User.GoRoot), returns "" if not set.
+func (u *User) GoExecutable() string {
+	if "" == u.GoRoot {
+		return ""
+	}
+
+	suffix := ""
+	if gulu.OS.IsWindows() {
+		suffix = ".exe"
+	}
+
+	return filepath.Join(u.GoRoot, "bin", "go"+suffix)
+}
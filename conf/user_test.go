@@ -0,0 +1,97 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	millis := time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	cases := []struct {
+		timeZone string
+		want     string
+	}{
+		{"UTC", "2020-01-01 00:30:00"},
+		{"Asia/Shanghai", "2020-01-01 08:30:00"},
+		{"America/New_York", "2019-12-31 19:30:00"},
+		{"Invalid/Zone", time.Unix(0, millis*int64(time.Millisecond)).Format(TimeLayout)},
+	}
+
+	for _, c := range cases {
+		user := &User{Name: "test", TimeZone: c.timeZone}
+		if got := user.FormatUnixMilli(millis); c.want != got {
+			t.Errorf("formats in [%s]: expected [%s], got [%s]", c.timeZone, c.want, got)
+		}
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	alice := &User{Id: "alice", Name: "alice"}
+	usersMutex.Lock()
+	prev := users
+	users = []*User{alice}
+	usersMutex.Unlock()
+	defer func() {
+		usersMutex.Lock()
+		users = prev
+		usersMutex.Unlock()
+	}()
+
+	token, id, err := alice.NewAPIToken("CI")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if strings.Contains(alice.APITokens[0].Hash, token) || token == alice.APITokens[0].Hash {
+		t.Errorf("token is stored in plaintext")
+	}
+
+	if u := GetUserByAPIToken(token); alice != u {
+		t.Errorf("expected user [alice], got [%v]", u)
+	}
+	if 0 == alice.APITokens[0].Used {
+		t.Errorf("token is not marked used")
+	}
+	if u := GetUserByAPIToken(token + "x"); nil != u {
+		t.Errorf("expected no user of an invalid token, got [%s]", u.Id)
+	}
+
+	// concurrent requests
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			GetUserByAPIToken(token)
+			alice.GetAPITokens()
+		}()
+	}
+	wg.Wait()
+	if tokens := alice.GetAPITokens(); 1 != len(tokens) || 0 == tokens[0].Used {
+		t.Errorf("unexpected tokens %v", tokens)
+	}
+
+	if !alice.RevokeAPIToken(id) || alice.RevokeAPIToken(id) {
+		t.Errorf("revokes token [%s] failed", id)
+	}
+	if u := GetUserByAPIToken(token); nil != u {
+		t.Errorf("expected no user of a revoked token, got [%s]", u.Id)
+	}
+}
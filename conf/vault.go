@@ -0,0 +1,151 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// vaultKeySize is the size of the vault key, AES-256 is used.
+const vaultKeySize = 32
+
+// vault key and its lock.
+var (
+	vaultKey   []byte
+	vaultMutex sync.Mutex
+)
+
+// Seal encrypts the specified plaintext with the vault key (AES-GCM), returns the base64 encoded ciphertext.
+//
+// The vault key is stored in {Wide.Data}/vault.key, generated at the first time it's used.
+func Seal(plaintext []byte) (string, error) {
+	aead, err := vaultAEAD()
+	if nil != err {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); nil != err {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts the specified ciphertext returned by Seal.
+func Open(ciphertext string) ([]byte, error) {
+	aead, err := vaultAEAD()
+	if nil != err {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if nil != err {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("truncated ciphertext")
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+// SealJSON encrypts the JSON encoding of the specified value with Seal.
+func SealJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if nil != err {
+		return "", err
+	}
+
+	return Seal(data)
+}
+
+// OpenJSON decrypts the specified ciphertext returned by SealJSON into the specified value.
+func OpenJSON(ciphertext string, v interface{}) error {
+	data, err := Open(ciphertext)
+	if nil != err {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// vaultAEAD creates the AES-GCM cipher with the vault key.
+func vaultAEAD() (cipher.AEAD, error) {
+	key, err := loadVaultKey()
+	if nil != err {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if nil != err {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// loadVaultKey loads the vault key from {Wide.Data}/vault.key, generates it if not exists.
+func loadVaultKey() ([]byte, error) {
+	vaultMutex.Lock()
+	defer vaultMutex.Unlock()
+
+	if nil != vaultKey {
+		return vaultKey, nil
+	}
+
+	keyPath := filepath.Join(Wide().Data, "vault.key")
+	key, err := ioutil.ReadFile(keyPath)
+	if nil == err {
+		if vaultKeySize != len(key) {
+			return nil, errors.New("invalid vault key [" + keyPath + "]")
+		}
+
+		vaultKey = key
+
+		return vaultKey, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, vaultKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); nil != err {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(keyPath, key, 0600); nil != err {
+		return nil, err
+	}
+	logger.Infof("Generated vault key [%s]", keyPath)
+
+	vaultKey = key
+
+	return vaultKey, nil
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conf
+
+// Webhook represents a webhook notified when a build/test job finished.
+type Webhook struct {
+	Type        string   // slack/dingtalk/generic
+	URL         string   // webhook URL
+	Jobs        []string // jobs to notify ("build", "test"), empty for all
+	OnlyFailure bool     // whether to notify failed jobs only
+}
+
+// Accepts determines whether the webhook should be notified of the specified job.
+func (w *Webhook) Accepts(job string, succ bool) bool {
+	if "" == w.URL || (succ && w.OnlyFailure) {
+		return false
+	}
+
+	if 1 > len(w.Jobs) {
+		return true
+	}
+
+	for _, j := range w.Jobs {
+		if j == job {
+			return true
+		}
+	}
+
+	return false
+}
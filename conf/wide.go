@@ -16,8 +16,10 @@
 package conf
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -25,6 +27,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kwokhunglee/wide/gulu"
@@ -65,16 +69,159 @@ type conf struct {
 	Locale                string        // default locale
 	Autocomplete          bool          // default autocomplete
 	SiteStatCode          template.HTML // site statistic code
+	GitHooksPath          string        // hooks directory of git commands, defaults to {Data}/git-hooks (empty)
+	PreCommitChecks       []string      // checks run before git commits: "gofmt", "vet" or lint commands
+	TLSCert               string        // path of the TLS certificate (PEM), HTTPS is enabled with TLSKey
+	TLSKey                string        // path of the TLS private key (PEM)
+	Autocert              string        // hostname to obtain the TLS certificate from Let's Encrypt, enables HTTPS
+	HTTPSAddr             string        // listen address of HTTPS, defaults to ":443"
+	GRPCAddr              string        // listen address of the gRPC control API (such as ":7071"), "" disables it
+	Context               string        // URL base path if served under a sub path (such as "/wide"), "" for root
+	LogFile               string        // path of the log file (relative to Data if not absolute), "" for stdout only
+	LogMaxSize            int           // max size (in MB) of the log file before rotating, 0 for unlimited
+	LogRotateDaily        bool          // whether to rotate the log file daily
+	LogMaxAge             int           // max days to keep rotated log files, 0 for unlimited
+	LogMaxBackups         int           // max number of rotated log files to keep, 0 for unlimited
+	Admins                []string      // ids of users allowed to access admin endpoints (such as downloading logs)
+	Webhooks              []*Webhook    // webhooks notified when build/test jobs of all users finished
+	WebhookMinDuration    int           // min duration (in second) of succeeded jobs to notify webhooks of
+	WebPushSubject        string        // contact (mailto: or https: URL) of Web Push, defaults to the Wide site
+	Backup                *Backup       // scheduled backups of users' workspaces and configurations, nil disables them
+	MaxFileSize           int           // max size (in MB) of files opened in editors, 0 for the default 5
+	WorkspaceQuota        int           // max size (in MB) of a user's workspace, 0 for unlimited
+	SignUpDisabled        bool          // whether to refuse creating users who sign in for the first time
+	PlaygroundDisabled    bool          // whether to disable the playground
+	BuildArgs             []string      // arguments added to "go build" of all users, such as "-trimpath"
+	Cluster               *Cluster      // multi-instance mode with shared storage, nil for a single instance
+	Database              *Database     // SQL store of users, playground snippets and audit records, nil for files
+	RateLimits            RateLimits    // rate limits of expensive handlers by group
+	CORS                  *CORS         // cross-origin requests to the JSON API, nil disallows them
+	AssetsDir             string        // dir serving static/ and views/ instead of the embedded ones, "." for development
+	Features              Features      // feature flags of experimental subsystems, see FeatureEnabled
+	Telemetry             *Telemetry    // opt-in anonymous usage telemetry, nil disables it
+	MaxSearchResults      int           // max snippets returned per text search request, 0 for the default 100
+	OutputFlushInterval   int           // interval (in ms) of batching build/run output into frames, 0 for 50, -1 disables
+	MaxTreeChildren       int           // max children of a directory loaded at a time in file tree, 0 for the default 1000
+	EagerFileTree         bool          // whether to load whole workspaces in file tree at once instead of on expanding
+	Plugins               []*Plugin     // plugins run as subprocesses (see package plugin), changes require restarting
+	Toolchains            []*Toolchain  // toolchains building and running files of non-Go languages, such as Python
+	Protoc                *Protoc       // protoc generating code of .proto files, nil disables it
+	DBConsole             *DBConsole    // limits of the database console (feature "dbconsole"), nil for the defaults
+	RESTClient            *RESTClient   // guards of the HTTP request tester (feature "restclient"), nil for the defaults
+	Containers            *Containers   // limits of containers of Dockerfiles (feature "docker"), nil for the defaults
+	Kubernetes            *Kubernetes   // cluster users deploy to (feature "deploy"), nil disables deploying
+	Storage               *Storage      // object storage workspaces persist in (cached locally), nil for local disks only
+}
+
+// IsAdmin checks whether the user specified by the given user id is an administrator.
+func (c *conf) IsAdmin(uid string) bool {
+	for _, admin := range c.Admins {
+		if admin == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TLSEnabled checks whether HTTPS is enabled.
+func (c *conf) TLSEnabled() bool {
+	return "" != c.TLSCert || "" != c.Autocert
+}
+
+// default max snippets returned per text search request.
+const defaultMaxSearchResults = 100
+
+// SearchPageSize gets the max snippets returned per text search request, more are returned with the continuation
+// token of the request.
+func (c *conf) SearchPageSize() int {
+	if 0 >= c.MaxSearchResults {
+		return defaultMaxSearchResults
+	}
+
+	return c.MaxSearchResults
+}
+
+// default max children of a directory loaded at a time in file tree.
+const defaultMaxTreeChildren = 1000
+
+// TreeChildrenLimit gets the max children of a directory loaded at a time in file tree, the remaining ones are loaded
+// on demand.
+func (c *conf) TreeChildrenLimit() int {
+	if 0 >= c.MaxTreeChildren {
+		return defaultMaxTreeChildren
+	}
+
+	return c.MaxTreeChildren
+}
+
+// TreeDepth gets the depth of directories of workspaces loaded in file tree at once, 0 for unlimited (see
+// EagerFileTree). Directories are loaded on expanding otherwise, except the ones expanded in the latest session.
+func (c *conf) TreeDepth() int {
+	if c.EagerFileTree {
+		return 0
+	}
+
+	return 1
+}
+
+// default interval of batching build/run output into frames.
+const defaultOutputFlushInterval = 50 * time.Millisecond
+
+// OutputFlushDuration gets the interval of batching build/run output into WebSocket frames, 0 if batching is disabled.
+func (c *conf) OutputFlushDuration() time.Duration {
+	if 0 > c.OutputFlushInterval {
+		return 0
+	}
+	if 0 == c.OutputFlushInterval {
+		return defaultOutputFlushInterval
+	}
+
+	return time.Duration(c.OutputFlushInterval) * time.Millisecond
 }
 
 // Logger.
 var logger = gulu.Log.NewLogger(os.Stdout)
 
-// Wide configurations.
-var Wide *conf
+// Wide configurations (*conf), replaced as a whole on reloading.
+var wideConf atomic.Value
+
+// configurations of users, guarded by usersMutex.
+var users []*User
+
+// users lock.
+var usersMutex sync.RWMutex
+
+// Wide returns the Wide configurations.
+//
+// The returned configurations are never modified since reloading replaces them, so they can be read without locking.
+func Wide() *conf {
+	ret, _ := wideConf.Load().(*conf)
+
+	return ret
+}
+
+// GetUsers returns configurations of all users.
+func GetUsers() []*User {
+	usersMutex.RLock()
+	defer usersMutex.RUnlock()
+
+	return append([]*User{}, users...)
+}
+
+// AddUser adds the specified user.
+func AddUser(user *User) {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+
+	users = append(users, user)
+}
 
-// configurations of users.
-var Users []*User
+// arguments of Load, kept for reloading.
+var loadArgs struct {
+	path, data, server, logLevel string
+	siteStatCode                 template.HTML
+}
 
 // Indicates whether Docker is available.
 var Docker bool
@@ -101,40 +248,33 @@ func Load(confPath, confData, confServer, confLogLevel string, confSiteStatCode
 }
 
 func initUsers() {
-	f, err := os.Open(Wide.Data + PathSeparator + "users")
-	if nil != err {
-		logger.Error(err)
+	initStore()
 
-		os.Exit(-1)
-	}
-
-	names, err := f.Readdirnames(-1)
+	loaded, err := loadUsers()
 	if nil != err {
 		logger.Error(err)
 
 		os.Exit(-1)
 	}
-	f.Close()
 
-	for _, name := range names {
-		if strings.HasPrefix(name, ".") { // hiden files that not be created by Wide
-			continue
-		}
-
-		if ".json" != filepath.Ext(name) { // such as backup (*.json~) not be created by Wide
-			continue
-		}
+	usersMutex.Lock()
+	users = loaded
+	usersMutex.Unlock()
 
-		user := &User{}
-
-		bytes, _ := ioutil.ReadFile(filepath.Join(Wide.Data, "users", name))
-		err := json.Unmarshal(bytes, user)
-		if err != nil {
-			logger.Errorf("Parses [%s] error: %v, skip loading this user", name, err)
+	initWorkspaceDirs()
+	initCustomizedConfs()
+}
 
-			continue
-		}
+// loadUsers loads users' configurations from the store, {Wide.Data}/users/{userId}.json (or .yaml, .yml and .toml) by
+// default.
+func loadUsers() ([]*User, error) {
+	loaded, err := store.LoadUsers()
+	if nil != err {
+		return nil, err
+	}
 
+	users := []*User{}
+	for _, user := range loaded {
 		// Compatibility upgrade (1.3.0): https://github.com/kwokhunglee/wide/issues/83
 		if "" == user.Keymap {
 			user.Keymap = "wide"
@@ -151,37 +291,61 @@ func initUsers() {
 			user.GoBuildArgsForDarwin = "-i"
 		}
 
-		Users = append(Users, user)
+		// Compatibility upgrade (1.6.0): moves plaintext git credential into the vault
+		if nil != user.GitCredential {
+			if err := user.SetGitCredential(user.GitCredential); nil != err {
+				logger.Errorf("Encrypts git credential of user [%s] failed: %s", user.Name, err)
+			} else {
+				user.Save()
+			}
+		}
+
+		users = append(users, user)
 	}
 
-	initWorkspaceDirs()
-	initCustomizedConfs()
+	return users, nil
 }
 
 func initWide(confPath, confData, confServer, confLogLevel string, confSiteStatCode template.HTML) {
-	bytes, err := ioutil.ReadFile(confPath)
+	loadArgs.path, loadArgs.data, loadArgs.server = confPath, confData, confServer
+	loadArgs.logLevel, loadArgs.siteStatCode = confLogLevel, confSiteStatCode
+
+	wide, err := loadWide()
 	if nil != err {
 		logger.Error(err)
 
 		os.Exit(-1)
 	}
 
-	Wide = &conf{}
+	wideConf.Store(wide)
 
-	err = json.Unmarshal(bytes, Wide)
-	if err != nil {
-		logger.Error("Parses [wide.json] error: ", err)
+	initLogFile(wide)
+}
 
-		os.Exit(-1)
+// loadWide loads the Wide configurations from wide.json (or a YAML/TOML file) with the arguments of Load.
+func loadWide() (*conf, error) {
+	bytes, err := ioutil.ReadFile(loadArgs.path)
+	if nil != err {
+		return nil, err
+	}
+
+	ret := &conf{}
+
+	if err := unmarshalConf(loadArgs.path, bytes, ret); nil != err {
+		return nil, err
 	}
 
-	Wide.Autocomplete = true // default to true
+	ret.Autocomplete = true // default to true
+
+	if err := applyOverrides(ret); nil != err {
+		return nil, err
+	}
 
 	// Logging Level
-	gulu.Log.SetLevel(Wide.LogLevel)
-	if "" != confLogLevel {
-		Wide.LogLevel = confLogLevel
-		gulu.Log.SetLevel(confLogLevel)
+	gulu.Log.SetLevel(ret.LogLevel)
+	if "" != loadArgs.logLevel {
+		ret.LogLevel = loadArgs.logLevel
+		gulu.Log.SetLevel(loadArgs.logLevel)
 	}
 
 	logger.Debug("Conf: \n" + string(bytes))
@@ -189,47 +353,105 @@ func initWide(confPath, confData, confServer, confLogLevel string, confSiteStatC
 	// User Home
 	home, err := gulu.OS.Home()
 	if nil != err {
-		logger.Error("Can't get user's home, please report this issue to developer", err)
-
-		os.Exit(-1)
+		return nil, errors.New("Can't get user's home, please report this issue to developer: " + err.Error())
 	}
 	logger.Debugf("${user.home} [%s]", home)
 
 	// Data directory
-	if "" != confData {
-		Wide.Data = confData
+	if "" != loadArgs.data {
+		ret.Data = loadArgs.data
+	}
+	ret.Data = strings.Replace(ret.Data, "${home}", home, -1)
+	ret.Data = filepath.Clean(ret.Data)
+	for _, dir := range []string{"playground", "users", "workspaces"} {
+		if err := os.MkdirAll(filepath.Join(ret.Data, dir), 0755); nil != err {
+			return nil, fmt.Errorf("Create data directory [%s] error", err)
+		}
 	}
-	Wide.Data = strings.Replace(Wide.Data, "${home}", home, -1)
-	Wide.Data = filepath.Clean(Wide.Data)
-	if err := os.MkdirAll(Wide.Data+"/playground/", 0755); nil != err {
-		logger.Errorf("Create data directory [%s] error", err)
 
-		os.Exit(-1)
+	// Settings edited by administrators at runtime
+	if err := applySettings(ret); nil != err {
+		return nil, err
 	}
-	if err := os.MkdirAll(Wide.Data+"/users/", 0755); nil != err {
-		logger.Errorf("Create data directory [%s] error", err)
 
-		os.Exit(-1)
+	// Log file
+	if "" != ret.LogFile {
+		ret.LogFile = strings.Replace(ret.LogFile, "${home}", home, -1)
+		if !filepath.IsAbs(ret.LogFile) {
+			ret.LogFile = filepath.Join(ret.Data, ret.LogFile)
+		}
+		ret.LogFile = filepath.Clean(ret.LogFile)
 	}
-	if err := os.MkdirAll(Wide.Data+"/workspaces/", 0755); nil != err {
-		logger.Errorf("Create data directory [%s] error", err)
 
-		os.Exit(-1)
+	// Git hooks, repository hooks are disabled by pointing git commands at this directory
+	if "" == ret.GitHooksPath {
+		ret.GitHooksPath = filepath.Join(ret.Data, "git-hooks")
+	}
+	ret.GitHooksPath = filepath.Clean(strings.Replace(ret.GitHooksPath, "${home}", home, -1))
+	if err := os.MkdirAll(ret.GitHooksPath, 0755); nil != err {
+		return nil, fmt.Errorf("Create git hooks directory [%s] error", err)
+	}
+
+	// Plugins
+	if err := checkPlugins(ret.Plugins); nil != err {
+		return nil, err
+	}
+
+	// Toolchains
+	if err := checkToolchains(ret.Toolchains); nil != err {
+		return nil, err
+	}
+
+	// HTTP request tester
+	if err := checkRESTClient(ret.RESTClient); nil != err {
+		return nil, err
+	}
+
+	// database console
+	if err := checkDBConsole(ret.DBConsole); nil != err {
+		return nil, err
+	}
+
+	// TLS
+	if ("" == ret.TLSCert) != ("" == ret.TLSKey) {
+		return nil, errors.New("both TLSCert and TLSKey should be specified to enable HTTPS")
+	}
+	if "" != ret.TLSCert && "" != ret.Autocert {
+		return nil, errors.New("TLSCert and Autocert can't be specified at the same time")
+	}
+	// Database
+	if nil != ret.Database {
+		ret.Database.DSN = strings.Replace(ret.Database.DSN, "${home}", home, -1)
+	}
+
+	// TLS
+	ret.TLSCert = strings.Replace(ret.TLSCert, "${home}", home, -1)
+	ret.TLSKey = strings.Replace(ret.TLSKey, "${home}", home, -1)
+	if "" == ret.HTTPSAddr {
+		ret.HTTPSAddr = ":443"
+	}
+
+	// Context, such as "/wide"
+	ret.Context = strings.TrimRight(strings.TrimSpace(ret.Context), "/")
+	if "" != ret.Context && !strings.HasPrefix(ret.Context, "/") {
+		ret.Context = "/" + ret.Context
 	}
 
 	// Server
-	if "" != confServer {
-		Wide.Server = confServer
+	if "" != loadArgs.server {
+		ret.Server = loadArgs.server
 	}
 
 	// SiteStatCode
-	if "" != confSiteStatCode {
-		Wide.SiteStatCode = confSiteStatCode
+	if "" != loadArgs.siteStatCode {
+		ret.SiteStatCode = loadArgs.siteStatCode
 	}
 
 	time := strconv.FormatInt(time.Now().UnixNano(), 10)
 	logger.Debugf("${time} [%s]", time)
-	Wide.StaticResourceVersion = strings.Replace(Wide.StaticResourceVersion, "${time}", time, 1)
+	ret.StaticResourceVersion = strings.Replace(ret.StaticResourceVersion, "${time}", time, 1)
+
+	return ret, nil
 }
 
 // FixedTimeCheckEnv checks Wide runtime enviorment periodically (7 minutes).
@@ -268,7 +490,7 @@ func checkEnv() {
 	cmd = exec.Command(gocode)
 	_, err = cmd.Output()
 	if nil != err {
-		event.EventQueue <- &event.Event{Code: event.EvtCodeGocodeNotFound}
+		event.Publish(&event.Event{Code: event.EvtCodeGocodeNotFound})
 
 		logger.Warnf("Not found gocode [%s], please install it with this command: go get github.com/nsf/gocode", gocode)
 	}
@@ -277,7 +499,7 @@ func checkEnv() {
 	cmd = exec.Command(ideStub, "version")
 	_, err = cmd.Output()
 	if nil != err {
-		event.EventQueue <- &event.Event{Code: event.EvtCodeIDEStubNotFound}
+		event.Publish(&event.Event{Code: event.EvtCodeIDEStubNotFound})
 
 		logger.Warnf("Not found gotools [%s], please install it with this command: go get github.com/visualfc/gotools", ideStub)
 	}
@@ -285,7 +507,7 @@ func checkEnv() {
 
 // GetUserWorkspace gets workspace path with the specified user id, returns "" if not found.
 func GetUserWorkspace(userId string) string {
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		if user.Id == userId {
 			return user.WorkspacePath()
 		}
@@ -296,7 +518,7 @@ func GetUserWorkspace(userId string) string {
 
 // GetGoFmt gets the path of Go format tool, returns "gofmt" if not found "goimports".
 func GetGoFmt(userId string) string {
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		if user.Id == userId {
 			switch user.GoFormat {
 			case "gofmt":
@@ -319,7 +541,7 @@ func GetUser(id string) *User {
 		return NewUser("playground", "playground", "", "")
 	}
 
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		if user.Id == id {
 			return user
 		}
@@ -330,7 +552,7 @@ func GetUser(id string) *User {
 
 // initCustomizedConfs initializes the user customized configurations.
 func initCustomizedConfs() {
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		UpdateCustomizedConf(user.Id)
 	}
 }
@@ -340,7 +562,7 @@ func initCustomizedConfs() {
 //  1. /static/users/{userId}/style.css
 func UpdateCustomizedConf(userId string) {
 	var u *User
-	for _, user := range Users { // maybe it is a beauty of the trade-off of the another world between design and implementation
+	for _, user := range GetUsers() { // maybe it is a beauty of the trade-off of the another world between design and implementation
 		if user.Id == userId {
 			u = user
 		}
@@ -352,14 +574,14 @@ func UpdateCustomizedConf(userId string) {
 
 	model := map[string]interface{}{"user": u}
 
-	t, err := template.ParseFiles("static/user/style.css.tmpl")
+	t, err := template.ParseFS(Assets(), "static/user/style.css.tmpl")
 	if nil != err {
 		logger.Error(err)
 
 		os.Exit(-1)
 	}
 
-	dir := filepath.Clean(Wide.Data + "/static/users/" + userId)
+	dir := filepath.Clean(Wide().Data + "/static/users/" + userId)
 	if err := os.MkdirAll(dir, 0755); nil != err {
 		logger.Error(err)
 
@@ -388,7 +610,7 @@ func UpdateCustomizedConf(userId string) {
 func initWorkspaceDirs() {
 	paths := []string{}
 
-	for _, user := range Users {
+	for _, user := range GetUsers() {
 		paths = append(paths, filepath.SplitList(user.WorkspacePath())...)
 	}
 
@@ -427,11 +649,9 @@ func createDir(path string) {
 func GetEditorThemes() []string {
 	ret := []string{}
 
-	f, _ := os.Open("static/js/overwrite/codemirror" + "/theme")
-	names, _ := f.Readdirnames(-1)
-	f.Close()
-
-	for _, name := range names {
+	entries, _ := fs.ReadDir(Assets(), "static/js/overwrite/codemirror/theme")
+	for _, entry := range entries {
+		name := entry.Name()
 		ret = append(ret, name[:strings.LastIndex(name, ".")])
 	}
 
@@ -444,11 +664,9 @@ func GetEditorThemes() []string {
 func GetThemes() []string {
 	ret := []string{}
 
-	f, _ := os.Open("static/css/themes")
-	names, _ := f.Readdirnames(-1)
-	f.Close()
-
-	for _, name := range names {
+	entries, _ := fs.ReadDir(Assets(), "static/css/themes")
+	for _, entry := range entries {
+		name := entry.Name()
 		ret = append(ret, name[:strings.LastIndex(name, ".")])
 	}
 
@@ -0,0 +1,78 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// corsWrapper wraps the process with CORS (Wide.CORS): responds preflight requests from the allowed origins and adds
+// the allowing headers to the actual requests. Requests from other origins are processed as before, browsers will
+// refuse to expose their responses.
+func corsWrapper(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cors := conf.Wide().CORS
+		origin := r.Header.Get("Origin")
+		if nil == cors || "" == origin {
+			handler(w, r)
+
+			return
+		}
+
+		preflight := http.MethodOptions == r.Method && "" != r.Header.Get("Access-Control-Request-Method")
+		w.Header().Add("Vary", "Origin")
+		if !cors.AllowsOrigin(origin) {
+			if preflight {
+				logger.Debugf("Refused CORS preflight of [%s] from [%s]", r.URL.Path, origin)
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			handler(w, r)
+
+			return
+		}
+
+		// credentials are never allowed for any origin ("*"), otherwise any site could act as the signed in user
+		if cors.AllowCredentials && !gulu.Str.Contains("*", cors.AllowedOrigins) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", util.RequestIdHeader+", Retry-After")
+			handler(w, r)
+
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); "" != headers {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		if 0 < cors.MaxAge {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,510 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dbconsole includes the database console, which queries and browses the schemas of the databases (PostgreSQL,
+// MySQL or SQLite) of apps developed in Wide with connections saved by users (see conf.DBConnection).
+//
+// Queries are limited by Wide.DBConsole: rows responded are truncated at MaxRows, queries are cancelled after Timeout
+// and run in read-only transactions if ReadOnly. SQLite databases should be in the user's workspaces, statements
+// can't attach other databases.
+package dbconsole
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// Drivers supported.
+var drivers = []string{"postgres", "mysql", "sqlite3"}
+
+// sqliteDriver is the name of the SQLite driver registered by init, which refuses statements attaching or detaching
+// databases so that statements can't reach databases other than the checked one (see sqlitePath).
+const sqliteDriver = "sqlite3_dbconsole"
+
+// mysqlNetwork is the network of MySQL connections registered by init, which is dialed with dial.
+const mysqlNetwork = "dbconsole"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		conn.RegisterAuthorizer(authorize)
+
+		return nil
+	}})
+
+	mysql.RegisterDialContext(mysqlNetwork, func(ctx context.Context, addr string) (net.Conn, error) {
+		return dial(ctx, "tcp", addr)
+	})
+}
+
+// authorize is the authorizer of SQLite connections, it denies attaching and detaching databases.
+func authorize(op int, arg1, arg2, arg3 string) int {
+	if sqlite3.SQLITE_ATTACH == op || sqlite3.SQLITE_DETACH == op {
+		return sqlite3.SQLITE_DENY
+	}
+
+	return sqlite3.SQLITE_OK
+}
+
+// errNotAccessible is returned if a SQLite database is not in the user's workspaces.
+var errNotAccessible = errors.New("the SQLite database should be in your workspaces")
+
+// errRefused is returned if a database server is not allowed to connect, see conf.DBConsole.
+var errRefused = errors.New("the database server is not allowed to connect")
+
+// Result represents the result of a query.
+type Result struct {
+	Columns   []string        `json:"columns"`   // column names, empty if the statement returns no rows
+	Rows      [][]interface{} `json:"rows"`      // rows
+	Truncated bool            `json:"truncated"` // whether there are more rows than responded
+	Affected  int64           `json:"affected"`  // number of rows affected if the statement returns no rows
+	Duration  int64           `json:"duration"`  // duration in milliseconds
+}
+
+// Table represents a table or view.
+type Table struct {
+	Schema  string    `json:"schema"`  // schema (PostgreSQL) or database (MySQL), "" for SQLite
+	Name    string    `json:"name"`    // name
+	Type    string    `json:"type"`    // "table" or "view"
+	Columns []*Column `json:"columns"` // columns
+}
+
+// Column represents a column of a table.
+type Column struct {
+	Name     string `json:"name"`     // name
+	Type     string `json:"type"`     // data type, such as "integer"
+	Nullable bool   `json:"nullable"` // whether the column is nullable
+}
+
+// open opens a database with the specified driver and data source name. Paths of SQLite databases are resolved
+// against the specified directory if relative, databases not accessible (see accessible) are refused. Servers of
+// PostgreSQL and MySQL databases are connected with dial, and MySQL can't read files of the server with
+// "LOAD DATA LOCAL INFILE".
+func open(driver, dsn, dir string, accessible func(path string) bool) (*sql.DB, error) {
+	var ret *sql.DB
+	switch driver {
+	case "sqlite3":
+		path, err := sqlitePath(dsn, dir, accessible)
+		if nil != err {
+			return nil, err
+		}
+
+		if ret, err = sql.Open(sqliteDriver, path); nil != err {
+			return nil, err
+		}
+	case "mysql":
+		cfg, err := mysqlConfig(dsn)
+		if nil != err {
+			return nil, err
+		}
+
+		connector, err := mysql.NewConnector(cfg)
+		if nil != err {
+			return nil, err
+		}
+		ret = sql.OpenDB(connector)
+	case "postgres":
+		connector, err := pq.NewConnector(dsn)
+		if nil != err {
+			return nil, err
+		}
+		connector.Dialer(dialer{})
+		ret = sql.OpenDB(connector)
+	default:
+		return nil, errors.New("unsupported driver [" + driver + "]")
+	}
+
+	// a query uses one connection, the database is closed once the query finished
+	ret.SetMaxOpenConns(1)
+
+	return ret, nil
+}
+
+// sqlitePath checks the path of the specified SQLite data source name ("{path}[?{params}]" or
+// "file:{path}[?{params}]"), returns the data source name with the absolute path (resolved against the specified
+// directory if relative).
+func sqlitePath(dsn, dir string, accessible func(path string) bool) (string, error) {
+	path, params := strings.TrimPrefix(dsn, "file:"), ""
+	if i := strings.Index(path, "?"); -1 < i {
+		path, params = path[:i], path[i:]
+	}
+	if "" == path || ":memory:" == path {
+		return dsn, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if !accessible(path) {
+		return "", errNotAccessible
+	}
+	if strings.Contains(params, "mode=memory") || strings.Contains(params, "vfs=") {
+		return "", errNotAccessible
+	}
+
+	return "file:" + path + params, nil
+}
+
+// mysqlConfig parses the specified MySQL data source name, the server is dialed with dial and files of the server
+// can't be read. Servers not connected with TCP (such as Unix sockets) are refused.
+func mysqlConfig(dsn string) (*mysql.Config, error) {
+	ret, err := mysql.ParseDSN(dsn)
+	if nil != err {
+		return nil, err
+	}
+	if "tcp" != ret.Net {
+		return nil, errRefused
+	}
+
+	ret.Net = mysqlNetwork
+	ret.AllowAllFiles = false
+
+	return ret, nil
+}
+
+// dialer dials servers of PostgreSQL databases with dial.
+type dialer struct{}
+
+func (dialer) Dial(network, address string) (net.Conn, error) {
+	return dial(context.Background(), network, address)
+}
+
+func (dialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return dial(ctx, network, address)
+}
+
+func (dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return dial(ctx, network, address)
+}
+
+// dial connects to the specified TCP address of a database server, the address resolved (see resolve) is connected so
+// the host can't be rebound to another address. Other networks (such as Unix sockets) are refused.
+func dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if "tcp" != network && "tcp4" != network && "tcp6" != network {
+		return nil, errRefused
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if nil != err {
+		return nil, err
+	}
+	ip, err := resolve(ctx, conf.Wide().DBConsole, host)
+	if nil != err {
+		return nil, err
+	}
+
+	d := &net.Dialer{Timeout: 10 * time.Second}
+
+	return d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+}
+
+// resolve resolves the specified host of a database server, returns the first address allowed by the specified
+// configurations. All addresses of the host should be public if the host is not allowed, so a host can't flip between
+// addresses.
+func resolve(ctx context.Context, dc *conf.DBConsole, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); nil != ip {
+		if !allowed(dc, host, ip) {
+			return nil, errRefused
+		}
+
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if nil != err {
+		return nil, err
+	}
+	if 1 > len(addrs) {
+		return nil, errors.New("no address of host [" + host + "]")
+	}
+
+	for _, addr := range addrs {
+		if !allowed(dc, host, addr.IP) {
+			return nil, errRefused
+		}
+	}
+
+	return addrs[0].IP, nil
+}
+
+// allowed checks whether the specified IP of the specified database server host is allowed to connect.
+func allowed(dc *conf.DBConsole, host string, ip net.IP) bool {
+	return util.IsPublicIP(ip) || dc.AllowsHost(host) || dc.AllowsIP(ip)
+}
+
+// ping checks the specified database is reachable.
+func ping(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// query runs the specified statement of the specified driver on the specified database, rows more than the specified
+// max rows are not responded. The statement is cancelled once the specified context is done.
+func query(ctx context.Context, driver string, db *sql.DB, statement string, maxRows int, readOnly bool) (*Result,
+	error) {
+	started := time.Now()
+	if readOnly && "sqlite3" == driver { // the SQLite driver ignores read-only transactions
+		if _, err := db.ExecContext(ctx, "PRAGMA query_only = ON"); nil != err {
+			return nil, err
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if nil != err {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ret := &Result{Columns: []string{}, Rows: [][]interface{}{}}
+	if returnsRows(statement) {
+		if err := queryRows(ctx, tx, statement, maxRows, ret); nil != err {
+			return nil, err
+		}
+	} else {
+		result, err := tx.ExecContext(ctx, statement)
+		if nil != err {
+			return nil, err
+		}
+		ret.Affected, _ = result.RowsAffected()
+	}
+
+	if !readOnly {
+		if err := tx.Commit(); nil != err {
+			return nil, err
+		}
+	}
+
+	ret.Duration = time.Since(started).Milliseconds()
+
+	return ret, nil
+}
+
+// queryRows runs the specified statement returning rows in the specified transaction, fills the specified result with
+// at most the specified max rows.
+func queryRows(ctx context.Context, tx *sql.Tx, statement string, maxRows int, result *Result) error {
+	rows, err := tx.QueryContext(ctx, statement)
+	if nil != err {
+		return err
+	}
+	defer rows.Close()
+
+	if result.Columns, err = rows.Columns(); nil != err {
+		return err
+	}
+
+	for rows.Next() {
+		if len(result.Rows) >= maxRows {
+			result.Truncated = true
+
+			break
+		}
+
+		values := make([]interface{}, len(result.Columns))
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); nil != err {
+			return err
+		}
+		for i, v := range values {
+			values[i] = jsonValue(v)
+		}
+
+		result.Rows = append(result.Rows, values)
+	}
+
+	return rows.Err()
+}
+
+// returnsRows determines whether the specified statement returns rows by its first keyword.
+func returnsRows(statement string) bool {
+	statement = strings.TrimSpace(statement)
+	for strings.HasPrefix(statement, "--") || strings.HasPrefix(statement, "/*") { // skips leading comments
+		end := "\n"
+		if strings.HasPrefix(statement, "/*") {
+			end = "*/"
+		}
+		i := strings.Index(statement, end)
+		if -1 == i {
+			return false
+		}
+		statement = strings.TrimSpace(statement[i+len(end):])
+	}
+
+	words := strings.FieldsFunc(statement, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	})
+	if 1 > len(words) {
+		return false
+	}
+
+	switch strings.ToLower(words[0]) {
+	case "select", "with", "show", "explain", "pragma", "describe", "desc", "values", "table":
+		return true
+	}
+
+	return false
+}
+
+// jsonValue converts the specified scanned value to the one encoded as JSON properly.
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []byte:
+		if utf8.Valid(value) {
+			return string(value)
+		}
+
+		return value // base64 encoded
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+
+	return v
+}
+
+// schema gets the tables and views of the specified database of the specified driver.
+func schema(ctx context.Context, driver string, db *sql.DB) ([]*Table, error) {
+	if "sqlite3" == driver {
+		return sqliteSchema(ctx, db)
+	}
+
+	var schemaCond string
+	if "mysql" == driver {
+		schemaCond = "table_schema = DATABASE()"
+	} else {
+		schemaCond = "table_schema NOT IN ('pg_catalog', 'information_schema')"
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT table_schema, table_name, table_type FROM information_schema.tables "+
+		"WHERE "+schemaCond+" ORDER BY table_schema, table_name")
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := []*Table{}
+	tables := map[string]*Table{}
+	for rows.Next() {
+		table := &Table{Columns: []*Column{}}
+		if err := rows.Scan(&table.Schema, &table.Name, &table.Type); nil != err {
+			return nil, err
+		}
+		if strings.Contains(strings.ToUpper(table.Type), "VIEW") {
+			table.Type = "view"
+		} else {
+			table.Type = "table"
+		}
+
+		ret = append(ret, table)
+		tables[table.Schema+"."+table.Name] = table
+	}
+	if err := rows.Err(); nil != err {
+		return nil, err
+	}
+
+	columns, err := db.QueryContext(ctx, "SELECT table_schema, table_name, column_name, data_type, is_nullable "+
+		"FROM information_schema.columns WHERE "+schemaCond+" ORDER BY table_schema, table_name, ordinal_position")
+	if nil != err {
+		return nil, err
+	}
+	defer columns.Close()
+
+	for columns.Next() {
+		var tableSchema, tableName, nullable string
+		column := &Column{}
+		if err := columns.Scan(&tableSchema, &tableName, &column.Name, &column.Type, &nullable); nil != err {
+			return nil, err
+		}
+		column.Nullable = "YES" == nullable
+
+		if table := tables[tableSchema+"."+tableName]; nil != table {
+			table.Columns = append(table.Columns, column)
+		}
+	}
+
+	return ret, columns.Err()
+}
+
+// sqliteSchema gets the tables and views of the specified SQLite database.
+func sqliteSchema(ctx context.Context, db *sql.DB) ([]*Table, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type FROM sqlite_master WHERE type IN ('table', 'view') AND "+
+		"name NOT LIKE 'sqlite_%' ORDER BY name")
+	if nil != err {
+		return nil, err
+	}
+
+	ret := []*Table{}
+	for rows.Next() {
+		table := &Table{Columns: []*Column{}}
+		if err := rows.Scan(&table.Name, &table.Type); nil != err {
+			rows.Close()
+
+			return nil, err
+		}
+		ret = append(ret, table)
+	}
+	rows.Close()
+	if err := rows.Err(); nil != err {
+		return nil, err
+	}
+
+	for _, table := range ret {
+		columns, err := db.QueryContext(ctx, "SELECT name, type, \"notnull\" FROM pragma_table_info(?)", table.Name)
+		if nil != err {
+			return nil, err
+		}
+
+		for columns.Next() {
+			column := &Column{}
+			var notNull bool
+			if err := columns.Scan(&column.Name, &column.Type, &notNull); nil != err {
+				columns.Close()
+
+				return nil, err
+			}
+			column.Nullable = !notNull
+			table.Columns = append(table.Columns, column)
+		}
+		columns.Close()
+		if err := columns.Err(); nil != err {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbconsole
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+func TestReturnsRows(t *testing.T) {
+	cases := []struct {
+		statement string
+		want      bool
+	}{
+		{"SELECT 1", true},
+		{"  with t as (select 1) select * from t", true},
+		{"-- comment\nSELECT 1", true},
+		{"/* comment */ EXPLAIN SELECT 1", true},
+		{"PRAGMA table_info(users)", true},
+		{"INSERT INTO users VALUES (1)", false},
+		{"update users set name = 'a'", false},
+		{"/* unterminated", false},
+		{"123", false},
+		{"", false},
+	}
+	for i, c := range cases {
+		if got := returnsRows(c.statement); c.want != got {
+			t.Errorf("case %d: expected [%v], got [%v]", i, c.want, got)
+		}
+	}
+}
+
+func TestSqlitePath(t *testing.T) {
+	dir := filepath.FromSlash("/data/workspaces/alice/src")
+	accessible := func(path string) bool {
+		return strings.HasPrefix(path, filepath.FromSlash("/data/workspaces/alice"))
+	}
+
+	cases := []struct {
+		dsn, want string
+		ok        bool
+	}{
+		{"app.db", "file:" + filepath.Join(dir, "app.db"), true},
+		{"file:app.db?cache=shared", "file:" + filepath.Join(dir, "app.db") + "?cache=shared", true},
+		{":memory:", ":memory:", true},
+		{"../../bob/src/app.db", "", false},
+		{filepath.FromSlash("/etc/app.db"), "", false},
+		{"app.db?vfs=unix-none", "", false},
+	}
+	for i, c := range cases {
+		got, err := sqlitePath(c.dsn, dir, accessible)
+		if c.ok != (nil == err) || c.want != got {
+			t.Errorf("case %d: expected [%s, %v], got [%s, %v]", i, c.want, c.ok, got, err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	db, err := open("sqlite3", "app.db", dir, func(path string) bool { return strings.HasPrefix(path, dir) })
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := query(ctx, "sqlite3", db, "CREATE TABLE users (id INTEGER NOT NULL, name TEXT)", 10,
+		false); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := query(ctx, "sqlite3", db, "CREATE VIEW names AS SELECT name FROM users", 10, false); nil != err {
+		t.Fatal(err)
+	}
+
+	result, err := query(ctx, "sqlite3", db, "INSERT INTO users VALUES (1, 'alice'), (2, 'bob'), (3, NULL)", 10, false)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 3 != result.Affected {
+		t.Errorf("expected [3] rows affected, got [%d]", result.Affected)
+	}
+
+	result, err = query(ctx, "sqlite3", db, "SELECT id, name FROM users ORDER BY id", 2, false)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"id", "name"}, result.Columns) {
+		t.Errorf("unexpected columns %v", result.Columns)
+	}
+	expected := [][]interface{}{{int64(1), "alice"}, {int64(2), "bob"}}
+	if !reflect.DeepEqual(expected, result.Rows) || !result.Truncated {
+		t.Errorf("expected rows %v truncated, got %v [%v]", expected, result.Rows, result.Truncated)
+	}
+
+	if _, err := query(ctx, "sqlite3", db, "DELETE FROM users", 10, true); nil == err {
+		t.Errorf("deleted in a read-only query")
+	}
+
+	tables, err := schema(ctx, "sqlite3", db)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(tables) || "names" != tables[0].Name || "view" != tables[0].Type || "users" != tables[1].Name ||
+		"table" != tables[1].Type {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	columns := tables[1].Columns
+	if 2 != len(columns) || "id" != columns[0].Name || columns[0].Nullable || !columns[1].Nullable {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+}
+
+func TestQueryAttach(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	db, err := open("sqlite3", "app.db", dir, func(path string) bool { return strings.HasPrefix(path, dir) })
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, readOnly := range []bool{false, true} {
+		for _, statement := range []string{"ATTACH DATABASE '" + filepath.Join(other, "other.db") + "' AS other",
+			"DETACH DATABASE main", "VACUUM INTO '" + filepath.Join(other, "copy.db") + "'"} {
+			if _, err := query(ctx, "sqlite3", db, statement, 10, readOnly); nil == err {
+				t.Errorf("statement [%s] should be denied", statement)
+			}
+		}
+	}
+
+	if entries, _ := os.ReadDir(other); 0 < len(entries) {
+		t.Errorf("files created out of the workspace %v", entries)
+	}
+}
+
+func TestMySQLConfig(t *testing.T) {
+	cfg, err := mysqlConfig("user:pass@tcp(db.example.com:3306)/app?allowAllFiles=true")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if cfg.AllowAllFiles || mysqlNetwork != cfg.Net || "db.example.com:3306" != cfg.Addr {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	if _, err := mysqlConfig("user:pass@unix(/var/run/mysqld/mysqld.sock)/app"); errRefused != err {
+		t.Errorf("expected Unix sockets refused, got [%v]", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dc := &conf.DBConsole{AllowedHosts: []string{"10.1.0.0/16"}}
+	cases := map[string]bool{"8.8.8.8": true, "127.0.0.1": false, "10.1.2.3": true, "10.2.0.1": false,
+		"169.254.169.254": false, "::1": false}
+	for host, expected := range cases {
+		if _, err := resolve(context.Background(), dc, host); expected != (nil == err) {
+			t.Errorf("expected [%v] of [%s], got [%v]", expected, host, err)
+		}
+	}
+
+	if _, err := dial(context.Background(), "unix", "/var/run/postgresql/.s.PGSQL.5432"); errRefused != err {
+		t.Errorf("expected Unix sockets refused, got [%v]", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"expvar"
+	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof/ to http.DefaultServeMux
+	"runtime"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// debugPathPrefix is the path prefix of the profiling (net/http/pprof) and metrics (expvar /debug/vars) endpoints, they
+// are registered to http.DefaultServeMux by the imported packages.
+const debugPathPrefix = "/debug/"
+
+func init() {
+	expvar.Publish("wide", expvar.Func(wideVars))
+}
+
+// wideVars returns the runtime statistics of Wide published via expvar, such as numbers of goroutines, sessions,
+// channels and running processes, helps to find leaks (such as stuck output readers) of a long-running instance.
+func wideVars() interface{} {
+	return map[string]interface{}{
+		"goroutines": runtime.NumGoroutine(),
+		"sessions":   len(session.WideSessions),
+		"channels": map[string]int{
+			session.ChannelSession:      len(session.SessionWS),
+			session.ChannelOutput:       len(session.OutputWS),
+			session.ChannelNotification: len(session.NotificationWS),
+			session.ChannelEditor:       len(session.EditorWS),
+			session.ChannelPlayground:   len(session.PlaygroundWS),
+		},
+		"processes": session.Processes.Count(),
+		"events":    event.Counts(),
+	}
+}
+
+// debugWrapper wraps the specified handler with responding 403 to requests of the debug endpoints (see
+// debugPathPrefix) not sent by administrators.
+func debugWrapper(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, debugPathPrefix) {
+			httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+			if uid, _ := httpSession.Values["uid"].(string); httpSession.IsNew || !conf.Wide().IsAdmin(uid) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
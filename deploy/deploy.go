@@ -0,0 +1,236 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deploy includes deploying to Kubernetes (see conf.Kubernetes), which applies manifests of users' workspaces
+// (or ones generated for built binaries) with server-side apply as kubectl apply --server-side does and streams the
+// events of the applied objects.
+//
+// Objects are applied to the user's namespace and labeled with conf.ContainerLabel, cluster-scoped objects and
+// objects labeled with other users are refused.
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// fieldManager is the field manager of server-side apply.
+const fieldManager = "wide"
+
+// Client represents a client of the cluster.
+type Client struct {
+	dynamic dynamic.Interface
+	mapper  meta.RESTMapper
+}
+
+// NewClient returns a client of the cluster of the specified configurations.
+func NewClient(k *conf.Kubernetes) (*Client, error) {
+	var config *rest.Config
+	var err error
+	if "" == k.Kubeconfig {
+		config, err = rest.InClusterConfig()
+	} else {
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: k.Kubeconfig},
+			&clientcmd.ConfigOverrides{CurrentContext: k.Context}).ClientConfig()
+	}
+	if nil != err {
+		return nil, err
+	}
+	config.UserAgent = "wide"
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if nil != err {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if nil != err {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	return &Client{dynamic: dynamicClient, mapper: mapper}, nil
+}
+
+// Decode decodes the objects of the specified YAML (multiple documents separated by "---") or JSON manifest, "List"
+// objects are expanded.
+func Decode(data []byte) ([]*unstructured.Unstructured, error) {
+	ret := []*unstructured.Unstructured{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); nil != err {
+			if io.EOF != err {
+				return nil, err
+			}
+
+			break
+		}
+		if 1 > len(obj.Object) { // empty document
+			continue
+		}
+
+		if !obj.IsList() {
+			ret = append(ret, obj)
+
+			continue
+		}
+
+		list, err := obj.ToList()
+		if nil != err {
+			return nil, err
+		}
+		for i := range list.Items {
+			ret = append(ret, &list.Items[i])
+		}
+	}
+
+	for _, obj := range ret {
+		if "" == obj.GetKind() || "" == obj.GetAPIVersion() || "" == obj.GetName() {
+			return nil, errors.New("apiVersion, kind and metadata.name of objects are required")
+		}
+	}
+
+	return ret, nil
+}
+
+// Apply applies the specified objects to the specified namespace for the user specified by the given user id, the
+// result of each object is written to the specified function, such as "deployment.apps/hello applied".
+func (c *Client) Apply(ctx context.Context, uid, namespace string, objs []*unstructured.Unstructured,
+	out func(line string)) error {
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if nil != err {
+			return err
+		}
+		if meta.RESTScopeNameNamespace != mapping.Scope.Name() {
+			return fmt.Errorf("cluster-scoped %s [%s] can't be deployed", gvk.Kind, obj.GetName())
+		}
+		if ns := obj.GetNamespace(); "" != ns && namespace != ns {
+			return fmt.Errorf("%s [%s] can't be deployed to namespace [%s]", gvk.Kind, obj.GetName(), ns)
+		}
+
+		obj.SetNamespace(namespace)
+		labels := obj.GetLabels()
+		if nil == labels {
+			labels = map[string]string{}
+		}
+		labels[conf.ContainerLabel] = uid
+		obj.SetLabels(labels)
+
+		resource := c.dynamic.Resource(mapping.Resource).Namespace(namespace)
+		existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if nil == err {
+			if owner := existing.GetLabels()[conf.ContainerLabel]; uid != owner {
+				return fmt.Errorf("%s [%s] belongs to others", gvk.Kind, obj.GetName())
+			}
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if _, err := resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: fieldManager,
+			Force: true}); nil != err {
+			return err
+		}
+
+		out(objectName(gvk.GroupKind(), obj.GetName()) + " applied")
+	}
+
+	return nil
+}
+
+// Events streams the events of the specified objects in the specified namespace to the specified function until the
+// specified context is done. Events of objects named after the objects (such as pods of a deployment) are included.
+func (c *Client) Events(ctx context.Context, namespace string, objs []*unstructured.Unstructured,
+	out func(line string)) error {
+	resource := c.dynamic.Resource(schema.GroupVersionResource{Version: "v1", Resource: "events"}).Namespace(namespace)
+	list, err := resource.List(ctx, metav1.ListOptions{Limit: 1})
+	if nil != err {
+		return err
+	}
+
+	// watches events since now
+	watcher, err := resource.Watch(ctx, metav1.ListOptions{ResourceVersion: list.GetResourceVersion()})
+	if nil != err {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			event, ok := e.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if line := eventLine(event, objs); "" != line {
+				out(line)
+			}
+		}
+	}
+}
+
+// eventLine formats the specified event, such as "Normal Scheduled Pod/hello-5d9c7: Successfully assigned ...",
+// returns "" if the event is not of the specified objects.
+func eventLine(event *unstructured.Unstructured, objs []*unstructured.Unstructured) string {
+	kind, _, _ := unstructured.NestedString(event.Object, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(event.Object, "involvedObject", "name")
+
+	for _, obj := range objs {
+		if name == obj.GetName() || strings.HasPrefix(name, obj.GetName()+"-") {
+			eventType, _, _ := unstructured.NestedString(event.Object, "type")
+			reason, _, _ := unstructured.NestedString(event.Object, "reason")
+			message, _, _ := unstructured.NestedString(event.Object, "message")
+
+			return eventType + " " + reason + " " + kind + "/" + name + ": " + message
+		}
+	}
+
+	return ""
+}
+
+// objectName returns the name of the specified object as kubectl prints, such as "deployment.apps/hello".
+func objectName(groupKind schema.GroupKind, name string) string {
+	kind := strings.ToLower(groupKind.Kind)
+	if "" != groupKind.Group {
+		kind += "." + groupKind.Group
+	}
+
+	return kind + "/" + name
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kwokhunglee/wide/conf"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestDecode(t *testing.T) {
+	objs, err := Decode([]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+# empty document
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: b
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: c
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, obj := range objs {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+	if "ConfigMap/a Service/b Deployment/c" != strings.Join(names, " ") {
+		t.Errorf("unexpected objects %v", names)
+	}
+
+	objs, err = Decode([]byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}}`))
+	if nil != err || 1 != len(objs) {
+		t.Errorf("decodes JSON failed: %v", err)
+	}
+
+	if _, err := Decode([]byte("apiVersion: v1\nkind: ConfigMap\n")); nil == err {
+		t.Error("objects without names should be refused")
+	}
+	if _, err := Decode([]byte("apiVersion: v1\nkind: List\nitems:\n- kind: Service\n")); nil == err {
+		t.Error("items without names should be refused")
+	}
+}
+
+func TestName(t *testing.T) {
+	cases := map[string]string{"Hello_World": "hello-world", "--": "app", "a.b": "a-b",
+		strings.Repeat("a", 62) + "-b": strings.Repeat("a", 62)}
+	for s, expected := range cases {
+		if got := Name(s); expected != got {
+			t.Errorf("expected [%s] of [%s], got [%s]", expected, s, got)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	objs := Generate("hello", "registry.local/hello:1", 8080)
+	if 2 != len(objs) || "Deployment" != objs[0].GetKind() || "Service" != objs[1].GetKind() {
+		t.Fatalf("unexpected objects %v", objs)
+	}
+
+	containers, _, _ := unstructured.NestedSlice(objs[0].Object, "spec", "template", "spec", "containers")
+	if image := containers[0].(map[string]interface{})["image"]; "registry.local/hello:1" != image {
+		t.Errorf("unexpected image [%v]", image)
+	}
+	selector, _, _ := unstructured.NestedStringMap(objs[1].Object, "spec", "selector")
+	if "hello" != selector["app"] {
+		t.Errorf("unexpected selector %v", selector)
+	}
+}
+
+func TestEventLine(t *testing.T) {
+	objs := Generate("hello", "hello:1", 8080)
+	event := &unstructured.Unstructured{Object: map[string]interface{}{
+		"type":           "Normal",
+		"reason":         "Scheduled",
+		"message":        "Successfully assigned",
+		"involvedObject": map[string]interface{}{"kind": "Pod", "name": "hello-5d9c7"},
+	}}
+	if line := eventLine(event, objs); "Normal Scheduled Pod/hello-5d9c7: Successfully assigned" != line {
+		t.Errorf("unexpected line [%s]", line)
+	}
+
+	unstructured.SetNestedField(event.Object, "helloworld", "involvedObject", "name")
+	if line := eventLine(event, objs); "" != line {
+		t.Errorf("events of other objects should be ignored, got [%s]", line)
+	}
+}
+
+func TestApply(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+
+	others := &unstructured.Unstructured{}
+	others.SetAPIVersion("v1")
+	others.SetKind("ConfigMap")
+	others.SetNamespace("wide-1")
+	others.SetName("taken")
+	others.SetLabels(map[string]string{conf.ContainerLabel: "2"})
+	client := &Client{dynamic: fake.NewSimpleDynamicClient(runtime.NewScheme(), others), mapper: mapper}
+
+	apply := func(manifest string) error {
+		objs, err := Decode([]byte(manifest))
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		return client.Apply(context.Background(), "1", "wide-1", objs, func(string) {})
+	}
+
+	if err := apply("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n"); nil == err {
+		t.Error("cluster-scoped objects should be refused")
+	}
+	if err := apply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  namespace: kube-system\n"); nil == err {
+		t.Error("objects of other namespaces should be refused")
+	}
+	if err := apply("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: taken\n"); nil == err ||
+		!strings.Contains(err.Error(), "belongs to others") {
+		t.Errorf("objects of other users should be refused, got [%v]", err)
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	if name := objectName(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "hello"); "deployment.apps/hello" != name {
+		t.Errorf("unexpected name [%s]", name)
+	}
+	if name := objectName(schema.GroupKind{Kind: "Service"}, "hello"); "service/hello" != name {
+		t.Errorf("unexpected name [%s]", name)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// nameRegexp matches characters not allowed in names of objects (DNS labels).
+var nameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Name converts the specified string to a name of objects, such as "hello-world" of "Hello_World".
+func Name(s string) string {
+	ret := strings.Trim(nameRegexp.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if 63 < len(ret) {
+		ret = strings.TrimRight(ret[:63], "-")
+	}
+	if "" == ret {
+		return "app"
+	}
+
+	return ret
+}
+
+// Generate generates the manifest of a binary: a Deployment of one replica running the specified image and a Service
+// exposing the specified port of it, both are named after the specified name.
+func Generate(name, image string, port int) []*unstructured.Unstructured {
+	labels := map[string]interface{}{"app": name}
+
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"selector": map[string]interface{}{"matchLabels": labels},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  name,
+							"image": image,
+							"ports": []interface{}{map[string]interface{}{"containerPort": int64(port)}},
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	service := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"selector": labels,
+			"ports":    []interface{}{map[string]interface{}{"port": int64(port), "targetPort": int64(port)}},
+		},
+	}}
+
+	return []*unstructured.Unstructured{deployment, service}
+}
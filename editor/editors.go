@@ -27,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gorilla/websocket"
 	"github.com/kwokhunglee/wide/conf"
@@ -64,6 +65,12 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 
 	logger.Tracef("Open a new [Editor] with session [%s], %d", sid, len(session.EditorWS))
 
+	stopHeartbeat := editorChan.Heartbeat()
+	defer func() {
+		stopHeartbeat()
+		session.ReleaseChannel(session.ChannelEditor, sid, &editorChan)
+	}()
+
 	args := map[string]interface{}{}
 	for {
 		if err := session.EditorWS[sid].ReadJSON(&args); err != nil {
@@ -342,6 +349,9 @@ func FindDeclarationHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // FindUsagesHandler handles request of finding usages.
+//
+// Usages are found by gotools with type information, falls back to the identifiers with the same name in the symbol
+// indexes (see file.SymbolUsages) if autocomplete (gopls) is disabled or gotools found nothing.
 func FindUsagesHandler(w http.ResponseWriter, r *http.Request) {
 	result := gulu.Ret.NewResult()
 	defer gulu.Ret.RetResult(w, r, result)
@@ -387,9 +397,17 @@ func FindUsagesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	file.UpdateSymbols(filePath)
+
 	line := int(args["cursorLine"].(float64))
 	ch := int(args["cursorCh"].(float64))
 
+	if !conf.Wide().Autocomplete {
+		findUsagesByName(result, uid, code, line, ch)
+
+		return
+	}
+
 	offset := getCursorOffset(code, line, ch)
 	logger.Tracef("offset [%d]", offset)
 
@@ -402,15 +420,15 @@ func FindUsagesHandler(w http.ResponseWriter, r *http.Request) {
 
 	output, err := cmd.CombinedOutput()
 	if nil != err {
-		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.Warnf("Finds usages with gotools failed [%s], falls back to the symbol index", err)
+		findUsagesByName(result, uid, code, line, ch)
 
 		return
 	}
 
 	out := strings.TrimSpace(string(output))
 	if "" == out {
-		result.Code = -1
+		findUsagesByName(result, uid, code, line, ch)
 
 		return
 	}
@@ -433,6 +451,54 @@ func FindUsagesHandler(w http.ResponseWriter, r *http.Request) {
 	result.Data = usages
 }
 
+// findUsagesByName finds usages of the identifier at the specified cursor in the specified code by name from the symbol
+// indexes of the specified user.
+func findUsagesByName(result *gulu.Result, uid, code string, line, ch int) {
+	name := identifierAt(code, line, ch)
+	if "" == name {
+		result.Code = -1
+
+		return
+	}
+
+	result.Data = file.SymbolUsages(uid, name)
+}
+
+// identifierAt returns the identifier at the specified cursor in the specified code, returns "" if there is not.
+//
+// line is the line number, starts with 0 that means the first line
+// ch is the column number, starts with 0 that means the first column
+func identifierAt(code string, line, ch int) string {
+	lines := strings.Split(code, "\n")
+	if 0 > line || len(lines) <= line {
+		return ""
+	}
+
+	runes := []rune(lines[line])
+	if 0 > ch || len(runes) < ch {
+		return ""
+	}
+
+	isIdent := func(r rune) bool {
+		return '_' == r || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	start, end := ch, ch
+	for 0 < start && isIdent(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isIdent(runes[end]) {
+		end++
+	}
+
+	ret := string(runes[start:end])
+	if "" == ret || unicode.IsDigit(runes[start]) {
+		return ""
+	}
+
+	return ret
+}
+
 // getCursorOffset calculates the cursor offset.
 //
 // line is the line number, starts with 0 that means the first line
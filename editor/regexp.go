@@ -0,0 +1,76 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// maxRegexpText is the maximum size (in bytes) of the sample text tested by RegexpHandler.
+const maxRegexpText = 1024 * 1024
+
+// maxRegexpMatches is the maximum number of matches responded by RegexpHandler.
+const maxRegexpMatches = 1000
+
+// RegexpHandler handles request of testing a Go regular expression against a sample text.
+//
+// Arguments: "pattern", optional "flags" (such as "im", see util.RegexpFlags), "text" and "replacement" (the text
+// with all matches replaced is responded if given). Responds the matches with capturing groups and positions (see
+// util.RegexpResult), or the compiling error as the message if the pattern is invalid.
+func RegexpHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args struct {
+		Pattern     string  `json:"pattern"`
+		Flags       string  `json:"flags"`
+		Text        string  `json:"text"`
+		Replacement *string `json:"replacement"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if maxRegexpText < len(args.Text) {
+		result.Code = -1
+
+		return
+	}
+
+	regexpResult, err := util.RegexpTest(args.Pattern, args.Flags, args.Text, args.Replacement, maxRegexpMatches)
+	if nil != err {
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	result.Data = regexpResult
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"sync"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// subscription represents a subscriber and the codes of events it subscribed.
+type subscription struct {
+	handler Handler
+	codes   map[int]bool // nil for all events
+}
+
+// subscriptions of the event bus.
+var subscriptions []*subscription
+
+// subscriptions lock.
+var subscriptionsMutex sync.RWMutex
+
+// Subscribe subscribes the specified handler to events of the specified codes, to all events if no code specified.
+//
+// Subscribers process events published to the bus whichever sessions they are related to, such as webhooks and audit
+// log. Events are pushed to browsers by handlers of user event queues.
+func Subscribe(handler Handler, codes ...int) {
+	s := &subscription{handler: handler}
+	if 0 < len(codes) {
+		s.codes = map[int]bool{}
+		for _, code := range codes {
+			s.codes[code] = true
+		}
+	}
+
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+
+	subscriptions = append(subscriptions, s)
+}
+
+// Publish publishes the specified event to the bus.
+//
+// The event is processed by the subscribers, then dispatched to the user event queue of its wide session if the
+// session id is specified, to each user event queue otherwise.
+func Publish(event *Event) {
+	EventQueue <- event
+}
+
+// dispatch dispatches the specified event to the subscribers and user event queues.
+func dispatch(event *Event) {
+	subscriptionsMutex.RLock()
+	subscribers := []Handler{}
+	for _, s := range subscriptions {
+		if nil == s.codes || s.codes[event.Code] {
+			subscribers = append(subscribers, s.handler)
+		}
+	}
+	subscriptionsMutex.RUnlock()
+
+	for _, handler := range subscribers {
+		func() {
+			defer gulu.Panic.Recover(nil) // a broken subscriber shouldn't stop the bus
+
+			handler.Handle(event)
+		}()
+	}
+
+	if "" != event.Sid {
+		if userQueue, ok := UserEventQueues[event.Sid]; ok {
+			send(userQueue, event)
+		}
+
+		return
+	}
+
+	for _, userQueue := range UserEventQueues {
+		e := *event
+		e.Sid = userQueue.Sid
+
+		send(userQueue, &e)
+	}
+}
+
+// send sends the specified event to the specified user event queue, which may be closed with its session meanwhile.
+func send(userQueue *UserEventQueue, event *Event) {
+	defer func() {
+		if r := recover(); nil != r {
+			logger.Debugf("Sends event [%s] to closed session [%s]", event.Name(), userQueue.Sid)
+		}
+	}()
+
+	userQueue.Queue <- event
+}
@@ -16,7 +16,10 @@
 package event
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/kwokhunglee/wide/gulu"
 )
@@ -32,8 +35,37 @@ const (
 	EvtCodeIDEStubNotFound
 	// EvtCodeServerInternalError indicates an event: server internal error
 	EvtCodeServerInternalError
+	// EvtCodeConfReloaded indicates an event: configurations reloaded
+	EvtCodeConfReloaded
+	// EvtCodeJobDone indicates an event: a build/test job finished, data is a *Job
+	EvtCodeJobDone
+	// EvtCodeBroadcast indicates an event: an administrator broadcast a message, data is a *Broadcast
+	EvtCodeBroadcast
+	// EvtCodeSaveConflict indicates an event: saving a file conflicted with changes saved by others, data is a
+	// *SaveConflict
+	EvtCodeSaveConflict
+	// EvtCodeFileChanged indicates an event: a file or directory was created, saved, removed or renamed, data is a
+	// *FileChange
+	EvtCodeFileChanged
+	// EvtCodeJobStarted indicates an event: a build/test job started, data is a *Job without the result
+	EvtCodeJobStarted
 )
 
+// names of events, used in logs and metrics.
+var names = map[int]string{
+	EvtCodeGOPATHNotFound:      "gopath-not-found",
+	EvtCodeGOROOTNotFound:      "goroot-not-found",
+	EvtCodeGocodeNotFound:      "gocode-not-found",
+	EvtCodeIDEStubNotFound:     "gotools-not-found",
+	EvtCodeServerInternalError: "server-internal-error",
+	EvtCodeConfReloaded:        "conf-reloaded",
+	EvtCodeJobDone:             "job-done",
+	EvtCodeBroadcast:           "broadcast",
+	EvtCodeSaveConflict:        "save-conflict",
+	EvtCodeFileChanged:         "file-changed",
+	EvtCodeJobStarted:          "job-started",
+}
+
 // Max length of queue.
 const maxQueueLength = 10
 
@@ -47,9 +79,59 @@ type Event struct {
 	Data interface{} `json:"data"` // event data
 }
 
-// Global event queue.
-//
-// Every event in this queue will be dispatched to each user event queue.
+// Name returns the name of the event, such as "job-done".
+func (e *Event) Name() string {
+	if name, ok := names[e.Code]; ok {
+		return name
+	}
+
+	return strconv.Itoa(e.Code)
+}
+
+// Job represents the summary of a finished build/test job.
+type Job struct {
+	Name      string        `json:"name"`      // job name, "build"/"test"
+	UserId    string        `json:"userId"`    // id of the user ran the job
+	Path      string        `json:"path"`      // directory the job ran in
+	RequestId string        `json:"requestId"` // correlation id of the request started the job
+	Succ      bool          `json:"succ"`      // whether the job succeeded
+	Duration  time.Duration `json:"duration"`  // duration of the job
+	Output    string        `json:"output"`    // output of the job
+}
+
+// String returns the summary of the job without the output.
+func (j *Job) String() string {
+	return fmt.Sprintf("%s of [%s] by [%s], succ [%v] in [%s] [requestId=%s]", j.Name, j.Path, j.UserId, j.Succ,
+		j.Duration, j.RequestId)
+}
+
+// Broadcast represents a message broadcast by an administrator to all sessions.
+type Broadcast struct {
+	UserId   string `json:"userId"`   // id of the administrator
+	Severity string `json:"severity"` // "ERROR", "WARN" or "INFO"
+	Message  string `json:"message"`  // message content
+}
+
+// String returns the summary of the broadcast.
+func (b *Broadcast) String() string {
+	return fmt.Sprintf("[%s] %s by [%s]", b.Severity, b.Message, b.UserId)
+}
+
+// SaveConflict represents a file save conflicted with changes saved by other sessions.
+type SaveConflict struct {
+	UserId string `json:"userId"` // id of the user saving the file
+	Path   string `json:"path"`   // path of the file
+}
+
+// FileChange represents a file or directory created, saved, removed or renamed by a user.
+type FileChange struct {
+	UserId  string `json:"userId"`            // id of the user
+	Op      string `json:"op"`                // "create", "save", "remove" or "rename"
+	Path    string `json:"path"`              // path of the file or directory
+	NewPath string `json:"newPath,omitempty"` // new path if renamed
+}
+
+// Global event queue, events should be published with Publish.
 var EventQueue = make(chan *Event, maxQueueLength)
 
 // UserEventQueue represents a user event queue.
@@ -66,20 +148,18 @@ type queues map[string]*UserEventQueue
 // <sid, *UserEventQueue>
 var UserEventQueues = queues{}
 
-// Load initializes the event handling.
+// Load initializes the event handling, the built-in subscribers (audit log and metrics) are subscribed.
 func Load() {
+	Subscribe(HandleFunc(audit))
+	Subscribe(HandleFunc(count))
+
 	go func() {
 		defer gulu.Panic.Recover(nil)
 
 		for event := range EventQueue {
 			logger.Debugf("Received a global event [code=%d]", event.Code)
 
-			// dispatch the event to each user event queue
-			for _, userQueue := range UserEventQueues {
-				event.Sid = userQueue.Sid
-
-				userQueue.Queue <- event
-			}
+			dispatch(event)
 		}
 	}()
 }
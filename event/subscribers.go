@@ -0,0 +1,57 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package event
+
+import (
+	"sync"
+)
+
+// numbers of published events, <event name, count>.
+var counts = map[string]int64{}
+
+// counts lock.
+var countsMutex sync.Mutex
+
+// Counts returns numbers of published events by names.
+func Counts() map[string]int64 {
+	countsMutex.Lock()
+	defer countsMutex.Unlock()
+
+	ret := map[string]int64{}
+	for name, count := range counts {
+		ret[name] = count
+	}
+
+	return ret
+}
+
+// audit records the specified event into the log.
+func audit(e *Event) {
+	if nil == e.Data {
+		logger.Infof("Event [%s], session [%s]", e.Name(), e.Sid)
+
+		return
+	}
+
+	logger.Infof("Event [%s], session [%s]: %v", e.Name(), e.Sid, e.Data)
+}
+
+// count counts the specified event.
+func count(e *Event) {
+	countsMutex.Lock()
+	defer countsMutex.Unlock()
+
+	counts[e.Name()]++
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binarySniffSize is the size of the first block of a file sampled to determine whether the file is binary.
+const binarySniffSize = 8 << 10
+
+// binaryExtensions are the (lower case) extensions of files treated as binaries without reading them. Images are
+// included, they are opened in browser tabs instead of editors (see GetFileHandler).
+var binaryExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".obj": true, ".lib": true,
+	".class": true, ".jar": true, ".war": true, ".pyc": true, ".wasm": true, ".bin": true,
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".7z": true, ".rar": true, ".tar": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true,
+	".mp3": true, ".mp4": true, ".avi": true, ".mov": true, ".wav": true, ".flac": true, ".ogg": true, ".webm": true,
+	".ttf": true, ".otf": true, ".woff": true, ".woff2": true, ".eot": true,
+	".pdf": true, ".psd": true, ".iso": true, ".dmg": true, ".db": true, ".sqlite": true,
+}
+
+// isBinaryFile determines whether the file with the specified path is a binary by its extension (see
+// binaryExtensions) or by sampling its first block (see isBinaryHead), the whole file is never read.
+func isBinaryFile(path string) (bool, error) {
+	if binaryExtensions[strings.ToLower(filepath.Ext(path))] {
+		return true, nil
+	}
+
+	f, err := os.Open(path)
+	if nil != err {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(f, head)
+	if nil != err && io.EOF != err && io.ErrUnexpectedEOF != err {
+		return false, err
+	}
+
+	return isBinaryHead(head[:n]), nil
+}
+
+// isBinaryHead determines whether the specified first block of a file is of a binary, it's a binary if there is a NUL
+// byte the same as git determines.
+func isBinaryHead(head []byte) bool {
+	return -1 != bytes.IndexByte(head, 0)
+}
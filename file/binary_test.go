@@ -0,0 +1,55 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBinaryFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content string
+		binary  bool
+	}{
+		"main.go":   {"package main\n", false},
+		"empty.txt": {"", false},
+		"blob.dat":  {"ab\x00cd", true},
+		"logo.PNG":  {"not sniffed", true},
+		"late.txt":  {strings.Repeat("a", binarySniffSize) + "\x00", false},
+	}
+
+	for name, file := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(file.content), 0644); nil != err {
+			t.Fatal(err)
+		}
+
+		binary, err := isBinaryFile(path)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if file.binary != binary {
+			t.Errorf("%s: expected binary %v, actual %v", name, file.binary, binary)
+		}
+	}
+
+	if _, err := isBinaryFile(filepath.Join(dir, "missing.go")); nil == err {
+		t.Error("expected an error of a missing file")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitUncommitted is the commit id git blame reports for lines not committed yet.
+const gitUncommitted = "0000000000000000000000000000000000000000"
+
+// gitBlameCommit represents a commit referenced by blame lines.
+type gitBlameCommit struct {
+	Hash        string `json:"hash"`
+	Author      string `json:"author"`
+	AuthorEmail string `json:"authorEmail"`
+	Time        int64  `json:"time"` // author time in unix milliseconds
+	Date        string `json:"date"` // author time formatted in the user's time zone
+	Summary     string `json:"summary"`
+	Uncommitted bool   `json:"uncommitted"` // whether the lines are not committed yet
+}
+
+// gitBlameLine represents the blame of a line.
+type gitBlameLine struct {
+	Line     int    `json:"line"`     // line number (1-based) in the blamed content
+	OrigLine int    `json:"origLine"` // line number in the commit
+	Commit   string `json:"commit"`   // commit id, key of the commits map
+}
+
+// GitBlameHandler handles request of getting the per-line blame of a file.
+//
+// Optional arguments: "revision" (blames the file at the revision) and "content" (blames the specified content, the
+// unsaved editor buffer for example, instead of the file in worktree).
+func GitBlameHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	gitArgs := []string{"blame", "--porcelain"}
+	content, hasContent := req.args["content"].(string)
+	if hasContent {
+		gitArgs = append(gitArgs, "--contents", "-")
+	}
+	if revision := req.arg("revision"); "" != revision {
+		if strings.HasPrefix(revision, "-") {
+			result.Code = -1
+
+			return
+		}
+
+		gitArgs = append(gitArgs, revision)
+	}
+	gitArgs = append(gitArgs, "--", req.path)
+
+	cmd := gitCommand(req.repo, gitArgs...)
+	if hasContent {
+		cmd.Stdin = strings.NewReader(content)
+	}
+
+	out, err := cmd.Output()
+	if nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]", strings.Join(gitArgs, " "), req.repo, err)
+		result.Code = -1
+
+		return
+	}
+
+	commits, lines := parseGitBlame(string(out))
+	for _, commit := range commits {
+		commit.Date = req.user.FormatUnixMilli(commit.Time)
+	}
+	result.Data = map[string]interface{}{"commits": commits, "lines": lines}
+}
+
+// parseGitBlame parses the specified output of "git blame --porcelain".
+func parseGitBlame(out string) (commits map[string]*gitBlameCommit, lines []*gitBlameLine) {
+	commits = map[string]*gitBlameCommit{}
+	lines = []*gitBlameLine{}
+
+	var commit *gitBlameCommit
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\t") { // content line, ends an entry
+			commit = nil
+
+			continue
+		}
+
+		if nil == commit { // header line: <commit> <orig line> <final line> [<lines in group>]
+			fields := strings.Fields(line)
+			if 3 > len(fields) {
+				continue
+			}
+
+			hash := fields[0]
+			commit = commits[hash]
+			if nil == commit {
+				commit = &gitBlameCommit{Hash: hash, Uncommitted: gitUncommitted == hash}
+				commits[hash] = commit
+			}
+
+			lines = append(lines, &gitBlameLine{Line: atoi(fields[2], 0), OrigLine: atoi(fields[1], 0), Commit: hash})
+
+			continue
+		}
+
+		key, value := line, ""
+		if i := strings.Index(line, " "); 0 < i {
+			key, value = line[:i], line[i+1:]
+		}
+
+		switch key {
+		case "author":
+			commit.Author = value
+		case "author-mail":
+			commit.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			seconds, _ := strconv.ParseInt(value, 10, 64)
+			commit.Time = seconds * 1000
+		case "summary":
+			commit.Summary = value
+		}
+	}
+
+	return
+}
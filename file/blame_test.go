@@ -0,0 +1,71 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"testing"
+)
+
+func TestParseGitBlame(t *testing.T) {
+	out := "1111111111111111111111111111111111111111 1 1 2\n" +
+		"author Alice\n" +
+		"author-mail <alice@example.com>\n" +
+		"author-time 1500000000\n" +
+		"author-tz +0800\n" +
+		"summary Initial commit\n" +
+		"filename main.go\n" +
+		"\tpackage main\n" +
+		"1111111111111111111111111111111111111111 2 2\n" +
+		"\t\n" +
+		"0000000000000000000000000000000000000000 3 3 1\n" +
+		"author Not Committed Yet\n" +
+		"author-mail <not.committed.yet>\n" +
+		"author-time 1600000000\n" +
+		"summary Version of main.go from main.go\n" +
+		"previous 1111111111111111111111111111111111111111 main.go\n" +
+		"filename main.go\n" +
+		"\tfunc main() {}\n" +
+		"1111111111111111111111111111111111111111 3 4 1\n" +
+		"\t// author fake header in content\n"
+
+	commits, lines := parseGitBlame(out)
+	if 2 != len(commits) {
+		t.Fatalf("expected [2] commits, got [%d]", len(commits))
+	}
+
+	committed := commits["1111111111111111111111111111111111111111"]
+	if "Alice" != committed.Author || "alice@example.com" != committed.AuthorEmail || 1500000000000 != committed.Time ||
+		"Initial commit" != committed.Summary || committed.Uncommitted {
+		t.Errorf("unexpected commit %+v", *committed)
+	}
+	if !commits[gitUncommitted].Uncommitted {
+		t.Error("the zero commit should be uncommitted")
+	}
+
+	expected := []gitBlameLine{
+		{Line: 1, OrigLine: 1, Commit: "1111111111111111111111111111111111111111"},
+		{Line: 2, OrigLine: 2, Commit: "1111111111111111111111111111111111111111"},
+		{Line: 3, OrigLine: 3, Commit: gitUncommitted},
+		{Line: 4, OrigLine: 3, Commit: "1111111111111111111111111111111111111111"},
+	}
+	if len(expected) != len(lines) {
+		t.Fatalf("expected [%d] lines, got [%d]", len(expected), len(lines))
+	}
+	for i, line := range expected {
+		if line != *lines[i] {
+			t.Errorf("expected line %+v, got %+v", line, *lines[i])
+		}
+	}
+}
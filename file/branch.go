@@ -0,0 +1,322 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// gitBranch represents a local or remote-tracking branch.
+type gitBranch struct {
+	Name     string `json:"name"`     // short name, for example "master" or "origin/master"
+	Commit   string `json:"commit"`   // short commit id the branch points to
+	Upstream string `json:"upstream"` // upstream branch of a local branch, "" if not set
+	Track    string `json:"track"`    // tracking state, for example "[ahead 1, behind 2]", "" if up to date
+	Current  bool   `json:"current"`  // whether is the checked out branch
+}
+
+// GitBranchesHandler handles request of listing local and remote-tracking branches of a repository.
+func GitBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	out, err := gitCommand(req.repo, "for-each-ref",
+		"--format=%(refname)%00%(refname:short)%00%(objectname:short)%00%(upstream:short)%00%(upstream:track)%00%(HEAD)",
+		"refs/heads", "refs/remotes").Output()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	locals := []*gitBranch{}
+	remotes := []*gitBranch{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if 6 != len(fields) || strings.HasSuffix(fields[0], "/HEAD") {
+			continue
+		}
+
+		branch := &gitBranch{Name: fields[1], Commit: fields[2], Upstream: fields[3], Track: fields[4],
+			Current: "*" == fields[5]}
+		if strings.HasPrefix(fields[0], "refs/heads/") {
+			locals = append(locals, branch)
+		} else {
+			remotes = append(remotes, branch)
+		}
+	}
+
+	result.Data = map[string]interface{}{"current": gitHeadBranch(req.repo), "local": locals, "remote": remotes}
+}
+
+// GitCreateBranchHandler handles request of creating a branch.
+//
+// Optional arguments: "startPoint" (defaults to HEAD) and "checkout" (switches to the new branch after created).
+func GitCreateBranchHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	name := req.arg("name")
+	if !gitValidBranchName(req.repo, name) {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	startPoint := req.arg("startPoint")
+	if strings.HasPrefix(startPoint, "-") {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	gitArgs := []string{"branch", name}
+	if req.flag("checkout") {
+		gitArgs = []string{"checkout", "-b", name}
+	}
+	if "" != startPoint {
+		gitArgs = append(gitArgs, startPoint)
+	}
+	if req.flag("checkout") {
+		gitArgs = append(gitArgs, "--") // no path after the start point, it's never taken as a file to restore
+	}
+
+	if !runGit(req, result, gitArgs...) {
+		return
+	}
+
+	if req.flag("checkout") {
+		refreshRepo(req)
+	}
+}
+
+// GitSwitchBranchHandler handles request of switching to a branch.
+//
+// Switching is refused if the worktree has uncommitted changes unless "force" is specified, in which case the changes
+// are carried to the target branch (git still refuses if they conflict). Switching to a remote-tracking branch such as
+// "origin/dev" creates a local branch "dev" tracking it.
+func GitSwitchBranchHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	name := req.arg("name")
+	if "" == name || strings.HasPrefix(name, "-") {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	if !req.flag("force") && gitDirty(req.repo) {
+		result.Code = -1
+		result.Msg = req.msg("git-dirty-worktree")
+
+		return
+	}
+
+	// the trailing "--" makes git take the name as a branch only, never as a file to restore
+	gitArgs := []string{"checkout", name, "--"}
+	if nil != gitCommand(req.repo, "show-ref", "--verify", "-q", "refs/heads/"+name).Run() &&
+		nil == gitCommand(req.repo, "show-ref", "--verify", "-q", "refs/remotes/"+name).Run() {
+		gitArgs = []string{"checkout", "--track", name, "--"}
+	}
+
+	if !runGit(req, result, gitArgs...) {
+		return
+	}
+
+	refreshRepo(req)
+	result.Data = gitHeadBranch(req.repo)
+}
+
+// GitDeleteBranchHandler handles request of deleting a local branch.
+//
+// An unmerged branch is deleted only if "force" is specified.
+func GitDeleteBranchHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	name := req.arg("name")
+	if "" == name {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	flag := "-d"
+	if req.flag("force") {
+		flag = "-D"
+	}
+
+	runGit(req, result, "branch", flag, "--", name)
+}
+
+// GitSetUpstreamHandler handles request of setting the upstream of a local branch.
+//
+// Arguments: "upstream" (for example "origin/master"), optional "name" (defaults to the current branch). An empty
+// upstream unsets the upstream.
+func GitSetUpstreamHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	upstream := req.arg("upstream")
+	name := req.arg("name")
+	if strings.HasPrefix(upstream, "-") || strings.HasPrefix(name, "-") {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	gitArgs := []string{"branch", "--unset-upstream"}
+	if "" != upstream {
+		gitArgs = []string{"branch", "--set-upstream-to=" + upstream}
+	}
+	if "" != name {
+		gitArgs = append(gitArgs, name)
+	}
+
+	runGit(req, result, gitArgs...)
+}
+
+// runGit runs a git command with the specified arguments in the repository of the specified request.
+//
+// If the command failed, the result is set to failure with git's output as the message. Returns whether succeeded.
+func runGit(req *gitRequest, result *gulu.Result, args ...string) bool {
+	out, err := gitCommand(req.repo, args...).CombinedOutput()
+	if nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(args, " "), req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return false
+	}
+
+	return true
+}
+
+// refreshRepo notifies the session of the specified request to refresh the repository directory in the file tree.
+func refreshRepo(req *gitRequest) {
+	wsChannel := session.SessionWS[req.sid]
+	if nil == wsChannel {
+		return
+	}
+
+	cmd := map[string]interface{}{"path": filepath.ToSlash(req.repo), "dir": filepath.ToSlash(filepath.Dir(req.repo)),
+		"cmd": "refresh-dir", "type": "d"}
+	wsChannel.WriteJSON(&cmd)
+}
+
+// gitValidBranchName determines whether the specified name is a valid branch name.
+func gitValidBranchName(repo, name string) bool {
+	if "" == name || strings.HasPrefix(name, "-") {
+		return false
+	}
+
+	return nil == gitCommand(repo, "check-ref-format", "--branch", name).Run()
+}
+
+// gitDirty determines whether the worktree of the specified repository has uncommitted changes of tracked files.
+func gitDirty(repo string) bool {
+	out, err := gitCommand(repo, "status", "--porcelain", "--untracked-files=no").Output()
+
+	return nil != err || 0 < len(bytes.TrimSpace(out))
+}
+
+// gitHeadBranch gets the current branch name of the repository of the specified root directory by reading .git/HEAD,
+// returns the short commit id if HEAD is detached, returns "" if failed.
+//
+// It's used when building the file tree, so git is not executed.
+func gitHeadBranch(repo string) string {
+	head := gitReadHead(repo)
+	if strings.HasPrefix(head, "ref:") {
+		return strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(head, "ref:")), "refs/heads/")
+	}
+
+	if 7 < len(head) {
+		head = head[:7]
+	}
+
+	return head
+}
+
+// gitReadHead reads the HEAD file of the repository of the specified root directory, returns "" if failed.
+func gitReadHead(repo string) string {
+	data, err := ioutil.ReadFile(filepath.Join(gitDir(repo), "HEAD"))
+	if nil != err {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// gitDir gets the git directory of the repository of the specified root directory.
+//
+// The git directory of a worktree or a submodule is not "<repo>/.git", its .git is a file containing
+// "gitdir: <path>". Returns "" if failed.
+func gitDir(repo string) string {
+	ret := filepath.Join(repo, ".git")
+	if gulu.File.IsDir(ret) {
+		return ret
+	}
+
+	data, err := ioutil.ReadFile(ret)
+	if nil != err {
+		return ""
+	}
+
+	ret = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+	if !filepath.IsAbs(ret) {
+		ret = filepath.Join(repo, ret)
+	}
+
+	return ret
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+const (
+	maxChatHistory    = 200  // max number of the recent messages kept for a workspace
+	maxChatMessageLen = 2000 // max number of characters of a message
+)
+
+// chatMessage represents a message of the chat channel of a workspace.
+type chatMessage struct {
+	Id   string `json:"id"`
+	Sid  string `json:"sid"`            // session id of the sender
+	User string `json:"user"`           // user name of the sender
+	Text string `json:"text"`           // message content
+	Path string `json:"path,omitempty"` // referenced file path relative to {workspace}/src, in slash form
+	Line int    `json:"line,omitempty"` // referenced line number (1-based)
+	Time int64  `json:"time"`           // send time in unix milliseconds
+}
+
+var (
+	// recent messages of workspaces, <workspace, messages>
+	chats = map[string][]*chatMessage{}
+
+	// guards chats and chat history files
+	chatsMutex sync.Mutex
+)
+
+// LoadChat registers the handlers of chat messages sent via session channels. Users sharing a workspace (the first
+// workspace path of their configurations) chat in the channel of it, the recent messages are kept in
+// {Wide.Data}/chats/{md5 of the workspace}.json.
+//
+//  1. chat: sends a message "text" with an optional reference to a line ("path" and "line"), broadcast as chat
+//  2. chat-history: requests the recent messages, replied with chat-history
+func LoadChat() {
+	session.SessionMessageHandlers["chat"] = sendChatMessage
+	session.SessionMessageHandlers["chat-history"] = chatHistory
+}
+
+// sendChatMessage handles chat messages.
+func sendChatMessage(sid, uid string, message map[string]interface{}) {
+	workspace := chatWorkspace(uid)
+	if "" == workspace {
+		return
+	}
+
+	text, _ := message["text"].(string)
+	text = strings.TrimSpace(text)
+	if "" == text || maxChatMessageLen < utf8.RuneCountInString(text) {
+		return
+	}
+
+	msg := &chatMessage{Id: gulu.Rand.String(16), Sid: sid, User: uid, Text: text,
+		Time: time.Now().UnixNano() / int64(time.Millisecond)}
+	if user := conf.GetUser(uid); nil != user && "" != user.Name {
+		msg.User = user.Name
+	}
+
+	p, _ := message["path"].(string)
+	line, _ := message["line"].(float64)
+	if p = path.Clean("/" + p)[1:]; "" != p && 0 < line {
+		msg.Path = p
+		msg.Line = int(line)
+	}
+
+	chatsMutex.Lock()
+	history := appendChatMessage(chatMessages(workspace), msg)
+	chats[workspace] = history
+	if err := saveChatHistory(chatHistoryPath(workspace), history); nil != err {
+		logger.Errorf("Saves chat history of workspace [%s] failed: %s", workspace, err)
+	}
+	chatsMutex.Unlock()
+
+	for _, user := range conf.GetUsers() {
+		if !conf.Wide().FeatureEnabled("collab", user.Id) || chatWorkspace(user.Id) != workspace {
+			continue
+		}
+
+		for _, s := range session.WideSessions.GetByUserId(user.Id) {
+			writeDocumentMessage(s.ID, map[string]interface{}{"cmd": "chat", "message": msg})
+		}
+	}
+}
+
+// chatHistory handles chat-history messages.
+func chatHistory(sid, uid string, message map[string]interface{}) {
+	workspace := chatWorkspace(uid)
+	if "" == workspace {
+		return
+	}
+
+	chatsMutex.Lock()
+	history := chatMessages(workspace)
+	chatsMutex.Unlock()
+
+	writeDocumentMessage(sid, map[string]interface{}{"cmd": "chat-history", "messages": history})
+}
+
+// chatWorkspace returns the workspace whose chat channel the specified user is in, returns "" if the user can't chat.
+func chatWorkspace(uid string) string {
+	if !conf.Wide().FeatureEnabled("collab", uid) {
+		return ""
+	}
+
+	workspaces := filepath.SplitList(conf.GetUserWorkspace(uid))
+	if 1 > len(workspaces) {
+		return ""
+	}
+
+	return filepath.Clean(workspaces[0])
+}
+
+// chatMessages returns the recent messages of the specified workspace, loads them from the history file if not cached.
+// chatsMutex should be locked by the caller.
+func chatMessages(workspace string) []*chatMessage {
+	if history, ok := chats[workspace]; ok {
+		return history
+	}
+
+	history, err := loadChatHistory(chatHistoryPath(workspace))
+	if nil != err {
+		logger.Errorf("Loads chat history of workspace [%s] failed: %s", workspace, err)
+	}
+	chats[workspace] = history
+
+	return history
+}
+
+// appendChatMessage appends the specified message to the specified history, keeps at most maxChatHistory messages.
+func appendChatMessage(history []*chatMessage, msg *chatMessage) []*chatMessage {
+	ret := make([]*chatMessage, 0, minInt(len(history)+1, maxChatHistory))
+	if maxChatHistory <= len(history) {
+		history = history[len(history)-maxChatHistory+1:]
+	}
+	ret = append(ret, history...)
+
+	return append(ret, msg)
+}
+
+// chatHistoryPath returns the path of the chat history file of the specified workspace.
+func chatHistoryPath(workspace string) string {
+	return filepath.Join(conf.Wide().Data, "chats", fmt.Sprintf("%x.json", md5.Sum([]byte(workspace))))
+}
+
+// loadChatHistory loads chat messages from the specified file, returns an empty history if the file does not exist.
+func loadChatHistory(path string) ([]*chatMessage, error) {
+	ret := []*chatMessage{}
+
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+
+		return ret, err
+	}
+
+	if err := json.Unmarshal(data, &ret); nil != err {
+		return []*chatMessage{}, err
+	}
+
+	return ret, nil
+}
+
+// saveChatHistory saves the specified chat messages into the specified file.
+func saveChatHistory(path string, history []*chatMessage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	data, err := json.Marshal(history)
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAppendChatMessage(t *testing.T) {
+	history := []*chatMessage{}
+	for i := 0; i < maxChatHistory+10; i++ {
+		history = appendChatMessage(history, &chatMessage{Id: strconv.Itoa(i)})
+	}
+
+	if maxChatHistory != len(history) {
+		t.Fatalf("history length: expected [%d], actual [%d]", maxChatHistory, len(history))
+	}
+	if "10" != history[0].Id || strconv.Itoa(maxChatHistory+9) != history[len(history)-1].Id {
+		t.Errorf("history: expected [10, %d], actual [%s, %s]", maxChatHistory+9, history[0].Id,
+			history[len(history)-1].Id)
+	}
+}
+
+func TestChatHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chats", "workspace.json")
+
+	history, err := loadChatHistory(path)
+	if nil != err || 0 != len(history) {
+		t.Fatalf("load missing history: expected empty, actual [%v], error [%v]", history, err)
+	}
+
+	history = appendChatMessage(history, &chatMessage{Id: "1", User: "alice", Text: "look at line 42",
+		Path: "hello/main.go", Line: 42, Time: 1})
+	if err := saveChatHistory(path, history); nil != err {
+		t.Fatal(err)
+	}
+
+	history, err = loadChatHistory(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(history) || "look at line 42" != history[0].Text || "hello/main.go" != history[0].Path ||
+		42 != history[0].Line {
+		t.Errorf("loaded history: unexpected [%+v]", history)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// GitChangesHandler handles request of listing changed files of the repository containing the specified path.
+func GitChangesHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	out, err := gitCommand(req.repo, "status", "--porcelain", "-z", "--untracked-files=all").Output()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	changes := []map[string]interface{}{}
+	for _, entry := range parseGitStatus(out) {
+		changes = append(changes, map[string]interface{}{
+			"path":     filepath.ToSlash(filepath.Join(req.repo, entry.Path)),
+			"xy":       entry.XY,
+			"status":   entry.Status,
+			"staged":   ' ' != entry.XY[0] && '?' != entry.XY[0],
+			"unstaged": ' ' != entry.XY[1],
+		})
+	}
+
+	result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "changes": changes}
+}
+
+// GitStageHandler handles request of staging a file (or directory) or a hunk.
+//
+// If the request carries a "patch" (a unified diff of the hunks to stage), only the patch is applied to the index.
+func GitStageHandler(w http.ResponseWriter, r *http.Request) {
+	gitIndexHandler(w, r, false)
+}
+
+// GitUnstageHandler handles request of unstaging a file (or directory) or a hunk.
+//
+// If the request carries a "patch" (a unified diff of the hunks to unstage), only the patch is reverted from the index.
+func GitUnstageHandler(w http.ResponseWriter, r *http.Request) {
+	gitIndexHandler(w, r, true)
+}
+
+// gitIndexHandler stages or unstages (if the specified unstage is true) a path or a patch.
+func gitIndexHandler(w http.ResponseWriter, r *http.Request, unstage bool) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	var gitArgs []string
+	patch, _ := req.args["patch"].(string)
+
+	if "" != patch {
+		gitArgs = []string{"apply", "--cached", "--unidiff-zero", "--whitespace=nowarn"}
+		if unstage {
+			gitArgs = append(gitArgs, "--reverse")
+		}
+		gitArgs = append(gitArgs, "-")
+	} else if unstage {
+		if gitHasHead(req.repo) {
+			gitArgs = []string{"reset", "-q", "HEAD", "--", req.path}
+		} else {
+			gitArgs = []string{"rm", "-q", "-r", "--cached", "--", req.path}
+		}
+	} else {
+		gitArgs = []string{"add", "-A", "--", req.path}
+	}
+
+	cmd := gitCommand(req.repo, gitArgs...)
+	if "" != patch {
+		cmd.Stdin = strings.NewReader(patch)
+	}
+
+	if out, err := cmd.CombinedOutput(); nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(gitArgs, " "), req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+	}
+}
+
+// GitCommitHandler handles request of committing the staged changes.
+//
+// The author is taken from the user's configurations, the output is pushed to the output channel. If pre-commit checks
+// are configured (see preCommitChecks), the commit is refused while they fail, the failures are returned as lints.
+func GitCommitHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	message := req.arg("message")
+	if "" == message {
+		result.Code = -1
+		result.Msg = req.msg("git-commit-empty-message")
+
+		return
+	}
+
+	if lints := preCommitChecks(req); 0 < len(lints) {
+		result.Code = -1
+		result.Msg = req.msg("git-pre-commit-failed")
+		result.Data = lints
+
+		pushOutput(req.sid, map[string]interface{}{"cmd": "git-pre-commit", "lints": lints},
+			"<span class='build-error'>"+result.Msg+"</span>\n")
+
+		return
+	}
+
+	gitArgs := []string{"commit", "-F", "-"}
+	if req.flag("all") {
+		gitArgs = append(gitArgs, "-a")
+	}
+
+	name, email := req.user.GitAuthor()
+	cmd := gitCommand(req.repo, append([]string{"-c", "user.name=" + name, "-c", "user.email=" + email}, gitArgs...)...)
+	cmd.Stdin = strings.NewReader(message)
+
+	if _, succ := runGitWithOutput(cmd, req.sid, req.locale, "git-commit"); !succ {
+		result.Code = -1
+
+		return
+	}
+
+	out, _ := gitCommand(req.repo, "rev-parse", "HEAD").Output()
+	result.Data = strings.TrimSpace(string(out))
+}
+
+// GitDiscardHandler handles request of discarding the changes of a file (or directory) by checking out its HEAD
+// version.
+//
+// Without "confirm", nothing is discarded and the diff to be discarded is returned in the same structure as
+// GitDiffHandler, so the user can review it before confirming. Staged changes are discarded as well, a file not in
+// HEAD is unstaged and left in the worktree. The session is told that the files changed so opening editors reload.
+func GitDiscardHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	if !gitHasHead(req.repo) {
+		result.Code = -1
+		result.Msg = req.msg("git-no-commit")
+
+		return
+	}
+
+	if !req.flag("confirm") {
+		out, err := gitCommand(req.repo, "-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff",
+			"--src-prefix=a/", "--dst-prefix=b/", "HEAD", "--", req.path).Output()
+		if nil != err {
+			logger.Error(err)
+			result.Code = -1
+
+			return
+		}
+
+		result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "files": parseGitDiff(req.repo, out)}
+
+		return
+	}
+
+	out, err := gitCommand(req.repo, "diff", "--name-only", "-z", "HEAD", "--", req.path).Output()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if !runGit(req, result, "reset", "-q", "HEAD", "--", req.path) {
+		return
+	}
+
+	rel, _ := filepath.Rel(req.repo, req.path)
+	if "." == rel {
+		rel = ""
+	}
+	if nil == gitCommand(req.repo, "cat-file", "-e", "HEAD:"+filepath.ToSlash(rel)).Run() {
+		if !runGit(req, result, "checkout", "HEAD", "--", req.path) {
+			return
+		}
+	}
+
+	for _, name := range strings.Split(string(out), "\x00") {
+		if "" != name {
+			notifyFileChanged(req.sid, filepath.Join(req.repo, name))
+		}
+	}
+}
+
+// notifyFileChanged tells the specified session that the content of the file of the specified path changed.
+func notifyFileChanged(sid, path string) {
+	if content, err := ioutil.ReadFile(path); nil == err {
+		syncDocument(filepath.ToSlash(path), string(content))
+	}
+
+	wsChannel := session.SessionWS[sid]
+	if nil == wsChannel {
+		return
+	}
+
+	path = filepath.ToSlash(path)
+	cmd := map[string]interface{}{"path": path, "dir": filepath.ToSlash(filepath.Dir(path)), "cmd": "file-changed",
+		"type": "f"}
+	wsChannel.WriteJSON(&cmd)
+}
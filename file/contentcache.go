@@ -0,0 +1,165 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// contentCacheSize is the max total bytes of the file contents cached by fileContents.
+	contentCacheSize = 64 << 20
+
+	// maxCachedContentSize is the max size of a cached file, larger files are read from disk every time so that one of
+	// them can't evict the whole cache.
+	maxCachedContentSize = contentCacheSize / 16
+)
+
+// fileContents caches the recently read file contents for repeated searches and finding usages over the same files.
+var fileContents = newContentCache(contentCacheSize)
+
+// contentCache represents a LRU cache of file contents bounded by the total bytes, an entry is valid only if the
+// modification time and the size of the file are unchanged.
+type contentCache struct {
+	mutex    sync.Mutex
+	capacity int64                    // max total bytes of the contents
+	size     int64                    // total bytes of the contents
+	entries  map[string]*list.Element // <path, element of *cachedContent>
+	order    *list.List               // entries ordered by the latest use, the most recently used in the front
+}
+
+// cachedContent represents a cached file content.
+type cachedContent struct {
+	path    string
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+// newContentCache creates a content cache with the specified capacity in bytes.
+func newContentCache(capacity int64) *contentCache {
+	return &contentCache{capacity: capacity, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// read returns the content of the file with the specified path from the cache, the file is read and cached if it's not
+// cached or has been modified since cached. The returned content must not be modified.
+func (c *contentCache) read(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if nil != err {
+		return nil, err
+	}
+
+	if content, ok := c.get(path, info); ok {
+		return content, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	c.put(path, info, content)
+
+	return content, nil
+}
+
+// open returns a reader of the file with the specified path and info, the content is returned from the cache (read and
+// cached if not) unless the file is larger than maxCachedContentSize.
+func (c *contentCache) open(path string, info os.FileInfo) (io.ReadCloser, error) {
+	if content, ok := c.get(path, info); ok {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	if maxCachedContentSize < info.Size() {
+		return os.Open(path)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	c.put(path, info, content)
+
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// get returns the cached content of the file with the specified path if it's unchanged since cached (compared with the
+// specified info), returns false otherwise.
+func (c *contentCache) get(path string, info os.FileInfo) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cachedContent)
+	if !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+		c.removeElement(element)
+
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return entry.content, true
+}
+
+// put caches the specified content of the file with the specified path and info, the least recently used contents are
+// evicted if the capacity is exceeded. The content isn't cached if it's too large or the file has been modified while
+// being read.
+func (c *contentCache) put(path string, info os.FileInfo, content []byte) {
+	size := int64(len(content))
+	if maxCachedContentSize < size || info.Size() != size {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[path]; ok {
+		c.removeElement(element)
+	}
+
+	c.entries[path] = c.order.PushFront(&cachedContent{path: path, modTime: info.ModTime(), size: size, content: content})
+	c.size += size
+
+	for c.capacity < c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// remove removes the content of the file with the specified path, it's called once the file is saved since its
+// modification time may be unchanged.
+func (c *contentCache) remove(path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[path]; ok {
+		c.removeElement(element)
+	}
+}
+
+// removeElement removes the specified element, the caller must hold the mutex.
+func (c *contentCache) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*cachedContent)
+	delete(c.entries, entry.path)
+	c.size -= entry.size
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	cache := newContentCache(8)
+	a, b := write("a", "aaaa"), write("b", "bbbb")
+	for _, path := range []string{a, b, a} {
+		if _, err := cache.read(path); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if 8 != cache.size || 2 != cache.order.Len() {
+		t.Fatalf("expected 2 contents of 8 bytes, actual %d of %d bytes", cache.order.Len(), cache.size)
+	}
+
+	// b is the least recently used one
+	c := write("c", "cc")
+	if _, err := cache.read(c); nil != err {
+		t.Fatal(err)
+	}
+	if _, ok := cache.entries[b]; ok {
+		t.Error("the least recently used content is not evicted")
+	}
+	if _, ok := cache.entries[a]; !ok {
+		t.Error("the recently used content is evicted")
+	}
+
+	// a modified file is read again
+	write("a", "AAA")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(a, later, later); nil != err {
+		t.Fatal(err)
+	}
+	if content, err := cache.read(a); nil != err || "AAA" != string(content) {
+		t.Errorf("expected [AAA], actual [%s] (%v)", content, err)
+	}
+	if 5 != cache.size {
+		t.Errorf("expected 5 bytes, actual %d", cache.size)
+	}
+
+	cache.remove(a)
+	if _, ok := cache.entries[a]; ok || 2 != cache.size {
+		t.Errorf("the removed content is kept, %d bytes", cache.size)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitEmptyTree is the id of the empty tree object, used as the base of diff in a repository without any commit.
+const gitEmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// gitDiffFile represents the diff of a file.
+type gitDiffFile struct {
+	OldPath string         `json:"oldPath"` // path before the change, "" if added
+	NewPath string         `json:"newPath"` // path after the change, "" if deleted
+	Status  string         `json:"status"`  // "added", "deleted", "modified" or "renamed"
+	Binary  bool           `json:"binary"`  // whether is a binary file, no hunks for it
+	Hunks   []*gitDiffHunk `json:"hunks"`
+}
+
+// gitDiffHunk represents a hunk of a file diff.
+type gitDiffHunk struct {
+	OldStart int            `json:"oldStart"`
+	OldLines int            `json:"oldLines"`
+	NewStart int            `json:"newStart"`
+	NewLines int            `json:"newLines"`
+	Header   string         `json:"header"` // the "@@ ... @@" line
+	Lines    []*gitDiffLine `json:"lines"`
+}
+
+// gitDiffLine represents a line of a hunk.
+type gitDiffLine struct {
+	Type      string `json:"type"`      // "context", "add" or "del"
+	Content   string `json:"content"`   // line content without the leading marker
+	OldLine   int    `json:"oldLine"`   // line number in the old file, 0 for an added line
+	NewLine   int    `json:"newLine"`   // line number in the new file, 0 for a deleted line
+	NoNewline bool   `json:"noNewline"` // whether the line is the last line without newline at end of file
+}
+
+var gitHunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// GitDiffHandler handles request of getting the diff of a file or a directory (the whole worktree if it's the
+// repository root), parsed into structured hunks.
+//
+// Compares the worktree with HEAD by default. Optional arguments:
+//  1. "staged": compares the index with HEAD
+//  2. "from": compares the worktree (or "to" if specified) with the commit "from"
+//  3. "to": the commit to compare with "from"
+//  4. "context": lines of context, defaults to 3
+func GitDiffHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	context := 3
+	if c, ok := req.args["context"].(float64); ok && 0 <= c {
+		context = int(c)
+	}
+
+	gitArgs := []string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "-M",
+		"--src-prefix=a/", "--dst-prefix=b/", "--unified=" + strconv.Itoa(context)}
+
+	from, to := req.arg("from"), req.arg("to")
+	switch {
+	case "" != from:
+		if strings.HasPrefix(from, "-") || strings.HasPrefix(to, "-") {
+			result.Code = -1
+
+			return
+		}
+
+		gitArgs = append(gitArgs, from)
+		if "" != to {
+			gitArgs = append(gitArgs, to)
+		}
+	case req.flag("staged"):
+		gitArgs = append(gitArgs, "--cached")
+		if !gitHasHead(req.repo) {
+			gitArgs = append(gitArgs, gitEmptyTree)
+		}
+	case gitHasHead(req.repo):
+		gitArgs = append(gitArgs, "HEAD")
+	default:
+		gitArgs = append(gitArgs, gitEmptyTree)
+	}
+	gitArgs = append(gitArgs, "--", req.path)
+
+	out, err := gitCommand(req.repo, gitArgs...).CombinedOutput()
+	if nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(gitArgs, " "), req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "files": parseGitDiff(req.repo, out)}
+}
+
+// parseGitDiff parses the specified output of "git diff" with "a/" and "b/" prefixes, paths are joined with the
+// specified repository root directory.
+func parseGitDiff(repo string, out []byte) []*gitDiffFile {
+	ret := []*gitDiffFile{}
+
+	var file *gitDiffFile
+	var hunk *gitDiffHunk
+	var oldLine, newLine int
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "diff --git ") {
+			file = &gitDiffFile{Status: "modified", Hunks: []*gitDiffHunk{}}
+			file.OldPath, file.NewPath = parseGitDiffHeader(line[len("diff --git "):])
+			ret = append(ret, file)
+			hunk = nil
+
+			continue
+		}
+
+		if nil == file {
+			continue
+		}
+
+		if nil == hunk {
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				file.Status = "added"
+			case strings.HasPrefix(line, "deleted file mode"):
+				file.Status = "deleted"
+			case strings.HasPrefix(line, "rename from "):
+				file.Status = "renamed"
+				file.OldPath = unquoteGitPath(line[len("rename from "):])
+			case strings.HasPrefix(line, "rename to "):
+				file.NewPath = unquoteGitPath(line[len("rename to "):])
+			case strings.HasPrefix(line, "Binary files "):
+				file.Binary = true
+			case strings.HasPrefix(line, "--- "):
+				file.OldPath = strings.TrimPrefix(unquoteGitPath(line[4:]), "a/")
+			case strings.HasPrefix(line, "+++ "):
+				file.NewPath = strings.TrimPrefix(unquoteGitPath(line[4:]), "b/")
+			}
+		}
+
+		if strings.HasPrefix(line, "@@ ") {
+			m := gitHunkHeaderPattern.FindStringSubmatch(line)
+			if nil == m {
+				continue
+			}
+
+			hunk = &gitDiffHunk{OldStart: atoi(m[1], 0), OldLines: atoi(m[2], 1), NewStart: atoi(m[3], 0),
+				NewLines: atoi(m[4], 1), Header: line, Lines: []*gitDiffLine{}}
+			file.Hunks = append(file.Hunks, hunk)
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+
+			continue
+		}
+
+		if nil == hunk || "" == line {
+			continue
+		}
+
+		switch line[0] {
+		case ' ':
+			hunk.Lines = append(hunk.Lines, &gitDiffLine{Type: "context", Content: line[1:], OldLine: oldLine, NewLine: newLine})
+			oldLine++
+			newLine++
+		case '-':
+			hunk.Lines = append(hunk.Lines, &gitDiffLine{Type: "del", Content: line[1:], OldLine: oldLine})
+			oldLine++
+		case '+':
+			hunk.Lines = append(hunk.Lines, &gitDiffLine{Type: "add", Content: line[1:], NewLine: newLine})
+			newLine++
+		case '\\': // "\ No newline at end of file"
+			if 0 < len(hunk.Lines) {
+				hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+			}
+		}
+	}
+
+	for _, file := range ret {
+		switch file.Status {
+		case "added":
+			file.OldPath = ""
+		case "deleted":
+			file.NewPath = ""
+		}
+
+		file.OldPath = gitDiffPath(repo, file.OldPath)
+		file.NewPath = gitDiffPath(repo, file.NewPath)
+	}
+
+	return ret
+}
+
+// gitDiffPath converts the specified path in a diff to an absolute path under the specified repository root directory,
+// returns "" for "/dev/null".
+func gitDiffPath(repo, path string) string {
+	if "" == path || "/dev/null" == path {
+		return ""
+	}
+
+	return filepath.ToSlash(filepath.Join(repo, path))
+}
+
+// parseGitDiffHeader parses paths from the specified "a/<old> b/<new>" part of a "diff --git" line.
+//
+// The result is only a fallback for diffs without "---" and "+++" lines (binary or mode changes), the paths are
+// ambiguous if they contain " b/".
+func parseGitDiffHeader(paths string) (oldPath, newPath string) {
+	if strings.HasPrefix(paths, "\"") {
+		if i := strings.Index(paths, "\" "); 0 < i {
+			return strings.TrimPrefix(unquoteGitPath(paths[:i+1]), "a/"),
+				strings.TrimPrefix(unquoteGitPath(paths[i+2:]), "b/")
+		}
+	}
+
+	if i := strings.LastIndex(paths, " b/"); 0 < i {
+		return strings.TrimPrefix(paths[:i], "a/"), paths[i+3:]
+	}
+
+	return "", ""
+}
+
+// unquoteGitPath unquotes the specified path quoted by git (C-style quoting), git appends a tab to the path of
+// "---" and "+++" lines if the path contains spaces.
+func unquoteGitPath(path string) string {
+	path = strings.TrimSuffix(path, "\t")
+	if !strings.HasPrefix(path, "\"") {
+		return path
+	}
+
+	ret, err := strconv.Unquote(path)
+	if nil != err {
+		return path
+	}
+
+	return ret
+}
+
+// atoi converts the specified string to int, returns the specified default value if failed.
+func atoi(s string, defaultValue int) int {
+	ret, err := strconv.Atoi(s)
+	if nil != err {
+		return defaultValue
+	}
+
+	return ret
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"testing"
+)
+
+func TestParseGitDiff(t *testing.T) {
+	cases := []struct {
+		name  string
+		out   string
+		files []gitDiffFile
+		lines [][]gitDiffLine // lines of the first hunk of each file
+	}{
+		{
+			name: "modified",
+			out: "diff --git a/main.go b/main.go\n" +
+				"index 1111111..2222222 100644\n" +
+				"--- a/main.go\n" +
+				"+++ b/main.go\n" +
+				"@@ -1,3 +1,3 @@ package main\n" +
+				" a\n" +
+				"-b\n" +
+				"+c\n" +
+				" d\n",
+			files: []gitDiffFile{{OldPath: "/repo/main.go", NewPath: "/repo/main.go", Status: "modified"}},
+			lines: [][]gitDiffLine{{
+				{Type: "context", Content: "a", OldLine: 1, NewLine: 1},
+				{Type: "del", Content: "b", OldLine: 2},
+				{Type: "add", Content: "c", NewLine: 2},
+				{Type: "context", Content: "d", OldLine: 3, NewLine: 3},
+			}},
+		},
+		{
+			name: "added and deleted",
+			out: "diff --git a/new.go b/new.go\n" +
+				"new file mode 100644\n" +
+				"--- /dev/null\n" +
+				"+++ b/new.go\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+x\n" +
+				"diff --git a/old.go b/old.go\n" +
+				"deleted file mode 100644\n" +
+				"--- a/old.go\n" +
+				"+++ /dev/null\n" +
+				"@@ -1 +0,0 @@\n" +
+				"-y\n",
+			files: []gitDiffFile{
+				{NewPath: "/repo/new.go", Status: "added"},
+				{OldPath: "/repo/old.go", Status: "deleted"},
+			},
+			lines: [][]gitDiffLine{
+				{{Type: "add", Content: "x", NewLine: 1}},
+				{{Type: "del", Content: "y", OldLine: 1}},
+			},
+		},
+		{
+			name: "renamed",
+			out: "diff --git a/a.go b/dir/b.go\n" +
+				"similarity index 100%\n" +
+				"rename from a.go\n" +
+				"rename to dir/b.go\n",
+			files: []gitDiffFile{{OldPath: "/repo/a.go", NewPath: "/repo/dir/b.go", Status: "renamed"}},
+		},
+		{
+			name: "quoted paths",
+			out: "diff --git \"a/\\346\\226\\207 1.txt\" \"b/\\346\\226\\207 1.txt\"\n" +
+				"--- \"a/\\346\\226\\207 1.txt\"\n" +
+				"+++ \"b/\\346\\226\\207 1.txt\"\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			files: []gitDiffFile{{OldPath: "/repo/文 1.txt", NewPath: "/repo/文 1.txt", Status: "modified"}},
+			lines: [][]gitDiffLine{{
+				{Type: "del", Content: "a", OldLine: 1},
+				{Type: "add", Content: "b", NewLine: 1},
+			}},
+		},
+		{
+			name: "spaces in paths",
+			out: "diff --git a/a b.txt b/a b.txt\n" +
+				"--- a/a b.txt\t\n" +
+				"+++ b/a b.txt\t\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			files: []gitDiffFile{{OldPath: "/repo/a b.txt", NewPath: "/repo/a b.txt", Status: "modified"}},
+			lines: [][]gitDiffLine{{
+				{Type: "del", Content: "a", OldLine: 1},
+				{Type: "add", Content: "b", NewLine: 1},
+			}},
+		},
+		{
+			name: "binary",
+			out: "diff --git a/logo.png b/logo.png\n" +
+				"index 1111111..2222222 100644\n" +
+				"Binary files a/logo.png and b/logo.png differ\n",
+			files: []gitDiffFile{{OldPath: "/repo/logo.png", NewPath: "/repo/logo.png", Status: "modified", Binary: true}},
+		},
+		{
+			name: "no newline at end of file",
+			out: "diff --git a/a.txt b/a.txt\n" +
+				"--- a/a.txt\n" +
+				"+++ b/a.txt\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"\\ No newline at end of file\n" +
+				"+a\n",
+			files: []gitDiffFile{{OldPath: "/repo/a.txt", NewPath: "/repo/a.txt", Status: "modified"}},
+			lines: [][]gitDiffLine{{
+				{Type: "del", Content: "a", OldLine: 1, NoNewline: true},
+				{Type: "add", Content: "a", NewLine: 1},
+			}},
+		},
+	}
+
+	for _, c := range cases {
+		files := parseGitDiff("/repo", []byte(c.out))
+		if len(c.files) != len(files) {
+			t.Errorf("[%s] expected [%d] files, got [%d]", c.name, len(c.files), len(files))
+
+			continue
+		}
+
+		for i, expected := range c.files {
+			file := files[i]
+			if expected.OldPath != file.OldPath || expected.NewPath != file.NewPath ||
+				expected.Status != file.Status || expected.Binary != file.Binary {
+				t.Errorf("[%s] expected file %+v, got %+v", c.name, expected, *file)
+			}
+
+			if len(c.lines) <= i {
+				if 0 != len(file.Hunks) {
+					t.Errorf("[%s] expected no hunks, got [%d]", c.name, len(file.Hunks))
+				}
+
+				continue
+			}
+
+			if 1 > len(file.Hunks) || len(c.lines[i]) != len(file.Hunks[0].Lines) {
+				t.Errorf("[%s] unexpected hunks %+v", c.name, file.Hunks)
+
+				continue
+			}
+			for j, line := range c.lines[i] {
+				if line != *file.Hunks[0].Lines[j] {
+					t.Errorf("[%s] expected line %+v, got %+v", c.name, line, *file.Hunks[0].Lines[j])
+				}
+			}
+		}
+	}
+}
+
+func TestParseGitDiffHunkHeader(t *testing.T) {
+	files := parseGitDiff("/repo", []byte("diff --git a/a b/a\n--- a/a\n+++ b/a\n@@ -10,2 +12 @@ func main() {\n x\n-y\n"))
+	hunk := files[0].Hunks[0]
+	if 10 != hunk.OldStart || 2 != hunk.OldLines || 12 != hunk.NewStart || 1 != hunk.NewLines {
+		t.Errorf("unexpected hunk %+v", *hunk)
+	}
+	if 11 != hunk.Lines[1].OldLine {
+		t.Errorf("expected old line [11], got [%d]", hunk.Lines[1].OldLine)
+	}
+}
@@ -0,0 +1,526 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// documentIdleTTL is how long a document is kept after its last editor left, so that editors reconnecting could
+// continue from their revisions.
+const documentIdleTTL = 30 * time.Minute
+
+// maxCursorRanges is the max number of selections (multiple cursors) of an editor shared with the others.
+const maxCursorRanges = 16
+
+// maxDocumentHistory is the max number of operations kept in the history of a document, editors lagging further
+// behind are reset to the current content.
+const maxDocumentHistory = 1000
+
+// document represents a file being edited collaboratively, editors (browser tabs, identified by their wide session
+// ids) send operations via their session channels. The buffer of a document is authoritative: an operation is
+// transformed against the concurrent ones in the history, applied and then broadcast to the other editors, saving the
+// file writes the buffer.
+//
+// Documents are kept in memory of the instance, editors of the same file connected to different instances of a
+// cluster don't see each other's changes.
+type document struct {
+	text    []uint16           // content in UTF-16 code units, the same as operations count
+	history []*revision        // applied operations, history[i] turned revision base+i into base+i+1
+	base    int                // revision of the first operation in history
+	saved   int                // revision of the content in the file
+	editors map[string]string  // <sid, path of the file sent by the editor>
+	cursors map[string]*cursor // <sid, cursor of the editor>, positions are of the current revision
+	idle    time.Time          // time the last editor left
+	mutex   sync.Mutex
+}
+
+// cursor represents the cursors and selections of an editor, each range is [anchor, head], the same if not selecting.
+type cursor struct {
+	user   string // name of the user
+	ranges [][2]int
+}
+
+// revision represents an applied operation.
+type revision struct {
+	sid string // session id of the editor, "" for changes made on the file
+	op  *textOperation
+}
+
+var (
+	// documents being edited, <absolute path, document>
+	documents = map[string]*document{}
+
+	// guards documents, should be locked before the mutex of a document if both
+	documentsMutex sync.Mutex
+)
+
+// LoadDocuments registers the handlers of collaborative editing messages sent via session channels.
+//
+//  1. doc-open: starts editing a file, replied with doc-opened carrying the content and the revision. An editor
+//     reconnecting sends its revision (and its unacknowledged operation), then it's replied with the operations it
+//     missed instead
+//  2. doc-op: an operation on the revision, replied with doc-ack and broadcast as doc-op to the other editors
+//  3. doc-cursor: cursors and selections on the revision, broadcast as doc-cursor to the other editors
+//  4. doc-close: stops editing a file
+func LoadDocuments() {
+	session.SessionMessageHandlers["doc-open"] = openDocument
+	session.SessionMessageHandlers["doc-op"] = editDocument
+	session.SessionMessageHandlers["doc-cursor"] = moveCursor
+	session.SessionMessageHandlers["doc-close"] = closeDocument
+	session.SessionClosedHandlers = append(session.SessionClosedHandlers, leaveDocuments)
+}
+
+// rev returns the current revision of the document.
+func (d *document) rev() int {
+	return d.base + len(d.history)
+}
+
+// content returns the current content of the document.
+func (d *document) content() string {
+	return string(utf16.Decode(d.text))
+}
+
+// receive transforms the specified operation on the specified revision against the concurrent ones and applies it.
+func (d *document) receive(sid string, rev int, op *textOperation) (*textOperation, error) {
+	if rev < d.base || rev > d.rev() {
+		return nil, fmt.Errorf("revision [%d] is out of the history [%d, %d]", rev, d.base, d.rev())
+	}
+
+	var err error
+	for _, r := range d.history[rev-d.base:] {
+		if op, _, err = transform(op, r.op); nil != err {
+			return nil, err
+		}
+	}
+
+	text, err := op.apply(d.text)
+	if nil != err {
+		return nil, err
+	}
+	if int64(len(text)) > conf.Wide().MaxFileSizeBytes() {
+		return nil, errors.New("document is too large")
+	}
+
+	d.text = text
+	d.history = append(d.history, &revision{sid: sid, op: op})
+	for _, c := range d.cursors {
+		for i := range c.ranges {
+			c.ranges[i][0] = op.transformIndex(c.ranges[i][0])
+			c.ranges[i][1] = op.transformIndex(c.ranges[i][1])
+		}
+	}
+	if n := len(d.history) - maxDocumentHistory; 0 < n {
+		d.history = append([]*revision{}, d.history[n:]...)
+		d.base += n
+	}
+
+	return op, nil
+}
+
+// edit applies the specified operation of the specified editor, acknowledges the editor and broadcasts it to the
+// others.
+func (d *document) edit(sid string, rev int, op *textOperation) {
+	op, err := d.receive(sid, rev, op)
+	if nil != err {
+		logger.Warnf("Applies operation of session [%s] to document [%s] failed: %s", sid, d.editors[sid], err)
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": d.editors[sid],
+			"msg": err.Error()})
+
+		return
+	}
+
+	writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-ack", "path": d.editors[sid], "rev": d.rev()})
+	d.broadcast(sid, op)
+}
+
+// broadcast sends the specified applied operation to the editors except the specified one.
+func (d *document) broadcast(sid string, op *textOperation) {
+	for editor, path := range d.editors {
+		if editor == sid {
+			continue
+		}
+
+		writeDocumentMessage(editor, map[string]interface{}{"cmd": "doc-op", "path": path, "rev": d.rev(),
+			"op": op.toJSON()})
+	}
+}
+
+// openDocument handles doc-open messages.
+func openDocument(sid, uid string, message map[string]interface{}) {
+	path, _ := message["path"].(string)
+	if !conf.Wide().FeatureEnabled("collab", uid) || "0" != fmt.Sprint(message["pathtype"]) {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": "Forbidden"})
+
+		return
+	}
+
+	filePath, _ := GetPath(uid, path, "0")
+	if !session.CanAccess(uid, filePath) {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": "Forbidden"})
+
+		return
+	}
+
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	d, err := loadDocument(filePath)
+	if nil != err {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": err.Error()})
+
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.editors[sid] = path
+
+	value, ok := message["rev"].(float64)
+	rev := int(value)
+	if !ok || rev < d.base || rev > d.rev() {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-opened", "path": path, "rev": d.rev(),
+			"content": d.content(), "dirty": d.saved != d.rev()})
+		d.sendCursors(sid)
+
+		return
+	}
+
+	// reconnecting, replays the missed operations, the ones of the editor itself are acknowledgements
+	writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-opened", "path": path, "rev": rev, "replay": true})
+	acknowledged := false
+	for i, r := range d.history[rev-d.base:] {
+		if sid == r.sid {
+			acknowledged = true
+			writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-ack", "path": path, "rev": rev + i + 1})
+
+			continue
+		}
+
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-op", "path": path, "rev": rev + i + 1,
+			"op": r.op.toJSON()})
+	}
+
+	if pending, ok := message["op"]; ok && !acknowledged {
+		op, err := parseTextOperation(pending, int(conf.Wide().MaxFileSizeBytes()))
+		if nil != err {
+			writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": err.Error()})
+
+			return
+		}
+
+		d.edit(sid, rev, op)
+	}
+	d.sendCursors(sid)
+}
+
+// editDocument handles doc-op messages.
+func editDocument(sid, uid string, message map[string]interface{}) {
+	path, _ := message["path"].(string)
+	filePath, _ := GetPath(uid, path, "0")
+
+	documentsMutex.Lock()
+	d := documents[filePath]
+	documentsMutex.Unlock()
+	if nil == d {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": "Not opened"})
+
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.editors[sid]; !ok {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": "Not opened"})
+
+		return
+	}
+
+	op, err := parseTextOperation(message["op"], int(conf.Wide().MaxFileSizeBytes()))
+	if nil != err {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "doc-error", "path": path, "msg": err.Error()})
+
+		return
+	}
+
+	rev, _ := message["rev"].(float64)
+	d.edit(sid, int(rev), op)
+}
+
+// moveCursor handles doc-cursor messages.
+func moveCursor(sid, uid string, message map[string]interface{}) {
+	path, _ := message["path"].(string)
+	filePath, _ := GetPath(uid, path, "0")
+
+	documentsMutex.Lock()
+	d := documents[filePath]
+	documentsMutex.Unlock()
+	if nil == d {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.editors[sid]; !ok {
+		return
+	}
+
+	value, _ := message["rev"].(float64)
+	rev := int(value)
+	if rev < d.base || rev > d.rev() {
+		return // outdated, the editor sends again after synchronized
+	}
+
+	ranges, _ := message["ranges"].([]interface{})
+	c := &cursor{user: uid, ranges: [][2]int{}}
+	if user := conf.GetUser(uid); nil != user && "" != user.Name {
+		c.user = user.Name
+	}
+	for _, v := range ranges {
+		r, ok := v.([]interface{})
+		if !ok || 2 != len(r) || maxCursorRanges <= len(c.ranges) {
+			continue
+		}
+
+		anchor, ok1 := r[0].(float64)
+		head, ok2 := r[1].(float64)
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		c.ranges = append(c.ranges, [2]int{d.transformIndex(int(anchor), rev), d.transformIndex(int(head), rev)})
+	}
+
+	d.cursors[sid] = c
+	for editor, p := range d.editors {
+		if editor != sid {
+			writeDocumentMessage(editor, c.message(p, sid))
+		}
+	}
+}
+
+// transformIndex transforms the specified index on the specified revision into the index of the current revision.
+func (d *document) transformIndex(index, rev int) int {
+	for _, r := range d.history[rev-d.base:] {
+		index = r.op.transformIndex(index)
+	}
+
+	if 0 > index {
+		return 0
+	}
+	if index > len(d.text) {
+		return len(d.text)
+	}
+
+	return index
+}
+
+// sendCursors sends the cursors of the other editors to the specified editor.
+func (d *document) sendCursors(sid string) {
+	for editor, c := range d.cursors {
+		if editor != sid {
+			writeDocumentMessage(sid, c.message(d.editors[sid], editor))
+		}
+	}
+}
+
+// message returns the doc-cursor message of the cursor of the specified editor.
+func (c *cursor) message(path, sid string) map[string]interface{} {
+	return map[string]interface{}{"cmd": "doc-cursor", "path": path, "sid": sid, "user": c.user, "ranges": c.ranges}
+}
+
+// closeDocument handles doc-close messages.
+func closeDocument(sid, uid string, message map[string]interface{}) {
+	path, _ := message["path"].(string)
+	filePath, _ := GetPath(uid, path, "0")
+
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	if d := documents[filePath]; nil != d {
+		d.leave(sid)
+	}
+}
+
+// leaveDocuments removes the editors of the specified session from all documents.
+func leaveDocuments(sid string) {
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	for _, d := range documents {
+		d.leave(sid)
+	}
+}
+
+// leave removes the specified editor from the document.
+func (d *document) leave(sid string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.editors[sid]; !ok {
+		return
+	}
+
+	delete(d.editors, sid)
+	if 1 > len(d.editors) {
+		d.idle = time.Now()
+	}
+
+	if _, ok := d.cursors[sid]; ok {
+		delete(d.cursors, sid)
+		for editor, path := range d.editors {
+			writeDocumentMessage(editor, map[string]interface{}{"cmd": "doc-cursor", "path": path, "sid": sid,
+				"ranges": [][2]int{}})
+		}
+	}
+}
+
+// loadDocument gets the document of the specified file, loads it from the file if not being edited. Documents idle
+// longer than documentIdleTTL are released meanwhile. documentsMutex should be locked by the caller.
+func loadDocument(path string) (*document, error) {
+	for p, d := range documents {
+		d.mutex.Lock()
+		if 1 > len(d.editors) && documentIdleTTL < time.Since(d.idle) {
+			delete(documents, p)
+		}
+		d.mutex.Unlock()
+	}
+
+	if d := documents[path]; nil != d {
+		return d, nil
+	}
+
+	if gulu.File.GetFileSize(path) > conf.Wide().MaxFileSizeBytes() {
+		return nil, errors.New("This file is too large to open :(")
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+	content := string(buf)
+	if gulu.File.IsBinary(content) {
+		return nil, errors.New("Can't open a binary file :(")
+	}
+
+	ret := &document{text: editorText(content), editors: map[string]string{},
+		cursors: map[string]*cursor{}, idle: time.Now()}
+	documents[path] = ret
+
+	return ret, nil
+}
+
+// documentContent returns the content and the revision of the document of the specified file if the specified session
+// is editing it.
+func documentContent(path, sid string) (content string, rev int, ok bool) {
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	d := documents[path]
+	if nil == d {
+		return "", 0, false
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.editors[sid]; !ok {
+		return "", 0, false
+	}
+
+	return d.content(), d.rev(), true
+}
+
+// documentSaved marks the specified revision of the document of the specified file saved and tells the editors.
+func documentSaved(path string, rev int) {
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	d := documents[path]
+	if nil == d {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.markSaved(rev)
+}
+
+// syncDocument applies the change of the specified file made not by its editors (such as saved by another editor
+// without collaborative editing or checked out with git) to the document of the file and broadcasts it.
+func syncDocument(path, content string) {
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	d := documents[path]
+	if nil == d {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	op := diffOperation(d.text, editorText(content))
+	if !op.isNoop() {
+		var err error
+		if op, err = d.receive("", d.rev(), op); nil != err {
+			logger.Warnf("Syncs document [%s] with the file failed: %s", path, err)
+
+			return
+		}
+
+		d.broadcast("", op)
+	}
+
+	d.markSaved(d.rev())
+}
+
+// markSaved marks the specified revision saved and tells the editors.
+func (d *document) markSaved(rev int) {
+	d.saved = rev
+	for editor, path := range d.editors {
+		writeDocumentMessage(editor, map[string]interface{}{"cmd": "doc-saved", "path": path, "rev": rev})
+	}
+}
+
+// editorText returns the specified content as editors see it: in UTF-16 code units with line breaks normalized to
+// "\n" (saving from the editor has always normalized them).
+func editorText(content string) []uint16 {
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	content = strings.Replace(content, "\r", "\n", -1)
+
+	return utf16.Encode([]rune(content))
+}
+
+// writeDocumentMessage writes the specified message to the session channel of the specified session.
+func writeDocumentMessage(sid string, message map[string]interface{}) {
+	if wsChannel := session.SessionWS[sid]; nil != wsChannel {
+		wsChannel.WriteJSON(&message)
+	}
+}
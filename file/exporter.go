@@ -15,9 +15,11 @@
 package file
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 
@@ -25,6 +27,68 @@ import (
 	"github.com/kwokhunglee/wide/session"
 )
 
+// DownloadHandler handles request of downloading a file or directory. A file is streamed as is, a directory is zipped
+// on the fly as "{name}.zip" with entries under a top-level directory named after it, ".git" of repositories excluded.
+//
+// Query parameters: "path" and optional "pathtype" (see GetPath).
+func DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	q := r.URL.Query()
+	pathtype := q.Get("pathtype")
+	if "" == pathtype {
+		pathtype = "0"
+	}
+	path, _ := GetPath(uid, q.Get("path"), pathtype)
+	path = filepath.FromSlash(path)
+	if "" == path || (!gulu.Go.IsAPI(path) && !gulu.Go.IsPath(path) && !session.CanAccess(uid, path)) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	info, err := os.Stat(path)
+	if nil != err {
+		http.Error(w, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	name := filepath.Base(path)
+	if !info.IsDir() {
+		f, err := os.Open(path)
+		if nil != err {
+			logger.Error(err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name))
+		http.ServeContent(w, r, name, info.ModTime(), f)
+
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name+".zip"))
+	w.Header().Set("Content-Type", "application/zip")
+
+	zipWriter := zip.NewWriter(w)
+	defer zipWriter.Close()
+
+	if err := addZipDirectory(zipWriter, path, name, true); nil != err {
+		// the response has been partly written, the client gets a broken archive
+		logger.Errorf("Downloads [%s] of user [%s] failed: %s", path, uid, err)
+	}
+}
+
 // GetZipHandler handles request of retrieving zip file.
 func GetZipHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -21,13 +21,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/kwokhunglee/wide/conf"
 	"github.com/kwokhunglee/wide/event"
 	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
 	"github.com/kwokhunglee/wide/session"
 )
 
@@ -41,14 +44,19 @@ type Node struct {
 	Path      string  `json:"path"`
 	IconSkin  string  `json:"iconSkin"` // Value should be end with a space
 	IsParent  bool    `json:"isParent"`
-	Type      string  `json:"type"`      // "f": file, "d": directory
+	Type      string  `json:"type"`      // "f": file, "d": directory, "m": marker of more children (see moreNode)
 	Creatable bool    `json:"creatable"` // whether can create file in this file node
 	Removable bool    `json:"removable"` // whether can remove this file node
 	IsGoAPI   bool    `json:"isGOAPI"`
 	Mode      string  `json:"mode"`
-	GitClone  bool    `json:"gitClone"` //是否允许GITClone
-	GitRepo   bool    `json:"gitRepo"`  //是否GIT目录
+	GitClone  bool    `json:"gitClone"`  //是否允许GITClone
+	GitRepo   bool    `json:"gitRepo"`   //是否GIT目录
+	GitStatus string  `json:"gitStatus"` // git status: "modified", "untracked", "staged", "conflicted" or "" for clean
+	GitBranch string  `json:"gitBranch"` // current branch of the repository if GitRepo, short commit id if detached
+	Submodule bool    `json:"submodule"` // whether is the root of a git submodule
 	Pathtype  int     `json:"pathtype"`
+	More      int     `json:"more,omitempty"`      // offset of the children not loaded if the marker of more children
+	Remaining int     `json:"remaining,omitempty"` // number of the children not loaded if the marker of more children
 	Children  []*Node `json:"children"`
 }
 
@@ -63,21 +71,30 @@ type Snippet struct {
 var rootNode *Node
 var pathNode *Node
 
-// initAPINode builds the Go API file node.
+// initAPINode builds the Go API file node, or loads it from the cache.
 func initGoRoot() {
 	goRoot := gulu.Go.GetAPIPath()
-	rootNode = &Node{Name: "Go API", Path: goRoot, IconSkin: "ico-ztree-dir-api ", Type: "d",
-		Creatable: false, Removable: false, IsGoAPI: true, GitClone: false, GitRepo: false, Pathtype: 1, Children: []*Node{}}
-	logger.Debugf("initGoRoot goRoot [%s] ", goRoot)
-	walk(goRoot, goRoot, rootNode, false, false, true, 1)
+	rootNode = cachedTree("goroot", goRoot, func() *Node {
+		ret := &Node{Name: "Go API", Path: goRoot, IconSkin: "ico-ztree-dir-api ", Type: "d",
+			Creatable: false, Removable: false, IsGoAPI: true, GitClone: false, GitRepo: false, Pathtype: 1, Children: []*Node{}}
+		logger.Debugf("initGoRoot goRoot [%s] ", goRoot)
+		walk(goRoot, goRoot, ret, false, false, true, 1, 1, nil, 0, conf.Wide().TreeChildrenLimit())
+
+		return ret
+	})
 }
 
+// initGoPath builds the Go PATH file node, or loads it from the cache.
 func initGoPath() {
 	goPath := gulu.Go.GetPathPath()
-	pathNode = &Node{Name: "Go PATH", Path: goPath, IconSkin: "ico-ztree-dir-api ", Type: "d",
-		Creatable: false, Removable: false, IsGoAPI: true, GitClone: false, GitRepo: false, Pathtype: 2, Children: []*Node{}}
-	logger.Debugf("initGoPath goPath [%s] ", goPath)
-	walk(goPath, goPath, pathNode, false, false, true, 2)
+	pathNode = cachedTree("gopath", goPath, func() *Node {
+		ret := &Node{Name: "Go PATH", Path: goPath, IconSkin: "ico-ztree-dir-api ", Type: "d",
+			Creatable: false, Removable: false, IsGoAPI: true, GitClone: false, GitRepo: false, Pathtype: 2, Children: []*Node{}}
+		logger.Debugf("initGoPath goPath [%s] ", goPath)
+		walk(goPath, goPath, ret, false, false, true, 2, 1, nil, 0, conf.Wide().TreeChildrenLimit())
+
+		return ret
+	})
 }
 
 // GetFilesHandler handles request of constructing user workspace file tree.
@@ -101,6 +118,12 @@ func GetFilesHandler(w http.ResponseWriter, r *http.Request) {
 
 	root := Node{Name: "root", Path: "", IconSkin: "ico-ztree-dir ", Type: "d", Pathtype: pathtype, IsParent: true, GitClone: true, GitRepo: false, Children: []*Node{}}
 
+	// directories expanded or containing opened files in the latest session are loaded for restoring it
+	var content *conf.LatestSessionContent
+	if user := conf.GetUser(uid); nil != user {
+		content = user.LatestSessionContent
+	}
+
 	if nil == rootNode { // lazy init
 		initGoRoot()
 	}
@@ -127,12 +150,46 @@ func GetFilesHandler(w http.ResponseWriter, r *http.Request) {
 			Pathtype:  pathtype,
 			Children:  []*Node{}}
 
-		walk(workspacePath, workspacePath, &workspaceNode, true, true, false, pathtype)
+		walk(workspacePath, workspacePath, &workspaceNode, true, true, false, pathtype, conf.Wide().TreeDepth(),
+			expandedPaths(content, ""), 0, conf.Wide().TreeChildrenLimit())
+		decorateGitStatus(workspacePath, &workspaceNode)
 
 		// add workspace node
 		root.Children = append(root.Children, &workspaceNode)
 	}
 
+	// invited projects node process
+	for _, invitation := range session.InvitedProjects(uid) {
+		project := invitation.ProjectPath()
+		name := filepath.Base(filepath.FromSlash(invitation.Project))
+		if owner := conf.GetUser(invitation.Owner); nil != owner {
+			name = owner.Name + "/" + name
+		}
+
+		projectNode := Node{
+			Id:        "/" + invitation.Id,
+			Name:      name,
+			Path:      "/" + invitation.Id,
+			IconSkin:  "ico-ztree-dir-workspace ",
+			Type:      "d",
+			Creatable: true,
+			Removable: false,
+			IsParent:  true,
+			Pathtype:  3,
+			Children:  []*Node{}}
+
+		walk(project, project, &projectNode, true, true, false, 3, conf.Wide().TreeDepth(),
+			expandedPaths(content, "/"+invitation.Id), 0, conf.Wide().TreeChildrenLimit())
+		prefixNodePaths(&projectNode, "/"+invitation.Id)
+
+		root.Children = append(root.Children, &projectNode)
+	}
+
+	// scratches node process
+	if scratches := scratchesNode(uid, content); nil != scratches {
+		root.Children = append(root.Children, scratches)
+	}
+
 	// add Go API node
 
 	root.Children = append(root.Children, rootNode)
@@ -141,7 +198,55 @@ func GetFilesHandler(w http.ResponseWriter, r *http.Request) {
 	result.Data = root
 }
 
+// prefixNodePaths prefixes the ids and paths of the descendants of the specified node with the specified prefix.
+func prefixNodePaths(node *Node, prefix string) {
+	for _, child := range node.Children {
+		child.Id = prefix + child.Id
+		child.Path = prefix + child.Path
+		prefixNodePaths(child, prefix)
+	}
+}
+
+// expandedPaths returns the paths (relative to a tree root) of the directories needed to restore the specified session
+// content: the expanded directories, parents of the opened files and their ancestors. Only paths with the specified
+// prefix are returned, and the prefix is trimmed.
+func expandedPaths(content *conf.LatestSessionContent, prefix string) map[string]bool {
+	ret := map[string]bool{}
+	if nil == content {
+		return ret
+	}
+
+	paths := append([]string{}, content.FileTree...)
+	for _, file := range content.Files {
+		paths = append(paths, path.Dir(file))
+	}
+
+	for _, p := range paths {
+		if "" != prefix {
+			if !strings.HasPrefix(p, prefix+"/") {
+				continue
+			}
+			p = p[len(prefix):]
+		}
+
+		for ; "/" != p && "." != p && "" != p && !ret[p]; p = path.Dir(p) {
+			ret[p] = true
+		}
+	}
+
+	return ret
+}
+
 // RefreshDirectoryHandler handles request of refresh a directory of file tree.
+//
+// It's also used to load children of a directory on demand (zTree async) since the file tree is built lazily, paths
+// of the returned nodes are relative to the root of the tree as the ones returned by GetFilesHandler.
+//
+// With argument "version" (empty for the first time) only the changes of the children since the version are returned
+// (see treeDiff) instead of all the children.
+//
+// At most Wide.MaxTreeChildren children are returned, followed by a marker node (see moreNode) if there are more. With
+// argument "offset" the children from the offset are returned, it's used to load the remaining children on demand.
 func RefreshDirectoryHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
@@ -151,16 +256,16 @@ func RefreshDirectoryHandler(w http.ResponseWriter, r *http.Request) {
 	uid := httpSession.Values["uid"].(string)
 	r.ParseForm()
 
-	pathValue := r.FormValue("path")
-	pathtype := 0
-	if r.FormValue("pathtype") == "1" {
-		pathtype = 1
-	} else if r.FormValue("pathtype") == "2" {
-		pathtype = 2
+	// paths of the root nodes (workspaces, Go API and Go PATH) are absolute
+	nodePath := strings.TrimSuffix(r.FormValue("path"), "/")
+	pathValue, pathtype := GetPath(uid, nodePath, r.FormValue("pathtype"))
+	if isTreeRoot(uid, nodePath) {
+		_, pathtype = GetPath(uid, "", r.FormValue("pathtype"))
+		pathValue, nodePath = nodePath, ""
 	}
-	// pathValue, pathtype := GetPath(uid, r.FormValue("path"), r.FormValue("pathtype"))
 
-	if !gulu.Go.IsAPI(pathValue) && !gulu.Go.IsPath(pathValue) && !session.CanAccess(uid, pathValue) {
+	isGOAPI := 1 == pathtype || 2 == pathtype
+	if "" == pathValue || (!gulu.Go.IsAPI(pathValue) && !gulu.Go.IsPath(pathValue) && !session.CanAccess(uid, pathValue)) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -168,17 +273,28 @@ func RefreshDirectoryHandler(w http.ResponseWriter, r *http.Request) {
 	gitPath := filepath.Join(pathValue, ".git")
 	isGit := pathExists(gitPath)
 	node := Node{Name: "root", Path: pathValue, IconSkin: "ico-ztree-dir ", Type: "d", Pathtype: pathtype, GitClone: false, GitRepo: isGit, Children: []*Node{}}
+	if isGit {
+		node.GitBranch = gitHeadBranch(pathValue)
+		node.Submodule = isGitSubmodule(pathValue)
+	}
+
+	offset, _ := strconv.Atoi(r.FormValue("offset"))
+	if 0 > offset {
+		offset = 0
+	}
 
-	walk(pathValue, pathValue, &node, true, true, false, pathtype)
+	walk(pathValue, pathValue, &node, !isGOAPI, !isGOAPI, isGOAPI, pathtype, 1, nil, offset,
+		conf.Wide().TreeChildrenLimit())
+	decorateGitStatus(pathValue, &node)
+	prefixNodePaths(&node, nodePath)
+
+	if _, diff := r.Form["version"]; diff && 0 == offset {
+		gulu.Ret.RetGzValue(w, r, diffTree(uid, pathValue, r.FormValue("version"), node.Children))
 
-	w.Header().Set("Content-Type", "application/json")
-	data, err := json.Marshal(node.Children)
-	if err != nil {
-		logger.Error(err)
 		return
 	}
 
-	w.Write(data)
+	gulu.Ret.RetGzValue(w, r, node.Children)
 }
 
 // GetFileHandler handles request of opening file by editor.
@@ -211,8 +327,38 @@ func GetFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// a file at a git revision is opened as read-only
+	if revision, _ := args["revision"].(string); "" != revision {
+		if strings.HasPrefix(revision, "-") {
+			result.Code = -1
+
+			return
+		}
+
+		buf, err := gitShowFile(path, revision)
+		if nil != err {
+			logger.Warnf("Shows file [%s] at revision [%s] failed [%s]", path, revision, err)
+			result.Code = -1
+			result.Msg = "Can't open the file at revision " + revision + " :("
+
+			return
+		}
+
+		if gulu.File.IsBinary(string(buf)) {
+			result.Code = -1
+			result.Msg = "Can't open a binary file :("
+
+			return
+		}
+
+		result.Data = &map[string]interface{}{"content": string(buf), "path": path, "revision": revision,
+			"readonly": true}
+
+		return
+	}
+
 	size := gulu.File.GetFileSize(path)
-	if size > 5242880 { // 5M
+	if size > conf.Wide().MaxFileSizeBytes() {
 		result.Code = -1
 		result.Msg = "This file is too large to open :("
 
@@ -222,8 +368,6 @@ func GetFileHandler(w http.ResponseWriter, r *http.Request) {
 	data := map[string]interface{}{}
 	result.Data = &data
 
-	buf, _ := ioutil.ReadFile(path)
-
 	extension := filepath.Ext(path)
 
 	if gulu.File.IsImg(extension) {
@@ -241,23 +385,35 @@ func GetFileHandler(w http.ResponseWriter, r *http.Request) {
 
 		user := conf.GetUser(uid)
 
-		data["path"] = "/workspace/" + user.Name + "/" + strings.Replace(path, user.WorkspacePath(), "", 1)
+		data["path"] = conf.Wide().Context + "/workspace/" + user.Name + "/" + strings.Replace(path, user.WorkspacePath(), "", 1)
 
 		return
 	}
 
-	content := string(buf)
-
-	if gulu.File.IsBinary(content) {
+	// binaries are detected before reading the whole file
+	if binary, _ := isBinaryFile(path); binary {
 		result.Code = -1
 		result.Msg = "Can't open a binary file :("
-	} else {
-		data["content"] = content
-		data["path"] = path
+
+		return
 	}
+
+	buf, _ := ioutil.ReadFile(path)
+	content := string(buf)
+
+	data["content"] = content
+	data["path"] = path
+
+	sid, _ := args["sid"].(string)
+	setSaveBase(sid, path, content)
+	touchRecentFile(uid, path)
 }
 
 // SaveFileHandler handles request of saving file.
+//
+// The content to save is argument "code", or argument "patch" (a text operation) applied to the content this session
+// last opened or saved whose checksum (see textChecksum) is argument "base". Responds "outOfSync" if the patch can't be
+// applied, the client should save the whole content then.
 func SaveFileHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
@@ -290,6 +446,69 @@ func SaveFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// warns before editing inside a submodule at a detached HEAD, the client saves again with "force" if confirmed
+	if force, _ := args["force"].(bool); !force {
+		if submodule := detachedSubmodule(filePath); "" != submodule {
+			result.Code = -1
+			result.Msg = i18n.Get(conf.GetUser(uid).Locale, "git-submodule-detached").(string)
+			result.Data = map[string]interface{}{"submodule": filepath.ToSlash(submodule)}
+
+			return
+		}
+	}
+
+	// the buffer of the document is authoritative if the file is being edited collaboratively, otherwise the whole
+	// content is saved or a patch is applied to the content this session last opened or saved
+	content, rev, collaborative := documentContent(filePath, sid)
+	code := content
+	if !collaborative {
+		if patch, ok := args["patch"]; ok {
+			checksum, _ := args["base"].(float64)
+			if code, ok = patchSave(sid, filePath, patch, checksum, int(conf.Wide().MaxFileSizeBytes())); !ok {
+				result.Code = -1
+				result.Data = map[string]interface{}{"outOfSync": true}
+
+				return
+			}
+		} else {
+			code = args["code"].(string)
+		}
+	}
+
+	previous, readErr := ioutil.ReadFile(filePath)
+
+	// merges the changes saved by other sessions since this session opened the file
+	merged := false
+	if !collaborative && nil == readErr {
+		mergedCode, conflicts := mergeSave(sid, filePath, string(previous), code)
+		if 0 < conflicts {
+			setSaveBase(sid, filePath, string(previous))
+			notifySaveConflict(sid, uid, filePath)
+
+			result.Code = -1
+			result.Msg = i18n.Get(conf.GetUser(uid).Locale, "save-conflict").(string)
+			result.Data = map[string]interface{}{"content": mergedCode, "conflicts": conflicts}
+
+			return
+		}
+
+		merged = mergedCode != code
+		code = mergedCode
+	}
+
+	delta := int64(len(code))
+	if info, err := os.Stat(filePath); nil == err {
+		delta -= info.Size()
+	}
+	if 0 < delta {
+		if msg := quotaExceeded(uid, delta); "" != msg {
+			result.Code = -1
+			result.Msg = msg
+
+			return
+		}
+	}
+
 	fout, err := os.Create(filePath)
 
 	if nil != err {
@@ -299,20 +518,37 @@ func SaveFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code := args["code"].(string)
-
 	fout.WriteString(code)
+	addUsage(uid, delta)
 
 	if err := fout.Close(); nil != err {
 		logger.Error(err)
 		result.Code = -1
 
-		wSession := session.WideSessions.Get(sid)
-		wSession.EventQueue.Queue <- &event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
-			Data: "can't save file " + filePath}
+		event.Publish(&event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
+			Data: "can't save file " + filePath})
 
 		return
 	}
+
+	if collaborative {
+		documentSaved(filePath, rev)
+	} else {
+		syncDocument(filePath, code)
+	}
+
+	setSaveBase(sid, filePath, code)
+	fileContents.remove(filePath)
+	UpdateSymbols(filePath)
+	if merged {
+		result.Data = map[string]interface{}{"merged": code}
+	}
+
+	if nil == readErr {
+		reanchorReviewComments(filePath, string(previous), code)
+	}
+
+	fileChanged(sid, &event.FileChange{UserId: uid, Op: "save", Path: filePath})
 }
 
 // NewFileHandler handles request of creating file or directory.
@@ -336,8 +572,7 @@ func NewFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := args["path"].(string)
-	// path, _ := GetPath(uid, args["path"].(string), fmt.Sprint(args["pathtype"]))
+	path := resolvePath(uid, args["path"].(string), fmt.Sprint(args["pathtype"]))
 
 	if gulu.Go.IsAPI(path) || gulu.Go.IsPath(path) || !session.CanAccess(uid, path) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
@@ -347,23 +582,29 @@ func NewFileHandler(w http.ResponseWriter, r *http.Request) {
 	fileType := args["fileType"].(string)
 	sid := args["sid"].(string)
 
-	wSession := session.WideSessions.Get(sid)
+	if msg := quotaExceeded(uid, 0); "" != msg {
+		result.Code = -1
+		result.Msg = msg
+
+		return
+	}
 
 	if !createFile(path, fileType) {
 		result.Code = -1
 
-		wSession.EventQueue.Queue <- &event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
-			Data: "can't create file " + path}
+		event.Publish(&event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
+			Data: "can't create file " + path})
 
 		return
 	}
 
 	if "f" == fileType {
-		logger.Debugf("Created a file [%s] by user [%s]", path, wSession.UserId)
+		logger.Debugf("Created a file [%s] by user [%s]", path, uid)
 	} else {
-		logger.Debugf("Created a dir [%s] by user [%s]", path, wSession.UserId)
+		logger.Debugf("Created a dir [%s] by user [%s]", path, uid)
 	}
 
+	fileChanged(sid, &event.FileChange{UserId: uid, Op: "create", Path: path})
 }
 
 // RemoveFileHandler handles request of removing file or directory.
@@ -397,18 +638,17 @@ func RemoveFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	sid := args["sid"].(string)
 
-	wSession := session.WideSessions.Get(sid)
-
 	if !removeFile(path) {
 		result.Code = -1
 
-		wSession.EventQueue.Queue <- &event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
-			Data: "can't remove file " + path}
+		event.Publish(&event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
+			Data: "can't remove file " + path})
 
 		return
 	}
 
-	logger.Debugf("Removed a file [%s] by user [%s]", path, wSession.UserId)
+	logger.Debugf("Removed a file [%s] by user [%s]", path, uid)
+	fileChanged(sid, &event.FileChange{UserId: uid, Op: "remove", Path: path})
 }
 
 // RenameFileHandler handles request of renaming file or directory.
@@ -433,10 +673,8 @@ func RenameFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// oldPath, _ := GetPath(uid, args["oldPath"].(string), fmt.Sprint(args["pathtype"]))
-	// newPath, _ := GetPath(uid, args["newPath"].(string), fmt.Sprint(args["pathtype"]))
-	oldPath := args["oldPath"].(string)
-	newPath := args["newPath"].(string)
+	oldPath := resolvePath(uid, args["oldPath"].(string), fmt.Sprint(args["pathtype"]))
+	newPath := resolvePath(uid, args["newPath"].(string), fmt.Sprint(args["pathtype"]))
 
 	logger.Debugf("Renamed check [%s] to [%s] ", oldPath, newPath)
 	if gulu.Go.IsAPI(oldPath) || gulu.Go.IsPath(oldPath) ||
@@ -451,33 +689,56 @@ func RenameFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sid := args["sid"].(string)
-	wSession := session.WideSessions.Get(sid)
 
 	logger.Debugf("Renamed renameFile [%s] to [%s] ", oldPath, newPath)
 	if !renameFile(oldPath, newPath) {
 		result.Code = -1
-		wSession.EventQueue.Queue <- &event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
-			Data: "can't rename file " + oldPath}
+		event.Publish(&event.Event{Code: event.EvtCodeServerInternalError, Sid: sid,
+			Data: "can't rename file " + oldPath})
 		return
 	}
 
-	logger.Debugf("Renamed a file [%s] to [%s] by user [%s]", oldPath, newPath, wSession.UserId)
+	logger.Debugf("Renamed a file [%s] to [%s] by user [%s]", oldPath, newPath, uid)
+	fileChanged(sid, &event.FileChange{UserId: uid, Op: "rename", Path: oldPath, NewPath: newPath})
+}
+
+// fileChanged emits event EvtCodeFileChanged of the specified change to the specified wide session.
+func fileChanged(sid string, change *event.FileChange) {
+	event.Publish(&event.Event{Code: event.EvtCodeFileChanged, Sid: sid, Data: change})
 }
 
 // Use to find results sorting.
 type foundPath struct {
 	Path     string `json:"path"`
 	score    int
+	recent   int // rank of the recently opened files, the latest is the highest, 0 if not opened recently
 	pathtype int
 }
 
+// before determines whether the found path is ranked before the specified one: recently opened files first, then the
+// ones with higher scores.
+func (f *foundPath) before(another *foundPath) bool {
+	if f.recent != another.recent {
+		return f.recent > another.recent
+	}
+	if f.score != another.score {
+		return f.score > another.score
+	}
+
+	return f.Path < another.Path
+}
+
 type foundPaths []*foundPath
 
 func (f foundPaths) Len() int           { return len(f) }
 func (f foundPaths) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
-func (f foundPaths) Less(i, j int) bool { return f[i].score > f[j].score }
+func (f foundPaths) Less(i, j int) bool { return f[i].before(f[j]) }
 
 // FindHandler handles request of find files under the specified directory with the specified filename pattern.
+//
+// Files are found in the path indexes of the workspaces (see pathIndex), the workspaces are walked if they can't be
+// indexed. Only the top ranked ones are kept (see topFoundPaths) and returned in pages of findPageSize, argument
+// "page" starts from 1.
 func FindHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
@@ -488,7 +749,7 @@ func FindHandler(w http.ResponseWriter, r *http.Request) {
 	uid := httpSession.Values["uid"].(string)
 
 	result := gulu.Ret.NewResult()
-	defer gulu.Ret.RetResult(w, r, result)
+	defer gulu.Ret.RetGzResult(w, r, result)
 
 	var args map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
@@ -516,24 +777,50 @@ func FindHandler(w http.ResponseWriter, r *http.Request) {
 		path = filepath.Dir(path)
 	}
 
-	founds := foundPaths{}
+	page := 1
+	if p, ok := args["page"].(float64); ok && 1 < p {
+		page = int(p)
+	}
+	top := newTopFoundPaths(minInt(page*findPageSize, maxFindResults))
+	recents := recentFileRanks(uid)
 
 	for _, workspace := range workspaces {
-		rs := find(workspace+conf.PathSeparator+"src", name, []*string{})
+		root := workspace + conf.PathSeparator + "src"
+		rs, ok := indexedFiles(root, name)
+		if !ok {
+			rs = []string{}
+			for _, r := range find(root, name, []*string{}) {
+				rs = append(rs, *r)
+			}
+		}
 
 		for _, r := range rs {
-			substr := gulu.Str.LCS(path, *r)
+			found := &foundPath{Path: filepath.ToSlash(r), recent: recents[filepath.ToSlash(r)]}
+			if top.full() && 0 == found.recent && !top.mayRank(found) {
+				top.skip() // the score is at most the length of the path, skips computing it
 
-			founds = append(founds, &foundPath{Path: filepath.ToSlash(*r), score: len(substr)})
+				continue
+			}
+			found.score = len(gulu.Str.LCS(path, r))
+
+			top.add(found)
 		}
 	}
 
-	sort.Sort(founds)
+	founds, more := top.page(page, findPageSize)
 
-	result.Data = founds
+	result.Data = map[string]interface{}{"paths": founds, "more": more}
 }
 
 // SearchTextHandler handles request of searching files under the specified directory with the specified keyword.
+//
+// The keyword is matched case-insensitively as a plain text by default, optional arguments "regex" (the keyword is a
+// Go regular expression), "caseSensitive" and "wholeWord" change the matching. An invalid regular expression is
+// reported as the message of the result.
+//
+// Snippets are returned in pages of at most Wide.MaxSearchResults, the scanning stops once a page is full. The
+// returned "token" continues the search for the next page if not empty. Files of workspaces which can't contain the
+// keyword are skipped with the text indexes (see textIndex).
 func SearchTextHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
@@ -541,9 +828,10 @@ func SearchTextHandler(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	uid := httpSession.Values["uid"].(string)
 
 	result := gulu.Ret.NewResult()
-	defer gulu.Ret.RetResult(w, r, result)
+	defer gulu.Ret.RetGzResult(w, r, result)
 
 	var args map[string]interface{}
 
@@ -554,40 +842,99 @@ func SearchTextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sid := args["sid"].(string)
-	wSession := session.WideSessions.Get(sid)
-	if nil == wSession {
-		result.Code = -1
+	var cursor *searchCursor
+	if token, _ := args["token"].(string); "" != token {
+		cursor = takeSearchCursor(uid, token)
+		if nil == cursor {
+			result.Code = -1
+			result.Msg = i18n.Get(conf.GetUser(uid).Locale, "search-expired").(string)
 
-		return
-	}
+			return
+		}
+	} else {
+		// XXX: just one directory
+
+		// dir := args["dir"].(string)
+		dir, _ := GetPath(uid, args["dir"].(string), fmt.Sprint(args["pathtype"]))
+		if "" == dir {
+			userWorkspace := conf.GetUserWorkspace(uid)
+			workspaces := filepath.SplitList(userWorkspace)
+			dir = workspaces[0]
+		}
 
-	// XXX: just one directory
+		if !gulu.Go.IsAPI(dir) && !gulu.Go.IsPath(dir) && !session.CanAccess(uid, dir) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
 
-	// dir := args["dir"].(string)
-	dir, _ := GetPath(sid, args["dir"].(string), fmt.Sprint(args["pathtype"]))
-	if "" == dir {
-		userWorkspace := conf.GetUserWorkspace(wSession.UserId)
-		workspaces := filepath.SplitList(userWorkspace)
-		dir = workspaces[0]
+			return
+		}
+
+		extension := args["extension"].(string)
+		text := args["text"].(string)
+		regex, _ := args["regex"].(bool)
+		caseSensitive, _ := args["caseSensitive"].(bool)
+		wholeWord, _ := args["wholeWord"].(bool)
+
+		matcher, err := newSearchMatcher(text, regex, caseSensitive, wholeWord)
+		if nil != err {
+			result.Code = -1
+			result.Msg = i18n.Get(conf.GetUser(uid).Locale, "search-regex-invalid",
+				i18n.Params{"err": err.Error()}).(string)
+
+			return
+		}
+
+		cursor = &searchCursor{uid: uid, extension: extension, matcher: matcher,
+			maxSize: conf.Wide().MaxFileSizeBytes(), dirs: []string{}, snippets: []*Snippet{}}
+		if gulu.File.IsDir(dir) {
+			cursor.dirs = append(cursor.dirs, dir)
+			cursor.index = textIndexOf(dir)
+		} else {
+			cursor.snippets = searchInFile(dir, matcher, cursor.maxSize)
+		}
 	}
 
-	extension := args["extension"].(string)
-	text := args["text"].(string)
+	founds, token := searchPage(cursor, conf.Wide().SearchPageSize())
 
-	founds := []*Snippet{}
-	if gulu.File.IsDir(dir) {
-		founds = search(dir, extension, text, []*Snippet{})
-	} else {
-		founds = searchInFile(dir, text)
+	result.Data = map[string]interface{}{"snippets": founds, "token": token}
+}
+
+// isTreeRoot determines whether the specified path is the path of a root node of the file tree of the specified user.
+func isTreeRoot(uid, path string) bool {
+	roots := []string{gulu.Go.GetAPIPath(), gulu.Go.GetPathPath()}
+	for _, workspace := range filepath.SplitList(conf.GetUserWorkspace(uid)) {
+		roots = append(roots, workspace+conf.PathSeparator+"src")
 	}
 
-	result.Data = founds
+	for _, root := range roots {
+		if filepath.ToSlash(root) == path {
+			return true
+		}
+	}
+
+	return false
 }
 
 // walk traverses the specified path to build a file tree.
-func walk(path, rootpath string, node *Node, creatable, removable, isGOAPI bool, pathtype int) {
+//
+// Only the specified depth of levels are traversed, children of the deeper directories are left empty to be loaded on
+// demand via RefreshDirectoryHandler, a non-positive depth means no limit. Directories whose paths (relative to the
+// specified root path) are in the specified expanded paths are traversed regardless of the depth.
+//
+// Children of the specified path from the specified offset are traversed, at most the specified limit of children are
+// added to each directory (a non-positive limit means no limit) and a marker node is added after them if there are more.
+func walk(path, rootpath string, node *Node, creatable, removable, isGOAPI bool, pathtype, depth int,
+	expanded map[string]bool, offset, limit int) {
 	files := listFiles(path)
+	if offset > len(files) {
+		offset = len(files)
+	}
+	files = files[offset:]
+
+	remaining := 0
+	if 0 < limit && limit < len(files) {
+		remaining = len(files) - limit
+		files = files[:limit]
+	}
 
 	for _, filename := range files {
 		fpath := filepath.Join(path, filename)
@@ -617,8 +964,16 @@ func walk(path, rootpath string, node *Node, creatable, removable, isGOAPI bool,
 			child.GitClone = false
 			gitPath := filepath.Join(fpath, ".git")
 			child.GitRepo = pathExists(gitPath)
+			if child.GitRepo {
+				child.GitBranch = gitHeadBranch(fpath)
+				child.Submodule = isGitSubmodule(fpath)
+			}
 
-			walk(fpath, rootpath, &child, creatable, removable, isGOAPI, pathtype)
+			if 1 != depth {
+				walk(fpath, rootpath, &child, creatable, removable, isGOAPI, pathtype, depth-1, expanded, 0, limit)
+			} else if expanded[child.Path] {
+				walk(fpath, rootpath, &child, creatable, removable, isGOAPI, pathtype, 1, expanded, 0, limit)
+			}
 		} else {
 			child.Type = "f"
 			child.Creatable = creatable
@@ -628,9 +983,43 @@ func walk(path, rootpath string, node *Node, creatable, removable, isGOAPI bool,
 		}
 	}
 
+	if 0 < remaining {
+		node.Children = append(node.Children, moreNode(filepath.ToSlash(path)[len(rootpath):], offset+len(files),
+			remaining))
+	}
+
 	return
 }
 
+// moreNode returns the marker node appended to the children of the directory specified by the path (relative to the
+// tree root) if there are more children than loaded, the remaining children from the specified offset are loaded on
+// demand via RefreshDirectoryHandler.
+//
+// Path of the marker is the directory path with a trailing "/", which never equals to the path of a file.
+func moreNode(dir string, offset, remaining int) *Node {
+	return &Node{
+		Id:        dir + "/",
+		Name:      "...",
+		Path:      dir + "/",
+		IconSkin:  "ico-ztree-more ",
+		Type:      "m",
+		More:      offset,
+		Remaining: remaining,
+		Children:  []*Node{}}
+}
+
+// resolvePath resolves the specified path of a file to create or rename. Absolute paths (the ones in the file tree of
+// the web UI) are returned as is, other paths (the ones the REST API accepts) are resolved with GetPath.
+func resolvePath(uid, pathValue, pathtype string) string {
+	if "3" != pathtype && "4" != pathtype && filepath.IsAbs(pathValue) {
+		return pathValue
+	}
+
+	ret, _ := GetPath(uid, pathValue, pathtype)
+
+	return ret
+}
+
 func GetPath(uid, pathValue, pathtype string) (string, int) {
 	logger.Debugf("User [%s] getPath pathtype:[%s] getPath [%s] ", uid, pathtype, pathValue)
 	if pathtype == "0" {
@@ -653,6 +1042,22 @@ func GetPath(uid, pathValue, pathtype string) (string, int) {
 		pathValue = filepath.ToSlash(pathValue)
 		logger.Debugf("User [%s] pathtype:[%s] getPath [%s] ", uid, pathtype, pathValue)
 		return pathValue, 2
+	} else if pathtype == "3" { // "/{invitation id}/{path relative to the invited project}"
+		parts := strings.SplitN(strings.TrimPrefix(filepath.ToSlash(pathValue), "/"), "/", 2)
+		if project := session.InvitedProject(uid, parts[0]); "" != project {
+			rel := ""
+			if 1 < len(parts) {
+				rel = filepath.FromSlash(parts[1])
+			}
+			pathValue = filepath.ToSlash(filepath.Join(project, filepath.Clean(string(filepath.Separator)+rel)))
+			logger.Debugf("User [%s] pathtype:[%s] getPath [%s] ", uid, pathtype, pathValue)
+			return pathValue, 3
+		}
+	} else if pathtype == "4" { // "/scratches/{path relative to the user's scratches}"
+		if pathValue = scratchPath(scratchesPath(uid), pathValue); "" != pathValue {
+			logger.Debugf("User [%s] pathtype:[%s] getPath [%s] ", uid, pathtype, pathValue)
+			return pathValue, 4
+		}
 	}
 
 	logger.Debugf("User [%s] pathtype:[%s] getPath [%s] ", uid, "-1", "")
@@ -859,69 +1264,3 @@ func find(dir, name string, results []*string) []*string {
 
 	return results
 }
-
-// search finds file under the specified dir and its sub-directories with the specified text, likes the command 'grep'
-// or 'findstr'.
-func search(dir, extension, text string, snippets []*Snippet) []*Snippet {
-	if !strings.HasSuffix(dir, conf.PathSeparator) {
-		dir += conf.PathSeparator
-	}
-
-	f, _ := os.Open(dir)
-	fileInfos, err := f.Readdir(-1)
-	f.Close()
-
-	if nil != err {
-		logger.Errorf("Read dir [%s] failed: [%s]", dir, err.Error())
-
-		return snippets
-	}
-
-	for _, fileInfo := range fileInfos {
-		path := dir + fileInfo.Name()
-
-		if fileInfo.IsDir() {
-			// enter the directory recursively
-			snippets = search(path, extension, text, snippets)
-		} else if strings.HasSuffix(path, extension) {
-			// grep in file
-			ss := searchInFile(path, text)
-
-			snippets = append(snippets, ss...)
-		}
-	}
-
-	return snippets
-}
-
-// searchInFile finds file with the specified path and text.
-func searchInFile(path string, text string) []*Snippet {
-	ret := []*Snippet{}
-
-	bytes, err := ioutil.ReadFile(path)
-	if nil != err {
-		logger.Errorf("Read file [%s] failed: [%s]", path, err.Error())
-
-		return ret
-	}
-
-	content := string(bytes)
-	if gulu.File.IsBinary(content) {
-		return ret
-	}
-
-	lines := strings.Split(content, "\n")
-
-	for idx, line := range lines {
-		ch := strings.Index(strings.ToLower(line), strings.ToLower(text))
-
-		if -1 != ch {
-			snippet := &Snippet{Path: filepath.ToSlash(path),
-				Line: idx + 1, Ch: ch + 1, Contents: []string{line}}
-
-			ret = append(ret, snippet)
-		}
-	}
-
-	return ret
-}
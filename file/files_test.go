@@ -0,0 +1,137 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b/c", "d/e"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); nil != err {
+			t.Fatal(err)
+		}
+	}
+	rootPath := filepath.ToSlash(root)
+
+	paths := func(node *Node) []string {
+		ret := []string{}
+		var collect func(*Node)
+		collect = func(n *Node) {
+			for _, child := range n.Children {
+				ret = append(ret, child.Path)
+				collect(child)
+			}
+		}
+		collect(node)
+
+		return ret
+	}
+
+	cases := []struct {
+		depth    int
+		expanded map[string]bool
+		expected []string
+	}{
+		{1, nil, []string{"/a", "/d"}},
+		{2, nil, []string{"/a", "/a/b", "/d", "/d/e"}},
+		{0, nil, []string{"/a", "/a/b", "/a/b/c", "/d", "/d/e"}},
+		{1, map[string]bool{"/a": true, "/a/b": true}, []string{"/a", "/a/b", "/a/b/c", "/d"}},
+	}
+
+	for _, c := range cases {
+		node := &Node{Children: []*Node{}}
+		walk(rootPath, rootPath, node, true, true, false, 0, c.depth, c.expanded, 0, 0)
+		if actual := paths(node); !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("walk with depth %d and expanded %v: expected %v, actual %v", c.depth, c.expanded, c.expected,
+				actual)
+		}
+	}
+}
+
+func TestWalkLimit(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0755); nil != err {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"x", "y", "z"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), nil, 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+	rootPath := filepath.ToSlash(root)
+
+	cases := []struct {
+		offset, limit int
+		expected      []string
+		more          int
+		remaining     int
+	}{
+		{0, 0, []string{"/a", "/b", "/x", "/y", "/z"}, 0, 0},
+		{0, 2, []string{"/a", "/b", "/"}, 2, 3},
+		{2, 2, []string{"/x", "/y", "/"}, 4, 1},
+		{4, 2, []string{"/z"}, 0, 0},
+		{9, 2, []string{}, 0, 0},
+	}
+
+	for _, c := range cases {
+		node := &Node{Children: []*Node{}}
+		walk(rootPath, rootPath, node, true, true, false, 0, 1, nil, c.offset, c.limit)
+
+		actual := []string{}
+		for _, child := range node.Children {
+			actual = append(actual, child.Path)
+		}
+		if !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("walk from %d with limit %d: expected %v, actual %v", c.offset, c.limit, c.expected, actual)
+
+			continue
+		}
+
+		more, remaining := 0, 0
+		if 0 < len(node.Children) {
+			if last := node.Children[len(node.Children)-1]; "m" == last.Type {
+				more, remaining = last.More, last.Remaining
+			}
+		}
+		if c.more != more || c.remaining != remaining {
+			t.Errorf("walk from %d with limit %d: expected more %d (%d remaining), actual %d (%d remaining)",
+				c.offset, c.limit, c.more, c.remaining, more, remaining)
+		}
+	}
+}
+
+func TestExpandedPaths(t *testing.T) {
+	content := &conf.LatestSessionContent{FileTree: []string{"/a/b", "/inv/x"}, Files: []string{"/c/d/main.go"}}
+
+	expected := map[string]bool{"/a": true, "/a/b": true, "/c": true, "/c/d": true, "/inv": true, "/inv/x": true}
+	if actual := expandedPaths(content, ""); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+
+	expected = map[string]bool{"/x": true}
+	if actual := expandedPaths(content, "/inv"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"container/heap"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// findPageSize is the number of paths returned per page by FindHandler.
+	findPageSize = 50
+
+	// maxFindResults is the max number of paths could be paged by FindHandler.
+	maxFindResults = 500
+
+	// maxRecentFiles is the max number of recently opened files kept per user.
+	maxRecentFiles = 20
+)
+
+var (
+	// paths of recently opened files of users, <user id, paths, the latest first>
+	recentFiles = map[string][]string{}
+
+	// guards recentFiles
+	recentFilesMutex sync.Mutex
+)
+
+// touchRecentFile records the specified file as the latest opened file of the specified user.
+func touchRecentFile(uid, path string) {
+	path = filepath.ToSlash(path)
+
+	recentFilesMutex.Lock()
+	defer recentFilesMutex.Unlock()
+
+	files := []string{path}
+	for _, file := range recentFiles[uid] {
+		if file != path && len(files) < maxRecentFiles {
+			files = append(files, file)
+		}
+	}
+	recentFiles[uid] = files
+}
+
+// recentFileRanks returns the ranks of the recently opened files of the specified user, <path, rank>, the latest
+// opened file has the highest rank.
+func recentFileRanks(uid string) map[string]int {
+	recentFilesMutex.Lock()
+	defer recentFilesMutex.Unlock()
+
+	files := recentFiles[uid]
+	ret := map[string]int{}
+	for i, file := range files {
+		ret[file] = len(files) - i
+	}
+
+	return ret
+}
+
+// topFoundPaths keeps the top K found paths (see foundPath.before) with a min-heap, so not all found paths are sorted.
+type topFoundPaths struct {
+	k     int
+	heap  foundPathHeap
+	total int // number of the added paths
+}
+
+// newTopFoundPaths creates a topFoundPaths keeping the specified number of paths.
+func newTopFoundPaths(k int) *topFoundPaths {
+	return &topFoundPaths{k: k, heap: foundPathHeap{}}
+}
+
+// full determines whether the top K paths have been found.
+func (t *topFoundPaths) full() bool {
+	return len(t.heap) >= t.k
+}
+
+// mayRank determines whether the specified not recently opened path may be ranked in the top K once its score is
+// computed, the score is at most the length of the path.
+func (t *topFoundPaths) mayRank(found *foundPath) bool {
+	lowest := t.heap[0]
+
+	return 0 == lowest.recent && len(found.Path) >= lowest.score
+}
+
+// add adds the specified path, the lowest ranked path is dropped if there are more than K.
+func (t *topFoundPaths) add(found *foundPath) {
+	t.total++
+	if 0 >= t.k {
+		return
+	}
+
+	if !t.full() {
+		heap.Push(&t.heap, found)
+
+		return
+	}
+
+	if found.before(t.heap[0]) {
+		t.heap[0] = found
+		heap.Fix(&t.heap, 0)
+	}
+}
+
+// skip counts a path which is found but can't be ranked in the top K.
+func (t *topFoundPaths) skip() {
+	t.total++
+}
+
+// page returns the paths of the specified page (starts from 1) of the specified size ordered by rank, and whether
+// there are more pages.
+func (t *topFoundPaths) page(page, size int) (foundPaths, bool) {
+	ret := append(foundPaths{}, t.heap...)
+	sort.Sort(ret)
+
+	start := minInt((page-1)*size, len(ret))
+	end := minInt(start+size, len(ret))
+
+	return ret[start:end], end < t.total && end < maxFindResults
+}
+
+// foundPathHeap is a min-heap of found paths, the lowest ranked one is at the top.
+type foundPathHeap []*foundPath
+
+func (h foundPathHeap) Len() int            { return len(h) }
+func (h foundPathHeap) Less(i, j int) bool  { return h[j].before(h[i]) }
+func (h foundPathHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *foundPathHeap) Push(x interface{}) { *h = append(*h, x.(*foundPath)) }
+func (h *foundPathHeap) Pop() interface{} {
+	old := *h
+	ret := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return ret
+}
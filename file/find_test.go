@@ -0,0 +1,73 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTopFoundPaths(t *testing.T) {
+	top := newTopFoundPaths(4)
+	for i := 0; i < 10; i++ {
+		top.add(&foundPath{Path: fmt.Sprintf("/p%d", i), score: i})
+	}
+	top.add(&foundPath{Path: "/recent", score: 0, recent: 1})
+
+	paths, more := top.page(1, 3)
+	if 3 != len(paths) || !more {
+		t.Fatalf("page 1: %d paths, more %v", len(paths), more)
+	}
+	expected := []string{"/recent", "/p9", "/p8", "/p7"}
+	for i, p := range paths {
+		if expected[i] != p.Path {
+			t.Errorf("page 1 [%d]: expected %s, got %s", i, expected[i], p.Path)
+		}
+	}
+
+	paths, more = top.page(2, 3)
+	if 1 != len(paths) || "/p7" != paths[0].Path {
+		t.Fatalf("page 2: unexpected %v", paths)
+	}
+	if !more {
+		t.Error("page 2: expected more paths beyond the kept ones")
+	}
+
+	paths, _ = top.page(3, 3)
+	if 0 != len(paths) {
+		t.Errorf("page 3: expected no paths, got %d", len(paths))
+	}
+}
+
+func TestRecentFileRanks(t *testing.T) {
+	uid := "test-recent"
+	defer func() {
+		recentFilesMutex.Lock()
+		delete(recentFiles, uid)
+		recentFilesMutex.Unlock()
+	}()
+
+	touchRecentFile(uid, "/a")
+	touchRecentFile(uid, "/b")
+	touchRecentFile(uid, "/a")
+
+	ranks := recentFileRanks(uid)
+	if 2 != len(ranks) || ranks["/a"] <= ranks["/b"] || 0 == ranks["/b"] {
+		t.Errorf("unexpected ranks %v", ranks)
+	}
+	if 0 != ranks["/c"] {
+		t.Error("expected no rank for a file not opened")
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+var (
+	// sessions following others, <follower sid, host sid>
+	following = map[string]string{}
+
+	// guards following
+	followMutex sync.Mutex
+)
+
+// LoadFollow registers the handlers of follow mode messages sent via session channels. A follower's editor tracks the
+// open file and the scroll position of the host session, a session could follow another one of the same user or of a
+// collaborator editing the same document.
+//
+//  1. follow: starts following the session "sid", replied with following, or follow-stopped if not allowed
+//  2. unfollow: stops following
+//  3. nav: the open file and the top line of the host, relayed to the followers with paths in their workspaces
+//
+// Hosts are told the number of their followers with followers messages so that they send nav only if followed.
+func LoadFollow() {
+	session.SessionMessageHandlers["follow"] = followSession
+	session.SessionMessageHandlers["unfollow"] = unfollowSession
+	session.SessionMessageHandlers["nav"] = navigate
+	session.SessionClosedHandlers = append(session.SessionClosedHandlers, leaveFollowing)
+}
+
+// followSession handles follow messages.
+func followSession(sid, uid string, message map[string]interface{}) {
+	host, _ := message["sid"].(string)
+	hostSession := session.WideSessions.Get(host)
+	if nil == hostSession || host == sid || (hostSession.UserId != uid && !coediting(sid, host)) {
+		writeDocumentMessage(sid, map[string]interface{}{"cmd": "follow-stopped", "sid": host,
+			"msg": i18n.Get(conf.GetUser(uid).Locale, "follow-forbidden")})
+
+		return
+	}
+
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	if previous, ok := following[sid]; ok && previous != host {
+		delete(following, sid)
+		notifyFollowers(previous)
+	}
+	following[sid] = host
+
+	user := hostSession.UserId
+	if u := conf.GetUser(user); nil != u && "" != u.Name {
+		user = u.Name
+	}
+	writeDocumentMessage(sid, map[string]interface{}{"cmd": "following", "sid": host, "user": user})
+	notifyFollowers(host)
+}
+
+// unfollowSession handles unfollow messages.
+func unfollowSession(sid, uid string, message map[string]interface{}) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	if host, ok := following[sid]; ok {
+		delete(following, sid)
+		notifyFollowers(host)
+	}
+}
+
+// navigate handles nav messages.
+func navigate(sid, uid string, message map[string]interface{}) {
+	path, _ := message["path"].(string)
+	filePath, _ := GetPath(uid, path, "0")
+
+	followMutex.Lock()
+	followers := []string{}
+	for follower, host := range following {
+		if host == sid {
+			followers = append(followers, follower)
+		}
+	}
+	followMutex.Unlock()
+
+	for _, follower := range followers {
+		followerSession := session.WideSessions.Get(follower)
+		if nil == followerSession || !session.CanAccess(followerSession.UserId, filePath) {
+			continue
+		}
+
+		workspace := filepath.SplitList(conf.GetUserWorkspace(followerSession.UserId))[0]
+		rel, err := filepath.Rel(filepath.Join(workspace, "src"), filepath.FromSlash(filePath))
+		if nil != err || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		writeDocumentMessage(follower, map[string]interface{}{"cmd": "nav", "sid": sid, "path": filepath.ToSlash(rel),
+			"top": message["top"]})
+	}
+}
+
+// leaveFollowing stops the following of the specified session and the following of it.
+func leaveFollowing(sid string) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	if host, ok := following[sid]; ok {
+		delete(following, sid)
+		notifyFollowers(host)
+	}
+
+	for follower, host := range following {
+		if host == sid {
+			delete(following, follower)
+			writeDocumentMessage(follower, map[string]interface{}{"cmd": "follow-stopped", "sid": sid})
+		}
+	}
+}
+
+// notifyFollowers tells the specified host the number of its followers. followMutex should be locked by the caller.
+func notifyFollowers(host string) {
+	count := 0
+	for _, h := range following {
+		if h == host {
+			count++
+		}
+	}
+
+	writeDocumentMessage(host, map[string]interface{}{"cmd": "followers", "count": count})
+}
+
+// coediting checks whether the specified sessions are editing the same document.
+func coediting(sid1, sid2 string) bool {
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+
+	for _, d := range documents {
+		d.mutex.Lock()
+		_, ok1 := d.editors[sid1]
+		_, ok2 := d.editors[sid2]
+		d.mutex.Unlock()
+
+		if ok1 && ok2 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/parnurzeal/gorequest"
+)
+
+// scpLikeURLPattern matches scp-like git URLs such as "git@github.com:owner/repo.git".
+var scpLikeURLPattern = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.+)$`)
+
+// GitForgeTokenHandler handles request of setting the user's API token of a forge (GitHub, GitLab, etc).
+//
+// Arguments: "host" (for example "github.com") and "token", an empty token revokes the stored one. The token is
+// encrypted in the vault.
+func GitForgeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	host := strings.ToLower(strings.TrimSpace(args["host"]))
+	if "" == host {
+		result.Code = -1
+
+		return
+	}
+
+	if err := user.SetForgeToken(host, strings.TrimSpace(args["token"])); nil != err {
+		logger.Errorf("Sets forge token of user [%s] failed: %s", user.Name, err)
+		result.Code = -1
+
+		return
+	}
+
+	if !user.Save() {
+		result.Code = -1
+	}
+}
+
+// GitPullRequestHandler handles request of pushing the current branch and opening a pull request (GitHub) or a
+// merge request (GitLab) for it.
+//
+// Arguments: "title", optional "body", "base" (target branch, defaults to the default branch of the project),
+// "remote" (defaults to "origin") and "forge" ("github" or "gitlab", detected from the remote host if not specified).
+// Result data is the URL of the created request.
+func GitPullRequestHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	title := req.arg("title")
+	if "" == title {
+		result.Code = -1
+		result.Msg = req.msg("git-pr-empty-title")
+
+		return
+	}
+
+	remote := req.arg("remote")
+	if "" == remote {
+		remote = "origin"
+	}
+	if strings.HasPrefix(remote, "-") {
+		result.Code = -1
+
+		return
+	}
+
+	out, err := gitCommand(req.repo, "remote", "get-url", remote).Output()
+	if nil != err {
+		result.Code = -1
+		result.Msg = req.msg("git-remote-unreachable")
+
+		return
+	}
+
+	host, project := parseRemoteURL(strings.TrimSpace(string(out)))
+	forge := req.arg("forge")
+	if "" == forge {
+		switch {
+		case "github.com" == host:
+			forge = "github"
+		case strings.Contains(host, "gitlab"):
+			forge = "gitlab"
+		}
+	}
+	if "" == host || "" == project || ("github" != forge && "gitlab" != forge) {
+		result.Code = -1
+		result.Msg = req.msg("git-forge-unsupported")
+
+		return
+	}
+
+	token := req.user.GetForgeToken(host)
+	if "" == token {
+		result.Code = -1
+		result.Msg = req.msg("git-forge-no-token")
+
+		return
+	}
+
+	head := gitReadHead(req.repo)
+	if !strings.HasPrefix(head, "ref: refs/heads/") {
+		result.Code = -1
+		result.Msg = req.msg("git-detached-head")
+
+		return
+	}
+	branch := strings.TrimPrefix(head, "ref: refs/heads/")
+
+	gitPushRef(req, result, remote, "refs/heads/"+branch+":refs/heads/"+branch)
+	if 0 != result.Code {
+		return
+	}
+
+	var prURL string
+	if "github" == forge {
+		prURL, err = createGitHubPullRequest(host, project, token, branch, req.arg("base"), title, req.arg("body"))
+	} else {
+		prURL, err = createGitLabMergeRequest(host, project, token, branch, req.arg("base"), title, req.arg("body"))
+	}
+	if nil != err {
+		logger.Warnf("Creates pull request of [%s/%s] failed: %s", host, project, err)
+		result.Code = -1
+		result.Msg = req.msg("git-pr-failed") + err.Error()
+
+		return
+	}
+
+	result.Data = prURL
+}
+
+// parseRemoteURL parses the host and the project path (for example "owner/repo") from the specified git remote URL.
+func parseRemoteURL(remoteURL string) (host, project string) {
+	if m := scpLikeURLPattern.FindStringSubmatch(remoteURL); nil != m && !strings.Contains(remoteURL, "://") {
+		host, project = m[1], m[2]
+	} else {
+		u, err := url.Parse(remoteURL)
+		if nil != err {
+			return "", ""
+		}
+
+		host, project = u.Hostname(), u.Path
+	}
+
+	project = strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+
+	return strings.ToLower(host), project
+}
+
+// createGitHubPullRequest creates a pull request on GitHub, returns its URL.
+func createGitHubPullRequest(host, project, token, head, base, title, body string) (string, error) {
+	api := "https://api.github.com/repos/" + project
+	if "github.com" != host { // GitHub Enterprise
+		api = "https://" + host + "/api/v3/repos/" + project
+	}
+	auth := "token " + token
+
+	if "" == base {
+		repo := map[string]interface{}{}
+		if err := forgeRequest("GET", api, "Authorization", auth, nil, &repo); nil != err {
+			return "", err
+		}
+		base, _ = repo["default_branch"].(string)
+	}
+
+	pr := map[string]interface{}{}
+	err := forgeRequest("POST", api+"/pulls", "Authorization", auth,
+		map[string]interface{}{"title": title, "body": body, "head": head, "base": base}, &pr)
+	if nil != err {
+		return "", err
+	}
+
+	ret, _ := pr["html_url"].(string)
+
+	return ret, nil
+}
+
+// createGitLabMergeRequest creates a merge request on GitLab, returns its URL.
+func createGitLabMergeRequest(host, project, token, source, target, title, description string) (string, error) {
+	api := "https://" + host + "/api/v4/projects/" + url.PathEscape(project)
+
+	if "" == target {
+		repo := map[string]interface{}{}
+		if err := forgeRequest("GET", api, "PRIVATE-TOKEN", token, nil, &repo); nil != err {
+			return "", err
+		}
+		target, _ = repo["default_branch"].(string)
+	}
+
+	mr := map[string]interface{}{}
+	err := forgeRequest("POST", api+"/merge_requests", "PRIVATE-TOKEN", token,
+		map[string]interface{}{"title": title, "description": description, "source_branch": source,
+			"target_branch": target}, &mr)
+	if nil != err {
+		return "", err
+	}
+
+	ret, _ := mr["web_url"].(string)
+
+	return ret, nil
+}
+
+// forgeRequest sends a forge API request with the specified authorization header, the JSON response is decoded into
+// the specified result.
+func forgeRequest(method, api, authHeader, authValue string, body, result interface{}) error {
+	request := gorequest.New().Timeout(15*time.Second).CustomMethod(method, api).
+		Set("User-Agent", conf.UserAgent).Set(authHeader, authValue)
+	if nil != body {
+		request = request.Send(body)
+	}
+
+	response, data, errs := request.EndBytes()
+	if nil != errs {
+		return errs[0]
+	}
+
+	if 300 <= response.StatusCode {
+		message := struct {
+			Message interface{} `json:"message"`
+		}{}
+		json.Unmarshal(data, &message)
+		if nil == message.Message {
+			return errors.New(response.Status)
+		}
+
+		return fmt.Errorf("%s: %v", response.Status, message.Message)
+	}
+
+	return json.Unmarshal(data, result)
+}
@@ -1 +1,644 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// askPassScript answers git's username/password prompts with the environment variables set by setGitAuthEnv.
+const askPassScript = `#!/bin/sh
+case "$1" in
+Username*) echo "$WIDE_GIT_USERNAME" ;;
+*) echo "$WIDE_GIT_PASSWORD" ;;
+esac
+`
+
+// Git repository URL patterns: https://host/path, ssh://user@host/path, git://host/path and user@host:path.
+var gitURLPattern = regexp.MustCompile(`^((https?|ssh|git)://[^\s]+|[\w.-]+@[\w.-]+:[^\s]+)$`)
+
+// GitCloneHandler handles request of cloning a git repository into the specified directory.
+//
+// The clone runs in background, its progress is pushed to the output channel, and the session channel
+// is told to refresh the directory after the clone finished. Submodules are cloned as well if "submodules" is
+// specified.
+//
+// For huge repositories, "depth" makes a shallow clone, "branch" and "singleBranch" limit the clone to one branch,
+// and "sparse" (a list of directories) checks out only the specified directories.
+func GitCloneHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid := args["sid"].(string)
+	dir, _ := GetPath(uid, args["path"].(string), fmt.Sprint(args["pathtype"]))
+	if gulu.Go.IsAPI(dir) || gulu.Go.IsPath(dir) || !session.CanAccess(uid, dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	if !gulu.File.IsDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	url := strings.TrimSpace(args["url"].(string))
+	if !gitURLPattern.MatchString(url) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "git-invalid-url").(string)
+
+		return
+	}
+
+	name := repoName(url)
+	if nil != args["name"] && "" != strings.TrimSpace(args["name"].(string)) {
+		name = strings.TrimSpace(args["name"].(string))
+	}
+	target := filepath.Join(dir, name)
+	if filepath.Dir(target) != filepath.Clean(dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	if gulu.File.IsExist(target) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "git-clone-exists").(string)
+
+		return
+	}
+	if msg := quotaExceeded(uid, 0); "" != msg {
+		result.Code = -1
+		result.Msg = msg
+
+		return
+	}
+
+	username, password := "", ""
+	if nil != args["username"] {
+		username = args["username"].(string)
+	}
+	if nil != args["password"] {
+		password = args["password"].(string)
+	}
+
+	gitArgs := []string{"clone", "--progress"}
+	if submodules, _ := args["submodules"].(bool); submodules {
+		gitArgs = append(gitArgs, "--recurse-submodules")
+	}
+	if depth, _ := args["depth"].(float64); 0 < depth {
+		gitArgs = append(gitArgs, "--depth="+strconv.Itoa(int(depth)))
+	}
+	if branch, _ := args["branch"].(string); "" != strings.TrimSpace(branch) {
+		gitArgs = append(gitArgs, "--branch="+strings.TrimSpace(branch))
+	}
+	if singleBranch, _ := args["singleBranch"].(bool); singleBranch {
+		gitArgs = append(gitArgs, "--single-branch")
+	}
+
+	// sparse checkout only the specified directories (cone mode), files at the top level are always checked out
+	sparse := []string{}
+	if paths, ok := args["sparse"].([]interface{}); ok {
+		for _, p := range paths {
+			path, _ := p.(string)
+			path = strings.Trim(filepath.ToSlash(filepath.Clean(strings.TrimSpace(path))), "/")
+			if "" == path || "." == path {
+				continue
+			}
+			if ".." == path || strings.HasPrefix(path, "../") || strings.HasPrefix(path, "-") {
+				result.Code = -1
+
+				return
+			}
+
+			sparse = append(sparse, path)
+		}
+	}
+	if 0 < len(sparse) {
+		gitArgs = append(gitArgs, "--sparse", "--filter=blob:none")
+	}
+	cmd := gitCommand(dir, append(gitArgs, "--", url, name)...)
+	keyFile := ""
+	if "" == username && "" == password {
+		keyFile = setGitCredentialEnv(cmd, uid, conf.GetUser(uid).GetGitCredential())
+	} else {
+		setGitAuthEnv(cmd, username, password)
+	}
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+		defer removeKeyFile(keyFile)
+
+		logger.Debugf("User [%s, %s] is cloning [%s] into [%s]", uid, sid, url, target)
+
+		_, succ := runGitWithOutput(cmd, sid, locale, "git-clone")
+		if succ && 0 < len(sparse) {
+			// blobs of the sparse directories are fetched lazily with the same credentials as the clone
+			sparseCmd := gitCommand(target, append([]string{"sparse-checkout", "set", "--"}, sparse...)...)
+			sparseCmd.Env = cmd.Env
+			_, succ = runGitWithOutput(sparseCmd, sid, locale, "git-sparse-checkout")
+		}
+		if !succ {
+			// leave nothing behind for a failed clone, so user can retry it directly
+			os.RemoveAll(target)
+		}
+
+		logger.Debugf("User [%s, %s] done cloning [%s] into [%s], succ [%v]", uid, sid, url, target, succ)
+
+		if wsChannel := session.SessionWS[sid]; nil != wsChannel && succ {
+			cmd := map[string]interface{}{"path": filepath.ToSlash(target), "dir": filepath.ToSlash(dir),
+				"cmd": "refresh-dir", "type": "d"}
+			wsChannel.WriteJSON(&cmd)
+		}
+	}()
+
+	result.Data = filepath.ToSlash(target)
+}
+
+// GitInitHandler handles request of initializing a git repository in the specified directory.
+//
+// Files in the directory are committed as the initial commit unless "commit" is false. Optional argument "remote" is
+// the URL of remote "origin". The session channel is told to refresh the directory so it's shown as a repository.
+func GitInitHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid, _ := args["sid"].(string)
+	pathArg, _ := args["path"].(string)
+	dir, _ := GetPath(uid, pathArg, fmt.Sprint(args["pathtype"]))
+	if gulu.Go.IsAPI(dir) || gulu.Go.IsPath(dir) || !session.CanAccess(uid, dir) || !gulu.File.IsDir(dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	if gulu.File.IsExist(filepath.Join(dir, ".git")) {
+		result.Code = -1
+		result.Msg = i18n.Get(user.Locale, "git-repo-exists").(string)
+
+		return
+	}
+
+	remote, _ := args["remote"].(string)
+	remote = strings.TrimSpace(remote)
+	if "" != remote && !gitURLPattern.MatchString(remote) {
+		result.Code = -1
+		result.Msg = i18n.Get(user.Locale, "git-invalid-url").(string)
+
+		return
+	}
+
+	name, email := user.GitAuthor()
+	steps := [][]string{{"init"}}
+	if commit, ok := args["commit"].(bool); !ok || commit {
+		steps = append(steps, []string{"add", "-A"},
+			[]string{"-c", "user.name=" + name, "-c", "user.email=" + email, "commit", "-q", "--allow-empty", "-m",
+				"Initial commit"})
+	}
+	if "" != remote {
+		steps = append(steps, []string{"remote", "add", "origin", "--", remote})
+	}
+
+	for _, step := range steps {
+		if out, err := gitCommand(dir, step...).CombinedOutput(); nil != err {
+			logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(step, " "), dir, err, out)
+			result.Code = -1
+			result.Msg = strings.TrimSpace(string(out))
+
+			return
+		}
+	}
+
+	if wsChannel := session.SessionWS[sid]; nil != wsChannel {
+		cmd := map[string]interface{}{"path": filepath.ToSlash(dir), "dir": filepath.ToSlash(filepath.Dir(dir)),
+			"cmd": "refresh-dir", "type": "d"}
+		wsChannel.WriteJSON(&cmd)
+	}
+
+	result.Data = gitHeadBranch(dir)
+}
+
+// repoName returns the directory name git would use to clone the repository of the specified URL.
+func repoName(url string) string {
+	url = strings.TrimRight(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+
+	idx := strings.LastIndexAny(url, "/:")
+
+	return url[idx+1:]
+}
+
+// gitRequest represents a request of a git operation on a repository.
+type gitRequest struct {
+	uid    string                 // user id
+	user   *conf.User             // user
+	locale string                 // user locale
+	sid    string                 // wide session id, may be ""
+	path   string                 // path of the selected file or directory
+	repo   string                 // root directory of the repository containing path
+	args   map[string]interface{} // request arguments
+}
+
+// newGitRequest parses the specified HTTP request as a git request.
+//
+// Returns nil if the request can't be served, the response has been written into w or the specified result then.
+func newGitRequest(w http.ResponseWriter, r *http.Request, result *gulu.Result) *gitRequest {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return nil
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return nil
+	}
+
+	pathArg, _ := args["path"].(string)
+	path, _ := GetPath(uid, pathArg, fmt.Sprint(args["pathtype"]))
+	if !session.CanAccess(uid, path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return nil
+	}
+
+	repo := gitRepoRoot(path)
+	if "" == repo {
+		result.Code = -1
+		result.Msg = i18n.Get(user.Locale, "git-not-repo").(string)
+
+		return nil
+	}
+
+	sid, _ := args["sid"].(string)
+
+	return &gitRequest{uid: uid, user: user, locale: user.Locale, sid: sid, path: path, repo: repo, args: args}
+}
+
+// arg gets the string argument of the specified name, returns "" if not found.
+func (req *gitRequest) arg(name string) string {
+	ret, _ := req.args[name].(string)
+
+	return strings.TrimSpace(ret)
+}
+
+// flag gets the boolean argument of the specified name, returns false if not found.
+func (req *gitRequest) flag(name string) bool {
+	ret, _ := req.args[name].(bool)
+
+	return ret
+}
+
+// msg gets the message of the specified i18n key in the request user's locale.
+func (req *gitRequest) msg(key string) string {
+	return i18n.Get(req.locale, key).(string)
+}
+
+// gitRepoRoot returns the root directory of the repository containing the specified path, returns "" if the path is
+// not in a repository.
+func gitRepoRoot(path string) string {
+	dir := path
+	if !gulu.File.IsDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	out, err := gitCommand(dir, "rev-parse", "--show-toplevel").Output()
+	if nil != err {
+		return ""
+	}
+
+	return filepath.FromSlash(strings.TrimSpace(string(out)))
+}
+
+// gitHasHead determines whether the repository of the specified root directory has any commit.
+func gitHasHead(repo string) bool {
+	return nil == gitCommand(repo, "rev-parse", "--verify", "-q", "HEAD").Run()
+}
+
+// gitCommand creates a git command with the specified working directory and arguments.
+//
+// Interactive prompts are disabled so that a command waiting for input never hangs a request. Hooks of a repository
+// (which may come from a clone) are disabled by pointing core.hooksPath at Wide.GitHooksPath, unless the owner of the
+// repository trusts them.
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	if !trustsGitHooks(dir) {
+		args = append([]string{"-c", "core.hooksPath=" + conf.Wide().GitHooksPath}, args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+
+	return cmd
+}
+
+// trustsGitHooks determines whether the owner of the specified directory trusts the git hooks of its repository.
+func trustsGitHooks(dir string) bool {
+	user := conf.GetUser(conf.GetOwner(dir))
+
+	return nil != user && user.TrustsGitHooks(dir)
+}
+
+// setGitAuthEnv sets HTTPS credentials into the environment of the specified git command.
+//
+// The credentials are passed to git by an askpass script, so they never appear in command line or in the remote URL
+// persisted in .git/config.
+func setGitAuthEnv(cmd *exec.Cmd, username, password string) {
+	if "" == username && "" == password {
+		return
+	}
+
+	askPass := filepath.Join(conf.Wide().Data, "git-askpass.sh")
+	if !gulu.File.IsExist(askPass) {
+		if err := ioutil.WriteFile(askPass, []byte(askPassScript), 0700); nil != err {
+			logger.Error(err)
+
+			return
+		}
+	}
+
+	cmd.Env = append(cmd.Env,
+		"GIT_ASKPASS="+askPass,
+		"WIDE_GIT_USERNAME="+username,
+		"WIDE_GIT_PASSWORD="+password)
+}
+
+// runGitWithOutput runs the specified git command and pushes its output to the output channel of the specified session.
+//
+// The name is used as the channel command and the prefix of the i18n keys, for example "git-clone" uses
+// "start-git-clone", "git-clone-succ" and "git-clone-error". Returns the output and whether the command succeeded.
+func runGitWithOutput(cmd *exec.Cmd, sid, locale, name string) (output string, succ bool) {
+	channelRet := map[string]interface{}{"cmd": name}
+	pushOutput(sid, channelRet, "<span class='start-build'>"+i18n.Get(locale, "start-"+name).(string)+"</span>\n")
+
+	stdout, err := cmd.StdoutPipe()
+	if nil != err {
+		logger.Error(err)
+
+		return "", false
+	}
+	cmd.Stderr = cmd.Stdout // git reports progress on stderr
+
+	if err := cmd.Start(); nil != err {
+		logger.Error(err)
+		pushOutput(sid, channelRet, "<span class='stderr'>"+err.Error()+"</span>\n")
+
+		return err.Error(), false
+	}
+
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(stdout)
+	scanner.Split(scanLinesOrCR)
+	for scanner.Scan() {
+		line := scanner.Text()
+		buf.WriteString(line + "\n")
+
+		line = strings.Replace(line, "<", "&lt;", -1)
+		line = strings.Replace(line, ">", "&gt;", -1)
+
+		pushOutput(sid, channelRet, line+"\n")
+	}
+
+	channelRet["cmd"] = name + "-done"
+	if err := cmd.Wait(); nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]", strings.Join(cmd.Args[1:], " "), cmd.Dir, err)
+		channelRet["succ"] = false
+		pushOutput(sid, channelRet, "<span class='build-error'>"+i18n.Get(locale, name+"-error").(string)+"</span>\n")
+
+		return buf.String(), false
+	}
+
+	channelRet["succ"] = true
+	pushOutput(sid, channelRet, "<span class='build-succ'>"+i18n.Get(locale, name+"-succ").(string)+"</span>\n")
+
+	return buf.String(), true
+}
+
+// pushOutput writes the specified output to the output channel of the specified session.
+func pushOutput(sid string, channelRet map[string]interface{}, output string) {
+	wsChannel := session.OutputWS[sid]
+	if nil == wsChannel {
+		return
+	}
+
+	channelRet["output"] = output
+	if err := wsChannel.WriteJSON(&channelRet); nil != err {
+		logger.Warn(err)
+
+		return
+	}
+
+	wsChannel.Refresh()
+}
+
+// scanLinesOrCR is a split function for bufio.Scanner which splits on "\n" or "\r", git uses "\r" to redraw the
+// progress line.
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && 0 == len(data) {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// Git status of a file node.
+const (
+	gitStatusModified   = "modified"
+	gitStatusUntracked  = "untracked"
+	gitStatusStaged     = "staged"
+	gitStatusConflicted = "conflicted"
+)
+
+// decorateGitStatus annotates the specified node (built by walk with the specified root path) and its descendants with
+// their git status.
+//
+// The node itself may be a repository, be inside a repository or contain nested repositories.
+func decorateGitStatus(rootpath string, node *Node) {
+	statuses := gitStatuses(rootpath)
+
+	applyGitStatus(rootpath, node, statuses)
+}
+
+// applyGitStatus annotates children of the specified node with the specified statuses, a nested repository gets its own
+// statuses.
+func applyGitStatus(rootpath string, node *Node, statuses map[string]string) {
+	for _, child := range node.Children {
+		if "m" == child.Type {
+			continue
+		}
+
+		path := filepath.Join(rootpath, filepath.FromSlash(child.Path))
+
+		childStatuses := statuses
+		if child.GitRepo {
+			childStatuses = gitStatuses(path)
+		}
+
+		child.GitStatus = statuses[path]
+
+		if "d" == child.Type {
+			applyGitStatus(rootpath, child, childStatuses)
+		}
+	}
+}
+
+// gitStatuses returns the git status of changed files under the specified directory in the repository containing it,
+// returns nil if the directory is not in a repository.
+//
+// Only the directory is scanned (pathspec "."), so refreshing a directory of a large repository doesn't walk the whole
+// worktree. Keys of the returned map are absolute paths, directories containing changes are reported as "modified".
+func gitStatuses(dir string) map[string]string {
+	if !gulu.File.IsDir(dir) {
+		return nil
+	}
+
+	top := gitRepoRoot(dir)
+	if "" == top {
+		return nil
+	}
+
+	out, err := gitCommand(dir, "status", "--porcelain", "-z", "--untracked-files=all", "--", ".").Output()
+	if nil != err {
+		logger.Warnf("Gets git status of [%s] failed [%s]", dir, err)
+
+		return nil
+	}
+
+	ret := map[string]string{}
+	for _, entry := range parseGitStatus(out) {
+		path := filepath.Join(top, filepath.FromSlash(entry.Path))
+		ret[path] = entry.Status
+
+		for parent := filepath.Dir(path); len(parent) > len(top); parent = filepath.Dir(parent) {
+			if _, ok := ret[parent]; ok {
+				break
+			}
+
+			ret[parent] = gitStatusModified
+		}
+	}
+
+	return ret
+}
+
+// gitStatusEntry represents an entry of "git status --porcelain".
+type gitStatusEntry struct {
+	Path   string `json:"path"`   // path relative to the repository root
+	XY     string `json:"xy"`     // two-letter status code
+	Status string `json:"status"` // "modified", "untracked", "staged" or "conflicted"
+}
+
+// parseGitStatus parses the output of "git status --porcelain -z".
+func parseGitStatus(out []byte) []*gitStatusEntry {
+	ret := []*gitStatusEntry{}
+
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 {
+			continue
+		}
+
+		xy := field[:2]
+		entry := &gitStatusEntry{Path: field[3:], XY: xy, Status: gitStatus(xy)}
+		ret = append(ret, entry)
+
+		if 'R' == xy[0] || 'C' == xy[0] {
+			i++ // skip the original path of a rename or copy
+		}
+	}
+
+	return ret
+}
+
+// gitStatus converts the specified two-letter porcelain status code to a node git status.
+func gitStatus(xy string) string {
+	switch xy {
+	case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+		return gitStatusConflicted
+	case "??":
+		return gitStatusUntracked
+	}
+
+	if ' ' != xy[1] {
+		return gitStatusModified
+	}
+
+	return gitStatusStaged
+}
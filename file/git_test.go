@@ -0,0 +1,80 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	out := " M main.go\x00" +
+		"M  staged.go\x00" +
+		"MM both.go\x00" +
+		"?? new file.go\x00" +
+		"R  new.go\x00old.go\x00" +
+		"UU conflict.go\x00" +
+		"AA added-by-both.go\x00" +
+		"DU deleted-by-us.go\x00" +
+		"UD deleted-by-them.go\x00" +
+		"D  removed.go\x00" +
+		" D missing.go\x00" +
+		"?? 文.txt\x00"
+
+	expected := []gitStatusEntry{
+		{Path: "main.go", XY: " M", Status: gitStatusModified},
+		{Path: "staged.go", XY: "M ", Status: gitStatusStaged},
+		{Path: "both.go", XY: "MM", Status: gitStatusModified},
+		{Path: "new file.go", XY: "??", Status: gitStatusUntracked},
+		{Path: "new.go", XY: "R ", Status: gitStatusStaged},
+		{Path: "conflict.go", XY: "UU", Status: gitStatusConflicted},
+		{Path: "added-by-both.go", XY: "AA", Status: gitStatusConflicted},
+		{Path: "deleted-by-us.go", XY: "DU", Status: gitStatusConflicted},
+		{Path: "deleted-by-them.go", XY: "UD", Status: gitStatusConflicted},
+		{Path: "removed.go", XY: "D ", Status: gitStatusStaged},
+		{Path: "missing.go", XY: " D", Status: gitStatusModified},
+		{Path: "文.txt", XY: "??", Status: gitStatusUntracked},
+	}
+
+	entries := parseGitStatus([]byte(out))
+	if len(expected) != len(entries) {
+		t.Fatalf("expected [%d] entries, got [%d]", len(expected), len(entries))
+	}
+	for i, entry := range expected {
+		if entry != *entries[i] {
+			t.Errorf("expected entry %+v, got %+v", entry, *entries[i])
+		}
+	}
+}
+
+func TestGitStatus(t *testing.T) {
+	cases := []struct {
+		xy     string
+		status string
+	}{
+		{"DD", gitStatusConflicted},
+		{"AU", gitStatusConflicted},
+		{"UA", gitStatusConflicted},
+		{"??", gitStatusUntracked},
+		{"A ", gitStatusStaged},
+		{"AM", gitStatusModified},
+		{" M", gitStatusModified},
+	}
+
+	for _, c := range cases {
+		if status := gitStatus(c.xy); c.status != status {
+			t.Errorf("expected status [%s] of [%s], got [%s]", c.status, c.xy, status)
+		}
+	}
+}
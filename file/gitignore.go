@@ -0,0 +1,211 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitignoreTemplates holds .gitignore templates, key is the template name.
+var gitignoreTemplates = map[string][]string{
+	"go": {
+		"# Go",
+		"*.exe",
+		"*.exe~",
+		"*.dll",
+		"*.so",
+		"*.dylib",
+		"*.test",
+		"*.out",
+		"/vendor/",
+	},
+	"editors": {
+		"# Editors",
+		".idea/",
+		".vscode/",
+		"*.swp",
+		"*.swo",
+		"*~",
+	},
+	"os": {
+		"# OS",
+		".DS_Store",
+		"Thumbs.db",
+		"desktop.ini",
+	},
+}
+
+// gitignoreTemplateNames holds the template names in the order they are written.
+var gitignoreTemplateNames = []string{"go", "editors", "os"}
+
+// GitIgnoreHandler handles request of creating or appending the .gitignore in the root directory of a repository.
+//
+// Optional argument "templates" is the names of templates to apply ("go", "editors" and "os"), defaults to all. The
+// executables built by Wide (named after their package directories) are always covered. Patterns already in the
+// file are not appended again.
+func GitIgnoreHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	names := gitignoreTemplateNames
+	if templates, ok := req.args["templates"].([]interface{}); ok {
+		names = []string{}
+		for _, template := range templates {
+			name, _ := template.(string)
+			if _, ok := gitignoreTemplates[name]; ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sections := [][]string{}
+	for _, name := range names {
+		sections = append(sections, gitignoreTemplates[name])
+	}
+	sections = append(sections, append([]string{"# Build artifacts"}, buildArtifacts(req.repo)...))
+
+	path := filepath.Join(req.repo, ".gitignore")
+	existing := map[string]bool{}
+	content := ""
+	if data, err := ioutil.ReadFile(path); nil == err {
+		content = string(data)
+		scanner := bufio.NewScanner(strings.NewReader(content))
+		for scanner.Scan() {
+			existing[strings.TrimSpace(scanner.Text())] = true
+		}
+	}
+
+	added := []string{}
+	var buf strings.Builder
+	for _, section := range sections {
+		var lines []string
+		for _, pattern := range section[1:] {
+			if !existing[pattern] {
+				existing[pattern] = true
+				lines = append(lines, pattern)
+			}
+		}
+		if 0 == len(lines) {
+			continue
+		}
+
+		if 0 < buf.Len() || "" != content {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(section[0] + "\n" + strings.Join(lines, "\n") + "\n")
+		added = append(added, lines...)
+	}
+
+	result.Data = added
+	if 0 == len(added) {
+		return
+	}
+
+	if "" != content && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content+buf.String()), 0644); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	notifyFileChanged(req.sid, path)
+}
+
+// buildArtifacts returns .gitignore patterns of the executables built by BuildHandler under the specified repository
+// root directory, an executable is named after the directory of its main package.
+func buildArtifacts(repo string) []string {
+	ret := []string{}
+
+	filepath.Walk(repo, func(path string, info os.FileInfo, err error) error {
+		if nil != err || !info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+		if path != repo && (strings.HasPrefix(name, ".") || "vendor" == name || "node_modules" == name) {
+			return filepath.SkipDir
+		}
+
+		if !isMainPackage(path) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(repo, path)
+		executable := "/" + filepath.ToSlash(filepath.Join(rel, name))
+		if path == repo {
+			executable = "/" + name
+		}
+		ret = append(ret, executable, executable+".exe")
+
+		return nil
+	})
+
+	return ret
+}
+
+// isMainPackage determines whether the specified directory contains Go files of package main.
+func isMainPackage(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if nil != err {
+		return false
+	}
+
+	for _, file := range files {
+		if file.IsDir() || ".go" != filepath.Ext(file.Name()) || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+
+		if "main" == goPackageName(filepath.Join(dir, file.Name())) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goPackageName gets the package name declared in the Go file of the specified path, returns "" if not found.
+func goPackageName(path string) string {
+	f, err := os.Open(path)
+	if nil != err {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if 2 <= len(fields) && "package" == fields[0] {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitCommit represents a commit in the history.
+type gitCommit struct {
+	Hash        string              `json:"hash"`
+	ShortHash   string              `json:"shortHash"`
+	Author      string              `json:"author"`
+	AuthorEmail string              `json:"authorEmail"`
+	Time        int64               `json:"time"` // author time in unix milliseconds
+	Date        string              `json:"date"` // author time formatted in the user's time zone
+	Subject     string              `json:"subject"`
+	Body        string              `json:"body"`
+	Files       []*gitCommitFileRef `json:"files"` // files changed
+}
+
+// gitCommitFileRef represents a file changed by a commit.
+type gitCommitFileRef struct {
+	Status  string `json:"status"`  // "A", "M", "D", "R", "C" or "T"
+	Path    string `json:"path"`    // path after the change
+	OldPath string `json:"oldPath"` // path before the change if renamed or copied, "" otherwise
+}
+
+// errNotRepo is returned if a path is not in a git repository.
+var errNotRepo = errors.New("not a git repository")
+
+// gitLogFormat is the pretty format of "git log", each commit starts with a record separator and its fields are
+// terminated by NUL, followed by the "--name-status" lines.
+const gitLogFormat = "--format=%x1e%H%x00%h%x00%an%x00%ae%x00%at%x00%s%x00%b%x00"
+
+// GitLogHandler handles request of getting the commit history of a repository, or of a file (or directory) if the
+// path is not the repository root.
+//
+// Optional arguments: "page" (1-based, defaults to 1), "pageSize" (defaults to 20, at most 100) and "revision"
+// (branch or commit to start from, defaults to HEAD). Renames of a single file are followed.
+func GitLogHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	page, pageSize := 1, 20
+	if p, ok := req.args["page"].(float64); ok && 1 <= p {
+		page = int(p)
+	}
+	if s, ok := req.args["pageSize"].(float64); ok && 1 <= s && 100 >= s {
+		pageSize = int(s)
+	}
+
+	if !gitHasHead(req.repo) {
+		result.Data = map[string]interface{}{"commits": []*gitCommit{}, "page": page, "hasMore": false}
+
+		return
+	}
+
+	revision := req.arg("revision")
+	if strings.HasPrefix(revision, "-") {
+		result.Code = -1
+
+		return
+	}
+	if "" == revision {
+		revision = "HEAD"
+	}
+
+	// one more commit is fetched to determine whether there is a next page
+	gitArgs := []string{"-c", "core.quotePath=false", "log", gitLogFormat, "--name-status",
+		"--skip=" + strconv.Itoa((page-1)*pageSize), "--max-count=" + strconv.Itoa(pageSize+1)}
+	if req.path != req.repo && !gulu.File.IsDir(req.path) {
+		gitArgs = append(gitArgs, "--follow")
+	}
+	gitArgs = append(gitArgs, revision, "--")
+	if req.path != req.repo {
+		gitArgs = append(gitArgs, req.path)
+	}
+
+	out, err := gitCommand(req.repo, gitArgs...).CombinedOutput()
+	if nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(gitArgs, " "), req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	commits := parseGitLog(string(out))
+	hasMore := len(commits) > pageSize
+	if hasMore {
+		commits = commits[:pageSize]
+	}
+	for _, commit := range commits {
+		commit.Date = req.user.FormatUnixMilli(commit.Time)
+		for _, file := range commit.Files {
+			file.Path = filepath.ToSlash(filepath.Join(req.repo, file.Path))
+			if "" != file.OldPath {
+				file.OldPath = filepath.ToSlash(filepath.Join(req.repo, file.OldPath))
+			}
+		}
+	}
+
+	result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "commits": commits, "page": page,
+		"hasMore": hasMore}
+}
+
+// parseGitLog parses the specified output of "git log" with gitLogFormat and "--name-status".
+func parseGitLog(out string) []*gitCommit {
+	ret := []*gitCommit{}
+
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(record, "\x00", 8)
+		if 8 != len(fields) {
+			continue
+		}
+
+		seconds, _ := strconv.ParseInt(fields[4], 10, 64)
+		commit := &gitCommit{Hash: fields[0], ShortHash: fields[1], Author: fields[2], AuthorEmail: fields[3],
+			Time: seconds * 1000, Subject: fields[5], Body: strings.TrimSpace(fields[6]),
+			Files: []*gitCommitFileRef{}}
+
+		for _, line := range strings.Split(fields[7], "\n") {
+			parts := strings.Split(line, "\t")
+			if 2 > len(parts) || "" == parts[0] {
+				continue
+			}
+
+			file := &gitCommitFileRef{Status: parts[0][:1], Path: unquoteGitPath(parts[len(parts)-1])}
+			if 3 == len(parts) {
+				file.OldPath = unquoteGitPath(parts[1])
+			}
+			commit.Files = append(commit.Files, file)
+		}
+
+		ret = append(ret, commit)
+	}
+
+	return ret
+}
+
+// gitShowFile gets the content of the file of the specified path at the specified revision.
+func gitShowFile(path, revision string) ([]byte, error) {
+	dir := filepath.Dir(path)
+	repo := gitRepoRoot(dir)
+	if "" == repo {
+		return nil, errNotRepo
+	}
+
+	rel, err := filepath.Rel(repo, path)
+	if nil != err {
+		return nil, err
+	}
+
+	return gitCommand(repo, "show", revision+":"+filepath.ToSlash(rel)).Output()
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"testing"
+)
+
+func TestParseGitLog(t *testing.T) {
+	out := "\x1e" + "1111111111111111111111111111111111111111\x001111111\x00Alice\x00alice@example.com\x001500000000\x00" +
+		"Rename files\x00Body line 1\nBody line 2\n\x00\n" +
+		"R100\told.go\tnew.go\n" +
+		"M\t\"\\346\\226\\207.txt\"\n" +
+		"A\tdir/a b.go\n" +
+		"\x1e" + "2222222222222222222222222222222222222222\x002222222\x00Bob\x00bob@example.com\x001400000000\x00" +
+		"Initial\x00\x00\n" +
+		"D\tgone.go\n" +
+		"C075\tsrc.go\tcopy.go\n" +
+		"\x1e" + "3333333333333333333333333333333333333333\x003333333\x00Bob\x00bob@example.com\x001300000000\x00" +
+		"Merge\x00\x00"
+
+	commits := parseGitLog(out)
+	if 3 != len(commits) {
+		t.Fatalf("expected [3] commits, got [%d]", len(commits))
+	}
+
+	first := commits[0]
+	if "1111111" != first.ShortHash || "Alice" != first.Author || "alice@example.com" != first.AuthorEmail ||
+		1500000000000 != first.Time || "Rename files" != first.Subject || "Body line 1\nBody line 2" != first.Body {
+		t.Errorf("unexpected commit %+v", *first)
+	}
+
+	cases := []struct {
+		commit int
+		file   gitCommitFileRef
+	}{
+		{0, gitCommitFileRef{Status: "R", Path: "new.go", OldPath: "old.go"}},
+		{0, gitCommitFileRef{Status: "M", Path: "文.txt"}},
+		{0, gitCommitFileRef{Status: "A", Path: "dir/a b.go"}},
+		{1, gitCommitFileRef{Status: "D", Path: "gone.go"}},
+		{1, gitCommitFileRef{Status: "C", Path: "copy.go", OldPath: "src.go"}},
+	}
+
+	index := map[int]int{}
+	for _, c := range cases {
+		files := commits[c.commit].Files
+		i := index[c.commit]
+		index[c.commit]++
+		if len(files) <= i {
+			t.Errorf("expected file %+v of commit [%d], got none", c.file, c.commit)
+
+			continue
+		}
+
+		if c.file != *files[i] {
+			t.Errorf("expected file %+v of commit [%d], got %+v", c.file, c.commit, *files[i])
+		}
+	}
+
+	if 0 != len(commits[2].Files) {
+		t.Errorf("expected no files of the merge commit, got [%d]", len(commits[2].Files))
+	}
+}
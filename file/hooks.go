@@ -0,0 +1,75 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// GitHooksHandler handles request of getting the git hooks of a repository and whether they are trusted.
+//
+// Hooks of a repository run only if the user trusts them, see gitCommand.
+func GitHooksHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	hooks := []string{}
+	if files, err := ioutil.ReadDir(filepath.Join(gitDir(req.repo), "hooks")); nil == err {
+		for _, file := range files {
+			if !file.IsDir() && ".sample" != filepath.Ext(file.Name()) {
+				hooks = append(hooks, file.Name())
+			}
+		}
+	}
+
+	result.Data = map[string]interface{}{"trusted": req.user.TrustsGitHooks(req.repo), "hooks": hooks}
+}
+
+// GitTrustHooksHandler handles request of trusting (or distrusting if "trust" is false) the git hooks of a
+// repository.
+func GitTrustHooksHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	trusted := []string{}
+	for _, repo := range req.user.TrustedGitHooks {
+		if repo != req.repo {
+			trusted = append(trusted, repo)
+		}
+	}
+	if req.flag("trust") {
+		trusted = append(trusted, req.repo)
+		logger.Infof("User [%s] trusts git hooks of [%s]", req.user.Name, req.repo)
+	}
+
+	req.user.TrustedGitHooks = trusted
+	if !req.user.Save() {
+		result.Code = -1
+	}
+}
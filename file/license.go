@@ -0,0 +1,369 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// licenseHeaderFile is the name of the file of the license header of a project, it's plain text without comment
+// markers and looked up from the checked directory to its ancestors.
+const licenseHeaderFile = ".license-header"
+
+// maxLicenseFiles is the maximum number of files checked at a time.
+const maxLicenseFiles = 10000
+
+// licenseYearPattern matches "{year}" of license headers when checked, such as "2014" or "2014-present".
+const licenseYearPattern = `[0-9]{4}(?:\s*-\s*(?:[0-9]{4}|present))?`
+
+// licenseCommentPrefixes holds the line comment prefixes of the source files checked, <extension, prefix>. Headers are
+// written with line comments, the ones of CSS are written with a block comment.
+var licenseCommentPrefixes = map[string]string{
+	".go": "//", ".js": "//", ".jsx": "//", ".ts": "//", ".tsx": "//", ".java": "//", ".kt": "//", ".scala": "//",
+	".c": "//", ".h": "//", ".cc": "//", ".cpp": "//", ".hpp": "//", ".cs": "//", ".swift": "//", ".rs": "//",
+	".dart": "//", ".proto": "//",
+	".py": "#", ".sh": "#", ".rb": "#", ".pl": "#", ".r": "#", ".yaml": "#", ".yml": "#", ".toml": "#",
+	".sql": "--", ".lua": "--",
+	".css": "", ".scss": "", ".less": "",
+}
+
+// generatedRegexp matches the comment of generated files, such as "// Code generated by protoc-gen-go. DO NOT EDIT.".
+var generatedRegexp = regexp.MustCompile(`(?m)^.{0,4}Code generated .* DO NOT EDIT\.`)
+
+// licenseHeader represents a license header, "{year}" in it is replaced with the current year when inserted and
+// matches any year (see licenseYearPattern) when checked.
+type licenseHeader struct {
+	lines  []string       // lines without comment markers
+	year   string         // year replacing "{year}"
+	regexp *regexp.Regexp // matches the text of the header
+}
+
+// licenseFile represents a file without the license header.
+type licenseFile struct {
+	Path   string `json:"path"`   // path relative to the checked directory
+	Status string `json:"status"` // "missing" if no license header, "outdated" if a different one
+}
+
+// newLicenseHeader creates a license header of the specified text.
+func newLicenseHeader(text string, year int) *licenseHeader {
+	text = strings.Trim(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	lines := strings.Split(text, "\n")
+	patterns := make([]string, len(lines))
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+		patterns[i] = strings.Replace(regexp.QuoteMeta(lines[i]), `\{year\}`, licenseYearPattern, -1)
+	}
+
+	return &licenseHeader{lines: lines, year: strconv.Itoa(year),
+		regexp: regexp.MustCompile(`^` + strings.Join(patterns, "\n") + `$`)}
+}
+
+// render renders the header commented with the specified line comment prefix (a block comment if ""), lines are
+// ended with the specified newline.
+func (h *licenseHeader) render(prefix, newline string) string {
+	ret := []string{}
+	if "" == prefix {
+		ret = append(ret, "/*")
+		prefix = " *"
+	}
+	for _, line := range h.lines {
+		line = strings.Replace(line, "{year}", h.year, -1)
+		if "" != line {
+			line = " " + line
+		}
+		ret = append(ret, prefix+line)
+	}
+	if "/*" == ret[0] {
+		ret = append(ret, " */")
+	}
+
+	return strings.Join(ret, newline) + newline
+}
+
+// checkLicenseHeader checks the license header of the specified content of a file with the specified extension,
+// returns the status (see licenseFile) and the content with the header inserted or updated, returns "" if the header
+// is up to date or the file is not checked (unknown extensions and generated files).
+//
+// The leading comment (after a shebang line) is the header, it's updated if it mentions copyright or license,
+// otherwise the header is inserted before it.
+func checkLicenseHeader(content []byte, ext string, header *licenseHeader) (string, []byte) {
+	prefix, ok := licenseCommentPrefixes[strings.ToLower(ext)]
+	if !ok || generatedRegexp.Match(content[:minInt(len(content), 1024)]) {
+		return "", nil
+	}
+
+	newline := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	text := string(content)
+	start := 0
+	if strings.HasPrefix(text, "#!") { // keeps the shebang line
+		start = strings.Index(text, "\n") + 1
+		if 0 == start {
+			text += newline
+			start = len(text)
+		}
+	}
+
+	commentStart, commentEnd, comment := leadingComment(text, start, prefix)
+	if -1 < commentStart && header.regexp.MatchString(comment) {
+		return "", nil
+	}
+
+	rendered := header.render(prefix, newline)
+	if -1 < commentStart && licenseRegexp.MatchString(comment) {
+		return "outdated", []byte(text[:commentStart] + rendered + text[commentEnd:])
+	}
+
+	rest := strings.TrimLeft(text[start:], "\r\n")
+	if "" != rest {
+		rendered += newline
+	}
+
+	return "missing", []byte(text[:start] + rendered + rest)
+}
+
+// licenseRegexp matches comments of licenses.
+var licenseRegexp = regexp.MustCompile(`(?i)copyright|license`)
+
+// leadingComment returns the start and end offsets (including the newline) and the text (without comment markers) of
+// the comment leading the specified text from the specified offset, blank lines before the comment are skipped.
+// Returns -1 offsets if there is no such comment.
+func leadingComment(text string, offset int, prefix string) (int, int, string) {
+	for offset < len(text) && ('\n' == text[offset] || '\r' == text[offset]) {
+		offset++
+	}
+	rest := text[offset:]
+
+	lines := []string{}
+	end := offset
+	if strings.HasPrefix(rest, "/*") && "#" != prefix && "--" != prefix {
+		closing := strings.Index(rest, "*/")
+		if -1 == closing {
+			return -1, -1, ""
+		}
+
+		for _, line := range strings.Split(rest[2:closing], "\n") {
+			line = strings.TrimLeft(line, " \t")
+			if strings.HasPrefix(line, "*") {
+				line = strings.TrimPrefix(line[1:], " ")
+			}
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+		end += closing + 2
+		for end < len(text) && ('\r' == text[end] || ' ' == text[end]) {
+			end++
+		}
+		if end < len(text) && '\n' == text[end] {
+			end++
+		}
+	} else {
+		if "" == prefix {
+			return -1, -1, ""
+		}
+
+		for "" != rest {
+			line := rest
+			next := len(rest)
+			if i := strings.Index(rest, "\n"); -1 < i {
+				line, next = rest[:i], i+1
+			}
+			line = strings.TrimRight(line, "\r")
+			if !strings.HasPrefix(line, prefix) || isDirective(line) {
+				break
+			}
+
+			lines = append(lines, strings.TrimRight(strings.TrimPrefix(strings.TrimPrefix(line, prefix), " "), " \t"))
+			end += next
+			rest = rest[next:]
+		}
+		if 1 > len(lines) {
+			return -1, -1, ""
+		}
+	}
+
+	return offset, end, strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// isDirective determines whether the specified comment line is a directive, such as "//go:build linux".
+func isDirective(line string) bool {
+	return strings.HasPrefix(line, "//go:") || strings.HasPrefix(line, "// +build")
+}
+
+// checkLicenseHeaders checks the license headers of the source files in the specified directory, inserts or updates
+// them if apply is true. Hidden directories, "vendor", "node_modules" and "testdata" are skipped, so are files larger
+// than the specified size. Returns the files without the header (or updated), the number of files checked and whether
+// there are more files than maxLicenseFiles.
+func checkLicenseHeaders(dir string, header *licenseHeader, apply bool, maxSize int64) ([]*licenseFile, int, bool) {
+	ret := []*licenseFile{}
+	checked := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			return nil
+		}
+
+		name := info.Name()
+		if info.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || "vendor" == name || "node_modules" == name ||
+				"testdata" == name) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if _, ok := licenseCommentPrefixes[strings.ToLower(filepath.Ext(name))]; !ok || !info.Mode().IsRegular() ||
+			info.Size() > maxSize {
+			return nil
+		}
+		if checked >= maxLicenseFiles {
+			return filepath.SkipDir
+		}
+		checked++
+
+		content, err := ioutil.ReadFile(path)
+		if nil != err || isBinaryHead(content[:minInt(len(content), binarySniffSize)]) {
+			return nil
+		}
+
+		status, updated := checkLicenseHeader(content, filepath.Ext(name), header)
+		if "" == status {
+			return nil
+		}
+
+		if apply {
+			if err := ioutil.WriteFile(path, updated, info.Mode().Perm()); nil != err {
+				logger.Warnf("Writes license header of [%s] failed: %s", path, err)
+
+				return nil
+			}
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		ret = append(ret, &licenseFile{Path: filepath.ToSlash(rel), Status: status})
+
+		return nil
+	})
+
+	return ret, checked, checked >= maxLicenseFiles
+}
+
+// findLicenseHeader finds the license header file (see licenseHeaderFile) of the specified directory from it to its
+// ancestors the user specified by the given user id can access, returns "" if not found.
+func findLicenseHeader(uid, dir string) string {
+	for {
+		path := filepath.Join(dir, licenseHeaderFile)
+		if gulu.File.IsExist(path) {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || !session.CanAccess(uid, parent) {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LicenseHeadersHandler handles request of checking the license headers of the source files in a directory.
+//
+// Arguments: "sid", "path" (the directory), "pathtype" and optional "apply" (whether to insert or update the headers).
+// The header is the one of the license header file (see licenseHeaderFile), responds the files without the header
+// (inserted or updated if apply), the number of files checked and the path of the header file relative to the
+// directory.
+func LicenseHeadersHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	pathArg, _ := args["path"].(string)
+	dir, _ := GetPath(uid, pathArg, fmt.Sprint(args["pathtype"]))
+	// user [playground] is a reserved mock user
+	if "playground" == uid || "" == dir || gulu.Go.IsAPI(dir) || gulu.Go.IsPath(dir) || !session.CanAccess(uid, dir) ||
+		!gulu.File.IsDir(dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	dir = filepath.FromSlash(dir)
+	apply, _ := args["apply"].(bool)
+	sid, _ := args["sid"].(string)
+
+	headerPath := findLicenseHeader(uid, dir)
+	if "" == headerPath {
+		result.Code = -1
+		result.Msg = i18n.Get(conf.GetUser(uid).Locale, "license-no-header",
+			i18n.Params{"file": licenseHeaderFile}).(string)
+
+		return
+	}
+	text, err := ioutil.ReadFile(headerPath)
+	if nil != err || "" == strings.TrimSpace(string(text)) {
+		result.Code = -1
+		result.Msg = i18n.Get(conf.GetUser(uid).Locale, "license-no-header",
+			i18n.Params{"file": licenseHeaderFile}).(string)
+
+		return
+	}
+
+	header := newLicenseHeader(string(text), time.Now().Year())
+	files, checked, truncated := checkLicenseHeaders(dir, header, apply, conf.Wide().MaxFileSizeBytes())
+	if apply {
+		for _, f := range files {
+			path := filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(f.Path)))
+			fileChanged(sid, &event.FileChange{UserId: uid, Op: "save", Path: path})
+		}
+		if 0 < len(files) {
+			logger.Infof("User [%s] updated license headers of [%d] files in [%s]", uid, len(files), dir)
+		}
+	}
+
+	rel, _ := filepath.Rel(dir, headerPath)
+	result.Data = map[string]interface{}{"header": filepath.ToSlash(rel), "files": files, "checked": checked,
+		"truncated": truncated, "applied": apply}
+}
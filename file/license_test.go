@@ -0,0 +1,123 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const apacheHeader = `Copyright (c) {year}-present, b3log.org
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+`
+
+func TestCheckLicenseHeader(t *testing.T) {
+	header := newLicenseHeader(apacheHeader, 2024)
+	goHeader := header.render("//", "\n")
+	if !strings.HasPrefix(goHeader, "// Copyright (c) 2024-present, b3log.org\n//\n// Licensed") ||
+		!strings.Contains(goHeader, "\n//     https://www.apache.org/licenses/LICENSE-2.0\n") {
+		t.Fatalf("unexpected header\n%s", goHeader)
+	}
+
+	cases := []struct {
+		name, ext, content, status, expected string
+	}{
+		{"up to date", ".go", strings.Replace(goHeader, "2024", "2014", 1) + "\npackage a\n", "", ""},
+		{"missing", ".go", "package a\n", "missing", goHeader + "\npackage a\n"},
+		{"package doc", ".go", "// Package a does nothing.\npackage a\n", "missing",
+			goHeader + "\n// Package a does nothing.\npackage a\n"},
+		{"outdated", ".go", "\n// Copyright 2010 Foo. MIT License.\n\n//go:build linux\n\npackage a\n", "outdated",
+			"\n" + goHeader + "\n//go:build linux\n\npackage a\n"},
+		{"block comment", ".go", newLicenseHeader(apacheHeader, 2014).render("", "\n") + "\npackage a\n", "", ""},
+		{"shebang", ".sh", "#!/bin/sh\necho\n", "missing",
+			"#!/bin/sh\n" + header.render("#", "\n") + "\necho\n"},
+		{"css", ".css", "a {}\n", "missing", header.render("", "\n") + "\na {}\n"},
+		{"crlf", ".js", "var a;\r\n", "missing", header.render("//", "\r\n") + "\r\nvar a;\r\n"},
+		{"generated", ".go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage a\n", "", ""},
+		{"unknown", ".txt", "text\n", "", ""},
+	}
+
+	for _, c := range cases {
+		status, updated := checkLicenseHeader([]byte(c.content), c.ext, header)
+		if c.status != status || c.expected != string(updated) {
+			t.Errorf("%s: expected [%s]\n%s\ngot [%s]\n%s", c.name, c.status, c.expected, status, updated)
+
+			continue
+		}
+
+		if "" != status {
+			if status, _ := checkLicenseHeader(updated, c.ext, header); "" != status {
+				t.Errorf("%s: expected up to date after updated, got [%s]", c.name, status)
+			}
+		}
+	}
+}
+
+func TestCheckLicenseHeaders(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":           "package main\n",
+		"ok.go":             "// Copyright 2020 Foo\n\npackage main\n",
+		"README.md":         "# readme\n",
+		"vendor/v/v.go":     "package v\n",
+		".git/hooks/pre.sh": "echo\n",
+		"web/app.js":        "// Copyright 2019 Bar, MIT License\nvar a;\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	header := newLicenseHeader("Copyright {year} Foo", 2024)
+	found, checked, truncated := checkLicenseHeaders(dir, header, false, 1<<20)
+	if 3 != checked || truncated || 2 != len(found) {
+		t.Fatalf("unexpected result %d %v %v", checked, truncated, found)
+	}
+	statuses := map[string]string{}
+	for _, f := range found {
+		statuses[f.Path] = f.Status
+	}
+	if "missing" != statuses["main.go"] || "outdated" != statuses["web/app.js"] {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "main.go")); "package main\n" != string(data) {
+		t.Errorf("files shouldn't be changed without applying, got\n%s", data)
+	}
+
+	if found, _, _ = checkLicenseHeaders(dir, header, true, 1<<20); 2 != len(found) {
+		t.Fatalf("unexpected applied %v", found)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "main.go")); "// Copyright 2024 Foo\n\npackage main\n" != string(data) {
+		t.Errorf("unexpected applied content\n%s", data)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "web", "app.js")); "// Copyright 2024 Foo\nvar a;\n" != string(data) {
+		t.Errorf("unexpected applied content\n%s", data)
+	}
+	if found, _, _ = checkLicenseHeaders(dir, header, false, 1<<20); 0 != len(found) {
+		t.Errorf("expected all up to date after applied, got %v", found)
+	}
+}
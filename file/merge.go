@@ -0,0 +1,299 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// GitMergeHandler handles request of merging a branch into the current branch.
+//
+// If the merge stops with conflicts, the conflicted files are returned as result data, they can be resolved by
+// GitResolveHandler then GitContinueHandler concludes the merge.
+func GitMergeHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	branch := req.arg("branch")
+	if "" == branch || strings.HasPrefix(branch, "-") {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	gitSequenceHandler(req, result, "git-merge", "merge", "--no-edit", branch)
+}
+
+// GitCherryPickHandler handles request of applying the changes of a commit onto the current branch.
+//
+// If the cherry-pick stops with conflicts, the conflicted files are returned as result data, they can be resolved by
+// GitResolveHandler then GitContinueHandler concludes the cherry-pick.
+func GitCherryPickHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	commit := req.arg("commit")
+	if "" == commit || strings.HasPrefix(commit, "-") {
+		result.Code = -1
+
+		return
+	}
+
+	gitSequenceHandler(req, result, "git-cherry-pick", "cherry-pick", commit)
+}
+
+// GitRebaseHandler handles request of rebasing the current branch onto a branch.
+//
+// If the rebase stops with conflicts, the conflicted files are returned as result data, they can be resolved by
+// GitResolveHandler then GitContinueHandler continues the rebase.
+func GitRebaseHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	branch := req.arg("branch")
+	if "" == branch || strings.HasPrefix(branch, "-") {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-branch")
+
+		return
+	}
+
+	if gitDirty(req.repo) {
+		result.Code = -1
+		result.Msg = req.msg("git-dirty-worktree")
+
+		return
+	}
+
+	gitSequenceHandler(req, result, "git-rebase", "rebase", branch)
+}
+
+// GitContinueHandler handles request of continuing the merge, cherry-pick or rebase in progress after conflicts
+// resolved.
+func GitContinueHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	switch op := gitOperation(req.repo); op {
+	case "merge":
+		gitSequenceHandler(req, result, "git-merge", "commit", "--no-edit")
+	case "cherry-pick", "rebase":
+		gitSequenceHandler(req, result, "git-"+op, op, "--continue")
+	default:
+		result.Code = -1
+		result.Msg = req.msg("git-no-operation")
+	}
+}
+
+// GitAbortHandler handles request of aborting the merge, cherry-pick or rebase in progress.
+func GitAbortHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	op := gitOperation(req.repo)
+	if "" == op {
+		result.Code = -1
+		result.Msg = req.msg("git-no-operation")
+
+		return
+	}
+
+	if runGit(req, result, op, "--abort") {
+		refreshRepo(req)
+	}
+}
+
+// gitSequenceHandler runs a git command which may stop with conflicts (merge, cherry-pick, rebase or their
+// continuation) with the user as the committer, the output is pushed to the output channel under the specified name.
+func gitSequenceHandler(req *gitRequest, result *gulu.Result, name string, args ...string) {
+	author, email := req.user.GitAuthor()
+	cmd := gitCommand(req.repo, append([]string{"-c", "user.name=" + author, "-c", "user.email=" + email}, args...)...)
+	cmd.Env = append(cmd.Env, "GIT_EDITOR=true") // keeps the prepared commit messages
+
+	output, succ := runGitWithOutput(cmd, req.sid, req.locale, name)
+	refreshRepo(req)
+	if succ {
+		return
+	}
+
+	result.Code = -1
+	if conflicts := gitConflicts(req.repo); 0 < len(conflicts) || "git-merge-conflict" == gitErrorKey(output) {
+		result.Msg = req.msg("git-merge-conflict")
+		result.Data = map[string]interface{}{"operation": gitOperation(req.repo), "conflicts": conflicts}
+	}
+}
+
+// gitOperation returns the operation in progress of the repository of the specified root directory, "merge",
+// "cherry-pick", "rebase" or "" if none.
+func gitOperation(repo string) string {
+	dir := gitDir(repo)
+	switch {
+	case "" == dir:
+		return ""
+	case gulu.File.IsExist(filepath.Join(dir, "rebase-merge")) || gulu.File.IsExist(filepath.Join(dir, "rebase-apply")):
+		return "rebase"
+	case gulu.File.IsExist(filepath.Join(dir, "CHERRY_PICK_HEAD")):
+		return "cherry-pick"
+	case gulu.File.IsExist(filepath.Join(dir, "MERGE_HEAD")):
+		return "merge"
+	}
+
+	return ""
+}
+
+// GitConflictsHandler handles request of listing conflicted files of a repository.
+func GitConflictsHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "operation": gitOperation(req.repo),
+		"conflicts": gitConflicts(req.repo)}
+}
+
+// GitConflictVersionsHandler handles request of getting the base, ours and theirs versions of a conflicted file, along
+// with the current worktree content containing conflict markers.
+//
+// A version is "" if it does not exist, for example there is no base if the file was added on both sides.
+func GitConflictVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	rel, err := filepath.Rel(req.repo, req.path)
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	data := map[string]interface{}{"path": filepath.ToSlash(req.path)}
+	for i, name := range []string{"base", "ours", "theirs"} { // index stages 1, 2 and 3
+		out, _ := gitCommand(req.repo, "show", ":"+strconv.Itoa(i+1)+":"+rel).Output()
+		data[name] = string(out)
+	}
+
+	merged, _ := ioutil.ReadFile(req.path)
+	data["merged"] = string(merged)
+
+	result.Data = data
+}
+
+// GitResolveHandler handles request of resolving a conflicted file.
+//
+// The file is written with the specified "content" (the merged result), or checked out from "take" ("ours" or
+// "theirs"), then is marked resolved by staging it.
+func GitResolveHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	switch take := req.arg("take"); take {
+	case "ours", "theirs":
+		if !runGit(req, result, "checkout", "--"+take, "--", req.path) {
+			return
+		}
+	case "":
+		content, ok := req.args["content"].(string)
+		if !ok {
+			result.Code = -1
+
+			return
+		}
+
+		if err := ioutil.WriteFile(req.path, []byte(content), 0644); nil != err {
+			logger.Error(err)
+			result.Code = -1
+
+			return
+		}
+	default:
+		result.Code = -1
+
+		return
+	}
+
+	if !runGit(req, result, "add", "--", req.path) {
+		return
+	}
+
+	result.Data = map[string]interface{}{"conflicts": gitConflicts(req.repo)}
+}
+
+// gitConflicts returns paths of conflicted files of the repository of the specified root directory.
+func gitConflicts(repo string) []string {
+	ret := []string{}
+
+	out, err := gitCommand(repo, "status", "--porcelain", "-z").Output()
+	if nil != err {
+		logger.Warnf("Gets git status of [%s] failed [%s]", repo, err)
+
+		return ret
+	}
+
+	for _, entry := range parseGitStatus(out) {
+		if gitStatusConflicted == entry.Status {
+			ret = append(ret, filepath.ToSlash(filepath.Join(repo, entry.Path)))
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// textOperation represents an operation of operational transformation on a text, it's a sequence of components
+// applied from the start of the text: retain n characters, delete n characters or insert a string.
+//
+// Lengths are counted in UTF-16 code units, the same as JavaScript (and CodeMirror) counts, and an operation is
+// transported as a JSON array the same as ot.js does: retain n as n, delete n as -n and insert as the string.
+type textOperation struct {
+	components   []component
+	baseLength   int // length of the text the operation applies to
+	targetLength int // length of the text after applied
+}
+
+// component represents a component of a text operation.
+type component struct {
+	n      int      // > 0 for retain, < 0 for delete, 0 for insert
+	insert []uint16 // inserted text if n is 0
+}
+
+// retain appends a component retaining the specified number of characters.
+func (o *textOperation) retain(n int) {
+	if 1 > n {
+		return
+	}
+
+	o.baseLength += n
+	o.targetLength += n
+	if l := len(o.components); 0 < l && 0 < o.components[l-1].n {
+		o.components[l-1].n += n
+
+		return
+	}
+
+	o.components = append(o.components, component{n: n})
+}
+
+// delete appends a component deleting the specified number of characters.
+func (o *textOperation) delete(n int) {
+	if 1 > n {
+		return
+	}
+
+	o.baseLength += n
+	if l := len(o.components); 0 < l && 0 > o.components[l-1].n {
+		o.components[l-1].n -= n
+
+		return
+	}
+
+	o.components = append(o.components, component{n: -n})
+}
+
+// insert appends a component inserting the specified text, an insert is always put before the adjacent delete so
+// that equivalent operations have the same components.
+func (o *textOperation) insert(text []uint16) {
+	if 1 > len(text) {
+		return
+	}
+
+	o.targetLength += len(text)
+	l := len(o.components)
+	if 0 < l && 0 == o.components[l-1].n {
+		o.components[l-1].insert = append(o.components[l-1].insert, text...)
+
+		return
+	}
+
+	if 0 < l && 0 > o.components[l-1].n {
+		if 1 < l && 0 == o.components[l-2].n {
+			o.components[l-2].insert = append(o.components[l-2].insert, text...)
+
+			return
+		}
+
+		o.components = append(o.components, o.components[l-1])
+		o.components[l-1] = component{insert: append([]uint16{}, text...)}
+
+		return
+	}
+
+	o.components = append(o.components, component{insert: append([]uint16{}, text...)})
+}
+
+// apply applies the operation to the specified text.
+func (o *textOperation) apply(text []uint16) ([]uint16, error) {
+	if len(text) != o.baseLength {
+		return nil, fmt.Errorf("operation base length [%d] mismatches text length [%d]", o.baseLength, len(text))
+	}
+
+	ret := make([]uint16, 0, o.targetLength)
+	pos := 0
+	for _, c := range o.components {
+		switch {
+		case 0 < c.n:
+			ret = append(ret, text[pos:pos+c.n]...)
+			pos += c.n
+		case 0 > c.n:
+			pos -= c.n
+		default:
+			ret = append(ret, c.insert...)
+		}
+	}
+
+	return ret, nil
+}
+
+// transformIndex transforms the specified index (such as a cursor position) in the text the operation applies to into
+// the index in the text after applied, inserts at the index move it.
+func (o *textOperation) transformIndex(index int) int {
+	ret := index
+	for _, c := range o.components {
+		switch {
+		case 0 < c.n:
+			index -= c.n
+		case 0 > c.n:
+			ret -= minInt(index, -c.n)
+			index += c.n
+		default:
+			ret += len(c.insert)
+		}
+
+		if 0 > index {
+			break
+		}
+	}
+
+	return ret
+}
+
+// isNoop checks whether the operation changes nothing.
+func (o *textOperation) isNoop() bool {
+	return 0 == len(o.components) || (1 == len(o.components) && 0 < o.components[0].n)
+}
+
+// toJSON returns the JSON representation of the operation.
+func (o *textOperation) toJSON() []interface{} {
+	ret := []interface{}{}
+	for _, c := range o.components {
+		if 0 == c.n {
+			ret = append(ret, string(utf16.Decode(c.insert)))
+		} else {
+			ret = append(ret, c.n)
+		}
+	}
+
+	return ret
+}
+
+// parseTextOperation parses the specified JSON representation of an operation. Operations on texts longer than the
+// specified max length (in UTF-16 code units) or resulting in such texts are refused, so the lengths can't overflow.
+func parseTextOperation(value interface{}, maxLength int) (*textOperation, error) {
+	components, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("operation should be an array")
+	}
+
+	ret := &textOperation{}
+	for _, c := range components {
+		switch v := c.(type) {
+		case float64:
+			if v != math.Trunc(v) || 0 == v || float64(maxLength) < math.Abs(v) {
+				return nil, fmt.Errorf("invalid operation component [%v]", v)
+			}
+
+			if 0 < v {
+				ret.retain(int(v))
+			} else {
+				ret.delete(int(-v))
+			}
+		case string:
+			if "" == v {
+				return nil, errors.New("invalid empty insert component")
+			}
+
+			text := utf16.Encode([]rune(v))
+			if maxLength < len(text) {
+				return nil, fmt.Errorf("insert component of length [%d] exceeds the max length", len(text))
+			}
+
+			ret.insert(text)
+		default:
+			return nil, fmt.Errorf("invalid operation component [%v]", c)
+		}
+
+		if maxLength < ret.baseLength || maxLength < ret.targetLength {
+			return nil, fmt.Errorf("operation of lengths [%d, %d] exceeds the max length [%d]", ret.baseLength,
+				ret.targetLength, maxLength)
+		}
+	}
+
+	return ret, nil
+}
+
+// transform transforms the specified concurrent operations a and b (both apply to the same text) to a' and b' so that
+// applying a then b' results in the same text as applying b then a'. Inserts of a at the same position are put before
+// the ones of b.
+func transform(a, b *textOperation) (*textOperation, *textOperation, error) {
+	if a.baseLength != b.baseLength {
+		return nil, nil, fmt.Errorf("base lengths [%d] and [%d] of concurrent operations mismatch", a.baseLength,
+			b.baseLength)
+	}
+
+	aPrime, bPrime := &textOperation{}, &textOperation{}
+	i, j := 0, 0
+	c1, ok1 := nextComponent(a.components, &i)
+	c2, ok2 := nextComponent(b.components, &j)
+	for ok1 || ok2 {
+		if ok1 && 0 == c1.n {
+			aPrime.insert(c1.insert)
+			bPrime.retain(len(c1.insert))
+			c1, ok1 = nextComponent(a.components, &i)
+
+			continue
+		}
+		if ok2 && 0 == c2.n {
+			aPrime.retain(len(c2.insert))
+			bPrime.insert(c2.insert)
+			c2, ok2 = nextComponent(b.components, &j)
+
+			continue
+		}
+		if !ok1 || !ok2 {
+			return nil, nil, errors.New("concurrent operations have different lengths")
+		}
+
+		switch {
+		case 0 < c1.n && 0 < c2.n:
+			n := minInt(c1.n, c2.n)
+			aPrime.retain(n)
+			bPrime.retain(n)
+			c1.n -= n
+			c2.n -= n
+		case 0 > c1.n && 0 > c2.n: // deleted by both
+			n := minInt(-c1.n, -c2.n)
+			c1.n += n
+			c2.n += n
+		case 0 > c1.n && 0 < c2.n:
+			n := minInt(-c1.n, c2.n)
+			aPrime.delete(n)
+			c1.n += n
+			c2.n -= n
+		default: // c1 retains and c2 deletes
+			n := minInt(c1.n, -c2.n)
+			bPrime.delete(n)
+			c1.n -= n
+			c2.n += n
+		}
+
+		if 0 == c1.n {
+			c1, ok1 = nextComponent(a.components, &i)
+		}
+		if 0 == c2.n {
+			c2, ok2 = nextComponent(b.components, &j)
+		}
+	}
+
+	return aPrime, bPrime, nil
+}
+
+// nextComponent returns a copy of the component at the specified index and advances the index.
+func nextComponent(components []component, i *int) (component, bool) {
+	if *i >= len(components) {
+		return component{}, false
+	}
+
+	ret := components[*i]
+	*i++
+
+	return ret, true
+}
+
+// diffOperation returns an operation turning the specified text from into the specified text to, it replaces the part
+// between the common prefix and the common suffix. Surrogate pairs are never split.
+func diffOperation(from, to []uint16) *textOperation {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	if 0 < prefix && isHighSurrogate(from[prefix-1]) {
+		prefix--
+	}
+
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix &&
+		from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	if 0 < suffix && isLowSurrogate(from[len(from)-suffix]) {
+		suffix--
+	}
+
+	ret := &textOperation{}
+	ret.retain(prefix)
+	ret.delete(len(from) - prefix - suffix)
+	ret.insert(to[prefix : len(to)-suffix])
+	ret.retain(suffix)
+
+	return ret
+}
+
+// isHighSurrogate checks whether the specified code unit is the first one of a surrogate pair.
+func isHighSurrogate(u uint16) bool {
+	return 0xd800 <= u && u < 0xdc00
+}
+
+// isLowSurrogate checks whether the specified code unit is the second one of a surrogate pair.
+func isLowSurrogate(u uint16) bool {
+	return 0xdc00 <= u && u < 0xe000
+}
+
+// minInt returns the smaller one of the specified integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
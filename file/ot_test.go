@@ -0,0 +1,163 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// op parses the specified JSON representation of an operation.
+func op(t *testing.T, components ...interface{}) *textOperation {
+	for i, c := range components {
+		if n, ok := c.(int); ok {
+			components[i] = float64(n)
+		}
+	}
+
+	ret, err := parseTextOperation(components, 1<<20)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return ret
+}
+
+func TestTransform(t *testing.T) {
+	text := utf16.Encode([]rune("hello world"))
+	cases := []struct {
+		name     string
+		a, b     *textOperation
+		expected string
+	}{
+		{"inserts at different positions", op(t, 5, ",", 6), op(t, 11, "!"), "hello, world!"},
+		{"inserts at the same position", op(t, 5, " a", 6), op(t, 5, " b", 6), "hello a b world"},
+		{"insert inside delete", op(t, 2, "X", 9), op(t, 1, -4, 6), "hX world"},
+		{"overlapping deletes", op(t, 3, -5, 3), op(t, 5, -6), "hel"},
+		{"delete and retain", op(t, -6, 5), op(t, 11, "😀"), "world😀"},
+	}
+
+	for _, c := range cases {
+		aPrime, bPrime, err := transform(c.a, c.b)
+		if nil != err {
+			t.Fatalf("[%s]: %s", c.name, err)
+		}
+
+		ab, err := c.a.apply(text)
+		if nil == err {
+			ab, err = bPrime.apply(ab)
+		}
+		if nil != err {
+			t.Fatalf("[%s]: %s", c.name, err)
+		}
+		ba, err := c.b.apply(text)
+		if nil == err {
+			ba, err = aPrime.apply(ba)
+		}
+		if nil != err {
+			t.Fatalf("[%s]: %s", c.name, err)
+		}
+
+		if string(utf16.Decode(ab)) != string(utf16.Decode(ba)) {
+			t.Errorf("[%s]: diverged [%s] and [%s]", c.name, string(utf16.Decode(ab)), string(utf16.Decode(ba)))
+		}
+		if c.expected != string(utf16.Decode(ab)) {
+			t.Errorf("[%s]: expected [%s], got [%s]", c.name, c.expected, string(utf16.Decode(ab)))
+		}
+	}
+
+	if _, _, err := transform(op(t, 3), op(t, 4)); nil == err {
+		t.Error("transformed operations with different base lengths")
+	}
+}
+
+func TestParseTextOperation(t *testing.T) {
+	if _, err := parseTextOperation([]interface{}{1.5}, 1<<20); nil == err {
+		t.Error("parsed a fractional component")
+	}
+	if _, err := parseTextOperation([]interface{}{true}, 1<<20); nil == err {
+		t.Error("parsed a boolean component")
+	}
+
+	// lengths overflowing to 3 if not bounded
+	huge := float64(1 << 62)
+	if _, err := parseTextOperation([]interface{}{huge, huge, huge, -huge, float64(3)}, 1<<20); nil == err {
+		t.Error("parsed an operation of overflowing lengths")
+	}
+	for _, components := range [][]interface{}{{float64(8), float64(-3)}, {float64(2), "abcdefgh"},
+		{float64(-9)}, {math.Inf(1)}, {math.NaN()}} {
+		if _, err := parseTextOperation(components, 8); nil == err {
+			t.Errorf("parsed operation %v exceeding the max length", components)
+		}
+	}
+	if _, err := parseTextOperation([]interface{}{float64(4), "abcd", float64(-4)}, 8); nil != err {
+		t.Errorf("parse an operation of the max length failed [%s]", err)
+	}
+
+	o := op(t, 2, -1, "a", "b", -2, 3)
+	if 8 != o.baseLength || 7 != o.targetLength {
+		t.Errorf("lengths are [%d, %d]", o.baseLength, o.targetLength)
+	}
+	if json := o.toJSON(); 4 != len(json) || "ab" != json[1] || -3 != json[2] {
+		t.Errorf("components are %v", json)
+	}
+	if _, err := o.apply(make([]uint16, 7)); nil == err {
+		t.Error("applied to a text of a different length")
+	}
+}
+
+func TestDiffOperation(t *testing.T) {
+	cases := []struct{ from, to string }{
+		{"", "abc"},
+		{"abc", ""},
+		{"hello world", "hello, world"},
+		{"aaa", "aaaa"},
+		{"x😀y", "x😃y"},
+		{"same", "same"},
+	}
+
+	for _, c := range cases {
+		from := utf16.Encode([]rune(c.from))
+		o := diffOperation(from, utf16.Encode([]rune(c.to)))
+		got, err := o.apply(from)
+		if nil != err {
+			t.Fatalf("[%s] to [%s]: %s", c.from, c.to, err)
+		}
+		if c.to != string(utf16.Decode(got)) {
+			t.Errorf("[%s] to [%s]: got [%s]", c.from, c.to, string(utf16.Decode(got)))
+		}
+		for _, component := range o.toJSON() {
+			if s, ok := component.(string); ok && strings.ContainsRune(s, utf8.RuneError) {
+				t.Errorf("[%s] to [%s]: split a surrogate pair", c.from, c.to)
+			}
+		}
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	o := op(t, 2, "ab", 3, -4, 1) // "0123456789" to "01ab234" + "9"
+	cases := []struct{ index, expected int }{
+		{0, 0}, {2, 4}, {3, 5}, {5, 7}, {6, 7}, {9, 7}, {10, 8},
+	}
+
+	for _, c := range cases {
+		if got := o.transformIndex(c.index); c.expected != got {
+			t.Errorf("index [%d]: expected [%d], got [%d]", c.index, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// pathIndexIdle is the duration after which an unused path index is closed.
+const pathIndexIdle = 30 * time.Minute
+
+// pathIndex represents an in-memory index of the file paths under a directory, kept fresh by a file system watcher so
+// that finding files by name doesn't walk the directory.
+//
+// Symbols of the Go files are also indexed once used (see loadSymbols) for "open symbol" and finding usages.
+type pathIndex struct {
+	root    string            // the indexed directory
+	watcher *fsnotify.Watcher // watcher of the directories under root
+
+	mutex   sync.RWMutex
+	files   map[string]bool         // paths of the files under root
+	symbols map[string]*fileSymbols // symbols of the Go files under root, <path, symbols>, nil if not loaded yet
+	stale   bool                    // whether events may have been lost, the index is rebuilt on next use
+	used    time.Time               // the latest time the index was used
+}
+
+var (
+	// path indexes, <root, index>
+	pathIndexes = map[string]*pathIndex{}
+
+	// guards pathIndexes
+	pathIndexesMutex sync.Mutex
+)
+
+// LoadPathIndexes closes path indexes unused for pathIndexIdle periodically. Indexes are built on demand by FindHandler.
+func LoadPathIndexes() {
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for range time.Tick(time.Minute) {
+			closeIdlePathIndexes()
+		}
+	}()
+}
+
+// indexedFiles returns paths of the files under the specified root whose names match the specified pattern (see
+// matchFileName) from the index of the root, the index is built if not yet. Returns false if the index can't be built.
+func indexedFiles(root, pattern string) ([]string, bool) {
+	index, err := getPathIndex(root)
+	if nil != err {
+		logger.Warnf("Indexes paths of [%s] failed [%s]", root, err)
+
+		return nil, false
+	}
+
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	ret := []string{}
+	for path := range index.files {
+		if matchFileName(pattern, path) {
+			ret = append(ret, path)
+		}
+	}
+
+	return ret, true
+}
+
+// matchFileName determines whether the name of the specified file matches the specified shell pattern (such as
+// "*.go"), ignoring case.
+func matchFileName(pattern, path string) bool {
+	match, err := filepath.Match(strings.ToLower(pattern), strings.ToLower(filepath.Base(path)))
+	if nil != err {
+		return false
+	}
+
+	return match
+}
+
+// getPathIndex returns the index of the specified root, builds it if not found or stale.
+func getPathIndex(root string) (*pathIndex, error) {
+	pathIndexesMutex.Lock()
+	defer pathIndexesMutex.Unlock()
+
+	if index, ok := pathIndexes[root]; ok {
+		index.mutex.Lock()
+		stale := index.stale
+		index.used = time.Now()
+		index.mutex.Unlock()
+		if !stale {
+			return index, nil
+		}
+
+		index.close()
+		delete(pathIndexes, root)
+	}
+
+	index, err := newPathIndex(root)
+	if nil != err {
+		return nil, err
+	}
+	pathIndexes[root] = index
+
+	return index, nil
+}
+
+// closeIdlePathIndexes closes the indexes unused for pathIndexIdle.
+func closeIdlePathIndexes() {
+	pathIndexesMutex.Lock()
+	defer pathIndexesMutex.Unlock()
+
+	for root, index := range pathIndexes {
+		index.mutex.RLock()
+		idle := time.Since(index.used) > pathIndexIdle
+		index.mutex.RUnlock()
+
+		if idle {
+			index.close()
+			delete(pathIndexes, root)
+		}
+	}
+}
+
+// newPathIndex builds the index of the specified root and starts watching it.
+func newPathIndex(root string) (*pathIndex, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if nil != err {
+		return nil, err
+	}
+
+	ret := &pathIndex{root: root, watcher: watcher, files: map[string]bool{}, used: time.Now()}
+	if err := ret.add(root); nil != err {
+		ret.close()
+
+		return nil, err
+	}
+
+	go ret.watch()
+
+	return ret, nil
+}
+
+// close stops watching.
+func (index *pathIndex) close() {
+	if err := index.watcher.Close(); nil != err {
+		logger.Warnf("Closes the watcher of [%s] failed [%s]", index.root, err)
+	}
+}
+
+// watch applies the file system events to the index until the watcher is closed.
+func (index *pathIndex) watch() {
+	defer gulu.Panic.Recover(nil)
+
+	for {
+		select {
+		case e, ok := <-index.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if e.Op&fsnotify.Create == fsnotify.Create {
+				if err := index.add(e.Name); nil != err {
+					logger.Warnf("Watches [%s] failed [%s], the index will be rebuilt", e.Name, err)
+					index.markStale()
+				}
+			} else if e.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				index.remove(e.Name)
+			} else if e.Op&fsnotify.Write == fsnotify.Write {
+				index.updateSymbols(e.Name)
+			}
+		case err, ok := <-index.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.Warnf("Watcher of [%s] failed [%s], the index will be rebuilt", index.root, err)
+			index.markStale()
+		}
+	}
+}
+
+// markStale marks the index stale since events may have been lost.
+func (index *pathIndex) markStale() {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.stale = true
+}
+
+// add indexes the specified path, directories are walked and watched. Returns the error of watching a directory, the
+// index can't be kept fresh then.
+func (index *pathIndex) add(path string) error {
+	files := []string{}
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if nil != err {
+			return nil
+		}
+
+		if !info.IsDir() {
+			files = append(files, p)
+
+			return nil
+		}
+
+		if p != index.root && gulu.Str.Contains(info.Name(), defaultExcludesFind) {
+			return filepath.SkipDir
+		}
+
+		if err := index.watcher.Add(p); nil != err && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	})
+
+	index.mutex.Lock()
+	for _, file := range files {
+		index.files[file] = true
+	}
+	index.mutex.Unlock()
+
+	for _, file := range files {
+		index.updateSymbols(file)
+	}
+
+	return err
+}
+
+// remove removes the specified path and the paths under it from the index.
+func (index *pathIndex) remove(path string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	delete(index.files, path)
+	delete(index.symbols, path)
+
+	prefix := path + string(filepath.Separator)
+	for file := range index.files {
+		if strings.HasPrefix(file, prefix) {
+			delete(index.files, file)
+			delete(index.symbols, file)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPathIndex(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b", ".git/objects"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); nil != err {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"main.go", "a/b/B.go", "a/readme.md", ".git/objects/x.go"} {
+		if err := ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(file)), nil, 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := newPathIndex(root)
+	if nil != err {
+		t.Skip("can't watch files: ", err)
+	}
+	defer index.close()
+
+	find := func(pattern string) []string {
+		index.mutex.RLock()
+		defer index.mutex.RUnlock()
+
+		ret := []string{}
+		for path := range index.files {
+			if matchFileName(pattern, path) {
+				rel, _ := filepath.Rel(root, path)
+				ret = append(ret, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(ret)
+
+		return ret
+	}
+	await := func(pattern string, expected int) []string {
+		for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if len(find(pattern)) == expected {
+				break
+			}
+		}
+
+		return find(pattern)
+	}
+
+	if actual := find("*.go"); 2 != len(actual) || "a/b/B.go" != actual[0] || "main.go" != actual[1] {
+		t.Errorf("expected [a/b/B.go main.go], actual %v", actual)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "c", "d"), 0755); nil != err {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond) // the new directories are watched
+	if err := ioutil.WriteFile(filepath.Join(root, "c", "d", "new.go"), nil, 0644); nil != err {
+		t.Fatal(err)
+	}
+	if actual := await("new.GO", 1); 1 != len(actual) || "c/d/new.go" != actual[0] {
+		t.Errorf("expected [c/d/new.go] indexed after created, actual %v", actual)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "a")); nil != err {
+		t.Fatal(err)
+	}
+	if actual := await("*.go", 2); 2 != len(actual) || "c/d/new.go" != actual[0] || "main.go" != actual[1] {
+		t.Errorf("expected [c/d/new.go main.go] after a removed, actual %v", actual)
+	}
+}
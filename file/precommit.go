@@ -0,0 +1,257 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+// lintLinePattern matches lines of go vet and lint tools output, such as "main.go:12:2: msg" and "main.go:12: msg".
+var lintLinePattern = regexp.MustCompile(`^(.+?\.go):(\d+)(?::\d+)?: (.+)$`)
+
+// preCommitLint represents a failure of the pre-commit checks, the same as output.Lint.
+type preCommitLint struct {
+	File     string `json:"file"`
+	LineNo   int    `json:"lineNo"`
+	Severity string `json:"severity"`
+	Msg      string `json:"msg"`
+}
+
+// preCommitTimeout is the deadline of each pre-commit check command.
+const preCommitTimeout = 2 * time.Minute
+
+// preCommitChecks runs the pre-commit checks configured in Wide.PreCommitChecks on the Go files to commit of the
+// specified request, returns the failures.
+//
+// Check "gofmt" reports files not formatted, check "vet" runs go vet on the packages of the files, others are lint
+// commands (for example "golint ./...") run in the repository, whose output is reported if located in the files.
+//
+// The staged content is what gets committed, so it's checked instead of the worktree: files of the index are
+// exported to a temporary tree, gofmt and lint commands run there, go vet overlays them on the repository. The
+// worktree is checked if "all" is specified since it's committed then.
+func preCommitChecks(req *gitRequest) []*preCommitLint {
+	ret := []*preCommitLint{}
+
+	all := req.flag("all")
+	files := preCommitFiles(req.repo, all)
+	if 1 > len(conf.Wide().PreCommitChecks) || 1 > len(files) {
+		return ret
+	}
+
+	committing := map[string]bool{}
+	pkgs := map[string]bool{}
+	for _, file := range files {
+		committing[file] = true
+		pkgs["./"+filepath.ToSlash(filepath.Dir(file))] = true
+	}
+
+	dir := req.repo // where the checked files are
+	if !all {
+		staged, err := exportIndex(req.repo)
+		if nil != err {
+			logger.Warnf("Exports staged files of [%s] failed: %s", req.repo, err)
+
+			return ret
+		}
+		defer os.RemoveAll(staged)
+
+		dir = staged
+	}
+
+	for _, check := range conf.Wide().PreCommitChecks {
+		switch check {
+		case "gofmt":
+			out, _ := runPreCommitCommand(req.uid, dir, "gofmt", append([]string{"-l"}, files...)...)
+			for _, file := range strings.Fields(string(out)) {
+				if !committing[filepath.FromSlash(file)] { // syntax errors are reported by vet
+					continue
+				}
+
+				ret = append(ret, &preCommitLint{File: filepath.ToSlash(filepath.Join(req.repo, file)),
+					Severity: "error", Msg: req.msg("git-pre-commit-gofmt")})
+			}
+		case "vet":
+			args := []string{"vet"}
+			if dir != req.repo {
+				overlay, err := vetOverlay(req.repo, dir, pkgs)
+				if nil != err {
+					logger.Warnf("Creates vet overlay of [%s] failed: %s", req.repo, err)
+
+					continue
+				}
+
+				args = append(args, "-overlay="+overlay)
+			}
+			first := len(args)
+			for pkg := range pkgs {
+				args = append(args, pkg)
+			}
+			sort.Strings(args[first:])
+
+			out, _ := runPreCommitCommand(req.uid, req.repo, "go", args...)
+			ret = append(ret, parsePreCommitOutput(req.repo, dir, req.repo, out, nil)...)
+		default:
+			fields := strings.Fields(check)
+			if 1 > len(fields) {
+				continue
+			}
+
+			out, _ := runPreCommitCommand(req.uid, dir, fields[0], fields[1:]...)
+			ret = append(ret, parsePreCommitOutput(req.repo, dir, dir, out, committing)...)
+		}
+	}
+
+	return ret
+}
+
+// exportIndex exports files of the index of the specified repository to a temporary directory, returns the directory
+// which should be removed after used.
+func exportIndex(repo string) (string, error) {
+	dir, err := ioutil.TempDir("", "wide-staged-")
+	if nil != err {
+		return "", err
+	}
+
+	out, err := gitCommand(repo, "checkout-index", "--all", "--prefix="+dir+string(filepath.Separator)).CombinedOutput()
+	if nil != err {
+		os.RemoveAll(dir)
+
+		return "", fmt.Errorf("%s: %s", err, bytes.TrimSpace(out))
+	}
+
+	return dir, nil
+}
+
+// vetOverlay creates a go build overlay (in the specified staged directory) replacing Go files of the specified
+// packages of the specified repository with the staged ones, files not in the index are hidden. Returns path of the
+// overlay file.
+func vetOverlay(repo, staged string, pkgs map[string]bool) (string, error) {
+	replace := map[string]string{}
+	for pkg := range pkgs {
+		worktree, _ := filepath.Glob(filepath.Join(repo, filepath.FromSlash(pkg), "*.go"))
+		for _, file := range worktree {
+			replace[file] = ""
+		}
+
+		index, _ := filepath.Glob(filepath.Join(staged, filepath.FromSlash(pkg), "*.go"))
+		for _, file := range index {
+			rel, _ := filepath.Rel(staged, file)
+			replace[filepath.Join(repo, rel)] = file
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"Replace": replace})
+	if nil != err {
+		return "", err
+	}
+
+	ret := filepath.Join(staged, ".wide-vet-overlay.json")
+
+	return ret, ioutil.WriteFile(ret, data, 0644)
+}
+
+// preCommitFiles returns the Go files (relative to the repository) to commit of the specified repository. The
+// modified tracked files are included if all is true.
+func preCommitFiles(repo string, all bool) []string {
+	args := []string{"diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z"}
+	if all && gitHasHead(repo) {
+		args = []string{"diff", "HEAD", "--name-only", "--diff-filter=ACMR", "-z"}
+	}
+
+	out, err := gitCommand(repo, append(args, "--", "*.go")...).Output()
+	if nil != err {
+		logger.Warnf("Gets files to commit of [%s] failed: %s", repo, err)
+
+		return nil
+	}
+
+	ret := []string{}
+	for _, file := range strings.Split(string(out), "\x00") {
+		if "" != file {
+			ret = append(ret, filepath.FromSlash(file))
+		}
+	}
+
+	return ret
+}
+
+// parsePreCommitOutput parses the specified output of a check run in the specified working directory, paths in the
+// output are relative to the specified repository or the staged tree of it. Lines located in the specified files are
+// reported only if files is not nil.
+func parsePreCommitOutput(repo, staged, cwd string, out []byte, files map[string]bool) []*preCommitLint {
+	ret := []*preCommitLint{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "vet: ")
+		m := lintLinePattern.FindStringSubmatch(line)
+		if nil == m {
+			continue
+		}
+
+		file := filepath.FromSlash(m[1])
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(cwd, file)
+		}
+		for _, dir := range []string{staged, repo} {
+			rel, err := filepath.Rel(dir, file)
+			if nil == err && ".." != rel && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				file = rel
+
+				break
+			}
+		}
+		if nil != files && !files[file] {
+			continue
+		}
+
+		ret = append(ret, &preCommitLint{File: filepath.ToSlash(filepath.Join(repo, file)),
+			LineNo: atoi(m[2], 1) - 1, Severity: "error", Msg: m[3]})
+	}
+
+	return ret
+}
+
+// runPreCommitCommand runs the specified check command in the specified directory with the user's Go environment
+// and preCommitTimeout, returns the combined output.
+func runPreCommitCommand(uid, dir, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), preCommitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPATH="+conf.GetUserWorkspace(uid))
+
+	out, err := cmd.CombinedOutput()
+	if context.DeadlineExceeded == ctx.Err() {
+		logger.Warnf("Runs pre-commit check [%s] in [%s] timed out", name, dir)
+	}
+
+	return out, err
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/i18n"
+)
+
+// how long a calculated workspace usage is cached.
+const usageTTL = time.Minute
+
+// workspaceUsage represents the calculated disk usage of a user's workspace.
+type workspaceUsage struct {
+	size       int64
+	calculated time.Time
+}
+
+// cached workspace usages, <user id, usage>, guarded by usagesMutex.
+var usages = map[string]*workspaceUsage{}
+
+// usages lock.
+var usagesMutex sync.Mutex
+
+// quotaExceeded checks whether the workspace of the user specified by the given user id exceeds the quota
+// (Wide.WorkspaceQuota) after adding the specified size (in byte), returns the message to respond if exceeded,
+// returns "" otherwise. The user's scratches count against the quota as well.
+func quotaExceeded(uid string, size int64) string {
+	quota := conf.Wide().WorkspaceQuotaBytes()
+	if 0 >= quota {
+		return ""
+	}
+
+	usagesMutex.Lock()
+	usage := usages[uid]
+	if nil == usage || time.Since(usage.calculated) > usageTTL {
+		size := dirSize(conf.GetUserWorkspace(uid))
+		if scratches := scratchesPath(uid); "" != scratches {
+			size += dirSize(scratches)
+		}
+		usage = &workspaceUsage{size: size, calculated: time.Now()}
+		usages[uid] = usage
+	}
+	used := usage.size
+	usagesMutex.Unlock()
+
+	if used+size <= quota {
+		return ""
+	}
+
+	logger.Warnf("Workspace of user [%s] exceeds the quota [%dMB]", uid, conf.Wide().WorkspaceQuota)
+
+	return i18n.Get(conf.GetUser(uid).Locale, "workspace-quota-exceeded",
+		i18n.Params{"quota": conf.Wide().WorkspaceQuota}).(string)
+}
+
+// addUsage adds the specified size (in byte) to the cached usage of the workspace of the user specified by the given
+// user id, so that writes before the usage is calculated again are counted.
+func addUsage(uid string, size int64) {
+	usagesMutex.Lock()
+	defer usagesMutex.Unlock()
+
+	if usage := usages[uid]; nil != usage {
+		usage.size += size
+	}
+}
+
+// dirSize returns the total size (in byte) of regular files under the specified directory.
+func dirSize(dir string) int64 {
+	var ret int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if nil == err && info.Mode().IsRegular() {
+			ret += info.Size()
+		}
+
+		return nil
+	})
+
+	return ret
+}
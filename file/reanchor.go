@@ -0,0 +1,244 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+// maxReanchorCells is the max size (lines of the changed part of the old text × lines of the changed part of the new
+// text) of a line diff computed by the longest common subsequence, changed parts larger than it are taken as replaced.
+const maxReanchorCells = 1 << 20
+
+// reanchorReviewComments moves the worktree review comments of the specified file along with the lines they are
+// attached to after the file changed from the specified previous content to the current content.
+//
+// Lines are tracked with a line diff. If none of the lines of a comment survived, the comment is anchored to the
+// nearest occurrence of its anchor content, or kept near its previous position. Comments are marked outdated if the
+// content of their lines differs from the anchor content.
+func reanchorReviewComments(path, previous, current string) {
+	if previous == current {
+		return
+	}
+
+	reviewsMutex.Lock()
+	defer reviewsMutex.Unlock()
+
+	var mapping []int
+	var lines []string
+	for _, user := range conf.GetUsers() {
+		workspace, rel := reviewWorkspace(user, filepath.FromSlash(path))
+		if "" == workspace {
+			continue
+		}
+
+		reviews := loadReviewComments(user)
+		changed := false
+		for _, comment := range reviews[workspace] {
+			if rel != comment.Path || "" != comment.Commit {
+				continue
+			}
+
+			if nil == mapping {
+				lines = textLines(current)
+				mapping = mapLines(textLines(previous), lines)
+			}
+
+			changed = reanchorComment(comment, mapping, lines) || changed
+		}
+
+		if changed {
+			if err := saveReviewComments(user, reviews); nil != err {
+				logger.Errorf("Saves review comments of user [%s] failed: %s", user.Name, err)
+			}
+		}
+	}
+}
+
+// reanchorComment moves the specified comment by the specified line mapping (see mapLines) to the specified lines of
+// the current content, returns whether the comment changed.
+//
+// A boundary line of the range removed or changed is mapped next to the nearest line kept outside the range, so that a
+// range with lines rewritten covers the rewritten lines.
+func reanchorComment(comment *ReviewComment, mapping []int, lines []string) bool {
+	line, endLine, outdated := comment.Line, comment.EndLine, comment.Outdated
+
+	// new line numbers of the nearest lines kept before and after the range, and of the lines kept in the range
+	before, after, first, last := 0, len(lines)+1, 0, 0
+	for l := 1; l <= len(mapping); l++ {
+		m := mapping[l-1]
+		if 1 > m {
+			continue
+		}
+
+		if l < comment.Line {
+			before = m
+		} else if l <= comment.lastLine() {
+			if 0 == first {
+				first = m
+			}
+			last = m
+		} else {
+			after = m
+
+			break
+		}
+	}
+
+	count := comment.lastLine() - comment.Line + 1
+	if 0 == first {
+		first = findAnchor(lines, comment.Anchor, before+1)
+		if 0 == first {
+			first = before + 1
+			count = minInt(count, after-first)
+		}
+		last = first + count - 1
+	} else {
+		if 1 > mapping[comment.Line-1] {
+			first = before + 1
+		}
+		if comment.lastLine() > len(mapping) || 1 > mapping[comment.lastLine()-1] {
+			last = after - 1
+		}
+	}
+
+	first = maxInt(1, minInt(first, len(lines)))
+	last = maxInt(first, minInt(last, len(lines)))
+	comment.Line, comment.EndLine = first, 0
+	if last > first {
+		comment.EndLine = last
+	}
+	if "" != comment.Anchor {
+		comment.Outdated = anchorText(lines, first, last) != comment.Anchor
+	}
+
+	return line != comment.Line || endLine != comment.EndLine || outdated != comment.Outdated
+}
+
+// findAnchor returns the first line number of the occurrence of the specified anchor content in the specified lines
+// nearest to the specified line, returns 0 if not found.
+func findAnchor(lines []string, anchor string, near int) int {
+	if "" == anchor {
+		return 0
+	}
+
+	anchorLines := strings.Split(anchor, "\n")
+	ret := 0
+	for i := 0; i+len(anchorLines) <= len(lines); i++ {
+		if lines[i] != anchorLines[0] || anchorText(lines, i+1, i+len(anchorLines)) != anchor {
+			continue
+		}
+
+		if 0 == ret || absInt(i+1-near) < absInt(ret-near) {
+			ret = i + 1
+		}
+	}
+
+	return ret
+}
+
+// mapLines maps lines of the specified old lines to the specified new lines by a line diff, the i-th element of the
+// returned mapping is the line number (1-based) in the new lines of the (i+1)-th old line, 0 if the line is removed or
+// changed.
+func mapLines(oldLines, newLines []string) []int {
+	ret := make([]int, len(oldLines))
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		ret[prefix] = prefix + 1
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		ret[len(oldLines)-1-suffix] = len(newLines) - suffix
+		suffix++
+	}
+
+	olds, news := oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix]
+	if 0 == len(olds) || 0 == len(news) || maxReanchorCells < len(olds)*len(news) {
+		return ret
+	}
+
+	// lengths of the longest common subsequences of olds[i:] and news[j:]
+	lcs := make([][]int32, len(olds)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(news)+1)
+	}
+	for i := len(olds) - 1; 0 <= i; i-- {
+		for j := len(news) - 1; 0 <= j; j-- {
+			if olds[i] == news[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(olds) && j < len(news); {
+		if olds[i] == news[j] {
+			ret[prefix+i] = prefix + j + 1
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return ret
+}
+
+// textLines splits the specified text into lines.
+func textLines(text string) []string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+
+	return strings.Split(strings.Replace(text, "\r", "\n", -1), "\n")
+}
+
+// anchorText returns the content of the lines from the specified first line to the specified last line (1-based),
+// returns "" if out of range.
+func anchorText(lines []string, first, last int) string {
+	if 1 > first || last > len(lines) || first > last {
+		return ""
+	}
+
+	return strings.Join(lines[first-1:last], "\n")
+}
+
+// maxInt returns the larger one of the specified integers.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// absInt returns the absolute value of the specified integer.
+func absInt(a int) int {
+	if 0 > a {
+		return -a
+	}
+
+	return a
+}
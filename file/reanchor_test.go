@@ -0,0 +1,78 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapLines(t *testing.T) {
+	cases := []struct {
+		old, new string
+		expected []int
+	}{
+		{"a\nb\nc", "a\nb\nc", []int{1, 2, 3}},
+		{"a\nb\nc", "x\na\nb\nc", []int{2, 3, 4}},
+		{"a\nb\nc", "a\nc", []int{1, 0, 2}},
+		{"a\nb\nc\nd", "a\nx\nc\ny\nd", []int{1, 0, 3, 5}},
+		{"a\nb\nc\nd", "d\nc\nb\na", []int{0, 0, 0, 1}},
+	}
+
+	for _, c := range cases {
+		actual := mapLines(strings.Split(c.old, "\n"), strings.Split(c.new, "\n"))
+		if !reflect.DeepEqual(c.expected, actual) {
+			t.Errorf("map [%q] to [%q]: expected %v, actual %v", c.old, c.new, c.expected, actual)
+		}
+	}
+}
+
+func TestReanchorComment(t *testing.T) {
+	previous := textLines("package main\n\nfunc main() {\n\tprintln(42)\n}\n")
+
+	// lines inserted above
+	current := textLines("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tprintln(42)\n}\n")
+	comment := &ReviewComment{Line: 3, EndLine: 4, Anchor: anchorText(previous, 3, 4)}
+	if !reanchorComment(comment, mapLines(previous, current), current) || 5 != comment.Line || 6 != comment.EndLine ||
+		comment.Outdated {
+		t.Errorf("reanchor below inserted lines: unexpected [%+v]", comment)
+	}
+
+	// a line of the range changed
+	current = textLines("package main\n\nfunc main() {\n\tprintln(43)\n}\n")
+	comment = &ReviewComment{Line: 3, EndLine: 4, Anchor: anchorText(previous, 3, 4)}
+	if !reanchorComment(comment, mapLines(previous, current), current) || 3 != comment.Line || 4 != comment.EndLine ||
+		!comment.Outdated {
+		t.Errorf("reanchor changed range: unexpected [%+v]", comment)
+	}
+
+	// the range moved below
+	current = textLines("package main\n\nfunc init() {\n}\n\nfunc main() {\n\tprintln(42)\n}\n")
+	comment = &ReviewComment{Line: 4, Anchor: anchorText(previous, 4, 4)}
+	reanchorComment(comment, mapLines(previous, current), current)
+	if 7 != comment.Line || comment.Outdated {
+		t.Errorf("reanchor moved range: unexpected [%+v]", comment)
+	}
+
+	// the line removed, anchored to the moved content
+	previous = textLines("a\nb\nc\nd")
+	current = textLines("a\nc\nd\nb")
+	comment = &ReviewComment{Line: 2, Anchor: "b"}
+	reanchorComment(comment, mapLines(previous, current), current)
+	if 4 != comment.Line || comment.Outdated {
+		t.Errorf("reanchor removed line: unexpected [%+v]", comment)
+	}
+}
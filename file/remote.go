@@ -0,0 +1,326 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// GitCredentialHandler handles request of setting the user's git credential.
+//
+// The credential is encrypted in the vault. An empty credential (no username, password and SSH key) revokes the
+// stored one.
+func GitCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	credential := &conf.GitCredential{}
+	if err := json.NewDecoder(r.Body).Decode(credential); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if "" == credential.Username && "" == credential.Password && "" == credential.SSHKey {
+		credential = nil
+	}
+
+	if err := user.SetGitCredential(credential); nil != err {
+		logger.Errorf("Sets git credential of user [%s] failed: %s", user.Name, err)
+		result.Code = -1
+
+		return
+	}
+
+	if !user.Save() {
+		result.Code = -1
+	}
+}
+
+// GitRevokeCredentialHandler handles request of revoking the user's git credential.
+func GitRevokeCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	user.SetGitCredential(nil)
+	if !user.Save() {
+		result.Code = -1
+	}
+}
+
+// GitTestCredentialHandler handles request of testing a git credential against a remote repository "url".
+//
+// Tests the credential carried by the request ("username", "password" and "sshKey") if any, the stored one otherwise.
+// Result data is the user name of the stored credential and whether a password and an SSH key are stored, secrets
+// are never returned.
+func GitTestCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	stored := user.GetGitCredential()
+	if nil != stored {
+		result.Data = map[string]interface{}{"username": stored.Username, "password": "" != stored.Password,
+			"sshKey": "" != stored.SSHKey}
+	}
+
+	url, _ := args["url"].(string)
+	url = strings.TrimSpace(url)
+	if "" == url {
+		return
+	}
+
+	if !gitURLPattern.MatchString(url) {
+		result.Code = -1
+		result.Msg = i18n.Get(user.Locale, "git-invalid-url").(string)
+
+		return
+	}
+
+	credential := stored
+	username, _ := args["username"].(string)
+	password, _ := args["password"].(string)
+	sshKey, _ := args["sshKey"].(string)
+	if "" != username || "" != password || "" != sshKey {
+		credential = &conf.GitCredential{Username: username, Password: password, SSHKey: sshKey}
+	}
+
+	cmd := gitCommand(conf.Wide().Data, "ls-remote", "--heads", "--", url)
+	keyFile := setGitCredentialEnv(cmd, uid, credential)
+	defer removeKeyFile(keyFile)
+
+	if out, err := cmd.CombinedOutput(); nil != err {
+		logger.Debugf("Tests git credential of user [%s] against [%s] failed: %s", user.Name, url, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+		if key := gitErrorKey(string(out)); "" != key {
+			result.Msg = i18n.Get(user.Locale, key).(string)
+		}
+	}
+}
+
+// GitPushHandler handles request of pushing the current branch to its remote.
+func GitPushHandler(w http.ResponseWriter, r *http.Request) {
+	gitRemoteHandler(w, r, "push")
+}
+
+// GitPullHandler handles request of pulling the current branch from its remote.
+func GitPullHandler(w http.ResponseWriter, r *http.Request) {
+	gitRemoteHandler(w, r, "pull")
+}
+
+// GitFetchHandler handles request of fetching from a remote.
+func GitFetchHandler(w http.ResponseWriter, r *http.Request) {
+	gitRemoteHandler(w, r, "fetch")
+}
+
+// gitRemoteHandler runs the specified git remote operation ("push", "pull" or "fetch") with the user's credential.
+//
+// Optional arguments: "remote", "branch", "setUpstream" (push), "force" (push) and "rebase" (pull).
+func gitRemoteHandler(w http.ResponseWriter, r *http.Request, op string) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	remote := req.arg("remote")
+	branch := req.arg("branch")
+	if strings.HasPrefix(remote, "-") || strings.HasPrefix(branch, "-") {
+		result.Code = -1
+
+		return
+	}
+
+	gitArgs := []string{op, "--progress"}
+	switch op {
+	case "push":
+		if req.flag("setUpstream") {
+			gitArgs = append(gitArgs, "--set-upstream")
+		}
+		if req.flag("force") {
+			gitArgs = append(gitArgs, "--force-with-lease")
+		}
+	case "pull":
+		if req.flag("rebase") {
+			gitArgs = append(gitArgs, "--rebase")
+		}
+
+		// a merge commit may be created by pull
+		name, email := req.user.GitAuthor()
+		gitArgs = append([]string{"-c", "user.name=" + name, "-c", "user.email=" + email}, gitArgs...)
+	case "fetch":
+		gitArgs = append(gitArgs, "--prune")
+	}
+	if "" != remote {
+		gitArgs = append(gitArgs, "--", remote)
+
+		if "" != branch && "fetch" != op {
+			gitArgs = append(gitArgs, branch)
+		}
+	}
+
+	cmd := gitCommand(req.repo, gitArgs...)
+	keyFile := setGitCredentialEnv(cmd, req.uid, req.user.GetGitCredential())
+	defer removeKeyFile(keyFile)
+
+	output, succ := runGitWithOutput(cmd, req.sid, req.locale, "git-"+op)
+	if succ {
+		return
+	}
+
+	result.Code = -1
+	if key := gitErrorKey(output); "" != key {
+		result.Msg = req.msg(key)
+		if "git-merge-conflict" == key {
+			result.Data = map[string]interface{}{"conflicts": gitConflicts(req.repo)}
+		}
+		pushOutput(req.sid, map[string]interface{}{"cmd": "git-" + op + "-done"},
+			"<span class='stderr'>"+result.Msg+"</span>\n")
+	}
+}
+
+// gitErrorKey recognizes common errors in the specified output of a git remote operation, returns the i18n key of the
+// error message or "" if not recognized.
+func gitErrorKey(output string) string {
+	switch {
+	case strings.Contains(output, "non-fast-forward") || strings.Contains(output, "[rejected]") ||
+		strings.Contains(output, "fetch first"):
+		return "git-non-fast-forward"
+	case strings.Contains(output, "Authentication failed") || strings.Contains(output, "could not read Username") ||
+		strings.Contains(output, "Permission denied") || strings.Contains(output, "Invalid username or password"):
+		return "git-auth-failed"
+	case strings.Contains(output, "Could not resolve host") || strings.Contains(output, "Connection timed out") ||
+		strings.Contains(output, "Could not read from remote repository"):
+		return "git-remote-unreachable"
+	case strings.Contains(output, "no upstream branch") || strings.Contains(output, "There is no tracking information"):
+		return "git-no-upstream"
+	case strings.Contains(output, "CONFLICT") || strings.Contains(output, "Automatic merge failed"):
+		return "git-merge-conflict"
+	}
+
+	return ""
+}
+
+// setGitCredentialEnv sets the specified git credential of the user specified by uid into the environment of the
+// specified git command.
+//
+// Returns the path of the temporary SSH key file which should be removed after the command finished, returns "" if no
+// SSH key used.
+func setGitCredentialEnv(cmd *exec.Cmd, uid string, credential *conf.GitCredential) string {
+	if nil == credential {
+		return ""
+	}
+
+	setGitAuthEnv(cmd, credential.Username, credential.Password)
+
+	if "" == credential.SSHKey {
+		return ""
+	}
+
+	dir := filepath.Join(conf.Wide().Data, "ssh")
+	if err := os.MkdirAll(dir, 0700); nil != err {
+		logger.Error(err)
+
+		return ""
+	}
+
+	keyFile, err := ioutil.TempFile(dir, uid+"-")
+	if nil != err {
+		logger.Error(err)
+
+		return ""
+	}
+	keyFile.WriteString(strings.TrimSpace(credential.SSHKey) + "\n")
+	keyFile.Close()
+	os.Chmod(keyFile.Name(), 0600)
+
+	cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -i '"+keyFile.Name()+
+		"' -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=accept-new")
+
+	return keyFile.Name()
+}
+
+// removeKeyFile removes the temporary SSH key file of the specified path.
+func removeKeyFile(path string) {
+	if "" == path {
+		return
+	}
+
+	if err := os.Remove(path); nil != err {
+		logger.Errorf("Removes SSH key file [%s] failed: [%s]", path, err.Error())
+	}
+}
+
+// GitPull pulls the repository of the specified root directory with the git credential of the specified user. Only
+// fast-forward is allowed, so a pull nobody watches (such as the one of a trigger) never leaves conflicts in the
+// workspace. Returns the output of git.
+func GitPull(user *conf.User, repo string) (string, error) {
+	cmd := gitCommand(repo, "pull", "--ff-only")
+	keyFile := setGitCredentialEnv(cmd, user.Id, user.GetGitCredential())
+	defer removeKeyFile(keyFile)
+
+	output, err := cmd.CombinedOutput()
+
+	return string(output), err
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// ReviewComment represents a code review comment attached to a line range of a file, which starts a discussion thread
+// continued by replies.
+//
+// Comments of the worktree are re-anchored when the file saved, see reanchorReviewComments.
+type ReviewComment struct {
+	Id         string         `json:"id"`
+	Path       string         `json:"path"`               // file path relative to the workspace, in slash form
+	Line       int            `json:"line"`               // line number (1-based)
+	EndLine    int            `json:"endLine,omitempty"`  // last line number of the range, 0 for the line only
+	Anchor     string         `json:"anchor,omitempty"`   // content of the lines when commented
+	Outdated   bool           `json:"outdated,omitempty"` // whether the lines have been changed since commented
+	Commit     string         `json:"commit"`             // commit id the comment is tied to, "" for the worktree
+	Author     string         `json:"author"`             // user name of the author
+	Content    string         `json:"content"`            // comment content
+	Created    int64          `json:"created"`            // create time in unix milliseconds
+	Date       string         `json:"date,omitempty"`     // create time in the time zone of the requesting user, not saved
+	Resolved   bool           `json:"resolved"`           // whether is resolved
+	ResolvedBy string         `json:"resolvedBy"`         // user name of the resolver
+	Replies    []*ReviewReply `json:"replies,omitempty"`  // replies in the thread
+}
+
+// ReviewReply represents a reply in the discussion thread of a review comment.
+type ReviewReply struct {
+	Id      string `json:"id"`
+	Author  string `json:"author"`         // user name of the author
+	Content string `json:"content"`        // reply content
+	Created int64  `json:"created"`        // create time in unix milliseconds
+	Date    string `json:"date,omitempty"` // create time in the time zone of the requesting user, not saved
+}
+
+// reviewsMutex guards review comments files.
+var reviewsMutex sync.Mutex
+
+// AddReviewCommentHandler handles request of adding a review comment to a line (or lines from "line" to "endLine") of a
+// file.
+//
+// Arguments: "path", "pathtype", "line", "content", optional "endLine", "commit" and "owner" (see reviewRequest).
+func AddReviewCommentHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newReviewRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	content, _ := req.args["content"].(string)
+	content = strings.TrimSpace(content)
+	line, _ := req.args["line"].(float64)
+	if "" == req.workspace || "" == content || 1 > line {
+		result.Code = -1
+
+		return
+	}
+
+	endLine, _ := req.args["endLine"].(float64)
+	if endLine <= line {
+		endLine = 0
+	}
+
+	commit, _ := req.args["commit"].(string)
+	comment := &ReviewComment{Id: gulu.Rand.String(16), Path: req.rel, Line: int(line), EndLine: int(endLine),
+		Commit: strings.TrimSpace(commit), Author: req.user.Name, Content: content,
+		Created: time.Now().UnixNano() / int64(time.Millisecond)}
+	if "" == comment.Commit {
+		if data, err := ioutil.ReadFile(req.path); nil == err {
+			comment.Anchor = anchorText(textLines(string(data)), comment.Line, comment.lastLine())
+		}
+	}
+
+	reviewsMutex.Lock()
+	defer reviewsMutex.Unlock()
+
+	reviews := loadReviewComments(req.owner)
+	reviews[req.workspace] = append(reviews[req.workspace], comment)
+	if err := saveReviewComments(req.owner, reviews); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	comment.Date = req.user.FormatUnixMilli(comment.Created)
+	result.Data = comment
+}
+
+// GetReviewCommentsHandler handles request of listing review comments of a file, or of files under a directory.
+//
+// Resolved comments are listed only if "resolved" is true. Paths of the returned comments are absolute.
+func GetReviewCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newReviewRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	if "" == req.workspace {
+		result.Code = -1
+
+		return
+	}
+
+	includeResolved, _ := req.args["resolved"].(bool)
+
+	reviewsMutex.Lock()
+	comments := loadReviewComments(req.owner)[req.workspace]
+	reviewsMutex.Unlock()
+
+	ret := []*ReviewComment{}
+	for _, comment := range comments {
+		if comment.Resolved && !includeResolved {
+			continue
+		}
+
+		if "." != req.rel && comment.Path != req.rel && !strings.HasPrefix(comment.Path, req.rel+"/") {
+			continue
+		}
+
+		comment.Path = filepath.ToSlash(filepath.Join(req.workspace, filepath.FromSlash(comment.Path)))
+		comment.Date = req.user.FormatUnixMilli(comment.Created)
+		for _, reply := range comment.Replies {
+			reply.Date = req.user.FormatUnixMilli(reply.Created)
+		}
+		ret = append(ret, comment)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+
+		return ret[i].Line < ret[j].Line
+	})
+
+	result.Data = ret
+}
+
+// ResolveReviewCommentHandler handles request of resolving (or reopening if "resolved" is false) a review comment.
+//
+// Arguments: "path" (the commented file), "pathtype", "id", "resolved" and optional "owner" (see reviewRequest).
+func ResolveReviewCommentHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newReviewRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	id, _ := req.args["id"].(string)
+	if "" == req.workspace || "" == id {
+		result.Code = -1
+
+		return
+	}
+
+	resolved := true
+	if value, ok := req.args["resolved"].(bool); ok {
+		resolved = value
+	}
+
+	reviewsMutex.Lock()
+	defer reviewsMutex.Unlock()
+
+	reviews := loadReviewComments(req.owner)
+	comment := findReviewComment(reviews[req.workspace], id)
+	if nil == comment {
+		result.Code = -1
+
+		return
+	}
+
+	comment.Resolved = resolved
+	comment.ResolvedBy = ""
+	if resolved {
+		comment.ResolvedBy = req.user.Name
+	}
+
+	if err := saveReviewComments(req.owner, reviews); nil != err {
+		logger.Error(err)
+		result.Code = -1
+	}
+}
+
+// ReplyReviewCommentHandler handles request of replying to the discussion thread of a review comment.
+//
+// Arguments: "path" (the commented file), "pathtype", "id", "content" and optional "owner" (see reviewRequest).
+func ReplyReviewCommentHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newReviewRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	id, _ := req.args["id"].(string)
+	content, _ := req.args["content"].(string)
+	content = strings.TrimSpace(content)
+	if "" == req.workspace || "" == id || "" == content {
+		result.Code = -1
+
+		return
+	}
+
+	reviewsMutex.Lock()
+	defer reviewsMutex.Unlock()
+
+	reviews := loadReviewComments(req.owner)
+	comment := findReviewComment(reviews[req.workspace], id)
+	if nil == comment {
+		result.Code = -1
+
+		return
+	}
+
+	reply := &ReviewReply{Id: gulu.Rand.String(16), Author: req.user.Name, Content: content,
+		Created: time.Now().UnixNano() / int64(time.Millisecond)}
+	comment.Replies = append(comment.Replies, reply)
+	if err := saveReviewComments(req.owner, reviews); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	reply.Date = req.user.FormatUnixMilli(reply.Created)
+	result.Data = reply
+}
+
+// findReviewComment returns the comment of the specified id in the specified comments, returns nil if not found.
+func findReviewComment(comments []*ReviewComment, id string) *ReviewComment {
+	for _, comment := range comments {
+		if id == comment.Id {
+			return comment
+		}
+	}
+
+	return nil
+}
+
+// lastLine returns the last line number of the range of the comment.
+func (comment *ReviewComment) lastLine() int {
+	if comment.EndLine > comment.Line {
+		return comment.EndLine
+	}
+
+	return comment.Line
+}
+
+// reviewRequest represents a parsed review request.
+type reviewRequest struct {
+	user      *conf.User             // the reviewer (current user)
+	owner     *conf.User             // owner of the reviewed workspace
+	path      string                 // absolute path of the reviewed file or directory
+	workspace string                 // the owner's workspace containing the path, "" if not found
+	rel       string                 // path relative to the workspace in slash form
+	args      map[string]interface{} // request arguments
+}
+
+// newReviewRequest parses the specified review request.
+//
+// Files of another user's workspace are reviewed by specifying the user id as "owner", which is allowed for the
+// reviewers listed in the owner's Reviewers and the administrators. The path is resolved against the owner's
+// workspace. Returns nil if the request can't be served, the response has been written into w or the specified
+// result then.
+func newReviewRequest(w http.ResponseWriter, r *http.Request, result *gulu.Result) *reviewRequest {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return nil
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return nil
+	}
+
+	user := conf.GetUser(uid)
+	owner := user
+	if ownerId, _ := args["owner"].(string); "" != ownerId && uid != ownerId {
+		owner = conf.GetUser(ownerId)
+		if nil == owner || (!owner.IsReviewer(uid) && !conf.Wide().IsAdmin(uid)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+
+			return nil
+		}
+	}
+
+	pathArg, _ := args["path"].(string)
+	path, _ := GetPath(owner.Id, pathArg, fmt.Sprint(args["pathtype"]))
+	if !session.CanAccess(owner.Id, path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return nil
+	}
+
+	ret := &reviewRequest{user: user, owner: owner, path: path, args: args}
+	ret.workspace, ret.rel = reviewWorkspace(owner, path)
+
+	return ret
+}
+
+// reviewWorkspace returns the workspace of the specified user containing the specified path and the path relative
+// to the workspace in slash form, returns "" workspace if not found.
+func reviewWorkspace(user *conf.User, path string) (workspace, rel string) {
+	for _, workspace := range filepath.SplitList(user.WorkspacePath()) {
+		rel, err := filepath.Rel(workspace, path)
+		if nil == err && ".." != rel && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return workspace, filepath.ToSlash(rel)
+		}
+	}
+
+	return "", ""
+}
+
+// loadReviewComments loads review comments of workspaces of the specified user from {Wide.Data}/reviews/{userId}.json,
+// keyed by workspace.
+//
+// Comments kept in {workspace}/.wide/reviews.json by previous versions are moved in.
+func loadReviewComments(user *conf.User) map[string][]*ReviewComment {
+	ret := map[string][]*ReviewComment{}
+
+	data, err := ioutil.ReadFile(reviewCommentsPath(user))
+	if nil == err {
+		if err := json.Unmarshal(data, &ret); nil != err {
+			logger.Errorf("Parses review comments of user [%s] failed: %s", user.Name, err)
+		}
+	} else if !os.IsNotExist(err) {
+		logger.Error(err)
+	}
+
+	migrated := false
+	for _, workspace := range filepath.SplitList(user.WorkspacePath()) {
+		legacy := filepath.Join(workspace, ".wide", "reviews.json")
+		data, err := ioutil.ReadFile(legacy)
+		if nil != err {
+			continue
+		}
+
+		comments := []*ReviewComment{}
+		if err := json.Unmarshal(data, &comments); nil != err {
+			logger.Errorf("Parses review comments [%s] failed: %s", legacy, err)
+
+			continue
+		}
+
+		ret[workspace] = append(ret[workspace], comments...)
+		migrated = true
+	}
+
+	if migrated {
+		if err := saveReviewComments(user, ret); nil != err {
+			logger.Errorf("Moves review comments of user [%s] failed: %s", user.Name, err)
+
+			return ret
+		}
+
+		for _, workspace := range filepath.SplitList(user.WorkspacePath()) {
+			os.Remove(filepath.Join(workspace, ".wide", "reviews.json"))
+			os.Remove(filepath.Join(workspace, ".wide")) // only if empty
+		}
+	}
+
+	return ret
+}
+
+// saveReviewComments saves the specified review comments (keyed by workspace) of the specified user into
+// {Wide.Data}/reviews/{userId}.json.
+func saveReviewComments(user *conf.User, reviews map[string][]*ReviewComment) error {
+	path := reviewCommentsPath(user)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	data, err := json.MarshalIndent(reviews, "", "    ")
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// reviewCommentsPath returns the path of the review comments file of the specified user.
+func reviewCommentsPath(user *conf.User) string {
+	return filepath.Join(conf.Wide().Data, "reviews", user.Id+".json")
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/session"
+)
+
+var (
+	// contents of files last opened or saved by sessions, <sid, <file path, content>>
+	saveBases = map[string]map[string]string{}
+
+	// guards saveBases
+	saveBasesMutex sync.Mutex
+)
+
+// LoadSaveMerge registers the handler clearing the save bases of closed sessions.
+//
+// A file saved by a session is merged with the changes saved by other sessions since the session opened (or last
+// saved) it, instead of overwriting them. The content the session got is the base of the three-way merge, a conflict
+// is not saved and the sessions involved are notified with EvtCodeSaveConflict events.
+//
+// The base is also the buffer a session saves patches against (see patchSave) instead of the whole content.
+func LoadSaveMerge() {
+	session.SessionClosedHandlers = append(session.SessionClosedHandlers, clearSaveBases)
+}
+
+// setSaveBase records the specified content as the base of the specified file opened or saved by the specified
+// session.
+func setSaveBase(sid, path, content string) {
+	if "" == sid {
+		return
+	}
+
+	saveBasesMutex.Lock()
+	defer saveBasesMutex.Unlock()
+
+	bases := saveBases[sid]
+	if nil == bases {
+		bases = map[string]string{}
+		saveBases[sid] = bases
+	}
+	bases[path] = content
+}
+
+// saveBase returns the base of the specified file of the specified session, returns false if the session never opened
+// the file.
+func saveBase(sid, path string) (string, bool) {
+	saveBasesMutex.Lock()
+	defer saveBasesMutex.Unlock()
+
+	ret, ok := saveBases[sid][path]
+
+	return ret, ok
+}
+
+// saveBaseHolders returns ids of the sessions other than the specified one having the specified file opened.
+func saveBaseHolders(sid, path string) []string {
+	saveBasesMutex.Lock()
+	defer saveBasesMutex.Unlock()
+
+	ret := []string{}
+	for holder, bases := range saveBases {
+		if _, ok := bases[path]; ok && holder != sid {
+			ret = append(ret, holder)
+		}
+	}
+
+	return ret
+}
+
+// clearSaveBases removes the bases of the specified session.
+func clearSaveBases(sid string) {
+	saveBasesMutex.Lock()
+	defer saveBasesMutex.Unlock()
+
+	delete(saveBases, sid)
+}
+
+// mergeSave merges the content being saved by the specified session with the current content of the specified file.
+//
+// Returns the content to save and the number of conflicts, the content being saved is returned as is if the file
+// hasn't been changed by others since the session got its base.
+func mergeSave(sid, path, current, code string) (string, int) {
+	base, ok := saveBase(sid, path)
+	if !ok || base == current || code == current {
+		return code, 0
+	}
+
+	merged, conflicts, err := mergeText(base, current, code)
+	if nil != err {
+		logger.Warnf("Merges file [%s] failed [%s], overwrites it", path, err)
+
+		return code, 0
+	}
+
+	return merged, conflicts
+}
+
+// patchSave applies the specified patch (a text operation, see textOperation) sent by the specified session to its base
+// of the specified file. The specified checksum (see textChecksum) of the text the patch made against is compared with
+// the one of the base.
+//
+// The patch is refused if the text is longer than the specified max length (in UTF-16 code units, see
+// parseTextOperation).
+//
+// Returns false if the session has no base of the file or the base is out of sync with the client, the client should
+// save the whole content instead.
+func patchSave(sid, path string, patch interface{}, checksum float64, maxLength int) (string, bool) {
+	base, ok := saveBase(sid, path)
+	if !ok {
+		return "", false
+	}
+
+	text := utf16.Encode([]rune(base))
+	if uint32(checksum) != textChecksum(text) {
+		return "", false
+	}
+
+	op, err := parseTextOperation(patch, maxLength)
+	if nil != err {
+		logger.Warnf("Parse save patch of file [%s] failed [%s]", path, err)
+
+		return "", false
+	}
+
+	patched, err := op.apply(text)
+	if nil != err {
+		return "", false
+	}
+
+	return string(utf16.Decode(patched)), true
+}
+
+// textChecksum returns the 32-bit FNV-1a hash of the UTF-16 code units of a text, the same as the client computes.
+func textChecksum(text []uint16) uint32 {
+	ret := uint32(2166136261)
+	for _, u := range text {
+		ret ^= uint32(u)
+		ret *= 16777619
+	}
+
+	return ret
+}
+
+// notifySaveConflict notifies the specified session and the other sessions having the specified file opened of a save
+// conflict caused by the specified user.
+func notifySaveConflict(sid, uid, path string) {
+	conflict := &event.SaveConflict{UserId: uid, Path: path}
+	event.Publish(&event.Event{Code: event.EvtCodeSaveConflict, Sid: sid, Data: conflict})
+	for _, holder := range saveBaseHolders(sid, path) {
+		event.Publish(&event.Event{Code: event.EvtCodeSaveConflict, Sid: holder, Data: conflict})
+	}
+}
+
+// mergeText merges the changes from base to theirs into ours with git merge-file.
+//
+// Returns the merged text and the number of conflicts, conflicts are marked in the merged text.
+func mergeText(base, ours, theirs string) (string, int, error) {
+	dir, err := ioutil.TempDir("", "wide-merge-")
+	if nil != err {
+		return "", 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{filepath.Join(dir, "ours"), filepath.Join(dir, "base"), filepath.Join(dir, "theirs")}
+	for i, content := range []string{ours, base, theirs} {
+		if err := ioutil.WriteFile(files[i], []byte(content), 0644); nil != err {
+			return "", 0, err
+		}
+	}
+
+	cmd := exec.Command("git", "merge-file", "-p", "-L", "saved", "-L", "base", "-L", "yours",
+		files[0], files[1], files[2])
+	out, err := cmd.Output()
+	if nil == err {
+		return string(out), 0, nil
+	}
+
+	// the exit code is the number of conflicts if positive
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && 0 < exitErr.ExitCode() && 128 > exitErr.ExitCode() {
+		return string(out), exitErr.ExitCode(), nil
+	}
+
+	return "", 0, err
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestMergeText(t *testing.T) {
+	if _, err := exec.LookPath("git"); nil != err {
+		t.Skip("git not found")
+	}
+
+	base := "a\nb\nc\nd\n"
+
+	merged, conflicts, err := mergeText(base, "a\nB\nc\nd\n", "a\nb\nc\nD\n")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 0 != conflicts || "a\nB\nc\nD\n" != merged {
+		t.Errorf("expected a clean merge, actual [%q] with %d conflicts", merged, conflicts)
+	}
+
+	merged, conflicts, err = mergeText(base, "a\nX\nc\nd\n", "a\nY\nc\nd\n")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != conflicts || !strings.Contains(merged, "<<<<<<< saved") || !strings.Contains(merged, ">>>>>>> yours") {
+		t.Errorf("expected 1 conflict, actual [%q] with %d conflicts", merged, conflicts)
+	}
+}
+
+func TestMergeSave(t *testing.T) {
+	sid, path := "test-merge-save", "/tmp/merge-save.go"
+	defer clearSaveBases(sid)
+
+	if code, conflicts := mergeSave(sid, path, "current", "code"); "code" != code || 0 != conflicts {
+		t.Errorf("expected the code saved as is without a base, actual [%s]", code)
+	}
+
+	setSaveBase(sid, path, "current")
+	if code, _ := mergeSave(sid, path, "current", "code"); "code" != code {
+		t.Errorf("expected the code saved as is if the file is unchanged, actual [%s]", code)
+	}
+}
+
+func TestPatchSave(t *testing.T) {
+	sid, path := "test-patch", "/test/patch.go"
+	defer clearSaveBases(sid)
+
+	base := "héllo 😀\n"
+	checksum := float64(textChecksum(utf16.Encode([]rune(base))))
+	if 450192558 != checksum { // computed by the client
+		t.Errorf("unexpected checksum %v", checksum)
+	}
+
+	patch := []interface{}{float64(6), "world ", float64(3)} // insert after "héllo "
+	if _, ok := patchSave(sid, path, patch, checksum, 1<<20); ok {
+		t.Error("expected out of sync without a base")
+	}
+
+	setSaveBase(sid, path, base)
+	patched, ok := patchSave(sid, path, patch, checksum, 1<<20)
+	if !ok || "héllo world 😀\n" != patched {
+		t.Errorf("unexpected patched [%q], %v", patched, ok)
+	}
+
+	if _, ok := patchSave(sid, path, patch, checksum+1, 1<<20); ok {
+		t.Error("expected out of sync with a mismatched checksum")
+	}
+	if _, ok := patchSave(sid, path, []interface{}{float64(2), "x"}, checksum, 1<<20); ok {
+		t.Error("expected out of sync with a mismatched length")
+	}
+
+	// lengths overflowing to the length of the base if not bounded
+	setSaveBase(sid, path, "abc")
+	huge := float64(1 << 62)
+	oversized := []interface{}{huge, huge, huge, -huge, float64(3)}
+	if _, ok := patchSave(sid, path, oversized, float64(textChecksum(utf16.Encode([]rune("abc")))), 1<<20); ok {
+		t.Error("expected an oversized patch refused")
+	}
+}
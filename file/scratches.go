@@ -0,0 +1,218 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// Scratches are throwaway notes and snippets of a user, stored in {Wide.Data}/scratches/{userId} (see
+// conf.User.ScratchesPath) instead of any project so they never show in project directories or git status.
+//
+// The scratches are a section of the file tree with path type 4 (see GetPath), paths of the nodes are relative to the
+// scratches and prefixed with scratchesRoot, such as "/scratches/notes.md". They are opened, saved, renamed and
+// removed with the handlers of files as other nodes of the file tree.
+
+// scratchesRoot is the path of the root node of the scratches in the file tree.
+const scratchesRoot = "/scratches"
+
+// maxScratches is the maximum number of scratches named after the same extension by NewScratchHandler.
+const maxScratches = 1000
+
+// scratchExtRegexp matches extensions of scratches, such as "go" or "md".
+var scratchExtRegexp = regexp.MustCompile(`^[a-zA-Z0-9]{1,16}$`)
+
+// NewScratchHandler handles request of creating a scratch.
+//
+// Arguments: "sid", optional "ext" (extension of the scratch, defaults to "txt") and "content". The scratch is named
+// "scratch.{ext}", "scratch_2.{ext}" and so on, responds the node of it in the file tree.
+func NewScratchHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	dir := scratchesPath(uid)
+	if "" == dir {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	ext, _ := args["ext"].(string)
+	if "" == ext {
+		ext = "txt"
+	}
+	if !scratchExtRegexp.MatchString(ext) {
+		result.Code = -1
+
+		return
+	}
+	content, _ := args["content"].(string)
+	sid, _ := args["sid"].(string)
+
+	if msg := quotaExceeded(uid, int64(len(content))); "" != msg {
+		result.Code = -1
+		result.Msg = msg
+
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	path := ""
+	for i := 1; i <= maxScratches; i++ {
+		name := "scratch." + ext
+		if 1 < i {
+			name = fmt.Sprintf("scratch_%d.%s", i, ext)
+		}
+
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if nil != err {
+			logger.Error(err)
+			result.Code = -1
+
+			return
+		}
+
+		_, err = f.WriteString(content)
+		f.Close()
+		if nil != err {
+			logger.Error(err)
+			result.Code = -1
+
+			return
+		}
+
+		path = filepath.Join(dir, name)
+
+		break
+	}
+	if "" == path {
+		result.Code = -1
+		result.Msg = i18n.Get(conf.GetUser(uid).Locale, "scratches-full", i18n.Params{"ext": ext}).(string)
+
+		return
+	}
+
+	addUsage(uid, int64(len(content)))
+	logger.Debugf("Created a scratch [%s] by user [%s]", path, uid)
+	fileChanged(sid, &event.FileChange{UserId: uid, Op: "create", Path: filepath.ToSlash(path)})
+
+	name := filepath.Base(path)
+	result.Data = &Node{
+		Id:        scratchesRoot + "/" + name,
+		Name:      name,
+		Path:      scratchesRoot + "/" + name,
+		IconSkin:  getIconSkin(filepath.Ext(name)),
+		Type:      "f",
+		Creatable: true,
+		Removable: true,
+		Pathtype:  4,
+		Children:  []*Node{}}
+}
+
+// scratchesPath returns the path of the scratches of the user specified by the given user id, returns "" if the user
+// can't have scratches.
+func scratchesPath(uid string) string {
+	user := conf.GetUser(uid)
+	// user [playground] is a reserved mock user
+	if nil == user || "playground" == uid {
+		return ""
+	}
+
+	return user.ScratchesPath()
+}
+
+// scratchPath returns the path of the specified node path of the scratches (prefixed with scratchesRoot) in the
+// specified directory of the scratches, returns "" if the directory is "" or the node path is not of the scratches.
+func scratchPath(dir, nodePath string) string {
+	nodePath = filepath.ToSlash(nodePath)
+	if "" == dir || (scratchesRoot != nodePath && !strings.HasPrefix(nodePath, scratchesRoot+"/")) {
+		return ""
+	}
+
+	rel := filepath.Clean(filepath.FromSlash("/" + nodePath[len(scratchesRoot):]))
+
+	return filepath.ToSlash(filepath.Join(dir, rel))
+}
+
+// scratchesNode returns the root node of the scratches of the user specified by the given user id in the file tree,
+// the directories needed to restore the specified session content are loaded. The directory of the scratches is created
+// if not exists, returns nil if the user can't have scratches.
+func scratchesNode(uid string, content *conf.LatestSessionContent) *Node {
+	dir := scratchesPath(uid)
+	if "" == dir {
+		return nil
+	}
+
+	ret := &Node{
+		Id:        scratchesRoot,
+		Name:      i18n.Get(conf.GetUser(uid).Locale, "scratches").(string),
+		Path:      scratchesRoot,
+		IconSkin:  "ico-ztree-dir-workspace ",
+		Type:      "d",
+		Creatable: true,
+		Removable: false,
+		IsParent:  true,
+		Pathtype:  4,
+		Children:  []*Node{}}
+
+	if err := os.MkdirAll(dir, 0755); nil != err {
+		logger.Error(err)
+
+		return nil
+	}
+
+	walk(dir, dir, ret, true, true, false, 4, conf.Wide().TreeDepth(), expandedPaths(content, scratchesRoot), 0,
+		conf.Wide().TreeChildrenLimit())
+	prefixNodePaths(ret, scratchesRoot)
+
+	return ret
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScratchPath(t *testing.T) {
+	dir := filepath.FromSlash("/data/scratches/1")
+	cases := map[string]string{
+		"/scratches":                "/data/scratches/1",
+		"/scratches/":               "/data/scratches/1",
+		"/scratches/notes.md":       "/data/scratches/1/notes.md",
+		"/scratches/a/../b.go":      "/data/scratches/1/b.go",
+		"/scratches/../../2/x.go":   "/data/scratches/1/2/x.go",
+		"/scratchesx/notes.md":      "",
+		"/workspace/src/notes.md":   "",
+		"scratches/notes.md":        "",
+		"/scratches/../../../etc/x": "/data/scratches/1/etc/x",
+	}
+	for nodePath, expected := range cases {
+		if got := scratchPath(dir, nodePath); expected != got {
+			t.Errorf("expected [%s] of [%s], got [%s]", expected, nodePath, got)
+		}
+	}
+
+	if "" != scratchPath("", "/scratches/notes.md") {
+		t.Error("users without scratches should get no path")
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// searchWorkers is the number of goroutines searching directories concurrently.
+var searchWorkers = runtime.NumCPU()
+
+const (
+	// searchCursorTTL is the duration a continuation token of a search is valid for.
+	searchCursorTTL = 10 * time.Minute
+
+	// searchMaxLineSize is the max size of a line to search, the rest of a file is skipped once a longer line (such as
+	// a minified script) is read.
+	searchMaxLineSize = 1 << 20
+)
+
+// searchMatcher matches lines of files with the query of a search, compiled once per search.
+type searchMatcher struct {
+	text          string         // text to find, lower-cased if not case-sensitive, used if re is nil
+	caseSensitive bool           // whether the text is matched case-sensitively
+	re            *regexp.Regexp // compiled pattern of regular expression or whole word searches
+	trigrams      []uint32       // trigrams every matched line contains, files are filtered with text indexes by them
+}
+
+// newSearchMatcher creates a matcher of the specified text, which is a regular expression (Go RE2 syntax) if the
+// specified regex is true. Only whole words (delimited by ASCII word boundaries, see regexp/syntax) are matched if the
+// specified wholeWord is true.
+//
+// Returns an error if the regular expression is invalid.
+func newSearchMatcher(text string, regex, caseSensitive, wholeWord bool) (*searchMatcher, error) {
+	if !regex && !wholeWord {
+		if !caseSensitive {
+			text = strings.ToLower(text)
+		}
+
+		return &searchMatcher{text: text, caseSensitive: caseSensitive, trigrams: queryTrigrams([]string{text})}, nil
+	}
+
+	pattern := text
+	if !regex {
+		pattern = regexp.QuoteMeta(text)
+	}
+	if wholeWord {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if nil != err {
+		return nil, err
+	}
+
+	ret := &searchMatcher{text: text, caseSensitive: caseSensitive, re: re}
+	if parsed, err := syntax.Parse(pattern, syntax.Perl); nil == err {
+		ret.trigrams = queryTrigrams(requiredLiterals(parsed))
+	}
+
+	return ret, nil
+}
+
+// index returns the byte offset of the first match in the specified line, -1 if not matched.
+func (m *searchMatcher) index(line string) int {
+	if nil != m.re {
+		if loc := m.re.FindStringIndex(line); nil != loc {
+			return loc[0]
+		}
+
+		return -1
+	}
+
+	if m.caseSensitive {
+		return strings.Index(line, m.text)
+	}
+
+	return strings.Index(strings.ToLower(line), m.text)
+}
+
+// searchCursor represents the state of a search continued with a continuation token.
+type searchCursor struct {
+	uid       string         // id of the user searching
+	extension string         // suffix of the files to search
+	matcher   *searchMatcher // matcher of the query
+	maxSize   int64          // files larger than it are skipped, 0 for no limit
+	index     *textIndex     // text index filtering the files to search, nil for searching all files
+	dirs      []string       // directories not scanned yet, in order
+	snippets  []*Snippet     // found snippets not returned yet
+	expires   time.Time      // expiration time
+}
+
+var (
+	// searches to be continued, <token, cursor>
+	searchCursors = map[string]*searchCursor{}
+
+	// guards searchCursors
+	searchCursorsMutex sync.Mutex
+)
+
+// searchPage returns the next page of at most the specified limit of snippets of the search of the specified cursor,
+// scanning stops once the page is full. Returns the continuation token of the next page, "" if there are no more
+// snippets.
+func searchPage(cursor *searchCursor, limit int) ([]*Snippet, string) {
+	if len(cursor.snippets) < limit {
+		s := newSearcher(cursor.extension, cursor.matcher, cursor.dirs, limit-len(cursor.snippets))
+		s.maxSize = cursor.maxSize
+		s.index = cursor.index
+		s.run()
+
+		cursor.snippets = append(cursor.snippets, s.snippets...)
+		cursor.dirs = s.dirs[s.emitted:]
+	}
+
+	ret := cursor.snippets
+	if len(ret) > limit {
+		ret = ret[:limit]
+	}
+	cursor.snippets = cursor.snippets[len(ret):]
+
+	if 0 == len(cursor.snippets) && 0 == len(cursor.dirs) {
+		return ret, ""
+	}
+
+	token := gulu.Rand.String(16)
+	cursor.expires = time.Now().Add(searchCursorTTL)
+
+	searchCursorsMutex.Lock()
+	defer searchCursorsMutex.Unlock()
+
+	for t, c := range searchCursors {
+		if c.expires.Before(time.Now()) {
+			delete(searchCursors, t)
+		}
+	}
+	searchCursors[token] = cursor
+
+	return ret, token
+}
+
+// takeSearchCursor removes the cursor of the specified continuation token of the specified user and returns it,
+// returns nil if not found or expired.
+func takeSearchCursor(uid, token string) *searchCursor {
+	searchCursorsMutex.Lock()
+	defer searchCursorsMutex.Unlock()
+
+	ret := searchCursors[token]
+	if nil == ret || ret.uid != uid {
+		return nil
+	}
+
+	delete(searchCursors, token)
+	if ret.expires.Before(time.Now()) {
+		return nil
+	}
+
+	return ret
+}
+
+// search finds file under the specified dir and its sub-directories with the specified matcher, likes the command
+// 'grep' or 'findstr'.
+func search(dir, extension string, matcher *searchMatcher) []*Snippet {
+	s := newSearcher(extension, matcher, []string{dir}, 0)
+	s.run()
+
+	return s.snippets
+}
+
+// searcher searches files of directory trees with a bounded pool of workers over a queue of directories.
+//
+// Directories are scanned concurrently but their snippets are collected in the order of the queue, sub-directories of
+// a directory are queued (by name) once it's collected, so the result is stable: breadth first, files of a directory
+// by name and then lines.
+type searcher struct {
+	extension string         // suffix of the files to search
+	matcher   *searchMatcher // matcher of the query
+	limit     int            // stops collecting once the number of snippets reaches it, 0 for no limit
+	maxSize   int64          // files larger than it are skipped, 0 for no limit
+	index     *textIndex     // text index filtering the files to search, nil for searching all files
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	dirs     []string               // queued directories, dirs[emitted:] are not collected yet
+	next     int                    // index of the next directory to scan
+	emitted  int                    // index of the next directory to collect
+	scanned  map[int]*searchScanned // scanned directories waiting for the ones before them to be collected
+	snippets []*Snippet             // collected snippets
+	stopped  bool                   // whether the limit is reached
+}
+
+// searchScanned represents the result of scanning a directory.
+type searchScanned struct {
+	subdirs  []string
+	snippets []*Snippet
+}
+
+// newSearcher creates a searcher of the specified directories.
+func newSearcher(extension string, matcher *searchMatcher, dirs []string, limit int) *searcher {
+	ret := &searcher{extension: extension, matcher: matcher, limit: limit, dirs: append([]string{}, dirs...),
+		scanned: map[int]*searchScanned{}, snippets: []*Snippet{}}
+	ret.cond = sync.NewCond(&ret.mutex)
+
+	return ret
+}
+
+// run scans the directories with searchWorkers workers until all directories have been scanned or the limit is
+// reached.
+func (s *searcher) run() {
+	workers := searchWorkers
+	if 1 > workers {
+		workers = 1
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			s.work()
+		}()
+	}
+	wg.Wait()
+}
+
+// work scans directories taken from the queue until there are no more to scan.
+func (s *searcher) work() {
+	for {
+		s.mutex.Lock()
+		// directories may be queued until all are collected
+		for !s.stopped && s.next == len(s.dirs) && s.emitted < len(s.dirs) {
+			s.cond.Wait()
+		}
+		if s.stopped || s.next == len(s.dirs) {
+			s.mutex.Unlock()
+
+			return
+		}
+		index := s.next
+		dir := s.dirs[index]
+		s.next++
+		s.mutex.Unlock()
+
+		scanned := s.scan(dir)
+
+		s.mutex.Lock()
+		s.scanned[index] = scanned
+		s.collect()
+		s.cond.Broadcast()
+		s.mutex.Unlock()
+	}
+}
+
+// collect collects the scanned directories in order, must be called with the mutex locked.
+func (s *searcher) collect() {
+	for !s.stopped {
+		scanned, ok := s.scanned[s.emitted]
+		if !ok {
+			return
+		}
+
+		delete(s.scanned, s.emitted)
+		s.emitted++
+		s.dirs = append(s.dirs, scanned.subdirs...)
+		s.snippets = append(s.snippets, scanned.snippets...)
+
+		if 0 < s.limit && len(s.snippets) >= s.limit {
+			s.stopped = true
+		}
+	}
+}
+
+// scan searches the files directly under the specified directory.
+func (s *searcher) scan(dir string) *searchScanned {
+	ret := &searchScanned{subdirs: []string{}, snippets: []*Snippet{}}
+
+	f, err := os.Open(dir)
+	if nil != err {
+		logger.Errorf("Read dir [%s] failed: [%s]", dir, err.Error())
+
+		return ret
+	}
+	fileInfos, err := f.Readdir(-1)
+	f.Close()
+
+	if nil != err {
+		logger.Errorf("Read dir [%s] failed: [%s]", dir, err.Error())
+
+		return ret
+	}
+
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].Name() < fileInfos[j].Name() })
+
+	for _, fileInfo := range fileInfos {
+		path := filepath.Join(dir, fileInfo.Name())
+
+		if fileInfo.IsDir() {
+			ret.subdirs = append(ret.subdirs, path)
+		} else if strings.HasSuffix(path, s.extension) && (0 >= s.maxSize || fileInfo.Size() <= s.maxSize) &&
+			(nil == s.index || s.index.mayContain(path, fileInfo, s.matcher.trigrams)) {
+			ret.snippets = append(ret.snippets, searchInFile(path, s.matcher, s.maxSize)...)
+		}
+	}
+
+	return ret
+}
+
+// searchInFile finds lines matched by the specified matcher in the file with the specified path.
+//
+// The file is read line by line (from fileContents if cached), it's skipped if it's larger than the specified max size
+// (0 for no limit) or it's a binary (see isBinaryFile).
+func searchInFile(path string, matcher *searchMatcher, maxSize int64) []*Snippet {
+	ret := []*Snippet{}
+
+	if binaryExtensions[strings.ToLower(filepath.Ext(path))] {
+		return ret
+	}
+
+	info, err := os.Stat(path)
+	if nil != err {
+		logger.Errorf("Read file [%s] failed: [%s]", path, err.Error())
+
+		return ret
+	}
+	if 0 < maxSize && info.Size() > maxSize {
+		return ret
+	}
+
+	f, err := fileContents.open(path, info)
+	if nil != err {
+		logger.Errorf("Read file [%s] failed: [%s]", path, err.Error())
+
+		return ret
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, binarySniffSize)
+	head, err := reader.Peek(binarySniffSize)
+	if nil != err && io.EOF != err {
+		logger.Errorf("Read file [%s] failed: [%s]", path, err.Error())
+
+		return ret
+	}
+	if isBinaryHead(head) {
+		return ret
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), searchMaxLineSize)
+
+	for idx := 1; scanner.Scan(); idx++ {
+		line := scanner.Text()
+		ch := matcher.index(line)
+
+		if -1 != ch {
+			snippet := &Snippet{Path: filepath.ToSlash(path),
+				Line: idx, Ch: ch + 1, Contents: []string{line}}
+
+			ret = append(ret, snippet)
+		}
+	}
+
+	if err := scanner.Err(); nil != err {
+		logger.Warnf("Search file [%s] stopped: [%s]", path, err.Error())
+	}
+
+	return ret
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":         "package main\n// TODO: a\n",
+		"a/a.go":          "// todo: b\npackage a\n// TODO: c\n",
+		"a/b/b.go":        "package b\n",
+		"a/b/c/c.go":      "package c // TODO: d\n",
+		"d/README.md":     "TODO: not a go file\n",
+		"d/e/f/g/h/h.go":  "// TODO: e\n",
+		"d/e/f/g/h/i.go":  "// TODO: f\n",
+		"d/e/f/g/h/j.txt": "TODO: g\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"main.go:2", "a/a.go:1", "a/a.go:3", "a/b/c/c.go:1", "d/e/f/g/h/h.go:1", "d/e/f/g/h/i.go:1"}
+	locations := func(snippets []*Snippet) []string {
+		ret := []string{}
+		for _, snippet := range snippets {
+			rel, _ := filepath.Rel(root, filepath.FromSlash(snippet.Path))
+			ret = append(ret, fmt.Sprintf("%s:%d", filepath.ToSlash(rel), snippet.Line))
+		}
+
+		return ret
+	}
+
+	todo, _ := newSearchMatcher("todo", false, false, false)
+	defer func(workers int) { searchWorkers = workers }(searchWorkers)
+	for _, workers := range []int{1, 4} {
+		searchWorkers = workers
+
+		if actual := locations(search(root, ".go", todo)); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("search with %d workers: expected %v, actual %v", workers, expected, actual)
+		}
+
+		// pages of 4 snippets
+		cursor := &searchCursor{uid: "test", extension: ".go", matcher: todo, dirs: []string{root}}
+		actual, token := searchPage(cursor, 4)
+		if "" == token || !reflect.DeepEqual(expected[:4], locations(actual)) {
+			t.Errorf("search page 1 with %d workers: expected %v, actual %v", workers, expected[:4],
+				locations(actual))
+		}
+		if nil != takeSearchCursor("another", token) {
+			t.Error("expected the search can't be continued by another user")
+		}
+
+		cursor = takeSearchCursor("test", token)
+		if nil == cursor {
+			t.Fatal("expected the search continued")
+		}
+		actual, token = searchPage(cursor, 4)
+		if "" != token || !reflect.DeepEqual(expected[4:], locations(actual)) {
+			t.Errorf("search page 2 with %d workers: expected %v, actual %v", workers, expected[4:],
+				locations(actual))
+		}
+	}
+}
+
+func TestSearchInFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	todo, _ := newSearchMatcher("todo", false, false, false)
+	text := write("text.go", "package a\r\n// TODO: a\n")
+	snippets := searchInFile(text, todo, 0)
+	if 1 != len(snippets) || 2 != snippets[0].Line || 4 != snippets[0].Ch || "// TODO: a" != snippets[0].Contents[0] {
+		t.Errorf("unexpected snippets %v", snippets)
+	}
+	if 0 != len(searchInFile(text, todo, 8)) {
+		t.Error("expected a file larger than the max size skipped")
+	}
+
+	binary := write("binary.go", "TODO\x00\n")
+	if 0 != len(searchInFile(binary, todo, 0)) {
+		t.Error("expected a binary file skipped")
+	}
+
+	long := write("long.go", "// TODO: a\n"+strings.Repeat("x", searchMaxLineSize+1)+"\n// TODO: b\n")
+	if snippets := searchInFile(long, todo, 0); 1 != len(snippets) {
+		t.Errorf("expected the lines after a too long line skipped, got %d snippets", len(snippets))
+	}
+}
+
+func TestSearchMatcher(t *testing.T) {
+	cases := []struct {
+		text                            string
+		regex, caseSensitive, wholeWord bool
+		line                            string
+		expected                        int
+	}{
+		{"todo", false, false, false, "// TODO: a", 3},
+		{"todo", false, true, false, "// TODO: a", -1},
+		{"TODO", false, true, false, "// TODO: a", 3},
+		{"a.b", false, false, false, "axb a.b", 4},
+		{"do", false, false, true, "todo do", 5},
+		{"do", false, false, true, "todo", -1},
+		{"a.b", false, false, true, "xa.b a.b", 5},
+		{`to+do\b`, true, false, false, "TOOODO:", 0},
+		{`to+do`, true, true, false, "TOOODO:", -1},
+		{`t.d`, true, false, true, "todos tod", 6},
+		{"(", false, false, true, "f(x)", 1},
+	}
+
+	for _, c := range cases {
+		matcher, err := newSearchMatcher(c.text, c.regex, c.caseSensitive, c.wholeWord)
+		if nil != err {
+			t.Errorf("compiles [%s] failed: %s", c.text, err)
+
+			continue
+		}
+		if actual := matcher.index(c.line); c.expected != actual {
+			t.Errorf("expected [%d] of [%s] (regex %v, case-sensitive %v, whole word %v) in [%s], actual [%d]",
+				c.expected, c.text, c.regex, c.caseSensitive, c.wholeWord, c.line, actual)
+		}
+	}
+
+	if _, err := newSearchMatcher("a(", true, false, false); nil == err {
+		t.Error("expected invalid regular expression refused")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+var gitStashRefPattern = regexp.MustCompile(`^stash@\{\d+\}$`)
+
+// gitStash represents a stash entry.
+type gitStash struct {
+	Ref     string `json:"ref"` // for example "stash@{0}"
+	Hash    string `json:"hash"`
+	Time    int64  `json:"time"` // unix milliseconds
+	Date    string `json:"date"` // time formatted in the user's time zone
+	Message string `json:"message"`
+}
+
+// GitStashSaveHandler handles request of stashing the uncommitted changes of a repository.
+//
+// Optional arguments: "message" and "untracked" (stashes untracked files as well).
+func GitStashSaveHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	// stash entries are commits, an author is required
+	name, email := req.user.GitAuthor()
+	gitArgs := []string{"-c", "user.name=" + name, "-c", "user.email=" + email, "stash", "push"}
+	if req.flag("untracked") {
+		gitArgs = append(gitArgs, "--include-untracked")
+	}
+	if message := req.arg("message"); "" != message {
+		gitArgs = append(gitArgs, "-m", message)
+	}
+
+	if runGit(req, result, gitArgs...) {
+		refreshRepo(req)
+	}
+}
+
+// GitStashListHandler handles request of listing stash entries of a repository.
+func GitStashListHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	stashes := []*gitStash{}
+	result.Data = &stashes
+
+	out, err := gitCommand(req.repo, "stash", "list", "--format=%gd%x00%H%x00%at%x00%gs").Output()
+	if nil != err {
+		logger.Warnf("Lists stash of [%s] failed [%s]", req.repo, err)
+
+		return
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if 4 != len(fields) {
+			continue
+		}
+
+		seconds, _ := strconv.ParseInt(fields[2], 10, 64)
+		stashes = append(stashes, &gitStash{Ref: fields[0], Hash: fields[1], Time: seconds * 1000,
+			Date: req.user.FormatUnixMilli(seconds * 1000), Message: fields[3]})
+	}
+}
+
+// GitStashApplyHandler handles request of applying a stash entry.
+func GitStashApplyHandler(w http.ResponseWriter, r *http.Request) {
+	gitStashHandler(w, r, "apply")
+}
+
+// GitStashPopHandler handles request of applying a stash entry and dropping it.
+func GitStashPopHandler(w http.ResponseWriter, r *http.Request) {
+	gitStashHandler(w, r, "pop")
+}
+
+// GitStashDropHandler handles request of dropping a stash entry.
+func GitStashDropHandler(w http.ResponseWriter, r *http.Request) {
+	gitStashHandler(w, r, "drop")
+}
+
+// GitStashShowHandler handles request of getting the changes of a stash entry as a diff, in the same structure as
+// GitDiffHandler.
+func GitStashShowHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	ref := gitStashRef(req)
+	if "" == ref {
+		result.Code = -1
+
+		return
+	}
+
+	gitArgs := []string{"-c", "core.quotePath=false", "stash", "show", "-p", "--no-color", "--no-ext-diff", "-M",
+		"--src-prefix=a/", "--dst-prefix=b/", ref}
+	out, err := gitCommand(req.repo, gitArgs...).CombinedOutput()
+	if nil != err {
+		logger.Warnf("Runs [git %s] in [%s] failed [%s]: %s", strings.Join(gitArgs, " "), req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	result.Data = map[string]interface{}{"repo": filepath.ToSlash(req.repo), "files": parseGitDiff(req.repo, out)}
+}
+
+// gitStashHandler runs the specified stash operation ("apply", "pop" or "drop") on the stash entry specified by
+// argument "ref" (defaults to the latest entry).
+func gitStashHandler(w http.ResponseWriter, r *http.Request, op string) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	ref := gitStashRef(req)
+	if "" == ref {
+		result.Code = -1
+
+		return
+	}
+
+	succ := runGit(req, result, "stash", op, ref)
+	if "drop" == op {
+		return
+	}
+
+	refreshRepo(req)
+	if !succ {
+		if conflicts := gitConflicts(req.repo); 0 < len(conflicts) {
+			result.Msg = req.msg("git-merge-conflict")
+			result.Data = map[string]interface{}{"conflicts": conflicts}
+		}
+	}
+}
+
+// gitStashRef gets the stash entry reference argument of the specified request, returns "" if it's invalid.
+func gitStashRef(req *gitRequest) string {
+	ref := req.arg("ref")
+	if "" == ref {
+		return "stash@{0}"
+	}
+
+	if !gitStashRefPattern.MatchString(ref) {
+		return ""
+	}
+
+	return ref
+}
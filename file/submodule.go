@@ -0,0 +1,141 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitSubmodule represents a submodule.
+type gitSubmodule struct {
+	Path     string `json:"path"`     // absolute path of the submodule
+	Commit   string `json:"commit"`   // commit id checked out (or recorded in the superproject if not initialized)
+	Status   string `json:"status"`   // "uninitialized", "modified" (checked out commit differs), "conflicted" or ""
+	Describe string `json:"describe"` // "git describe" of the commit, "" if not initialized
+}
+
+// GitSubmodulesHandler handles request of listing submodules (recursively) of a repository.
+func GitSubmodulesHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	out, err := gitCommand(req.repo, "submodule", "status", "--recursive").CombinedOutput()
+	if nil != err {
+		logger.Warnf("Gets submodules of [%s] failed [%s]: %s", req.repo, err, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	submodules := []*gitSubmodule{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if 2 > len(line) {
+			continue
+		}
+
+		// <status flag><commit> <path>[ (<describe>)]
+		fields := strings.SplitN(line[1:], " ", 3)
+		if 2 > len(fields) {
+			continue
+		}
+
+		submodule := &gitSubmodule{Path: filepath.ToSlash(filepath.Join(req.repo, fields[1])), Commit: fields[0]}
+		if 3 == len(fields) {
+			submodule.Describe = strings.TrimSuffix(strings.TrimPrefix(fields[2], "("), ")")
+		}
+		switch line[0] {
+		case '-':
+			submodule.Status = "uninitialized"
+		case '+':
+			submodule.Status = "modified"
+		case 'U':
+			submodule.Status = "conflicted"
+		}
+
+		submodules = append(submodules, submodule)
+	}
+
+	result.Data = submodules
+}
+
+// GitSubmoduleUpdateHandler handles request of initializing and updating submodules (recursively) of a repository.
+//
+// The output is pushed to the output channel, the user's credential is used to fetch submodules.
+func GitSubmoduleUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	cmd := gitCommand(req.repo, "submodule", "update", "--init", "--recursive", "--progress")
+	keyFile := setGitCredentialEnv(cmd, req.uid, req.user.GetGitCredential())
+	defer removeKeyFile(keyFile)
+
+	output, succ := runGitWithOutput(cmd, req.sid, req.locale, "git-submodule")
+	refreshRepo(req)
+	if succ {
+		return
+	}
+
+	result.Code = -1
+	if key := gitErrorKey(output); "" != key {
+		result.Msg = req.msg(key)
+	}
+}
+
+// isGitSubmodule determines whether the specified directory is the root of a checked out submodule.
+//
+// The .git of a submodule is a file pointing to a directory under the superproject's ".git/modules/".
+func isGitSubmodule(dir string) bool {
+	gitPath := filepath.Join(dir, ".git")
+	if !gulu.File.IsExist(gitPath) || gulu.File.IsDir(gitPath) {
+		return false
+	}
+
+	return strings.Contains(filepath.ToSlash(gitDir(dir)), "/modules/")
+}
+
+// detachedSubmodule returns the root directory of the submodule containing the specified path if the submodule's HEAD
+// is detached, returns "" otherwise.
+//
+// Submodules are checked out at a detached HEAD by "git submodule update", commits made there are easy to lose.
+func detachedSubmodule(path string) string {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if gulu.File.IsExist(filepath.Join(dir, ".git")) {
+			if isGitSubmodule(dir) && !strings.HasPrefix(gitReadHead(dir), "ref:") {
+				return dir
+			}
+
+			return ""
+		}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+const (
+	// maxSymbolFileSize is the max size of Go files parsed into symbol indexes, larger ones (such as generated code) are
+	// skipped.
+	maxSymbolFileSize = 1 << 20
+
+	// maxFoundSymbols is the max symbols returned per "open symbol" request.
+	maxFoundSymbols = 100
+
+	// maxSymbolUsages is the max usages returned by SymbolUsages.
+	maxSymbolUsages = 500
+)
+
+// Symbol represents a top-level declaration (function, method or type) of a Go file in a symbol index.
+type Symbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`           // "func", "method" or "type"
+	Recv string `json:"recv,omitempty"` // receiver type of a method
+	Path string `json:"path"`
+	Line int    `json:"line"` // line number, starts with 0
+	Ch   int    `json:"ch"`   // column number (in characters), starts with 0
+}
+
+// fileSymbols represents the symbols of a Go file.
+type fileSymbols struct {
+	decls  []*Symbol
+	idents map[string]bool // names of the identifiers in the file, files without a name are skipped finding its usages
+}
+
+// FindSymbolHandler handles request of finding symbols (functions, methods and types) by name in the workspaces of the
+// user for "open symbol".
+//
+// Symbols are found in the symbol indexes of the workspaces (see pathIndex.loadSymbols), the ones whose names equal to
+// the specified name are ranked first, followed by the ones starting with and containing it. Name "Recv.Name" matches
+// methods with their receivers.
+func FindSymbolHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetGzResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	name, _ := args["name"].(string)
+	name = strings.ToLower(strings.TrimSpace(name))
+	if "" == name {
+		result.Data = []*Symbol{}
+
+		return
+	}
+
+	type rankedSymbol struct {
+		*Symbol
+		rank int
+	}
+
+	founds := []*rankedSymbol{}
+	for _, root := range symbolRoots(uid) {
+		index := symbolIndex(root)
+		if nil == index {
+			continue
+		}
+
+		index.mutex.RLock()
+		for _, symbols := range index.symbols {
+			for _, symbol := range symbols.decls {
+				if rank := symbolRank(symbol, name); 0 <= rank {
+					founds = append(founds, &rankedSymbol{Symbol: symbol, rank: rank})
+				}
+			}
+		}
+		index.mutex.RUnlock()
+	}
+
+	sort.Slice(founds, func(i, j int) bool {
+		if founds[i].rank != founds[j].rank {
+			return founds[i].rank < founds[j].rank
+		}
+		if len(founds[i].Name) != len(founds[j].Name) {
+			return len(founds[i].Name) < len(founds[j].Name)
+		}
+		if founds[i].Name != founds[j].Name {
+			return founds[i].Name < founds[j].Name
+		}
+		if founds[i].Path != founds[j].Path {
+			return founds[i].Path < founds[j].Path
+		}
+
+		return founds[i].Line < founds[j].Line
+	})
+
+	ret := []*Symbol{}
+	for i := 0; i < len(founds) && i < maxFoundSymbols; i++ {
+		ret = append(ret, founds[i].Symbol)
+	}
+
+	result.Data = ret
+}
+
+// symbolRank returns the rank of the specified symbol matching the specified name (in lower case): 0 if equal to, 1 if
+// starting with and 2 if containing the name, -1 if not matched.
+func symbolRank(symbol *Symbol, name string) int {
+	symbolName := symbol.Name
+	if strings.Contains(name, ".") {
+		if "" == symbol.Recv {
+			return -1
+		}
+
+		symbolName = symbol.Recv + "." + symbol.Name
+	}
+	symbolName = strings.ToLower(symbolName)
+
+	switch {
+	case symbolName == name:
+		return 0
+	case strings.HasPrefix(symbolName, name):
+		return 1
+	case strings.Contains(symbolName, name):
+		return 2
+	}
+
+	return -1
+}
+
+// SymbolUsages finds usages of the identifiers with the specified name in the workspaces of the specified user, only the
+// files referring to the name in the symbol indexes are scanned. It's the fallback of finding usages without type
+// information, so the identifiers are matched by name only.
+func SymbolUsages(uid, name string) []*Snippet {
+	ret := []*Snippet{}
+	for _, root := range symbolRoots(uid) {
+		index := symbolIndex(root)
+		if nil == index {
+			continue
+		}
+
+		paths := []string{}
+		index.mutex.RLock()
+		for path, symbols := range index.symbols {
+			if symbols.idents[name] {
+				paths = append(paths, path)
+			}
+		}
+		index.mutex.RUnlock()
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			ret = append(ret, identUsages(path, name)...)
+			if maxSymbolUsages <= len(ret) {
+				return ret[:maxSymbolUsages]
+			}
+		}
+	}
+
+	return ret
+}
+
+// UpdateSymbols updates the specified saved file in the symbol indexes containing it immediately, without waiting for
+// the file system watchers.
+func UpdateSymbols(path string) {
+	pathIndexesMutex.Lock()
+	indexes := []*pathIndex{}
+	for root, index := range pathIndexes {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			indexes = append(indexes, index)
+		}
+	}
+	pathIndexesMutex.Unlock()
+
+	for _, index := range indexes {
+		index.updateSymbols(path)
+	}
+}
+
+// symbolRoots returns the roots of the symbol indexes of the specified user, which are the src directories of the
+// workspaces.
+func symbolRoots(uid string) []string {
+	ret := []string{}
+	for _, workspace := range filepath.SplitList(conf.GetUserWorkspace(uid)) {
+		ret = append(ret, workspace+conf.PathSeparator+"src")
+	}
+
+	return ret
+}
+
+// symbolIndex returns the path index of the specified root with symbols loaded, returns nil if the root can't be
+// indexed.
+func symbolIndex(root string) *pathIndex {
+	index, err := getPathIndex(root)
+	if nil != err {
+		logger.Warnf("Indexes symbols of [%s] failed [%s]", root, err)
+
+		return nil
+	}
+
+	index.loadSymbols()
+
+	return index
+}
+
+// loadSymbols parses symbols of the indexed Go files if not yet, they are kept fresh by the watcher and UpdateSymbols
+// since then.
+func (index *pathIndex) loadSymbols() {
+	index.mutex.RLock()
+	loaded := nil != index.symbols
+	paths := []string{}
+	if !loaded {
+		for path := range index.files {
+			if ".go" == filepath.Ext(path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	index.mutex.RUnlock()
+
+	if loaded {
+		return
+	}
+
+	symbols := map[string]*fileSymbols{}
+	for _, path := range paths {
+		if s := parseSymbols(path); nil != s {
+			symbols[path] = s
+		}
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if nil == index.symbols {
+		index.symbols = symbols
+	}
+}
+
+// updateSymbols parses symbols of the specified file again if it's an indexed Go file and the symbols are loaded.
+func (index *pathIndex) updateSymbols(path string) {
+	if ".go" != filepath.Ext(path) {
+		return
+	}
+
+	index.mutex.RLock()
+	loaded := nil != index.symbols
+	index.mutex.RUnlock()
+	if !loaded {
+		return
+	}
+
+	symbols := parseSymbols(path)
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if nil == index.symbols {
+		return
+	}
+
+	if nil == symbols || !index.files[path] {
+		delete(index.symbols, path)
+
+		return
+	}
+
+	index.symbols[path] = symbols
+}
+
+// parseSymbols parses symbols of the specified Go file, returns nil if the file can't be read or is too large.
+// Declarations before a syntax error are still parsed.
+func parseSymbols(path string) *fileSymbols {
+	if maxSymbolFileSize < gulu.File.GetFileSize(path) {
+		return nil
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if nil == f {
+		return nil
+	}
+
+	ret := &fileSymbols{decls: []*Symbol{}, idents: map[string]bool{}}
+	slashPath := filepath.ToSlash(path)
+	declare := func(name *ast.Ident, kind, recv string) {
+		pos := fset.Position(name.Pos())
+		ret.decls = append(ret.decls, &Symbol{Name: name.Name, Kind: kind, Recv: recv, Path: slashPath,
+			Line: pos.Line - 1, Ch: utf8.RuneCount(src[pos.Offset-pos.Column+1 : pos.Offset])})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if nil == d.Recv || 1 > len(d.Recv.List) {
+				declare(d.Name, "func", "")
+			} else {
+				declare(d.Name, "method", receiverType(d.Recv.List[0].Type))
+			}
+		case *ast.GenDecl:
+			if token.TYPE != d.Tok {
+				continue
+			}
+
+			for _, spec := range d.Specs {
+				declare(spec.(*ast.TypeSpec).Name, "type", "")
+			}
+		}
+	}
+
+	ast.Inspect(f, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			ret.idents[ident.Name] = true
+		}
+
+		return true
+	})
+
+	return ret
+}
+
+// receiverType returns the type name of the specified receiver expression, such as "T" of "*T" and "T[K]".
+func receiverType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(e.X)
+	case *ast.IndexExpr:
+		return receiverType(e.X)
+	case *ast.IndexListExpr:
+		return receiverType(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+
+	return ""
+}
+
+// identUsages returns the positions of the identifiers with the specified name in the specified Go file, line and
+// column numbers of the returned snippets start with 1. The file is read from fileContents if cached.
+func identUsages(path, name string) []*Snippet {
+	src, err := fileContents.read(path)
+	if nil != err {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	f := fset.AddFile(path, -1, len(src))
+	var s scanner.Scanner
+	s.Init(f, src, nil, 0)
+
+	lines := strings.Split(string(src), "\n")
+	ret := []*Snippet{}
+	for {
+		pos, tok, lit := s.Scan()
+		if token.EOF == tok {
+			break
+		}
+		if token.IDENT != tok || name != lit {
+			continue
+		}
+
+		position := fset.Position(pos)
+		line := lines[position.Line-1]
+		ret = append(ret, &Snippet{Path: filepath.ToSlash(path), Line: position.Line,
+			Ch: utf8.RuneCountInString(line[:position.Column-1]) + 1, Contents: []string{line}})
+	}
+
+	return ret
+}
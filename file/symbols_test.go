@@ -0,0 +1,154 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const symbolsSource = `package p
+
+type T[K comparable] struct{}
+
+func (t *T[K]) Get(k K) {}
+
+// New returns a T.
+func Néw() *T[string] { return &T[string]{} }
+
+func broken( {
+`
+
+func TestParseSymbols(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p.go")
+	if err := ioutil.WriteFile(path, []byte(symbolsSource), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	symbols := parseSymbols(path)
+	if nil == symbols {
+		t.Fatal("symbols are not parsed")
+	}
+
+	actual := []Symbol{}
+	for _, decl := range symbols.decls {
+		actual = append(actual, *decl)
+	}
+	slashPath := filepath.ToSlash(path)
+	expected := []Symbol{
+		{Name: "T", Kind: "type", Path: slashPath, Line: 2, Ch: 5},
+		{Name: "Get", Kind: "method", Recv: "T", Path: slashPath, Line: 4, Ch: 15},
+		{Name: "Néw", Kind: "func", Path: slashPath, Line: 7, Ch: 5},
+	}
+	if !reflect.DeepEqual(expected, actual[:len(expected)]) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+
+	for _, name := range []string{"T", "K", "comparable", "string"} {
+		if !symbols.idents[name] {
+			t.Errorf("identifier [%s] is not indexed", name)
+		}
+	}
+}
+
+func TestSymbolRank(t *testing.T) {
+	get := &Symbol{Name: "GetFile", Kind: "method", Recv: "Store"}
+	cases := []struct {
+		name     string
+		expected int
+	}{
+		{"getfile", 0},
+		{"get", 1},
+		{"file", 2},
+		{"put", -1},
+		{"store.get", 1},
+		{"other.get", -1},
+	}
+
+	for _, c := range cases {
+		if actual := symbolRank(get, c.name); c.expected != actual {
+			t.Errorf("rank of [%s]: expected %d, actual %d", c.name, c.expected, actual)
+		}
+	}
+
+	if actual := symbolRank(&Symbol{Name: "Get", Kind: "func"}, "x.get"); -1 != actual {
+		t.Errorf("rank of a function with a receiver: expected -1, actual %d", actual)
+	}
+}
+
+func TestIdentUsages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p.go")
+	if err := ioutil.WriteFile(path, []byte(symbolsSource), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	actual := [][2]int{}
+	for _, usage := range identUsages(path, "T") {
+		actual = append(actual, [2]int{usage.Line, usage.Ch})
+	}
+	expected := [][2]int{{3, 6}, {5, 10}, {8, 13}, {8, 33}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestPathIndexSymbols(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "p.go")
+	if err := ioutil.WriteFile(path, []byte("package p\n\nfunc A() {}\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	index, err := newPathIndex(root)
+	if nil != err {
+		t.Skip("can't watch files: ", err)
+	}
+	defer index.close()
+
+	names := func() []string {
+		index.mutex.RLock()
+		defer index.mutex.RUnlock()
+
+		ret := []string{}
+		for _, symbol := range index.symbols[path].decls {
+			ret = append(ret, symbol.Name)
+		}
+
+		return ret
+	}
+
+	index.loadSymbols()
+	if actual := names(); !reflect.DeepEqual([]string{"A"}, actual) {
+		t.Errorf("expected [A], actual %v", actual)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("package p\n\nfunc B() {}\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	index.updateSymbols(path)
+	if actual := names(); !reflect.DeepEqual([]string{"B"}, actual) {
+		t.Errorf("expected [B], actual %v", actual)
+	}
+
+	index.remove(path)
+	index.mutex.RLock()
+	_, ok := index.symbols[path]
+	index.mutex.RUnlock()
+	if ok {
+		t.Error("symbols of the removed file are kept")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// gitTag represents a tag.
+type gitTag struct {
+	Name      string `json:"name"`
+	Commit    string `json:"commit"`    // short id of the commit tagged
+	Annotated bool   `json:"annotated"` // whether is an annotated tag
+	Time      int64  `json:"time"`      // tagging time of an annotated tag or commit time, in unix milliseconds
+	Date      string `json:"date"`      // the time formatted in the user's time zone
+	Message   string `json:"message"`   // subject of the tag message (or of the commit for a lightweight tag)
+}
+
+// GitTagsHandler handles request of listing tags of a repository, the latest first.
+func GitTagsHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	out, err := gitCommand(req.repo, "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname:short)%00%(objecttype)%00%(objectname:short)%00%(*objectname:short)%00%(creatordate:unix)%00%(contents:subject)",
+		"refs/tags").Output()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	tags := []*gitTag{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if 6 != len(fields) {
+			continue
+		}
+
+		seconds, _ := strconv.ParseInt(fields[4], 10, 64)
+		tag := &gitTag{Name: fields[0], Commit: fields[2], Annotated: "tag" == fields[1], Time: seconds * 1000,
+			Date: req.user.FormatUnixMilli(seconds * 1000), Message: fields[5]}
+		if tag.Annotated {
+			tag.Commit = fields[3]
+		}
+
+		tags = append(tags, tag)
+	}
+
+	result.Data = tags
+}
+
+// GitCreateTagHandler handles request of creating a tag.
+//
+// Creates an annotated tag if "message" is specified, a lightweight tag otherwise. Optional argument "target" is the
+// commit to tag, defaults to HEAD.
+func GitCreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	name := req.arg("name")
+	if "" == name || strings.HasPrefix(name, "-") ||
+		nil != gitCommand(req.repo, "check-ref-format", "refs/tags/"+name).Run() {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-tag")
+
+		return
+	}
+
+	gitArgs := []string{"tag"}
+	if message := req.arg("message"); "" != message {
+		author, email := req.user.GitAuthor()
+		gitArgs = []string{"-c", "user.name=" + author, "-c", "user.email=" + email, "tag", "-a", "-m", message}
+	}
+	gitArgs = append(gitArgs, name)
+	if target := req.arg("target"); "" != target {
+		if strings.HasPrefix(target, "-") {
+			result.Code = -1
+
+			return
+		}
+
+		gitArgs = append(gitArgs, target)
+	}
+
+	runGit(req, result, gitArgs...)
+}
+
+// GitDeleteTagHandler handles request of deleting a tag.
+//
+// The tag is deleted from remote "remote" as well if it's specified.
+func GitDeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	name := req.arg("name")
+	if "" == name {
+		result.Code = -1
+		result.Msg = req.msg("git-invalid-tag")
+
+		return
+	}
+
+	if !runGit(req, result, "tag", "-d", "--", name) {
+		return
+	}
+
+	if remote := req.arg("remote"); "" != remote {
+		gitPushRef(req, result, remote, ":refs/tags/"+name)
+	}
+}
+
+// GitPushTagHandler handles request of pushing a tag (or all tags if "name" is not specified) to remote "remote"
+// (defaults to "origin").
+func GitPushTagHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	req := newGitRequest(w, r, result)
+	if nil == req {
+		return
+	}
+
+	remote := req.arg("remote")
+	if "" == remote {
+		remote = "origin"
+	}
+
+	refspec := "refs/tags/*:refs/tags/*"
+	if name := req.arg("name"); "" != name {
+		refspec = "refs/tags/" + name + ":refs/tags/" + name
+	}
+
+	gitPushRef(req, result, remote, refspec)
+}
+
+// gitPushRef pushes the specified refspec to the specified remote with the user's credential, the output is pushed
+// to the output channel.
+func gitPushRef(req *gitRequest, result *gulu.Result, remote, refspec string) {
+	if strings.HasPrefix(remote, "-") {
+		result.Code = -1
+
+		return
+	}
+
+	cmd := gitCommand(req.repo, "push", "--progress", "--", remote, refspec)
+	keyFile := setGitCredentialEnv(cmd, req.uid, req.user.GetGitCredential())
+	defer removeKeyFile(keyFile)
+
+	output, succ := runGitWithOutput(cmd, req.sid, req.locale, "git-push")
+	if succ {
+		return
+	}
+
+	result.Code = -1
+	if key := gitErrorKey(output); "" != key {
+		result.Msg = req.msg(key)
+	}
+}
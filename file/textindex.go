@@ -0,0 +1,681 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+const (
+	// textIndexVersion is the version of the format of persisted text indexes, indexes of other versions are rebuilt.
+	textIndexVersion = 1
+
+	// textIndexInterval is the interval of refreshing the loaded text indexes with the changes not notified by events
+	// (such as files pulled by git or generated by builds).
+	textIndexInterval = 10 * time.Minute
+
+	// textIndexIdle is the duration after which an unused text index is unloaded from memory.
+	textIndexIdle = 30 * time.Minute
+
+	// textIndexDelay is the delay of indexing a changed file, so that files saved repeatedly are indexed once and the
+	// modified times are not racy (see textIndexRacy) when indexed.
+	textIndexDelay = 3 * time.Second
+
+	// textIndexRacy is the duration files modified within are not indexed, a file modified again right after being
+	// indexed may keep the same modified time on file systems with coarse timestamps.
+	textIndexRacy = 2 * time.Second
+
+	// textFilterBits is the number of bits of the filter of a file per distinct trigram.
+	textFilterBits = 8
+
+	// textFilterHashes is the number of bits set in the filter of a file per trigram.
+	textFilterHashes = 3
+)
+
+// textIndex represents a persistent index of the contents of the files under a workspace, so that searches read only
+// the files which may contain the query (see searchMatcher.trigrams) instead of every file.
+//
+// The trigrams of the contents (see foldText) of each file are kept in a Bloom filter, which is much smaller than
+// posting lists and is simply replaced once the file changes. An entry is used only if the size and modified time of
+// its file are unchanged, so a stale or partial index costs reads but never loses matches.
+type textIndex struct {
+	root    string // the indexed directory
+	path    string // path of the persisted index, "" for not persisted
+	maxSize int64  // files larger than it are not indexed
+
+	mutex      sync.RWMutex
+	files      map[string]*indexedText // <path relative to root, entry>
+	dirty      bool                    // whether changed since persisted
+	refreshing bool                    // whether a refresh is running
+	discarded  bool                    // whether discarded by a rebuild, it's not persisted then
+	used       time.Time               // the latest time the index was used
+}
+
+// indexedText represents the indexed contents of a file.
+type indexedText struct {
+	Size    int64    // size of the file when indexed
+	ModTime int64    // modified time (in nanosecond) of the file when indexed
+	Binary  bool     // whether the file is a binary, which is never matched
+	Filter  []uint64 // Bloom filter of the trigrams of the contents, the number of bits is a power of two
+}
+
+// textIndexFile represents a persisted text index.
+type textIndexFile struct {
+	Version int
+	Root    string
+	Files   map[string]*indexedText
+}
+
+// textIndexUpdate represents a changed path to be indexed.
+type textIndexUpdate struct {
+	path    string
+	changed time.Time
+}
+
+var (
+	// text indexes loaded, <root, index>
+	textIndexes = map[string]*textIndex{}
+
+	// guards textIndexes
+	textIndexesMutex sync.Mutex
+
+	// changed paths to be indexed, changes are dropped once it's full and picked up by the next refresh
+	textIndexUpdates = make(chan *textIndexUpdate, 1024)
+)
+
+// LoadTextIndexes starts keeping the text indexes of workspaces fresh: files are indexed right after they are changed
+// (saved, created, removed or renamed) and the loaded indexes are refreshed every textIndexInterval in background.
+//
+// Indexes are loaded (or built in background) on demand by SearchTextHandler and persisted in the data directory.
+func LoadTextIndexes() {
+	event.Subscribe(event.HandleFunc(textChanged), event.EvtCodeFileChanged)
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for update := range textIndexUpdates {
+			if d := textIndexDelay - time.Since(update.changed); 0 < d {
+				time.Sleep(d)
+			}
+
+			if index := loadedTextIndex(update.path); nil != index {
+				index.update(update.path)
+			}
+		}
+	}()
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for range time.Tick(textIndexInterval) {
+			refreshTextIndexes()
+		}
+	}()
+}
+
+// textChanged queues the paths of the file change of the specified event to be indexed.
+func textChanged(e *event.Event) {
+	change, ok := e.Data.(*event.FileChange)
+	if !ok {
+		return
+	}
+
+	for _, path := range []string{change.Path, change.NewPath} {
+		if "" == path {
+			continue
+		}
+
+		select {
+		case textIndexUpdates <- &textIndexUpdate{path: filepath.FromSlash(path), changed: time.Now()}:
+		default:
+		}
+	}
+}
+
+// refreshTextIndexes unloads the text indexes unused for textIndexIdle and refreshes the others.
+func refreshTextIndexes() {
+	indexes := []*textIndex{}
+
+	textIndexesMutex.Lock()
+	for root, index := range textIndexes {
+		index.mutex.RLock()
+		idle := time.Since(index.used) > textIndexIdle
+		index.mutex.RUnlock()
+
+		if idle {
+			delete(textIndexes, root)
+			if err := index.save(); nil != err {
+				logger.Warnf("Saves the text index of [%s] failed [%s]", root, err)
+			}
+
+			continue
+		}
+
+		indexes = append(indexes, index)
+	}
+	textIndexesMutex.Unlock()
+
+	for _, index := range indexes {
+		index.refresh()
+	}
+}
+
+// textIndexOf returns the text index of the workspace the specified directory belongs to, returns nil if the directory
+// is not in any workspace (such as Go API).
+func textIndexOf(dir string) *textIndex {
+	dir = filepath.Clean(dir)
+	for _, user := range conf.GetUsers() {
+		for _, workspace := range filepath.SplitList(user.WorkspacePath()) {
+			workspace = filepath.Clean(workspace)
+			if dir == workspace || strings.HasPrefix(dir, workspace+string(filepath.Separator)) {
+				return getTextIndex(workspace)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadedTextIndex returns the loaded text index whose root contains the specified path, returns nil if not found.
+func loadedTextIndex(path string) *textIndex {
+	textIndexesMutex.Lock()
+	defer textIndexesMutex.Unlock()
+
+	for root, index := range textIndexes {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return index
+		}
+	}
+
+	return nil
+}
+
+// getTextIndex returns the text index of the specified root, loads it from the data directory if not loaded yet.
+// Loaded indexes are refreshed (or built for the first time) in background, searches work with partial indexes.
+func getTextIndex(root string) *textIndex {
+	textIndexesMutex.Lock()
+	defer textIndexesMutex.Unlock()
+
+	if index, ok := textIndexes[root]; ok {
+		index.mutex.Lock()
+		index.used = time.Now()
+		index.mutex.Unlock()
+
+		return index
+	}
+
+	index := newTextIndex(root, textIndexPath(root), conf.Wide().MaxFileSizeBytes())
+	if files := loadTextIndex(index.path, root); nil != files {
+		index.files = files
+	}
+	textIndexes[root] = index
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		index.refresh()
+	}()
+
+	return index
+}
+
+// newTextIndex creates an empty text index of the specified root.
+func newTextIndex(root, path string, maxSize int64) *textIndex {
+	return &textIndex{root: root, path: path, maxSize: maxSize, files: map[string]*indexedText{}, used: time.Now()}
+}
+
+// textIndexPath returns the path of the persisted text index of the specified root.
+func textIndexPath(root string) string {
+	sum := sha1.Sum([]byte(root))
+
+	return filepath.Join(conf.Wide().Data, "indexes", hex.EncodeToString(sum[:])+".gob")
+}
+
+// loadTextIndex loads the entries of the text index of the specified root from the specified file, returns nil if the
+// file does not exist or it's not an index of the root in the current format.
+func loadTextIndex(path, root string) map[string]*indexedText {
+	f, err := os.Open(path)
+	if nil != err {
+		return nil
+	}
+	defer f.Close()
+
+	data := &textIndexFile{}
+	if err := gob.NewDecoder(f).Decode(data); nil != err {
+		logger.Warnf("Parses the text index [%s] failed [%s]", path, err)
+
+		return nil
+	}
+
+	if textIndexVersion != data.Version || root != data.Root || nil == data.Files {
+		return nil
+	}
+
+	return data.Files
+}
+
+// save persists the index if it's changed.
+func (index *textIndex) save() error {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if !index.dirty || index.discarded || "" == index.path {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(index.path), 0755); nil != err {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	data := &textIndexFile{Version: textIndexVersion, Root: index.root, Files: index.files}
+	if err := gob.NewEncoder(buf).Encode(data); nil != err {
+		return err
+	}
+
+	tmp := index.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); nil != err {
+		return err
+	}
+	if err := os.Rename(tmp, index.path); nil != err {
+		return err
+	}
+
+	index.dirty = false
+
+	return nil
+}
+
+// mayContain determines whether the file of the specified path and info may contain all the specified trigrams.
+// Returns true if the file is not indexed or changed since indexed.
+func (index *textIndex) mayContain(path string, info os.FileInfo, trigrams []uint32) bool {
+	if 1 > len(trigrams) {
+		return true
+	}
+
+	rel, ok := index.rel(path)
+	if !ok {
+		return true
+	}
+
+	index.mutex.RLock()
+	entry := index.files[rel]
+	index.mutex.RUnlock()
+
+	if nil == entry || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return true
+	}
+	if entry.Binary {
+		return false
+	}
+
+	for _, trigram := range trigrams {
+		if !filterHas(entry.Filter, trigram) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rel returns the path relative to the root of the specified path, returns false if the path is not under the root.
+func (index *textIndex) rel(path string) (string, bool) {
+	prefix := index.root + string(filepath.Separator)
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+
+	return filepath.ToSlash(path[len(prefix):]), true
+}
+
+// refresh indexes the files changed since indexed and removes the entries of the files removed, then persists the
+// index. Does nothing if a refresh is running.
+func (index *textIndex) refresh() {
+	index.mutex.Lock()
+	if index.refreshing {
+		index.mutex.Unlock()
+
+		return
+	}
+	index.refreshing = true
+	index.mutex.Unlock()
+
+	defer func() {
+		index.mutex.Lock()
+		index.refreshing = false
+		index.mutex.Unlock()
+	}()
+
+	seen := index.walk(index.root)
+
+	index.mutex.Lock()
+	for rel := range index.files {
+		if !seen[rel] {
+			delete(index.files, rel)
+			index.dirty = true
+		}
+	}
+	index.mutex.Unlock()
+
+	if err := index.save(); nil != err {
+		logger.Warnf("Saves the text index of [%s] failed [%s]", index.root, err)
+	}
+}
+
+// update indexes the specified changed path, the files under it are indexed if it's a directory, and the entries are
+// removed if it's removed.
+func (index *textIndex) update(path string) {
+	rel, ok := index.rel(path)
+	if !ok || skippedIndexDir(filepath.Dir(rel)) {
+		return
+	}
+
+	seen := map[string]bool{}
+	if _, err := os.Lstat(path); nil == err {
+		seen = index.walk(path)
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	prefix := rel + "/"
+	for file := range index.files {
+		if (file == rel || strings.HasPrefix(file, prefix)) && !seen[file] {
+			delete(index.files, file)
+			index.dirty = true
+		}
+	}
+}
+
+// walk indexes the files under the specified path, returns the relative paths of the files which are indexed (or
+// unchanged since indexed). Hidden directories and the ones excluded by find (such as ".git") are skipped.
+func (index *textIndex) walk(path string) map[string]bool {
+	ret := map[string]bool{}
+
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if nil != err {
+			return nil
+		}
+		if info.IsDir() && p != index.root && skippedIndexDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if rel, ok := index.rel(p); ok && index.indexFile(p, rel, info) {
+			ret[rel] = true
+		}
+
+		return nil
+	})
+
+	return ret
+}
+
+// skippedIndexDir determines whether the directory of the specified relative path (slash separated) or any of its
+// parents is not indexed, which is hidden or excluded by find.
+func skippedIndexDir(rel string) bool {
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if (strings.HasPrefix(name, ".") && "." != name) || gulu.Str.Contains(name, defaultExcludesFind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexFile indexes the file of the specified path, relative path and info if it's changed since indexed. Returns
+// false if the file is not indexed (such as too large or modified right now).
+func (index *textIndex) indexFile(path, rel string, info os.FileInfo) bool {
+	if binaryExtensions[strings.ToLower(filepath.Ext(path))] || (0 < index.maxSize && info.Size() > index.maxSize) ||
+		time.Since(info.ModTime()) < textIndexRacy {
+		return false
+	}
+
+	modTime := info.ModTime().UnixNano()
+
+	index.mutex.RLock()
+	entry := index.files[rel]
+	index.mutex.RUnlock()
+	if nil != entry && entry.Size == info.Size() && entry.ModTime == modTime {
+		return true
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if nil != err || int64(len(content)) != info.Size() {
+		return false
+	}
+
+	entry = &indexedText{Size: info.Size(), ModTime: modTime}
+	if isBinaryHead(content[:minInt(len(content), binarySniffSize)]) {
+		entry.Binary = true
+	} else {
+		entry.Filter = newTextFilter(foldText(content))
+	}
+
+	index.mutex.Lock()
+	index.files[rel] = entry
+	index.dirty = true
+	index.mutex.Unlock()
+
+	return true
+}
+
+// foldText folds the specified text for indexing, each rune becomes a byte: ASCII letters are lower-cased, other runes
+// are folded to the ASCII letters they are case-insensitively equal to (such as the Kelvin sign 'K' to 'k') or byte
+// 0x80. Trigrams of folded queries are thus contained in the folded contents however the query is matched.
+func foldText(text []byte) []byte {
+	ret := make([]byte, 0, len(text))
+	for i := 0; i < len(text); {
+		if b := text[i]; b < utf8.RuneSelf {
+			if 'A' <= b && 'Z' >= b {
+				b += 'a' - 'A'
+			}
+			ret = append(ret, b)
+			i++
+
+			continue
+		}
+
+		r, size := utf8.DecodeRune(text[i:])
+		ret = append(ret, foldRune(r))
+		i += size
+	}
+
+	return ret
+}
+
+// foldRune folds the specified non-ASCII rune to the lower-cased ASCII letter it's case-insensitively equal to, returns
+// 0x80 if there is no such letter.
+func foldRune(r rune) byte {
+	if lower := unicode.ToLower(r); lower < utf8.RuneSelf {
+		return byte(lower)
+	}
+
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < utf8.RuneSelf {
+			return byte(unicode.ToLower(f))
+		}
+	}
+
+	return utf8.RuneSelf
+}
+
+// textTrigrams calls the specified function with each trigram of the specified folded text, trigrams spanning lines or
+// containing non-ASCII runes are skipped as queries never contain them.
+func textTrigrams(folded []byte, fn func(trigram uint32)) {
+	for i := 0; i+2 < len(folded); i++ {
+		a, b, c := folded[i], folded[i+1], folded[i+2]
+		if a >= utf8.RuneSelf || b >= utf8.RuneSelf || c >= utf8.RuneSelf || '\n' == a || '\n' == b || '\n' == c {
+			continue
+		}
+
+		fn(uint32(a)<<16 | uint32(b)<<8 | uint32(c))
+	}
+}
+
+// newTextFilter creates the Bloom filter of the trigrams of the specified folded text.
+func newTextFilter(folded []byte) []uint64 {
+	trigrams := map[uint32]bool{}
+	textTrigrams(folded, func(trigram uint32) { trigrams[trigram] = true })
+
+	bits := 64
+	for bits < len(trigrams)*textFilterBits {
+		bits <<= 1
+	}
+
+	ret := make([]uint64, bits/64)
+	for trigram := range trigrams {
+		h1, h2 := filterHashes(trigram)
+		for i := uint32(0); i < textFilterHashes; i++ {
+			bit := (h1 + i*h2) & uint32(bits-1)
+			ret[bit/64] |= 1 << (bit % 64)
+		}
+	}
+
+	return ret
+}
+
+// filterHas determines whether the specified trigram may be in the specified Bloom filter.
+func filterHas(filter []uint64, trigram uint32) bool {
+	if 1 > len(filter) {
+		return false
+	}
+
+	mask := uint32(len(filter)*64 - 1)
+	h1, h2 := filterHashes(trigram)
+	for i := uint32(0); i < textFilterHashes; i++ {
+		bit := (h1 + i*h2) & mask
+		if 0 == filter[bit/64]&(1<<(bit%64)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterHashes returns the two hashes of the specified trigram the bits of a Bloom filter are derived from.
+func filterHashes(trigram uint32) (uint32, uint32) {
+	h := uint64(trigram) * 0x9E3779B97F4A7C15
+
+	return uint32(h >> 32), uint32(h) | 1
+}
+
+// queryTrigrams returns the distinct trigrams of the specified literals which every match of a query contains.
+func queryTrigrams(literals []string) []uint32 {
+	ret := []uint32{}
+	seen := map[uint32]bool{}
+	for _, literal := range literals {
+		textTrigrams(foldText([]byte(literal)), func(trigram uint32) {
+			if !seen[trigram] {
+				seen[trigram] = true
+				ret = append(ret, trigram)
+			}
+		})
+	}
+
+	return ret
+}
+
+// requiredLiterals returns the literals which every match of the specified parsed regular expression contains.
+func requiredLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if 0 < re.Min {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		ret := []string{}
+		for _, sub := range re.Sub {
+			ret = append(ret, requiredLiterals(sub)...)
+		}
+
+		return ret
+	}
+
+	return nil
+}
+
+// ReindexHandler handles request of rebuilding the text indexes of all workspaces, administrators only. The indexes
+// are discarded and rebuilt in background, searches read all files until rebuilt.
+func ReindexHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	textIndexesMutex.Lock()
+	for _, index := range textIndexes {
+		index.mutex.Lock()
+		index.discarded = true
+		index.mutex.Unlock()
+	}
+	textIndexes = map[string]*textIndex{}
+	textIndexesMutex.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(conf.Wide().Data, "indexes")); nil != err {
+		logger.Error(err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	count := 0
+	for _, user := range conf.GetUsers() {
+		for _, workspace := range filepath.SplitList(user.WorkspacePath()) {
+			getTextIndex(filepath.Clean(workspace))
+			count++
+		}
+	}
+
+	logger.Infof("User [%s] rebuilds text indexes of [%d] workspaces", uid, count)
+
+	result.Data = map[string]interface{}{"workspaces": count}
+}
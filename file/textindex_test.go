@@ -0,0 +1,190 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeIndexed writes the specified content into the file of the specified relative path under the specified root,
+// the file is modified an hour ago so that it's not racy to be indexed.
+func writeIndexed(t *testing.T, root, rel, content string) string {
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); nil != err {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestTextIndex(t *testing.T) {
+	root := t.TempDir()
+	a := writeIndexed(t, root, "a/a.go", "package a\n\nfunc Hello() {}\n")
+	b := writeIndexed(t, root, "b/b.go", "package b\n\nconst K = \"\u212Aelvin\"\n")
+	writeIndexed(t, root, "c.png", "HelloHello")
+	writeIndexed(t, root, "d.txt", "Hello\x00")
+	writeIndexed(t, root, ".git/config", "[core]\n")
+	writeIndexed(t, root, "a/.cache/e.go", "package e\n")
+	writeIndexed(t, root, "a/CVS/f.go", "package f\n")
+
+	index := newTextIndex(root, filepath.Join(t.TempDir(), "index.gob"), 0)
+	index.refresh()
+	if 3 != len(index.files) || !index.files["d.txt"].Binary {
+		t.Fatalf("unexpected indexed files %v", index.files)
+	}
+
+	mayContain := func(path, text string) bool {
+		info, err := os.Lstat(path)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		matcher, _ := newSearchMatcher(text, false, false, false)
+
+		return index.mayContain(path, info, matcher.trigrams)
+	}
+
+	cases := []struct {
+		path     string
+		text     string
+		expected bool
+	}{
+		{a, "hello", true},
+		{a, "func hello()", true},
+		{a, "kelvin", false},
+		{b, "kelvin", true}, // folded from the Kelvin sign
+		{b, "hello", false},
+		{b, "he", true}, // too short to be filtered
+		{filepath.Join(root, "d.txt"), "hello", false},
+	}
+	for _, c := range cases {
+		if got := mayContain(c.path, c.text); c.expected != got {
+			t.Errorf("expected [%v] of [%s] in [%s], got [%v]", c.expected, c.text, c.path, got)
+		}
+	}
+
+	// changed files are searched until indexed
+	writeIndexed(t, root, "b/b.go", "package b\n\nfunc Hello() {}\n")
+	if !mayContain(b, "hello") {
+		t.Error("changed file should be searched")
+	}
+	index.update(filepath.Join(root, "b"))
+	if !mayContain(b, "hello") || mayContain(b, "kelvin") {
+		t.Error("changed file should be indexed")
+	}
+
+	// hidden and excluded directories are not indexed
+	index.update(filepath.Join(root, ".git", "config"))
+	index.update(filepath.Join(root, "a", ".cache"))
+	if 3 != len(index.files) {
+		t.Errorf("hidden directories shouldn't be indexed, got %v", index.files)
+	}
+
+	os.RemoveAll(filepath.Join(root, "a"))
+	index.update(filepath.Join(root, "a"))
+	if _, ok := index.files["a/a.go"]; ok {
+		t.Error("removed file should be removed from the index")
+	}
+
+	// persisted
+	if err := index.save(); nil != err {
+		t.Fatal(err)
+	}
+	files := loadTextIndex(index.path, root)
+	if !reflect.DeepEqual(index.files, files) {
+		t.Errorf("expected loaded %v, got %v", index.files, files)
+	}
+	if nil != loadTextIndex(index.path, filepath.Join(root, "another")) {
+		t.Error("index of another root shouldn't be loaded")
+	}
+}
+
+func TestTextIndexRacy(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.go")
+	if err := ioutil.WriteFile(path, []byte("package a\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	index := newTextIndex(root, "", 0)
+	index.refresh()
+	if 0 != len(index.files) {
+		t.Errorf("file modified right now shouldn't be indexed, got %v", index.files)
+	}
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	cases := map[string][]string{
+		`hello`:             {"hello"},
+		`(?i)hello\s+world`: {"hello", "world"},
+		`\b(?:hello)\b`:     {"hello"},
+		`(hello)+x{2,}`:     {"hello", "x"},
+		`hello|world`:       nil,
+		`(hello)?`:          nil,
+		`hel*lo`:            {"he", "lo"},
+	}
+	for pattern, expected := range cases {
+		re, err := syntax.Parse(pattern, syntax.Perl)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for _, literal := range requiredLiterals(re) {
+			got = append(got, strings.ToLower(literal))
+		}
+		if 0 == len(expected) && 0 == len(got) {
+			continue
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected %q of [%s], got %q", expected, pattern, got)
+		}
+	}
+}
+
+func TestSearchWithTextIndex(t *testing.T) {
+	root := t.TempDir()
+	writeIndexed(t, root, "a.go", "// TODO: a\n")
+	writeIndexed(t, root, "b/b.go", "package b\n")
+	writeIndexed(t, root, "b/c.go", "package c // todo\n")
+
+	index := newTextIndex(root, "", 0)
+	index.refresh()
+
+	for _, regex := range []bool{false, true} {
+		matcher, _ := newSearchMatcher("todo", regex, false, false)
+		s := newSearcher(".go", matcher, []string{root}, 0)
+		s.index = index
+		s.run()
+
+		if 2 != len(s.snippets) || 1 != s.snippets[1].Ch-len("package c // ") {
+			t.Errorf("unexpected snippets %v", s.snippets)
+		}
+	}
+}
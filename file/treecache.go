@@ -0,0 +1,126 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+// treeCache represents a Go API or Go PATH file tree persisted in the data directory, so it's not rebuilt on every
+// server start.
+type treeCache struct {
+	Key  string `json:"key"`  // Go version, root path and modified time of the root, see treeCacheKey
+	Root *Node  `json:"root"` // the tree
+}
+
+// cachedTree returns the tree of the specified name ("goroot" or "gopath") of the specified root path from the cache,
+// the tree is built with the specified function and cached if the cache is missing or stale.
+func cachedTree(name, rootPath string, build func() *Node) *Node {
+	path := treeCachePath(name)
+	key := treeCacheKey(rootPath)
+
+	if ret := loadTreeCache(path, key); nil != ret {
+		logger.Debugf("Loaded the [%s] tree from cache [%s]", name, path)
+
+		return ret
+	}
+
+	ret := build()
+	if err := saveTreeCache(path, &treeCache{Key: key, Root: ret}); nil != err {
+		logger.Warnf("Saves the [%s] tree cache [%s] failed [%s]", name, path, err)
+	}
+
+	return ret
+}
+
+// treeCachePath returns the path of the cache file of the tree of the specified name.
+func treeCachePath(name string) string {
+	return filepath.Join(conf.Wide().Data, "trees", name+".json")
+}
+
+// treeCacheKey returns the key of the tree of the specified root path. The key changes if the Go toolchain is changed
+// or entries of the root are added or removed.
+func treeCacheKey(rootPath string) string {
+	modTime := int64(0)
+	if info, err := os.Stat(rootPath); nil == err {
+		modTime = info.ModTime().UnixNano()
+	}
+
+	return fmt.Sprintf("%s %s %d", goVersion(), rootPath, modTime)
+}
+
+// goVersion returns the version of the Go toolchain at GOROOT, such as "go1.20.5". The version of the runtime is
+// returned if GOROOT has no VERSION file (built from source).
+func goVersion() string {
+	f, err := os.Open(filepath.Join(runtime.GOROOT(), "VERSION"))
+	if nil != err {
+		return runtime.Version()
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		if ret := strings.TrimSpace(scanner.Text()); "" != ret {
+			return ret
+		}
+	}
+
+	return runtime.Version()
+}
+
+// loadTreeCache loads the tree from the specified cache file, returns nil if the file does not exist or its key is not
+// the specified key.
+func loadTreeCache(path, key string) *Node {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil
+	}
+
+	cache := &treeCache{}
+	if err := json.Unmarshal(data, cache); nil != err {
+		logger.Warnf("Parses the tree cache [%s] failed [%s]", path, err)
+
+		return nil
+	}
+
+	if key != cache.Key || nil == cache.Root {
+		return nil
+	}
+
+	return cache.Root
+}
+
+// saveTreeCache saves the specified tree cache into the specified file.
+func saveTreeCache(path string, cache *treeCache) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trees", "goroot.json")
+	root := &Node{Name: "Go API", Children: []*Node{{Name: "fmt", Path: "/fmt", IsParent: true}}}
+
+	if nil != loadTreeCache(path, "go1.20 /usr/local/go/src 1") {
+		t.Error("expected no tree loaded from a missing cache")
+	}
+
+	if err := saveTreeCache(path, &treeCache{Key: "go1.20 /usr/local/go/src 1", Root: root}); nil != err {
+		t.Fatal(err)
+	}
+
+	cached := loadTreeCache(path, "go1.20 /usr/local/go/src 1")
+	if nil == cached || 1 != len(cached.Children) || "/fmt" != cached.Children[0].Path {
+		t.Errorf("expected the saved tree loaded, actual %+v", cached)
+	}
+
+	if nil != loadTreeCache(path, "go1.21 /usr/local/go/src 1") {
+		t.Error("expected no tree loaded from a cache of another Go version")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// treeVersionTTL is the duration a version of the children of a directory is kept for incremental refreshes.
+const treeVersionTTL = 30 * time.Minute
+
+// treeVersion represents the children of a directory last sent to a user, the base of the next incremental refresh.
+type treeVersion struct {
+	token    string            // version token sent to the user
+	children map[string]string // signatures of the children, <path, signature>
+	expires  time.Time         // expiration time
+}
+
+var (
+	// versions of refreshed directories, <user id + "\x00" + directory path, version>
+	treeVersions = map[string]*treeVersion{}
+
+	// guards treeVersions
+	treeVersionsMutex sync.Mutex
+)
+
+// treeDiff represents the changes of the children of a directory since a version, see RefreshDirectoryHandler.
+type treeDiff struct {
+	Version string         `json:"version"` // version token of the children after the changes
+	Full    bool           `json:"full"`    // whether the version is unknown (or expired) and Nodes are all children
+	Nodes   []*Node        `json:"nodes,omitempty"`
+	Added   []*indexedNode `json:"added,omitempty"`   // ordered by index
+	Removed []string       `json:"removed,omitempty"` // paths of the removed children
+	Changed []*Node        `json:"changed,omitempty"` // children with changed attributes (such as git status)
+}
+
+// indexedNode represents a node with its index in the children of its parent.
+type indexedNode struct {
+	Index int `json:"index"`
+	*Node
+}
+
+// diffTree returns the changes of the specified children of the specified directory since the specified version of the
+// specified user, all the children are returned if the version is unknown. The children are recorded as a new
+// version.
+func diffTree(uid, dir, version string, children []*Node) *treeDiff {
+	signatures := map[string]string{}
+	for _, child := range children {
+		signatures[child.Path] = nodeSignature(child)
+	}
+
+	key := uid + "\x00" + dir
+	now := time.Now()
+
+	treeVersionsMutex.Lock()
+	last := treeVersions[key]
+	for k, v := range treeVersions {
+		if v.expires.Before(now) {
+			delete(treeVersions, k)
+		}
+	}
+	ret := &treeDiff{Version: gulu.Rand.String(16)}
+	treeVersions[key] = &treeVersion{token: ret.Version, children: signatures, expires: now.Add(treeVersionTTL)}
+	treeVersionsMutex.Unlock()
+
+	if "" == version || nil == last || last.token != version || last.expires.Before(now) {
+		ret.Full = true
+		ret.Nodes = children
+
+		return ret
+	}
+
+	for i, child := range children {
+		signature, ok := last.children[child.Path]
+		if !ok {
+			ret.Added = append(ret.Added, &indexedNode{Index: i, Node: child})
+		} else if signature != signatures[child.Path] {
+			ret.Changed = append(ret.Changed, child)
+		}
+	}
+	for path := range last.children {
+		if _, ok := signatures[path]; !ok {
+			ret.Removed = append(ret.Removed, path)
+		}
+	}
+	sort.Strings(ret.Removed)
+
+	return ret
+}
+
+// nodeSignature returns the signature of the attributes of the specified node, the children are excluded.
+func nodeSignature(node *Node) string {
+	n := *node
+	n.Children = nil
+	data, _ := json.Marshal(&n)
+
+	return string(data)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTree(t *testing.T) {
+	uid, dir := "test-tree", "/test/src"
+	defer func() {
+		treeVersionsMutex.Lock()
+		delete(treeVersions, uid+"\x00"+dir)
+		treeVersionsMutex.Unlock()
+	}()
+
+	children := []*Node{{Path: "a", Type: "d"}, {Path: "b.go", Type: "f"}, {Path: "c.go", Type: "f"}}
+	full := diffTree(uid, dir, "", children)
+	if !full.Full || 3 != len(full.Nodes) || "" == full.Version {
+		t.Fatalf("expected all children of the first version, actual %+v", full)
+	}
+
+	children = []*Node{{Path: "a", Type: "d", Children: []*Node{{Path: "a/x.go"}}}, {Path: "aa.go", Type: "f"},
+		{Path: "c.go", Type: "f", GitStatus: "modified"}}
+	diff := diffTree(uid, dir, full.Version, children)
+	if diff.Full || diff.Version == full.Version {
+		t.Fatalf("expected an incremental diff, actual %+v", diff)
+	}
+	if 1 != len(diff.Added) || 1 != diff.Added[0].Index || "aa.go" != diff.Added[0].Path {
+		t.Errorf("unexpected added %+v", diff.Added)
+	}
+	if !reflect.DeepEqual([]string{"b.go"}, diff.Removed) {
+		t.Errorf("unexpected removed %v", diff.Removed)
+	}
+	if 1 != len(diff.Changed) || "c.go" != diff.Changed[0].Path {
+		t.Errorf("expected only c.go changed (children are ignored), actual %+v", diff.Changed)
+	}
+
+	if stale := diffTree(uid, dir, full.Version, children); !stale.Full {
+		t.Error("expected all children of a replaced version")
+	}
+}
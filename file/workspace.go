@@ -0,0 +1,319 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// max size (in byte) of an uploaded archive to import.
+const importMaxUploadSize = 256 << 20
+
+// max total size (in byte) of files extracted from an archive to import, guards against zip bombs.
+const importMaxExtractSize = 1 << 30
+
+// ExportWorkspaceHandler handles request of exporting a directory of the user's workspace as a zip archive, the whole
+// "src" of the workspace is exported if "path" is not specified.
+//
+// Query parameters: optional "path" and "pathtype" (see GetPath). Entries of the archive are under a top-level
+// directory named after the exported one, which is the layout ImportWorkspaceHandler accepts.
+func ExportWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	q := r.URL.Query()
+	pathtype := q.Get("pathtype")
+	if "" == pathtype {
+		pathtype = "0"
+	}
+	dir, _ := GetPath(uid, q.Get("path"), pathtype)
+	dir = filepath.FromSlash(dir)
+	if "" == dir || !session.CanAccess(uid, dir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	if !gulu.File.IsDir(dir) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	base := filepath.Base(dir)
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(base+".zip"))
+	w.Header().Set("Content-Type", "application/zip")
+
+	zipWriter := zip.NewWriter(w)
+	defer zipWriter.Close()
+
+	if err := addZipDirectory(zipWriter, dir, base, false); nil != err {
+		// the response has been partly written, the client gets a broken archive
+		logger.Errorf("Exports [%s] of user [%s] failed: %s", dir, uid, err)
+	}
+}
+
+// addZipDirectory adds files of the specified directory to the specified zip writer under the specified top-level
+// directory name, ".git" of repositories are skipped if the specified skipGit is true.
+func addZipDirectory(zipWriter *zip.Writer, dir, base string, skipGit bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+		if skipGit && ".git" == info.Name() { // a directory, or a file linking to the one of a submodule
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+		if !info.Mode().IsRegular() { // directories are implied by files, others such as symbolic links are skipped
+			return nil
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+
+		return addZipEntry(zipWriter, path, info, base+"/"+filepath.ToSlash(rel))
+	})
+}
+
+// addZipEntry adds the specified file to the specified zip writer as the specified name.
+func addZipEntry(zipWriter *zip.Writer, path string, info os.FileInfo, name string) error {
+	f, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	header, err := zip.FileInfoHeader(info)
+	if nil != err {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	entry, err := zipWriter.CreateHeader(header)
+	if nil != err {
+		return err
+	}
+
+	_, err = io.CopyN(entry, f, info.Size())
+
+	return err
+}
+
+// ImportWorkspaceHandler handles request of importing a zip archive into a new project directory.
+//
+// Multipart form values: "file" (the zip archive), "path" and "pathtype" of the parent directory (see GetPath),
+// optional "name" of the project directory (defaults to the archive name without extension) and "sid" (the tree of
+// the session is refreshed). If all entries of the archive are under a single top-level directory (such as an
+// exported one), the directory is stripped.
+func ImportWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxUploadSize)
+	file, header, err := r.FormFile("file")
+	if nil != err {
+		logger.Warnf("Reads uploaded archive of user [%s] failed: %s", uid, err)
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "import-invalid-archive").(string)
+
+		return
+	}
+	defer file.Close()
+
+	pathtype := r.FormValue("pathtype")
+	if "" == pathtype {
+		pathtype = "0"
+	}
+	parent, _ := GetPath(uid, r.FormValue("path"), pathtype)
+	parent = filepath.FromSlash(parent)
+	if "" == parent || !session.CanAccess(uid, parent) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if "" == name {
+		name = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if "" == name || "." == name || ".." == name || strings.ContainsAny(name, `/\`) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "import-invalid-name").(string)
+
+		return
+	}
+
+	target := filepath.Join(parent, name)
+	if gulu.File.IsExist(target) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "import-exists", i18n.Params{"name": name}).(string)
+
+		return
+	}
+
+	reader, err := zip.NewReader(file, header.Size)
+	if nil != err {
+		logger.Warnf("Opens uploaded archive of user [%s] failed: %s", uid, err)
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "import-invalid-archive").(string)
+
+		return
+	}
+
+	var size int64
+	for _, f := range reader.File {
+		size += int64(f.UncompressedSize64)
+	}
+	if msg := quotaExceeded(uid, size); "" != msg {
+		result.Code = -1
+		result.Msg = msg
+
+		return
+	}
+
+	if err := extractArchive(reader, target); nil != err {
+		os.RemoveAll(target)
+
+		logger.Warnf("Imports archive into [%s] of user [%s] failed: %s", target, uid, err)
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "import-invalid-archive").(string) + " [" + err.Error() + "]"
+
+		return
+	}
+
+	addUsage(uid, size)
+	logger.Debugf("User [%s] imported [%s] into [%s]", uid, header.Filename, target)
+	result.Data = filepath.ToSlash(target)
+
+	if wsChannel := session.SessionWS[r.FormValue("sid")]; nil != wsChannel {
+		cmd := map[string]interface{}{"path": filepath.ToSlash(target), "dir": filepath.ToSlash(parent),
+			"cmd": "refresh-dir", "type": "d"}
+		wsChannel.WriteJSON(&cmd)
+	}
+}
+
+// extractArchive extracts files of the specified archive into the specified directory, which is created. The single
+// top-level directory containing all entries is stripped.
+func extractArchive(reader *zip.Reader, dir string) error {
+	root := archiveRoot(reader)
+
+	var extracted int64
+	for _, f := range reader.File {
+		name := strings.TrimPrefix(f.Name, root)
+		if "" == name || f.FileInfo().IsDir() {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue // symbolic links may point outside of the workspace
+		}
+
+		name = path.Clean("/" + strings.Replace(name, `\`, "/", -1))[1:]
+		if "" == name {
+			continue
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+
+		extracted += int64(f.UncompressedSize64)
+		if importMaxExtractSize < extracted {
+			return errors.New("archive is too large")
+		}
+
+		if err := extractZipEntry(f, dest, importMaxExtractSize-extracted+int64(f.UncompressedSize64)); nil != err {
+			return err
+		}
+	}
+
+	return os.MkdirAll(dir, 0755)
+}
+
+// archiveRoot returns the top-level directory (with the trailing "/") containing all entries of the specified archive,
+// returns "" if there is not such a directory.
+func archiveRoot(reader *zip.Reader) string {
+	root := ""
+	for _, f := range reader.File {
+		i := strings.Index(f.Name, "/")
+		if 1 > i {
+			return ""
+		}
+
+		if top := f.Name[:i+1]; "" == root {
+			root = top
+		} else if root != top {
+			return ""
+		}
+	}
+
+	return root
+}
+
+// extractZipEntry extracts the specified zip file to the specified path, at most limit bytes are written since the
+// declared size in the archive may be forged.
+func extractZipEntry(f *zip.File, dest string, limit int64) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); nil != err {
+		return err
+	}
+
+	reader, err := f.Open()
+	if nil != err {
+		return err
+	}
+	defer reader.Close()
+
+	perm := f.Mode().Perm() | 0600
+	writer, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if nil != err {
+		return err
+	}
+
+	n, err := io.Copy(writer, io.LimitReader(reader, limit+1))
+	if closeErr := writer.Close(); nil == err {
+		err = closeErr
+	}
+	if nil == err && n > limit {
+		err = errors.New("archive is too large")
+	}
+
+	return err
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newZipReader creates a zip reader of the specified files keyed by entry name.
+func newZipReader(t *testing.T, files map[string]string) *zip.Reader {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	for name, content := range files {
+		entry, err := zipWriter.Create(name)
+		if nil != err {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(content))
+	}
+	zipWriter.Close()
+
+	ret, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return ret
+}
+
+func TestExtractArchive(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected map[string]string // extracted files relative to the target directory
+	}{
+		{"exported", map[string]string{"hello/main.go": "package main", "hello/pkg/a.go": "package pkg"},
+			map[string]string{"main.go": "package main", "pkg/a.go": "package pkg"}},
+		{"flat", map[string]string{"main.go": "package main", "pkg/a.go": "package pkg"},
+			map[string]string{"main.go": "package main", "pkg/a.go": "package pkg"}},
+		{"slip", map[string]string{"../../evil.go": "evil", `..\..\evil2.go`: "evil", "/abs/evil3.go": "evil",
+			"a/../../../evil4.go": "evil"},
+			map[string]string{"evil.go": "evil", "evil2.go": "evil", "abs/evil3.go": "evil", "evil4.go": "evil"}},
+	}
+
+	for _, c := range cases {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "project")
+		if err := extractArchive(newZipReader(t, c.files), dir); nil != err {
+			t.Errorf("[%s] extracts failed: %s", c.name, err)
+
+			continue
+		}
+
+		count := 0
+		filepath.Walk(parent, func(path string, info os.FileInfo, err error) error {
+			if nil == err && !info.IsDir() {
+				count++
+			}
+
+			return nil
+		})
+		if len(c.expected) != count {
+			t.Errorf("[%s] expected %d files extracted, got %d", c.name, len(c.expected), count)
+		}
+
+		for name, content := range c.expected {
+			data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if nil != err || content != string(data) {
+				t.Errorf("[%s] expected [%s] of [%s], got [%s] (%v)", c.name, content, name, data, err)
+			}
+		}
+	}
+}
+
+func TestAddZipDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hello")
+	files := map[string]string{"main.go": "package main", "pkg/a.go": "package pkg", ".git/HEAD": "ref",
+		"sub/.git": "gitdir: ../.git/modules/sub", "sub/b.go": "package sub"}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	for _, skipGit := range []bool{true, false} {
+		buf := &bytes.Buffer{}
+		zipWriter := zip.NewWriter(buf)
+		if err := addZipDirectory(zipWriter, dir, "hello", skipGit); nil != err {
+			t.Fatal(err)
+		}
+		zipWriter.Close()
+
+		reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if nil != err {
+			t.Fatal(err)
+		}
+		names := map[string]bool{}
+		for _, f := range reader.File {
+			names[f.Name] = true
+		}
+
+		if !names["hello/main.go"] || !names["hello/pkg/a.go"] || !names["hello/sub/b.go"] {
+			t.Errorf("expected files missing, got %v", names)
+		}
+		if skipGit == (names["hello/.git/HEAD"] || names["hello/sub/.git"]) {
+			t.Errorf("unexpected .git entries with skipGit [%v], got %v", skipGit, names)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcSrv is the gRPC server, nil if the gRPC control API is disabled.
+var grpcSrv *grpc.Server
+
+// gRPC server lock.
+var grpcMutex sync.Mutex
+
+// serveGRPC serves the gRPC control API (see rpc/wide.proto) on Wide.GRPCAddr if it's set. The server uses the TLS
+// certificate of HTTPS if TLSCert is configured.
+func serveGRPC() {
+	addr := conf.Wide().GRPCAddr
+	if "" == addr {
+		return
+	}
+
+	var opts []grpc.ServerOption
+	if "" != conf.Wide().TLSCert {
+		creds, err := credentials.NewServerTLSFromFile(conf.Wide().TLSCert, conf.Wide().TLSKey)
+		if nil != err {
+			logger.Errorf("Loads TLS certificate of gRPC failed: %s", err)
+
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else {
+		logger.Warnf("gRPC is served without TLS, API tokens are sent in plaintext")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if nil != err {
+		logger.Errorf("Listens gRPC on [%s] failed: %s", addr, err)
+
+		return
+	}
+
+	server := grpc.NewServer(opts...)
+	rpc.RegisterWideServer(server, newGRPCServer())
+	grpcMutex.Lock()
+	grpcSrv = server
+	grpcMutex.Unlock()
+
+	logger.Infof("Wide is serving gRPC on [%s]", addr)
+	go func() {
+		if err := server.Serve(listener); nil != err {
+			logger.Error(err)
+		}
+	}()
+}
+
+// stopGRPC stops the gRPC server gracefully, in-flight calls are cancelled once the specified context is done.
+func stopGRPC(ctx context.Context) {
+	grpcMutex.Lock()
+	server := grpcSrv
+	grpcMutex.Unlock()
+	if nil == server {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// grpcServer implements the gRPC control API by calling the handlers of the REST API in-process, so both share the
+// authentication, parameter checks, rate limits and access checks of the routes (see apiWrapper).
+type grpcServer struct {
+	rpc.UnimplementedWideServer
+
+	routes   map[string]*apiRoute                                // REST API routes by path
+	handlers map[string]func(http.ResponseWriter, *http.Request) // wrapped handlers of the routes by path
+}
+
+// newGRPCServer creates a gRPC server of the REST API routes (apiRoutes).
+func newGRPCServer() *grpcServer {
+	ret := &grpcServer{routes: map[string]*apiRoute{}, handlers: map[string]func(http.ResponseWriter, *http.Request){}}
+	for _, route := range apiRoutes {
+		ret.routes[route.Path] = route
+		ret.handlers[route.Path] = handlerWrapper(apiWrapper(route))
+	}
+
+	return ret
+}
+
+func (s *grpcServer) ListFiles(ctx context.Context, req *rpc.ListFilesRequest) (*rpc.ListFilesResponse, error) {
+	w := newGRPCResponse(nil)
+	s.serve(ctx, w, "/files/tree", map[string]interface{}{"path": req.Path, "pathtype": pathtype(req.Pathtype),
+		"offset": req.Offset})
+	if err := w.err(); nil != err {
+		return nil, err
+	}
+
+	var nodes []*struct {
+		Name      string `json:"name"`
+		Path      string `json:"path"`
+		Type      string `json:"type"`
+		GitStatus string `json:"gitStatus"`
+		More      int32  `json:"more"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &nodes); nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	ret := &rpc.ListFilesResponse{}
+	for _, node := range nodes {
+		if "m" == node.Type { // marker of more children
+			ret.More = node.More
+
+			continue
+		}
+
+		ret.Nodes = append(ret.Nodes, &rpc.Node{Name: node.Name, Path: node.Path, Type: node.Type,
+			GitStatus: node.GitStatus})
+	}
+
+	return ret, nil
+}
+
+func (s *grpcServer) ReadFile(ctx context.Context, req *rpc.ReadFileRequest) (*rpc.ReadFileResponse, error) {
+	data := struct {
+		Content *string `json:"content"`
+		Mode    string  `json:"mode"`
+	}{}
+	if err := s.call(ctx, "/files/read", map[string]interface{}{"path": req.Path, "pathtype": pathtype(req.Pathtype),
+		"revision": req.Revision}, &data); nil != err {
+		return nil, err
+	}
+	if nil == data.Content { // an image
+		return nil, status.Error(codes.FailedPrecondition, "not a text file")
+	}
+
+	return &rpc.ReadFileResponse{Content: *data.Content, Mode: data.Mode}, nil
+}
+
+func (s *grpcServer) WriteFile(ctx context.Context, req *rpc.WriteFileRequest) (*rpc.WriteFileResponse, error) {
+	if err := s.call(ctx, "/files/save", map[string]interface{}{"file": req.Path, "pathtype": pathtype(req.Pathtype),
+		"code": req.Content}, nil); nil != err {
+		return nil, err
+	}
+
+	return &rpc.WriteFileResponse{}, nil
+}
+
+func (s *grpcServer) CreateFile(ctx context.Context, req *rpc.CreateFileRequest) (*rpc.CreateFileResponse, error) {
+	fileType := "f"
+	if req.Directory {
+		fileType = "d"
+	}
+	if err := s.call(ctx, "/files/new", map[string]interface{}{"path": req.Path, "pathtype": pathtype(req.Pathtype),
+		"fileType": fileType}, nil); nil != err {
+		return nil, err
+	}
+
+	return &rpc.CreateFileResponse{}, nil
+}
+
+func (s *grpcServer) RemoveFile(ctx context.Context, req *rpc.RemoveFileRequest) (*rpc.RemoveFileResponse, error) {
+	if err := s.call(ctx, "/files/remove", map[string]interface{}{"path": req.Path,
+		"pathtype": pathtype(req.Pathtype)}, nil); nil != err {
+		return nil, err
+	}
+
+	return &rpc.RemoveFileResponse{}, nil
+}
+
+func (s *grpcServer) RenameFile(ctx context.Context, req *rpc.RenameFileRequest) (*rpc.RenameFileResponse, error) {
+	if err := s.call(ctx, "/files/rename", map[string]interface{}{"oldPath": req.OldPath, "newPath": req.NewPath,
+		"pathtype": pathtype(req.Pathtype)}, nil); nil != err {
+		return nil, err
+	}
+
+	return &rpc.RenameFileResponse{}, nil
+}
+
+func (s *grpcServer) SearchText(ctx context.Context, req *rpc.SearchTextRequest) (*rpc.SearchTextResponse, error) {
+	data := struct {
+		Snippets []*file.Snippet `json:"snippets"`
+	}{}
+	if err := s.call(ctx, "/search/text", map[string]interface{}{"dir": req.Dir, "pathtype": pathtype(req.Pathtype),
+		"text": req.Text, "extension": req.Extension}, &data); nil != err {
+		return nil, err
+	}
+
+	ret := &rpc.SearchTextResponse{}
+	for _, snippet := range data.Snippets {
+		ret.Snippets = append(ret.Snippets, &rpc.Snippet{Path: snippet.Path, Line: int32(snippet.Line),
+			Ch: int32(snippet.Ch), Contents: snippet.Contents})
+	}
+
+	return ret, nil
+}
+
+func (s *grpcServer) FindFiles(ctx context.Context, req *rpc.FindFilesRequest) (*rpc.FindFilesResponse, error) {
+	data := struct {
+		Paths []*struct {
+			Path string `json:"path"`
+		} `json:"paths"`
+		More bool `json:"more"`
+	}{}
+	args := map[string]interface{}{"path": req.Path, "pathtype": pathtype(req.Pathtype), "name": req.Name}
+	if 0 < req.Page {
+		args["page"] = req.Page
+	}
+	if err := s.call(ctx, "/search/files", args, &data); nil != err {
+		return nil, err
+	}
+
+	ret := &rpc.FindFilesResponse{More: data.More}
+	for _, found := range data.Paths {
+		ret.Paths = append(ret.Paths, found.Path)
+	}
+
+	return ret, nil
+}
+
+func (s *grpcServer) FindSymbols(ctx context.Context, req *rpc.FindSymbolsRequest) (*rpc.FindSymbolsResponse, error) {
+	var symbols []*file.Symbol
+	if err := s.call(ctx, "/search/symbols", map[string]interface{}{"name": req.Name}, &symbols); nil != err {
+		return nil, err
+	}
+
+	ret := &rpc.FindSymbolsResponse{}
+	for _, symbol := range symbols {
+		ret.Symbols = append(ret.Symbols, &rpc.Symbol{Name: symbol.Name, Kind: symbol.Kind, Recv: symbol.Recv,
+			Path: symbol.Path, Line: int32(symbol.Line), Ch: int32(symbol.Ch)})
+	}
+
+	return ret, nil
+}
+
+func (s *grpcServer) Build(req *rpc.JobRequest, stream rpc.Wide_BuildServer) error {
+	return s.stream(stream.Context(), "/build", req, stream.Send)
+}
+
+func (s *grpcServer) Test(req *rpc.JobRequest, stream rpc.Wide_TestServer) error {
+	return s.stream(stream.Context(), "/test", req, stream.Send)
+}
+
+func (s *grpcServer) Run(req *rpc.JobRequest, stream rpc.Wide_RunServer) error {
+	return s.stream(stream.Context(), "/run", req, stream.Send)
+}
+
+// stream calls the REST API route of the specified path with "stream" set, the output is sent while the job runs and
+// the exit code is sent last.
+func (s *grpcServer) stream(ctx context.Context, path string, req *rpc.JobRequest, send func(*rpc.Output) error) error {
+	w := newGRPCResponse(func(data []byte) error {
+		return send(&rpc.Output{Data: data})
+	})
+	s.serve(ctx, w, path, map[string]interface{}{"path": req.Path, "pathtype": pathtype(req.Pathtype), "stream": true})
+	if err := w.err(); nil != err {
+		return err
+	}
+
+	exitCode, err := strconv.Atoi(w.header.Get("X-Exit-Code"))
+	if nil != err {
+		return status.Error(codes.Aborted, "the job is interrupted")
+	}
+
+	return send(&rpc.Output{Done: true, ExitCode: int32(exitCode)})
+}
+
+// call calls the REST API route of the specified path with the specified arguments, the data of the result is decoded
+// into the specified value if it's not nil. Results of nonzero code are turned into errors of FailedPrecondition.
+func (s *grpcServer) call(ctx context.Context, path string, args map[string]interface{}, v interface{}) error {
+	w := newGRPCResponse(nil)
+	s.serve(ctx, w, path, args)
+	if err := w.err(); nil != err {
+		return err
+	}
+
+	result := struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.body.Bytes(), &result); nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if 0 != result.Code {
+		msg := result.Msg
+		if "" == msg {
+			msg = "failed"
+		}
+
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+
+	if nil == v || 0 == len(result.Data) {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, v); nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
+
+// serve serves the REST API route of the specified path with a request of the specified arguments (in the JSON body,
+// or the query of GET routes). The API token in metadata "authorization" and the peer address of the specified
+// context of the call are set to the request.
+func (s *grpcServer) serve(ctx context.Context, w *grpcResponse, path string, args map[string]interface{}) {
+	route := s.routes[path]
+
+	target := apiPrefix + path
+	var body []byte
+	if http.MethodGet == route.Method {
+		query := url.Values{}
+		for name, value := range args {
+			query.Set(name, fmt.Sprint(value))
+		}
+		target += "?" + query.Encode()
+	} else {
+		body, _ = json.Marshal(args)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, route.Method, target, bytes.NewReader(body))
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if auth := md.Get("authorization"); 0 < len(auth) {
+			r.Header.Set("Authorization", auth[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	s.handlers[path](w, r)
+}
+
+// pathtype returns the argument "pathtype" of the REST API of the specified path type.
+func pathtype(t rpc.PathType) string {
+	return strconv.Itoa(int(t))
+}
+
+// grpcResponse records the response of a REST API handler called by the gRPC server.
+type grpcResponse struct {
+	header http.Header
+	status int                     // status code, 0 if not written
+	body   bytes.Buffer            // body, except for the output sent while streaming
+	send   func(data []byte) error // sends the output of a succeeded streaming response, nil if not streaming
+}
+
+// newGRPCResponse creates a response, the body of a succeeded response is sent with the specified function if it's not
+// nil.
+func newGRPCResponse(send func(data []byte) error) *grpcResponse {
+	return &grpcResponse{header: http.Header{}, send: send}
+}
+
+func (w *grpcResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponse) WriteHeader(statusCode int) {
+	if 0 == w.status {
+		w.status = statusCode
+	}
+}
+
+func (w *grpcResponse) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if nil != w.send && http.StatusOK == w.status {
+		if err := w.send(p); nil != err {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	return w.body.Write(p)
+}
+
+// Flush implements http.Flusher, the output is sent on each write.
+func (w *grpcResponse) Flush() {}
+
+// err returns the gRPC status error of the response status, nil if it's 200.
+func (w *grpcResponse) err() error {
+	code := codes.Internal
+	switch w.status {
+	case 0, http.StatusOK:
+		return nil
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests:
+		return status.Error(codes.ResourceExhausted, "rate limited, retry after "+w.header.Get("Retry-After")+"s")
+	}
+
+	// the first line is the message of http.Error, some handlers write a result after it
+	msg, _, _ := strings.Cut(w.body.String(), "\n")
+
+	return status.Error(code, msg)
+}
@@ -132,6 +132,10 @@ function minWideJS () {
     './static/js/tree.js',
     './static/js/wide.js',
     './static/js/session.js',
+    './static/js/collab.js',
+    './static/js/follow.js',
+    './static/js/chat.js',
+    './static/js/sharedOutput.js',
     './static/js/menu.js',
     './static/js/windows.js',
     './static/js/hotkeys.js',
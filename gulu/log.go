@@ -70,6 +70,13 @@ func (*guluLog) SetLevel(level string) {
 	}
 }
 
+// SetOutput sets the output destination of all loggers.
+func (*guluLog) SetOutput(out io.Writer) {
+	for _, l := range loggers {
+		l.logger.SetOutput(out)
+	}
+}
+
 // getLevel gets logging level int value corresponding to the specified level.
 func getLevel(level string) int {
 	level = strings.ToLower(level)
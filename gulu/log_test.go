@@ -16,6 +16,9 @@
 package gulu
 
 import (
+	"bytes"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +26,18 @@ func TestSetLevel(t *testing.T) {
 	Log.SetLevel("trace")
 }
 
+func TestSetOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	Log.SetOutput(buf)
+	defer Log.SetOutput(os.Stdout)
+
+	logger.SetLevel("info")
+	logger.Info("info")
+	if !strings.Contains(buf.String(), "info") {
+		t.Errorf("output [%s] should contain [info]", buf.String())
+	}
+}
+
 func TestTrace(t *testing.T) {
 	Log.SetLevel("trace")
 	logger.Trace("trace")
@@ -16,9 +16,13 @@
 package gulu
 
 import (
+	"bufio"
 	"compress/gzip"
 	"encoding/json"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
 )
 
 // Result represents a common-used result struct.
@@ -51,25 +55,10 @@ func (*GuluRet) RetResult(w http.ResponseWriter, r *http.Request, res *Result) {
 	w.Write(data)
 }
 
-// RetGzResult writes HTTP response with "Content-Type, application/json" and "Content-Encoding, gzip".
-func (*GuluRet) RetGzResult(w http.ResponseWriter, r *http.Request, res *Result) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Encoding", "gzip")
-
-	gz := gzip.NewWriter(w)
-	err := json.NewEncoder(gz).Encode(res)
-	if nil != err {
-		logger.Error(err)
-
-		return
-	}
-
-	err = gz.Close()
-	if nil != err {
-		logger.Error(err)
-
-		return
-	}
+// RetGzResult writes HTTP response with "Content-Type, application/json" and "Content-Encoding, gzip" (if accepted by
+// the client), see RetGzValue.
+func (ret *GuluRet) RetGzResult(w http.ResponseWriter, r *http.Request, res *Result) {
+	ret.RetGzValue(w, r, res)
 }
 
 // RetJSON writes HTTP response with "Content-Type, application/json".
@@ -86,23 +75,87 @@ func (*GuluRet) RetJSON(w http.ResponseWriter, r *http.Request, res map[string]i
 	w.Write(data)
 }
 
-// RetGzJSON writes HTTP response with "Content-Type, application/json" and "Content-Encoding, gzip".
-func (*GuluRet) RetGzJSON(w http.ResponseWriter, r *http.Request, res map[string]interface{}) {
+// RetGzJSON writes HTTP response with "Content-Type, application/json" and "Content-Encoding, gzip" (if accepted by the
+// client), see RetGzValue.
+func (ret *GuluRet) RetGzJSON(w http.ResponseWriter, r *http.Request, res map[string]interface{}) {
+	ret.RetGzValue(w, r, res)
+}
+
+// RetGzValue writes HTTP response of the JSON encoding of the specified value with "Content-Type, application/json".
+//
+// Elements of a slice are encoded one by one (see writeJSON) instead of being marshaled into memory as a whole, and the
+// encoding is compressed with "Content-Encoding, gzip" if the client accepts it.
+func (*GuluRet) RetGzValue(w http.ResponseWriter, r *http.Request, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		buf := bufio.NewWriter(w)
+		if err := writeJSON(buf, v); nil != err {
+			logger.Error(err)
+
+			return
+		}
+
+		if err := buf.Flush(); nil != err {
+			logger.Error(err)
+		}
+
+		return
+	}
+
 	w.Header().Set("Content-Encoding", "gzip")
 
 	gz := gzip.NewWriter(w)
-	err := json.NewEncoder(gz).Encode(res)
-	if nil != err {
+	if err := writeJSON(gz, v); nil != err {
 		logger.Error(err)
 
 		return
 	}
 
-	err = gz.Close()
-	if nil != err {
+	if err := gz.Close(); nil != err {
 		logger.Error(err)
+	}
+}
 
-		return
+// writeJSON writes the JSON encoding of the specified value to the specified writer with json.Encoder.
+//
+// Elements of a slice or an array are encoded one by one, so only one element of a large slice (such as nodes of a file
+// tree) is held in memory at a time.
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	if _, ok := v.(json.Marshaler); ok {
+		return encoder.Encode(v)
 	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.IsNil() || reflect.Uint8 == value.Type().Elem().Kind() { // nil and []byte (base64)
+			return encoder.Encode(v)
+		}
+	case reflect.Array: // encoded one by one below
+	default:
+		return encoder.Encode(v)
+	}
+
+	if _, err := io.WriteString(w, "["); nil != err {
+		return err
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		if 0 < i {
+			if _, err := io.WriteString(w, ","); nil != err {
+				return err
+			}
+		}
+
+		if err := encoder.Encode(value.Index(i).Interface()); nil != err {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]")
+
+	return err
 }
@@ -15,7 +15,13 @@
 
 package gulu
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
 
 func TestNewResult(t *testing.T) {
 	result := Ret.NewResult()
@@ -23,3 +29,74 @@ func TestNewResult(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestWriteJSON(t *testing.T) {
+	type node struct {
+		Name     string  `json:"name"`
+		Size     int     `json:"size,omitempty"`
+		Hidden   string  `json:"-"`
+		Children []*node `json:"children"`
+		private  bool
+	}
+	type embedded struct {
+		*node
+		Extra int
+	}
+
+	values := []interface{}{
+		nil,
+		&Result{Code: -1, Msg: "<msg>", Data: []*node{{Name: "a", Children: []*node{{Name: "b", Size: 1}}}, nil}},
+		map[string]interface{}{"paths": []string{"x", "y"}, "more": true, "empty": []int{}, "nil": []int(nil)},
+		[2]float64{1.5, 1e21},
+		[]byte("bytes"),
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		embedded{node: &node{Name: "e"}, Extra: 1},
+		map[int]string{2: "b", 1: "a"},
+		[]interface{}{"<a>", &node{Name: "n"}, []*node{}, nil},
+		[0]int{},
+	}
+
+	for _, v := range values {
+		expected, err := json.Marshal(v)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		buf := &bytes.Buffer{}
+		if err := writeJSON(buf, v); nil != err {
+			t.Errorf("writes %#v failed: %s", v, err)
+
+			continue
+		}
+		compacted := &bytes.Buffer{}
+		if err := json.Compact(compacted, buf.Bytes()); nil != err {
+			t.Errorf("writes invalid JSON %s: %s", buf, err)
+
+			continue
+		}
+		if actual := compacted.String(); string(expected) != actual {
+			t.Errorf("expected %s, actual %s", expected, actual)
+		}
+	}
+}
+
+func TestRetGzValue(t *testing.T) {
+	for _, encoding := range []string{"", "gzip"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+
+		Ret.RetGzValue(w, r, []int{1, 2})
+		if actual := w.Header().Get("Content-Encoding"); encoding != actual {
+			t.Errorf("expected encoding [%s], actual [%s]", encoding, actual)
+		}
+		if "" != encoding {
+			continue
+		}
+
+		var actual []int
+		if err := json.Unmarshal(w.Body.Bytes(), &actual); nil != err || 2 != len(actual) || 2 != actual[1] {
+			t.Errorf("expected [1,2], actual %s", w.Body.String())
+		}
+	}
+}
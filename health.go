@@ -0,0 +1,107 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// readinessCheckTimeout is the timeout of each readiness check.
+const readinessCheckTimeout = 5 * time.Second
+
+// healthzHandler handles request of liveness probe, responds "ok" as long as the process is up.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler handles request of readiness probe, checks whether the Go toolchain is reachable, the data directory
+// is writable and gopls is responsive (if autocomplete is enabled).
+//
+// Responds 200 if all checks passed, 503 otherwise. The JSON response tells "ok" or "fail" of each check, the errors,
+// numbers of published events and numbers of rate limited requests are included only for administrators since the
+// endpoint is not authenticated.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"go": checkCommand("go", "version"), "data": checkDataWritable()}
+	if conf.Wide().Autocomplete {
+		checks["gopls"] = checkCommand(gulu.Go.GetExecutableInGOBIN("gopls"), "version")
+	}
+
+	status := http.StatusOK
+	ret := map[string]interface{}{"status": "ok"}
+	for _, check := range checks {
+		if "ok" != check {
+			status = http.StatusServiceUnavailable
+			ret["status"] = "unavailable"
+
+			break
+		}
+	}
+
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if uid, _ := httpSession.Values["uid"].(string); !httpSession.IsNew && conf.Wide().IsAdmin(uid) {
+		ret["checks"] = checks
+		ret["events"] = event.Counts()
+		ret["rateLimited"] = rateLimitedCounts()
+	} else {
+		results := map[string]string{}
+		for name, check := range checks {
+			results[name] = "ok"
+			if "ok" != check {
+				results[name] = "fail"
+			}
+		}
+		ret["checks"] = results
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ret)
+}
+
+// checkCommand runs the specified command, returns "ok" if it succeeded, the error otherwise.
+func checkCommand(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+	defer cancel()
+
+	if err := exec.CommandContext(ctx, name, args...).Run(); nil != err {
+		return err.Error()
+	}
+
+	return "ok"
+}
+
+// checkDataWritable checks whether the data directory is writable, returns "ok" if it is, the error otherwise.
+func checkDataWritable() string {
+	f, err := ioutil.TempFile(conf.Wide().Data, ".readyz-")
+	if nil != err {
+		return err.Error()
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return "ok"
+}
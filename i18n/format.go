@@ -0,0 +1,100 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Params represents the named parameters of a message, such as {"name": "hello", "count": 2}.
+//
+// A message refers to a parameter by {name}. A pluralized message is an object of plural forms ("zero", "one", "two",
+// "few", "many" and "other") in the locale pack, the form is selected by the "count" parameter, for example:
+//
+//	"files": {"one": "{count} file", "other": "{count} files"}
+type Params map[string]interface{}
+
+// paramRegexp matches parameter references of messages.
+var paramRegexp = regexp.MustCompile(`\{(\w+)\}`)
+
+// format formats the specified message with the specified parameters, references of missing parameters are kept.
+func format(locale string, msg interface{}, params Params) interface{} {
+	if forms, ok := msg.(map[string]interface{}); ok {
+		msg = forms[pluralForm(locale, params["count"])]
+		if nil == msg {
+			msg = forms["other"]
+		}
+	}
+
+	text, ok := msg.(string)
+	if !ok || nil == params {
+		return msg
+	}
+
+	return paramRegexp.ReplaceAllStringFunc(text, func(ref string) string {
+		if param, ok := params[ref[1:len(ref)-1]]; ok {
+			return fmt.Sprint(param)
+		}
+
+		return ref
+	})
+}
+
+// pluralForm gets the plural form of the specified count in the specified locale, see
+// https://unicode-org.github.io/cldr-staging/charts/latest/supplemental/language_plural_rules.html for more details.
+func pluralForm(locale string, count interface{}) string {
+	var n int64
+	switch c := count.(type) {
+	case int:
+		n = int64(c)
+	case int64:
+		n = c
+	case float64: // numbers decoded from JSON
+		n = int64(c)
+	default:
+		return "other"
+	}
+	if 0 > n {
+		n = -n
+	}
+
+	lang := strings.SplitN(locale, "_", 2)[0]
+	switch {
+	case "zh" == lang || "ja" == lang || "ko" == lang:
+		return "other"
+	case "fr" == lang || "pt_BR" == locale:
+		if 0 == n || 1 == n {
+			return "one"
+		}
+	case "ru" == lang || "uk" == lang || "pl" == lang:
+		mod10, mod100 := n%10, n%100
+		switch {
+		case 1 == n || ("pl" != lang && 1 == mod10 && 11 != mod100):
+			return "one"
+		case 2 <= mod10 && 4 >= mod10 && (12 > mod100 || 14 < mod100):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if 1 == n {
+			return "one"
+		}
+	}
+
+	return "other"
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package i18n
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	files := map[string]interface{}{"one": "{count} file in {dir}", "other": "{count} files in {dir}"}
+
+	cases := []struct {
+		locale string
+		msg    interface{}
+		params Params
+		want   interface{}
+	}{
+		{"en_US", "START [go build{args}]", Params{"args": " [-i]"}, "START [go build [-i]]"},
+		{"en_US", "{user} ran {job} in {duration}", Params{"user": "admin", "job": "build"}, "admin ran build in {duration}"},
+		{"en_US", "no params {name}", nil, "no params {name}"},
+		{"en_US", files, Params{"count": 1, "dir": "src"}, "1 file in src"},
+		{"en_US", files, Params{"count": 0, "dir": "src"}, "0 files in src"},
+		{"fr_FR", files, Params{"count": 0, "dir": "src"}, "0 file in src"},
+		{"zh_CN", files, Params{"count": 1, "dir": "src"}, "1 files in src"},
+		{"en_US", files, Params{"count": float64(1), "dir": "src"}, "1 file in src"},
+		{"en_US", files, nil, "{count} files in {dir}"},
+		{"en_US", nil, Params{"count": 1}, nil},
+	}
+
+	for _, c := range cases {
+		if got := format(c.locale, c.msg, c.params); c.want != got {
+			t.Errorf("formats [%v] with %v in [%s]: expected [%v], got [%v]", c.msg, c.params, c.locale, c.want, got)
+		}
+	}
+}
+
+func TestPluralForm(t *testing.T) {
+	cases := []struct {
+		locale string
+		count  int
+		want   string
+	}{
+		{"en_US", 1, "one"},
+		{"en_US", 2, "other"},
+		{"es_ES", 0, "other"},
+		{"pt_BR", 0, "one"},
+		{"pt_PT", 0, "other"},
+		{"ja_JP", 1, "other"},
+		{"ru_RU", 21, "one"},
+		{"ru_RU", 11, "many"},
+		{"ru_RU", 23, "few"},
+		{"ru_RU", 13, "many"},
+		{"pl_PL", 21, "many"},
+		{"pl_PL", 22, "few"},
+	}
+
+	for _, c := range cases {
+		if got := pluralForm(c.locale, c.count); c.want != got {
+			t.Errorf("plural form of [%d] in [%s]: expected [%s], got [%s]", c.count, c.locale, c.want, got)
+		}
+	}
+}
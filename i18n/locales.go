@@ -13,83 +13,288 @@
 // limitations under the License.
 
 // Package i18n includes internationalization related manipulations.
+//
+// Locale packs are the i18n/{locale}.json files, such as i18n/pt_BR.json. Packs dropped into (or changed in) the
+// directory at runtime are picked up without restarting, messages missing in a pack fall back to the default locale.
 package i18n
 
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/kwokhunglee/wide/gulu"
 )
 
 // Logger.
 var logger = gulu.Log.NewLogger(os.Stdout)
 
+// DefaultLocale is the locale which messages missing in other locales fall back to.
+const DefaultLocale = "en_US"
+
+// dir is the directory of locale packs.
+const dir = "i18n"
+
+// localeNameRegexp matches valid locale names, such as "es" and "pt_BR".
+var localeNameRegexp = regexp.MustCompile(`^[a-z]{2,3}(_[A-Z]{2})?$`)
+
 // Locale.
 type locale struct {
 	Name     string
-	Langs    map[string]interface{}
+	Langs    map[string]interface{} // messages of the pack merged with messages of the default locale
 	TimeZone string
+
+	own map[string]interface{} // messages of the pack itself
 }
 
 // All locales.
 var Locales = map[string]locale{}
 
-// Load loads i18n message configurations.
+// localesMutex guards Locales.
+var localesMutex sync.RWMutex
+
+// Load loads i18n message configurations, exits if any of them is broken.
 func Load() {
-	f, _ := os.Open("i18n")
-	names, _ := f.Readdirnames(-1)
-	f.Close()
+	for _, name := range packNames() {
+		if err := load(name); nil != err {
+			logger.Errorf("Loads locale [%s] failed: %s", name, err)
+
+			os.Exit(-1)
+		}
+	}
+}
+
+// Watch watches the locale packs directory, loads the packs which are added or changed and unloads the removed
+// packs. A broken pack is logged and the previous loaded messages of it are kept.
+func Watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if nil != err {
+		logger.Error(err)
 
-	if len(Locales) == len(names)-1 {
 		return
 	}
 
-	for _, name := range names {
-		if !strings.HasSuffix(name, ".json") {
-			continue
+	if err := watcher.Add(dir); nil != err {
+		logger.Warnf("Watches [%s] failed: %s", dir, err)
+
+		return
+	}
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		timers := map[string]*time.Timer{}
+		for {
+			select {
+			case e := <-watcher.Events:
+				name := packName(filepath.Base(e.Name))
+				if "" == name {
+					continue
+				}
+
+				// editors may write a file several times, loads once after they finished
+				if timer := timers[name]; nil != timer {
+					timer.Stop()
+				}
+				timers[name] = time.AfterFunc(time.Second, func() {
+					defer gulu.Panic.Recover(nil)
+
+					reload(name)
+				})
+			case err := <-watcher.Errors:
+				if nil != err {
+					logger.Error("Locales watcher ERROR: ", err)
+				}
+			}
 		}
+	}()
+}
+
+// reload loads the pack of the specified locale, unloads the locale if the pack has been removed.
+func reload(name string) {
+	if !gulu.File.IsExist(packPath(name)) {
+		if DefaultLocale == name {
+			logger.Warnf("Locale pack of the default locale [%s] has been removed, keeps the loaded one", name)
 
-		loc := name[:strings.LastIndex(name, ".")]
-		load(loc)
+			return
+		}
+
+		localesMutex.Lock()
+		delete(Locales, name)
+		localesMutex.Unlock()
+
+		logger.Infof("Unloaded locale [%s]", name)
+
+		return
 	}
+
+	if err := load(name); nil != err {
+		logger.Errorf("Loads locale [%s] failed: %s", name, err)
+
+		return
+	}
+
+	logger.Infof("Loaded locale [%s]", name)
 }
 
-func load(localeStr string) {
-	bytes, err := ioutil.ReadFile("i18n/" + localeStr + ".json")
+// load loads the pack of the specified locale.
+func load(name string) error {
+	bytes, err := ioutil.ReadFile(packPath(name))
 	if nil != err {
-		logger.Error(err)
+		return err
+	}
 
-		os.Exit(-1)
+	l := locale{Name: name}
+	if err = json.Unmarshal(bytes, &l.own); nil != err {
+		return err
 	}
 
-	l := locale{Name: localeStr}
+	localesMutex.Lock()
+	defer localesMutex.Unlock()
 
-	err = json.Unmarshal(bytes, &l.Langs)
+	Locales[name] = l
+	if DefaultLocale == name {
+		for n, loc := range Locales {
+			loc.Langs = merge(l.own, loc.own)
+			Locales[n] = loc
+		}
+	} else {
+		l.Langs = merge(Locales[DefaultLocale].own, l.own)
+		Locales[name] = l
+	}
+
+	return nil
+}
+
+// merge returns messages of the specified pack merged with the specified default messages.
+func merge(defaults, own map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(defaults))
+	for key, msg := range defaults {
+		ret[key] = msg
+	}
+	for key, msg := range own {
+		ret[key] = msg
+	}
+
+	return ret
+}
+
+// packNames gets names of the locales of all packs in the locale packs directory.
+func packNames() []string {
+	ret := []string{}
+
+	f, err := os.Open(dir)
 	if nil != err {
 		logger.Error(err)
 
-		os.Exit(-1)
+		return ret
+	}
+	names, _ := f.Readdirnames(-1)
+	f.Close()
+
+	for _, name := range names {
+		if name = packName(name); "" != name {
+			ret = append(ret, name)
+		}
+	}
+
+	// loads the default locale first, other locales are merged with it
+	sort.Slice(ret, func(i, j int) bool {
+		return DefaultLocale == ret[i] || (DefaultLocale != ret[j] && ret[i] < ret[j])
+	})
+
+	return ret
+}
+
+// packName gets the locale name of the specified pack file name, returns "" if it is not a locale pack.
+func packName(fileName string) string {
+	if !strings.HasSuffix(fileName, ".json") {
+		return ""
+	}
+
+	name := strings.TrimSuffix(fileName, ".json")
+	if !localeNameRegexp.MatchString(name) {
+		return ""
 	}
 
-	Locales[localeStr] = l
+	return name
 }
 
-// Get gets a message with the specified locale and key.
-func Get(locale, key string) interface{} {
-	return Locales[locale].Langs[key]
+// packPath gets the path of the pack of the specified locale.
+func packPath(name string) string {
+	return filepath.Join(dir, name+".json")
 }
 
-// GetAll gets all messages with the specified locale.
+// Exists checks whether the specified locale has been loaded.
+func Exists(locale string) bool {
+	localesMutex.RLock()
+	defer localesMutex.RUnlock()
+
+	_, ok := Locales[locale]
+
+	return ok
+}
+
+// Get gets a message with the specified locale and key, falls back to the default locale if the locale does not exist.
+//
+// The message is formatted with the specified parameters, see Params for more details.
+func Get(locale, key string, params ...Params) interface{} {
+	var p Params
+	if 0 < len(params) {
+		p = params[0]
+	}
+
+	return format(locale, GetAll(locale)[key], p)
+}
+
+// GetAll gets all messages with the specified locale, falls back to the default locale if the locale does not exist.
 func GetAll(locale string) map[string]interface{} {
-	return Locales[locale].Langs
+	localesMutex.RLock()
+	defer localesMutex.RUnlock()
+
+	if l, ok := Locales[locale]; ok {
+		return l.Langs
+	}
+
+	return Locales[DefaultLocale].Langs
+}
+
+// GetMissing gets keys of the messages of the default locale which are not translated in the specified locale, sorted
+// in ascending order. Returns nil if the locale does not exist.
+func GetMissing(locale string) []string {
+	localesMutex.RLock()
+	defer localesMutex.RUnlock()
+
+	l, ok := Locales[locale]
+	if !ok {
+		return nil
+	}
+
+	ret := []string{}
+	for key := range Locales[DefaultLocale].own {
+		if _, ok := l.own[key]; !ok {
+			ret = append(ret, key)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret
 }
 
 // GetLocalesNames gets names of all locales. Returns ["zh_CN", "en_US"] for example.
 func GetLocalesNames() []string {
+	localesMutex.RLock()
+	defer localesMutex.RUnlock()
+
 	ret := []string{}
 
 	for name := range Locales {
@@ -100,3 +305,31 @@ func GetLocalesNames() []string {
 
 	return ret
 }
+
+// MissingHandler handles request of getting keys of the messages not translated yet, responses missing keys of the
+// locale specified by the "locale" parameter, or counts of missing messages of all locales if not specified.
+func MissingHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	name := r.URL.Query().Get("locale")
+	if "" == name {
+		counts := map[string]int{}
+		for _, name := range GetLocalesNames() {
+			counts[name] = len(GetMissing(name))
+		}
+		result.Data = counts
+
+		return
+	}
+
+	missing := GetMissing(name)
+	if nil == missing {
+		result.Code = -1
+		result.Msg = "locale [" + name + "] not found"
+
+		return
+	}
+
+	result.Data = map[string]interface{}{"locale": name, "missing": missing}
+}
@@ -15,21 +15,24 @@
 package main
 
 import (
+	"bufio"
 	"compress/gzip"
+	"errors"
 	"flag"
 	"html/template"
 	"io"
 	"mime"
+	"net"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/kwokhunglee/wide/backup"
 	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/dbconsole"
 	"github.com/kwokhunglee/wide/editor"
 	"github.com/kwokhunglee/wide/event"
 	"github.com/kwokhunglee/wide/file"
@@ -38,7 +41,13 @@ import (
 	"github.com/kwokhunglee/wide/notification"
 	"github.com/kwokhunglee/wide/output"
 	"github.com/kwokhunglee/wide/playground"
+	"github.com/kwokhunglee/wide/plugin"
+	"github.com/kwokhunglee/wide/restclient"
 	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/storage"
+	"github.com/kwokhunglee/wide/telemetry"
+	"github.com/kwokhunglee/wide/util"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // Logger
@@ -46,12 +55,13 @@ var logger *gulu.Logger
 
 // The only one init function in Wide.
 func init() {
-	confPath := flag.String("conf", "conf/wide.json", "path of wide.json")
+	confPath := flag.String("conf", "conf/wide.json", "path of wide.json, YAML (.yaml/.yml) and TOML (.toml) are supported as well")
 	confData := flag.String("data", "", "path of data dir")
 	confServer := flag.String("server", "", "this will overwrite Wide.Server if specified")
 	confLogLevel := flag.String("log_level", "", "this will overwrite Wide.LogLevel if specified")
 	confSiteStatCode := flag.String("site_stat_code", "", "this will overrite Wide.SiteStatCode if specified")
 
+	conf.RegisterFlags()
 	flag.Parse()
 
 	gulu.Log.SetLevel("warn")
@@ -65,9 +75,26 @@ func init() {
 	//}
 
 	i18n.Load()
+	i18n.Watch()
+	notification.Load()
 	event.Load()
+	conf.SetAssets(assets)
 	conf.Load(*confPath, *confData, *confServer, *confLogLevel, template.HTML(*confSiteStatCode))
 
+	conf.WatchConf()
+	backup.Load()
+	storage.Load()
+	telemetry.Load()
+	plugin.Load()
+	file.LoadDocuments()
+	file.LoadFollow()
+	file.LoadChat()
+	file.LoadSaveMerge()
+	file.LoadPathIndexes()
+	file.LoadTextIndexes()
+	output.Load()
+	session.LoadInvitations()
+	session.LoadCluster()
 	conf.FixedTimeCheckEnv()
 	session.FixedTimeSave()
 	session.FixedTimeRelease()
@@ -87,30 +114,52 @@ func main() {
 	http.HandleFunc("/about", handlerWrapper(aboutHandler))
 	http.HandleFunc("/keyboard_shortcuts", handlerWrapper(keyboardShortcutsHandler))
 
+	// health
+	http.HandleFunc("/healthz", panicRecover(healthzHandler))
+	http.HandleFunc("/readyz", panicRecover(readyzHandler))
+
 	// static resources
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
-	http.Handle("/static/users/", http.StripPrefix("/static/", http.FileServer(http.Dir(conf.Wide.Data+"/static"))))
-	serveSingle("/favicon.ico", "./static/images/favicon.png")
+	http.Handle("/static/", http.StripPrefix("/static/", http.HandlerFunc(staticHandler)))
+	http.Handle("/static/users/", http.StripPrefix("/static/", http.FileServer(http.Dir(conf.Wide().Data+"/static"))))
+	serveSingle("/favicon.ico", "static/images/favicon.png")
 
 	// oauth
-	http.HandleFunc("/oauth/github/redirect", session.RedirectGitHubHandler)
-	http.HandleFunc("/oauth/github/callback", session.GithubCallbackHandler)
+	http.HandleFunc("/oauth/github/redirect", handlerWrapper(session.RedirectGitHubHandler))
+	http.HandleFunc("/oauth/github/callback", handlerWrapper(session.GithubCallbackHandler))
 
 	// session
 	http.HandleFunc("/session/ws", handlerWrapper(session.WSHandler))
 	http.HandleFunc("/session/save", handlerWrapper(session.SaveContentHandler))
 
+	// REST API
+	registerAPI()
+
+	// incoming webhooks, verified with their secrets instead of sessions
+	http.HandleFunc("/hook/", handlerWrapper(output.TriggerHandler))
+
 	// run
-	http.HandleFunc("/build", handlerWrapper(output.BuildHandler))
-	http.HandleFunc("/run", handlerWrapper(output.RunHandler))
+	http.HandleFunc("/build", handlerWrapper(rateLimitWrapper("build", output.BuildHandler)))
+	http.HandleFunc("/run", handlerWrapper(rateLimitWrapper("build", output.RunHandler)))
 	http.HandleFunc("/stop", handlerWrapper(output.StopHandler))
-	http.HandleFunc("/go/test", handlerWrapper(output.GoTestHandler))
-	http.HandleFunc("/go/vet", handlerWrapper(output.GoVetHandler))
-	http.HandleFunc("/go/install", handlerWrapper(output.GoInstallHandler))
+	http.HandleFunc("/go/test", handlerWrapper(rateLimitWrapper("build", output.GoTestHandler)))
+	http.HandleFunc("/go/vet", handlerWrapper(rateLimitWrapper("build", output.GoVetHandler)))
+	http.HandleFunc("/go/install", handlerWrapper(rateLimitWrapper("build", output.GoInstallHandler)))
+	http.HandleFunc("/protoc", handlerWrapper(rateLimitWrapper("build", output.ProtocHandler)))
+	http.HandleFunc("/docker/build",
+		handlerWrapper(featureWrapper("docker", rateLimitWrapper("build", output.DockerBuildHandler))))
+	http.HandleFunc("/docker/run",
+		handlerWrapper(featureWrapper("docker", rateLimitWrapper("build", output.DockerRunHandler))))
+	http.HandleFunc("/docker/stop", handlerWrapper(featureWrapper("docker", output.DockerStopHandler)))
+	http.HandleFunc("/docker/list", handlerWrapper(featureWrapper("docker", output.DockerListHandler)))
+	http.HandleFunc("/deploy", handlerWrapper(featureWrapper("deploy", rateLimitWrapper("build", output.DeployHandler))))
+	http.HandleFunc("/go/migrate",
+		handlerWrapper(featureWrapper("migrate", rateLimitWrapper("build", output.MigrateHandler))))
 	http.HandleFunc("/output/ws", handlerWrapper(output.WSHandler))
+	http.HandleFunc("/output/share", handlerWrapper(output.ShareHandler))
+	http.HandleFunc("/output/unshare", handlerWrapper(output.UnshareHandler))
 
 	// cross-compilation
-	http.HandleFunc("/cross", handlerWrapper(output.CrossCompilationHandler))
+	http.HandleFunc("/cross", handlerWrapper(rateLimitWrapper("build", output.CrossCompilationHandler)))
 
 	// file tree
 	http.HandleFunc("/files", handlerWrapper(file.GetFilesHandler))
@@ -120,15 +169,79 @@ func main() {
 	http.HandleFunc("/file/new", handlerWrapper(file.NewFileHandler))
 	http.HandleFunc("/file/remove", handlerWrapper(file.RemoveFileHandler))
 	http.HandleFunc("/file/rename", handlerWrapper(file.RenameFileHandler))
-	http.HandleFunc("/file/search/text", handlerWrapper(file.SearchTextHandler))
-	http.HandleFunc("/file/find/name", handlerWrapper(file.FindHandler))
+	http.HandleFunc("/scratch/new", handlerWrapper(file.NewScratchHandler))
+	http.HandleFunc("/file/license", handlerWrapper(file.LicenseHeadersHandler))
+	http.HandleFunc("/file/search/text", handlerWrapper(rateLimitWrapper("search", file.SearchTextHandler)))
+	http.HandleFunc("/file/find/name", handlerWrapper(rateLimitWrapper("search", file.FindHandler)))
+	http.HandleFunc("/file/find/symbol", handlerWrapper(rateLimitWrapper("search", file.FindSymbolHandler)))
+
+	// git
+	http.HandleFunc("/git/clone", handlerWrapper(rateLimitWrapper("clone", file.GitCloneHandler)))
+	http.HandleFunc("/git/init", handlerWrapper(file.GitInitHandler))
+	http.HandleFunc("/git/changes", handlerWrapper(file.GitChangesHandler))
+	http.HandleFunc("/git/stage", handlerWrapper(file.GitStageHandler))
+	http.HandleFunc("/git/unstage", handlerWrapper(file.GitUnstageHandler))
+	http.HandleFunc("/git/commit", handlerWrapper(file.GitCommitHandler))
+	http.HandleFunc("/git/discard", handlerWrapper(file.GitDiscardHandler))
+	http.HandleFunc("/git/ignore", handlerWrapper(file.GitIgnoreHandler))
+	http.HandleFunc("/git/hooks", handlerWrapper(file.GitHooksHandler))
+	http.HandleFunc("/git/hooks/trust", handlerWrapper(file.GitTrustHooksHandler))
+	http.HandleFunc("/git/credential", handlerWrapper(file.GitCredentialHandler))
+	http.HandleFunc("/git/credential/test", handlerWrapper(file.GitTestCredentialHandler))
+	http.HandleFunc("/git/credential/revoke", handlerWrapper(file.GitRevokeCredentialHandler))
+	http.HandleFunc("/git/forge/token", handlerWrapper(file.GitForgeTokenHandler))
+	http.HandleFunc("/git/pullrequest", handlerWrapper(file.GitPullRequestHandler))
+	http.HandleFunc("/git/push", handlerWrapper(file.GitPushHandler))
+	http.HandleFunc("/git/pull", handlerWrapper(file.GitPullHandler))
+	http.HandleFunc("/git/fetch", handlerWrapper(file.GitFetchHandler))
+	http.HandleFunc("/git/branches", handlerWrapper(file.GitBranchesHandler))
+	http.HandleFunc("/git/branch/new", handlerWrapper(file.GitCreateBranchHandler))
+	http.HandleFunc("/git/branch/switch", handlerWrapper(file.GitSwitchBranchHandler))
+	http.HandleFunc("/git/branch/remove", handlerWrapper(file.GitDeleteBranchHandler))
+	http.HandleFunc("/git/branch/upstream", handlerWrapper(file.GitSetUpstreamHandler))
+	http.HandleFunc("/git/diff", handlerWrapper(file.GitDiffHandler))
+	http.HandleFunc("/git/log", handlerWrapper(file.GitLogHandler))
+	http.HandleFunc("/git/blame", handlerWrapper(file.GitBlameHandler))
+	http.HandleFunc("/git/merge", handlerWrapper(file.GitMergeHandler))
+	http.HandleFunc("/git/cherrypick", handlerWrapper(file.GitCherryPickHandler))
+	http.HandleFunc("/git/rebase", handlerWrapper(file.GitRebaseHandler))
+	http.HandleFunc("/git/continue", handlerWrapper(file.GitContinueHandler))
+	http.HandleFunc("/git/abort", handlerWrapper(file.GitAbortHandler))
+	http.HandleFunc("/git/conflicts", handlerWrapper(file.GitConflictsHandler))
+	http.HandleFunc("/git/conflict/versions", handlerWrapper(file.GitConflictVersionsHandler))
+	http.HandleFunc("/git/conflict/resolve", handlerWrapper(file.GitResolveHandler))
+	http.HandleFunc("/git/stash/save", handlerWrapper(file.GitStashSaveHandler))
+	http.HandleFunc("/git/stash/list", handlerWrapper(file.GitStashListHandler))
+	http.HandleFunc("/git/stash/apply", handlerWrapper(file.GitStashApplyHandler))
+	http.HandleFunc("/git/stash/pop", handlerWrapper(file.GitStashPopHandler))
+	http.HandleFunc("/git/stash/drop", handlerWrapper(file.GitStashDropHandler))
+	http.HandleFunc("/git/stash/show", handlerWrapper(file.GitStashShowHandler))
+	http.HandleFunc("/git/submodules", handlerWrapper(file.GitSubmodulesHandler))
+	http.HandleFunc("/git/submodule/update", handlerWrapper(file.GitSubmoduleUpdateHandler))
+	http.HandleFunc("/git/tags", handlerWrapper(file.GitTagsHandler))
+	http.HandleFunc("/git/tag/new", handlerWrapper(file.GitCreateTagHandler))
+	http.HandleFunc("/git/tag/remove", handlerWrapper(file.GitDeleteTagHandler))
+	http.HandleFunc("/git/tag/push", handlerWrapper(file.GitPushTagHandler))
+
+	// code review
+	http.HandleFunc("/review/comments", handlerWrapper(file.GetReviewCommentsHandler))
+	http.HandleFunc("/review/comment/new", handlerWrapper(file.AddReviewCommentHandler))
+	http.HandleFunc("/review/comment/resolve", handlerWrapper(file.ResolveReviewCommentHandler))
+	http.HandleFunc("/review/comment/reply", handlerWrapper(file.ReplyReviewCommentHandler))
+
+	// pair programming invitations
+	http.HandleFunc("/invitation/new", handlerWrapper(session.NewInvitationHandler))
+	http.HandleFunc("/invitation", handlerWrapper(session.AcceptInvitationHandler))
 
 	// outline
 	http.HandleFunc("/outline", handlerWrapper(file.GetOutlineHandler))
 
 	// file export
+	http.HandleFunc("/file/download", handlerWrapper(file.DownloadHandler))
 	http.HandleFunc("/file/zip/new", handlerWrapper(file.CreateZipHandler))
 	http.HandleFunc("/file/zip", handlerWrapper(file.GetZipHandler))
+	http.HandleFunc("/workspace/export", handlerWrapper(file.ExportWorkspaceHandler))
+	http.HandleFunc("/workspace/import", handlerWrapper(file.ImportWorkspaceHandler))
 
 	// editor
 	http.HandleFunc("/editor/ws", handlerWrapper(editor.WSHandler))
@@ -136,78 +249,201 @@ func main() {
 	http.HandleFunc("/autocomplete", handlerWrapper(editor.AutocompleteHandler))
 	http.HandleFunc("/exprinfo", handlerWrapper(editor.GetExprInfoHandler))
 	http.HandleFunc("/find/decl", handlerWrapper(editor.FindDeclarationHandler))
-	http.HandleFunc("/find/usages", handlerWrapper(editor.FindUsagesHandler))
+	http.HandleFunc("/find/usages", handlerWrapper(rateLimitWrapper("search", editor.FindUsagesHandler)))
+	http.HandleFunc("/regexp", handlerWrapper(editor.RegexpHandler))
 
 	// notification
 	http.HandleFunc("/notification/ws", handlerWrapper(notification.WSHandler))
+	http.HandleFunc("/notification/push/key", handlerWrapper(notification.PushKeyHandler))
+	http.HandleFunc("/notification/push/subscribe", handlerWrapper(notification.PushSubscribeHandler))
+	http.HandleFunc("/notification/push/unsubscribe", handlerWrapper(notification.PushUnsubscribeHandler))
 
 	// user
 	http.HandleFunc("/login", handlerWrapper(session.LoginHandler))
 	http.HandleFunc("/logout", handlerWrapper(session.LogoutHandler))
 	http.HandleFunc("/preference", handlerWrapper(session.PreferenceHandler))
+	http.HandleFunc("/conf/reload", handlerWrapper(session.ReloadConfHandler))
+	http.HandleFunc("/admin/settings", handlerWrapper(session.SettingsHandler))
+	http.HandleFunc("/admin/audits", handlerWrapper(session.AuditsHandler))
+	http.HandleFunc("/admin/broadcast", handlerWrapper(notification.BroadcastHandler))
+	http.HandleFunc("/admin/telemetry", handlerWrapper(telemetry.PreviewHandler))
+	http.HandleFunc("/admin/search/reindex", handlerWrapper(file.ReindexHandler))
+	http.HandleFunc("/logs", handlerWrapper(session.LogsHandler))
+
+	// backup
+	http.HandleFunc("/backups", handlerWrapper(backup.ListHandler))
+	http.HandleFunc("/backup/new", handlerWrapper(backup.CreateHandler))
+	http.HandleFunc("/backup/restore", handlerWrapper(backup.RestoreHandler))
+
+	// plugins
+	http.HandleFunc("/plugin/actions", handlerWrapper(plugin.ActionsHandler))
+	http.HandleFunc("/plugin/action", handlerWrapper(plugin.ActionHandler))
+	http.HandleFunc("/plugins/", handlerWrapper(plugin.ServeHandler))
+
+	// database console
+	http.HandleFunc("/db/connections", handlerWrapper(featureWrapper("dbconsole", dbconsole.ConnectionsHandler)))
+	http.HandleFunc("/db/connection/new", handlerWrapper(featureWrapper("dbconsole", dbconsole.NewConnectionHandler)))
+	http.HandleFunc("/db/connection/remove",
+		handlerWrapper(featureWrapper("dbconsole", dbconsole.RemoveConnectionHandler)))
+	http.HandleFunc("/db/query",
+		handlerWrapper(featureWrapper("dbconsole", rateLimitWrapper("query", dbconsole.QueryHandler))))
+	http.HandleFunc("/db/schema",
+		handlerWrapper(featureWrapper("dbconsole", rateLimitWrapper("query", dbconsole.SchemaHandler))))
+
+	// HTTP request tester
+	http.HandleFunc("/http/send",
+		handlerWrapper(featureWrapper("restclient", rateLimitWrapper("request", restclient.SendHandler))))
+
+	// i18n
+	http.HandleFunc("/i18n/missing", handlerWrapper(i18n.MissingHandler))
 
 	// playground
-	http.HandleFunc("/playground", handlerWrapper(playground.IndexHandler))
-	http.HandleFunc("/playground/", handlerWrapper(playground.IndexHandler))
-	http.HandleFunc("/playground/ws", handlerWrapper(playground.WSHandler))
-	http.HandleFunc("/playground/save", handlerWrapper(playground.SaveHandler))
-	http.HandleFunc("/playground/build", handlerWrapper(playground.BuildHandler))
-	http.HandleFunc("/playground/run", handlerWrapper(playground.RunHandler))
-	http.HandleFunc("/playground/stop", handlerWrapper(playground.StopHandler))
-	http.HandleFunc("/playground/autocomplete", handlerWrapper(playground.AutocompleteHandler))
-
-	logger.Infof("Wide is running [%s]", conf.Wide.Server)
-
-	err := http.ListenAndServe(":7070", nil)
-	if err != nil {
+	http.HandleFunc("/playground", handlerWrapper(playgroundWrapper(playground.IndexHandler)))
+	http.HandleFunc("/playground/", handlerWrapper(playgroundWrapper(playground.IndexHandler)))
+	http.HandleFunc("/playground/ws", handlerWrapper(playgroundWrapper(playground.WSHandler)))
+	http.HandleFunc("/playground/save", handlerWrapper(playgroundWrapper(playground.SaveHandler)))
+	http.HandleFunc("/playground/build",
+		handlerWrapper(rateLimitWrapper("playground", playgroundWrapper(playground.BuildHandler))))
+	http.HandleFunc("/playground/run",
+		handlerWrapper(rateLimitWrapper("playground", playgroundWrapper(playground.RunHandler))))
+	http.HandleFunc("/playground/stop", handlerWrapper(playgroundWrapper(playground.StopHandler)))
+	http.HandleFunc("/playground/autocomplete", handlerWrapper(playgroundWrapper(playground.AutocompleteHandler)))
+
+	logger.Infof("Wide is running [%s]", conf.Wide().Server)
+
+	serveGRPC()
+
+	if conf.Wide().TLSEnabled() {
+		serveTLS()
+
+		return
+	}
+
+	server := &http.Server{Addr: ":7070", Handler: rootHandler()}
+	addServer(server)
+	if err := server.ListenAndServe(); http.ErrServerClosed != err {
 		logger.Error(err)
+
+		return
+	}
+
+	select {} // shutting down, exits after the shutdown finished
+}
+
+// rootHandler returns the handler serving all routes under the URL base path Wide.Context.
+func rootHandler() http.Handler {
+	if "" == conf.Wide().Context {
+		return debugWrapper(http.DefaultServeMux)
 	}
+
+	ret := http.NewServeMux()
+	ret.Handle(conf.Wide().Context+"/", http.StripPrefix(conf.Wide().Context, debugWrapper(http.DefaultServeMux)))
+	ret.Handle(conf.Wide().Context, http.RedirectHandler(conf.Wide().Context+"/", http.StatusFound))
+
+	return ret
+}
+
+// serveTLS serves Wide over HTTPS on Wide.HTTPSAddr with the certificate of Wide.TLSCert, or the one obtained from
+// Let's Encrypt for Wide.Autocert. Requests of HTTP are redirected to HTTPS.
+func serveTLS() {
+	session.HTTPSession.Options.Secure = true
+
+	server := &http.Server{Addr: conf.Wide().HTTPSAddr, Handler: rootHandler()}
+	var httpHandler http.Handler = http.HandlerFunc(redirectHTTPSHandler)
+	if "" != conf.Wide().Autocert {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(conf.Wide().Autocert),
+			Cache:      autocert.DirCache(filepath.Join(conf.Wide().Data, "autocert")),
+		}
+		server.TLSConfig = manager.TLSConfig()
+		httpHandler = manager.HTTPHandler(httpHandler) // serves ACME HTTP challenges as well
+	}
+
+	httpServer := &http.Server{Addr: ":7070", Handler: httpHandler}
+	addServer(httpServer)
+	addServer(server)
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		if err := httpServer.ListenAndServe(); http.ErrServerClosed != err {
+			logger.Error(err)
+		}
+	}()
+
+	logger.Infof("Wide is serving HTTPS on [%s]", conf.Wide().HTTPSAddr)
+	if err := server.ListenAndServeTLS(conf.Wide().TLSCert, conf.Wide().TLSKey); http.ErrServerClosed != err {
+		logger.Error(err)
+
+		return
+	}
+
+	select {} // shutting down, exits after the shutdown finished
+}
+
+// redirectHTTPSHandler redirects HTTP requests to HTTPS.
+func redirectHTTPSHandler(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); nil == err {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(conf.Wide().HTTPSAddr); nil == err && "" != port && "443" != port {
+		host = net.JoinHostPort(host, port)
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
 }
 
 // indexHandler handles request of Wide index.
 func indexHandler(w http.ResponseWriter, r *http.Request) {
-	if "/" != r.RequestURI {
-		http.Redirect(w, r, "/", http.StatusFound)
+	if "/" != r.URL.RequestURI() {
+		http.Redirect(w, r, conf.Wide().Context+"/", http.StatusFound)
 
 		return
 	}
 
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
 	uid := httpSession.Values["uid"].(string)
 	if "playground" == uid { // reserved user for Playground
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	user := conf.GetUser(uid)
 	if nil == user {
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
+	// the workspace may not be cached on this instance yet
+	if err := storage.Sync(uid); nil != err {
+		logger.Errorf("Synchronizes workspace of user [%s] failed: %s", uid, err)
+	}
+
 	locale := user.Locale
 
 	wideSessions := session.WideSessions.GetByUserId(uid)
 
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(locale), "locale": locale,
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(locale), "locale": locale,
 		"uid": uid, "sid": session.WideSessions.GenId(), "latestSessionContent": user.LatestSessionContent,
 		"pathSeparator": conf.PathSeparator, "codeMirrorVer": conf.CodeMirrorVer,
-		"user": user, "editorThemes": conf.GetEditorThemes(), "crossPlatforms": []string{"darwin_amd64", "linux_amd64", "windows_amd64"}}
+		"user": user, "editorThemes": conf.GetEditorThemes(), "crossPlatforms": []string{"darwin_amd64", "linux_amd64", "windows_amd64"},
+		"features": conf.Wide().UserFeatures(uid)}
 
 	logger.Debugf("User [%s] has [%d] sessions", uid, len(wideSessions))
 
-	t, err := template.ParseFiles("views/index.html")
+	t, err := template.ParseFS(conf.Assets(), "views/index.html")
 	if nil != err {
 		logger.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -218,39 +454,16 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	t.Execute(w, model)
 }
 
-// handleSignal handles system signal for graceful shutdown.
-func handleSignal() {
-	go func() {
-		c := make(chan os.Signal)
-
-		signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
-		s := <-c
-		logger.Tracef("Got signal [%s]", s)
-
-		session.SaveOnlineUsers()
-		logger.Tracef("Saved all online user, exit")
-
-		os.Exit(0)
-	}()
-}
-
-// serveSingle registers the handler function for the given pattern and filename.
-func serveSingle(pattern string, filename string) {
-	http.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, filename)
-	})
-}
-
 // startHandler handles request of start page.
 func startHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
-		http.Redirect(w, r, "/s", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/s", http.StatusFound)
 
 		return
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	uid := httpSession.Values["uid"].(string)
@@ -263,10 +476,10 @@ func startHandler(w http.ResponseWriter, r *http.Request) {
 		logger.Errorf("Session [%s] not found", sid)
 	}
 
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(locale), "locale": locale,
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(locale), "locale": locale,
 		"uid": uid, "workspace": userWorkspace, "ver": conf.WideVersion, "sid": sid}
 
-	t, err := template.ParseFiles("views/start.html")
+	t, err := template.ParseFS(conf.Assets(), "views/start.html")
 
 	if nil != err {
 		logger.Error(err)
@@ -282,20 +495,20 @@ func startHandler(w http.ResponseWriter, r *http.Request) {
 func keyboardShortcutsHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	uid := httpSession.Values["uid"].(string)
 	locale := conf.GetUser(uid).Locale
 
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(locale), "locale": locale}
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(locale), "locale": locale}
 
-	t, err := template.ParseFiles("views/keyboard_shortcuts.html")
+	t, err := template.ParseFS(conf.Assets(), "views/keyboard_shortcuts.html")
 
 	if nil != err {
 		logger.Error(err)
@@ -311,21 +524,21 @@ func keyboardShortcutsHandler(w http.ResponseWriter, r *http.Request) {
 func aboutHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
 	if httpSession.IsNew {
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	uid := httpSession.Values["uid"].(string)
 	locale := conf.GetUser(uid).Locale
 
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(locale), "locale": locale,
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(locale), "locale": locale,
 		"ver": conf.WideVersion, "goos": runtime.GOOS, "goarch": runtime.GOARCH, "gover": runtime.Version()}
 
-	t, err := template.ParseFiles("views/about.html")
+	t, err := template.ParseFS(conf.Assets(), "views/about.html")
 
 	if nil != err {
 		logger.Error(err)
@@ -340,12 +553,12 @@ func aboutHandler(w http.ResponseWriter, r *http.Request) {
 // handlerWrapper wraps the HTTP Handler for some common processes.
 //
 //  1. panic recover
-//  2. request stopwatch
-//  3. i18n
+//  2. CORS
+//  3. request logging
 func handlerWrapper(f func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	handler := panicRecover(f)
-	handler = stopwatch(handler)
-	handler = i18nLoad(handler)
+	handler = corsWrapper(handler)
+	handler = requestLog(handler)
 
 	return handler
 }
@@ -354,13 +567,11 @@ func handlerWrapper(f func(w http.ResponseWriter, r *http.Request)) func(w http.
 //
 //  1. panic recover
 //  2. gzip response
-//  3. request stopwatch
-//  4. i18n
+//  3. request logging
 func handlerGzWrapper(f func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	handler := panicRecover(f)
 	handler = gzipWrapper(handler)
-	handler = stopwatch(handler)
-	handler = i18nLoad(handler)
+	handler = requestLog(handler)
 
 	return handler
 }
@@ -383,25 +594,64 @@ func gzipWrapper(f func(http.ResponseWriter, *http.Request)) func(w http.Respons
 	}
 }
 
-// i18nLoad wraps the i18n process.
-func i18nLoad(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+// playgroundWrapper wraps the process with responding 404 if the playground is disabled (Wide.PlaygroundDisabled).
+func playgroundWrapper(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		i18n.Load()
+		if conf.Wide().PlaygroundDisabled {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// featureWrapper wraps the process with responding 404 if the feature specified by the given name (Wide.Features) is
+// not enabled for the user.
+func featureWrapper(name string, handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+		uid, _ := httpSession.Values["uid"].(string)
+		if !conf.Wide().FeatureEnabled(name, uid) {
+			http.NotFound(w, r)
+
+			return
+		}
 
 		handler(w, r)
 	}
 }
 
-// stopwatch wraps the request stopwatch process.
-func stopwatch(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+// requestLog wraps the process with assigning a correlation id (header X-Request-Id, see util.AssignRequestId) and
+// logging the method, path, user, status and duration of the request. Handlers could get the id with util.RequestId
+// to trace jobs they start.
+func requestLog(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		requestId := util.AssignRequestId(r)
+		w.Header().Set(util.RequestIdHeader, requestId)
+		sw := &statusResponseWriter{ResponseWriter: w}
 
 		defer func() {
-			logger.Tracef("[%s, %s, %s]", r.Method, r.RequestURI, time.Since(start))
+			uid := ""
+			if httpSession, _ := session.HTTPSession.Get(r, session.CookieName); !httpSession.IsNew {
+				uid, _ = httpSession.Values["uid"].(string)
+			}
+
+			status := sw.status
+			if 0 == status {
+				status = http.StatusOK
+			}
+			if http.StatusInternalServerError <= status {
+				telemetry.CountError()
+			}
+
+			logger.Infof("[%s] [%s %s] [uid=%s] [%d] [%s]", requestId, r.Method, r.URL.Path, uid, status,
+				time.Since(start))
 		}()
 
-		handler(w, r)
+		handler(sw, r)
 	}
 }
 
@@ -423,6 +673,49 @@ func initMime() {
 	mime.AddExtensionType(".json", "application/json")
 }
 
+// statusResponseWriter represents a response writer recording the status code.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and then writes it.
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if 0 == w.status {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the default status code 200 if no status code has been written.
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if 0 == w.status {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack hijacks the underlying connection for WebSocket handlers.
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// Flush flushes buffered data to the client if the underlying response writer supports it.
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // gzipResponseWriter represents a gzip response writer.
 type gzipResponseWriter struct {
 	io.Writer
@@ -0,0 +1,89 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// maxBroadcastLen is the max number of characters of a broadcast message.
+const maxBroadcastLen = 1000
+
+// BroadcastHandler handles request of broadcasting a message (such as "maintenance in 10 minutes") to all connected
+// sessions via their notification channels, administrators only.
+//
+// Arguments: "message" and optional "severity" ("ERROR", "WARN" or "INFO" by default).
+func BroadcastHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args struct {
+		Message  string
+		Severity string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	message := strings.TrimSpace(args.Message)
+	if "" == message || maxBroadcastLen < utf8.RuneCountInString(message) {
+		result.Code = -1
+		result.Msg = "invalid message"
+
+		return
+	}
+
+	severity := strings.ToUpper(strings.TrimSpace(args.Severity))
+	switch severity {
+	case error, warn, info:
+	case "":
+		severity = info
+	default:
+		result.Code = -1
+		result.Msg = "invalid severity"
+
+		return
+	}
+
+	// messages are rendered as HTML by browsers
+	event.Publish(&event.Event{Code: event.EvtCodeBroadcast,
+		Data: &event.Broadcast{UserId: uid, Severity: severity, Message: html.EscapeString(message)}})
+	conf.AddAudit(uid, "notification.broadcast", severity+" "+message)
+}
@@ -16,6 +16,8 @@
 package notification
 
 import (
+	"fmt"
+	"html"
 	"net/http"
 	"os"
 	"strconv"
@@ -27,7 +29,6 @@ import (
 	"github.com/kwokhunglee/wide/i18n"
 	"github.com/kwokhunglee/wide/session"
 	"github.com/kwokhunglee/wide/util"
-	"github.com/gorilla/websocket"
 )
 
 const (
@@ -35,19 +36,31 @@ const (
 	warn  = "WARN"  // notification.severity: WARN
 	info  = "INFO"  // notification.severity: INFO
 
-	setup  = "Setup"  // notification.type: setup
-	server = "Server" // notification.type: server
+	setup     = "Setup"     // notification.type: setup
+	server    = "Server"    // notification.type: server
+	broadcast = "Broadcast" // notification.type: broadcast
+	conflict  = "Conflict"  // notification.type: conflict
 )
 
 // Logger.
 var logger = gulu.Log.NewLogger(os.Stdout)
 
+// Load subscribes the notification integrations (webhooks and Web Push) to the event bus, and pushes notifications of
+// wide sessions held by other instances in the multi-instance mode.
+func Load() {
+	event.Subscribe(event.HandleFunc(fireWebhooks), event.EvtCodeJobDone)
+	event.Subscribe(event.HandleFunc(pushJob), event.EvtCodeJobDone)
+
+	session.RemoteEventHandlers = append(session.RemoteEventHandlers, event.HandleFunc(event2Notification))
+}
+
 // Notification represents a notification.
 type Notification struct {
 	event    *event.Event
 	Type     string `json:"type"`
 	Severity string `json:"severity"`
 	Message  string `json:"message"`
+	Time     string `json:"time"` // notifying time formatted in the user's time zone
 }
 
 // event2Notification processes user event by converting the specified event to a notification, and then push it to front
@@ -62,9 +75,12 @@ func event2Notification(e *event.Event) {
 		return
 	}
 
-	httpSession, _ := session.HTTPSession.Get(wsChannel.Request, session.CookieName)
-	uid := httpSession.Values["uid"].(string)
-	locale := conf.GetUser(uid).Locale
+	wSession := session.WideSessions.Get(e.Sid)
+	if nil == wSession {
+		return
+	}
+	user := conf.GetUser(wSession.UserId)
+	locale := user.Locale
 
 	var notification *Notification
 
@@ -77,12 +93,32 @@ func event2Notification(e *event.Event) {
 	case event.EvtCodeServerInternalError:
 		notification = &Notification{event: e, Type: server, Severity: error,
 			Message: i18n.Get(locale, "notification_"+strconv.Itoa(e.Code)).(string) + " [" + e.Data.(string) + "]"}
+	case event.EvtCodeConfReloaded:
+		notification = &Notification{event: e, Type: server, Severity: info,
+			Message: i18n.Get(locale, "notification_"+strconv.Itoa(e.Code)).(string)}
+	case event.EvtCodeJobDone: // the output has been pushed with output channel
+		return
+	case event.EvtCodeFileChanged, event.EvtCodeJobStarted: // for subscribers such as plugins
+		return
+	case event.EvtCodeBroadcast:
+		b := e.Data.(*event.Broadcast)
+		notification = &Notification{event: e, Type: broadcast, Severity: b.Severity, Message: b.Message}
+	case event.EvtCodeSaveConflict:
+		c := e.Data.(*event.SaveConflict)
+		userName := c.UserId
+		if u := conf.GetUser(c.UserId); nil != u {
+			userName = u.Name
+		}
+		msg := i18n.Get(locale, "notification_"+strconv.Itoa(e.Code)).(string)
+		notification = &Notification{event: e, Type: conflict, Severity: warn,
+			Message: fmt.Sprintf(msg, html.EscapeString(userName), html.EscapeString(c.Path))}
 	default:
 		logger.Warnf("Can't handle event[code=%d]", e.Code)
 
 		return
 	}
 
+	notification.Time = user.FormatTime(time.Now())
 	wsChannel.WriteJSON(notification)
 
 	wsChannel.Refresh()
@@ -98,7 +134,7 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, _ := websocket.Upgrade(w, r, nil, 1024, 1024)
+	conn, _ := util.UpgradeCompressed(w, r)
 	wsChan := util.WSChannel{Sid: sid, Conn: conn, Request: r, Time: time.Now()}
 
 	ret := map[string]interface{}{"notification": "Notification initialized", "cmd": "init-notification"}
@@ -108,12 +144,19 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	session.NotificationWS[sid] = &wsChan
+	session.AnnounceChannel(session.ChannelNotification, sid)
 
 	logger.Tracef("Open a new [Notification] with session [%s], %d", sid, len(session.NotificationWS))
 
 	// add user event handler
 	wSession.EventQueue.AddHandler(event.HandleFunc(event2Notification))
 
+	stopHeartbeat := wsChan.Heartbeat()
+	defer func() {
+		stopHeartbeat()
+		session.ReleaseChannel(session.ChannelNotification, sid, &wsChan)
+	}()
+
 	input := map[string]interface{}{}
 
 	for {
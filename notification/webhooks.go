@@ -0,0 +1,128 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+)
+
+// max lines of the job output tail sent to webhooks.
+const webhookOutputLines = 20
+
+// HTTP client of webhooks.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// fireWebhooks posts the summary of the finished job of the specified event to the webhooks of the instance and the
+// user ran the job.
+//
+// Succeeded jobs shorter than Wide.WebhookMinDuration are not notified.
+func fireWebhooks(e *event.Event) {
+	job := e.Data.(*event.Job)
+	if job.Succ && job.Duration < time.Duration(conf.Wide().WebhookMinDuration)*time.Second {
+		return
+	}
+
+	webhooks := conf.Wide().Webhooks
+	locale := conf.Wide().Locale
+	if user := conf.GetUser(job.UserId); nil != user {
+		webhooks = append(append([]*conf.Webhook{}, webhooks...), user.Webhooks...)
+		locale = user.Locale
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Accepts(job.Name, job.Succ) {
+			continue
+		}
+
+		go func(webhook *conf.Webhook) {
+			defer gulu.Panic.Recover(nil)
+
+			postWebhook(webhook, job, locale)
+		}(webhook)
+	}
+}
+
+// postWebhook posts the summary of the specified job to the specified webhook.
+func postWebhook(webhook *conf.Webhook, job *event.Job, locale string) {
+	key := "webhook-job-succ"
+	if !job.Succ {
+		key = "webhook-job-error"
+	}
+	text := i18n.Get(locale, key, jobParams(job)).(string)
+	output := outputTail(job.Output)
+	if !job.Succ && "" != output {
+		text += "\n" + output
+	}
+
+	var payload interface{}
+	switch webhook.Type {
+	case "slack":
+		payload = map[string]interface{}{"text": text}
+	case "dingtalk":
+		payload = map[string]interface{}{"msgtype": "text", "text": map[string]interface{}{"content": text}}
+	default: // generic
+		payload = map[string]interface{}{"name": job.Name, "userId": job.UserId, "path": job.Path, "succ": job.Succ,
+			"duration": int64(job.Duration / time.Millisecond), "output": output, "text": text,
+			"requestId": job.RequestId}
+	}
+
+	data, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(data))
+	if nil != err {
+		logger.Warnf("Posts job [%s] of user [%s] to webhook [%s] failed: %s", job.Name, job.UserId, webhook.URL, err)
+
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", conf.UserAgent)
+
+	resp, err := webhookClient.Do(req)
+	if nil != err {
+		logger.Warnf("Posts job [%s] of user [%s] to webhook [%s] failed: %s", job.Name, job.UserId, webhook.URL, err)
+
+		return
+	}
+	defer resp.Body.Close()
+
+	if 200 > resp.StatusCode || 300 <= resp.StatusCode {
+		logger.Warnf("Posts job [%s] of user [%s] to webhook [%s] failed: responded [%s]", job.Name, job.UserId,
+			webhook.URL, resp.Status)
+	}
+}
+
+// outputTail returns the last lines of the specified job output.
+func outputTail(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if webhookOutputLines < len(lines) {
+		lines = lines[len(lines)-webhookOutputLines:]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// jobParams returns the parameters of the summary message of the specified job.
+func jobParams(job *event.Job) i18n.Params {
+	return i18n.Params{"job": job.Name, "path": job.Path, "user": job.UserId,
+		"duration": job.Duration.Round(time.Millisecond)}
+}
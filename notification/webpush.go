@@ -0,0 +1,232 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notification
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// VAPID key pair identifying Wide to push services, loaded from (or generated to) {Data}/vapid.pem on first use.
+var vapidKey *ecdsa.PrivateKey
+
+// VAPID key pair lock.
+var vapidMutex sync.Mutex
+
+// Push subscriptions lock.
+var pushMutex sync.Mutex
+
+// PushKeyHandler handles request of getting the VAPID public key for browsers to subscribe Web Push.
+func PushKeyHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	key := getVAPIDKey()
+	if nil == key {
+		result.Code = -1
+
+		return
+	}
+
+	result.Data = util.VAPIDPublicKey(key)
+}
+
+// PushSubscribeHandler handles request of adding a Web Push subscription (PushSubscription.toJSON() of the browser)
+// of the current user, the endpoint must be an https URL of a public host.
+func PushSubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args struct {
+		Endpoint string
+		Keys     struct {
+			P256dh string
+			Auth   string
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if !util.ValidPushEndpoint(args.Endpoint) || "" == args.Keys.P256dh || "" == args.Keys.Auth {
+		result.Code = -1
+		result.Msg = "invalid subscription"
+
+		return
+	}
+
+	user := conf.GetUser(uid)
+
+	pushMutex.Lock()
+	defer pushMutex.Unlock()
+
+	subscriptions := []*conf.PushSubscription{{Endpoint: args.Endpoint, P256dh: args.Keys.P256dh,
+		Auth: args.Keys.Auth}}
+	for _, s := range user.PushSubscriptions {
+		if s.Endpoint != args.Endpoint {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	user.PushSubscriptions = subscriptions
+
+	if !user.Save() {
+		result.Code = -1
+	}
+}
+
+// PushUnsubscribeHandler handles request of removing a Web Push subscription of the current user.
+func PushUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	endpoint, _ := args["endpoint"].(string)
+	if !removePushSubscription(conf.GetUser(uid), endpoint) {
+		result.Code = -1
+	}
+}
+
+// pushJob pushes the finished job of the specified event to the browsers subscribed by the user ran the job.
+//
+// Failed jobs and succeeded ones not shorter than Wide.WebhookMinDuration are pushed, browsers show them only if
+// Wide is not focused.
+func pushJob(e *event.Event) {
+	job := e.Data.(*event.Job)
+	if job.Succ && job.Duration < time.Duration(conf.Wide().WebhookMinDuration)*time.Second {
+		return
+	}
+
+	user := conf.GetUser(job.UserId)
+	if nil == user {
+		return
+	}
+
+	pushMutex.Lock()
+	subscriptions := append([]*conf.PushSubscription{}, user.PushSubscriptions...)
+	pushMutex.Unlock()
+
+	if 1 > len(subscriptions) {
+		return
+	}
+
+	key := getVAPIDKey()
+	if nil == key {
+		return
+	}
+
+	msgKey := "webhook-job-succ"
+	if !job.Succ {
+		msgKey = "webhook-job-error"
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"title": "Wide", "succ": job.Succ,
+		"body": i18n.Get(user.Locale, msgKey, jobParams(job))})
+
+	subject := conf.Wide().WebPushSubject
+	if "" == subject {
+		subject = "https://github.com/kwokhunglee/wide"
+	}
+
+	for _, subscription := range subscriptions {
+		go func(s *conf.PushSubscription) {
+			defer gulu.Panic.Recover(nil)
+
+			gone, err := util.SendPush(util.PushClient, key, subject, s.Endpoint, s.P256dh, s.Auth, payload)
+			if nil != err {
+				logger.Warnf("Pushes job [%s] to user [%s] failed: %s", job.Name, job.UserId, err)
+			}
+			if gone {
+				removePushSubscription(user, s.Endpoint)
+			}
+		}(subscription)
+	}
+}
+
+// removePushSubscription removes the subscription of the specified endpoint from the specified user.
+func removePushSubscription(user *conf.User, endpoint string) bool {
+	pushMutex.Lock()
+	defer pushMutex.Unlock()
+
+	subscriptions := []*conf.PushSubscription{}
+	for _, s := range user.PushSubscriptions {
+		if s.Endpoint != endpoint {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	user.PushSubscriptions = subscriptions
+
+	return user.Save()
+}
+
+// getVAPIDKey returns the VAPID key pair, returns nil if it can't be loaded.
+func getVAPIDKey() *ecdsa.PrivateKey {
+	vapidMutex.Lock()
+	defer vapidMutex.Unlock()
+
+	if nil == vapidKey {
+		key, err := util.LoadVAPIDKey(filepath.Join(conf.Wide().Data, "vapid.pem"))
+		if nil != err {
+			logger.Errorf("Loads VAPID key failed: %s", err)
+
+			return nil
+		}
+		vapidKey = key
+	}
+
+	return vapidKey
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// maxAPIOutput is the max length of the output of a go command responded by the REST API, the head is kept.
+const maxAPIOutput = 1 << 20
+
+// APIBuildHandler handles REST API request of go build. Unlike BuildHandler it neither saves the file nor streams the
+// output to an output channel, it waits for the build and responds the output.
+//
+// Arguments: "path" (a file or directory of the package), "pathtype" and "stream". Responds "succ" and "output", or the
+// output as it's produced if "stream" is true (see apiGoHandler).
+func APIBuildHandler(w http.ResponseWriter, r *http.Request) {
+	apiGoHandler(w, r, "build")
+}
+
+// APITestHandler handles REST API request of go test, it waits for the tests and responds the output.
+//
+// Arguments: "path" (a file or directory of the package), "pathtype" and "stream". Responds "succ" and "output", or the
+// output as it's produced if "stream" is true (see apiGoHandler).
+func APITestHandler(w http.ResponseWriter, r *http.Request) {
+	apiGoHandler(w, r, "test")
+}
+
+// APIRunHandler handles REST API request of running a program. The package is built and then the executable is run as
+// RunHandler does: in Docker if it's available, and killed if it runs longer than apiRunTimeout.
+//
+// Arguments: "path" (a file or directory of the package), "pathtype" and "stream". Responds "succ" and "output" (of
+// both the build and the program), or the output as it's produced if "stream" is true (see apiGoHandler).
+func APIRunHandler(w http.ResponseWriter, r *http.Request) {
+	apiGoHandler(w, r, "run")
+}
+
+// apiRunTimeout is the max running time of a program run via the REST API.
+const apiRunTimeout = 5 * time.Second
+
+// goJobArgs returns the arguments of the go command of the specified job ("build", "run" or "test") of the specified
+// user, a program is built before it's run.
+func goJobArgs(job string, user *conf.User) []string {
+	if "build" == job || "run" == job {
+		return append([]string{"build"}, user.BuildArgs(runtime.GOOS)...)
+	}
+
+	return []string{"test", "-v"}
+}
+
+// apiCommand is a command run by the REST API.
+type apiCommand struct {
+	*exec.Cmd
+	timeout time.Duration // the command is killed if it runs longer than the timeout, 0 for no limit
+}
+
+// run runs the command with its output written to the specified writer.
+func (cmd *apiCommand) run(out io.Writer) error {
+	cmd.Stdout, cmd.Stderr = out, out
+	if 0 == cmd.timeout {
+		return cmd.Run()
+	}
+
+	if err := cmd.Start(); nil != err {
+		return err
+	}
+	timer := time.AfterFunc(cmd.timeout, func() {
+		if err := cmd.Cancel(); nil != err {
+			logger.Warnf("Kills %v failed [%s]", cmd.Args, err)
+		}
+	})
+
+	err := cmd.Wait()
+	if !timer.Stop() { // written after Wait so it does not interleave with the output of the command
+		fmt.Fprintf(out, "\nrun program timeout in %s\n", cmd.timeout)
+	}
+
+	return err
+}
+
+// goCommands returns the commands of the specified job of the specified user in the specified package directory, the
+// commands are killed once the specified context is done.
+func goCommands(ctx context.Context, job string, user *conf.User, dir string) []*apiCommand {
+	cmd := exec.CommandContext(ctx, "go", goJobArgs(job, user)...)
+	cmd.Dir = dir
+	setCmdEnv(cmd, user.Id)
+	ret := []*apiCommand{{Cmd: cmd}}
+	if "run" != job {
+		return ret
+	}
+
+	executable := filepath.Base(dir)
+	if gulu.OS.IsWindows() {
+		executable += ".exe"
+	}
+	executable = filepath.Join(dir, executable)
+
+	if conf.Docker {
+		rid := strconv.Itoa(rand.Int())
+		cmd = exec.CommandContext(ctx, "docker", "run", "--rm", "--cpus", "0.05", "--name", rid, "-v",
+			executable+":/"+filepath.Base(executable), conf.DockerImageGo, "/"+filepath.Base(executable))
+		cmd.Cancel = func() error { // killing the docker client leaves the container running
+			return exec.Command("docker", "rm", "-f", rid).Run()
+		}
+	} else {
+		cmd = exec.CommandContext(ctx, executable)
+		cmd.Dir = dir
+	}
+
+	return append(ret, &apiCommand{Cmd: cmd, timeout: apiRunTimeout})
+}
+
+// apiGoHandler runs the go commands of the specified job in the package directory of the request, the commands are
+// killed if the client goes away.
+//
+// If argument "stream" is true the output is responded as plain text while the commands run (so clients could tail
+// it), followed by trailer X-Exit-Code, the exit code of the last command run.
+func apiGoHandler(w http.ResponseWriter, r *http.Request, job string) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	user := conf.GetUser(uid)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+
+	pathArg, _ := args["path"].(string)
+	path, _ := file.GetPath(uid, pathArg, fmt.Sprint(args["pathtype"]))
+	if "" == path || gulu.Go.IsAPI(path) || !session.CanAccess(uid, path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	dir := path
+	if !gulu.File.IsDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	cmds := goCommands(r.Context(), job, user, dir)
+
+	if stream, _ := args["stream"].(bool); stream {
+		streamGoCommands(w, r, cmds, uid, user.Locale)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	// waits for a worker, released once the commands exit
+	release := acquireWorker("", user.Locale)
+	defer release()
+
+	output := &bytes.Buffer{}
+	for _, cmd := range cmds {
+		started := time.Now()
+		err := cmd.run(output)
+		if nil != r.Context().Err() {
+			result.Code = -1
+
+			return
+		}
+		if _, ok := err.(*exec.ExitError); nil != err && !ok {
+			logger.Error(err)
+			result.Code = -1
+
+			return
+		}
+
+		logger.Debugf("User [%s] ran %v in [%s] via API, took %s", uid, cmd.Args, dir, time.Since(started))
+
+		if nil != err {
+			result.Data = map[string]interface{}{"succ": false, "output": apiOutput(output)}
+
+			return
+		}
+	}
+
+	result.Data = map[string]interface{}{"succ": true, "output": apiOutput(output)}
+}
+
+// apiOutput returns the specified output as a string, truncated to maxAPIOutput.
+func apiOutput(output *bytes.Buffer) string {
+	if maxAPIOutput < output.Len() {
+		output.Truncate(maxAPIOutput)
+	}
+
+	return output.String()
+}
+
+// streamGoCommands runs the specified commands one after another, stops at the first failure, and responds their
+// output as it's produced, followed by trailer X-Exit-Code (-1 if a command can't be run).
+func streamGoCommands(w http.ResponseWriter, r *http.Request, cmds []*apiCommand, uid, locale string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Trailer", "X-Exit-Code")
+	w.WriteHeader(http.StatusOK)
+	out := &flushWriter{w: w}
+	out.flush()
+
+	// waits for a worker, released once the commands exit
+	release := acquireWorker("", locale)
+	defer release()
+
+	exitCode := 0
+	for _, cmd := range cmds {
+		started := time.Now()
+		if err := cmd.run(out); nil != err {
+			exitCode = -1
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+			} else if nil == r.Context().Err() {
+				logger.Error(err)
+			}
+		}
+
+		logger.Debugf("User [%s] ran %v in [%s] via API, took %s", uid, cmd.Args, cmd.Dir, time.Since(started))
+
+		if 0 != exitCode {
+			break
+		}
+	}
+	w.Header().Set("X-Exit-Code", strconv.Itoa(exitCode))
+}
+
+// flushWriter flushes the response after each write, so clients get the output of a command at once.
+//
+// It's used as both stdout and stderr of a command, exec.Cmd calls Write from one goroutine at a time then.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.flush()
+
+	return n, err
+}
+
+func (fw *flushWriter) flush() {
+	if flusher, ok := fw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -27,11 +27,14 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/kwokhunglee/wide/event"
 	"github.com/kwokhunglee/wide/file"
 	"github.com/kwokhunglee/wide/conf"
 	"github.com/kwokhunglee/wide/i18n"
 	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
 )
 
 // BuildHandler handles request of building.
@@ -46,6 +49,7 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	uid := httpSession.Values["uid"].(string)
+	requestId := util.RequestId(r)
 	user := conf.GetUser(uid)
 	locale := user.Locale
 
@@ -84,12 +88,18 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	fout.Close()
 
+	// files of other languages are built by their toolchains
+	if toolchain := conf.Wide().Toolchain(filePath); nil != toolchain {
+		toolchainBuild(sid, user, toolchain, filePath, requestId, args["nextCmd"])
+
+		return
+	}
+
 	channelRet := map[string]interface{}{}
 	if nil != session.OutputWS[sid] {
 		// display "START [go build]" in front-end browser
 
-		msg := i18n.Get(locale, "start-build").(string)
-		msg = strings.Replace(msg, "build]", "build "+fmt.Sprint(user.BuildArgs(runtime.GOOS))+"]", 1)
+		msg := i18n.Get(locale, "start-build", i18n.Params{"args": " " + fmt.Sprint(user.BuildArgs(runtime.GOOS))}).(string)
 
 		channelRet["output"] = "<span class='start-build'>" + msg + "</span>\n"
 		channelRet["cmd"] = "start-build"
@@ -99,17 +109,7 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 		wsChannel.Refresh()
 	}
 
-	var goModCmd *exec.Cmd
-	if !gulu.File.IsExist(filepath.Join(curDir, "go.mod")) {
-		curDirName := filepath.Base(curDir)
-		goModCmd = exec.Command("go", "mod", "init", curDirName)
-	} else {
-		goModCmd = exec.Command("go", "mod", "tidy")
-	}
-	goModCmd.Dir = curDir
-	setCmdEnv(goModCmd, uid)
-	outputBytes, err := goModCmd.CombinedOutput()
-	output := string(outputBytes)
+	output, err := goMod(curDir, filepath.Base(curDir), uid)
 	if nil != err && strings.Contains(output, "go.mod already exists") {
 		logger.Error(err.Error() + ": " + output)
 		result.Code = -1
@@ -155,6 +155,12 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// waits for a worker, released once the build exits
+	release := acquireWorker(sid, locale)
+	defer release()
+
+	jobStarted(sid, &event.Job{Name: "build", UserId: uid, Path: curDir, RequestId: requestId})
+	started := time.Now()
 	if err := cmd.Start(); nil != err {
 		logger.Error(err)
 		result.Code = -1
@@ -165,6 +171,17 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 	channelRet["cmd"] = "build"
 	channelRet["executable"] = executable
 
+	// the output lines are sent in batches
+	batcher := util.NewOutputBatcher(conf.Wide().OutputFlushDuration(), func(text string) {
+		if wsChannel := session.OutputWS[sid]; nil != wsChannel {
+			if err := wsChannel.WriteJSON(map[string]interface{}{"cmd": "build", "executable": executable,
+				"output": text}); nil != err {
+				logger.Warn(err)
+			}
+			wsChannel.Refresh()
+		}
+	})
+
 	outReader := bufio.NewReader(stdout)
 
 	/////////
@@ -194,16 +211,7 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 
-			channelRet["output"] = line
-
-			err = wsChannel.WriteJSON(&channelRet)
-			if nil != err {
-				logger.Warn(err)
-
-				break
-			}
-
-			wsChannel.Refresh()
+			batcher.Write(line)
 		}
 	}()
 
@@ -230,18 +238,15 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 
 		// path process
 		errOutWithPath := parsePath(curDir, line)
-		channelRet["output"] = "<span class='stderr'>" + errOutWithPath + "</span>"
-
-		err = wsChannel.WriteJSON(&channelRet)
-		if nil != err {
-			logger.Warn(err)
-			break
-		}
-
-		wsChannel.Refresh()
+		batcher.Write("<span class='stderr'>" + errOutWithPath + "</span>")
 	}
 
-	if nil == cmd.Wait() {
+	succ := nil == cmd.Wait()
+	batcher.Flush()
+	jobDone(sid, &event.Job{Name: "build", UserId: uid, Path: curDir, RequestId: requestId, Succ: succ,
+		Output: strings.Join(lines, "")}, started)
+
+	if succ {
 		channelRet["nextCmd"] = args["nextCmd"]
 		channelRet["output"] = "<span class='build-succ'>" + i18n.Get(locale, "build-succ").(string) + "</span>\n"
 	} else {
@@ -309,3 +314,19 @@ func BuildHandler(w http.ResponseWriter, r *http.Request) {
 
 	wsChannel.Refresh()
 }
+
+// goMod initializes the module (go mod init) of the specified directory with the specified module path if there is no
+// go.mod, tidies the module (go mod tidy) otherwise. Returns the combined output of the command.
+func goMod(dir, modulePath, uid string) (string, error) {
+	var cmd *exec.Cmd
+	if !gulu.File.IsExist(filepath.Join(dir, "go.mod")) {
+		cmd = exec.Command("go", "mod", "init", modulePath)
+	} else {
+		cmd = exec.Command("go", "mod", "tidy")
+	}
+	cmd.Dir = dir
+	setCmdEnv(cmd, uid)
+	output, err := cmd.CombinedOutput()
+
+	return string(output), err
+}
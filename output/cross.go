@@ -132,7 +132,7 @@ func CrossCompilationHandler(w http.ResponseWriter, r *http.Request) {
 	if nil != session.OutputWS[sid] {
 		// display "START [go build]" in front-end browser
 
-		channelRet["output"] = "<span class='start-build'>" + i18n.Get(locale, "start-build").(string) + "</span>\n"
+		channelRet["output"] = "<span class='start-build'>" + i18n.Get(locale, "start-build", i18n.Params{"args": ""}).(string) + "</span>\n"
 		channelRet["cmd"] = "start-build"
 
 		wsChannel := session.OutputWS[sid]
@@ -148,7 +148,11 @@ func CrossCompilationHandler(w http.ResponseWriter, r *http.Request) {
 
 	reader := bufio.NewReader(io.MultiReader(stdout, stderr))
 
+	// waits for a worker, released once the build exits
+	release := acquireWorker(sid, locale)
+
 	if err := cmd.Start(); nil != err {
+		release()
 		logger.Error(err)
 		result.Code = -1
 
@@ -157,6 +161,7 @@ func CrossCompilationHandler(w http.ResponseWriter, r *http.Request) {
 
 	go func(runningId int) {
 		defer gulu.Panic.Recover(nil)
+		defer release()
 		defer cmd.Wait()
 
 		// read all
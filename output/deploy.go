@@ -0,0 +1,280 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/deploy"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// applyTimeout is the timeout of applying the objects of a deployment.
+const applyTimeout = 2 * time.Minute
+
+// DeployHandler handles request of deploying to Kubernetes (see conf.Kubernetes).
+//
+// Arguments: "sid", "file" (a manifest, a directory of manifests or a Go package) and "pathtype". Manifests (.yaml,
+// .yml or .json) are applied as they are, a Go package without manifests is built, pushed as an image and deployed
+// with a generated manifest. The output and the events of the applied objects are streamed to the output channel of
+// the wide session.
+func DeployHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid := args["sid"].(string)
+	fileArg, _ := args["file"].(string)
+	path, _ := file.GetPath(uid, fileArg, fmt.Sprint(args["pathtype"]))
+	// user [playground] is a reserved mock user
+	if "playground" == uid || "" == path || gulu.Go.IsAPI(path) || !session.CanAccess(uid, path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	k := conf.Wide().Kubernetes
+	if nil == k {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "deploy-disabled").(string)
+
+		return
+	}
+
+	dir := path
+	if !gulu.File.IsDir(path) {
+		dir = filepath.Dir(path)
+	}
+	manifests := manifestFiles(path)
+	if 1 > len(manifests) && "" == k.Registry {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "deploy-no-registry").(string)
+
+		return
+	}
+
+	namespace := k.UserNamespace(uid)
+	writeOutput(sid, map[string]interface{}{"cmd": "start-deploy",
+		"output": "<span class='start-build'>" + html.EscapeString(i18n.Get(locale, "start-deploy",
+			i18n.Params{"path": filepath.Base(path), "namespace": namespace}).(string)) + "</span>\n"})
+
+	// waits for a worker, released once the objects are applied
+	release := acquireWorker(sid, locale)
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+		defer release()
+
+		logger.Debugf("User [%s, %s] is deploying [%s] to namespace [%s]", uid, sid, path, namespace)
+
+		out := func(line string) {
+			writeOutput(sid, map[string]interface{}{"cmd": "deploy",
+				"output": "<span class='stderr'>" + html.EscapeString(line) + "</span>\n"})
+		}
+		fail := func(err error) {
+			logger.Warnf("User [%s] deploys [%s] failed: %s", uid, path, err)
+			writeOutput(sid, map[string]interface{}{"cmd": "deploy",
+				"output": "<span class='build-error'>" + html.EscapeString(err.Error()) + "</span>\n"})
+		}
+
+		var objs []*unstructured.Unstructured
+		var err error
+		if 0 < len(manifests) {
+			objs, err = decodeManifests(manifests)
+		} else {
+			objs, err = deployBinary(uid, dir, k, out)
+		}
+		if nil != err {
+			fail(err)
+
+			return
+		}
+
+		client, err := deploy.NewClient(k)
+		if nil != err {
+			fail(err)
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), applyTimeout)
+		err = client.Apply(ctx, uid, namespace, objs, out)
+		cancel()
+		if nil != err {
+			fail(err)
+
+			return
+		}
+		release() // streaming events doesn't occupy a worker
+
+		logger.Infof("User [%s] deployed [%s] to namespace [%s]", uid, path, namespace)
+		writeOutput(sid, map[string]interface{}{"cmd": "deploy",
+			"output": "<span class='build-succ'>" + html.EscapeString(i18n.Get(locale, "deploy-succ",
+				i18n.Params{"namespace": namespace}).(string)) + "</span>\n"})
+
+		ctx, cancel = context.WithTimeout(context.Background(), k.EventsDuration())
+		defer cancel()
+		if err := client.Events(ctx, namespace, objs, out); nil != err {
+			logger.Warnf("Watches events of namespace [%s] failed: %s", namespace, err)
+		}
+	}()
+}
+
+// manifestFiles returns the manifests of the specified path: the path itself if it's a manifest, the manifests in it
+// if it's a directory, otherwise none.
+func manifestFiles(path string) []string {
+	if !gulu.File.IsDir(path) {
+		if isManifest(path) {
+			return []string{path}
+		}
+
+		return nil
+	}
+
+	ret := []string{}
+	entries, err := os.ReadDir(path)
+	if nil != err {
+		return ret
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isManifest(entry.Name()) {
+			ret = append(ret, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	return ret
+}
+
+// isManifest checks whether the specified file is a manifest by its extension.
+func isManifest(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+
+	return false
+}
+
+// decodeManifests decodes the objects of the specified manifests.
+func decodeManifests(manifests []string) ([]*unstructured.Unstructured, error) {
+	ret := []*unstructured.Unstructured{}
+	for _, manifest := range manifests {
+		data, err := ioutil.ReadFile(manifest)
+		if nil != err {
+			return nil, err
+		}
+
+		objs, err := deploy.Decode(data)
+		if nil != err {
+			return nil, fmt.Errorf("%s: %s", filepath.Base(manifest), err)
+		}
+		ret = append(ret, objs...)
+	}
+
+	return ret, nil
+}
+
+// deployBinary builds the Go package of the specified directory for linux/amd64, pushes an image of the binary to the
+// registry and returns the generated objects of it.
+func deployBinary(uid, dir string, k *conf.Kubernetes, out func(line string)) ([]*unstructured.Unstructured, error) {
+	tmp, err := os.MkdirTemp("", "wide-deploy-")
+	if nil != err {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	goBuildArgs := append([]string{"build"}, conf.GetUser(uid).BuildArgs("linux")...)
+	goBuildArgs = append(goBuildArgs, "-o", filepath.Join(tmp, "app"))
+	cmd := exec.Command("go", goBuildArgs...)
+	cmd.Dir = dir
+	setCmdEnv(cmd, uid)
+	for i, env := range cmd.Env {
+		if strings.HasPrefix(env, "GOOS=") {
+			cmd.Env[i] = "GOOS=linux"
+		} else if strings.HasPrefix(env, "GOARCH=") {
+			cmd.Env[i] = "GOARCH=amd64"
+		}
+	}
+	cmd.Env = append(cmd.Env, "CGO_ENABLED=0")
+	if err := runLines(cmd, out); nil != err {
+		return nil, err
+	}
+
+	dockerfile := "FROM " + k.GetBaseImage() + "\nCOPY app /app\nENTRYPOINT [\"/app\"]\n"
+	if err := ioutil.WriteFile(filepath.Join(tmp, "Dockerfile"), []byte(dockerfile), 0644); nil != err {
+		return nil, err
+	}
+
+	name := deploy.Name(filepath.Base(dir))
+	image := strings.TrimSuffix(k.Registry, "/") + "/wide-" + dockerName(uid) + "-" + name + ":" +
+		time.Now().Format("20060102150405")
+	cmd = exec.Command("docker", "build", "--label", conf.ContainerLabel+"="+uid, "-t", image, ".")
+	cmd.Dir = tmp
+	if err := runLines(cmd, out); nil != err {
+		return nil, err
+	}
+	if err := runLines(exec.Command("docker", "push", image), out); nil != err {
+		return nil, err
+	}
+
+	return deploy.Generate(name, image, k.GetPort()), nil
+}
+
+// runLines runs the specified command, writes the lines of the output to the specified function.
+func runLines(cmd *exec.Cmd, out func(line string)) error {
+	out(strings.Join(cmd.Args, " "))
+	data, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); "" != line {
+			out(line)
+		}
+	}
+	if nil != err {
+		return fmt.Errorf("%s: %s", filepath.Base(cmd.Args[0]), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,481 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Port is a published port of a container.
+type Port struct {
+	Container string `json:"container"` // container port, such as "8080/tcp"
+	Host      string `json:"host"`      // host address, such as "127.0.0.1:49153"
+	URL       string `json:"url"`       // URL of the host address
+}
+
+// DockerBuildHandler handles request of building an image of a Dockerfile with docker build.
+//
+// Arguments: "sid", "file" (the Dockerfile or the directory of it) and "pathtype". The directory of the Dockerfile is
+// the build context, the output is streamed to the output channel of the wide session. Images are named after the
+// user and the directory (see dockerImage) and labeled with conf.ContainerLabel.
+func DockerBuildHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid := args["sid"].(string)
+	dockerfile, ok := dockerfilePath(w, uid, args)
+	if !ok {
+		return
+	}
+	if !conf.Docker {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "docker-unavailable").(string)
+
+		return
+	}
+	if !gulu.File.IsExist(dockerfile) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "docker-no-dockerfile", i18n.Params{"path": filepath.Base(dockerfile)}).(string)
+
+		return
+	}
+
+	curDir := filepath.Dir(dockerfile)
+	image := dockerImage(uid, curDir)
+	cmd := exec.Command("docker", "build", "--label", conf.ContainerLabel+"="+uid, "-t", image, "-f",
+		filepath.Base(dockerfile), ".")
+	cmd.Dir = curDir
+
+	stdout, err := cmd.StdoutPipe()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+	cmd.Stderr = cmd.Stdout
+
+	writeOutput(sid, map[string]interface{}{"cmd": "start-docker-build",
+		"output": "<span class='start-build'>" + html.EscapeString(i18n.Get(locale, "start-toolchain",
+			i18n.Params{"cmd": strings.Join(cmd.Args, " ")}).(string)) + "</span>\n"})
+
+	// waits for a worker, released once docker build exits
+	release := acquireWorker(sid, locale)
+
+	if err := cmd.Start(); nil != err {
+		release()
+		logger.Error(err)
+		writeOutput(sid, map[string]interface{}{"cmd": "docker-build",
+			"output": "<span class='build-error'>" + html.EscapeString(err.Error()) + "</span>\n"})
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	result.Data = map[string]interface{}{"image": image}
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+		defer release()
+
+		logger.Debugf("User [%s, %s] is building image [%s] of [%s]", uid, sid, image, dockerfile)
+
+		// the output lines are sent in batches
+		batcher := util.NewOutputBatcher(conf.Wide().OutputFlushDuration(), func(text string) {
+			writeOutput(sid, map[string]interface{}{"cmd": "docker-build", "output": text})
+		})
+
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadString('\n')
+			if "" != line {
+				batcher.Write("<span class='stderr'>" + html.EscapeString(line) + "</span>")
+			}
+			if nil != err {
+				break
+			}
+		}
+
+		err := cmd.Wait()
+		batcher.Flush()
+
+		channelRet := map[string]interface{}{"cmd": "docker-build", "image": image}
+		if nil == err {
+			channelRet["output"] = "<span class='build-succ'>" + html.EscapeString(i18n.Get(locale,
+				"docker-build-succ", i18n.Params{"image": image}).(string)) + "</span>\n"
+		} else {
+			channelRet["output"] = "<span class='build-error'>" + html.EscapeString(i18n.Get(locale,
+				"toolchain-error", i18n.Params{"cmd": "docker build"}).(string)) + "</span>\n"
+		}
+		writeOutput(sid, channelRet)
+
+		logger.Debugf("User [%s, %s] done building image [%s], succ [%v]", uid, sid, image, nil == err)
+	}()
+}
+
+// DockerRunHandler handles request of running a detached container of the image built of a Dockerfile.
+//
+// Arguments: "sid", "file" (the Dockerfile or the directory of it), "pathtype" and optional "ports" (container ports
+// to publish, such as ["8080"], defaults to the ones exposed by the Dockerfile). Responds the name of the container
+// and the host addresses of the ports, which are also sent to the output channel of the wide session.
+func DockerRunHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid := args["sid"].(string)
+	dockerfile, ok := dockerfilePath(w, uid, args)
+	if !ok {
+		return
+	}
+	if !conf.Docker {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "docker-unavailable").(string)
+
+		return
+	}
+
+	image := dockerImage(uid, filepath.Dir(dockerfile))
+	if owner, err := dockerLabel("image", image); nil != err || uid != owner {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "docker-no-image", i18n.Params{"path": filepath.Base(dockerfile)}).(string)
+
+		return
+	}
+
+	ports := []string{}
+	if portsArg, ok := args["ports"].([]interface{}); ok {
+		for _, port := range portsArg {
+			ports = append(ports, fmt.Sprint(port))
+		}
+	} else {
+		ports = exposedPorts(dockerfile)
+	}
+	for _, port := range ports {
+		if !portRegexp.MatchString(port) {
+			result.Code = -1
+
+			return
+		}
+	}
+
+	max := conf.Wide().MaxContainers()
+	if running := dockerLines("ps", "-q", "--filter", "label="+conf.ContainerLabel+"="+uid); len(running) >= max {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "docker-max-containers", i18n.Params{"max": max}).(string)
+
+		return
+	}
+
+	name := "wide-" + dockerName(uid) + "-" + strings.ToLower(gulu.Rand.String(8))
+	out, err := exec.Command("docker", conf.Wide().ContainerRunArgs(name, uid, image, ports)...).CombinedOutput()
+	if nil != err {
+		logger.Warnf("User [%s] runs container of image [%s] failed: %s", uid, image, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	published := []*Port{}
+	for _, line := range dockerLines("port", name) { // "8080/tcp -> 127.0.0.1:49153"
+		parts := strings.SplitN(line, " -> ", 2)
+		if 2 != len(parts) {
+			continue
+		}
+
+		published = append(published, &Port{Container: parts[0], Host: parts[1], URL: portURL(parts[1])})
+	}
+
+	logger.Infof("User [%s] runs container [%s] of image [%s]", uid, name, image)
+	result.Data = map[string]interface{}{"name": name, "image": image, "ports": published}
+
+	output := "<span class='build-succ'>" + html.EscapeString(i18n.Get(locale, "docker-running",
+		i18n.Params{"name": name, "image": image}).(string)) + "</span>\n"
+	for _, port := range published {
+		output += html.EscapeString(port.Container) + " -> <a href='" + html.EscapeString(port.URL) +
+			"' target='_blank'>" + html.EscapeString(port.URL) + "</a>\n"
+	}
+	writeOutput(sid, map[string]interface{}{"cmd": "docker-run", "output": output})
+}
+
+// DockerStopHandler handles request of stopping a container of the user.
+//
+// Arguments: "name" of the container. The container is removed once it stopped.
+func DockerStopHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	name, _ := args["name"].(string)
+	if owner, err := dockerLabel("container", name); nil != err || uid != owner {
+		result.Code = -1
+
+		return
+	}
+
+	if out, err := exec.Command("docker", "stop", name).CombinedOutput(); nil != err {
+		logger.Warnf("User [%s] stops container [%s] failed: %s", uid, name, out)
+		result.Code = -1
+		result.Msg = strings.TrimSpace(string(out))
+
+		return
+	}
+
+	logger.Infof("User [%s] stopped container [%s]", uid, name)
+}
+
+// DockerListHandler handles request of listing the images and running containers of the user.
+func DockerListHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	filter := "label=" + conf.ContainerLabel + "=" + uid
+	images := []map[string]interface{}{}
+	for _, line := range dockerLines("images", "--filter", filter, "--format",
+		`{"image":"{{.Repository}}:{{.Tag}}","id":"{{.ID}}","created":"{{.CreatedSince}}","size":"{{.Size}}"}`) {
+		image := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &image); nil == err {
+			images = append(images, image)
+		}
+	}
+
+	containers := []map[string]interface{}{}
+	for _, line := range dockerLines("ps", "--filter", filter, "--format",
+		`{"name":{{json .Names}},"image":{{json .Image}},"status":{{json .Status}},"ports":{{json .Ports}}}`) {
+		container := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &container); nil == err {
+			containers = append(containers, container)
+		}
+	}
+
+	result.Data = map[string]interface{}{"images": images, "containers": containers}
+}
+
+// StopContainers stops all the containers run by users, it's called when Wide shuts down.
+func StopContainers() {
+	if !conf.Docker {
+		return
+	}
+
+	names := dockerLines("ps", "-q", "--filter", "label="+conf.ContainerLabel)
+	if 1 > len(names) {
+		return
+	}
+
+	if out, err := exec.Command("docker", append([]string{"rm", "-f"}, names...)...).CombinedOutput(); nil != err {
+		logger.Warnf("Removes containers %v failed: %s", names, out)
+
+		return
+	}
+
+	logger.Infof("Removed [%d] containers", len(names))
+}
+
+// dockerfilePath gets the path of the Dockerfile of the "file" and "pathtype" arguments, responds forbidden if the
+// user can't access it.
+func dockerfilePath(w http.ResponseWriter, uid string, args map[string]interface{}) (string, bool) {
+	fileArg, _ := args["file"].(string)
+	ret, _ := file.GetPath(uid, fileArg, fmt.Sprint(args["pathtype"]))
+	// user [playground] is a reserved mock user
+	if "playground" == uid || "" == ret || gulu.Go.IsAPI(ret) || !session.CanAccess(uid, ret) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return "", false
+	}
+
+	if gulu.File.IsDir(ret) {
+		ret = filepath.Join(ret, "Dockerfile")
+	}
+
+	return ret, true
+}
+
+// dockerImage returns the name of the image built of the specified directory by the user specified by the given user
+// id, such as "wide-alice/hello-1a2b3c4d:latest".
+func dockerImage(uid, dir string) string {
+	hash := sha1.Sum([]byte(filepath.Clean(dir)))
+
+	return "wide-" + dockerName(uid) + "/" + dockerName(filepath.Base(dir)) + "-" + hex.EncodeToString(hash[:4]) +
+		":latest"
+}
+
+// dockerNameRegexp matches characters not allowed in names of images and containers.
+var dockerNameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// dockerName converts the specified string to a component of names of images and containers.
+func dockerName(s string) string {
+	ret := strings.Trim(dockerNameRegexp.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if "" == ret {
+		return "x"
+	}
+
+	return ret
+}
+
+// portRegexp matches container ports, such as "8080" or "53/udp".
+var portRegexp = regexp.MustCompile(`^[0-9]{1,5}(/(tcp|udp))?$`)
+
+// exposedPorts returns the ports exposed (EXPOSE instructions) by the specified Dockerfile.
+func exposedPorts(dockerfile string) []string {
+	ret := []string{}
+	data, err := ioutil.ReadFile(dockerfile)
+	if nil != err {
+		return ret
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if 2 > len(fields) || !strings.EqualFold("EXPOSE", fields[0]) {
+			continue
+		}
+
+		for _, port := range fields[1:] {
+			if portRegexp.MatchString(port) && !gulu.Str.Contains(port, ret) {
+				ret = append(ret, port)
+			}
+		}
+	}
+
+	return ret
+}
+
+// portURL returns the URL of the specified published host address, the host of Wide is used if the address is
+// published on all interfaces.
+func portURL(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if nil != err {
+		return ""
+	}
+
+	if ip := net.ParseIP(host); nil != ip && ip.IsUnspecified() {
+		if u, err := url.Parse(conf.Wide().Server); nil == err && "" != u.Hostname() {
+			host = u.Hostname()
+		}
+	}
+
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// dockerLabel returns the value of conf.ContainerLabel of the specified object ("image" or "container").
+func dockerLabel(object, name string) (string, error) {
+	if "" == name || strings.HasPrefix(name, "-") {
+		return "", fmt.Errorf("invalid %s name [%s]", object, name)
+	}
+
+	out, err := exec.Command("docker", object, "inspect", "--format",
+		`{{index .Config.Labels "`+conf.ContainerLabel+`"}}`, name).Output()
+	if nil != err {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// dockerLines runs docker with the specified arguments, returns the non-empty lines of the output.
+func dockerLines(args ...string) []string {
+	ret := []string{}
+	data, err := exec.Command("docker", args...).Output()
+	if nil != err {
+		logger.Warnf("Executes [docker %s] failed: %s", strings.Join(args, " "), err)
+
+		return ret
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); "" != line {
+			ret = append(ret, line)
+		}
+	}
+
+	return ret
+}
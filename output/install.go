@@ -111,7 +111,11 @@ func GoInstallHandler(w http.ResponseWriter, r *http.Request) {
 
 	reader := bufio.NewReader(io.MultiReader(stdout, stderr))
 
+	// waits for a worker, released once go install exits
+	release := acquireWorker(sid, locale)
+
 	if err := cmd.Start(); nil != err {
+		release()
 		logger.Error(err)
 		result.Code = -1
 
@@ -120,6 +124,7 @@ func GoInstallHandler(w http.ResponseWriter, r *http.Request) {
 
 	go func(runningId int) {
 		defer gulu.Panic.Recover(nil)
+		defer release()
 		defer cmd.Wait()
 
 		logger.Debugf("User [%s, %s] is running [go install] [id=%d, dir=%s]", uid, sid, runningId, curDir)
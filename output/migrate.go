@@ -0,0 +1,314 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// importCommentRegexp matches canonical import comments of package clauses (such as package foo // import "x/foo"),
+// they are ignored in module mode.
+var importCommentRegexp = regexp.MustCompile(`(?m)^(package[ \t]+\w+)[ \t]*` +
+	`(//[ \t]*import[ \t]+"[^"\n]*"|/\*[ \t]*import[ \t]+"[^"\n]*"[ \t]*\*/)`)
+
+// migration represents the report of migrating a GOPATH project to a module.
+type migration struct {
+	Dir      string     `json:"dir"`      // directory of the project, relative to {workspace}/src
+	Module   string     `json:"module"`   // module path, the import path of the directory in GOPATH
+	Rewrites []*rewrite `json:"rewrites"` // rewritten import paths and removed import comments
+	Replaces []string   `json:"replaces"` // module paths of other projects in the workspace replaced with their dirs
+	Output   string     `json:"output"`   // output of go mod init and go mod tidy
+	Err      string     `json:"error"`    // "" if succeeded
+}
+
+// rewrite represents a rewritten line of a source file.
+type rewrite struct {
+	File string `json:"file"` // path relative to the project directory
+	Line int    `json:"line"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MigrateHandler handles request of migrating GOPATH-style projects in the user's workspace to modules.
+//
+// Each top-most directory containing Go files under {workspace}/src without a go.mod is a project, its module path is
+// its import path in GOPATH so existing imports keep working. Relative imports are rewritten to module imports,
+// canonical import comments are removed, imported projects of the workspace are replaced with their directories and
+// then go.mod is generated (go mod init and go mod tidy, the same as building). Nothing is changed with "dryRun".
+func MigrateHandler(w http.ResponseWriter, r *http.Request) {
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+	dryRun, _ := args["dryRun"].(bool)
+
+	src := filepath.Join(filepath.SplitList(conf.GetUserWorkspace(uid))[0], "src")
+	projects, skipped, err := gopathProjects(src)
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	modules := map[string]string{} // <module path, dir>
+	for _, dir := range projects {
+		modules[importPath(src, dir)] = dir
+	}
+
+	migrations := []*migration{}
+	for _, dir := range projects {
+		m := migrate(src, dir, modules, uid, dryRun)
+		if "" != m.Err {
+			logger.Warnf("Migrates [%s] of user [%s] failed: %s", dir, uid, m.Err)
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	result.Data = map[string]interface{}{"projects": migrations, "skipped": skipped, "dryRun": dryRun}
+}
+
+// gopathProjects finds the top-most directories containing Go files under the specified src directory. Directories
+// already having go.mod are returned as skipped (relative to src), vendor, testdata and hidden directories are ignored.
+func gopathProjects(src string) (projects, skipped []string, err error) {
+	projects, skipped = []string{}, []string{}
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != src && ignoredDir(info.Name()) {
+			return filepath.SkipDir
+		}
+
+		if gulu.File.IsExist(filepath.Join(path, "go.mod")) {
+			skipped = append(skipped, importPath(src, path))
+
+			return filepath.SkipDir
+		}
+
+		if path != src && hasGoFiles(path) {
+			projects = append(projects, path)
+
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return projects, skipped, err
+}
+
+// migrate migrates the project of the specified directory to a module, modules holds all the projects of the
+// workspace by their module paths.
+func migrate(src, dir string, modules map[string]string, uid string, dryRun bool) *migration {
+	ret := &migration{Dir: importPath(src, dir), Module: importPath(src, dir), Rewrites: []*rewrite{},
+		Replaces: []string{}}
+
+	replaces := map[string]bool{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && (ignoredDir(info.Name()) || gulu.File.IsExist(filepath.Join(path, "go.mod"))) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+		if ".go" != filepath.Ext(path) {
+			return nil
+		}
+
+		content, imports, rewrites, err := rewriteImports(src, dir, path, ret.Module)
+		if nil != err {
+			return err
+		}
+		ret.Rewrites = append(ret.Rewrites, rewrites...)
+
+		for _, imp := range imports {
+			for module := range modules {
+				if module != ret.Module && (imp == module || strings.HasPrefix(imp, module+"/")) {
+					replaces[module] = true
+				}
+			}
+		}
+
+		if dryRun || 1 > len(rewrites) {
+			return nil
+		}
+
+		return ioutil.WriteFile(path, content, info.Mode())
+	})
+	if nil != err {
+		ret.Err = err.Error()
+
+		return ret
+	}
+
+	for module := range replaces {
+		ret.Replaces = append(ret.Replaces, module)
+	}
+	sort.Strings(ret.Replaces)
+
+	if dryRun {
+		return ret
+	}
+
+	output, err := goMod(dir, ret.Module, uid)
+	ret.Output += output
+	if nil != err {
+		ret.Err = err.Error()
+
+		return ret
+	}
+
+	for _, module := range ret.Replaces {
+		rel, _ := filepath.Rel(dir, modules[module])
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, "../") {
+			rel = "./" + rel
+		}
+
+		cmd := exec.Command("go", "mod", "edit", "-replace="+module+"="+rel)
+		cmd.Dir = dir
+		setCmdEnv(cmd, uid)
+		output, err := cmd.CombinedOutput()
+		ret.Output += string(output)
+		if nil != err {
+			ret.Err = err.Error()
+
+			return ret
+		}
+	}
+
+	output, err = goMod(dir, ret.Module, uid)
+	ret.Output += output
+	if nil != err {
+		ret.Err = err.Error()
+	}
+
+	return ret
+}
+
+// rewriteImports rewrites relative imports (such as "./util") of the specified file to imports of the module and
+// removes the canonical import comment. Returns the rewritten content, the (rewritten) import paths and the rewrites.
+func rewriteImports(src, dir, path, module string) ([]byte, []string, []*rewrite, error) {
+	content, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, content, parser.ImportsOnly)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+
+	file, _ := filepath.Rel(dir, path)
+	file = filepath.ToSlash(file)
+	imports := []string{}
+	rewrites := []*rewrite{}
+	ret := content
+	for i := len(f.Imports) - 1; 0 <= i; i-- { // backwards, so that offsets of the former ones keep valid
+		spec := f.Imports[i]
+		imp, _ := strconv.Unquote(spec.Path.Value)
+		if !strings.HasPrefix(imp, "./") && !strings.HasPrefix(imp, "../") {
+			imports = append(imports, imp)
+
+			continue
+		}
+
+		target := filepath.Join(filepath.Dir(path), filepath.FromSlash(imp))
+		to := importPath(src, target)
+		if rel, err := filepath.Rel(dir, target); nil == err && !strings.HasPrefix(rel, "..") {
+			to = module
+			if "." != rel {
+				to += "/" + filepath.ToSlash(rel)
+			}
+		}
+		imports = append(imports, to)
+
+		start, end := fset.Position(spec.Path.Pos()).Offset, fset.Position(spec.Path.End()).Offset
+		ret = append(append(append([]byte{}, ret[:start]...), strconv.Quote(to)...), ret[end:]...)
+		rewrites = append(rewrites, &rewrite{File: file, Line: fset.Position(spec.Path.Pos()).Line, From: imp, To: to})
+	}
+
+	if loc := importCommentRegexp.FindSubmatchIndex(ret); nil != loc {
+		comment := string(ret[loc[4]:loc[5]])
+		line := fset.Position(f.Package).Line
+		ret = append(append([]byte{}, ret[:loc[3]]...), ret[loc[5]:]...)
+		rewrites = append(rewrites, &rewrite{File: file, Line: line, From: comment})
+	}
+
+	return ret, imports, rewrites, nil
+}
+
+// importPath gets the GOPATH import path of the specified directory under the specified src directory.
+func importPath(src, dir string) string {
+	rel, err := filepath.Rel(src, dir)
+	if nil != err {
+		return filepath.ToSlash(dir)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// hasGoFiles checks whether the specified directory contains Go files.
+func hasGoFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+
+	return 0 < len(matches)
+}
+
+// ignoredDir checks whether the directory of the specified name is ignored by the go tool.
+func ignoredDir(name string) bool {
+	return "vendor" == name || "testdata" == name || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
@@ -27,9 +27,9 @@ import (
 
 	"github.com/kwokhunglee/wide/gulu"
 	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
 	"github.com/kwokhunglee/wide/session"
 	"github.com/kwokhunglee/wide/util"
-	"github.com/gorilla/websocket"
 )
 
 const (
@@ -52,7 +52,7 @@ type Lint struct {
 func WSHandler(w http.ResponseWriter, r *http.Request) {
 	sid := r.URL.Query()["sid"][0]
 
-	conn, _ := websocket.Upgrade(w, r, nil, 1024, 1024)
+	conn, _ := util.UpgradeCompressed(w, r)
 	wsChan := util.WSChannel{Sid: sid, Conn: conn, Request: r, Time: time.Now()}
 
 	ret := map[string]interface{}{"output": "Ouput initialized", "cmd": "init-output"}
@@ -61,9 +61,27 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wsChan.Mirror = func(v interface{}) { mirrorOutput(sid, v) }
 	session.OutputWS[sid] = &wsChan
+	session.AnnounceChannel(session.ChannelOutput, sid)
 
 	logger.Tracef("Open a new [Output] with session [%s], %d", sid, len(session.OutputWS))
+
+	wsChan.KeepAlive()
+	session.ReleaseChannel(session.ChannelOutput, sid, &wsChan)
+}
+
+// jobStarted emits event EvtCodeJobStarted of the specified job to the specified wide session.
+func jobStarted(sid string, job *event.Job) {
+	event.Publish(&event.Event{Code: event.EvtCodeJobStarted, Sid: sid, Data: job})
+}
+
+// jobDone emits event EvtCodeJobDone of the specified finished job to the specified wide session.
+func jobDone(sid string, job *event.Job, started time.Time) {
+	job.Duration = time.Since(started)
+	logger.Infof("Job %s", job)
+
+	event.Publish(&event.Event{Code: event.EvtCodeJobDone, Sid: sid, Data: job})
 }
 
 // parsePath parses file path in the specified outputLine, and returns new line with front-end friendly.
@@ -108,19 +126,50 @@ func setCmdEnv(cmd *exec.Cmd, uid string) {
 	userWorkspace := conf.GetUserWorkspace(uid)
 	cache, err := os.UserCacheDir()
 	if nil != err {
-		logger.Warnf("Get user cache dir failed [" + err.Error() + "]")
+		logger.Warn("Get user cache dir failed [" + err.Error() + "]")
 		cache = os.TempDir()
 	}
 
+	goProxy := "https://goproxy.cn"
+	goRoot := runtime.GOROOT()
+	path := os.Getenv("PATH")
+	user := conf.GetUser(uid)
+	if nil != user {
+		if "" != user.GoProxy {
+			goProxy = user.GoProxy
+		}
+
+		if goExecutable := user.GoExecutable(); "" != goExecutable {
+			goRoot = user.GoRoot
+			path = filepath.Join(goRoot, "bin") + conf.PathListSeparator + path
+			if "go" == cmd.Args[0] { // runs the go command of the user's toolchain
+				cmd.Path = goExecutable
+				cmd.Err = nil
+			}
+		}
+	}
+
 	cmd.Env = append(cmd.Env,
-		"GOPROXY=https://goproxy.cn",
+		"GOPROXY="+goProxy,
 		"GO111MODULE=on",
 		"GOPATH="+userWorkspace,
 		"GOOS="+runtime.GOOS,
 		"GOARCH="+runtime.GOARCH,
-		"GOROOT="+runtime.GOROOT(),
+		"GOROOT="+goRoot,
 		"GOCACHE="+cache,
-		"PATH="+os.Getenv("PATH"))
+		"PATH="+path)
+
+	if nil != user {
+		if "" != user.GoPrivate {
+			cmd.Env = append(cmd.Env, "GOPRIVATE="+user.GoPrivate)
+		}
+		if "" != user.GoNoSumDB {
+			cmd.Env = append(cmd.Env, "GONOSUMDB="+user.GoNoSumDB)
+		}
+		if "" != user.GoFlags {
+			cmd.Env = append(cmd.Env, "GOFLAGS="+user.GoFlags)
+		}
+	}
 
 	if gulu.OS.IsWindows() {
 		// FIXME: for some weird issues on Windows, such as: The requested service provider could not be loaded or initialized.
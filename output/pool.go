@@ -0,0 +1,123 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// goWorkers is the number of go commands (build, test, vet and install) running concurrently, the others wait in a
+// queue in the order they are requested, so a burst of users doesn't spawn dozens of compilers.
+var goWorkers = runtime.NumCPU()
+
+// goQueue is the queue of go commands waiting for workers.
+var goQueue = &workerQueue{}
+
+// workerQueue represents a queue of commands waiting for workers.
+type workerQueue struct {
+	mutex   sync.Mutex
+	running int             // number of the running commands
+	waiting []*workerTicket // waiting commands, the first one runs next
+}
+
+// workerTicket represents a command waiting in a queue.
+type workerTicket struct {
+	sid    string        // id of the wide session requesting the command
+	locale string        // locale of the user
+	ready  chan struct{} // closed once the command gets a worker
+}
+
+// acquireWorker waits for a worker to run a go command of the specified session. While waiting the session is notified
+// of its position in the queue (output "go-queued"), and then notified once the command is started (output
+// "go-started").
+//
+// Returns the function releasing the worker, it must be called once the command exits.
+func acquireWorker(sid, locale string) func() {
+	var once sync.Once
+	release := func() { once.Do(goQueue.release) }
+
+	goQueue.mutex.Lock()
+	if goQueue.running < goWorkers && 0 == len(goQueue.waiting) {
+		goQueue.running++
+		goQueue.mutex.Unlock()
+
+		return release
+	}
+
+	ticket := &workerTicket{sid: sid, locale: locale, ready: make(chan struct{})}
+	goQueue.waiting = append(goQueue.waiting, ticket)
+	position := len(goQueue.waiting)
+	goQueue.mutex.Unlock()
+
+	ticket.notifyQueued(position)
+	<-ticket.ready
+	ticket.notifyStarted()
+
+	return release
+}
+
+// release hands the worker of a finished command over to the first waiting command, the other waiting commands are
+// notified of their new positions.
+func (q *workerQueue) release() {
+	q.mutex.Lock()
+	if 0 == len(q.waiting) {
+		q.running--
+		q.mutex.Unlock()
+
+		return
+	}
+
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	waiting := append([]*workerTicket{}, q.waiting...)
+	q.mutex.Unlock()
+
+	close(next.ready)
+	for i, ticket := range waiting {
+		ticket.notifyQueued(i + 1)
+	}
+}
+
+// notifyQueued notifies the session of the ticket of the specified position in the queue.
+func (t *workerTicket) notifyQueued(position int) {
+	msg := fmt.Sprintf(i18n.Get(t.locale, "go-queued").(string), position)
+	t.notify(map[string]interface{}{"cmd": "go-queued", "position": position,
+		"output": "<span class='start-build go-queued'>" + msg + "</span>\n"})
+}
+
+// notifyStarted notifies the session of the ticket that the command is started.
+func (t *workerTicket) notifyStarted() {
+	msg := i18n.Get(t.locale, "go-started").(string)
+	t.notify(map[string]interface{}{"cmd": "go-started",
+		"output": "<span class='start-build'>" + msg + "</span>\n"})
+}
+
+// notify sends the specified message to the output channel of the session of the ticket.
+func (t *workerTicket) notify(message map[string]interface{}) {
+	wsChannel := session.OutputWS[t.sid]
+	if nil == wsChannel {
+		return
+	}
+
+	if err := wsChannel.WriteJSON(&message); nil != err {
+		logger.Warn(err)
+	}
+	wsChannel.Refresh()
+}
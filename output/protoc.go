@@ -0,0 +1,181 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// ProtocHandler handles request of generating code of a .proto file with protoc (see conf.Protoc).
+//
+// The output is streamed to the output channel of the wide session, errors of protoc are sent as lints of the file
+// once protoc exits.
+func ProtocHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	locale := conf.GetUser(uid).Locale
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid := args["sid"].(string)
+	filePath, _ := file.GetPath(uid, args["file"].(string), fmt.Sprint(args["pathtype"]))
+	if gulu.Go.IsAPI(filePath) || !session.CanAccess(uid, filePath) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	protoc := conf.Wide().Protoc
+	if nil == protoc {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "protoc-disabled").(string)
+
+		return
+	}
+	if ".proto" != strings.ToLower(filepath.Ext(filePath)) {
+		result.Code = -1
+		result.Msg = i18n.Get(locale, "protoc-not-proto", i18n.Params{"path": filepath.Base(filePath)}).(string)
+
+		return
+	}
+
+	curDir := filepath.Dir(filePath)
+	cmd := exec.Command(protoc.Executable(), protoc.Args(filepath.Base(filePath))...)
+	cmd.Dir = curDir
+	setCmdEnv(cmd, uid)
+	setWorkspaceBinPath(cmd, uid)
+
+	stdout, err := cmd.StdoutPipe()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	writeOutput(sid, map[string]interface{}{"cmd": "start-protoc",
+		"output": "<span class='start-build'>" + html.EscapeString(i18n.Get(locale, "start-toolchain",
+			i18n.Params{"cmd": strings.Join(cmd.Args, " ")}).(string)) + "</span>\n"})
+
+	// waits for a worker, released once protoc exits
+	release := acquireWorker(sid, locale)
+
+	if err := cmd.Start(); nil != err {
+		release()
+		logger.Error(err)
+		writeOutput(sid, map[string]interface{}{"cmd": "protoc",
+			"output": "<span class='build-error'>" + html.EscapeString(err.Error()) + "</span>\n"})
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+		defer release()
+
+		logger.Debugf("User [%s, %s] is running [protoc] of [%s]", uid, sid, filePath)
+
+		// the output lines are sent in batches
+		batcher := util.NewOutputBatcher(conf.Wide().OutputFlushDuration(), func(text string) {
+			writeOutput(sid, map[string]interface{}{"cmd": "protoc", "output": text})
+		})
+
+		lines := []string{}
+		reader := bufio.NewReader(io.MultiReader(stdout, stderr))
+		for {
+			line, err := reader.ReadString('\n')
+			if "" != line {
+				lines = append(lines, line)
+				batcher.Write("<span class='stderr'>" + parsePath(curDir, html.EscapeString(line)) + "</span>")
+			}
+			if nil != err {
+				break
+			}
+		}
+
+		err := cmd.Wait()
+		batcher.Flush()
+
+		channelRet := map[string]interface{}{"cmd": "protoc"}
+		if nil == err {
+			channelRet["output"] = "<span class='build-succ'>" + html.EscapeString(i18n.Get(locale, "toolchain-succ",
+				i18n.Params{"cmd": "protoc"}).(string)) + "</span>\n"
+		} else {
+			channelRet["output"] = "<span class='build-error'>" + html.EscapeString(i18n.Get(locale, "toolchain-error",
+				i18n.Params{"cmd": "protoc"}).(string)) + "</span>\n"
+			channelRet["lints"] = parseLints(curDir, strings.Join(lines, ""))
+		}
+		writeOutput(sid, channelRet)
+
+		logger.Debugf("User [%s, %s] done running [protoc] of [%s], succ [%v]", uid, sid, filePath, nil == err)
+	}()
+}
+
+// setWorkspaceBinPath prepends the bin directories of the workspaces of the user specified by the given user id to
+// PATH of the specified command, so the command finds the tools the user installed, such as protoc plugins.
+func setWorkspaceBinPath(cmd *exec.Cmd, uid string) {
+	bins := []string{}
+	for _, workspace := range filepath.SplitList(conf.GetUserWorkspace(uid)) {
+		bins = append(bins, filepath.Join(workspace, "bin"))
+	}
+
+	for i := len(cmd.Env) - 1; 0 <= i; i-- {
+		if strings.HasPrefix(cmd.Env[i], "PATH=") {
+			// the last PATH is used if duplicated
+			cmd.Env = append(cmd.Env, "PATH="+strings.Join(bins, conf.PathListSeparator)+conf.PathListSeparator+
+				strings.TrimPrefix(cmd.Env[i], "PATH="))
+
+			return
+		}
+	}
+}
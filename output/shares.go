@@ -0,0 +1,241 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// share represents a grant of watching the output channel of a wide session, all the build, run and go tool output
+// written to the channel is relayed to the watching sessions.
+type share struct {
+	id       string          // share id, told to the watcher instead of the shared session id
+	owner    string          // user id of the owner
+	watcher  string          // user id of the user granted
+	watching map[string]bool // sessions of the watcher watching the output
+}
+
+var (
+	// shared output channels, <shared sid, *share>
+	shares = map[string]*share{}
+
+	// guards shares
+	sharesMutex sync.Mutex
+)
+
+// Load registers the handlers of output sharing messages sent via session channels.
+//
+//  1. output-watch: starts watching the shared output "id", replied with output-watching
+//  2. output-unwatch: stops watching the shared output "id"
+//
+// Sessions of the watcher are told a granted share with output-shared and a revoked one with output-unshared, the
+// output is relayed to the watching sessions with shared-output messages, the original output message is "data".
+func Load() {
+	session.SessionMessageHandlers["output-watch"] = watchOutput
+	session.SessionMessageHandlers["output-unwatch"] = unwatchOutput
+	session.SessionClosedHandlers = append(session.SessionClosedHandlers, closeShares)
+}
+
+// ShareHandler handles request of granting the user specified by "user" (name or id) read access to the output
+// channel of the session "sid", the previous grant of the session is revoked.
+func ShareHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid, _ := args["sid"].(string)
+	if wSession := session.WideSessions.Get(sid); nil == wSession || uid != wSession.UserId {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	name, _ := args["user"].(string)
+	watcher := findUser(strings.TrimSpace(name))
+	if nil == watcher || uid == watcher.Id {
+		result.Code = -1
+		result.Msg = i18n.Get(conf.GetUser(uid).Locale, "user-not-found").(string)
+
+		return
+	}
+
+	owner := conf.GetUser(uid)
+
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	revokeShare(sid)
+	shares[sid] = &share{id: gulu.Rand.String(16), owner: uid, watcher: watcher.Id, watching: map[string]bool{}}
+	for _, s := range session.WideSessions.GetByUserId(watcher.Id) {
+		writeSessionMessage(s.ID, map[string]interface{}{"cmd": "output-shared", "id": shares[sid].id,
+			"user": owner.Name})
+	}
+
+	result.Data = watcher.Name
+}
+
+// UnshareHandler handles request of revoking the grant of the output channel of the session "sid".
+func UnshareHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	sid, _ := args["sid"].(string)
+	if wSession := session.WideSessions.Get(sid); nil == wSession || uid != wSession.UserId {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	revokeShare(sid)
+}
+
+// watchOutput handles output-watch messages.
+func watchOutput(sid, uid string, message map[string]interface{}) {
+	id, _ := message["id"].(string)
+
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	for _, s := range shares {
+		if id == s.id && uid == s.watcher {
+			s.watching[sid] = true
+			owner := s.owner
+			if u := conf.GetUser(owner); nil != u {
+				owner = u.Name
+			}
+			writeSessionMessage(sid, map[string]interface{}{"cmd": "output-watching", "id": id, "user": owner})
+
+			return
+		}
+	}
+
+	writeSessionMessage(sid, map[string]interface{}{"cmd": "output-unshared", "id": id})
+}
+
+// unwatchOutput handles output-unwatch messages.
+func unwatchOutput(sid, uid string, message map[string]interface{}) {
+	id, _ := message["id"].(string)
+
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	for _, s := range shares {
+		if id == s.id {
+			delete(s.watching, sid)
+		}
+	}
+}
+
+// mirrorOutput relays the specified message written to the output channel of the specified session to the sessions
+// watching it.
+func mirrorOutput(sid string, v interface{}) {
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	s := shares[sid]
+	if nil == s {
+		return
+	}
+
+	for watching := range s.watching {
+		writeSessionMessage(watching, map[string]interface{}{"cmd": "shared-output", "id": s.id, "data": v})
+	}
+}
+
+// closeShares revokes the grant of the specified session and stops the watching of it after its session channel
+// closed.
+func closeShares(sid string) {
+	sharesMutex.Lock()
+	defer sharesMutex.Unlock()
+
+	revokeShare(sid)
+	for _, s := range shares {
+		delete(s.watching, sid)
+	}
+}
+
+// revokeShare revokes the grant of the output channel of the specified session. sharesMutex should be locked by the
+// caller.
+func revokeShare(sid string) {
+	s := shares[sid]
+	if nil == s {
+		return
+	}
+
+	delete(shares, sid)
+	for _, ws := range session.WideSessions.GetByUserId(s.watcher) {
+		writeSessionMessage(ws.ID, map[string]interface{}{"cmd": "output-unshared", "id": s.id})
+	}
+}
+
+// findUser returns the user of the specified name or id, returns nil if not found.
+func findUser(name string) *conf.User {
+	for _, user := range conf.GetUsers() {
+		if name == user.Name || name == user.Id {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// writeSessionMessage writes the specified message to the session channel of the specified session.
+func writeSessionMessage(sid string, message map[string]interface{}) {
+	if wsChannel := session.SessionWS[sid]; nil != wsChannel {
+		wsChannel.WriteJSON(&message)
+	}
+}
@@ -24,12 +24,15 @@ import (
 	"net/http"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
 	"github.com/kwokhunglee/wide/file"
 	"github.com/kwokhunglee/wide/gulu"
 	"github.com/kwokhunglee/wide/i18n"
 	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
 )
 
 // GoTestHandler handles request of go test.
@@ -44,6 +47,7 @@ func GoTestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	uid := httpSession.Values["uid"].(string)
+	requestId := util.RequestId(r)
 	locale := conf.GetUser(uid).Locale
 
 	var args map[string]interface{}
@@ -107,7 +111,13 @@ func GoTestHandler(w http.ResponseWriter, r *http.Request) {
 
 	reader := bufio.NewReader(io.MultiReader(stdout, stderr))
 
+	// waits for a worker, released once go test exits
+	release := acquireWorker(sid, locale)
+
+	jobStarted(sid, &event.Job{Name: "test", UserId: uid, Path: curDir, RequestId: requestId})
+	started := time.Now()
 	if err := cmd.Start(); nil != err {
+		release()
 		logger.Error(err)
 		result.Code = -1
 
@@ -116,8 +126,10 @@ func GoTestHandler(w http.ResponseWriter, r *http.Request) {
 
 	go func(runningId int) {
 		defer gulu.Panic.Recover(nil)
+		defer release()
 
-		logger.Debugf("User [%s, %s] is running [go test] [runningId=%d]", uid, sid, runningId)
+		logger.Debugf("User [%s, %s] is running [go test] [runningId=%d] [requestId=%s]", uid, sid, runningId,
+			requestId)
 
 		channelRet := map[string]interface{}{}
 		channelRet["cmd"] = "go test"
@@ -128,12 +140,17 @@ func GoTestHandler(w http.ResponseWriter, r *http.Request) {
 		// waiting for go test finished
 		cmd.Wait()
 
+		jobDone(sid, &event.Job{Name: "test", UserId: uid, Path: curDir, RequestId: requestId,
+			Succ: cmd.ProcessState.Success(), Output: string(buf)}, started)
+
 		if !cmd.ProcessState.Success() {
-			logger.Debugf("User [%s, %s] 's running [go test] [runningId=%d] has done (with error)", uid, sid, runningId)
+			logger.Debugf("User [%s, %s] 's running [go test] [runningId=%d] has done (with error) [requestId=%s]",
+				uid, sid, runningId, requestId)
 
 			channelRet["output"] = "<span class='test-error'>" + i18n.Get(locale, "test-error").(string) + "</span>\n" + string(buf)
 		} else {
-			logger.Debugf("User [%s, %s] 's running [go test] [runningId=%d] has done", uid, sid, runningId)
+			logger.Debugf("User [%s, %s] 's running [go test] [runningId=%d] has done [requestId=%s]", uid,
+				sid, runningId, requestId)
 
 			channelRet["output"] = "<span class='test-succ'>" + i18n.Get(locale, "test-succ").(string) + "</span>\n" + string(buf)
 		}
@@ -0,0 +1,199 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"html"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// toolchainBuild builds the specified file with the build command of the specified toolchain, the output is sent to
+// the output channel of the specified wide session as go build does. The file is run next (see session.RunHandler) if
+// the build succeeded and nextCmd is "run", toolchains without a build command run the file directly.
+func toolchainBuild(sid string, user *conf.User, toolchain *conf.Toolchain, filePath, requestId string,
+	nextCmd interface{}) {
+	curDir := filepath.Dir(filePath)
+	locale := user.Locale
+
+	channelRet := map[string]interface{}{"cmd": "build", "executable": filePath}
+	if 1 > len(toolchain.Build) {
+		channelRet["nextCmd"] = nextCmd
+		channelRet["output"] = ""
+		writeOutput(sid, channelRet)
+
+		return
+	}
+
+	cmd := toolchainCmd(toolchain, toolchain.Build, filePath, user.Id)
+	cmdLine := strings.Join(cmd.Args, " ")
+	writeOutput(sid, map[string]interface{}{"cmd": "start-build",
+		"output": "<span class='start-build'>" + html.EscapeString(i18n.Get(locale, "start-toolchain",
+			i18n.Params{"cmd": cmdLine}).(string)) + "</span>\n"})
+
+	// waits for a worker, released once the build exits
+	release := acquireWorker(sid, locale)
+	defer release()
+
+	jobStarted(sid, &event.Job{Name: "build", UserId: user.Id, Path: curDir, RequestId: requestId})
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); nil != err && !ok {
+		logger.Error(err)
+		out = append(out, []byte(err.Error()+"\n")...)
+	}
+	succ := nil == err
+	jobDone(sid, &event.Job{Name: "build", UserId: user.Id, Path: curDir, RequestId: requestId, Succ: succ,
+		Output: string(out)}, started)
+
+	output := toolchainOutput(curDir, string(out))
+	if succ {
+		channelRet["nextCmd"] = nextCmd
+		channelRet["output"] = output + "<span class='build-succ'>" + html.EscapeString(i18n.Get(locale,
+			"toolchain-succ", i18n.Params{"cmd": toolchain.Build[0]}).(string)) + "</span>\n"
+	} else {
+		channelRet["output"] = output + "<span class='build-error'>" + html.EscapeString(i18n.Get(locale,
+			"toolchain-error", i18n.Params{"cmd": toolchain.Build[0]}).(string)) + "</span>\n"
+		channelRet["lints"] = parseLints(curDir, string(out))
+	}
+	writeOutput(sid, channelRet)
+}
+
+// toolchainLint lints the specified file with the lint command of the specified toolchain in the background, the
+// output is sent to the output channel of the specified wide session as go vet does.
+func toolchainLint(sid string, user *conf.User, toolchain *conf.Toolchain, filePath string) {
+	locale := user.Locale
+	if 1 > len(toolchain.Lint) {
+		writeOutput(sid, map[string]interface{}{"cmd": "start-vet",
+			"output": "<span class='vet-error'>" + html.EscapeString(i18n.Get(locale, "toolchain-no-lint",
+				i18n.Params{"name": toolchain.Name}).(string)) + "</span>\n"})
+
+		return
+	}
+
+	cmd := toolchainCmd(toolchain, toolchain.Lint, filePath, user.Id)
+	writeOutput(sid, map[string]interface{}{"cmd": "start-vet",
+		"output": "<span class='start-vet'>" + html.EscapeString(i18n.Get(locale, "start-toolchain",
+			i18n.Params{"cmd": strings.Join(cmd.Args, " ")}).(string)) + "</span>\n"})
+
+	// waits for a worker, released once the lint exits
+	release := acquireWorker(sid, locale)
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+		defer release()
+
+		out, err := cmd.CombinedOutput()
+		if _, ok := err.(*exec.ExitError); nil != err && !ok {
+			logger.Error(err)
+			out = append(out, []byte(err.Error()+"\n")...)
+		}
+
+		key := "toolchain-succ"
+		class := "vet-succ"
+		if nil != err {
+			key = "toolchain-error"
+			class = "vet-error"
+		}
+		msg := i18n.Get(locale, key, i18n.Params{"cmd": toolchain.Lint[0]}).(string)
+		writeOutput(sid, map[string]interface{}{"cmd": "go vet", "output": "<span class='" + class + "'>" +
+			html.EscapeString(msg) + "</span>\n" + toolchainOutput(filepath.Dir(filePath), string(out))})
+	}()
+}
+
+// toolchainCmd returns the specified command of the specified toolchain for the specified file. The command runs in
+// the directory of the file and the file is passed by its name, so paths in the output are relative to the directory.
+func toolchainCmd(toolchain *conf.Toolchain, command []string, filePath, uid string) *exec.Cmd {
+	args := toolchain.Command(command, filepath.Base(filePath))
+	ret := exec.Command(args[0], args[1:]...)
+	ret.Dir = filepath.Dir(filePath)
+	setCmdEnv(ret, uid)
+
+	return ret
+}
+
+// toolchainOutput escapes the specified output of a toolchain command and makes file paths in it clickable.
+func toolchainOutput(curDir, output string) string {
+	buf := strings.Builder{}
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if "" == line {
+			continue
+		}
+
+		buf.WriteString(parsePath(curDir, html.EscapeString(line)))
+	}
+
+	return buf.String()
+}
+
+// parseLints parses lints of the lines "{file}:{line}[:{column}]: [error: |warning: |note: ]{message}" in the specified
+// output of a toolchain command, paths of the files are relative to the specified directory. Notes are skipped.
+func parseLints(curDir, output string) []*Lint {
+	ret := []*Lint{}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, ":", 4)
+		if 3 > len(parts) || "" == parts[0] {
+			continue
+		}
+
+		lineNo, err := strconv.Atoi(parts[1])
+		if nil != err {
+			continue
+		}
+
+		msg := strings.Join(parts[2:], ":")
+		if 4 == len(parts) {
+			if _, err := strconv.Atoi(parts[2]); nil == err { // column
+				msg = parts[3]
+			}
+		}
+		msg = strings.TrimSpace(msg)
+		if strings.HasPrefix(msg, "note:") { // notes of the previous lint
+			continue
+		}
+
+		severity := lintSeverityError
+		if strings.HasPrefix(msg, "warning:") {
+			severity = lintSeverityWarn
+		}
+
+		ret = append(ret, &Lint{File: filepath.ToSlash(filepath.Join(curDir, parts[0])), LineNo: lineNo - 1,
+			Severity: severity, Msg: msg})
+	}
+
+	return ret
+}
+
+// writeOutput writes the specified message to the output channel of the specified wide session.
+func writeOutput(sid string, channelRet map[string]interface{}) {
+	wsChannel := session.OutputWS[sid]
+	if nil == wsChannel {
+		return
+	}
+
+	if err := wsChannel.WriteJSON(&channelRet); nil != err {
+		logger.Warn(err)
+	}
+	wsChannel.Refresh()
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/file"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// maxTriggerEventSize is the max size of the body of an event posted to a trigger.
+const maxTriggerEventSize = 10 << 20
+
+// triggerMutexes serializes the runs of each trigger, <trigger id, *sync.Mutex>.
+var triggerMutexes sync.Map
+
+// TriggerHandler handles push events of GitHub/GitLab posted to the trigger (see conf.Trigger) of path /hook/{id}.
+//
+// The event is verified with the secret of the trigger, then the repository of the project is pulled and the job of
+// the trigger is run in the background. Results are published as finished jobs ("pull", "build" or "test"), so they
+// are posted to the webhooks and Web Push subscriptions of the user.
+func TriggerHandler(w http.ResponseWriter, r *http.Request) {
+	if http.MethodPost != r.Method {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	user, trigger := conf.GetTrigger(strings.TrimPrefix(r.URL.Path, "/hook/"))
+	if nil == trigger {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxTriggerEventSize))
+	if nil != err {
+		logger.Warn(err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+
+	if !trigger.Verify(r.Header.Get("X-Hub-Signature-256"), r.Header.Get("X-Gitlab-Token"), body) {
+		logger.Warnf("Refused unverified event of trigger [%s] from [%s]", trigger.Id, r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	switch eventType := r.Header.Get("X-GitHub-Event") + r.Header.Get("X-Gitlab-Event"); eventType {
+	case "ping":
+		w.Write([]byte("pong"))
+
+		return
+	case "push", "Push Hook":
+	default:
+		w.Write([]byte("ignored event [" + eventType + "]"))
+
+		return
+	}
+
+	push := struct {
+		Ref string `json:"ref"`
+	}{}
+	if err := json.Unmarshal(body, &push); nil != err {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+
+		return
+	}
+	if !trigger.Accepts(push.Ref) {
+		w.Write([]byte("ignored ref [" + push.Ref + "]"))
+
+		return
+	}
+
+	requestId := util.RequestId(r)
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		runTrigger(user, trigger, requestId)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runTrigger pulls the repository of the project of the specified trigger and then runs the job of the trigger.
+func runTrigger(user *conf.User, trigger *conf.Trigger, requestId string) {
+	mutex, _ := triggerMutexes.LoadOrStore(trigger.Id, &sync.Mutex{})
+	mutex.(*sync.Mutex).Lock()
+	defer mutex.(*sync.Mutex).Unlock()
+
+	workspaces := filepath.SplitList(user.WorkspacePath())
+	if 1 > len(workspaces) {
+		return
+	}
+	repo := filepath.Join(workspaces[0], "src", filepath.FromSlash(trigger.Project))
+	if !gulu.File.IsDir(repo) {
+		logger.Warnf("Project [%s] of trigger [%s] is not found", repo, trigger.Id)
+
+		return
+	}
+
+	logger.Infof("Trigger [%s] of user [%s] is pulling [%s] [requestId=%s]", trigger.Id, user.Id, repo, requestId)
+
+	jobStarted("", &event.Job{Name: "pull", UserId: user.Id, Path: repo, RequestId: requestId})
+	started := time.Now()
+	output, err := file.GitPull(user, repo)
+	jobDone("", &event.Job{Name: "pull", UserId: user.Id, Path: repo, RequestId: requestId, Succ: nil == err,
+		Output: output}, started)
+	if nil != err || "" == trigger.Job {
+		return
+	}
+
+	cmd := exec.Command("go", goJobArgs(trigger.Job, user)...)
+	cmd.Dir = repo
+	setCmdEnv(cmd, user.Id)
+
+	// waits for a worker, released once the job exits
+	release := acquireWorker("", user.Locale)
+	defer release()
+
+	jobStarted("", &event.Job{Name: trigger.Job, UserId: user.Id, Path: repo, RequestId: requestId})
+	started = time.Now()
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); nil != err && !ok {
+		logger.Error(err)
+	}
+	jobDone("", &event.Job{Name: trigger.Job, UserId: user.Id, Path: repo, RequestId: requestId, Succ: nil == err,
+		Output: string(out)}, started)
+}
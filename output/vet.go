@@ -61,6 +61,13 @@ func GoVetHandler(w http.ResponseWriter, r *http.Request) {
 	filePath, _ := file.GetPath(uid, args["file"].(string), fmt.Sprint(args["pathtype"]))
 	curDir := filepath.Dir(filePath)
 
+	// files of other languages are linted by their toolchains
+	if toolchain := conf.Wide().Toolchain(filePath); nil != toolchain {
+		toolchainLint(sid, conf.GetUser(uid), toolchain, filePath)
+
+		return
+	}
+
 	cmd := exec.Command("go", "vet", ".")
 	cmd.Dir = curDir
 
@@ -107,7 +114,11 @@ func GoVetHandler(w http.ResponseWriter, r *http.Request) {
 
 	reader := bufio.NewReader(io.MultiReader(stdout, stderr))
 
+	// waits for a worker, released once go vet exits
+	release := acquireWorker(sid, locale)
+
 	if err := cmd.Start(); nil != err {
+		release()
 		logger.Error(err)
 		result.Code = -1
 
@@ -116,6 +127,7 @@ func GoVetHandler(w http.ResponseWriter, r *http.Request) {
 
 	go func(runningId int) {
 		defer gulu.Panic.Recover(nil)
+		defer release()
 
 		logger.Debugf("User [%s, %s] is running [go vet] [runningId=%d]", uid, sid, runningId)
 
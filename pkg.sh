@@ -9,7 +9,7 @@
 
 ver=$1
 target=$2
-list="conf doc i18n static views README.md TERMS.md LICENSE"
+list="conf doc i18n README.md TERMS.md LICENSE" # static and views are embedded in the binary
 
 mkdir -p ${target}
 
@@ -20,9 +20,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 	"os/exec"
-	"path/filepath"
 
 	"github.com/kwokhunglee/wide/gulu"
 	"github.com/kwokhunglee/wide/conf"
@@ -83,11 +81,8 @@ func SaveHandler(w http.ResponseWriter, r *http.Request) {
 	fileName += ".go"
 	data["fileName"] = fileName
 
-	// Step3. write file
-	filePath := filepath.Clean(conf.Wide.Data + "/playground/" + fileName)
-	fout, err := os.Create(filePath)
-	fout.WriteString(code)
-	if err := fout.Close(); nil != err {
+	// Step3. save snippet
+	if err := conf.GetStore().SaveSnippet(fileName, []byte(code)); nil != err {
 		logger.Error(err)
 		result.Code = -1
 
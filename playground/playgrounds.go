@@ -17,11 +17,9 @@ package playground
 
 import (
 	"html/template"
-	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -46,12 +44,12 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 		httpSession.Values["uid"] = "playground"
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	uid := httpSession.Values["uid"].(string)
 
-	locale := conf.Wide.Locale
+	locale := conf.Wide().Locale
 
 	// try to load file
 	code := conf.HelloWorld
@@ -59,9 +57,8 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 
 	if strings.HasSuffix(r.URL.Path, ".go") {
 		fileNameArg := r.URL.Path[len("/playground/"):]
-		filePath := filepath.Clean(conf.Wide.Data + "/playground/" + fileNameArg)
 
-		bytes, err := ioutil.ReadFile(filePath)
+		bytes, err := conf.GetStore().GetSnippet(fileNameArg)
 		if nil != err {
 			logger.Warn(err)
 		} else {
@@ -83,7 +80,7 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 		disqus = true
 	}
 
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(locale), "locale": locale,
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(locale), "locale": locale,
 		"sid": session.WideSessions.GenId(), "pathSeparator": conf.PathSeparator,
 		"codeMirrorVer": conf.CodeMirrorVer,
 		"code":          template.HTML(code), "ver": conf.WideVersion, "year": time.Now().Year(),
@@ -93,7 +90,7 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 
 	logger.Debugf("User [%s] has [%d] sessions", uid, len(wideSessions))
 
-	t, err := template.ParseFiles("views/playground/index.html")
+	t, err := template.ParseFS(conf.Assets(), "views/playground/index.html")
 	if nil != err {
 		logger.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -120,4 +117,7 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 	session.PlaygroundWS[sid] = &wsChan
 
 	logger.Tracef("Open a new [PlaygroundWS] with session [%s], %d", sid, len(session.PlaygroundWS))
+
+	wsChan.KeepAlive()
+	session.ReleaseChannel(session.ChannelPlayground, sid, &wsChan)
 }
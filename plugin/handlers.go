@@ -0,0 +1,218 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+const (
+	// actionTimeout is the max time waiting for a plugin to run an action.
+	actionTimeout = 5 * time.Minute
+	// httpTimeout is the max time waiting for a plugin to respond an HTTP request.
+	httpTimeout = time.Minute
+	// maxHTTPBodySize is the max size of the body of an HTTP request to a plugin.
+	maxHTTPBodySize = 10 << 20
+)
+
+// HTTPRequest represents an HTTP request to /plugins/{name}/..., params of "http".
+type HTTPRequest struct {
+	UserId string      `json:"userId"` // id of the user
+	Method string      `json:"method"` // such as "GET"
+	Path   string      `json:"path"`   // path after /plugins/{name}, such as "/", "/status"
+	Query  string      `json:"query"`  // raw query without '?'
+	Header http.Header `json:"header"` // header, cookies excluded
+	Body   string      `json:"body"`   // body
+}
+
+// HTTPResponse represents the response of a plugin to an HTTPRequest.
+type HTTPResponse struct {
+	Status int               `json:"status"` // status code, 200 if 0
+	Header map[string]string `json:"header"` // header, such as {"Content-Type": "text/html; charset=utf-8"}
+	Body   string            `json:"body"`   // body
+}
+
+// ActionRequest represents an action run by a user on a node of the file tree, params of "action".
+type ActionRequest struct {
+	UserId string `json:"userId"` // id of the user
+	Action string `json:"action"` // id of the action
+	Path   string `json:"path"`   // path of the node
+}
+
+// ActionResult represents the result of an action responded by a plugin.
+type ActionResult struct {
+	Message string `json:"message"` // message shown to the user, "" for nothing
+	Refresh bool   `json:"refresh"` // whether to refresh the node (the parent directory of a file) in the file tree
+}
+
+// pluginAction represents an action with the plugin name.
+type pluginAction struct {
+	*Action
+	Plugin string `json:"plugin"`
+}
+
+// ActionsHandler handles request of listing the actions of plugins on nodes of the file tree.
+func ActionsHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	actions := []*pluginAction{}
+	for _, name := range pluginNames {
+		proc, err := plugins[name].get()
+		if nil != err {
+			continue
+		}
+
+		for _, action := range proc.manifest.Actions {
+			actions = append(actions, &pluginAction{Action: action, Plugin: name})
+		}
+	}
+
+	result.Data = actions
+}
+
+// ActionHandler handles request of running an action of a plugin on a node of the file tree.
+func ActionHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	name, _ := args["plugin"].(string)
+	action, _ := args["action"].(string)
+	path, _ := args["path"].(string)
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) || !session.CanAccess(uid, path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	p := plugins[name]
+	if nil == p {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	proc, err := p.get()
+	if nil != err {
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	ret := &ActionResult{}
+	if err := proc.call("action", &ActionRequest{UserId: uid, Action: action, Path: path}, ret,
+		actionTimeout); nil != err {
+		logger.Warnf("Runs action [%s] of plugin [%s] on [%s] failed: %s", action, name, path, err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	logger.Debugf("User [%s] ran action [%s] of plugin [%s] on [%s]", uid, action, name, path)
+	result.Data = ret
+}
+
+// ServeHandler handles HTTP requests to /plugins/{name}/..., the requests are passed to the plugin of the name.
+func ServeHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	name, path := strings.TrimPrefix(r.URL.Path, "/plugins/"), "/"
+	if i := strings.Index(name, "/"); -1 < i {
+		name, path = name[:i], name[i:]
+	}
+
+	p := plugins[name]
+	if nil == p {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	proc, err := p.get()
+	if nil != err || !proc.manifest.HTTP {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodySize))
+	if nil != err {
+		http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	header := r.Header.Clone()
+	header.Del("Cookie")
+	header.Del("Authorization")
+
+	resp := &HTTPResponse{}
+	if err := proc.call("http", &HTTPRequest{UserId: uid, Method: r.Method, Path: path, Query: r.URL.RawQuery,
+		Header: header, Body: string(body)}, resp, httpTimeout); nil != err {
+		logger.Warnf("Plugin [%s] failed to serve [%s %s]: %s", name, r.Method, r.URL.Path, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+
+		return
+	}
+
+	for k, v := range resp.Header {
+		w.Header().Set(k, v)
+	}
+	if 0 == resp.Status {
+		resp.Status = http.StatusOK
+	}
+	w.WriteHeader(resp.Status)
+	w.Write([]byte(resp.Body))
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin includes the plugins, subprocesses (see conf.Plugin) extending Wide without changing it.
+//
+// Wide talks to a plugin with JSON-RPC 2.0, one message per line, over the stdin and stdout of the plugin process, the
+// stderr of the process is logged. Wide calls these methods:
+//
+//   - "initialize" once started, params {"version", "server"}, the plugin responds its Manifest
+//   - "file.changed" notification if hooked, params an event.FileChange
+//   - "job.started" and "job.done" notifications if hooked, params an event.Job
+//   - "http" for requests to /plugins/{name}/... if the plugin serves HTTP, params an HTTPRequest, the plugin responds
+//     an HTTPResponse
+//   - "action" when a user runs an Action of the plugin on a node of the file tree, params an ActionRequest, the plugin
+//     responds an ActionResult
+//   - "shutdown" notification before Wide exits, stdin is closed then
+//
+// A plugin exited is restarted on demand, at most once a minute.
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// restartInterval is the min interval between starts of a plugin.
+const restartInterval = time.Minute
+
+// Hooks of plugins.
+const (
+	HookFileChanged = "file.changed"
+	HookJobStarted  = "job.started"
+	HookJobDone     = "job.done"
+)
+
+// Manifest represents what a plugin provides, responded to "initialize".
+type Manifest struct {
+	Hooks   []string  `json:"hooks"`   // hooks notified, such as "file.changed"
+	HTTP    bool      `json:"http"`    // whether the plugin serves HTTP requests to /plugins/{name}/
+	Actions []*Action `json:"actions"` // actions on nodes of the file tree
+}
+
+// hooked determines whether the manifest hooks the specified hook.
+func (m *Manifest) hooked(hook string) bool {
+	for _, h := range m.Hooks {
+		if h == hook {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Action represents an action a plugin adds to the context menus of the file tree.
+type Action struct {
+	Id      string `json:"id"`      // id of the action, unique in the plugin
+	Label   string `json:"label"`   // label of the menu item
+	Target  string `json:"target"`  // "file", "dir" or "" for both
+	Pattern string `json:"pattern"` // name pattern of the nodes, such as "*.proto", "" for all
+}
+
+// plugin represents a configured plugin and its process.
+type plugin struct {
+	conf    *conf.Plugin
+	process *process  // nil if not started or failed to start
+	started time.Time // the latest start time
+	mutex   sync.Mutex
+}
+
+var (
+	// configured plugins, <name, *plugin>
+	plugins = map[string]*plugin{}
+
+	// names of the configured plugins in configuration order
+	pluginNames []string
+)
+
+// Load starts the configured plugins and subscribes them to events.
+func Load() {
+	for _, p := range conf.Wide().Plugins {
+		plugins[p.Name] = &plugin{conf: p}
+		pluginNames = append(pluginNames, p.Name)
+	}
+	if 1 > len(plugins) {
+		return
+	}
+
+	for _, name := range pluginNames {
+		if _, err := plugins[name].get(); nil != err {
+			logger.Errorf("Starts plugin [%s] failed: %s", name, err)
+		}
+	}
+
+	event.Subscribe(event.HandleFunc(notify), event.EvtCodeFileChanged, event.EvtCodeJobStarted, event.EvtCodeJobDone)
+}
+
+// Stop stops the running plugins.
+func Stop() {
+	wg := sync.WaitGroup{}
+	for _, name := range pluginNames {
+		p := plugins[name]
+		p.mutex.Lock()
+		proc := p.process
+		p.process = nil
+		p.mutex.Unlock()
+
+		if nil != proc && proc.alive() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				proc.stop()
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// notify notifies the plugins hooking the specified event.
+func notify(e *event.Event) {
+	var hook string
+	switch e.Code {
+	case event.EvtCodeFileChanged:
+		hook = HookFileChanged
+	case event.EvtCodeJobStarted:
+		hook = HookJobStarted
+	case event.EvtCodeJobDone:
+		hook = HookJobDone
+	default:
+		return
+	}
+
+	for _, name := range pluginNames {
+		proc, err := plugins[name].get()
+		if nil != err || !proc.manifest.hooked(hook) {
+			continue
+		}
+
+		proc.enqueue(hook, e.Data)
+	}
+}
+
+// get returns the process of the plugin, starts the process if it's not running and wasn't started in
+// restartInterval.
+func (p *plugin) get() (*process, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if nil != p.process && p.process.alive() {
+		return p.process, nil
+	}
+	if !p.started.IsZero() && time.Since(p.started) < restartInterval {
+		return nil, errExited
+	}
+
+	p.started = time.Now()
+	cmd := exec.Command(p.conf.Executable(conf.Wide().Data), p.conf.Command[1:]...)
+	cmd.Dir = conf.Wide().Data
+	cmd.Env = append(os.Environ(), p.conf.Env...)
+
+	proc, err := startProcess(p.conf.Name, cmd, map[string]string{"version": conf.WideVersion,
+		"server": conf.Wide().Server})
+	if nil != err {
+		p.process = nil
+
+		return nil, err
+	}
+
+	logger.Infof("Started plugin [%s], hooks %v, http [%v], %d actions", p.conf.Name, proc.manifest.Hooks,
+		proc.manifest.HTTP, len(proc.manifest.Actions))
+	p.process = proc
+
+	return proc, nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/kwokhunglee/wide/event"
+)
+
+// TestMain runs the test binary as a plugin if WIDE_TEST_PLUGIN is set.
+func TestMain(m *testing.M) {
+	if "" != os.Getenv("WIDE_TEST_PLUGIN") {
+		testPlugin()
+
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// testPlugin hooks file changes and answers "action" with the path of the latest file changed.
+func testPlugin() {
+	latest := ""
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		req := struct {
+			Id     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}{}
+		json.Unmarshal(scanner.Bytes(), &req)
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			fmt.Fprintln(os.Stderr, "initializing")
+			result = &Manifest{Hooks: []string{HookFileChanged},
+				Actions: []*Action{{Id: "latest", Label: "Latest", Target: "file"}}}
+		case HookFileChanged:
+			change := &event.FileChange{}
+			json.Unmarshal(req.Params, change)
+			latest = change.Path
+		case "action":
+			result = &ActionResult{Message: latest}
+		case "sleep":
+			time.Sleep(time.Second)
+		case "shutdown":
+			return
+		}
+
+		if nil != req.Id {
+			data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.Id, "result": result})
+			fmt.Println(string(data))
+		}
+	}
+}
+
+func startTestProcess(t *testing.T) *process {
+	exe, err := os.Executable()
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), "WIDE_TEST_PLUGIN=1")
+	ret, err := startProcess("test", cmd, map[string]string{"version": "test"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return ret
+}
+
+func TestProcess(t *testing.T) {
+	proc := startTestProcess(t)
+
+	if !proc.manifest.hooked(HookFileChanged) || proc.manifest.hooked(HookJobDone) {
+		t.Errorf("hooks should be [%s], got %v", HookFileChanged, proc.manifest.Hooks)
+	}
+	if 1 != len(proc.manifest.Actions) || "latest" != proc.manifest.Actions[0].Id {
+		t.Errorf("actions should be [latest], got %v", proc.manifest.Actions)
+	}
+
+	proc.enqueue(HookFileChanged, &event.FileChange{UserId: "1", Op: "save", Path: "/ws/src/a.go"})
+
+	// notifications are sent in order before later calls
+	time.Sleep(100 * time.Millisecond)
+	ret := &ActionResult{}
+	if err := proc.call("action", &ActionRequest{UserId: "1", Action: "latest", Path: "/ws/src"}, ret,
+		time.Second); nil != err {
+		t.Fatal(err)
+	}
+	if "/ws/src/a.go" != ret.Message {
+		t.Errorf("message should be [/ws/src/a.go], got [%s]", ret.Message)
+	}
+
+	if err := proc.call("sleep", nil, nil, 100*time.Millisecond); nil == err {
+		t.Error("call should time out")
+	}
+
+	proc.stop()
+	if proc.alive() {
+		t.Error("process should exit")
+	}
+	if err := proc.call("action", nil, nil, time.Second); nil == err {
+		t.Error("call of an exited process should fail")
+	}
+}
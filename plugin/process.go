@@ -0,0 +1,289 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+const (
+	// initializeTimeout is the max time waiting for a started plugin to respond its manifest.
+	initializeTimeout = 10 * time.Second
+	// stopTimeout is the max time waiting for a plugin to exit after notified of shutdown before killing it.
+	stopTimeout = 3 * time.Second
+	// maxQueuedNotifications is the max number of notifications queued for a plugin, more are dropped.
+	maxQueuedNotifications = 64
+	// maxMessageSize is the max size of a message from a plugin.
+	maxMessageSize = 16 << 20
+)
+
+// errExited is returned by calls of a plugin which has exited.
+var errExited = errors.New("plugin exited")
+
+// request is a JSON-RPC 2.0 request, a notification if it has no id.
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Id      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC 2.0 response.
+type response struct {
+	Id     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcError is the error of a JSON-RPC 2.0 response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// process is a running plugin process, it's called with JSON-RPC 2.0 messages (one per line) over its stdin and
+// responds over its stdout.
+type process struct {
+	name     string
+	cmd      *exec.Cmd
+	manifest *Manifest
+
+	stdin      io.WriteCloser
+	writeMutex sync.Mutex // serializes writing messages
+
+	nextId       int64
+	pending      map[int64]chan *response // calls waiting for responses by request ids
+	pendingMutex sync.Mutex
+
+	notifications chan *request // queued notifications, sent in order
+	exited        chan struct{} // closed once the process exited
+}
+
+// startProcess starts a plugin process of the specified name with the specified command, and initializes it with the
+// specified params.
+func startProcess(name string, cmd *exec.Cmd, params interface{}) (*process, error) {
+	stdin, err := cmd.StdinPipe()
+	if nil != err {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if nil != err {
+		return nil, err
+	}
+	cmd.Stderr = &logWriter{name: name}
+
+	if err := cmd.Start(); nil != err {
+		return nil, err
+	}
+
+	ret := &process{name: name, cmd: cmd, stdin: stdin, pending: map[int64]chan *response{},
+		notifications: make(chan *request, maxQueuedNotifications), exited: make(chan struct{})}
+	go ret.read(stdout)
+	go ret.notify()
+
+	manifest := &Manifest{}
+	if err := ret.call("initialize", params, manifest, initializeTimeout); nil != err {
+		ret.stop()
+
+		return nil, fmt.Errorf("initializes plugin [%s] failed: %s", name, err)
+	}
+	ret.manifest = manifest
+
+	return ret, nil
+}
+
+// call calls the specified method with the specified params, the result is decoded into the specified value if it's
+// not nil. Returns an error if the process doesn't respond in the specified timeout.
+func (p *process) call(method string, params, result interface{}, timeout time.Duration) error {
+	p.pendingMutex.Lock()
+	p.nextId++
+	id := p.nextId
+	ch := make(chan *response, 1)
+	p.pending[id] = ch
+	p.pendingMutex.Unlock()
+
+	defer func() {
+		p.pendingMutex.Lock()
+		delete(p.pending, id)
+		p.pendingMutex.Unlock()
+	}()
+
+	if err := p.write(&request{JSONRPC: "2.0", Id: &id, Method: method, Params: params}); nil != err {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		if nil != resp.Error {
+			return resp.Error
+		}
+		if nil == result || 0 == len(resp.Result) {
+			return nil
+		}
+
+		return json.Unmarshal(resp.Result, result)
+	case <-p.exited:
+		return errExited
+	case <-timer.C:
+		return fmt.Errorf("%s timeout in %s", method, timeout)
+	}
+}
+
+// enqueue queues a notification of the specified method with the specified params, the notification is dropped if the
+// queue is full (the process is too slow).
+func (p *process) enqueue(method string, params interface{}) {
+	select {
+	case p.notifications <- &request{JSONRPC: "2.0", Method: method, Params: params}:
+	default:
+		logger.Warnf("Dropped notification [%s] of plugin [%s], the queue is full", method, p.name)
+	}
+}
+
+// notify sends the queued notifications until the process exited.
+func (p *process) notify() {
+	defer gulu.Panic.Recover(nil)
+
+	for {
+		select {
+		case n := <-p.notifications:
+			if err := p.write(n); nil != err {
+				logger.Warnf("Sends notification [%s] to plugin [%s] failed: %s", n.Method, p.name, err)
+			}
+		case <-p.exited:
+			return
+		}
+	}
+}
+
+// write writes the specified message as a line.
+func (p *process) write(msg *request) error {
+	data, err := json.Marshal(msg)
+	if nil != err {
+		return err
+	}
+
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+
+	_, err = p.stdin.Write(append(data, '\n'))
+
+	return err
+}
+
+// read reads the responses from the specified stdout of the process until it's closed, then waits for the process to
+// exit.
+func (p *process) read(stdout io.Reader) {
+	defer gulu.Panic.Recover(nil)
+	defer close(p.exited)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxMessageSize)
+	for scanner.Scan() {
+		resp := &response{}
+		if err := json.Unmarshal(scanner.Bytes(), resp); nil != err || nil == resp.Id {
+			msg := scanner.Text()
+			if 256 < len(msg) {
+				msg = msg[:256] + "..."
+			}
+			logger.Warnf("Ignored message of plugin [%s]: %s", p.name, msg)
+
+			continue
+		}
+
+		p.pendingMutex.Lock()
+		ch := p.pending[*resp.Id]
+		p.pendingMutex.Unlock()
+		if nil != ch {
+			ch <- resp
+		}
+	}
+	if err := scanner.Err(); nil != err {
+		logger.Warnf("Reads plugin [%s] failed: %s", p.name, err)
+	}
+
+	if err := p.cmd.Wait(); nil != err {
+		logger.Warnf("Plugin [%s] exited: %s", p.name, err)
+	} else {
+		logger.Infof("Plugin [%s] exited", p.name)
+	}
+}
+
+// alive determines whether the process is running.
+func (p *process) alive() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop notifies the process of shutdown and closes its stdin, kills it if it doesn't exit in stopTimeout.
+func (p *process) stop() {
+	p.write(&request{JSONRPC: "2.0", Method: "shutdown"})
+	p.stdin.Close()
+
+	select {
+	case <-p.exited:
+	case <-time.After(stopTimeout):
+		if err := p.cmd.Process.Kill(); nil != err && !errors.Is(err, os.ErrProcessDone) {
+			logger.Warnf("Kills plugin [%s] failed: %s", p.name, err)
+		}
+		<-p.exited
+	}
+}
+
+// logWriter logs the lines written to it (stderr of a plugin process).
+type logWriter struct {
+	name string
+	buf  []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if -1 == i {
+			break
+		}
+
+		logger.Infof("[plugin %s] %s", w.name, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	if maxMessageSize < len(w.buf) { // a line too long
+		w.buf = w.buf[:0]
+	}
+
+	return len(p), nil
+}
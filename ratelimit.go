@@ -0,0 +1,124 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// groupLimiter is the rate limiter of a handler group, created with the configured limit.
+type groupLimiter struct {
+	limit   conf.RateLimit
+	limiter *util.RateLimiter
+}
+
+var (
+	// rate limiters by handler groups, recreated after the configured limits changed. <group, *groupLimiter>
+	limiters = map[string]*groupLimiter{}
+
+	// numbers of rejected requests by handler groups. <group, count>
+	limitedCounts = map[string]int64{}
+
+	// guards limiters and limitedCounts
+	limitersMutex sync.Mutex
+)
+
+// rateLimitWrapper wraps the process with the rate limit of the specified handler group (Wide.RateLimits), responds
+// 429 if the limit is exceeded.
+func rateLimitWrapper(group string, handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := groupRateLimiter(group)
+		if nil == limiter {
+			handler(w, r)
+
+			return
+		}
+
+		key := rateLimitKey(r)
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			limitersMutex.Lock()
+			limitedCounts[group]++
+			limitersMutex.Unlock()
+
+			logger.Debugf("Rate limited [%s] of [%s]", r.URL.Path, key)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// groupRateLimiter gets the rate limiter of the specified handler group, returns nil if the group is not limited.
+func groupRateLimiter(group string) *util.RateLimiter {
+	limit := conf.Wide().RateLimits[group]
+	if nil == limit || 0 >= limit.PerMinute {
+		return nil
+	}
+
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	l := limiters[group]
+	if nil == l || l.limit != *limit {
+		burst := limit.Burst
+		if 0 >= burst {
+			burst = int(math.Ceil(limit.PerMinute))
+		}
+		l = &groupLimiter{limit: *limit, limiter: util.NewRateLimiter(limit.PerMinute, burst)}
+		limiters[group] = l
+	}
+
+	return l.limiter
+}
+
+// rateLimitKey returns the key the specified request is limited by, the user id for signed in users, the IP otherwise.
+func rateLimitKey(r *http.Request) string {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if uid, _ := httpSession.Values["uid"].(string); !httpSession.IsNew && "" != uid && "playground" != uid {
+		return "user:" + uid
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if nil != err {
+		ip = r.RemoteAddr
+	}
+
+	return "ip:" + ip
+}
+
+// rateLimitedCounts returns numbers of requests rejected by rate limits by handler groups.
+func rateLimitedCounts() map[string]int64 {
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+
+	ret := map[string]int64{}
+	for group, count := range limitedCounts {
+		ret[group] = count
+	}
+
+	return ret
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// SendHandler handles request of sending a HTTP request from the server's network.
+//
+// Arguments: "method" (defaults to "GET"), "url", optional "headers" ([{"name", "value"}]) and "body". Responds the
+// status, headers, body and timing of the response (see Response), the body is truncated at RESTClient.MaxBodySize.
+func SendHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if "playground" == uid { // user [playground] is a reserved mock user
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	request := &Request{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+	request.Method = strings.ToUpper(strings.TrimSpace(request.Method))
+	if "" == request.Method {
+		request.Method = http.MethodGet
+	}
+	for _, header := range request.Headers {
+		if nil == header {
+			result.Code = -1
+
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), conf.Wide().RESTClientTimeout())
+	defer cancel()
+
+	logger.Infof("User [%s] is sending request [%s %s]", uid, request.Method, request.URL)
+	response, err := send(ctx, newClient(conf.Wide().RESTClient), request, conf.Wide().RESTClientMaxBodySize())
+	if nil != err {
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	result.Data = response
+}
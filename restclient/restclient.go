@@ -0,0 +1,219 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package restclient includes the HTTP request tester, which sends requests described by users (method, URL, headers
+// and body) from the server's network and responds the status, headers, body and timing.
+//
+// Requests are guarded against SSRF by conf.RESTClient: addresses are checked after resolving and the checked ones
+// are dialed (so DNS rebinding doesn't bypass the check), proxies of the environment are not used and redirects are
+// not followed, so every address requested is checked.
+package restclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// Header represents a header of a request or response, headers are kept in order with duplicates.
+type Header struct {
+	Name  string `json:"name"`  // name
+	Value string `json:"value"` // value
+}
+
+// Request represents a request described by a user.
+type Request struct {
+	Method  string    `json:"method"`  // method, such as "GET"
+	URL     string    `json:"url"`     // http or https URL
+	Headers []*Header `json:"headers"` // headers
+	Body    string    `json:"body"`    // body
+}
+
+// Response represents the response of a request.
+type Response struct {
+	Status    int       `json:"status"`    // status code
+	Proto     string    `json:"proto"`     // protocol, such as "HTTP/1.1"
+	Headers   []*Header `json:"headers"`   // headers sorted by names
+	Body      string    `json:"body"`      // body, encoded in base64 if not UTF-8 text
+	Base64    bool      `json:"base64"`    // whether the body is encoded in base64
+	Truncated bool      `json:"truncated"` // whether the body is larger than responded
+	Timing    *Timing   `json:"timing"`    // timing
+}
+
+// Timing represents the durations (in millisecond) of the phases of a request since it started.
+type Timing struct {
+	DNS       int64 `json:"dns"`       // host resolved, 0 if the host is an IP
+	Connect   int64 `json:"connect"`   // connected
+	TLS       int64 `json:"tls"`       // TLS handshake done, 0 for http
+	FirstByte int64 `json:"firstByte"` // first byte of the response received
+	Total     int64 `json:"total"`     // body received
+}
+
+// errRefused is returned if the address of a request is not allowed.
+var errRefused = errors.New("refuses to request non-public address")
+
+// newClient returns a HTTP client guarded by the specified configurations.
+func newClient(rc *conf.RESTClient) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: nil, // proxies would request addresses not checked
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(address)
+				if nil != err {
+					return nil, err
+				}
+
+				ip, err := resolve(ctx, rc, host)
+				if nil != err {
+					return nil, err
+				}
+
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+			TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // redirects are responded to the user
+		},
+	}
+}
+
+// resolve resolves the specified host, returns the first address allowed by the specified configurations. All
+// addresses of the host should be public if the host is not allowed, so a host can't flip between addresses.
+func resolve(ctx context.Context, rc *conf.RESTClient, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); nil != ip {
+		if !allowed(rc, host, ip) {
+			return nil, errRefused
+		}
+
+		return ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if nil != err {
+		return nil, err
+	}
+	if 1 > len(addrs) {
+		return nil, errors.New("no address of host [" + host + "]")
+	}
+
+	for _, addr := range addrs {
+		if !allowed(rc, host, addr.IP) {
+			return nil, errRefused
+		}
+	}
+
+	return addrs[0].IP, nil
+}
+
+// allowed checks whether the specified IP of the specified host is allowed to request.
+func allowed(rc *conf.RESTClient, host string, ip net.IP) bool {
+	return util.IsPublicIP(ip) || rc.AllowsHost(host) || rc.AllowsIP(ip)
+}
+
+// send sends the specified request with the specified client, responds at most the specified max body size of the
+// response body.
+func send(ctx context.Context, client *http.Client, request *Request, maxBodySize int) (*Response, error) {
+	var body io.Reader
+	if "" != request.Body {
+		body = strings.NewReader(request.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, request.Method, request.URL, body)
+	if nil != err {
+		return nil, err
+	}
+	if "http" != req.URL.Scheme && "https" != req.URL.Scheme {
+		return nil, errors.New("unsupported scheme [" + req.URL.Scheme + "]")
+	}
+
+	for _, header := range request.Headers {
+		if "" == header.Name {
+			continue
+		}
+		if strings.EqualFold("Host", header.Name) {
+			req.Host = header.Value
+
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	timing := &Timing{}
+	started := time.Now()
+	since := func() int64 { return time.Since(started).Milliseconds() }
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSDone:              func(httptrace.DNSDoneInfo) { timing.DNS = since() },
+		ConnectDone:          func(string, string, error) { timing.Connect = since() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timing.TLS = since() },
+		GotFirstResponseByte: func() { timing.FirstByte = since() },
+	}))
+
+	resp, err := client.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(maxBodySize)+1))
+	if nil != err {
+		return nil, err
+	}
+	truncated := len(data) > maxBodySize
+	if truncated {
+		data = data[:maxBodySize]
+	}
+	timing.Total = since()
+
+	ret := &Response{Status: resp.StatusCode, Proto: resp.Proto, Headers: []*Header{}, Truncated: truncated,
+		Timing: timing}
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			ret.Headers = append(ret.Headers, &Header{Name: name, Value: value})
+		}
+	}
+	if utf8.Valid(data) {
+		ret.Body = string(data)
+	} else {
+		ret.Body, ret.Base64 = base64.StdEncoding.EncodeToString(data), true
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+func TestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "/redirect" == r.URL.Path {
+			http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Add("X-Token", r.Header.Get("X-Token"))
+		w.Write([]byte(strings.Repeat(string(body), 3)))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	request := &Request{Method: "POST", URL: server.URL, Headers: []*Header{{Name: "X-Token", Value: "t"}},
+		Body: "abc"}
+
+	if _, err := send(ctx, newClient(nil), request, 1024); nil == err {
+		t.Fatalf("requested loopback address not allowed")
+	}
+
+	client := newClient(&conf.RESTClient{AllowedHosts: []string{"127.0.0.0/8"}})
+	response, err := send(ctx, client, request, 5)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 200 != response.Status || "abcab" != response.Body || !response.Truncated || response.Base64 {
+		t.Errorf("unexpected response %+v", response)
+	}
+	headers := map[string]string{}
+	for _, header := range response.Headers {
+		headers[header.Name] = header.Value
+	}
+	if "POST" != headers["X-Method"] || "t" != headers["X-Token"] {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	response, err = send(ctx, client, &Request{Method: "GET", URL: server.URL + "/redirect"}, 1024)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if http.StatusFound != response.Status {
+		t.Errorf("expected redirect not followed, got [%d]", response.Status)
+	}
+
+	if _, err := send(ctx, client, &Request{Method: "GET", URL: "file:///etc/passwd"}, 1024); nil == err {
+		t.Errorf("requested unsupported scheme")
+	}
+}
@@ -0,0 +1,647 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The gRPC control API of Wide, it mirrors the core operations of the REST API (/api/v1).
+//
+// Requests are authenticated with an API token in metadata "authorization: Bearer {token}". Paths are relative to
+// {workspace}/src of the user unless pathtype says otherwise (see PathType).
+//
+// Regenerate the Go code after changing this file:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rpc/wide.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: rpc/wide.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Wide_ListFiles_FullMethodName   = "/wide.v1.Wide/ListFiles"
+	Wide_ReadFile_FullMethodName    = "/wide.v1.Wide/ReadFile"
+	Wide_WriteFile_FullMethodName   = "/wide.v1.Wide/WriteFile"
+	Wide_CreateFile_FullMethodName  = "/wide.v1.Wide/CreateFile"
+	Wide_RemoveFile_FullMethodName  = "/wide.v1.Wide/RemoveFile"
+	Wide_RenameFile_FullMethodName  = "/wide.v1.Wide/RenameFile"
+	Wide_SearchText_FullMethodName  = "/wide.v1.Wide/SearchText"
+	Wide_FindFiles_FullMethodName   = "/wide.v1.Wide/FindFiles"
+	Wide_FindSymbols_FullMethodName = "/wide.v1.Wide/FindSymbols"
+	Wide_Build_FullMethodName       = "/wide.v1.Wide/Build"
+	Wide_Test_FullMethodName        = "/wide.v1.Wide/Test"
+	Wide_Run_FullMethodName         = "/wide.v1.Wide/Run"
+)
+
+// WideClient is the client API for Wide service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WideClient interface {
+	// ListFiles lists a directory.
+	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// ReadFile reads a text file.
+	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
+	// WriteFile saves a file, overwriting changes saved by others since the file was read.
+	WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*WriteFileResponse, error)
+	// CreateFile creates a file or directory, its parent must exist.
+	CreateFile(ctx context.Context, in *CreateFileRequest, opts ...grpc.CallOption) (*CreateFileResponse, error)
+	// RemoveFile removes a file or directory.
+	RemoveFile(ctx context.Context, in *RemoveFileRequest, opts ...grpc.CallOption) (*RemoveFileResponse, error)
+	// RenameFile renames a file or directory.
+	RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*RenameFileResponse, error)
+	// SearchText searches text in files.
+	SearchText(ctx context.Context, in *SearchTextRequest, opts ...grpc.CallOption) (*SearchTextResponse, error)
+	// FindFiles finds files by name.
+	FindFiles(ctx context.Context, in *FindFilesRequest, opts ...grpc.CallOption) (*FindFilesResponse, error)
+	// FindSymbols finds symbols of the workspace.
+	FindSymbols(ctx context.Context, in *FindSymbolsRequest, opts ...grpc.CallOption) (*FindSymbolsResponse, error)
+	// Build builds a package, the output is streamed while the build runs.
+	Build(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_BuildClient, error)
+	// Test tests a package, the output is streamed while the tests run.
+	Test(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_TestClient, error)
+	// Run builds a package and runs the program, the output is streamed while they run. The program is run in Docker if
+	// it's available on the server and killed if it runs longer than 5 seconds.
+	Run(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_RunClient, error)
+}
+
+type wideClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWideClient(cc grpc.ClientConnInterface) WideClient {
+	return &wideClient{cc}
+}
+
+func (c *wideClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, Wide_ListFiles_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error) {
+	out := new(ReadFileResponse)
+	err := c.cc.Invoke(ctx, Wide_ReadFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*WriteFileResponse, error) {
+	out := new(WriteFileResponse)
+	err := c.cc.Invoke(ctx, Wide_WriteFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) CreateFile(ctx context.Context, in *CreateFileRequest, opts ...grpc.CallOption) (*CreateFileResponse, error) {
+	out := new(CreateFileResponse)
+	err := c.cc.Invoke(ctx, Wide_CreateFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) RemoveFile(ctx context.Context, in *RemoveFileRequest, opts ...grpc.CallOption) (*RemoveFileResponse, error) {
+	out := new(RemoveFileResponse)
+	err := c.cc.Invoke(ctx, Wide_RemoveFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*RenameFileResponse, error) {
+	out := new(RenameFileResponse)
+	err := c.cc.Invoke(ctx, Wide_RenameFile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) SearchText(ctx context.Context, in *SearchTextRequest, opts ...grpc.CallOption) (*SearchTextResponse, error) {
+	out := new(SearchTextResponse)
+	err := c.cc.Invoke(ctx, Wide_SearchText_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) FindFiles(ctx context.Context, in *FindFilesRequest, opts ...grpc.CallOption) (*FindFilesResponse, error) {
+	out := new(FindFilesResponse)
+	err := c.cc.Invoke(ctx, Wide_FindFiles_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) FindSymbols(ctx context.Context, in *FindSymbolsRequest, opts ...grpc.CallOption) (*FindSymbolsResponse, error) {
+	out := new(FindSymbolsResponse)
+	err := c.cc.Invoke(ctx, Wide_FindSymbols_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wideClient) Build(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_BuildClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Wide_ServiceDesc.Streams[0], Wide_Build_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &wideBuildClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Wide_BuildClient interface {
+	Recv() (*Output, error)
+	grpc.ClientStream
+}
+
+type wideBuildClient struct {
+	grpc.ClientStream
+}
+
+func (x *wideBuildClient) Recv() (*Output, error) {
+	m := new(Output)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *wideClient) Test(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_TestClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Wide_ServiceDesc.Streams[1], Wide_Test_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &wideTestClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Wide_TestClient interface {
+	Recv() (*Output, error)
+	grpc.ClientStream
+}
+
+type wideTestClient struct {
+	grpc.ClientStream
+}
+
+func (x *wideTestClient) Recv() (*Output, error) {
+	m := new(Output)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *wideClient) Run(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (Wide_RunClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Wide_ServiceDesc.Streams[2], Wide_Run_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &wideRunClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Wide_RunClient interface {
+	Recv() (*Output, error)
+	grpc.ClientStream
+}
+
+type wideRunClient struct {
+	grpc.ClientStream
+}
+
+func (x *wideRunClient) Recv() (*Output, error) {
+	m := new(Output)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WideServer is the server API for Wide service.
+// All implementations must embed UnimplementedWideServer
+// for forward compatibility
+type WideServer interface {
+	// ListFiles lists a directory.
+	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
+	// ReadFile reads a text file.
+	ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error)
+	// WriteFile saves a file, overwriting changes saved by others since the file was read.
+	WriteFile(context.Context, *WriteFileRequest) (*WriteFileResponse, error)
+	// CreateFile creates a file or directory, its parent must exist.
+	CreateFile(context.Context, *CreateFileRequest) (*CreateFileResponse, error)
+	// RemoveFile removes a file or directory.
+	RemoveFile(context.Context, *RemoveFileRequest) (*RemoveFileResponse, error)
+	// RenameFile renames a file or directory.
+	RenameFile(context.Context, *RenameFileRequest) (*RenameFileResponse, error)
+	// SearchText searches text in files.
+	SearchText(context.Context, *SearchTextRequest) (*SearchTextResponse, error)
+	// FindFiles finds files by name.
+	FindFiles(context.Context, *FindFilesRequest) (*FindFilesResponse, error)
+	// FindSymbols finds symbols of the workspace.
+	FindSymbols(context.Context, *FindSymbolsRequest) (*FindSymbolsResponse, error)
+	// Build builds a package, the output is streamed while the build runs.
+	Build(*JobRequest, Wide_BuildServer) error
+	// Test tests a package, the output is streamed while the tests run.
+	Test(*JobRequest, Wide_TestServer) error
+	// Run builds a package and runs the program, the output is streamed while they run. The program is run in Docker if
+	// it's available on the server and killed if it runs longer than 5 seconds.
+	Run(*JobRequest, Wide_RunServer) error
+	mustEmbedUnimplementedWideServer()
+}
+
+// UnimplementedWideServer must be embedded to have forward compatible implementations.
+type UnimplementedWideServer struct {
+}
+
+func (UnimplementedWideServer) ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedWideServer) ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadFile not implemented")
+}
+func (UnimplementedWideServer) WriteFile(context.Context, *WriteFileRequest) (*WriteFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteFile not implemented")
+}
+func (UnimplementedWideServer) CreateFile(context.Context, *CreateFileRequest) (*CreateFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateFile not implemented")
+}
+func (UnimplementedWideServer) RemoveFile(context.Context, *RemoveFileRequest) (*RemoveFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFile not implemented")
+}
+func (UnimplementedWideServer) RenameFile(context.Context, *RenameFileRequest) (*RenameFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameFile not implemented")
+}
+func (UnimplementedWideServer) SearchText(context.Context, *SearchTextRequest) (*SearchTextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchText not implemented")
+}
+func (UnimplementedWideServer) FindFiles(context.Context, *FindFilesRequest) (*FindFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindFiles not implemented")
+}
+func (UnimplementedWideServer) FindSymbols(context.Context, *FindSymbolsRequest) (*FindSymbolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindSymbols not implemented")
+}
+func (UnimplementedWideServer) Build(*JobRequest, Wide_BuildServer) error {
+	return status.Errorf(codes.Unimplemented, "method Build not implemented")
+}
+func (UnimplementedWideServer) Test(*JobRequest, Wide_TestServer) error {
+	return status.Errorf(codes.Unimplemented, "method Test not implemented")
+}
+func (UnimplementedWideServer) Run(*JobRequest, Wide_RunServer) error {
+	return status.Errorf(codes.Unimplemented, "method Run not implemented")
+}
+func (UnimplementedWideServer) mustEmbedUnimplementedWideServer() {}
+
+// UnsafeWideServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WideServer will
+// result in compilation errors.
+type UnsafeWideServer interface {
+	mustEmbedUnimplementedWideServer()
+}
+
+func RegisterWideServer(s grpc.ServiceRegistrar, srv WideServer) {
+	s.RegisterService(&Wide_ServiceDesc, srv)
+}
+
+func _Wide_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_ListFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).ListFiles(ctx, req.(*ListFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_ReadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).ReadFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_ReadFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).ReadFile(ctx, req.(*ReadFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_WriteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).WriteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_WriteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).WriteFile(ctx, req.(*WriteFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_CreateFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).CreateFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_CreateFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).CreateFile(ctx, req.(*CreateFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_RemoveFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).RemoveFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_RemoveFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).RemoveFile(ctx, req.(*RemoveFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).RenameFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_RenameFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).RenameFile(ctx, req.(*RenameFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_SearchText_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchTextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).SearchText(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_SearchText_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).SearchText(ctx, req.(*SearchTextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_FindFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).FindFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_FindFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).FindFiles(ctx, req.(*FindFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_FindSymbols_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindSymbolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WideServer).FindSymbols(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Wide_FindSymbols_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WideServer).FindSymbols(ctx, req.(*FindSymbolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Wide_Build_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WideServer).Build(m, &wideBuildServer{stream})
+}
+
+type Wide_BuildServer interface {
+	Send(*Output) error
+	grpc.ServerStream
+}
+
+type wideBuildServer struct {
+	grpc.ServerStream
+}
+
+func (x *wideBuildServer) Send(m *Output) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Wide_Test_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WideServer).Test(m, &wideTestServer{stream})
+}
+
+type Wide_TestServer interface {
+	Send(*Output) error
+	grpc.ServerStream
+}
+
+type wideTestServer struct {
+	grpc.ServerStream
+}
+
+func (x *wideTestServer) Send(m *Output) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Wide_Run_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WideServer).Run(m, &wideRunServer{stream})
+}
+
+type Wide_RunServer interface {
+	Send(*Output) error
+	grpc.ServerStream
+}
+
+type wideRunServer struct {
+	grpc.ServerStream
+}
+
+func (x *wideRunServer) Send(m *Output) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Wide_ServiceDesc is the grpc.ServiceDesc for Wide service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Wide_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wide.v1.Wide",
+	HandlerType: (*WideServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFiles",
+			Handler:    _Wide_ListFiles_Handler,
+		},
+		{
+			MethodName: "ReadFile",
+			Handler:    _Wide_ReadFile_Handler,
+		},
+		{
+			MethodName: "WriteFile",
+			Handler:    _Wide_WriteFile_Handler,
+		},
+		{
+			MethodName: "CreateFile",
+			Handler:    _Wide_CreateFile_Handler,
+		},
+		{
+			MethodName: "RemoveFile",
+			Handler:    _Wide_RemoveFile_Handler,
+		},
+		{
+			MethodName: "RenameFile",
+			Handler:    _Wide_RenameFile_Handler,
+		},
+		{
+			MethodName: "SearchText",
+			Handler:    _Wide_SearchText_Handler,
+		},
+		{
+			MethodName: "FindFiles",
+			Handler:    _Wide_FindFiles_Handler,
+		},
+		{
+			MethodName: "FindSymbols",
+			Handler:    _Wide_FindSymbols_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Build",
+			Handler:       _Wide_Build_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Test",
+			Handler:       _Wide_Test_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Run",
+			Handler:       _Wide_Run_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/wide.proto",
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// AuthenticateAPIToken authenticates the request with the API token of its "Authorization: Bearer {token}" header. The
+// HTTP session of the request is signed in as the owner of the token, so handlers get the user as usual.
+//
+// Returns false if the request has no bearer token or the token is not valid.
+func AuthenticateAPIToken(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	user := conf.GetUserByAPIToken(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	if nil == user {
+		return false
+	}
+
+	// the session is cached in the registry of the request, it's never saved into a cookie
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	httpSession.Values["uid"] = user.Id
+	httpSession.Values["id"] = "api"
+	httpSession.IsNew = false
+
+	return true
+}
+
+// APITokensHandler handles request of listing API tokens of the user.
+func APITokensHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	tokens := []map[string]interface{}{}
+	for _, token := range conf.GetUser(uid).GetAPITokens() {
+		tokens = append(tokens, map[string]interface{}{
+			"id": token.Id, "name": token.Name, "created": token.Created, "used": token.Used})
+	}
+	result.Data = tokens
+}
+
+// NewAPITokenHandler handles request of creating an API token.
+//
+// Arguments: "name" of the token. Responds the id and the token, the token can't be got again.
+func NewAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+	if "" == name {
+		result.Code = -1
+
+		return
+	}
+
+	user := conf.GetUser(uid)
+	token, id, err := user.NewAPIToken(name)
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if !user.Save() {
+		user.RevokeAPIToken(id)
+		result.Code = -1
+
+		return
+	}
+
+	logger.Infof("User [%s] created API token [%s, %s]", uid, id, strconv.Quote(name))
+	result.Data = map[string]interface{}{"id": id, "token": token}
+}
+
+// RevokeAPITokenHandler handles request of revoking an API token.
+//
+// Arguments: "id" of the token.
+func RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	id, _ := args["id"].(string)
+	user := conf.GetUser(uid)
+	if !user.RevokeAPIToken(id) || !user.Save() {
+		result.Code = -1
+
+		return
+	}
+
+	logger.Infof("User [%s] revoked API token [%s]", uid, id)
+}
+
+// UserHandler handles request of getting the profile of the user.
+func UserHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	user := conf.GetUser(uid)
+	result.Data = map[string]interface{}{"id": user.Id, "name": user.Name, "email": user.Email,
+		"avatar": user.Avatar, "locale": user.Locale, "timeZone": user.TimeZone, "admin": conf.Wide().IsAdmin(uid)}
+}
+
+// UsersHandler handles request of listing all users, administrators only.
+func UsersHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	users := []map[string]interface{}{}
+	for _, user := range conf.GetUsers() {
+		users = append(users, map[string]interface{}{"id": user.Id, "name": user.Name, "avatar": user.Avatar,
+			"created": user.Created, "lived": user.Lived, "admin": conf.Wide().IsAdmin(user.Id)})
+	}
+	result.Data = users
+}
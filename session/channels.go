@@ -0,0 +1,63 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Kinds of WebSocket channels of this instance only.
+const (
+	ChannelEditor     = "editor"
+	ChannelPlayground = "playground"
+)
+
+// channelsOf returns the channels of the specified kind.
+func channelsOf(kind string) map[string]*util.WSChannel {
+	switch kind {
+	case ChannelEditor:
+		return EditorWS
+	case ChannelPlayground:
+		return PlaygroundWS
+	}
+
+	return clusterChannels(kind)
+}
+
+// ReleaseChannel closes the specified channel of the specified kind of the specified session once it's closed by the
+// browser or dead (see util.WSChannel.Heartbeat), and removes it from the channels unless it has been replaced by a
+// reconnected one.
+//
+// The running processes of the session are killed with its output (or playground) channel since their output can't be
+// delivered any more, and the goroutines sending output to the channel stop once it's removed.
+func ReleaseChannel(kind, sid string, channel *util.WSChannel) {
+	channel.Close()
+
+	channels := channelsOf(kind)
+	if channel != channels[sid] {
+		return
+	}
+
+	delete(channels, sid)
+	if nil != clusterChannels(kind) {
+		announceClosed(kind, sid)
+	}
+
+	logger.Tracef("Released [%s] channel of session [%s]", kind, sid)
+
+	if ChannelOutput == kind || ChannelPlayground == kind {
+		Processes.killSession(sid)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Kinds of WebSocket channels shared in the cluster.
+const (
+	ChannelSession      = "session"
+	ChannelOutput       = "output"
+	ChannelNotification = "notification"
+)
+
+// Types of cluster messages.
+const (
+	clusterOpen   = "open"   // a channel opened on the sending instance
+	clusterClose  = "close"  // a channel closed on the sending instance
+	clusterWrite  = "write"  // a message to write to a channel held by the receiving instance
+	clusterSync   = "sync"   // the sending instance started, others announce their channels again
+	clusterReload = "reload" // configurations changed, others reload them
+)
+
+// clusterMessage represents a message exchanged between Wide instances.
+type clusterMessage struct {
+	Instance string          // id of the sending instance
+	Type     string          // message type
+	Kind     string          // channel kind
+	Sid      string          // wide session id
+	UserId   string          // user id of the wide session, for "open" messages
+	Data     json.RawMessage `json:",omitempty"` // the message to write, for "write" messages
+}
+
+// id of this instance.
+var instanceId = gulu.Rand.String(16)
+
+// publish/subscribe client, nil if not in the multi-instance mode.
+var clusterClient *util.RedisClient
+
+// RemoteEventHandlers holds the handlers added to event queues of wide sessions held by other instances, such as the
+// one pushing notifications.
+var RemoteEventHandlers []event.Handler
+
+// LoadCluster joins the cluster if Wide.Cluster is configured.
+//
+// Each instance announces the WebSocket channels it holds, other instances register remote channels (see
+// util.WSChannel.Remote) and wide sessions for them, so messages written on any instance are forwarded to the
+// browser tab. HTTP sessions are shared already since they are stored in cookies.
+func LoadCluster() {
+	cluster := conf.Wide().Cluster
+	if nil == cluster {
+		return
+	}
+
+	clusterClient = &util.RedisClient{Addr: cluster.Redis, Password: cluster.Password}
+	channel := cluster.ChannelName()
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for {
+			err := clusterClient.Subscribe(channel, func(data []byte) {
+				defer gulu.Panic.Recover(nil)
+
+				msg := &clusterMessage{}
+				if err := json.Unmarshal(data, msg); nil != err {
+					logger.Warnf("Malformed cluster message [%s]: %s", data, err)
+
+					return
+				}
+				if instanceId != msg.Instance {
+					handleClusterMessage(msg)
+				}
+			})
+			logger.Errorf("Subscribes cluster channel [%s] failed, retries later: %s", channel, err)
+
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	publishCluster(&clusterMessage{Type: clusterSync})
+
+	logger.Infof("Joined the cluster as instance [%s] via [%s]", instanceId, cluster.Redis)
+}
+
+// AnnounceChannel announces the channel of this instance specified by the given kind and wide session id to other
+// instances, does nothing if not in the multi-instance mode.
+func AnnounceChannel(kind, sid string) {
+	if nil == clusterClient {
+		return
+	}
+
+	userId := ""
+	if wSession := WideSessions.Get(sid); nil != wSession {
+		userId = wSession.UserId
+	}
+
+	publishCluster(&clusterMessage{Type: clusterOpen, Kind: kind, Sid: sid, UserId: userId})
+}
+
+// AnnounceReload notifies other instances to reload configurations, does nothing if not in the multi-instance mode.
+//
+// Other instances may not be notified by the file system of the changes in the shared Data directory.
+func AnnounceReload() {
+	if nil == clusterClient {
+		return
+	}
+
+	publishCluster(&clusterMessage{Type: clusterReload})
+}
+
+// announceClosed announces the channel of this instance specified by the given kind and wide session id closed.
+func announceClosed(kind, sid string) {
+	if nil == clusterClient {
+		return
+	}
+
+	publishCluster(&clusterMessage{Type: clusterClose, Kind: kind, Sid: sid})
+}
+
+// publishCluster publishes the specified message to other instances.
+func publishCluster(msg *clusterMessage) error {
+	msg.Instance = instanceId
+	data, err := json.Marshal(msg)
+	if nil != err {
+		return err
+	}
+
+	if err := clusterClient.Publish(conf.Wide().Cluster.ChannelName(), data); nil != err {
+		logger.Errorf("Publishes cluster message [%s] failed: %s", msg.Type, err)
+
+		return err
+	}
+
+	return nil
+}
+
+// handleClusterMessage handles the specified message sent by another instance.
+func handleClusterMessage(msg *clusterMessage) {
+	channels := clusterChannels(msg.Kind)
+
+	switch msg.Type {
+	case clusterOpen:
+		if nil == channels {
+			return
+		}
+		if ch := channels[msg.Sid]; nil != ch && nil == ch.Remote {
+			return // the channel is held by this instance
+		}
+
+		if ChannelSession == msg.Kind && nil == WideSessions.Get(msg.Sid) && "" != msg.UserId {
+			WideSessions.remote(msg.Sid, msg.UserId)
+		}
+
+		kind, sid := msg.Kind, msg.Sid
+		channels[sid] = &util.WSChannel{Sid: sid, Time: time.Now(), Remote: func(v interface{}) error {
+			data, err := json.Marshal(v)
+			if nil != err {
+				return err
+			}
+
+			return publishCluster(&clusterMessage{Type: clusterWrite, Kind: kind, Sid: sid, Data: data})
+		}}
+		logger.Tracef("Registered a remote [%s] channel of session [%s]", kind, sid)
+	case clusterClose:
+		if ch := channels[msg.Sid]; nil == ch || nil == ch.Remote {
+			return
+		}
+
+		if ChannelSession == msg.Kind {
+			WideSessions.Remove(msg.Sid) // deletes remote channels of the session as well
+		} else {
+			delete(channels, msg.Sid)
+		}
+	case clusterWrite:
+		if ch := channels[msg.Sid]; nil != ch && nil == ch.Remote {
+			ch.WriteJSON(msg.Data)
+		}
+	case clusterSync:
+		for _, kind := range []string{ChannelSession, ChannelOutput, ChannelNotification} {
+			for sid, ch := range clusterChannels(kind) {
+				if nil == ch.Remote {
+					AnnounceChannel(kind, sid)
+				}
+			}
+		}
+	case clusterReload:
+		if err := conf.Reload(); nil != err {
+			logger.Errorf("Reloads configurations failed: %s", err)
+		}
+	}
+}
+
+// clusterChannels returns the channels of the specified kind.
+func clusterChannels(kind string) map[string]*util.WSChannel {
+	switch kind {
+	case ChannelSession:
+		return SessionWS
+	case ChannelOutput:
+		return OutputWS
+	case ChannelNotification:
+		return NotificationWS
+	}
+
+	return nil
+}
+
+// remote creates a wide session for the session channel held by another instance.
+//
+// The session has an event queue so that events published on this instance are sent to the notification channel,
+// files are watched by the instance holding the session channel only.
+func (sessions *wSessions) remote(sid, uid string) *WideSession {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	ret := &WideSession{
+		ID:         sid,
+		UserId:     uid,
+		EventQueue: event.UserEventQueues.New(sid),
+		State:      sessionStateActive,
+		Content:    &conf.LatestSessionContent{},
+		Created:    now,
+		Updated:    now,
+	}
+
+	ret.EventQueue.AddHandler(RemoteEventHandlers...)
+
+	*sessions = append(*sessions, ret)
+
+	return ret
+}
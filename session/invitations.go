@@ -0,0 +1,311 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/i18n"
+)
+
+const (
+	defaultInvitationHours = 2      // default lifetime of invitations in hours
+	maxInvitationHours     = 7 * 24 // max lifetime of invitations in hours
+)
+
+// Invitation represents a pair programming invitation, which grants the user accepted it read-write access to a
+// project of the inviter until it expires.
+//
+// The invited project is listed in the file tree of the user accepted with path type 3, paths of its files are
+// "/{invitation id}/{path relative to the project}" (see file.GetPath).
+type Invitation struct {
+	Id       string `json:"id"`
+	Token    string `json:"token"`    // secret of the invitation link
+	Owner    string `json:"owner"`    // user id of the inviter
+	Project  string `json:"project"`  // project path relative to {workspace}/src of the inviter, in slash form
+	Invitee  string `json:"invitee"`  // user id of the user invited, "" for anyone opening the link (a guest)
+	Accepted string `json:"accepted"` // user id of the user accepted, "" if not accepted yet
+	Expires  int64  `json:"expires"`  // expire time in unix milliseconds
+}
+
+var (
+	// unexpired invitations
+	invitations []*Invitation
+
+	// guards invitations and the invitations file
+	invitationsMutex sync.Mutex
+)
+
+// LoadInvitations loads invitations from {Wide.Data}/invitations.json and revokes them on expiry.
+func LoadInvitations() {
+	invitationsMutex.Lock()
+	data, err := ioutil.ReadFile(invitationsPath())
+	if nil == err {
+		if err := json.Unmarshal(data, &invitations); nil != err {
+			logger.Errorf("Parses invitations failed: %s", err)
+		}
+	} else if !os.IsNotExist(err) {
+		logger.Error(err)
+	}
+	invitationsMutex.Unlock()
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for range time.Tick(time.Minute) {
+			revokeExpiredInvitations()
+		}
+	}()
+}
+
+// NewInvitationHandler handles request of inviting a user to pair on a project (a directory of the workspace).
+//
+// Arguments: "path" (the project), "pathtype" (must be 0), optional "user" (name or id of the user invited, anyone
+// opening the link if not specified) and "hours" (lifetime, 2 by default). Responds the invitation link and the expire
+// time.
+func NewInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	user := conf.GetUser(uid)
+	pathArg, _ := args["path"].(string)
+	project := filepath.ToSlash(filepath.Clean("/" + pathArg))[1:]
+	if pathtype, _ := args["pathtype"].(float64); 0 != pathtype || "" == project ||
+		!gulu.File.IsDir(invitationProjectPath(uid, project)) {
+		result.Code = -1
+
+		return
+	}
+
+	invitation := &Invitation{Id: gulu.Rand.String(16), Token: gulu.Rand.String(32), Owner: uid, Project: project}
+	if name, _ := args["user"].(string); "" != strings.TrimSpace(name) {
+		for _, u := range conf.GetUsers() {
+			if strings.TrimSpace(name) == u.Name || strings.TrimSpace(name) == u.Id {
+				invitation.Invitee = u.Id
+
+				break
+			}
+		}
+
+		if "" == invitation.Invitee || uid == invitation.Invitee {
+			result.Code = -1
+			result.Msg = i18n.Get(user.Locale, "user-not-found").(string)
+
+			return
+		}
+	}
+
+	hours, _ := args["hours"].(float64)
+	if 0 >= hours {
+		hours = defaultInvitationHours
+	} else if maxInvitationHours < hours {
+		hours = maxInvitationHours
+	}
+	expires := time.Now().Add(time.Duration(hours * float64(time.Hour)))
+	invitation.Expires = expires.UnixNano() / int64(time.Millisecond)
+
+	invitationsMutex.Lock()
+	defer invitationsMutex.Unlock()
+
+	invitations = append(invitations, invitation)
+	if err := saveInvitations(); nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	result.Data = map[string]interface{}{"link": conf.Wide().Server + conf.Wide().Context + "/invitation?token=" +
+		invitation.Token, "expires": user.FormatTime(expires)}
+}
+
+// AcceptInvitationHandler handles request of accepting an invitation by opening the invitation link, redirects to
+// the index page with the invited project listed in the file tree.
+//
+// An invitation is accepted once, by the user invited, or by anyone opening the link if no user specified.
+func AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	token := r.URL.Query().Get("token")
+
+	invitationsMutex.Lock()
+	defer invitationsMutex.Unlock()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, invitation := range invitations {
+		if "" == token || token != invitation.Token || now >= invitation.Expires {
+			continue
+		}
+
+		if uid == invitation.Owner || uid == invitation.Accepted {
+			break
+		}
+
+		if ("" != invitation.Invitee && uid != invitation.Invitee) || "" != invitation.Accepted {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+
+			return
+		}
+
+		invitation.Accepted = uid
+		if err := saveInvitations(); nil != err {
+			logger.Error(err)
+		}
+
+		break
+	}
+
+	http.Redirect(w, r, conf.Wide().Context+"/", http.StatusFound)
+}
+
+// InvitedProjects returns the unexpired invitations accepted by the specified user.
+func InvitedProjects(userId string) []*Invitation {
+	invitationsMutex.Lock()
+	defer invitationsMutex.Unlock()
+
+	ret := []*Invitation{}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, invitation := range invitations {
+		if userId == invitation.Accepted && now < invitation.Expires {
+			ret = append(ret, invitation)
+		}
+	}
+
+	return ret
+}
+
+// InvitedProject returns the path of the project of the specified invitation accepted by the specified user, returns
+// "" if not found or expired.
+func InvitedProject(userId, id string) string {
+	for _, invitation := range InvitedProjects(userId) {
+		if id == invitation.Id {
+			return invitation.ProjectPath()
+		}
+	}
+
+	return ""
+}
+
+// ProjectPath returns the path of the invited project.
+func (invitation *Invitation) ProjectPath() string {
+	return invitationProjectPath(invitation.Owner, invitation.Project)
+}
+
+// invitedAccess checks whether the specified path is in a project the specified user is invited to.
+func invitedAccess(userId, path string) bool {
+	for _, invitation := range InvitedProjects(userId) {
+		rel, err := filepath.Rel(invitation.ProjectPath(), path)
+		if nil == err && ".." != rel && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// revokeExpiredInvitations removes the expired invitations, the invited projects are removed from the file trees of
+// the sessions of the users accepted them.
+func revokeExpiredInvitations() {
+	invitationsMutex.Lock()
+	defer invitationsMutex.Unlock()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	remains := []*Invitation{}
+	for _, invitation := range invitations {
+		if now < invitation.Expires {
+			remains = append(remains, invitation)
+
+			continue
+		}
+
+		logger.Infof("Invitation [%s] of user [%s] to project [%s] expired", invitation.Id, invitation.Owner,
+			invitation.Project)
+		if "" == invitation.Accepted {
+			continue
+		}
+
+		for _, s := range WideSessions.GetByUserId(invitation.Accepted) {
+			if ws := SessionWS[s.ID]; nil != ws {
+				ws.WriteJSON(map[string]interface{}{"cmd": "remove-file", "path": "/" + invitation.Id})
+			}
+		}
+	}
+
+	if len(remains) == len(invitations) {
+		return
+	}
+
+	invitations = remains
+	if err := saveInvitations(); nil != err {
+		logger.Error(err)
+	}
+}
+
+// saveInvitations saves the invitations into {Wide.Data}/invitations.json. invitationsMutex should be locked by the
+// caller.
+func saveInvitations() error {
+	data, err := json.MarshalIndent(invitations, "", "    ")
+	if nil != err {
+		return err
+	}
+
+	return ioutil.WriteFile(invitationsPath(), data, 0644)
+}
+
+// invitationsPath returns the path of the invitations file.
+func invitationsPath() string {
+	return filepath.Join(conf.Wide().Data, "invitations.json")
+}
+
+// invitationProjectPath returns the path of the specified project of the specified user.
+func invitationProjectPath(userId, project string) string {
+	workspaces := filepath.SplitList(conf.GetUserWorkspace(userId))
+	if 1 > len(workspaces) {
+		return ""
+	}
+
+	return filepath.Join(workspaces[0], "src", filepath.FromSlash(project))
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"archive/zip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+)
+
+// LogsHandler handles request of downloading recent log files (a zip), only administrators are allowed.
+//
+// Argument "days" (defaults to 1) specifies log files modified in how many recent days are included.
+func LogsHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	paths := conf.LogFiles()
+	if 1 > len(paths) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if nil != err || 1 > days {
+		days = 1
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	w.Header().Set("Content-Disposition", "attachment; filename=wide-logs.zip")
+	w.Header().Set("Content-Type", "application/zip")
+
+	zipWriter := zip.NewWriter(w)
+	defer zipWriter.Close()
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if nil != err || info.ModTime().Before(since) {
+			continue
+		}
+
+		if err := addLogEntry(zipWriter, path, info, conf.GetUser(uid)); nil != err {
+			logger.Errorf("Adds log file [%s] to zip failed: %s", path, err)
+
+			return
+		}
+	}
+}
+
+// addLogEntry adds the specified log file to the specified zip writer, the modification time of the entry is in the
+// specified user's time zone.
+func addLogEntry(zipWriter *zip.Writer, path string, info os.FileInfo, user *conf.User) error {
+	f, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	header, err := zip.FileInfoHeader(info)
+	if nil != err {
+		return err
+	}
+	header.Name = filepath.Base(path)
+	header.Method = zip.Deflate
+	header.Modified = info.ModTime().In(user.Location())
+
+	entry, err := zipWriter.CreateHeader(header)
+	if nil != err {
+		return err
+	}
+
+	// the log file is being written, copies the size at the moment only
+	_, err = io.CopyN(entry, f, info.Size())
+
+	return err
+}
@@ -56,7 +56,7 @@ func RedirectGitHubHandler(w http.ResponseWriter, r *http.Request) {
 	loginAuthURL := data["loginAuthURL"].(string)
 
 	state := r.URL.Query().Get("state")
-	referer := conf.Wide.Server + "__" + state
+	referer := conf.Wide().Server + "__" + state
 	state = gulu.Rand.String(16) + referer
 	states[state] = state
 	path := loginAuthURL + "?client_id=" + clientId + "&state=" + state + "&scope=public_repo,read:user,user:follow"
@@ -79,7 +79,7 @@ func GithubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	accessToken := r.URL.Query().Get("ak")
 	githubUser := GitHubUserInfo(accessToken)
 	if nil == githubUser {
-		logger.Warnf("Can not get user info with token [" + accessToken + "]")
+		logger.Warn("Can not get user info with token [" + accessToken + "]")
 		http.Error(w, "Get user info failed", http.StatusUnauthorized)
 
 		return
@@ -106,10 +106,10 @@ func GithubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := HTTPSession.Get(r, CookieName)
 	httpSession.Values["uid"] = githubId
 	httpSession.Values["id"] = strconv.Itoa(rand.Int())
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	http.Redirect(w, r, conf.Wide().Context+"/", http.StatusSeeOther)
 }
 
 // GitHubUserInfo returns GitHub user info specified by the given access token.
@@ -133,10 +133,10 @@ func GitHubUserInfo(accessToken string) (ret map[string]interface{}) {
 
 // LoginHandler handles request of show login page.
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(conf.Wide.Locale),
-		"locale": conf.Wide.Locale, "ver": conf.WideVersion, "year": time.Now().Year()}
+	model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(conf.Wide().Locale),
+		"locale": conf.Wide().Locale, "ver": conf.WideVersion, "year": time.Now().Year()}
 
-	t, err := template.ParseFiles("views/login.html")
+	t, err := template.ParseFS(conf.Assets(), "views/login.html")
 	if nil != err {
 		logger.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -165,7 +165,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 //  3. update the user customized configurations, such as style.css
 //  4. serve files of the user's workspace via HTTP
 //
-// Note: user [playground] is a reserved mock user
+// Note: user [playground] is a reserved mock user, no user is created if signing up is disabled (Wide.SignUpDisabled)
 func addUser(userId, userName, userAvatar string) string {
 	if "playground" == userId {
 		return userExists
@@ -174,15 +174,21 @@ func addUser(userId, userName, userAvatar string) string {
 	addUserMutex.Lock()
 	defer addUserMutex.Unlock()
 
-	for _, user := range conf.Users {
+	for _, user := range conf.GetUsers() {
 		if strings.ToLower(user.Id) == strings.ToLower(userId) {
 			return userExists
 		}
 	}
 
-	workspace := filepath.Join(conf.Wide.Data, "workspaces", userId)
+	if conf.Wide().SignUpDisabled {
+		logger.Infof("Refused to create a user [%s] since signing up is disabled", userId)
+
+		return userSignUpOff
+	}
+
+	workspace := filepath.Join(conf.Wide().Data, "workspaces", userId)
 	newUser := conf.NewUser(userId, userName, userAvatar, workspace)
-	conf.Users = append(conf.Users, newUser)
+	conf.AddUser(newUser)
 	if !newUser.Save() {
 		return userCreateError
 	}
@@ -191,6 +197,7 @@ func addUser(userId, userName, userAvatar string) string {
 	helloWorld(workspace)
 	conf.UpdateCustomizedConf(userId)
 
+	conf.AddAudit(userId, "user.create", userName)
 	logger.Infof("Created a user [%s]", userId)
 
 	return userCreated
@@ -66,7 +66,12 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 	randInt := rand.Int()
 	rid := strconv.Itoa(randInt)
 	var cmd *exec.Cmd
-	if conf.Docker {
+	// files of other languages are run by their toolchains, the executable is the source file then
+	toolchain := conf.Wide().Toolchain(filePath)
+	docker := conf.Docker && (nil == toolchain || "" != toolchain.Image)
+	if nil != toolchain {
+		cmd = toolchainCmd(toolchain, filePath, rid, docker)
+	} else if docker {
 		fileName := filepath.Base(filePath)
 		cmd = exec.Command("docker", "run", "--rm", "--cpus", "0.05", "--name", rid, "-v", filePath+":/"+fileName, conf.DockerImageGo, "/"+fileName)
 	} else {
@@ -121,6 +126,15 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 		}
 	}
 
+	// the output is sent in batches since programs may print character by character
+	pid := cmd.Process.Pid
+	batcher := util.NewOutputBatcher(conf.Wide().OutputFlushDuration(), func(text string) {
+		if wsChannel := channel[sid]; nil != wsChannel {
+			wsChannel.WriteJSON(map[string]interface{}{"cmd": "run", "output": text, "pid": pid})
+			wsChannel.Refresh()
+		}
+	})
+
 	go func() {
 		defer gulu.Panic.Recover(nil)
 
@@ -138,13 +152,7 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 				oneRuneStr := string(r)
 				oneRuneStr = strings.Replace(oneRuneStr, "<", "&lt;", -1)
 				oneRuneStr = strings.Replace(oneRuneStr, ">", "&gt;", -1)
-				channelRet["cmd"] = "run"
-				channelRet["output"] = oneRuneStr
-				wsChannel := channel[sid]
-				if nil != wsChannel {
-					wsChannel.WriteJSON(&channelRet)
-					wsChannel.Refresh()
-				}
+				batcher.Write(oneRuneStr)
 			}
 		}()
 
@@ -157,13 +165,7 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 			oneRuneStr := string(r)
 			oneRuneStr = strings.Replace(oneRuneStr, "<", "&lt;", -1)
 			oneRuneStr = strings.Replace(oneRuneStr, ">", "&gt;", -1)
-			channelRet["cmd"] = "run"
-			channelRet["output"] = "<span class='stderr'>" + oneRuneStr + "</span>"
-			wsChannel := channel[sid]
-			if nil != wsChannel {
-				wsChannel.WriteJSON(&channelRet)
-				wsChannel.Refresh()
-			}
+			batcher.Write("<span class='stderr'>" + oneRuneStr + "</span>")
 		}
 	}()
 
@@ -171,10 +173,10 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 	kill := false
 	select {
 	case <-after:
-		if conf.Docker {
+		if docker {
 			killCmd := exec.Command("docker", "rm", "-f", rid)
 			if err := killCmd.Run(); nil != err {
-				logger.Errorf("executes [docker rm -f " + rid + "] failed [" + err.Error() + "], this will cause resource leaking")
+				logger.Error("executes [docker rm -f " + rid + "] failed [" + err.Error() + "], this will cause resource leaking")
 			}
 		} else {
 			cmd.Process.Kill()
@@ -189,6 +191,7 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 	Processes.Remove(wSession, cmd.Process)
 	logger.Debugf("User [%s, %s] done running [id=%s, file=%s, kill=%v]", wSession.UserId, sid, rid, filePath, kill)
 
+	batcher.Flush()
 	if nil != wsChannel {
 		channelRet["cmd"] = "run-done"
 		wsChannel.WriteJSON(&channelRet)
@@ -196,6 +199,25 @@ func RunHandler(w http.ResponseWriter, r *http.Request, channel map[string]*util
 	}
 }
 
+// toolchainCmd returns the command running the specified source file with the run command of the specified toolchain.
+// The directory of the file is mounted at /src of a container of the image of the toolchain if runs in Docker.
+func toolchainCmd(toolchain *conf.Toolchain, filePath, rid string, docker bool) *exec.Cmd {
+	curDir := filepath.Dir(filePath)
+	if docker {
+		args := []string{"run", "--rm", "--cpus", "0.05", "--name", rid, "-v", curDir + ":/src", "-w", "/src",
+			toolchain.Image}
+		args = append(args, toolchain.Command(toolchain.Run, "/src/"+filepath.Base(filePath))...)
+
+		return exec.Command("docker", args...)
+	}
+
+	run := toolchain.Command(toolchain.Run, filePath)
+	ret := exec.Command(run[0], run[1:]...)
+	ret.Dir = curDir
+
+	return ret
+}
+
 // StopHandler handles request of stopping a running process.
 func StopHandler(w http.ResponseWriter, r *http.Request) {
 	result := gulu.Ret.NewResult()
@@ -293,3 +315,63 @@ func (procs *procs) Kill(wSession *WideSession, pid int) {
 		}
 	}
 }
+
+// killSession kills all processes of the specified session.
+func (procs *procs) killSession(sid string) {
+	procMutex.Lock()
+	defer procMutex.Unlock()
+
+	for _, p := range (*procs)[sid] {
+		if err := p.Kill(); nil != err {
+			logger.Errorf("Kill a process [pid=%d] of session [%s] failed [error=%v]", p.Pid, sid, err)
+		} else {
+			logger.Debugf("Killed a process [pid=%d] of session [%s]", p.Pid, sid)
+		}
+	}
+	delete(*procs, sid)
+
+	if wSession := WideSessions.Get(sid); nil != wSession {
+		wSession.SetProcesses(nil)
+	}
+}
+
+// KillAll stops all processes of all users: interrupts them, then kills the ones still running after the specified
+// timeout.
+func (procs *procs) KillAll(timeout time.Duration) {
+	for _, p := range procs.all() {
+		if err := p.Signal(os.Interrupt); nil != err { // not supported on Windows
+			p.Kill()
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for 0 < len(procs.all()) && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, p := range procs.all() {
+		if err := p.Kill(); nil != err {
+			logger.Errorf("Kill a process [pid=%d] failed [error=%v]", p.Pid, err)
+		} else {
+			logger.Debugf("Killed a process [pid=%d]", p.Pid)
+		}
+	}
+}
+
+// Count returns the number of running processes of all users.
+func (procs *procs) Count() int {
+	return len(procs.all())
+}
+
+// all returns processes of all users.
+func (procs *procs) all() []*os.Process {
+	procMutex.Lock()
+	defer procMutex.Unlock()
+
+	ret := []*os.Process{}
+	for _, userProcesses := range *procs {
+		ret = append(ret, userProcesses...)
+	}
+
+	return ret
+}
@@ -25,6 +25,7 @@ package session
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
@@ -192,16 +193,16 @@ func (f userReports) Len() int           { return len(f) }
 func (f userReports) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
 func (f userReports) Less(i, j int) bool { return f[i].processCnt > f[j].processCnt }
 
-const (
-	// Time allowed to write a message to the peer.
-	writeWait = 10 * time.Second
+// SessionMessageHandler handles a message sent by the browser via the session channel of the specified session of
+// the specified user.
+type SessionMessageHandler func(sid, uid string, message map[string]interface{})
 
-	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
+// SessionMessageHandlers holds the handlers of messages sent via session channels by message commands ("cmd"), messages
+// of other commands are replied with "session-output".
+var SessionMessageHandlers = map[string]SessionMessageHandler{}
 
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = (pongWait * 9) / 10
-)
+// SessionClosedHandlers are called with the session id after the session channel of a session closed.
+var SessionClosedHandlers []func(sid string)
 
 // WSHandler handles request of creating session channel.
 //
@@ -228,48 +229,43 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+		httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 		httpSession.Save(r, w)
 
 		wSession = WideSessions.new(httpSession, sid)
 
 		logger.Tracef("Created a wide session [%s] for websocket reconnecting, user [%s]", sid, wSession.UserId)
 	}
+	AnnounceChannel(ChannelSession, sid)
 
 	logger.Tracef("Open a new [Session Channel] with session [%s], %d", sid, len(SessionWS))
 
-	input := map[string]interface{}{}
-
-	wsChan.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	wsChan.Conn.SetPongHandler(func(string) error { wsChan.Conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-	ticker := time.NewTicker(pingPeriod)
+	stopHeartbeat := wsChan.Heartbeat()
 
 	defer func() {
+		for _, handler := range SessionClosedHandlers {
+			handler(sid)
+		}
 		WideSessions.Remove(sid)
-		ticker.Stop()
+		stopHeartbeat()
 		wsChan.Close()
 	}()
 
-	// send websocket ping message.
-	go func(t *time.Ticker, channel util.WSChannel) {
-		for {
-			select {
-			case <-t.C:
-				if err := channel.Conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
-					return
-				}
-			}
-		}
-
-	}(ticker, wsChan)
-
 	for {
+		input := map[string]interface{}{}
 		if err := wsChan.ReadJSON(&input); err != nil {
 			logger.Tracef("[Session Channel] of session [%s] disconnected, releases all resources with it, user [%s]", sid, wSession.UserId)
 
 			return
 		}
 
+		if handler := SessionMessageHandlers[fmt.Sprint(input["cmd"])]; nil != handler {
+			handler(sid, wSession.UserId, input)
+			wsChan.Time = time.Now()
+
+			continue
+		}
+
 		ret = map[string]interface{}{"output": "", "cmd": "session-output"}
 
 		if err := wsChan.WriteJSON(&ret); err != nil {
@@ -308,7 +304,7 @@ func SaveContentHandler(w http.ResponseWriter, r *http.Request) {
 
 	wSession.Content = args.LatestSessionContent
 
-	for _, user := range conf.Users {
+	for _, user := range conf.GetUsers() {
 		if user.Id == wSession.UserId {
 			// update the variable in-memory, session.FixedTimeSave() function will persist it periodically
 			user.LatestSessionContent = wSession.Content
@@ -341,6 +337,17 @@ func (sessions *wSessions) GenId() string {
 	return strconv.Itoa(rand.Int())
 }
 
+// NotifyShutdown notifies all connected clients that the server is shutting down, clients save unsaved files and
+// session content once notified.
+func NotifyShutdown() {
+	ret := map[string]interface{}{"cmd": "shutdown"}
+	for sid, ws := range SessionWS {
+		if err := ws.WriteJSON(&ret); nil != err {
+			logger.Debugf("Notifies session [%s] of shutdown failed: %s", sid, err)
+		}
+	}
+}
+
 // Get gets a wide session with the specified session id.
 func (sessions *wSessions) Get(sid string) *WideSession {
 	mutex.Lock()
@@ -386,16 +393,25 @@ func (sessions *wSessions) Remove(sid string) {
 
 			// close websocket channels
 			if ws, ok := OutputWS[sid]; ok {
+				if nil == ws.Remote {
+					announceClosed(ChannelOutput, sid)
+				}
 				ws.Close()
 				delete(OutputWS, sid)
 			}
 
 			if ws, ok := NotificationWS[sid]; ok {
+				if nil == ws.Remote {
+					announceClosed(ChannelNotification, sid)
+				}
 				ws.Close()
 				delete(NotificationWS, sid)
 			}
 
 			if ws, ok := SessionWS[sid]; ok {
+				if nil == ws.Remote {
+					announceClosed(ChannelSession, sid)
+				}
 				ws.Close()
 				delete(SessionWS, sid)
 			}
@@ -0,0 +1,104 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// SettingsHandler handles request of getting (GET) or updating (POST) the instance settings, only administrators are
+// allowed.
+//
+// The request body of updating is a JSON object of conf.Settings, only the specified settings are updated. Updated
+// settings are saved in {Data}/settings.json and applied immediately, the effective settings are responded.
+func SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	if http.MethodPost == r.Method {
+		settings := &conf.Settings{}
+		if err := json.NewDecoder(r.Body).Decode(settings); nil != err {
+			logger.Error(err)
+			result.Code = -1
+			result.Msg = err.Error()
+
+			return
+		}
+
+		if err := conf.SaveSettings(settings); nil != err {
+			logger.Errorf("Saves settings failed: %s", err)
+			result.Code = -1
+			result.Msg = err.Error()
+
+			return
+		}
+
+		AnnounceReload()
+		data, _ := json.Marshal(settings)
+		conf.AddAudit(uid, "settings.update", string(data))
+		logger.Infof("Administrator [%s] updated settings", uid)
+	}
+
+	result.Data = conf.GetSettings()
+}
+
+// AuditsHandler handles request of listing the latest audit records, only administrators are allowed.
+//
+// Query parameter: optional "limit" (defaults to 100).
+func AuditsHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew || !conf.Wide().IsAdmin(httpSession.Values["uid"].(string)) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if nil != err || 0 >= limit {
+		limit = 100
+	}
+
+	audits, err := conf.GetStore().GetAudits(limit)
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+		result.Msg = err.Error()
+
+		return
+	}
+
+	result.Data = audits
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// TriggersHandler handles request of listing triggers (incoming webhooks, see conf.Trigger) of the user.
+func TriggersHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	triggers := []map[string]interface{}{}
+	for _, trigger := range conf.GetUser(uid).Triggers {
+		triggers = append(triggers, map[string]interface{}{"id": trigger.Id, "url": triggerURL(trigger),
+			"project": trigger.Project, "branch": trigger.Branch, "job": trigger.Job})
+	}
+	result.Data = triggers
+}
+
+// NewTriggerHandler handles request of creating a trigger of a project.
+//
+// Arguments: "project" (path of the repository relative to {workspace}/src), optional "branch" (pushes of any branch
+// trigger if not specified) and "job" ("build", "test" or "" for pulling only). Responds the id, the URL and the
+// secret to configure in the webhook of GitHub/GitLab, the secret can't be got again.
+func NewTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	projectArg, _ := args["project"].(string)
+	project := filepath.ToSlash(filepath.Clean("/" + projectArg))[1:]
+	branch, _ := args["branch"].(string)
+	job, _ := args["job"].(string)
+	if "" == project || !gulu.File.IsDir(filepath.Join(invitationProjectPath(uid, project), ".git")) ||
+		("" != job && "build" != job && "test" != job) {
+		result.Code = -1
+
+		return
+	}
+
+	user := conf.GetUser(uid)
+	trigger, secret, err := user.NewTrigger(project, branch, job)
+	if nil != err {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	if !user.Save() {
+		user.RemoveTrigger(trigger.Id)
+		result.Code = -1
+
+		return
+	}
+
+	logger.Infof("User [%s] created trigger [%s] of project [%s]", uid, trigger.Id, project)
+	result.Data = map[string]interface{}{"id": trigger.Id, "url": triggerURL(trigger), "secret": secret}
+}
+
+// RemoveTriggerHandler handles request of removing a trigger.
+//
+// Arguments: "id" of the trigger.
+func RemoveTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	var args map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		logger.Error(err)
+		result.Code = -1
+
+		return
+	}
+
+	id, _ := args["id"].(string)
+	user := conf.GetUser(uid)
+	if !user.RemoveTrigger(id) || !user.Save() {
+		result.Code = -1
+
+		return
+	}
+
+	logger.Infof("User [%s] removed trigger [%s]", uid, id)
+}
+
+// triggerURL returns the URL of the endpoint of the specified trigger.
+func triggerURL(trigger *conf.Trigger) string {
+	return conf.Wide().Server + conf.Wide().Context + "/hook/" + trigger.Id
+}
@@ -35,6 +35,7 @@ const (
 	userExists      = "user exists"
 	userCreated     = "user created"
 	userCreateError = "user create error"
+	userSignUpOff   = "sign up disabled"
 )
 
 // Exclusive lock for adding user.
@@ -45,12 +46,12 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 	httpSession, _ := HTTPSession.Get(r, CookieName)
 
 	if httpSession.IsNew {
-		http.Redirect(w, r, "/login", http.StatusFound)
+		http.Redirect(w, r, conf.Wide().Context+"/login", http.StatusFound)
 
 		return
 	}
 
-	httpSession.Options.MaxAge = conf.Wide.HTTPSessionMaxAge
+	httpSession.Options.MaxAge = conf.Wide().HTTPSessionMaxAge
 	httpSession.Save(r, w)
 
 	uid := httpSession.Values["uid"].(string)
@@ -65,12 +66,12 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 		user.GoBuildArgsForWindows = strings.Replace(user.GoBuildArgsForWindows, `"`, `&quot;`, -1)
 		user.GoBuildArgsForDarwin = strings.Replace(user.GoBuildArgsForDarwin, `"`, `&quot;`, -1)
 
-		model := map[string]interface{}{"conf": conf.Wide, "i18n": i18n.GetAll(user.Locale), "user": user,
+		model := map[string]interface{}{"conf": conf.Wide(), "i18n": i18n.GetAll(user.Locale), "user": user,
 			"ver": conf.WideVersion, "goos": runtime.GOOS, "goarch": runtime.GOARCH, "gover": runtime.Version(),
 			"locales": i18n.GetLocalesNames(), "gofmts": gulu.Go.GetGoFormats(),
 			"themes": conf.GetThemes(), "editorThemes": conf.GetEditorThemes()}
 
-		t, err := template.ParseFiles("views/preference.html")
+		t, err := template.ParseFS(conf.Assets(), "views/preference.html")
 
 		if nil != err {
 			logger.Error(err)
@@ -106,6 +107,13 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 		Keymap                string
 		Workspace             string
 		Username              string
+		Email                 *string // nil if not submitted, keeps the current one
+		TimeZone              *string // nil if not submitted, keeps the current one
+		GoProxy               *string // nil if not submitted, keeps the current one
+		GoPrivate             *string // nil if not submitted, keeps the current one
+		GoNoSumDB             *string // nil if not submitted, keeps the current one
+		GoFlags               *string // nil if not submitted, keeps the current one
+		GoRoot                *string // nil if not submitted, keeps the current one
 		Locale                string
 		Theme                 string
 		EditorFontFamily      string
@@ -122,6 +130,28 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	timeZone := user.TimeZone
+	if nil != args.TimeZone {
+		timeZone = strings.TrimSpace(*args.TimeZone)
+		if _, err := time.LoadLocation(timeZone); nil != err {
+			result.Code = -1
+			result.Msg = i18n.Get(user.Locale, "invalid_time_zone", i18n.Params{"timeZone": timeZone}).(string)
+
+			return
+		}
+	}
+
+	goRoot := user.GoRoot
+	if nil != args.GoRoot {
+		goRoot = strings.TrimSpace(*args.GoRoot)
+		if "" != goRoot && !gulu.File.IsExist((&conf.User{GoRoot: goRoot}).GoExecutable()) {
+			result.Code = -1
+			result.Msg = i18n.Get(user.Locale, "invalid_go_root", i18n.Params{"goRoot": goRoot}).(string)
+
+			return
+		}
+	}
+
 	user.FontFamily = args.FontFamily
 	user.FontSize = args.FontSize
 	user.GoFormat = args.GoFmt
@@ -132,7 +162,26 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 	// XXX: disallow change workspace at present
 	// user.Workspace = args.Workspace
 
-	user.Locale = args.Locale
+	if nil != args.Email {
+		user.Email = strings.TrimSpace(*args.Email)
+	}
+	user.TimeZone = timeZone
+	user.GoRoot = goRoot
+	if nil != args.GoProxy {
+		user.GoProxy = strings.TrimSpace(*args.GoProxy)
+	}
+	if nil != args.GoPrivate {
+		user.GoPrivate = strings.TrimSpace(*args.GoPrivate)
+	}
+	if nil != args.GoNoSumDB {
+		user.GoNoSumDB = strings.TrimSpace(*args.GoNoSumDB)
+	}
+	if nil != args.GoFlags {
+		user.GoFlags = strings.TrimSpace(*args.GoFlags)
+	}
+	if i18n.Exists(args.Locale) {
+		user.Locale = args.Locale
+	}
 	user.Theme = args.Theme
 	user.Editor.FontFamily = args.EditorFontFamily
 	user.Editor.FontSize = args.EditorFontSize
@@ -148,11 +197,43 @@ func PreferenceHandler(w http.ResponseWriter, r *http.Request) {
 
 	if user.Save() {
 		result.Code = 0
+		AnnounceReload()
 	} else {
 		result.Code = -1
 	}
 }
 
+// ReloadConfHandler handles request of reloading the Wide configurations and users' configurations, only administrators
+// are allowed.
+func ReloadConfHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := HTTPSession.Get(r, CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	if err := conf.Reload(); nil != err {
+		logger.Errorf("Reloads configurations failed: %s", err)
+		result.Code = -1
+		result.Msg = i18n.Get(conf.GetUser(uid).Locale, "conf-reload-failed").(string)
+
+		return
+	}
+
+	AnnounceReload()
+	conf.AddAudit(uid, "conf.reload", "")
+}
+
 // FixedTimeSave saves online users' configurations periodically (1 minute).
 //
 // Main goal of this function is to save user session content, for restoring session content while user open Wide next time.
@@ -166,7 +247,8 @@ func FixedTimeSave() {
 	}()
 }
 
-// CanAccess determines whether the user specified by the given user id can access the specified path.
+// CanAccess determines whether the user specified by the given user id can access the specified path, paths of the
+// projects the user is invited to are accessible until the invitations expire (see Invitation).
 func CanAccess(userId, path string) bool {
 	path = filepath.FromSlash(path)
 
@@ -179,7 +261,20 @@ func CanAccess(userId, path string) bool {
 		}
 	}
 
-	return false
+	return scratchesAccess(userId, path) || invitedAccess(userId, path)
+}
+
+// scratchesAccess determines whether the specified path is in the scratches of the user specified by the given user id.
+func scratchesAccess(userId, path string) bool {
+	user := conf.GetUser(userId)
+	// user [playground] is a reserved mock user
+	if nil == user || "playground" == userId {
+		return false
+	}
+
+	rel, err := filepath.Rel(user.ScratchesPath(), path)
+
+	return nil == err && ".." != rel && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 // SaveOnlineUsers saves online users' configurations at once.
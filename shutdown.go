@@ -0,0 +1,101 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kwokhunglee/wide/output"
+	"github.com/kwokhunglee/wide/plugin"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/storage"
+)
+
+const (
+	// shutdownFlushWait is the time waiting for clients to save unsaved files and session content.
+	shutdownFlushWait = 3 * time.Second
+	// shutdownRequestTimeout is the max time waiting for in-flight requests to complete.
+	shutdownRequestTimeout = 10 * time.Second
+	// shutdownProcessTimeout is the max time waiting for interrupted user processes to exit before killing them.
+	shutdownProcessTimeout = 3 * time.Second
+)
+
+// HTTP servers to shut down.
+var servers []*http.Server
+
+// servers lock.
+var serversMutex sync.Mutex
+
+// addServer registers the specified HTTP server to shut down gracefully.
+func addServer(server *http.Server) {
+	serversMutex.Lock()
+	defer serversMutex.Unlock()
+
+	servers = append(servers, server)
+}
+
+// handleSignal handles system signal for graceful shutdown.
+func handleSignal() {
+	go func() {
+		c := make(chan os.Signal, 1)
+
+		signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+		s := <-c
+		logger.Infof("Got signal [%s], shutting down", s)
+
+		shutdown()
+
+		os.Exit(0)
+	}()
+}
+
+// shutdown shuts down Wide gracefully:
+//
+//  1. notifies connected clients to save unsaved files and session content, waits for them
+//  2. stops accepting new requests, waits for in-flight requests to complete
+//  3. stops running user processes and plugins
+//  4. synchronizes online users' workspaces with the object storage
+//  5. saves online users' configurations (session content)
+func shutdown() {
+	session.NotifyShutdown()
+	time.Sleep(shutdownFlushWait)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownRequestTimeout)
+	defer cancel()
+
+	serversMutex.Lock()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); nil != err {
+			logger.Warnf("Shuts down server [%s] failed: %s", server.Addr, err)
+		}
+	}
+	serversMutex.Unlock()
+	stopGRPC(ctx)
+
+	session.Processes.KillAll(shutdownProcessTimeout)
+	plugin.Stop()
+	output.StopContainers()
+
+	storage.Flush()
+
+	session.SaveOnlineUsers()
+	logger.Infof("Saved all online users, exit")
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2014-present, b3log.org
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * @file chat.js
+ *
+ * Chat channel of the workspace: messages are sent (chat) and received (chat, chat-history) via the session channel,
+ * a message could reference a line of a file, which is opened when the reference clicked.
+ *
+ * @version 1.0.0.0, Oct 17, 2026
+ */
+var chat = {
+    ref: undefined, // line referenced by the message being written, {path, line}
+    unread: 0, // number of messages received while the chat tab is not current
+    init: function () {
+        if (!config.features || !config.features.collab) {
+            return;
+        }
+
+        var $chat = $('.bottom-window-group .chat');
+        $('.bottom-window-group .tabs > div[data-index="chat"]').show().click(function () {
+            chat.unread = 0;
+            chat._refreshUnread();
+        });
+
+        $chat.find('input').keydown(function (event) {
+            if (13 === event.which) {
+                chat.send();
+                event.preventDefault();
+            }
+        });
+
+        $chat.find('.chat-ref').click(function () {
+            chat.reference();
+        });
+
+        $chat.on('click', '.messages .path', function (event) {
+            var tId = tree.getTIdByPath($(this).data("path"));
+            if (tId) {
+                tree.openFile(tree.fileTree.getNodeByTId(tId), CodeMirror.Pos($(this).data("line") - 1, 0));
+                tree.fileTree.selectNode(wide.curNode);
+            }
+            event.preventDefault();
+        });
+    },
+    // requests the recent messages, called after the session channel (re)connected
+    history: function () {
+        if (config.features && config.features.collab) {
+            chat._send({cmd: "chat-history"});
+        }
+    },
+    // toggles the reference to the cursor line of the current editor
+    reference: function () {
+        var $ref = $('.bottom-window-group .chat .chat-ref');
+        if (chat.ref || !wide.curEditor || !editors.getCurrentPath()) {
+            chat.ref = undefined;
+            $ref.text(config.label["chat-ref"]).removeClass("selected");
+
+            return;
+        }
+
+        chat.ref = {path: editors.getCurrentPath(), line: wide.curEditor.getCursor().line + 1};
+        $ref.text(chat._refText(chat.ref.path, chat.ref.line)).addClass("selected");
+    },
+    send: function () {
+        var $input = $('.bottom-window-group .chat input'),
+                text = $.trim($input.val());
+        if ("" === text) {
+            return;
+        }
+
+        var message = {cmd: "chat", text: text};
+        if (chat.ref) {
+            message.path = chat.ref.path;
+            message.line = chat.ref.line;
+        }
+
+        chat._send(message);
+        $input.val('');
+        if (chat.ref) {
+            chat.reference();
+        }
+    },
+    // handles the chat messages of the session channel
+    handle: function (data) {
+        var $messages = $('.bottom-window-group .chat .messages');
+        switch (data.cmd) {
+            case 'chat-history':
+                $messages.html('');
+                for (var i = 0, ii = data.messages.length; i < ii; i++) {
+                    $messages.append(chat._messageHTML(data.messages[i]));
+                }
+
+                break;
+            case 'chat':
+                $messages.append(chat._messageHTML(data.message));
+                if ("chat" !== bottomGroup.tabs.getCurrentId() && data.message.sid !== config.wideSessionId) {
+                    chat.unread++;
+                    chat._refreshUnread();
+                }
+
+                break;
+        }
+
+        $messages.parent().parent().scrollTop($messages[0].scrollHeight);
+    },
+    _messageHTML: function (message) {
+        var date = new Date(message.time),
+                pad = function (n) {
+                    return (10 > n ? "0" : "") + n;
+                },
+                html = '<div class="message"><span class="time">' + pad(date.getHours()) + ':'
+                + pad(date.getMinutes()) + '</span> <span class="user">' + $('<div/>').text(message.user).html()
+                + '</span> ';
+
+        if (message.path) {
+            html += '<a href="#" class="path" data-path="' + $('<div/>').text(message.path).html()
+                    + '" data-line="' + message.line + '">'
+                    + $('<div/>').text(chat._refText(message.path, message.line)).html() + '</a> ';
+        }
+
+        return html + '<span class="text">' + $('<div/>').text(message.text).html() + '</span></div>';
+    },
+    _refText: function (path, line) {
+        return path.substring(path.lastIndexOf("/") + 1) + ":" + line;
+    },
+    _refreshUnread: function () {
+        var label = config.label.chat;
+        if (0 < chat.unread) {
+            label += " (" + chat.unread + ")";
+        }
+        $('.bottom-window-group .tabs > div[data-index="chat"] span').text(label);
+    },
+    _send: function (message) {
+        try {
+            session.ws.send(JSON.stringify(message));
+        } catch (e) {
+            // reconnecting
+        }
+    }
+};
@@ -0,0 +1,565 @@
+/*
+ * Copyright (c) 2014-present, b3log.org
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * @file collab.js
+ *
+ * Collaborative editing: editors of the same file (in different tabs or of different users) send operations via the
+ * session channel, the server transforms them against the concurrent ones, applies them to its buffer of the file and
+ * broadcasts them. An editor has at most one operation awaiting the acknowledgement, edits meanwhile are composed into
+ * a buffer sent after it.
+ *
+ * Operations are arrays the same as the server (and ot.js): retain n as n, delete n as -n and insert as the string.
+ *
+ * Cursors and selections are shared as well, as [anchor, head] indexes on the revision sent only if there are no
+ * local edits awaiting, the server keeps them following the operations.
+ *
+ * @version 1.0.0.0, Oct 17, 2026
+ */
+var collab = {
+    // documents being edited collaboratively, <path, document>
+    docs: {},
+    enabled: function () {
+        return config.features && config.features.collab;
+    },
+    // starts editing the file of the specified path collaboratively with the specified editor
+    open: function (editor, path, pathtype) {
+        if (!collab.enabled() || "0" !== String(pathtype) || editor.getOption("readOnly")) {
+            return;
+        }
+
+        var doc = {
+            path: path,
+            pathtype: pathtype,
+            editor: editor,
+            loaded: editor.getValue(), // content loaded, local edits before opened are on it
+            text: editor.getValue(), // content of the editor the operations are applied to
+            rev: -1, // revision of the server known, -1 before opened
+            opened: false,
+            outstanding: null, // operation awaiting the acknowledgement
+            buffer: null, // operations to send after the outstanding one acknowledged
+            callbacks: [], // called after all local edits acknowledged
+            cursorPending: true, // whether the local cursor should be sent after synchronized
+            remotes: {} // cursors of the other editors, <sid, text markers>
+        };
+        collab.docs[path] = doc;
+
+        editor.on('changes', function () {
+            if (doc.applying || collab.docs[path] !== doc) {
+                return;
+            }
+
+            var text = editor.getValue(), op = collab.diff(doc.text, text);
+            if (collab.isNoop(op)) {
+                return;
+            }
+
+            doc.text = text;
+            collab._edit(doc, op);
+        });
+
+        editor.on('cursorActivity', function () {
+            if (doc.applying || collab.docs[path] !== doc || doc.cursorTimer) {
+                return;
+            }
+
+            doc.cursorTimer = setTimeout(function () {
+                doc.cursorTimer = undefined;
+                doc.cursorPending = true;
+                collab._sendCursor(doc);
+            }, 100);
+        });
+
+        collab._send({cmd: "doc-open", path: path, pathtype: pathtype});
+    },
+    // stops editing collaboratively with the specified editor
+    close: function (editor) {
+        for (var path in collab.docs) {
+            if (collab.docs[path].editor === editor) {
+                delete collab.docs[path];
+                collab._send({cmd: "doc-close", path: path});
+
+                return;
+            }
+        }
+    },
+    // checks whether the file of the specified path is being edited collaboratively
+    isShared: function (path) {
+        return undefined !== collab.docs[path];
+    },
+    // calls the specified callback after the local edits of the specified editor acknowledged, returns false if there
+    // are none to wait for
+    afterSynced: function (editor, callback) {
+        for (var path in collab.docs) {
+            var doc = collab.docs[path];
+            if (doc.editor === editor && (doc.outstanding || doc.buffer)) {
+                doc.callbacks.push(callback);
+
+                return true;
+            }
+        }
+
+        return false;
+    },
+    // opens the documents again after the session channel reconnected, with the revisions and the outstanding
+    // operations so that the server replays the missed operations
+    reconnect: function () {
+        for (var path in collab.docs) {
+            var doc = collab.docs[path], message = {cmd: "doc-open", path: path, pathtype: doc.pathtype};
+            if (-1 < doc.rev) {
+                message.rev = doc.rev;
+                if (doc.outstanding) {
+                    message.op = doc.outstanding;
+                }
+            }
+
+            collab._send(message);
+        }
+    },
+    disconnected: function () {
+        for (var path in collab.docs) {
+            collab.docs[path].opened = false;
+        }
+    },
+    // handles the doc-* messages of the session channel
+    handle: function (data) {
+        var doc = collab.docs[data.path];
+        if (!doc) {
+            return;
+        }
+
+        switch (data.cmd) {
+            case 'doc-opened':
+                collab._opened(doc, data);
+
+                break;
+            case 'doc-ack':
+                doc.rev = data.rev;
+                doc.outstanding = null;
+                if (doc.buffer) {
+                    doc.outstanding = doc.buffer;
+                    doc.buffer = null;
+                    collab._send({cmd: "doc-op", path: doc.path, rev: doc.rev, op: doc.outstanding});
+                } else {
+                    collab._synced(doc);
+                }
+
+                break;
+            case 'doc-op':
+                doc.rev = data.rev;
+
+                var op = data.op, pair;
+                if (doc.outstanding) {
+                    pair = collab.transform(doc.outstanding, op);
+                    doc.outstanding = pair[0];
+                    op = pair[1];
+                }
+                if (doc.buffer) {
+                    pair = collab.transform(doc.buffer, op);
+                    doc.buffer = pair[0];
+                    op = pair[1];
+                }
+                collab._apply(doc, op);
+
+                break;
+            case 'doc-cursor':
+                collab._showCursor(doc, data);
+
+                break;
+            case 'doc-saved':
+                if (data.rev === doc.rev && !doc.outstanding && !doc.buffer) {
+                    collab._markClean(doc);
+                }
+
+                break;
+            case 'doc-error':
+                // falls back to editing alone, saving posts the content of the editor
+                console.log('[collab] ' + data.path + ': ' + data.msg);
+                delete collab.docs[data.path];
+                collab._synced(doc);
+
+                break;
+        }
+    },
+    _opened: function (doc, data) {
+        doc.opened = true;
+        for (var sid in doc.remotes) { // sent again after opened
+            collab._clearCursor(doc, sid);
+        }
+
+        if (data.replay) { // the missed operations follow
+            doc.rev = data.rev;
+            if (!doc.outstanding && doc.buffer) {
+                doc.outstanding = doc.buffer;
+                doc.buffer = null;
+                collab._send({cmd: "doc-op", path: doc.path, rev: doc.rev, op: doc.outstanding});
+            }
+
+            return;
+        }
+
+        var local = null, remote = null;
+        if (-1 === doc.rev) { // opened the first time, local edits are on the content loaded
+            remote = collab.diff(doc.loaded, data.content);
+            local = doc.buffer;
+            if (local) {
+                var pair = collab.transform(local, remote);
+                local = pair[0];
+                remote = pair[1];
+            }
+        } else if (doc.outstanding || doc.buffer) { // the server lost the history, keeps the local content
+            local = collab.diff(data.content, doc.text);
+        } else {
+            remote = collab.diff(doc.text, data.content);
+        }
+
+        doc.rev = data.rev;
+        doc.outstanding = null;
+        doc.buffer = null;
+        if (remote && !collab.isNoop(remote)) {
+            collab._apply(doc, remote);
+        }
+
+        if (local && !collab.isNoop(local)) {
+            collab._edit(doc, local);
+
+            return;
+        }
+
+        if (!data.dirty) {
+            collab._markClean(doc);
+        }
+        collab._synced(doc);
+    },
+    _edit: function (doc, op) {
+        if (doc.opened && !doc.outstanding) {
+            doc.outstanding = op;
+            collab._send({cmd: "doc-op", path: doc.path, rev: doc.rev, op: op});
+
+            return;
+        }
+
+        doc.buffer = doc.buffer ? collab.compose(doc.buffer, op) : op;
+    },
+    // applies the specified remote operation to the document and its editor
+    _apply: function (doc, op) {
+        var text = doc.text, index = 0, result = "", i, c;
+        for (i = 0; i < op.length; i++) {
+            c = op[i];
+            if ("string" === typeof c) {
+                result += c;
+            } else if (0 < c) {
+                result += text.substr(index, c);
+                index += c;
+            } else {
+                index -= c;
+            }
+        }
+        doc.text = result;
+
+        var editor = doc.editor;
+        doc.applying = true;
+        editor.operation(function () {
+            var index = 0;
+            for (var i = 0; i < op.length; i++) {
+                var c = op[i];
+                if ("string" === typeof c) {
+                    editor.replaceRange(c, editor.posFromIndex(index));
+                    index += c.length;
+                } else if (0 < c) {
+                    index += c;
+                } else {
+                    editor.replaceRange("", editor.posFromIndex(index), editor.posFromIndex(index - c));
+                }
+            }
+        });
+        doc.applying = false;
+    },
+    _synced: function (doc) {
+        collab._sendCursor(doc);
+
+        var callbacks = doc.callbacks;
+        doc.callbacks = [];
+        for (var i = 0; i < callbacks.length; i++) {
+            callbacks[i]();
+        }
+    },
+    _sendCursor: function (doc) {
+        if (!doc.cursorPending || !doc.opened || doc.outstanding || doc.buffer || collab.docs[doc.path] !== doc) {
+            return;
+        }
+
+        var editor = doc.editor, selections = editor.listSelections(), ranges = [];
+        for (var i = 0; i < selections.length; i++) {
+            ranges.push([editor.indexFromPos(selections[i].anchor), editor.indexFromPos(selections[i].head)]);
+        }
+        doc.cursorPending = false;
+        collab._send({cmd: "doc-cursor", path: doc.path, rev: doc.rev, ranges: ranges});
+    },
+    // shows the cursors of another editor, the indexes are of the revision and moved by the local edits awaiting
+    _showCursor: function (doc, data) {
+        collab._clearCursor(doc, data.sid);
+        if (!data.ranges.length) {
+            return;
+        }
+
+        var editor = doc.editor, hue = 0, marks = [], i;
+        for (i = 0; i < data.sid.length; i++) {
+            hue = (hue * 31 + data.sid.charCodeAt(i)) % 360;
+        }
+        var color = "hsl(" + hue + ", 70%, 45%)";
+
+        for (i = 0; i < data.ranges.length; i++) {
+            var anchor = data.ranges[i][0], head = data.ranges[i][1];
+            if (doc.outstanding) {
+                anchor = collab.transformIndex(doc.outstanding, anchor);
+                head = collab.transformIndex(doc.outstanding, head);
+            }
+            if (doc.buffer) {
+                anchor = collab.transformIndex(doc.buffer, anchor);
+                head = collab.transformIndex(doc.buffer, head);
+            }
+
+            if (anchor !== head) {
+                marks.push(editor.markText(editor.posFromIndex(Math.min(anchor, head)),
+                        editor.posFromIndex(Math.max(anchor, head)),
+                        {css: "background-color: hsla(" + hue + ", 70%, 45%, .25)"}));
+            }
+
+            var widget = document.createElement("span");
+            widget.className = "collab-cursor";
+            widget.style.borderLeftColor = color;
+            widget.title = data.user;
+            if (0 === i) {
+                var name = document.createElement("span");
+                name.className = "collab-name";
+                name.style.backgroundColor = color;
+                name.appendChild(document.createTextNode(data.user));
+                name.onmousedown = (function (sid, user) {
+                    return function (event) {
+                        event.preventDefault();
+                        follow.start(sid, user);
+                    };
+                })(data.sid, data.user);
+                widget.appendChild(name);
+            }
+            marks.push(editor.setBookmark(editor.posFromIndex(head), {widget: widget}));
+        }
+
+        doc.remotes[data.sid] = marks;
+    },
+    _clearCursor: function (doc, sid) {
+        var marks = doc.remotes[sid] || [];
+        for (var i = 0; i < marks.length; i++) {
+            marks[i].clear();
+        }
+        delete doc.remotes[sid];
+    },
+    _markClean: function (doc) {
+        doc.editor.doc.markClean();
+        $(".edit-panel .tabs > div").each(function () {
+            var $span = $(this).find("span:eq(0)");
+            if ($span.attr("title") === doc.path) {
+                $span.removeClass("changed");
+            }
+        });
+    },
+    _send: function (message) {
+        try {
+            session.ws.send(JSON.stringify(message));
+        } catch (e) {
+            // reconnecting, documents are opened again after reconnected
+        }
+    },
+    // appends the specified component to the specified operation, an insert is always put before the adjacent delete
+    _push: function (op, c) {
+        if (0 === c || "" === c) {
+            return op;
+        }
+
+        var last = op[op.length - 1];
+        if ("string" === typeof c) {
+            if ("string" === typeof last) {
+                op[op.length - 1] = last + c;
+            } else if (0 > last) {
+                if ("string" === typeof op[op.length - 2]) {
+                    op[op.length - 2] += c;
+                } else {
+                    op.splice(op.length - 1, 0, c);
+                }
+            } else {
+                op.push(c);
+            }
+
+            return op;
+        }
+
+        if ("number" === typeof last && (0 < last) === (0 < c)) {
+            op[op.length - 1] = last + c;
+        } else {
+            op.push(c);
+        }
+
+        return op;
+    },
+    isNoop: function (op) {
+        return 0 === op.length || (1 === op.length && "number" === typeof op[0] && 0 < op[0]);
+    },
+    // returns an operation turning the text from into the text to, surrogate pairs are never split
+    diff: function (from, to) {
+        var prefix = 0, suffix = 0;
+        while (prefix < from.length && prefix < to.length && from.charCodeAt(prefix) === to.charCodeAt(prefix)) {
+            prefix++;
+        }
+        if (0 < prefix && /[\ud800-\udbff]/.test(from.charAt(prefix - 1))) {
+            prefix--;
+        }
+        while (suffix < from.length - prefix && suffix < to.length - prefix
+                && from.charCodeAt(from.length - 1 - suffix) === to.charCodeAt(to.length - 1 - suffix)) {
+            suffix++;
+        }
+        if (0 < suffix && /[\udc00-\udfff]/.test(from.charAt(from.length - suffix))) {
+            suffix--;
+        }
+
+        var op = [];
+        collab._push(op, prefix);
+        collab._push(op, -(from.length - prefix - suffix));
+        collab._push(op, to.substring(prefix, to.length - suffix));
+        collab._push(op, suffix);
+
+        return op;
+    },
+    // transforms the concurrent operations a and b to [a', b'], inserts of a at the same position go first
+    transform: function (a, b) {
+        var aPrime = [], bPrime = [], i = 0, j = 0, c1 = a[i++], c2 = b[j++], n;
+        while (undefined !== c1 || undefined !== c2) {
+            if ("string" === typeof c1) {
+                collab._push(aPrime, c1);
+                collab._push(bPrime, c1.length);
+                c1 = a[i++];
+                continue;
+            }
+            if ("string" === typeof c2) {
+                collab._push(aPrime, c2.length);
+                collab._push(bPrime, c2);
+                c2 = b[j++];
+                continue;
+            }
+            if (undefined === c1 || undefined === c2) {
+                throw new Error("concurrent operations have different lengths");
+            }
+
+            if (0 < c1 && 0 < c2) {
+                n = Math.min(c1, c2);
+                collab._push(aPrime, n);
+                collab._push(bPrime, n);
+                c1 -= n;
+                c2 -= n;
+            } else if (0 > c1 && 0 > c2) { // deleted by both
+                n = Math.min(-c1, -c2);
+                c1 += n;
+                c2 += n;
+            } else if (0 > c1) {
+                n = Math.min(-c1, c2);
+                collab._push(aPrime, -n);
+                c1 += n;
+                c2 -= n;
+            } else {
+                n = Math.min(c1, -c2);
+                collab._push(bPrime, -n);
+                c1 -= n;
+                c2 += n;
+            }
+
+            if (0 === c1) {
+                c1 = a[i++];
+            }
+            if (0 === c2) {
+                c2 = b[j++];
+            }
+        }
+
+        return [aPrime, bPrime];
+    },
+    // transforms the specified index of the text the operation applies to into the index after applied
+    transformIndex: function (op, index) {
+        var ret = index;
+        for (var i = 0; i < op.length && 0 <= index; i++) {
+            if ("string" === typeof op[i]) {
+                ret += op[i].length;
+            } else if (0 < op[i]) {
+                index -= op[i];
+            } else {
+                ret -= Math.min(index, -op[i]);
+                index += op[i];
+            }
+        }
+
+        return ret;
+    },
+    // composes the consecutive operations a and b into one
+    compose: function (a, b) {
+        var ret = [], i = 0, j = 0, c1 = a[i++], c2 = b[j++], n;
+        while (undefined !== c1 || undefined !== c2) {
+            if ("number" === typeof c1 && 0 > c1) {
+                collab._push(ret, c1);
+                c1 = a[i++];
+                continue;
+            }
+            if ("string" === typeof c2) {
+                collab._push(ret, c2);
+                c2 = b[j++];
+                continue;
+            }
+            if (undefined === c1 || undefined === c2) {
+                throw new Error("consecutive operations have mismatched lengths");
+            }
+
+            if ("string" === typeof c1) {
+                if (0 > c2) { // inserted then deleted
+                    n = Math.min(c1.length, -c2);
+                    c1 = c1.substring(n);
+                    c2 += n;
+                } else {
+                    n = Math.min(c1.length, c2);
+                    collab._push(ret, c1.substring(0, n));
+                    c1 = c1.substring(n);
+                    c2 -= n;
+                }
+            } else if (0 > c2) { // retained then deleted
+                n = Math.min(c1, -c2);
+                collab._push(ret, -n);
+                c1 -= n;
+                c2 += n;
+            } else {
+                n = Math.min(c1, c2);
+                collab._push(ret, n);
+                c1 -= n;
+                c2 -= n;
+            }
+
+            if (0 === c1 || "" === c1) {
+                c1 = a[i++];
+            }
+            if (0 === c2) {
+                c2 = b[j++];
+            }
+        }
+
+        return ret;
+    }
+};
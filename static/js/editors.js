@@ -32,6 +32,44 @@ var editors = {
             }
         }
     },
+    // 从服务端重新加载指定路径（文件或目录）下打开的文件，有未保存修改的编辑器保持不变，协同编辑的文件由服务端同步
+    reload: function (path) {
+        for (var i = 0, ii = editors.data.length; i < ii; i++) {
+            var id = editors.data[i].id, editor = editors.data[i].editor;
+            if ((id !== path && 0 !== id.indexOf(path + '/')) || !editor.doc.isClean() || collab.isShared(id)) {
+                continue;
+            }
+
+            (function (id, editor) {
+                var request = newWideRequest();
+                request.path = id;
+                request.pathtype = $('.edit-panel .tabs span[title="' + id + '"]').attr("pathtype");
+
+                $.ajax({
+                    type: 'POST',
+                    url: '/file',
+                    data: JSON.stringify(request),
+                    dataType: "json",
+                    success: function (result) {
+                        if (0 != result.code || !editor.doc.isClean()) {
+                            return;
+                        }
+
+                        editor.savedText = result.data.content;
+                        if (editor.getValue() === result.data.content) {
+                            return;
+                        }
+
+                        var cursor = editor.getCursor(), scrollInfo = editor.getScrollInfo();
+                        editor.setValue(result.data.content);
+                        editor.setCursor(cursor);
+                        editor.scrollTo(null, scrollInfo.top);
+                        editor.doc.markClean();
+                    }
+                });
+            })(id, editor);
+        }
+    },
     close: function () {
         $('.edit-panel .tabs > div[data-index="' + $('.edit-panel .frame').data('index') + ']').find('.ico-close').click();
     },
@@ -172,13 +210,14 @@ var editors = {
                 // 移除编辑器
                 for (var i = 0, ii = editors.data.length; i < ii; i++) {
                     if (editors.data[i].id === id) {
+                        collab.close(editors.data[i].editor);
                         editors.data.splice(i, 1);
                         break;
                     }
                 }
 
                 if (editors.data.length === 0) { // 起始页可能存在，所以用编辑器数据判断
-                    menu.disabled(['save-all', 'build', 'run', 'go-test', 'go-vet', 'go-mod', 'go-install',
+                    menu.disabled(['save-all', 'build', 'run', 'go-test', 'go-vet', 'protoc', 'go-mod', 'go-install',
                         'find', 'find-next', 'find-previous', 'replace', 'replace-all',
                         'format', 'autocomplete', 'jump-to-decl', 'expr-info', 'find-usages', 'toggle-comment',
                         'edit']);
@@ -640,19 +679,20 @@ var editors = {
             cm.extendSelection(word.anchor, word.head);
         };
     },
-    appendSearch: function (data, type, key) {
-        var searcHTML = '<ul class="list">',
-                key = key.toLowerCase();
+    // 搜索结果条目，有 token 时追加“更多”条目用于加载下一页
+    _searchItemsHTML: function (data, key, token) {
+        var searcHTML = '',
+                lowerCaseKey = key.toLowerCase();
 
         for (var i = 0, ii = data.length; i < ii; i++) {
             var contents = '',
                     lowerCaseContents = data[i].contents[0].toLowerCase(),
-                    matches = lowerCaseContents.split(key),
+                    matches = lowerCaseContents.split(lowerCaseKey),
                     startIndex = 0,
                     endIndex = 0;
             for (var j = 0, max = matches.length; j < max; j++) {
                 startIndex = endIndex + matches[j].length;
-                endIndex = startIndex + key.length;
+                endIndex = startIndex + lowerCaseKey.length;
                 var keyWord = data[i].contents[0].substring(startIndex, endIndex);
                 if (keyWord !== '') {
                     keyWord = '<b>' + keyWord + '</b>';
@@ -667,6 +707,40 @@ var editors = {
                     + data[i].ch + ')</i></span></li>';
         }
 
+        if (token) {
+            searcHTML += '<li class="search-more" data-token="' + token + '" data-key="'
+                    + key.replace(/&/g, '&amp;').replace(/"/g, '&quot;') + '">' + config.label['search-more'] + '</li>';
+        }
+
+        return searcHTML;
+    },
+    // 加载搜索结果的下一页
+    searchMore: function ($more) {
+        var request = newWideRequest();
+        request.token = $more.data("token");
+
+        $more.removeClass("search-more");
+        $.ajax({
+            type: 'POST',
+            url: '/file/search/text',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code) {
+                    $more.remove();
+                    $("#dialogAlert").dialog("open", result.msg);
+
+                    return;
+                }
+
+                $more.replaceWith(editors._searchItemsHTML(result.data.snippets, String($more.data("key")),
+                        result.data.token));
+            }
+        });
+    },
+    appendSearch: function (data, type, key, token) {
+        var searcHTML = '<ul class="list">' + editors._searchItemsHTML(data, key, token);
+
         if (data.length === 0) {
             searcHTML += '<li>' + config.label.search_no_match + '</li>';
         }
@@ -692,9 +766,17 @@ var editors = {
                 $(this).addClass("selected");
             });
 
+            $search.on("click", "li.search-more", function () {
+                editors.searchMore($(this));
+            });
+
             $search.on("dblclick", "li", function () {
-                var $it = $(this),
-                        tId = tree.getTIdByPath($it.attr("title"));
+                var $it = $(this);
+                if (!$it.attr("title")) { // “更多”或没有匹配
+                    return;
+                }
+
+                var tId = tree.getTIdByPath($it.attr("title"));
                 tree.openFile(tree.fileTree.getNodeByTId(tId));
                 tree.fileTree.selectNode(wide.curNode);
 
@@ -740,7 +822,7 @@ var editors = {
             content: '<textarea id="editor' + id + '"></textarea>'
         });
 
-        menu.undisabled(['save-all', 'close-all', 'build', 'run', 'go-test', 'go-vet', 'go-mod', 'go-install',
+        menu.undisabled(['save-all', 'close-all', 'build', 'run', 'go-test', 'go-vet', 'protoc', 'go-mod', 'go-install',
             'find', 'find-next', 'find-previous', 'replace', 'replace-all',
             'format', 'autocomplete', 'jump-to-decl', 'expr-info', 'find-usages', 'toggle-comment',
             'edit']);
@@ -804,6 +886,9 @@ var editors = {
             }
         });
 
+        // 文件上次打开或保存的内容，保存时只发送相对它的补丁
+        editor.savedText = data.content;
+
         if ("text/html" === data.mode) {
             emmetCodeMirror(editor);
         }
@@ -892,6 +977,8 @@ var editors = {
             "editor": editor,
             "id": id
         });
+        collab.open(editor, wide.curNode.path, wide.curNode.pathtype);
+        follow.attach(editor, wide.curNode.path);
 
         $(".footer .cursor").text('|   ' + (cursor.line + 1) + ':' + (cursor.ch + 1) + '   |');
 
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2014-present, b3log.org
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * @file follow.js
+ *
+ * Follow mode for pair programming: the follower's editor tracks the open file and the scroll position of the host
+ * session, the host sends them (nav) via the session channel only while followed.
+ *
+ * @version 1.0.0.0, Oct 17, 2026
+ */
+var follow = {
+    host: undefined, // session followed, {sid, user}
+    followers: 0, // number of sessions following this one
+    init: function () {
+        $(".footer .follow").click(function () {
+            if (follow.host) {
+                follow.stop();
+            }
+        });
+    },
+    // starts following the specified session of the specified user after confirmed
+    start: function (sid, user) {
+        if ((follow.host && sid === follow.host.sid)
+                || !confirm(config.label["follow-confirm"].replace("%s", user))) {
+            return;
+        }
+
+        follow._send({cmd: "follow", sid: sid});
+    },
+    stop: function () {
+        follow._send({cmd: "unfollow"});
+        follow.host = undefined;
+        follow._refresh();
+    },
+    // sends navigation of the specified editor while followed
+    attach: function (editor, path) {
+        var nav = function () {
+            if (0 === follow.followers || follow.navTimer) {
+                return;
+            }
+
+            follow.navTimer = setTimeout(function () {
+                follow.navTimer = undefined;
+                if (wide.curEditor === editor) {
+                    follow._nav(editor, path);
+                }
+            }, 200);
+        };
+
+        editor.on('focus', nav);
+        editor.on('scroll', nav);
+    },
+    // handles the follow messages of the session channel
+    handle: function (data) {
+        switch (data.cmd) {
+            case 'following':
+                follow.host = {sid: data.sid, user: data.user};
+                follow._refresh();
+
+                break;
+            case 'follow-stopped':
+                if (data.msg) {
+                    $("#dialogAlert").dialog("open", data.msg);
+                }
+                if (follow.host && data.sid === follow.host.sid) {
+                    follow.host = undefined;
+                    follow._refresh();
+                }
+
+                break;
+            case 'followers':
+                follow.followers = data.count;
+                follow._refresh();
+                if (0 < follow.followers && wide.curEditor) {
+                    follow._nav(wide.curEditor, editors.getCurrentPath());
+                }
+
+                break;
+            case 'nav':
+                if (!follow.host || data.sid !== follow.host.sid) {
+                    return;
+                }
+
+                if (editors.getCurrentPath() !== data.path) {
+                    var tId = tree.getTIdByPath(data.path);
+                    if (!tId) {
+                        return;
+                    }
+
+                    tree.openFile(tree.fileTree.getNodeByTId(tId));
+                    tree.fileTree.selectNode(wide.curNode);
+                }
+
+                if (wide.curEditor) {
+                    wide.curEditor.scrollTo(null, wide.curEditor.heightAtLine(Math.max(0, data.top), "local"));
+                }
+
+                break;
+        }
+    },
+    _nav: function (editor, path) {
+        if (!path) {
+            return;
+        }
+
+        follow._send({cmd: "nav", path: path, top: editor.lineAtHeight(editor.getScrollInfo().top, "local")});
+    },
+    _refresh: function () {
+        var $follow = $(".footer .follow");
+        if (follow.host) {
+            $follow.text(config.label["follow-following"].replace("%s", follow.host.user))
+                    .attr("title", config.label["follow-stop"]).show();
+        } else if (0 < follow.followers) {
+            $follow.text(config.label["follow-followers"].replace("%d", follow.followers)).attr("title", "").show();
+        } else {
+            $follow.hide();
+        }
+    },
+    _send: function (message) {
+        try {
+            session.ws.send(JSON.stringify(message));
+        } catch (e) {
+            // reconnecting
+        }
+    }
+};
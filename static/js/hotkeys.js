@@ -141,6 +141,13 @@ var hotkeys = {
             shiftKey: true,
             which: 79
         },
+        // Shift-Alt-S 跳转到符号
+        goSymbol: {
+            ctrlKey: false,
+            altKey: true,
+            shiftKey: true,
+            which: 83
+        },
         // F5 Build
         build: {
             ctrlKey: false,
@@ -499,6 +506,13 @@ var hotkeys = {
                     && event.which === hotKeys.goFile.which) { // Shift-Alt-O 跳转到文件
                 $("#dialogGoFilePrompt").dialog("open");
             }
+
+            if (event.ctrlKey === hotKeys.goSymbol.ctrlKey
+                    && event.altKey === hotKeys.goSymbol.altKey
+                    && event.shiftKey === hotKeys.goSymbol.shiftKey
+                    && event.which === hotKeys.goSymbol.which) { // Shift-Alt-S 跳转到符号
+                $("#dialogGoSymbolPrompt").dialog("open");
+            }
         });
     },
     init: function () {
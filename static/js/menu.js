@@ -154,6 +154,41 @@ var menu = {
         // 开始关闭
         $(".edit-panel .tabs .ico-close:eq(0)").click();
     },
+    // 新建草稿：草稿保存在数据目录下，不属于任何项目
+    newScratch: function () {
+        var ext = prompt(config.label["scratch-prompt"], "txt");
+        if (null === ext) {
+            return;
+        }
+
+        var request = newWideRequest();
+        request.ext = $.trim(ext);
+
+        $.ajax({
+            type: 'POST',
+            url: '/scratch/new',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code) {
+                    if (result.msg) {
+                        $("#dialogAlert").dialog("open", result.msg);
+                    }
+
+                    return;
+                }
+
+                var root = tree.fileTree.getNodeByTId(tree.getTIdByPath("/scratches"));
+                if (!root) {
+                    return;
+                }
+
+                var node = tree.fileTree.addNodes(root, result.data)[0];
+                tree.openFile(node);
+                tree.fileTree.selectNode(node);
+            }
+        });
+    },
     exit: function () {
         var request = newWideRequest();
 
@@ -164,7 +199,7 @@ var menu = {
             dataType: "json",
             success: function (result) {
                 if (0 == result.code) {
-                    window.location.href = "/login";
+                    window.location.href = config.context + "/login";
                 }
             }
         });
@@ -258,6 +293,38 @@ var menu = {
             }
         });
     },
+    // protoc.
+    protoc: function () {
+        menu.saveAllFiles();
+
+        var currentPath = editors.getCurrentPath();
+        if (!currentPath) {
+            return false;
+        }
+
+        if ($(".menu li.protoc").hasClass("disabled")) {
+            return false;
+        }
+
+        var request = newWideRequest();
+        request.file = currentPath;
+        request.pathtype = 0;
+
+        $.ajax({
+            type: 'POST',
+            url: '/protoc',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+                }
+            }
+        });
+    },
     // Build & Run.
     run: function () {
         menu.saveAllFiles();
@@ -341,7 +408,7 @@ var menu = {
                         isChange = true;
                     }
 
-                    if ($.trim($it.val()) === '') {
+                    if ($.trim($it.val()) === '' && !$it.data("optional")) {
                         emptys.push($it);
                     }
                 });
@@ -408,6 +475,12 @@ var menu = {
                             $workspace = $dialogPreference.find("input[name=workspace]"),
                             $password = $dialogPreference.find("input[name=password]"),
                             $email = $dialogPreference.find("input[name=email]"),
+                            $timeZone = $dialogPreference.find("input[name=timeZone]"),
+                            $goProxy = $dialogPreference.find("input[name=goProxy]"),
+                            $goPrivate = $dialogPreference.find("input[name=goPrivate]"),
+                            $goNoSumDB = $dialogPreference.find("input[name=goNoSumDB]"),
+                            $goFlags = $dialogPreference.find("input[name=goFlags]"),
+                            $goRoot = $dialogPreference.find("input[name=goRoot]"),
                             $locale = $dialogPreference.find("select[name=locale]"),
                             $theme = $dialogPreference.find("select[name=theme]"),
                             $editorFontFamily = $dialogPreference.find("input[name=editorFontFamily]"),
@@ -426,6 +499,13 @@ var menu = {
                         "GoBuildArgsForDarwin": $GoBuildArgsForDarwin.val(),
                         "workspace": $workspace.val(),
                         "password": $password.val(),
+                        "email": $email.val(),
+                        "timeZone": $timeZone.val(),
+                        "goProxy": $goProxy.val(),
+                        "goPrivate": $goPrivate.val(),
+                        "goNoSumDB": $goNoSumDB.val(),
+                        "goFlags": $goFlags.val(),
+                        "goRoot": $goRoot.val(),
                         "locale": $locale.val(),
                         "theme": $theme.val(),
                         "editorFontFamily": $editorFontFamily.val(),
@@ -446,9 +526,12 @@ var menu = {
                         data: JSON.stringify(request),
                         success: function (result, textStatus, jqXHR) {
                             if (0 != result.code) {
+                                $dialogPreference.find(".tip").html(result.msg);
+
                                 return false;
                             }
 
+                            $dialogPreference.find(".tip").html("");
                             $fontFamily.data("value", $fontFamily.val());
                             $fontSize.data("value", $fontSize.val());
                             $goFmt.data("value", $goFmt.val());
@@ -458,6 +541,12 @@ var menu = {
                             $workspace.data("value", $workspace.val());
                             $password.data("value", $password.val());
                             $email.data("value", $email.val());
+                            $timeZone.data("value", $timeZone.val());
+                            $goProxy.data("value", $goProxy.val());
+                            $goPrivate.data("value", $goPrivate.val());
+                            $goNoSumDB.data("value", $goNoSumDB.val());
+                            $goFlags.data("value", $goFlags.val());
+                            $goRoot.data("value", $goRoot.val());
                             $locale.data("value", $locale.val());
                             $theme.data("value", $theme.val());
                             $editorFontFamily.data("value", $editorFontFamily.val());
@@ -473,7 +562,7 @@ var menu = {
                             var $okBtn = $("#dialogPreference").closest(".dialog-main").find(".dialog-footer > button:eq(0)");
                             $okBtn.prop("disabled", true);
 
-                            $("#themesLink").attr("href", '/static/css/themes/' + $theme.val() + '.css');
+                            $("#themesLink").attr("href", config.context + '/static/css/themes/' + $theme.val() + '.css');
 
                             config.editorTheme = $editorTheme.val();
                             for (var i = 0, ii = editors.data.length; i < ii; i++) {
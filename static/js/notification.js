@@ -30,6 +30,78 @@ var notification = {
         });
 
         this._initWS();
+        this._initPush();
+    },
+    _initPush: function () {
+        if (!('serviceWorker' in navigator) || !('PushManager' in window) || !window.isSecureContext) {
+            return;
+        }
+
+        navigator.serviceWorker.register(config.context + '/static/js/push-sw.js').then(function (registration) {
+            notification._pushRegistration = registration;
+
+            return registration.pushManager.getSubscription();
+        }).then(function (subscription) {
+            notification._setPushLabel(null !== subscription);
+            $(".menu li.push-notification").show();
+        }).catch(function (e) {
+            console.log('[notification push]', e);
+        });
+    },
+    _setPushLabel: function (subscribed) {
+        $(".menu li.push-notification > span:eq(1)").text(subscribed
+                ? config.label.disable_desktop_notification : config.label.enable_desktop_notification);
+    },
+    togglePush: function () {
+        var pushManager = notification._pushRegistration.pushManager;
+
+        pushManager.getSubscription().then(function (subscription) {
+            if (subscription) {
+                return subscription.unsubscribe().then(function () {
+                    $.ajax({
+                        type: 'POST',
+                        url: '/notification/push/unsubscribe',
+                        data: JSON.stringify({endpoint: subscription.endpoint}),
+                        dataType: "json"
+                    });
+                    notification._setPushLabel(false);
+                });
+            }
+
+            $.ajax({
+                type: 'GET',
+                url: '/notification/push/key',
+                dataType: "json",
+                success: function (result) {
+                    if (0 !== result.code) {
+                        return;
+                    }
+
+                    // base64url to Uint8Array
+                    var base64 = (result.data + '='.repeat((4 - result.data.length % 4) % 4))
+                            .replace(/-/g, '+').replace(/_/g, '/'),
+                            raw = window.atob(base64),
+                            key = new Uint8Array(raw.length);
+                    for (var i = 0, ii = raw.length; i < ii; i++) {
+                        key[i] = raw.charCodeAt(i);
+                    }
+
+                    pushManager.subscribe({userVisibleOnly: true, applicationServerKey: key}).then(function (subscription) {
+                        $.ajax({
+                            type: 'POST',
+                            url: '/notification/push/subscribe',
+                            data: JSON.stringify(subscription.toJSON()),
+                            dataType: "json",
+                            success: function (result) {
+                                notification._setPushLabel(0 === result.code);
+                            }
+                        });
+                    }).catch(function (e) {
+                        console.log('[notification push]', e);
+                    });
+                }
+            });
+        });
     },
     _initWS: function () {
         var notificationWS = new ReconnectingWebSocket(config.channel + '/notification/ws?sid=' + config.wideSessionId);
@@ -50,11 +122,15 @@ var notification = {
             }
 
             notificationHTML += '<tr><td class="severity">' + data.severity
-                    + '</td><td class="message">' + data.message
-                    + '</td><td class="type">' + data.type + '</td></tr>';
+                    + '</td><td class="message">' + (data.time ? '<span class="time">' + data.time + '</span>' : '')
+                    + data.message + '</td><td class="type">' + data.type + '</td></tr>';
             $notification.append(notificationHTML);
 
             $(".notification-count").show();
+
+            if ("Broadcast" === data.type && "INFO" !== data.severity) { // such as "maintenance in 10 minutes"
+                $("#dialogAlert").dialog("open", data.message);
+            }
         };
 
         notificationWS.onclose = function (e) {
@@ -375,7 +375,7 @@ var playground = {
                     return;
                 }
 
-                var url = window.location.protocol + "//" + window.location.host + '/playground/' + data.fileName;
+                var url = window.location.protocol + "//" + window.location.host + config.context + '/playground/' + data.fileName;
 
                 var request = newWideRequest();
                 request.url = url;
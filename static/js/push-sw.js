@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2014-present, b3log.org
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * @file push-sw.js
+ *
+ * @version 1.0.0.0, Oct 17, 2026
+ */
+// service worker showing Web Push notifications of background jobs (builds and tests)
+
+// URL of Wide, such as "https://example.com/wide/", the worker is registered with scope "{Wide URL}static/js/"
+var wideURL = self.registration.scope.replace(/static\/js\/$/, '');
+
+self.addEventListener('push', function (event) {
+    var data = event.data ? event.data.json() : {};
+
+    event.waitUntil(self.clients.matchAll({type: 'window', includeUncontrolled: true}).then(function (clients) {
+        for (var i = 0, ii = clients.length; i < ii; i++) {
+            if (clients[i].focused && 0 === clients[i].url.indexOf(wideURL)) {
+                return; // the output and notification panels of Wide tell already
+            }
+        }
+
+        return self.registration.showNotification(data.title || 'Wide', {
+            body: data.body,
+            icon: wideURL + 'static/images/favicon.png',
+            tag: 'wide-job'
+        });
+    }));
+});
+
+self.addEventListener('notificationclick', function (event) {
+    event.notification.close();
+
+    event.waitUntil(self.clients.matchAll({type: 'window', includeUncontrolled: true}).then(function (clients) {
+        for (var i = 0, ii = clients.length; i < ii; i++) {
+            if (0 === clients[i].url.indexOf(wideURL) && 'focus' in clients[i]) {
+                return clients[i].focus();
+            }
+        }
+
+        return self.clients.openWindow(wideURL);
+    }));
+});
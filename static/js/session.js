@@ -24,6 +24,12 @@ var session = {
     init: function () {
         this._initWS();
 
+        // save session content every 30 seconds
+        setInterval(function () {
+            session.saveContent();
+        }, 30000);
+    },
+    saveContent: function () {
         var getLayoutState = function (paneState) {
             var state = 'normal';
             if (paneState.isClosed) {
@@ -35,52 +41,49 @@ var session = {
             return state;
         };
 
-        // save session content every 30 seconds
-        setInterval(function () {
-            var request = newWideRequest(),
-                    filse = [],
-                    fileTree = [],
-                    currentId = editors.getCurrentId(),
-                    currentFile = currentId ? editors.getCurrentPath() : "";
-
-            editors.tabs.obj._$tabs.find("div").each(function () {
-                var $it = $(this);
-                if ($it.find("span:eq(0)").attr("title") !== config.label.start_page) {
-                    filse.push($it.find("span:eq(0)").attr("title"));
-                }
-            });
-
-            fileTree = tree.getOpenPaths();
-
-            request.currentFile = currentFile; // current editor file
-            request.fileTree = fileTree; // file tree expansion state
-            request.files = filse; // editor tabs
-
-
-            request.layout = {
-                "side": {
-                    "size": windows.outerLayout.west.state.size,
-                    "state": getLayoutState(windows.outerLayout.west.state)
-                },
-                "sideRight": {
-                    "size": windows.innerLayout.east.state.size,
-                    "state": getLayoutState(windows.innerLayout.east.state)
-                },
-                "bottom": {
-                    "size": windows.innerLayout.south.state.size,
-                    "state": getLayoutState(windows.innerLayout.south.state)
-                }
-            };
+        var request = newWideRequest(),
+                filse = [],
+                fileTree = [],
+                currentId = editors.getCurrentId(),
+                currentFile = currentId ? editors.getCurrentPath() : "";
 
-            $.ajax({
-                type: 'POST',
-                url: '/session/save',
-                data: JSON.stringify(request),
-                dataType: "json",
-                success: function (result) {
-                }
-            });
-        }, 30000);
+        editors.tabs.obj._$tabs.find("div").each(function () {
+            var $it = $(this);
+            if ($it.find("span:eq(0)").attr("title") !== config.label.start_page) {
+                filse.push($it.find("span:eq(0)").attr("title"));
+            }
+        });
+
+        fileTree = tree.getOpenPaths();
+
+        request.currentFile = currentFile; // current editor file
+        request.fileTree = fileTree; // file tree expansion state
+        request.files = filse; // editor tabs
+
+
+        request.layout = {
+            "side": {
+                "size": windows.outerLayout.west.state.size,
+                "state": getLayoutState(windows.outerLayout.west.state)
+            },
+            "sideRight": {
+                "size": windows.innerLayout.east.state.size,
+                "state": getLayoutState(windows.innerLayout.east.state)
+            },
+            "bottom": {
+                "size": windows.innerLayout.south.state.size,
+                "state": getLayoutState(windows.innerLayout.south.state)
+            }
+        };
+
+        $.ajax({
+            type: 'POST',
+            url: '/session/save',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+            }
+        });
     },
     restore: function () {
         if (!config.latestSessionContent) {
@@ -156,6 +159,7 @@ var session = {
     _initWS: function () {
         // Used for session retention, server will release all resources of the session if this channel closed
         var sessionWS = new ReconnectingWebSocket(config.channel + '/session/ws?sid=' + config.wideSessionId);
+        session.ws = sessionWS;
 
         sessionWS.onopen = function () {
             var dateFormat = function (time, fmt) {
@@ -188,6 +192,10 @@ var session = {
                     + '</td><td class="message">' + data.message
                     + '</td><td class="type">' + data.type + '</td></tr>';
             $notification.append(notificationHTML);
+
+            collab.reconnect();
+            chat.history();
+            sharedOutput.reconnect();
         };
 
         sessionWS.onmessage = function (e) {
@@ -221,6 +229,20 @@ var session = {
                                 "isParent": true
                             }]);
                     }
+                    break;
+                case 'shutdown':
+                    // server is shutting down, flushes unsaved files and session content before it exits
+                    menu.saveAllFiles();
+                    session.saveContent();
+
+                    $('.bottom-window-group .notification > table').append('<tr><td class="severity">WARN</td><td class="message">'
+                            + config.label.server_shutting_down + '</td><td class="type">Server</td></tr>');
+                    $(".notification-count").show();
+                    break;
+                case 'file-changed': // 文件被 git 操作（比如放弃修改）改写
+                case 'refresh-dir': // 目录被 git 操作（比如切换分支、克隆、初始化）改写
+                    tree.refreshDir(data.path, data.dir);
+
                     break;
                 case 'remove-file':
                 case 'rename-file':
@@ -232,11 +254,40 @@ var session = {
                         editors.tabs.del(nodes[i].path);
                     }
 
+                    break;
+                case 'doc-opened':
+                case 'doc-ack':
+                case 'doc-op':
+                case 'doc-cursor':
+                case 'doc-saved':
+                case 'doc-error':
+                    collab.handle(data);
+
+                    break;
+                case 'following':
+                case 'follow-stopped':
+                case 'followers':
+                case 'nav':
+                    follow.handle(data);
+
+                    break;
+                case 'chat':
+                case 'chat-history':
+                    chat.handle(data);
+
+                    break;
+                case 'output-shared':
+                case 'output-watching':
+                case 'output-unshared':
+                case 'shared-output':
+                    sharedOutput.handle(data);
+
                     break;
             }
         };
         sessionWS.onclose = function (e) {
             // console.log('[session onclose] disconnected (' + e.code + ')');
+            collab.disconnected();
 
             var data = {type: "Network", severity: "ERROR",
                 message: "Disconnected from server, trying to reconnect it [sid=" + config.wideSessionId + "]"},
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2014-present, b3log.org
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+/*
+ * @file sharedOutput.js
+ *
+ * Output sharing: a user grants another user read access to the output of the current session, the sessions of the
+ * watcher are asked to watch it, then the build, run and go tool output is relayed to them via the session channel.
+ *
+ * @version 1.0.0.0, Oct 17, 2026
+ */
+var sharedOutput = {
+    id: undefined, // id of the shared output being watched
+    running: false, // whether a run output is being received
+    share: function () {
+        var user = prompt(config.label["output-share-prompt"]);
+        if (!user || "" === $.trim(user)) {
+            return;
+        }
+
+        var request = newWideRequest();
+        request.user = $.trim(user);
+
+        $.ajax({
+            type: 'POST',
+            url: '/output/share',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+
+                    return;
+                }
+
+                $("#dialogAlert").dialog("open", config.label["output-share-granted"].replace("%s", result.data));
+            }
+        });
+    },
+    unshare: function () {
+        $.ajax({
+            type: 'POST',
+            url: '/output/unshare',
+            data: JSON.stringify(newWideRequest()),
+            dataType: "json"
+        });
+    },
+    // watches the shared output again after the session channel reconnected
+    reconnect: function () {
+        if (sharedOutput.id) {
+            sharedOutput._send({cmd: "output-watch", id: sharedOutput.id});
+        }
+    },
+    // handles the output sharing messages of the session channel
+    handle: function (data) {
+        var $tab = $('.bottom-window-group .tabs > div[data-index="shared-output"]');
+        switch (data.cmd) {
+            case 'output-shared':
+                if (data.id !== sharedOutput.id
+                        && confirm(config.label["output-share-confirm"].replace("%s", data.user))) {
+                    sharedOutput._send({cmd: "output-watch", id: data.id});
+                }
+
+                break;
+            case 'output-watching':
+                if (data.id === sharedOutput.id) { // watching again after reconnected
+                    return;
+                }
+
+                if (sharedOutput.id) {
+                    sharedOutput._send({cmd: "output-unwatch", id: sharedOutput.id});
+                }
+
+                sharedOutput.id = data.id;
+                sharedOutput.running = false;
+                $('.bottom-window-group .shared-output > pre').text('');
+                $tab.find("span").text(config.label["output-shared"].replace("%s", data.user)).attr("title",
+                        config.label["output-shared"].replace("%s", data.user));
+                $tab.show();
+                bottomGroup.tabs.setCurrent("shared-output");
+                windows.flowBottom();
+
+                break;
+            case 'output-unshared':
+                if (data.id === sharedOutput.id) {
+                    sharedOutput.id = undefined;
+                    if ("shared-output" === bottomGroup.tabs.getCurrentId()) {
+                        bottomGroup.tabs.setCurrent("output");
+                    }
+                    $tab.hide();
+                }
+
+                break;
+            case 'shared-output':
+                if (data.id === sharedOutput.id) {
+                    sharedOutput._fill(data.data);
+                }
+
+                break;
+        }
+    },
+    // appends the relayed output message as text, the shared output is not trusted as HTML
+    _fill: function (message) {
+        var $output = $('.bottom-window-group .shared-output'),
+                $pre = $output.find("pre"),
+                text = new DOMParser().parseFromString((message.output || "").replace(/<br\/?>/g, "\n"),
+                        "text/html").body.textContent;
+
+        switch (message.cmd) {
+            case 'start-build':
+            case 'start-test':
+            case 'start-vet':
+            case 'start-install':
+                $pre.text(text);
+
+                break;
+            case 'run':
+                if (!sharedOutput.running) {
+                    sharedOutput.running = true;
+                    text = "\n" + text;
+                }
+                $pre.text($pre.text() + text);
+
+                break;
+            case 'run-done':
+                sharedOutput.running = false;
+                $pre.text($pre.text() + text);
+
+                break;
+            case 'build':
+            case 'cross-build':
+            case 'go test':
+            case 'go vet':
+            case 'go install':
+            case 'go-started':
+            case 'git clone':
+                $pre.text($pre.text() + text);
+
+                break;
+        }
+
+        $output.parent().scrollTop($output[0].scrollHeight);
+    },
+    _send: function (message) {
+        try {
+            session.ws.send(JSON.stringify(message));
+        } catch (e) {
+            // reconnecting
+        }
+    }
+};
@@ -73,6 +73,139 @@ var tree = {
 
         return undefined;
     },
+    // 根据服务端推送的绝对路径获取节点（节点路径是相对于根目录的），没有找到时返回 undefined
+    getNodeByAbsPath: function (path) {
+        var nodes = tree.fileTree.transformToArray(tree.fileTree.getNodes()),
+                ret = undefined;
+        for (var i = 0, ii = nodes.length; i < ii; i++) {
+            var nodePath = nodes[i].path;
+            if (!nodePath || (ret && ret.path.length >= nodePath.length)) {
+                continue;
+            }
+
+            if (path === nodePath || (path.length > nodePath.length
+                    && path.substring(path.length - nodePath.length) === nodePath
+                    && ('/' === nodePath.charAt(0) || '/' === path.charAt(path.length - nodePath.length - 1)))) {
+                ret = nodes[i];
+            }
+        }
+
+        return ret;
+    },
+    // 服务端在 git 操作后推送的刷新：刷新目录节点（不在树中时刷新上级目录），重新加载其下打开的文件
+    refreshDir: function (path, dir) {
+        var node = tree.getNodeByAbsPath(path), base = path;
+        if (!node) {
+            node = tree.getNodeByAbsPath(dir);
+            base = dir;
+        }
+        if (!node) {
+            return;
+        }
+
+        var root = base.substring(0, base.length - node.path.length);
+        editors.reload(path.substring(root.length));
+
+        if (!node.isParent) {
+            node = node.getParentNode();
+        }
+        if (node) {
+            tree.refreshNode(node);
+        }
+    },
+    // 增量刷新目录节点：只获取上次加载以来增加、删除和变化的子节点，服务端没有该版本时全部重新加载
+    refreshNode: function (node) {
+        if (!node.treeVersion || !node.zAsync || node.morePages) { // 版本只包含第一页子节点
+            tree.fileTree.reAsyncChildNodes(node, "refresh", true);
+
+            return;
+        }
+
+        $.ajax({
+            type: 'POST',
+            url: '/file/refresh',
+            data: {path: node.path, pathtype: node.pathtype, version: node.treeVersion},
+            dataType: "json",
+            success: function (data) {
+                if (data.full) {
+                    tree.fileTree.reAsyncChildNodes(node, "refresh", true);
+
+                    return;
+                }
+
+                node.treeVersion = data.version;
+
+                var child = function (path) {
+                    var children = node.children || [];
+                    for (var i = 0, ii = children.length; i < ii; i++) {
+                        if (children[i].path === path) {
+                            return children[i];
+                        }
+                    }
+
+                    return null;
+                };
+
+                var removed = data.removed || [];
+                for (var i = 0, ii = removed.length; i < ii; i++) {
+                    var old = child(removed[i]);
+                    if (old) {
+                        tree.fileTree.removeNode(old);
+                    }
+                }
+
+                var changed = data.changed || [];
+                for (var i = 0, ii = changed.length; i < ii; i++) {
+                    var old = child(changed[i].path);
+                    if (!old) {
+                        continue;
+                    }
+
+                    delete changed[i].children; // keeps the loaded children
+                    $.extend(old, changed[i]);
+                    tree.fileTree.updateNode(old);
+
+                    var $a = $("#" + old.tId + "_a").removeClass("git-submodule-node");
+                    $a.find(".git-submodule, .git-badge, .git-branch").remove();
+                    tree._addDiyDom(tree.fileTree.setting.treeId, old);
+                }
+
+                var added = data.added || [];
+                for (var i = 0, ii = added.length; i < ii; i++) {
+                    var next = (node.children || [])[added[i].index];
+                    delete added[i].index;
+
+                    var newNode = tree.fileTree.addNodes(node, added[i], true)[0];
+                    if (next) {
+                        tree.fileTree.moveNode(next, newNode, "prev", true);
+                    }
+                }
+            }
+        });
+    },
+    // 加载目录节点中未加载的子节点：子节点超过 Wide.MaxTreeChildren 时以标记节点结尾，点击后加载下一页
+    loadMore: function (marker) {
+        var node = marker.getParentNode();
+        if (!node || marker.loading) {
+            return;
+        }
+        marker.loading = true;
+
+        $.ajax({
+            type: 'POST',
+            url: '/file/refresh',
+            data: {path: node.path, pathtype: node.pathtype, offset: marker.more},
+            dataType: "json",
+            success: function (data) {
+                tree.fileTree.removeNode(marker);
+                tree.fileTree.addNodes(node, data, true);
+                node.morePages = true;
+            },
+            complete: function () {
+                marker.loading = false;
+            }
+        });
+    },
     getOpenPaths: function () {
         var nodes = tree.fileTree.transformToArray(tree.fileTree.getNodes()),
                 paths = [];
@@ -159,32 +292,41 @@ var tree = {
 
         $("#dialogRenamePrompt").dialog("open");
     },
+    // 下载文件，目录打包为 zip 下载（不含 .git）
     export: function () {
-        var request = newWideRequest(),
-        isSucc = false;
+        window.open(config.context + '/file/download?path=' + encodeURIComponent(wide.curNode.path)
+                + '&pathtype=' + wide.curNode.pathtype);
+    },
+    // invites a user (anyone opening the link if no user specified) to pair on the current directory for a while
+    invite: function () {
+        var user = prompt(config.label["invitation-prompt"]);
+        if (null === user) {
+            return;
+        }
+
+        var request = newWideRequest();
         request.path = wide.curNode.path;
-        request.pathtype = wide.curNode.pathtype;        
+        request.pathtype = wide.curNode.pathtype;
+        request.user = $.trim(user);
 
         $.ajax({
-            async: false,
             type: 'POST',
-            url: '/file/zip/new',
+            url: '/invitation/new',
             data: JSON.stringify(request),
             dataType: "json",
             success: function (result) {
                 if (0 != result.code) {
-                    $("#dialogAlert").dialog("open", result.msg);
+                    $("#dialogAlert").dialog("open", result.msg || config.label["invitation-failed"]);
 
-                    return false;
+                    return;
                 }
 
-                isSucc = true;
+                $("#dialogAlert").dialog("open", $('<div/>').text(config.label["invitation-created"]
+                        .replace("%s", result.data.expires)).html() + '<br/><input class="invitation-link" readonly value="'
+                        + $('<div/>').text(result.data.link).html() + '"/>');
+                $("#dialogAlert .invitation-link").select();
             }
         });
-
-        if (isSucc) {
-            window.open('/file/zip?path=' + wide.curNode.path + ".zip");
-        }
     },
     crossCompile: function (platform) {
         var request = newWideRequest();
@@ -207,6 +349,226 @@ var tree = {
             }
         });
     },
+    protoc: function () {
+        var request = newWideRequest();
+        request.file = wide.curNode.path;
+        request.pathtype = wide.curNode.pathtype;
+
+        $.ajax({
+            type: 'POST',
+            url: '/protoc',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+                }
+            }
+        });
+    },
+    dockerContainers: {}, // 每个 Dockerfile 最近运行的容器名
+    dockerBuild: function () {
+        var request = newWideRequest();
+        request.file = wide.curNode.path;
+        request.pathtype = wide.curNode.pathtype;
+
+        $.ajax({
+            type: 'POST',
+            url: '/docker/build',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+                }
+            }
+        });
+    },
+    dockerRun: function () {
+        var request = newWideRequest();
+        request.file = wide.curNode.path;
+        request.pathtype = wide.curNode.pathtype;
+
+        $.ajax({
+            type: 'POST',
+            url: '/docker/run',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+
+                    return;
+                }
+
+                tree.dockerContainers[request.file] = result.data.name;
+            }
+        });
+    },
+    dockerStop: function () {
+        var path = wide.curNode.path,
+                request = newWideRequest();
+        request.name = tree.dockerContainers[path];
+        if (!request.name) {
+            return;
+        }
+
+        $.ajax({
+            type: 'POST',
+            url: '/docker/stop',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                delete tree.dockerContainers[path];
+                if (0 != result.code && result.msg) {
+                    $("#dialogAlert").dialog("open", result.msg);
+                }
+            }
+        });
+    },
+    deploy: function () {
+        var request = newWideRequest();
+        request.file = wide.curNode.path;
+        request.pathtype = wide.curNode.pathtype;
+
+        $.ajax({
+            type: 'POST',
+            url: '/deploy',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+                }
+            }
+        });
+    },
+    licenseHeaders: function (apply) {
+        var node = wide.curNode,
+                request = newWideRequest();
+        request.path = node.path;
+        request.pathtype = node.pathtype;
+        request.apply = apply;
+
+        $.ajax({
+            type: 'POST',
+            url: '/file/license',
+            data: JSON.stringify(request),
+            dataType: "json",
+            beforeSend: function () {
+                bottomGroup.resetOutput();
+            },
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+
+                    return;
+                }
+
+                var data = result.data,
+                        output = config.label[apply ? "license-applied" : "license-result"]
+                        .replace("{checked}", data.checked).replace("{header}", data.header)
+                        .replace("{count}", data.files.length) + "\n";
+                if (data.truncated) {
+                    output += config.label["license-truncated"] + "\n";
+                }
+                for (var i = 0; i < data.files.length; i++) {
+                    var path = $('<div/>').text(node.path + "/" + data.files[i].path).html();
+                    output += '<span class="path" data-path="' + path + '" data-line="1" data-column="1">'
+                            + $('<div/>').text(data.files[i].path).html() + '</span> '
+                            + config.label["license-" + data.files[i].status] + "\n";
+
+                    if (apply) {
+                        editors.reload(node.path + "/" + data.files[i].path);
+                    }
+                }
+
+                bottomGroup.fillOutput(output);
+            }
+        });
+    },
+    pluginActions: [],
+    loadPluginActions: function () {
+        $.ajax({
+            type: 'GET',
+            url: '/plugin/actions',
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code || !result.data) {
+                    return;
+                }
+
+                tree.pluginActions = result.data;
+                $("#fileRMenu li.plugin-action, #dirRMenu li.plugin-action").remove();
+                for (var i = 0; i < result.data.length; i++) {
+                    var action = result.data[i],
+                            $li = $('<li class="plugin-action" onclick="tree.runPluginAction(this);">'
+                                    + '<span class="space"></span> </li>');
+                    $li.attr("data-index", i).append(document.createTextNode(action.label));
+                    if ("dir" !== action.target) {
+                        $("#fileRMenu > ul").append($li.clone());
+                    }
+                    if ("file" !== action.target) {
+                        $("#dirRMenu > ul").append($li.clone());
+                    }
+                }
+            }
+        });
+    },
+    _showPluginActions: function ($menu) {
+        $menu.find("li.plugin-action").each(function () {
+            var pattern = tree.pluginActions[$(this).data("index")].pattern;
+            if (!pattern) {
+                $(this).show();
+
+                return;
+            }
+
+            // glob to regexp, such as "*.proto" to /^[^/]*\.proto$/
+            var regexp = new RegExp("^" + pattern.replace(/[.+^${}()|[\]\\]/g, "\\$&")
+                    .replace(/\*/g, "[^/]*").replace(/\?/g, ".") + "$");
+            $(this).toggle(regexp.test(wide.curNode.name));
+        });
+    },
+    runPluginAction: function (it) {
+        var action = tree.pluginActions[$(it).data("index")],
+                node = wide.curNode;
+
+        $("#fileRMenu, #dirRMenu").hide();
+
+        $.ajax({
+            type: 'POST',
+            url: '/plugin/action',
+            data: JSON.stringify({plugin: action.plugin, action: action.id, path: node.path}),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code) {
+                    $("#dialogAlert").dialog("open", result.msg);
+
+                    return false;
+                }
+
+                if (result.data.message) {
+                    $("#dialogAlert").dialog("open", result.data.message);
+                }
+                if (result.data.refresh) {
+                    tree.refreshNode(node.isParent ? node : node.getParentNode());
+                }
+            }
+        });
+    },
     refresh: function (it) {
         if (it) {
             if ($(it).hasClass("disabled")) {
@@ -214,13 +576,15 @@ var tree = {
             }
         }
 
-        tree.fileTree.reAsyncChildNodes(wide.curNode, "refresh", true);
+        tree.refreshNode(wide.curNode);
     },
     init: function () {
         $("#file").click(function () {
             $(this).focus();
         });
 
+        tree.loadPluginActions();
+
         var request = newWideRequest();
         // request.path = wide.curNode.path;
         // request.pathtype = wide.curNode.pathtype;   
@@ -243,22 +607,33 @@ var tree = {
                         },
                         view: {
                             showTitle: true,
-                            selectedMulti: false
+                            selectedMulti: false,
+                            addDiyDom: tree._addDiyDom
                         },
                         async: {
                             enable: true,
                             url: "/file/refresh",
-                            autoParam: ["path","pathtype"]
+                            autoParam: ["path","pathtype"],
+                            otherParam: {version: ""},
+                            // 记录子节点的版本，之后增量刷新
+                            dataFilter: function (treeId, parentNode, data) {
+                                if (parentNode) {
+                                    parentNode.treeVersion = data.version;
+                                    parentNode.morePages = false;
+                                }
+
+                                return data.nodes || [];
+                            }
                         },
                         callback: {
                             onDblClick: function (event, treeId, treeNode) {
-                                if (treeNode) {
+                                if (treeNode && "m" !== treeNode.type) {
                                     tree.openFile(treeNode);
                                 }
                             },
                             onRightClick: function (event, treeId, treeNode) {
 
-                                if (treeNode && !treeNode.isGOAPI) {
+                                if (treeNode && !treeNode.isGOAPI && "m" !== treeNode.type) {
                                     menu.undisabled(['import', 'export', 'git-clone']);
 
                                     wide.curNode = treeNode;
@@ -277,12 +652,40 @@ var tree = {
                                             $fileRMenu.find(".decompress").show();
                                         }
 
+                                        if (-1 === wide.curNode.path.indexOf(".proto", wide.curNode.path.length - ".proto".length)) { // !path.endsWith(".proto")
+                                            $fileRMenu.find(".protoc").hide();
+                                        } else {
+                                            $fileRMenu.find(".protoc").show();
+                                        }
+
+                                        if (!config.features || !config.features.docker
+                                                || !/(^|[\\\/])Dockerfile[^\\\/]*$/.test(wide.curNode.path)) {
+                                            $fileRMenu.find(".docker-build, .docker-run, .docker-stop").hide();
+                                        } else {
+                                            $fileRMenu.find(".docker-build, .docker-run").show();
+                                            if (tree.dockerContainers[wide.curNode.path]) {
+                                                $fileRMenu.find(".docker-stop").show();
+                                            } else {
+                                                $fileRMenu.find(".docker-stop").hide();
+                                            }
+                                        }
+
+                                        // 清单文件或 Go 源码文件可以部署
+                                        if (!config.features || !config.features.deploy
+                                                || !/\.(ya?ml|json|go)$/i.test(wide.curNode.path)) {
+                                            $fileRMenu.find(".deploy").hide();
+                                        } else {
+                                            $fileRMenu.find(".deploy").show();
+                                        }
+
                                         if (-1 === wide.curNode.path.indexOf("go", wide.curNode.path.length - "go".length)) { // !path.endsWith("go")
                                             $fileRMenu.find(".linux64").hide();
                                         } else {
                                             $fileRMenu.find(".linux64").show();
                                         }
 
+                                        tree._showPluginActions($fileRMenu);
+
                                         var top = event.clientY - 10;
                                         if ($fileRMenu.height() + top > $('.content').height()) {
                                             top = top - $fileRMenu.height() - 25;
@@ -325,8 +728,22 @@ var tree = {
                                             $dirRMenu.find(".gitLocalBranches").addClass("disabled");
                                         }
 
+                                        if (0 === wide.curNode.pathtype && wide.curNode.removable) {
+                                            $dirRMenu.find(".invite").removeClass("disabled");
+                                        } else {
+                                            $dirRMenu.find(".invite").addClass("disabled");
+                                        }
+
+                                        if (!config.features || !config.features.deploy) {
+                                            $dirRMenu.find(".deploy").hide();
+                                        } else {
+                                            $dirRMenu.find(".deploy").show();
+                                        }
+
 
                                         
+                                        tree._showPluginActions($dirRMenu);
+
                                         var top = event.clientY - 10;
                                         if ($dirRMenu.height() + top > $('.content').height()) {
                                             top = top - $dirRMenu.height() - 25;
@@ -344,6 +761,12 @@ var tree = {
                                 }
                             },
                             onClick: function (event, treeId, treeNode, clickFlag) {
+                                if (treeNode && "m" === treeNode.type) {
+                                    tree.loadMore(treeNode);
+
+                                    return;
+                                }
+
                                 if (treeNode) {
                                     wide.curNode = treeNode;
                                     tree.fileTree.selectNode(treeNode);
@@ -368,6 +791,34 @@ var tree = {
         this._initSearch();
         this._initRename();
     },
+    // 文件树节点的自定义内容：“加载更多”标记节点显示剩余子节点数，其他节点显示 git 状态
+    _addDiyDom: function (treeId, treeNode) {
+        if ("m" === treeNode.type) {
+            $("#" + treeNode.tId + "_a").addClass("ztree-more").attr("title", "");
+            $("#" + treeNode.tId + "_span").text(config.label.tree_load_more.replace("%d", treeNode.remaining));
+
+            return;
+        }
+
+        tree._addGitBadge(treeId, treeNode);
+    },
+    // 在文件树节点后显示 git 状态、子模块标记和仓库分支
+    _addGitBadge: function (treeId, treeNode) {
+        var $a = $("#" + treeNode.tId + "_a"), html = '';
+        if (treeNode.submodule) {
+            $a.addClass("git-submodule-node");
+            html += '<span class="git-submodule">' + config.label.git_submodule + '</span>';
+        }
+        if (treeNode.gitStatus) {
+            html += '<span class="git-badge git-' + treeNode.gitStatus + '" title="'
+                    + config.label['git_status_' + treeNode.gitStatus] + '">'
+                    + treeNode.gitStatus.charAt(0).toUpperCase() + '</span>';
+        }
+        if (treeNode.gitRepo && treeNode.gitBranch) {
+            html += '<span class="git-branch">' + $('<div/>').text(treeNode.gitBranch).html() + '</span>';
+        }
+        $a.append(html);
+    },
     openFile: function (treeNode, cursor) {
         wide.curNode = treeNode;
         var tempCursor = cursor;
@@ -467,7 +918,7 @@ var tree = {
 
         $("#dialogSearchForm").dialog({
             "modal": true,
-            "height": 80,
+            "height": 104,
             "width": 260,
             "title": config.label.search,
             "okText": config.label.search,
@@ -489,6 +940,9 @@ var tree = {
 
                 request.text = $("#dialogSearchForm > input:eq(0)").val();
                 request.extension = $("#dialogSearchForm > input:eq(1)").val();
+                request.regex = $("#dialogSearchForm .search-regex").prop("checked");
+                request.caseSensitive = $("#dialogSearchForm .search-case-sensitive").prop("checked");
+                request.wholeWord = $("#dialogSearchForm .search-whole-word").prop("checked");
 
                 $.ajax({
                     type: 'POST',
@@ -497,11 +951,15 @@ var tree = {
                     dataType: "json",
                     success: function (result) {
                         if (0 != result.code) {
+                            if (result.msg) {
+                                $("#dialogAlert").dialog("open", result.msg);
+                            }
+
                             return;
                         }
 
                         $("#dialogSearchForm").dialog("close");
-                        editors.appendSearch(result.data, 'founds', request.text);
+                        editors.appendSearch(result.data.snippets, 'founds', request.text, result.data.token);
                     }
                 });
             }
@@ -158,7 +158,7 @@ var wide = {
 
                 request.path = wide.curNode.path + "/" + name;
                 request.fileType = "f";
-                request.pathtype = 0;
+                request.pathtype = wide.curNode.pathtype;
 
                 $.ajax({
                     type: 'POST',
@@ -176,6 +176,12 @@ var wide = {
 
                         $("#dialogNewFilePrompt").dialog("close");
 
+                        if (0 !== request.pathtype) { // 只监听了工作空间的文件变化，其他目录（如草稿）直接刷新
+                            tree.refreshNode(wide.curNode);
+
+                            return;
+                        }
+
                         setTimeout(function () { // Delay, waiting the file change notified and then open it
                             var tId = tree.getTIdByPath(request.path);
                             tree.openFile(tree.fileTree.getNodeByTId(tId));
@@ -203,7 +209,7 @@ var wide = {
 
                 request.path = wide.curNode.path + "/" + name;
                 request.fileType = "d";
-                request.pathtype = 0;
+                request.pathtype = wide.curNode.pathtype;
 
                 $.ajax({
                     type: 'POST',
@@ -220,6 +226,10 @@ var wide = {
                         }
 
                         $("#dialogNewDirPrompt").dialog("close");
+
+                        if (0 !== request.pathtype) { // 只监听了工作空间的文件变化，其他目录（如草稿）直接刷新
+                            tree.refreshNode(wide.curNode);
+                        }
                     }
                 });
             }
@@ -274,6 +284,10 @@ var wide = {
             "cancelText": config.label.cancel,
             "afterInit": function () {
                 $("#dialogGoFilePrompt").on("dblclick", "li", function () {
+                    if ($(this).hasClass("find-more")) {
+                        return;
+                    }
+
                     var tId = tree.getTIdByPath($(this).find(".ft-small").text());
                     tree.openFile(tree.fileTree.getNodeByTId(tId));
                     tree.fileTree.selectNode(wide.curNode);
@@ -286,9 +300,19 @@ var wide = {
                     $list.find("li").removeClass("selected");
                     $list.data("index", $(this).data("index"));
                     $(this).addClass("selected");
+
+                    if ($(this).hasClass("find-more")) {
+                        wide._findFiles($(this).data("page"));
+                    }
                 });
 
                 hotkeys.bindList($("#dialogGoFilePrompt > input"), $("#dialogGoFilePrompt > .list"), function ($selected) {
+                    if ($selected.hasClass("find-more")) {
+                        wide._findFiles($selected.data("page"));
+
+                        return;
+                    }
+
                     var tId = tree.getTIdByPath($selected.find(".ft-small").text());
                     tree.openFile(tree.fileTree.getNodeByTId(tId));
                     tree.fileTree.selectNode(wide.curNode);
@@ -297,51 +321,7 @@ var wide = {
                 });
 
                 $("#dialogGoFilePrompt > input").bind("input", function () {
-                    var name = $("#dialogGoFilePrompt > input").val();
-
-                    var request = newWideRequest();
-                    request.pathtype = 0;
-                    request.path = '';
-                    request.name = '*' + name + '*';
-                    if (wide.curNode) {
-                        request.path = wide.curNode.path;
-                        request.pathtype = wide.curNode.pathtype;
-                    }
-
-                    $.ajax({
-                        type: 'POST',
-                        url: '/file/find/name',
-                        data: JSON.stringify(request),
-                        dataType: "json",
-                        success: function (result) {
-                            if (0 != result.code) {
-                                return;
-                            }
-
-                            var data = result.data;
-
-                            var goFileHTML = '';
-                            for (var i = 0, max = data.length; i < max; i++) {
-                                var path = data[i].path,
-                                        name = path.substr(path.lastIndexOf("/") + 1),
-                                        icoSkin = wide.getClassBySuffix(name.split(".")[1]);
-                                if (i === 0) {
-                                    goFileHTML += '<li data-index="' + i + '" class="selected" title="'
-                                            + path + '"><span class="'
-                                            + icoSkin + 'ico"></span>'
-                                            + name + '&nbsp;&nbsp;&nbsp;&nbsp;<span class="ft-small">'
-                                            + path + '</span></li>';
-                                } else {
-                                    goFileHTML += '<li data-index="' + i + '" title="'
-                                            + path + '"><span class="' + icoSkin + 'ico"></span>'
-                                            + name + '&nbsp;&nbsp;&nbsp;&nbsp;<span class="ft-small">'
-                                            + path + '</span></li>';
-                                }
-                            }
-
-                            $("#dialogGoFilePrompt > ul").html(goFileHTML);
-                        }
-                    });
+                    wide._findFiles(1);
                 });
             },
             "afterOpen": function () {
@@ -350,6 +330,12 @@ var wide = {
                 $("#dialogGoFilePrompt .list").html('').data("index", 0);
             },
             "ok": function () {
+                if ($("#dialogGoFilePrompt .selected").hasClass("find-more")) {
+                    wide._findFiles($("#dialogGoFilePrompt .selected").data("page"));
+
+                    return;
+                }
+
                 var tId = tree.getTIdByPath($("#dialogGoFilePrompt .selected .ft-small").text());
                 tree.openFile(tree.fileTree.getNodeByTId(tId));
                 tree.fileTree.selectNode(wide.curNode);
@@ -358,6 +344,43 @@ var wide = {
             }
         });
 
+        $("#dialogGoSymbolPrompt").dialog({
+            "modal": true,
+            "height": 320,
+            "width": 660,
+            "title": config.label.goto_symbol,
+            "okText": config.label.go,
+            "cancelText": config.label.cancel,
+            "afterInit": function () {
+                $("#dialogGoSymbolPrompt").on("dblclick", "li", function () {
+                    wide._openSymbol($(this));
+                });
+
+                $("#dialogGoSymbolPrompt").on("click", "li", function () {
+                    var $list = $("#dialogGoSymbolPrompt > .list");
+                    $list.find("li").removeClass("selected");
+                    $list.data("index", $(this).data("index"));
+                    $(this).addClass("selected");
+                });
+
+                hotkeys.bindList($("#dialogGoSymbolPrompt > input"), $("#dialogGoSymbolPrompt > .list"), function ($selected) {
+                    wide._openSymbol($selected);
+                });
+
+                $("#dialogGoSymbolPrompt > input").bind("input", function () {
+                    wide._findSymbols();
+                });
+            },
+            "afterOpen": function () {
+                $("#dialogGoSymbolPrompt > input").val('').focus();
+                $("#dialogGoSymbolPrompt").closest(".dialog-main").find(".dialog-footer > button:eq(0)").prop("disabled", true);
+                $("#dialogGoSymbolPrompt .list").html('').data("index", 0);
+            },
+            "ok": function () {
+                wide._openSymbol($("#dialogGoSymbolPrompt .selected"));
+            }
+        });
+
         $("#dialogGoLinePrompt").dialog({
             "modal": true,
             "height": 52,
@@ -438,12 +461,24 @@ var wide = {
                 case 'start-test':
                 case 'start-vet':
                 case 'start-install':
+                case 'start-protoc':
+                case 'start-docker-build':
+                case 'start-deploy':
                     bottomGroup.fillOutput(data.output);
 
                     break;
                 case 'go test':
                 case 'go vet':
                 case 'go install':
+                case 'docker-build':
+                case 'docker-run':
+                case 'deploy':
+                    bottomGroup.fillOutput($('.bottom-window-group .output > div').html() + data.output);
+
+                    break;
+                case 'go-queued': // 等待构建工作者，只显示最新的排队位置
+                case 'go-started':
+                    $('.bottom-window-group .output > div .go-queued').remove();
                     bottomGroup.fillOutput($('.bottom-window-group .output > div').html() + data.output);
 
                     break;
@@ -454,6 +489,7 @@ var wide = {
                     break;
                 case 'build':
                 case 'cross-build':
+                case 'protoc':
                     bottomGroup.fillOutput($('.bottom-window-group .output > div').html() + data.output);
 
                     if (data.lints) { // has build error
@@ -503,7 +539,7 @@ var wide = {
                             });
 
                             if (path) {
-                                window.open('/file/zip?path=' + path + ".zip");
+                                window.open(config.context + '/file/zip?path=' + path + ".zip");
                             }
                         }
                     }
@@ -557,15 +593,140 @@ var wide = {
 
         this._initDialog();
     },
-    _save: function (path, pathtype,editor) {
+    // 按文件名查找文件，page 大于 1 时把该页追加到列表
+    _findFiles: function (page) {
+        var request = newWideRequest();
+        request.pathtype = 0;
+        request.path = '';
+        request.name = '*' + $("#dialogGoFilePrompt > input").val() + '*';
+        request.page = page;
+        if (wide.curNode) {
+            request.path = wide.curNode.path;
+            request.pathtype = wide.curNode.pathtype;
+        }
+
+        $.ajax({
+            type: 'POST',
+            url: '/file/find/name',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code) {
+                    return;
+                }
+
+                var data = result.data.paths,
+                        $list = $("#dialogGoFilePrompt > ul"),
+                        offset = 1 === page ? 0 : $list.find("li").not(".find-more").length;
+
+                var goFileHTML = '';
+                for (var i = 0, max = data.length; i < max; i++) {
+                    var path = data[i].path,
+                            name = path.substr(path.lastIndexOf("/") + 1),
+                            icoSkin = wide.getClassBySuffix(name.split(".")[1]);
+                    goFileHTML += '<li data-index="' + (offset + i) + '"'
+                            + (0 === offset + i ? ' class="selected"' : '') + ' title="'
+                            + path + '"><span class="' + icoSkin + 'ico"></span>'
+                            + name + '&nbsp;&nbsp;&nbsp;&nbsp;<span class="ft-small">'
+                            + path + '</span></li>';
+                }
+
+                if (result.data.more) {
+                    goFileHTML += '<li data-index="' + (offset + data.length) + '" data-page="' + (page + 1)
+                            + '" class="find-more">' + config.label['search-more'] + '</li>';
+                }
+
+                if (1 === page) {
+                    $list.html(goFileHTML).data("index", 0);
+                } else {
+                    $list.find(".find-more").remove();
+                    $list.append(goFileHTML);
+                    $list.find("li").removeClass("selected");
+                    $list.find("li:eq(" + offset + ")").addClass("selected");
+                    $list.data("index", offset);
+                }
+            }
+        });
+    },
+    // 按名称查找工作空间中的函数、方法和类型
+    _findSymbols: function () {
+        var request = newWideRequest();
+        request.name = $("#dialogGoSymbolPrompt > input").val();
+
+        $.ajax({
+            type: 'POST',
+            url: '/file/find/symbol',
+            data: JSON.stringify(request),
+            dataType: "json",
+            success: function (result) {
+                if (0 != result.code || request.name !== $("#dialogGoSymbolPrompt > input").val()) {
+                    return;
+                }
+
+                var data = result.data,
+                        goSymbolHTML = '';
+                for (var i = 0, max = data.length; i < max; i++) {
+                    var name = (data[i].recv ? data[i].recv + '.' : '') + data[i].name;
+                    goSymbolHTML += '<li data-index="' + i + '"' + (0 === i ? ' class="selected"' : '')
+                            + ' title="' + data[i].path + '" data-path="' + data[i].path + '" data-line="'
+                            + data[i].line + '" data-ch="' + data[i].ch + '">' + name
+                            + '&nbsp;&nbsp;&nbsp;&nbsp;<span class="ft-small">' + data[i].kind + ' '
+                            + data[i].path + ':' + (data[i].line + 1) + '</span></li>';
+                }
+
+                $("#dialogGoSymbolPrompt > ul").html(goSymbolHTML).data("index", 0);
+            }
+        });
+    },
+    // 打开符号所在的文件并定位到声明
+    _openSymbol: function ($symbol) {
+        if (0 === $symbol.length) {
+            return;
+        }
+
+        var tId = tree.getTIdByPath($symbol.data("path"));
+        tree.openFile(tree.fileTree.getNodeByTId(tId), CodeMirror.Pos($symbol.data("line"), $symbol.data("ch")));
+        tree.fileTree.selectNode(wide.curNode);
+        $("#dialogGoSymbolPrompt").dialog("close");
+        wide.curEditor.focus();
+    },
+    // 32 位 FNV-1a 哈希（按 UTF-16 码元），与服务端 textChecksum 一致
+    _checksum: function (text) {
+        var ret = 0x811c9dc5;
+        for (var i = 0, max = text.length; i < max; i++) {
+            ret ^= text.charCodeAt(i);
+            ret = Math.imul(ret, 16777619) >>> 0;
+        }
+
+        return ret >>> 0;
+    },
+    // 保存文件，编辑器有上次打开或保存的内容（editor.savedText）时只发送补丁，服务端不同步时再发送全部内容
+    _save: function (path, pathtype, editor, force, full) {
         if (!path) {
             return false;
         }
 
+        // saves after the collaborative edits acknowledged, the server saves its buffer of the file
+        if (collab.afterSynced(editor, function () {
+            wide._save(path, pathtype, editor, force);
+        })) {
+            return false;
+        }
+
+        var code = editor.getValue();
         var request = newWideRequest();
         request.file = path;
         request.pathtype=pathtype;
-        request.code = editor.getValue();
+        request.force = force === true;
+        request.code = code;
+        if (!full && undefined !== editor.savedText) {
+            var patch = collab.diff(editor.savedText, code);
+            if (JSON.stringify(patch).length < code.length) {
+                delete request.code;
+                request.patch = patch;
+                request.base = wide._checksum(editor.savedText);
+            }
+        }
 
         $.ajax({
             type: 'POST',
@@ -573,6 +734,43 @@ var wide = {
             data: JSON.stringify(request),
             dataType: "json",
             success: function (result) {
+                if (0 != result.code) {
+                    // 服务端的内容与补丁基础不一致，改为保存全部内容
+                    if (result.data && result.data.outOfSync) {
+                        wide._save(path, pathtype, editor, force, true);
+
+                        return false;
+                    }
+
+                    // 子模块处于游离 HEAD，确认后强制保存
+                    if (result.data && result.data.submodule && confirm(result.msg)) {
+                        wide._save(path, pathtype, editor, true, full);
+                    }
+
+                    // the file has been changed by others, conflicts are marked in the content
+                    if (result.data && result.data.conflicts) {
+                        editor.savedText = undefined; // the base on the server is the content of the file now
+                        editor.setValue(result.data.content);
+                        $("#dialogAlert").dialog("open", result.msg);
+                    }
+
+                    return false;
+                }
+
+                editor.savedText = code;
+
+                // the changes saved by others have been merged
+                if (result.data && undefined !== result.data.merged) {
+                    editor.savedText = result.data.merged;
+                }
+                if (result.data && undefined !== result.data.merged && code === editor.getValue()) {
+                    var cursor = editor.getCursor();
+                    var scrollInfo = editor.getScrollInfo();
+                    editor.setValue(result.data.merged);
+                    editor.setCursor(cursor);
+                    editor.scrollTo(null, scrollInfo.top);
+                }
+
                 // reset the save state
                 editor.doc.markClean();
                 $(".edit-panel .tabs > div").each(function () {
@@ -783,6 +981,8 @@ $(document).ready(function () {
     menu.init();
     hotkeys.init();
     session.init();
+    follow.init();
+    chat.init();
     notification.init();
     editors.init();
     windows.init();
@@ -0,0 +1,462 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage includes object-storage-backed workspaces (see conf.Storage).
+//
+// Workspace paths of users are local caches of the objects keyed {Prefix}{userId}/{i}/{path}, where i is the index
+// of the workspace path in the user's GOPATH. Each cache keeps a manifest (manifestFile) of the files synchronized,
+// so a synchronization tells which side changed: changes are uploaded or downloaded, removes are applied to the other
+// side, and the newer one wins if both changed. Directories "pkg" and "bin" are build caches, not synchronized.
+//
+// Objects are compared with their ETags, which are the MD5 of the contents if uploaded in a single part (by AWS S3
+// without SSE-KMS and MinIO), so files synchronized by other instances are not downloaded again. Empty directories
+// and file modes are not kept in the storage.
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+	"github.com/kwokhunglee/wide/util"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// manifestFile is the name of the manifest in the root of a workspace cache.
+const manifestFile = ".wide-storage.json"
+
+// tempPrefix is the name prefix of temporary files of downloading.
+const tempPrefix = ".wide-storage-"
+
+// syncDelay is the delay of synchronizing a workspace after a file changed, changes made meanwhile are synchronized
+// together.
+const syncDelay = 2 * time.Second
+
+// objectStore is the object storage workspaces persist in, implemented by util.S3Client.
+type objectStore interface {
+	PutObject(key string, content io.ReadSeeker) error
+	GetObject(key string, w io.Writer) error
+	DeleteObject(key string) error
+	ListObjects(prefix string) ([]*util.S3Object, error)
+}
+
+// entry represents a file synchronized in the manifest of a workspace cache.
+type entry struct {
+	Size    int64  `json:"size"`    // size of the local file
+	ModTime int64  `json:"modTime"` // modification time of the local file in unix nano
+	ETag    string `json:"etag"`    // ETag of the object
+}
+
+// localFile represents a file of a workspace cache.
+type localFile struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// syncStats represents the counts of files transferred by a synchronization.
+type syncStats struct {
+	Uploaded   int
+	Downloaded int
+	Removed    int // files removed locally or remotely
+}
+
+// userMutexes serialize synchronizations of the same user.
+//
+// <userId, *sync.Mutex>
+var userMutexes sync.Map
+
+// users whose synchronizations are scheduled after files changed.
+var pending = map[string]bool{}
+
+// pending lock.
+var pendingMutex sync.Mutex
+
+// Load starts synchronizing workspaces of online users every Storage.Interval and right after files changed, the
+// schedule follows configurations reloading.
+func Load() {
+	event.Subscribe(event.HandleFunc(fileChanged), event.EvtCodeFileChanged)
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		for {
+			s := conf.Wide().Storage
+			if nil == s {
+				time.Sleep(time.Minute)
+
+				continue
+			}
+
+			time.Sleep(s.SyncInterval())
+			Flush()
+		}
+	}()
+}
+
+// Flush synchronizes workspaces of the users online on this instance.
+func Flush() {
+	if nil == conf.Wide().Storage {
+		return
+	}
+
+	for _, user := range conf.GetUsers() {
+		if 1 > len(session.WideSessions.GetByUserId(user.Id)) {
+			continue
+		}
+
+		if err := Sync(user.Id); nil != err {
+			logger.Warnf("Synchronizes workspace of user [%s] failed: %s", user.Id, err)
+		}
+	}
+}
+
+// Sync synchronizes workspaces of the user specified by the given user id with the storage, does nothing if the
+// storage is not configured.
+//
+// All files are synchronized even if some failed, the first error is returned.
+func Sync(uid string) error {
+	s := conf.Wide().Storage
+	user := conf.GetUser(uid)
+	// user [playground] is a reserved mock user
+	if nil == s || nil == user || "playground" == uid {
+		return nil
+	}
+
+	mutex, _ := userMutexes.LoadOrStore(uid, &sync.Mutex{})
+	mutex.(*sync.Mutex).Lock()
+	defer mutex.(*sync.Mutex).Unlock()
+
+	client := &util.S3Client{Endpoint: s.Endpoint, Region: s.Region, Bucket: s.Bucket, AccessKey: s.AccessKey,
+		SecretKey: s.SecretKey, Client: &http.Client{Timeout: 10 * time.Minute}}
+
+	var ret error
+	for i, workspace := range filepath.SplitList(user.WorkspacePath()) {
+		start := time.Now()
+		stats, err := syncWorkspace(client, workspace, s.Prefix+uid+"/"+strconv.Itoa(i)+"/")
+		if nil != err && nil == ret {
+			ret = err
+		}
+
+		if 0 < stats.Uploaded+stats.Downloaded+stats.Removed {
+			logger.Debugf("Synchronized workspace [%s] of user [%s] in [%s], uploaded [%d], downloaded [%d], "+
+				"removed [%d]", workspace, uid, time.Since(start), stats.Uploaded, stats.Downloaded, stats.Removed)
+		}
+	}
+
+	return ret
+}
+
+// fileChanged schedules synchronizing the workspace of the user who changed a file.
+func fileChanged(e *event.Event) {
+	change, ok := e.Data.(*event.FileChange)
+	if !ok || nil == conf.Wide().Storage {
+		return
+	}
+
+	uid := change.UserId
+
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+
+	if pending[uid] {
+		return
+	}
+	pending[uid] = true
+
+	time.AfterFunc(syncDelay, func() {
+		defer gulu.Panic.Recover(nil)
+
+		pendingMutex.Lock()
+		delete(pending, uid)
+		pendingMutex.Unlock()
+
+		if err := Sync(uid); nil != err {
+			logger.Warnf("Synchronizes workspace of user [%s] failed: %s", uid, err)
+		}
+	})
+}
+
+// syncWorkspace synchronizes the workspace cache in the specified directory with the objects whose keys start with
+// the specified prefix.
+func syncWorkspace(store objectStore, dir, prefix string) (*syncStats, error) {
+	stats := &syncStats{}
+
+	manifestPath := filepath.Join(dir, manifestFile)
+	manifest := map[string]*entry{}
+	if data, err := ioutil.ReadFile(manifestPath); nil == err {
+		if err = json.Unmarshal(data, &manifest); nil != err {
+			logger.Warnf("Parses manifest [%s] failed, synchronizes as a new cache: %s", manifestPath, err)
+			manifest = map[string]*entry{}
+		}
+	}
+
+	locals, err := localFiles(dir)
+	if nil != err {
+		return stats, err
+	}
+
+	objects, err := store.ListObjects(prefix)
+	if nil != err {
+		return stats, err
+	}
+	remotes := map[string]*util.S3Object{}
+	for _, object := range objects {
+		if rel := strings.TrimPrefix(object.Key, prefix); synchronized(rel) {
+			remotes[rel] = object
+		}
+	}
+
+	paths := map[string]bool{}
+	for rel := range locals {
+		paths[rel] = true
+	}
+	for rel := range remotes {
+		paths[rel] = true
+	}
+	for rel := range manifest {
+		paths[rel] = true
+	}
+	sorted := []string{}
+	for rel := range paths {
+		sorted = append(sorted, rel)
+	}
+	sort.Strings(sorted)
+
+	var ret error
+	for _, rel := range sorted {
+		if err := syncFile(store, dir, prefix, rel, locals[rel], remotes[rel], manifest, stats); nil != err {
+			logger.Warnf("Synchronizes [%s] of workspace [%s] failed: %s", rel, dir, err)
+			if nil == ret {
+				ret = err
+			}
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if nil != err {
+		return stats, err
+	}
+	if err = ioutil.WriteFile(manifestPath, data, 0644); nil != err {
+		return stats, err
+	}
+
+	return stats, ret
+}
+
+// syncFile synchronizes the file specified by the given relative path (slash separated) of the workspace cache in the
+// specified directory, the specified local file and object are nil if not exist. The manifest is updated.
+func syncFile(store objectStore, dir, prefix, rel string, local *localFile, remote *util.S3Object,
+	manifest map[string]*entry, stats *syncStats) error {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	m := manifest[rel]
+	localChanged := nil != local && (nil == m || local.Size != m.Size || local.ModTime.UnixNano() != m.ModTime)
+	remoteChanged := nil != remote && (nil == m || remote.ETag != m.ETag)
+
+	switch {
+	case localChanged && remoteChanged:
+		if nil == m { // first synchronization of a file in both, such as caches created before the storage
+			if sum, err := md5File(path); nil == err && sum == remote.ETag {
+				manifest[rel] = &entry{Size: local.Size, ModTime: local.ModTime.UnixNano(), ETag: remote.ETag}
+
+				return nil
+			}
+		}
+
+		if local.ModTime.After(remote.LastModified) {
+			return upload(store, path, prefix+rel, rel, manifest, stats)
+		}
+
+		return download(store, path, remote, rel, local, manifest, stats)
+	case localChanged:
+		return upload(store, path, prefix+rel, rel, manifest, stats)
+	case remoteChanged:
+		return download(store, path, remote, rel, local, manifest, stats)
+	case nil == local && nil != remote: // removed locally
+		if err := store.DeleteObject(prefix + rel); nil != err {
+			return err
+		}
+		delete(manifest, rel)
+		stats.Removed++
+	case nil == remote && nil != local: // removed remotely
+		if err := os.Remove(path); nil != err && !os.IsNotExist(err) {
+			return err
+		}
+		delete(manifest, rel)
+		stats.Removed++
+	case nil == local && nil == remote:
+		delete(manifest, rel)
+	}
+
+	return nil
+}
+
+// upload uploads the file of the specified path as the object specified by the given key.
+func upload(store objectStore, path, key, rel string, manifest map[string]*entry, stats *syncStats) error {
+	f, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if nil != err {
+		return err
+	}
+
+	hash := md5.New()
+	if _, err = io.Copy(hash, f); nil != err {
+		return err
+	}
+	if _, err = f.Seek(0, io.SeekStart); nil != err {
+		return err
+	}
+
+	if err = store.PutObject(key, f); nil != err {
+		return err
+	}
+
+	manifest[rel] = &entry{Size: info.Size(), ModTime: info.ModTime().UnixNano(),
+		ETag: hex.EncodeToString(hash.Sum(nil))}
+	stats.Uploaded++
+
+	return nil
+}
+
+// download downloads the specified object to the specified path, the file is kept if it changed since the
+// synchronization started (the specified local file).
+func download(store objectStore, path string, remote *util.S3Object, rel string, local *localFile,
+	manifest map[string]*entry, stats *syncStats) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if nil != err {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = store.GetObject(remote.Key, f)
+	if closeErr := f.Close(); nil == err {
+		err = closeErr
+	}
+	if nil != err {
+		return err
+	}
+
+	if info, err := os.Lstat(path); nil == err {
+		if nil == local || info.Size() != local.Size || !info.ModTime().Equal(local.ModTime) {
+			logger.Debugf("File [%s] changed while downloading, synchronizes it later", path)
+
+			return nil
+		}
+	}
+
+	if err = os.Chtimes(f.Name(), remote.LastModified, remote.LastModified); nil != err {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); nil != err {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if nil != err {
+		return err
+	}
+	manifest[rel] = &entry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), ETag: remote.ETag}
+	stats.Downloaded++
+
+	return nil
+}
+
+// localFiles lists regular files of the workspace cache in the specified directory keyed by relative paths (slash
+// separated), files not synchronized are excluded.
+func localFiles(dir string) (map[string]*localFile, error) {
+	ret := map[string]*localFile{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if nil != err {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if "pkg" == rel || "bin" == rel {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || !synchronized(rel) {
+			return nil
+		}
+
+		ret[rel] = &localFile{Size: info.Size(), ModTime: info.ModTime()}
+
+		return nil
+	})
+
+	return ret, err
+}
+
+// synchronized checks whether the specified relative path (slash separated) of an object is of a file synchronized,
+// paths out of the workspace, of directories, of build caches and of the manifest are not.
+func synchronized(rel string) bool {
+	if "" == rel || path.Clean(rel) != rel || path.IsAbs(rel) || ".." == rel || strings.HasPrefix(rel, "../") {
+		return false
+	}
+
+	top := strings.SplitN(rel, "/", 2)[0]
+
+	return "pkg" != top && "bin" != top && manifestFile != rel && !strings.HasPrefix(path.Base(rel), tempPrefix)
+}
+
+// md5File returns the MD5 (hex) of the file of the specified path.
+func md5File(path string) (string, error) {
+	f, err := os.Open(path)
+	if nil != err {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	if _, err = io.Copy(hash, f); nil != err {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
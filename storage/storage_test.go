@@ -0,0 +1,193 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kwokhunglee/wide/util"
+)
+
+// memoryStore is an object store in memory, ETags are the MD5 of the contents as single part uploads.
+type memoryStore struct {
+	objects  map[string][]byte
+	modified map[string]time.Time
+	puts     int
+	gets     int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: map[string][]byte{}, modified: map[string]time.Time{}}
+}
+
+func (s *memoryStore) PutObject(key string, content io.ReadSeeker) error {
+	data, err := io.ReadAll(content)
+	if nil != err {
+		return err
+	}
+	s.objects[key] = data
+	s.modified[key] = time.Now()
+	s.puts++
+
+	return nil
+}
+
+func (s *memoryStore) GetObject(key string, w io.Writer) error {
+	data, ok := s.objects[key]
+	if !ok {
+		return errors.New("not found")
+	}
+	s.gets++
+	_, err := w.Write(data)
+
+	return err
+}
+
+func (s *memoryStore) DeleteObject(key string) error {
+	delete(s.objects, key)
+	delete(s.modified, key)
+
+	return nil
+}
+
+func (s *memoryStore) ListObjects(prefix string) ([]*util.S3Object, error) {
+	ret := []*util.S3Object{}
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			sum := md5.Sum(data)
+			ret = append(ret, &util.S3Object{Key: key, Size: int64(len(data)), LastModified: s.modified[key],
+				ETag: hex.EncodeToString(sum[:])})
+		}
+	}
+
+	return ret, nil
+}
+
+func writeFile(t *testing.T, dir, rel, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func readFile(dir, rel string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	if nil != err {
+		return "<" + err.Error() + ">"
+	}
+
+	return string(data)
+}
+
+func TestSyncWorkspace(t *testing.T) {
+	store := newMemoryStore()
+	a, b := t.TempDir(), t.TempDir()
+	writeFile(t, a, "src/hello/main.go", "package main\n")
+	writeFile(t, a, "src/hello/.git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, a, "pkg/linux_amd64/hello.a", "archive")
+	writeFile(t, a, "bin/hello", "binary")
+
+	stats, err := syncWorkspace(store, a, "ws/1/0/")
+	if nil != err || 2 != stats.Uploaded || 2 != len(store.objects) {
+		t.Fatalf("unexpected uploaded %+v %v %d", stats, err, len(store.objects))
+	}
+	if _, ok := store.objects["ws/1/0/src/hello/main.go"]; !ok {
+		t.Errorf("unexpected objects %v", store.objects)
+	}
+
+	// another instance without the cache
+	if stats, err = syncWorkspace(store, b, "ws/1/0/"); nil != err || 2 != stats.Downloaded {
+		t.Fatalf("unexpected downloaded %+v %v", stats, err)
+	}
+	if "package main\n" != readFile(b, "src/hello/main.go") {
+		t.Errorf("unexpected downloaded content [%s]", readFile(b, "src/hello/main.go"))
+	}
+	if stats, _ = syncWorkspace(store, b, "ws/1/0/"); 0 != stats.Uploaded+stats.Downloaded+stats.Removed {
+		t.Errorf("expected nothing synchronized, got %+v", stats)
+	}
+
+	// changed in b
+	writeFile(t, b, "src/hello/main.go", "package main\n\nfunc main() {}\n")
+	os.Remove(filepath.Join(b, "src", "hello", ".git", "HEAD"))
+	if stats, _ = syncWorkspace(store, b, "ws/1/0/"); 1 != stats.Uploaded || 1 != stats.Removed {
+		t.Errorf("unexpected synchronized %+v", stats)
+	}
+
+	// then synchronized to a
+	if stats, _ = syncWorkspace(store, a, "ws/1/0/"); 1 != stats.Downloaded || 1 != stats.Removed {
+		t.Errorf("unexpected synchronized %+v", stats)
+	}
+	if "package main\n\nfunc main() {}\n" != readFile(a, "src/hello/main.go") {
+		t.Errorf("unexpected synchronized content [%s]", readFile(a, "src/hello/main.go"))
+	}
+	if _, err := os.Stat(filepath.Join(a, "src", "hello", ".git", "HEAD")); !os.IsNotExist(err) {
+		t.Error("file removed remotely should be removed")
+	}
+	if "binary" != readFile(a, "bin/hello") {
+		t.Error("build caches should be kept")
+	}
+
+	// both changed, the newer wins
+	writeFile(t, a, "src/hello/main.go", "// a\n")
+	store.objects["ws/1/0/src/hello/main.go"] = []byte("// remote\n")
+	store.modified["ws/1/0/src/hello/main.go"] = time.Now().Add(time.Hour)
+	if stats, _ = syncWorkspace(store, a, "ws/1/0/"); 1 != stats.Downloaded || "// remote\n" != readFile(a,
+		"src/hello/main.go") {
+		t.Errorf("the newer object should win, got %+v [%s]", stats, readFile(a, "src/hello/main.go"))
+	}
+}
+
+func TestSyncWorkspaceExisting(t *testing.T) {
+	store := newMemoryStore()
+	dir := t.TempDir()
+	writeFile(t, dir, "src/a.go", "package a\n")
+	store.PutObject("ws/src/a.go", bytes.NewReader([]byte("package a\n")))
+	store.PutObject("ws/../escaped.go", bytes.NewReader([]byte("package escaped\n")))
+	store.PutObject("ws/pkg/a.a", bytes.NewReader([]byte("archive")))
+	store.puts = 0
+
+	stats, err := syncWorkspace(store, dir, "ws/")
+	if nil != err || 0 != stats.Uploaded+stats.Downloaded+stats.Removed || 0 != store.puts || 0 != store.gets {
+		t.Errorf("identical files shouldn't be transferred, got %+v %v", stats, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "escaped.go")); !os.IsNotExist(err) {
+		t.Error("objects out of the workspace should be ignored")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pkg")); !os.IsNotExist(err) {
+		t.Error("build caches shouldn't be downloaded")
+	}
+}
+
+func TestSynchronized(t *testing.T) {
+	cases := map[string]bool{"src/a.go": true, "a.go": true, "pkg": false, "pkg/a.a": false, "bin/a": false,
+		"src/pkg/a.go": true, "../a": false, "src/../../a": false, "/a": false, "src/": false, "": false,
+		manifestFile: false, "src/" + tempPrefix + "1": false}
+	for rel, expected := range cases {
+		if got := synchronized(rel); expected != got {
+			t.Errorf("expected [%v] of [%s], got [%v]", expected, rel, got)
+		}
+	}
+}
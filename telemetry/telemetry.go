@@ -0,0 +1,253 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry includes the opt-in anonymous usage telemetry, which posts aggregate counts to the configured
+// endpoint (Wide.Telemetry) periodically.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwokhunglee/wide/conf"
+	"github.com/kwokhunglee/wide/event"
+	"github.com/kwokhunglee/wide/gulu"
+	"github.com/kwokhunglee/wide/session"
+)
+
+// Logger.
+var logger = gulu.Log.NewLogger(os.Stdout)
+
+// defaultInterval is the interval between reports if not configured.
+const defaultInterval = 24 * time.Hour
+
+// client posting reports.
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Report represents a telemetry report, counts are of the period since the previous report.
+type Report struct {
+	Instance    string           `json:"instance"`    // random id of the Wide instance, kept in {Data}/telemetry.id
+	Version     string           `json:"version"`     // version of Wide
+	GoVersion   string           `json:"goVersion"`   // version of Go Wide built with
+	OS          string           `json:"os"`          // runtime.GOOS
+	Arch        string           `json:"arch"`        // runtime.GOARCH
+	Period      int64            `json:"period"`      // seconds since the previous report (or the start)
+	Users       int              `json:"users"`       // number of users
+	ActiveUsers int              `json:"activeUsers"` // number of users active in the period
+	Builds      int64            `json:"builds"`      // number of builds
+	Tests       int64            `json:"tests"`       // number of go test runs
+	FailedJobs  int64            `json:"failedJobs"`  // number of failed builds and go test runs
+	Errors      int64            `json:"errors"`      // number of requests responded with 5xx
+	Events      map[string]int64 `json:"events"`      // numbers of published events by names
+}
+
+var (
+	// counts of the current period, <"builds"/"tests"/"failedJobs"/"errors", count>
+	counts = map[string]int64{}
+
+	// event counts (see event.Counts) reported so far, <event name, count>
+	reportedEvents = map[string]int64{}
+
+	// start time of the current period
+	periodStart = time.Now()
+
+	// guards counts, reportedEvents and periodStart
+	mutex sync.Mutex
+)
+
+// Load starts counting and the scheduled reports, the schedule follows configurations reloading. Nothing is reported
+// unless Wide.Telemetry is configured.
+func Load() {
+	event.Subscribe(event.HandleFunc(countJob), event.EvtCodeJobDone)
+
+	go func() {
+		defer gulu.Panic.Recover(nil)
+
+		var next time.Time
+		for range time.Tick(time.Minute) {
+			t := conf.Wide().Telemetry
+			if nil == t || "" == t.Endpoint {
+				continue
+			}
+
+			interval := defaultInterval
+			if 0 < t.Interval {
+				interval = time.Duration(t.Interval) * time.Hour
+			}
+			if next.IsZero() {
+				next = periodStart.Add(interval)
+			}
+			if time.Now().Before(next) {
+				continue
+			}
+
+			report := current()
+			if err := post(t.Endpoint, report); nil != err {
+				logger.Warnf("Posts telemetry to [%s] failed: %s", t.Endpoint, err)
+
+				// retries later instead of every minute
+				if time.Hour < interval {
+					interval = time.Hour
+				}
+			} else {
+				reported(report)
+			}
+			next = time.Now().Add(interval)
+		}
+	}()
+}
+
+// CountError counts a request responded with 5xx.
+func CountError() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	counts["errors"]++
+}
+
+// countJob counts the finished build/test job of the specified event.
+func countJob(e *event.Event) {
+	job, ok := e.Data.(*event.Job)
+	if !ok {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	switch job.Name {
+	case "build":
+		counts["builds"]++
+	case "test":
+		counts["tests"]++
+	}
+	if !job.Succ {
+		counts["failedJobs"]++
+	}
+}
+
+// current returns the report of the current period.
+func current() *Report {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	ret := &Report{Instance: instanceId(), Version: conf.WideVersion, GoVersion: runtime.Version(), OS: runtime.GOOS,
+		Arch: runtime.GOARCH, Period: int64(time.Since(periodStart) / time.Second), Builds: counts["builds"],
+		Tests: counts["tests"], FailedJobs: counts["failedJobs"], Errors: counts["errors"], Events: map[string]int64{}}
+
+	for _, user := range conf.GetUsers() {
+		ret.Users++
+		if user.Lived > periodStart.UnixNano() {
+			ret.ActiveUsers++
+		}
+	}
+
+	for name, count := range event.Counts() {
+		if delta := count - reportedEvents[name]; 0 < delta {
+			ret.Events[name] = delta
+		}
+	}
+
+	return ret
+}
+
+// reported starts a new period after the specified report has been posted, counts increased while posting are kept.
+func reported(report *Report) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	counts["builds"] -= report.Builds
+	counts["tests"] -= report.Tests
+	counts["failedJobs"] -= report.FailedJobs
+	counts["errors"] -= report.Errors
+	for name, delta := range report.Events {
+		reportedEvents[name] += delta
+	}
+	periodStart = time.Now()
+}
+
+// post posts the specified report to the specified endpoint.
+func post(endpoint string, report *Report) error {
+	data, err := json.Marshal(report)
+	if nil != err {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if nil != err {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", conf.UserAgent)
+
+	resp, err := client.Do(req)
+	if nil != err {
+		return err
+	}
+	resp.Body.Close()
+
+	if 200 > resp.StatusCode || 300 <= resp.StatusCode {
+		return fmt.Errorf("responded [%s]", resp.Status)
+	}
+
+	return nil
+}
+
+// instanceId gets the random id of the Wide instance, it's generated at the first time and kept in
+// {Data}/telemetry.id, so that reports of the same instance could be told apart without identifying anything.
+func instanceId() string {
+	path := filepath.Join(conf.Wide().Data, "telemetry.id")
+	if bytes, err := ioutil.ReadFile(path); nil == err && "" != strings.TrimSpace(string(bytes)) {
+		return strings.TrimSpace(string(bytes))
+	}
+
+	ret := gulu.Rand.String(32)
+	if err := ioutil.WriteFile(path, []byte(ret), 0644); nil != err {
+		logger.Warnf("Writes telemetry instance id [%s] failed: %s", path, err)
+	}
+
+	return ret
+}
+
+// PreviewHandler handles request of previewing the telemetry report of the current period, exactly what would be
+// posted, only administrators are allowed.
+func PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	httpSession, _ := session.HTTPSession.Get(r, session.CookieName)
+	if httpSession.IsNew {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+	uid := httpSession.Values["uid"].(string)
+	if !conf.Wide().IsAdmin(uid) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	result := gulu.Ret.NewResult()
+	defer gulu.Ret.RetResult(w, r, result)
+
+	t := conf.Wide().Telemetry
+	result.Data = map[string]interface{}{"enabled": nil != t && "" != t.Endpoint, "report": current()}
+}
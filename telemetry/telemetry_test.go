@@ -0,0 +1,71 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kwokhunglee/wide/event"
+)
+
+func TestCounts(t *testing.T) {
+	countJob(&event.Event{Code: event.EvtCodeJobDone, Data: &event.Job{Name: "build", Succ: true}})
+	countJob(&event.Event{Code: event.EvtCodeJobDone, Data: &event.Job{Name: "test", Succ: false}})
+	CountError()
+
+	if 1 != counts["builds"] || 1 != counts["tests"] || 1 != counts["failedJobs"] || 1 != counts["errors"] {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+
+	// a build finished while posting the report is kept for the next period
+	report := &Report{Builds: 1, Tests: 1, FailedJobs: 1, Errors: 1, Events: map[string]int64{"job-done": 2}}
+	countJob(&event.Event{Code: event.EvtCodeJobDone, Data: &event.Job{Name: "build", Succ: true}})
+	reported(report)
+
+	if 1 != counts["builds"] || 0 != counts["tests"] || 0 != counts["failedJobs"] || 0 != counts["errors"] {
+		t.Errorf("unexpected counts %v after reported", counts)
+	}
+	if 2 != reportedEvents["job-done"] {
+		t.Errorf("unexpected reported events %v", reportedEvents)
+	}
+}
+
+func TestPost(t *testing.T) {
+	var got *Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = &Report{}
+		json.NewDecoder(r.Body).Decode(got)
+	}))
+	defer server.Close()
+
+	if err := post(server.URL, &Report{Instance: "i", Builds: 3}); nil != err {
+		t.Fatal(err)
+	}
+	if nil == got || "i" != got.Instance || 3 != got.Builds {
+		t.Errorf("unexpected posted report %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := post(failing.URL, &Report{}); nil == err {
+		t.Error("expected an error of a failing endpoint")
+	}
+}
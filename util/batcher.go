@@ -0,0 +1,74 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputBatcher batches output texts written in an interval into one, so high-frequency output (such as a program
+// printing character by character) is sent in periodic WebSocket frames instead of one message per write.
+type OutputBatcher struct {
+	interval time.Duration     // flush interval, texts are flushed at once if not positive
+	flush    func(text string) // sends the batched text
+
+	mutex sync.Mutex
+	buf   strings.Builder
+	timer *time.Timer
+}
+
+// NewOutputBatcher creates a batcher flushing the texts written with the specified function every the specified
+// interval.
+func NewOutputBatcher(interval time.Duration, flush func(text string)) *OutputBatcher {
+	return &OutputBatcher{interval: interval, flush: flush}
+}
+
+// Write appends the specified text to the batch, which is flushed after the interval.
+func (b *OutputBatcher) Write(text string) {
+	if 0 >= b.interval {
+		b.flush(text)
+
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.buf.WriteString(text)
+	if nil == b.timer {
+		b.timer = time.AfterFunc(b.interval, b.Flush)
+	}
+}
+
+// Flush sends the batched text at once, should be called before sending messages following the output.
+func (b *OutputBatcher) Flush() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if nil != b.timer {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	if 0 == b.buf.Len() {
+		return
+	}
+
+	text := b.buf.String()
+	b.buf.Reset()
+	b.flush(text)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutputBatcher(t *testing.T) {
+	mutex := sync.Mutex{}
+	flushed := []string{}
+	batcher := NewOutputBatcher(time.Hour, func(text string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		flushed = append(flushed, text)
+	})
+
+	for _, text := range []string{"a", "b", "c"} {
+		batcher.Write(text)
+	}
+	batcher.Flush()
+	batcher.Flush()
+	if 1 != len(flushed) || "abc" != flushed[0] {
+		t.Errorf("expected [abc] flushed, actual %v", flushed)
+	}
+
+	batcher = NewOutputBatcher(10*time.Millisecond, batcher.flush)
+	batcher.Write("d")
+	batcher.Write("e")
+	time.Sleep(100 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if 2 != len(flushed) || "de" != flushed[1] {
+		t.Errorf("expected [abc de] flushed after the interval, actual %v", flushed)
+	}
+}
+
+func TestOutputBatcherDisabled(t *testing.T) {
+	flushed := []string{}
+	batcher := NewOutputBatcher(0, func(text string) { flushed = append(flushed, text) })
+
+	batcher.Write("a")
+	batcher.Write("b")
+	if 2 != len(flushed) {
+		t.Errorf("expected texts flushed at once, actual %v", flushed)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// number of buckets above which full buckets are swept.
+const rateLimiterSweepSize = 10000
+
+// RateLimiter limits rates of requests by keys (such as user ids or IPs) with token buckets.
+type RateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // capacity of a bucket
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket represents the token bucket of a key.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing the specified number of requests per minute for each key, at most
+// burst requests are allowed at once.
+func NewRateLimiter(perMinute float64, burst int) *RateLimiter {
+	if 1 > burst {
+		burst = 1
+	}
+
+	return &RateLimiter{rate: perMinute / 60, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+}
+
+// Allow checks whether a request of the specified key is allowed now, returns how long to wait before retrying if not.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	return l.allow(key, time.Now())
+}
+
+func (l *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.buckets[key]
+	if nil == b {
+		if len(l.buckets) >= rateLimiterSweepSize {
+			l.sweep(now)
+		}
+
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.updated).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.updated = now
+	}
+
+	if 1 <= b.tokens {
+		b.tokens--
+
+		return true, 0
+	}
+
+	if 0 >= l.rate {
+		return false, time.Hour
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep removes the buckets which are full at the specified time, they are the same as new ones.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
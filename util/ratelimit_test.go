@@ -0,0 +1,50 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(60, 2) // a request per second
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request [%d] within the burst should be allowed", i)
+		}
+	}
+
+	ok, retryAfter := l.allow("a", now)
+	if ok {
+		t.Fatal("request exceeding the burst should be rejected")
+	}
+	if time.Second != retryAfter {
+		t.Errorf("expected retry after [%s], got [%s]", time.Second, retryAfter)
+	}
+
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("requests of other keys should be allowed")
+	}
+
+	if ok, _ := l.allow("a", now.Add(time.Second)); !ok {
+		t.Error("request should be allowed after a token is added")
+	}
+	if ok, _ := l.allow("a", now.Add(time.Second)); ok {
+		t.Error("request should be rejected after the added token is taken")
+	}
+}
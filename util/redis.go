@@ -0,0 +1,213 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisClient is a minimal Redis client supporting publish/subscribe only, speaking RESP (REdis Serialization
+// Protocol) over TCP.
+type RedisClient struct {
+	Addr     string        // such as "127.0.0.1:6379"
+	Password string        // "" if authentication is not required
+	Timeout  time.Duration // timeout of dialing and publishing, defaults to 5 seconds
+
+	mutex  sync.Mutex // guards the publishing connection
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Publish publishes the specified message to the specified channel. The publishing connection is kept and dialed
+// again after failures.
+func (c *RedisClient) Publish(channel string, message []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if nil == c.conn {
+		conn, reader, err := c.dial()
+		if nil != err {
+			return err
+		}
+		c.conn, c.reader = conn, reader
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout()))
+	_, err := c.do(c.conn, c.reader, []byte("PUBLISH"), []byte(channel), message)
+	if nil != err {
+		c.conn.Close()
+		c.conn, c.reader = nil, nil
+	}
+
+	return err
+}
+
+// Subscribe subscribes the specified channel and calls the specified handler with each received message, blocks until
+// the connection fails.
+func (c *RedisClient) Subscribe(channel string, handler func(message []byte)) error {
+	conn, reader, err := c.dial()
+	if nil != err {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(c.timeout()))
+	if _, err := c.do(conn, reader, []byte("SUBSCRIBE"), []byte(channel)); nil != err {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	for {
+		reply, err := readRedisReply(reader)
+		if nil != err {
+			return err
+		}
+
+		// ["message", channel, payload]
+		if items, ok := reply.([]interface{}); ok && 3 == len(items) {
+			if kind, _ := items[0].([]byte); "message" == string(kind) {
+				payload, _ := items[2].([]byte)
+				handler(payload)
+			}
+		}
+	}
+}
+
+// Close closes the publishing connection.
+func (c *RedisClient) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if nil != c.conn {
+		c.conn.Close()
+		c.conn, c.reader = nil, nil
+	}
+}
+
+// dial dials the server and authenticates if the password is specified.
+func (c *RedisClient) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", c.Addr, c.timeout())
+	if nil != err {
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+
+	if "" != c.Password {
+		conn.SetDeadline(time.Now().Add(c.timeout()))
+		if _, err := c.do(conn, reader, []byte("AUTH"), []byte(c.Password)); nil != err {
+			conn.Close()
+
+			return nil, nil, err
+		}
+		conn.SetDeadline(time.Time{})
+	}
+
+	return conn, reader, nil
+}
+
+// do sends the specified command on the specified connection and reads the reply.
+func (c *RedisClient) do(conn net.Conn, reader *bufio.Reader, args ...[]byte) (interface{}, error) {
+	if err := writeRedisCommand(conn, args...); nil != err {
+		return nil, err
+	}
+
+	return readRedisReply(reader)
+}
+
+// timeout gets the timeout of dialing and publishing.
+func (c *RedisClient) timeout() time.Duration {
+	if 0 >= c.Timeout {
+		return 5 * time.Second
+	}
+
+	return c.Timeout
+}
+
+// writeRedisCommand writes the specified command as an array of bulk strings.
+func writeRedisCommand(w io.Writer, args ...[]byte) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+
+	_, err := w.Write(buf)
+
+	return err
+}
+
+// readRedisReply reads a reply: simple strings and bulk strings are returned as []byte (nil for the null bulk string),
+// integers as int64 and arrays as []interface{}. Error replies are returned as errors.
+func readRedisReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if nil != err {
+		return nil, err
+	}
+	if 3 > len(line) || '\r' != line[len(line)-2] {
+		return nil, fmt.Errorf("malformed reply [%q]", line)
+	}
+	kind, content := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return []byte(content), nil
+	case '-':
+		return nil, errors.New(content)
+	case ':':
+		return strconv.ParseInt(content, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(content)
+		if nil != err {
+			return nil, err
+		}
+		if 0 > n {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, buf); nil != err {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(content)
+		if nil != err {
+			return nil, err
+		}
+		if 0 > n {
+			return nil, nil
+		}
+
+		ret := make([]interface{}, n)
+		for i := range ret {
+			if ret[i], err = readRedisReply(reader); nil != err {
+				return nil, err
+			}
+		}
+
+		return ret, nil
+	}
+
+	return nil, fmt.Errorf("malformed reply [%q]", line)
+}
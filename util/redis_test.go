@@ -0,0 +1,71 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteRedisCommand(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeRedisCommand(buf, []byte("PUBLISH"), []byte("wide"), []byte("hi\r\n")); nil != err {
+		t.Fatal(err)
+	}
+
+	expected := "*3\r\n$7\r\nPUBLISH\r\n$4\r\nwide\r\n$4\r\nhi\r\n\r\n"
+	if got := buf.String(); expected != got {
+		t.Errorf("expected [%q], got [%q]", expected, got)
+	}
+}
+
+func TestReadRedisReply(t *testing.T) {
+	cases := []struct {
+		reply    string
+		expected interface{}
+		fail     bool
+	}{
+		{reply: "+OK\r\n", expected: []byte("OK")},
+		{reply: ":2\r\n", expected: int64(2)},
+		{reply: "$5\r\nhi\r\n!\r\n", expected: []byte("hi\r\n!")},
+		{reply: "$-1\r\n", expected: nil},
+		{reply: "*3\r\n$7\r\nmessage\r\n$4\r\nwide\r\n$2\r\n{}\r\n",
+			expected: []interface{}{[]byte("message"), []byte("wide"), []byte("{}")}},
+		{reply: "-ERR unknown command\r\n", fail: true},
+		{reply: "OK\n", fail: true},
+	}
+
+	for _, c := range cases {
+		got, err := readRedisReply(bufio.NewReader(strings.NewReader(c.reply)))
+		if c.fail {
+			if nil == err {
+				t.Errorf("reply [%q] should fail", c.reply)
+			}
+
+			continue
+		}
+		if nil != err {
+			t.Errorf("reads reply [%q] failed: %s", c.reply, err)
+
+			continue
+		}
+		if !reflect.DeepEqual(c.expected, got) {
+			t.Errorf("expected [%v] of reply [%q], got [%v]", c.expected, c.reply, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RegexpFlags are the flags of Go regular expressions could be set by RegexpTest, see regexp/syntax.
+const RegexpFlags = "imsU"
+
+// RegexpGroup represents a capturing group of a match.
+type RegexpGroup struct {
+	Index   int    `json:"index"`   // index of the group, starts from 1
+	Name    string `json:"name"`    // name of the group, "" if not named
+	Matched bool   `json:"matched"` // whether the group participated in the match
+	Start   int    `json:"start"`   // byte offset of the start in the text, -1 if not matched
+	End     int    `json:"end"`     // byte offset of the end in the text, -1 if not matched
+	Text    string `json:"text"`    // matched text
+}
+
+// RegexpMatch represents a match of a regular expression.
+type RegexpMatch struct {
+	Start  int            `json:"start"`  // byte offset of the start in the text
+	End    int            `json:"end"`    // byte offset of the end in the text
+	Line   int            `json:"line"`   // line of the start, starts from 1
+	Column int            `json:"column"` // column (in characters) of the start, starts from 1
+	Text   string         `json:"text"`   // matched text
+	Groups []*RegexpGroup `json:"groups"` // capturing groups
+}
+
+// RegexpResult represents the result of testing a regular expression against a text.
+type RegexpResult struct {
+	Pattern   string         `json:"pattern"`   // the compiled pattern with flags
+	Groups    []string       `json:"groups"`    // names of the capturing groups, "" for the unnamed ones
+	Matches   []*RegexpMatch `json:"matches"`   // matches in order
+	Truncated bool           `json:"truncated"` // whether there are more matches than the limit
+	Replaced  *string        `json:"replaced"`  // the text with all matches replaced, nil if no replacement given
+}
+
+// RegexpTest compiles the specified pattern with the specified flags (a subset of RegexpFlags) in Go's RE2 syntax and
+// finds at most the specified limit matches in the specified text. If the specified replacement is not nil, the text
+// with all matches replaced (expanding $1, ${name} and so on) is also returned.
+//
+// Returns an error if the flags are unknown or the pattern is invalid, the error of regexp.Compile is descriptive
+// enough to show to users as is.
+func RegexpTest(pattern, flags, text string, replacement *string, limit int) (*RegexpResult, error) {
+	for _, flag := range flags {
+		if !strings.ContainsRune(RegexpFlags, flag) {
+			return nil, errors.New("unknown flag [" + string(flag) + "], flags could be [" + RegexpFlags + "]")
+		}
+	}
+	if "" != flags {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if nil != err {
+		return nil, err
+	}
+
+	ret := &RegexpResult{Pattern: re.String(), Groups: re.SubexpNames()[1:], Matches: []*RegexpMatch{}}
+
+	indexes := re.FindAllStringSubmatchIndex(text, limit+1)
+	if len(indexes) > limit {
+		indexes = indexes[:limit]
+		ret.Truncated = true
+	}
+
+	line, lineStart, offset := 1, 0, 0
+	for _, index := range indexes {
+		// lines are counted incrementally as the matches are in order
+		for ; offset < index[0]; offset++ {
+			if '\n' == text[offset] {
+				line++
+				lineStart = offset + 1
+			}
+		}
+
+		match := &RegexpMatch{Start: index[0], End: index[1], Line: line,
+			Column: utf8.RuneCountInString(text[lineStart:index[0]]) + 1, Text: text[index[0]:index[1]],
+			Groups: []*RegexpGroup{}}
+		for i := 1; i < len(index)/2; i++ {
+			group := &RegexpGroup{Index: i, Name: ret.Groups[i-1], Start: index[2*i], End: index[2*i+1]}
+			if 0 <= group.Start {
+				group.Matched = true
+				group.Text = text[group.Start:group.End]
+			}
+			match.Groups = append(match.Groups, group)
+		}
+		ret.Matches = append(ret.Matches, match)
+	}
+
+	if nil != replacement {
+		replaced := re.ReplaceAllString(text, *replacement)
+		ret.Replaced = &replaced
+	}
+
+	return ret, nil
+}
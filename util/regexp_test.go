@@ -0,0 +1,74 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestRegexpTest(t *testing.T) {
+	replacement := "${key}=$2"
+	result, err := RegexpTest(`(?P<key>[^:\s]+): (\d+)?`, "", "a: 1\nbé: \nc: 3", &replacement, 10)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(result.Groups) || "key" != result.Groups[0] || "" != result.Groups[1] {
+		t.Errorf("unexpected groups %v", result.Groups)
+	}
+	if 3 != len(result.Matches) || result.Truncated {
+		t.Fatalf("unexpected matches %d", len(result.Matches))
+	}
+
+	second := result.Matches[1]
+	if 5 != second.Start || 10 != second.End || 2 != second.Line || 1 != second.Column || "bé: " != second.Text {
+		t.Errorf("unexpected match %+v", second)
+	}
+	if "bé" != second.Groups[0].Text || !second.Groups[0].Matched || "key" != second.Groups[0].Name {
+		t.Errorf("unexpected group %+v", second.Groups[0])
+	}
+	if second.Groups[1].Matched || -1 != second.Groups[1].Start {
+		t.Errorf("unmatched group expected, got %+v", second.Groups[1])
+	}
+	if third := result.Matches[2]; 3 != third.Line || "3" != third.Groups[1].Text {
+		t.Errorf("unexpected match %+v", third)
+	}
+	if nil == result.Replaced || "a=1\nbé=\nc=3" != *result.Replaced {
+		t.Errorf("unexpected replaced %v", result.Replaced)
+	}
+
+	result, err = RegexpTest(`é(\w)`, "i", "xÉa Éb", nil, 1)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "(?i)é(\\w)" != result.Pattern || 1 != len(result.Matches) || !result.Truncated || nil != result.Replaced {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if match := result.Matches[0]; 1 != match.Start || 2 != match.Column || "a" != match.Groups[0].Text {
+		t.Errorf("unexpected match %+v", match)
+	}
+
+	if result, _ := RegexpTest(`x*`, "", "ab", nil, 10); 3 != len(result.Matches) {
+		t.Errorf("expected empty matches, got %d", len(result.Matches))
+	}
+	if _, err := RegexpTest(`a(`, "", "a", nil, 10); nil == err {
+		t.Error("invalid pattern should be refused")
+	}
+	if _, err := RegexpTest(`a`, "g", "a", nil, 10); nil == err {
+		t.Error("unknown flag should be refused")
+	}
+	if _, err := RegexpTest(`a(?=b)`, "", "ab", nil, 10); nil == err {
+		t.Error("lookahead isn't supported by RE2")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"net/http"
+
+	"github.com/kwokhunglee/wide/gulu"
+)
+
+// RequestIdHeader is the header carrying the correlation id of a request.
+const RequestIdHeader = "X-Request-Id"
+
+// max length of a request id accepted from clients.
+const maxRequestIdLen = 64
+
+// RequestId gets the correlation id of the specified request, returns "" if the request has not been assigned one.
+func RequestId(r *http.Request) string {
+	return r.Header.Get(RequestIdHeader)
+}
+
+// AssignRequestId assigns a correlation id to the specified request and returns it. The id sent by the client (such
+// as a proxy in front of Wide) is reused if it is valid, a random one is generated otherwise.
+func AssignRequestId(r *http.Request) string {
+	ret := r.Header.Get(RequestIdHeader)
+	if !validRequestId(ret) {
+		ret = gulu.Rand.String(16)
+	}
+
+	r.Header.Set(RequestIdHeader, ret)
+
+	return ret
+}
+
+// validRequestId checks whether the specified request id is not empty, not too long and consists of letters, digits,
+// '-', '_' and '.' only, so that it's safe to be logged.
+func validRequestId(id string) bool {
+	if "" == id || maxRequestIdLen < len(id) {
+		return false
+	}
+
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || '-' == c || '_' == c || '.' == c) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAssignRequestId(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/build", nil)
+	r.Header.Set(RequestIdHeader, "proxy-1.a_b")
+	if id := AssignRequestId(r); "proxy-1.a_b" != id {
+		t.Errorf("expected the client's request id, got [%s]", id)
+	}
+
+	for _, invalid := range []string{"", "a b", "a\nb", strings.Repeat("a", maxRequestIdLen+1)} {
+		r.Header.Set(RequestIdHeader, invalid)
+		id := AssignRequestId(r)
+		if id == invalid || !validRequestId(id) {
+			t.Errorf("expected a generated request id for [%q], got [%s]", invalid, id)
+		}
+		if RequestId(r) != id {
+			t.Errorf("expected request id [%s] set on the request, got [%s]", id, RequestId(r))
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3EmptyPayloadHash is the SHA-256 hash of an empty payload.
+const s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Client is a minimal client of S3-compatible object storages (AWS S3, MinIO, etc), requests are signed with AWS
+// Signature Version 4 and objects are addressed in path style ({Endpoint}/{Bucket}/{key}).
+type S3Client struct {
+	Endpoint  string // such as "https://s3.us-east-1.amazonaws.com"
+	Region    string // such as "us-east-1"
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client // http.DefaultClient if nil
+}
+
+// S3Object represents an object listed from a bucket.
+type S3Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string // entity tag without quotes, the MD5 (hex) of the content if uploaded in a single part
+}
+
+// PutObject uploads the specified content as the object specified by the given key.
+func (c *S3Client) PutObject(key string, content io.ReadSeeker) error {
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if nil != err {
+		return err
+	}
+	if _, err = content.Seek(0, io.SeekStart); nil != err {
+		return err
+	}
+
+	resp, err := c.do(http.MethodPut, key, nil, ioutil.NopCloser(content), size, hex.EncodeToString(hash.Sum(nil)))
+	if nil != err {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// GetObject downloads the object specified by the given key to the specified writer.
+func (c *S3Client) GetObject(key string, w io.Writer) error {
+	resp, err := c.do(http.MethodGet, key, nil, nil, 0, s3EmptyPayloadHash)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
+
+// DeleteObject deletes the object specified by the given key.
+func (c *S3Client) DeleteObject(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil, nil, 0, s3EmptyPayloadHash)
+	if nil != err {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// ListObjects lists objects whose keys start with the specified prefix.
+func (c *S3Client) ListObjects(prefix string) ([]*S3Object, error) {
+	ret := []*S3Object{}
+
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := c.do(http.MethodGet, "", query, nil, 0, s3EmptyPayloadHash)
+		if nil != err {
+			return nil, err
+		}
+
+		result := struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				LastModified time.Time
+				ETag         string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}{}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if nil != err {
+			return nil, err
+		}
+
+		for _, content := range result.Contents {
+			ret = append(ret, &S3Object{Key: content.Key, Size: content.Size, LastModified: content.LastModified,
+				ETag: strings.Trim(content.ETag, `"`)})
+		}
+
+		if !result.IsTruncated || "" == result.NextContinuationToken {
+			return ret, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// do sends a signed request of the object specified by the given key (the bucket if key is ""), returns an error if
+// the response status is not 2xx.
+func (c *S3Client) do(method, key string, query url.Values, body io.ReadCloser, size int64,
+	payloadHash string) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/") + "/" + c.Bucket + "/" + key)
+	if nil != err {
+		return nil, err
+	}
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if nil != err {
+		return nil, err
+	}
+	req.URL.RawPath = s3Escape(req.URL.Path, false) // sends the path escaped as signed
+	if nil != body {
+		req.Body = body
+		req.ContentLength = size
+	}
+	signS3Request(req, payloadHash, c.Region, c.AccessKey, c.SecretKey, time.Now())
+
+	client := c.Client
+	if nil == client {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if nil != err {
+		return nil, err
+	}
+
+	if 200 > resp.StatusCode || 300 <= resp.StatusCode {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%s [%s] responded [%s]: %s", method, u.Path, resp.Status,
+			strings.TrimSpace(string(data)))
+	}
+
+	return resp, nil
+}
+
+// signS3Request signs the specified request with AWS Signature Version 4, the signed headers are "Host", "Range" (if
+// present), "X-Amz-Content-Sha256" and "X-Amz-Date".
+func signS3Request(req *http.Request, payloadHash, region, accessKey, secretKey string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if "" == host {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host, "x-amz-content-sha256": payloadHash, "x-amz-date": amzDate}
+	if rng := req.Header.Get("Range"); "" != rng {
+		headers["range"] = rng
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, s3Escape(req.URL.Path, false), req.URL.RawQuery,
+		canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, data := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, data)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the specified data with the specified key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery returns the canonical query string of the specified query, keys are sorted and keys and values are
+// escaped with s3Escape.
+func s3CanonicalQuery(query url.Values) string {
+	params := []string{}
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// s3Escape escapes the specified string as RFC 3986 requires, "/" is kept unless escapeSlash is true.
+func s3Escape(s string, escapeSlash bool) string {
+	ret := strings.Builder{}
+	for _, b := range []byte(s) {
+		if ('A' <= b && 'Z' >= b) || ('a' <= b && 'z' >= b) || ('0' <= b && '9' >= b) ||
+			'-' == b || '.' == b || '_' == b || '~' == b || ('/' == b && !escapeSlash) {
+			ret.WriteByte(b)
+		} else {
+			fmt.Fprintf(&ret, "%%%02X", b)
+		}
+	}
+
+	return ret.String()
+}
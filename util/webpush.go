@@ -0,0 +1,285 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// record size of the Web Push encrypted content.
+const pushRecordSize = 4096
+
+// PushClient is the HTTP client sending pushes. Endpoints come from browsers, so it refuses to connect to non-public
+// addresses (checked after resolving) to keep subscriptions from making Wide request internal services.
+var PushClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// ValidPushEndpoint checks whether the specified endpoint of a push subscription is an https URL of a public host.
+func ValidPushEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if nil != err || "https" != u.Scheme || "" == u.Hostname() || nil != u.User {
+		return false
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if nil != err || 1 > len(ips) {
+		return false
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dialPublicOnly is the control function of dialers refusing to connect to non-public addresses.
+func dialPublicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if nil != err {
+		return err
+	}
+
+	if ip := net.ParseIP(host); nil == ip || !IsPublicIP(ip) {
+		return errors.New("refuses to connect to non-public address [" + host + "]")
+	}
+
+	return nil
+}
+
+// IsPublicIP checks whether the specified IP is a public unicast address.
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	// shared address space (RFC 6598)
+	_, shared, _ := net.ParseCIDR("100.64.0.0/10")
+
+	return !shared.Contains(ip)
+}
+
+// LoadVAPIDKey loads the VAPID key pair identifying the application server to push services from the specified PEM
+// file, generates and saves one if the file doesn't exist.
+func LoadVAPIDKey(path string) (*ecdsa.PrivateKey, error) {
+	if data, err := ioutil.ReadFile(path); nil == err {
+		block, _ := pem.Decode(data)
+		if nil == block {
+			return nil, fmt.Errorf("parses [%s] failed", path)
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if nil != err {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); nil != err {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// VAPIDPublicKey returns the public key (base64url of the uncompressed point) of the specified VAPID key pair, which
+// is the applicationServerKey of browsers subscribing.
+func VAPIDPublicKey(key *ecdsa.PrivateKey) string {
+	public, err := key.PublicKey.ECDH()
+	if nil != err {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(public.Bytes())
+}
+
+// SendPush sends the specified payload to the push subscription of the specified endpoint, public key (p256dh) and
+// authentication secret (auth) with the specified client, VAPID key pair and contact (mailto: or https: URL).
+//
+// Returns whether the subscription is gone (expired or unsubscribed), which should be removed then.
+func SendPush(client *http.Client, key *ecdsa.PrivateKey, subject, endpoint, p256dh, auth string,
+	payload []byte) (bool, error) {
+	u, err := url.Parse(endpoint)
+	if nil != err {
+		return true, err
+	}
+
+	body, err := encryptPush(p256dh, auth, payload)
+	if nil != err {
+		return false, err
+	}
+
+	authorization, err := vapidAuthorization(key, u.Scheme+"://"+u.Host, subject)
+	if nil != err {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if nil != err {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := client.Do(req)
+	if nil != err {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if http.StatusNotFound == resp.StatusCode || http.StatusGone == resp.StatusCode {
+		return true, nil
+	}
+	if 200 > resp.StatusCode || 300 <= resp.StatusCode {
+		return false, errors.New("push service responded [" + resp.Status + "]")
+	}
+
+	return false, nil
+}
+
+// encryptPush encrypts the specified payload for the subscription of the specified public key and authentication
+// secret with content encoding "aes128gcm" (RFC 8291).
+func encryptPush(p256dh, auth string, payload []byte) ([]byte, error) {
+	uaPublic, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(p256dh, "="))
+	if nil != err {
+		return nil, err
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(auth, "="))
+	if nil != err {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if nil != err {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); nil != err {
+		return nil, err
+	}
+
+	return encryptPushWith(asPrivate, salt, uaPublic, authSecret, payload)
+}
+
+// encryptPushWith encrypts the specified payload for the specified user agent public key and authentication secret
+// with the specified application server key pair and salt, which are generated for each message.
+func encryptPushWith(asPrivate *ecdh.PrivateKey, salt, uaPublic, authSecret, payload []byte) ([]byte, error) {
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if nil != err {
+		return nil, errors.New("invalid public key of subscription")
+	}
+
+	ecdhSecret, err := asPrivate.ECDH(uaKey)
+	if nil != err {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := hkdfExpand(ecdhSecret, authSecret, keyInfo, 32)
+	cek := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if nil != err {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if nil != err {
+		return nil, err
+	}
+
+	// header: salt | record size | key id length | key id (public key of the application server)
+	ret := append([]byte{}, salt...)
+	ret = append(ret, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(ret[16:], pushRecordSize)
+	ret = append(ret, byte(len(asPublic)))
+	ret = append(ret, asPublic...)
+
+	// a single record, padding delimiter 0x02 marks the last one
+	return gcm.Seal(ret, nonce, append(append([]byte{}, payload...), 2), nil), nil
+}
+
+// vapidAuthorization returns the value of header Authorization identifying the application server of the specified
+// key pair and contact to the push service of the specified origin (RFC 8292).
+func vapidAuthorization(key *ecdsa.PrivateKey, audience, subject string) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{"aud": audience, "sub": subject,
+		"exp": time.Now().Add(12 * time.Hour).Unix()})
+	if nil != err {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if nil != err {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return "vapid t=" + unsigned + "." + base64.RawURLEncoding.EncodeToString(signature) + ", k=" + VAPIDPublicKey(key), nil
+}
+
+// hkdfExpand derives a key of the specified length with HKDF-SHA256.
+func hkdfExpand(secret, salt, info []byte, length int) []byte {
+	ret := make([]byte, length)
+	io.ReadFull(hkdf.New(sha256.New, secret, salt, info), ret)
+
+	return ret
+}
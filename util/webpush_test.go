@@ -0,0 +1,134 @@
+// Copyright (c) 2014-present, b3log.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestEncryptPush verifies the encryption with the example of RFC 8291 Appendix A.
+func TestEncryptPush(t *testing.T) {
+	decode := func(s string) []byte {
+		ret, err := base64.RawURLEncoding.DecodeString(s)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		return ret
+	}
+
+	asPrivate, err := ecdh.P256().NewPrivateKey(decode("yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	body, err := encryptPushWith(asPrivate, decode("DGv6ra1nlYgDCS1FRnbzlw"),
+		decode("BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"),
+		decode("BTBZMqHH6r4Tts7J_aSIgg"), []byte("When I grow up, I want to be a watermelon"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6T" +
+		"lzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); expected != got {
+		t.Errorf("expected body [%s], got [%s]", expected, got)
+	}
+}
+
+func TestEncryptPushInvalidKey(t *testing.T) {
+	if _, err := encryptPush("AAAA", "BTBZMqHH6r4Tts7J_aSIgg", []byte("x")); nil == err {
+		t.Error("encrypting for an invalid public key should fail")
+	}
+}
+
+func TestVAPIDAuthorization(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	authorization, err := vapidAuthorization(key, "https://push.example.net", "mailto:admin@example.com")
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(authorization, "vapid t="), ", k=")
+	if 2 != len(parts) || VAPIDPublicKey(key) != parts[1] {
+		t.Fatalf("unexpected authorization [%s]", authorization)
+	}
+
+	token := strings.Split(parts[0], ".")
+	if 3 != len(token) {
+		t.Fatalf("unexpected token [%s]", parts[0])
+	}
+
+	claims := map[string]interface{}{}
+	data, _ := base64.RawURLEncoding.DecodeString(token[1])
+	if err := json.Unmarshal(data, &claims); nil != err || "https://push.example.net" != claims["aud"] {
+		t.Errorf("unexpected claims [%s]", data)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(token[2])
+	hash := sha256.Sum256([]byte(token[0] + "." + token[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if 64 != len(signature) || !ecdsa.Verify(&key.PublicKey, hash[:], r, s) {
+		t.Error("invalid signature")
+	}
+}
+
+func TestValidPushEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"https://8.8.8.8/push/abc", true},
+		{"http://8.8.8.8/push/abc", false},
+		{"https://127.0.0.1/push", false},
+		{"https://[::1]/push", false},
+		{"https://10.0.0.1/push", false},
+		{"https://192.168.1.1:8443/push", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://100.64.0.1/push", false},
+		{"https://0.0.0.0/push", false},
+		{"https://user@8.8.8.8/push", false},
+		{"https://localhost/push", false},
+		{"not a url", false},
+	}
+
+	for _, c := range cases {
+		if valid := ValidPushEndpoint(c.endpoint); c.valid != valid {
+			t.Errorf("expected [%v] of [%s], got [%v]", c.valid, c.endpoint, valid)
+		}
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	if err := dialPublicOnly("tcp", "127.0.0.1:443", nil); nil == err {
+		t.Error("dialing a loopback address should be refused")
+	}
+	if err := dialPublicOnly("tcp", "8.8.8.8:443", nil); nil != err {
+		t.Errorf("dialing a public address should be allowed, got [%s]", err)
+	}
+}
@@ -17,25 +17,73 @@ package util
 import (
 	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// WSWriteWait is the time allowed to write a control message (such as a ping) to the peer.
+	WSWriteWait = 10 * time.Second
+
+	// WSPongWait is the time allowed to read the next pong message from the peer.
+	WSPongWait = 60 * time.Second
+
+	// WSPingPeriod is the period sending pings to the peer, must be less than WSPongWait.
+	WSPingPeriod = (WSPongWait * 9) / 10
+)
+
+// compressedUpgrader upgrades HTTP connections to WebSocket connections negotiating permessage-deflate (RFC 7692) with
+// the browsers supporting it. Origins are not checked, same as websocket.Upgrade.
+var compressedUpgrader = websocket.Upgrader{
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
+	CheckOrigin:       func(r *http.Request) bool { return true },
+	Error:             func(w http.ResponseWriter, r *http.Request, status int, reason error) {},
+}
+
+// UpgradeCompressed upgrades the specified request to a WebSocket connection with messages compressed if the browser
+// supports it, used by the channels of chatty messages such as build/run output.
+func UpgradeCompressed(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	return compressedUpgrader.Upgrade(w, r, nil)
+}
+
 // WSChannel represents a WebSocket channel.
 type WSChannel struct {
 	Sid     string          // wide session id
 	Conn    *websocket.Conn // websocket connection
 	Request *http.Request   // HTTP request related
 	Time    time.Time       // the latest use time
+
+	// Remote forwards the messages written to the channel to the Wide instance holding the connection, nil for
+	// channels of this instance (see session.LoadCluster)
+	Remote func(v interface{}) error
+
+	// Mirror receives the messages written to the channel as well, nil if not mirrored (see output.ShareHandler)
+	Mirror func(v interface{})
+
+	writeMutex sync.Mutex // serializes writes, the channel may be written by goroutines of other sessions
 }
 
 // WriteJSON writes the JSON encoding of v to the channel.
 func (c *WSChannel) WriteJSON(v interface{}) (ret error) {
+	if nil != c.Mirror {
+		c.Mirror(v)
+	}
+
+	if nil != c.Remote {
+		return c.Remote(v)
+	}
+
 	if nil == c.Conn {
 		return errors.New("connection is nil, channel has been closed")
 	}
 
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
 	defer func() {
 		if r := recover(); nil != r {
 			ret = errors.New("channel has been closed")
@@ -71,3 +119,59 @@ func (c *WSChannel) Close() {
 func (c *WSChannel) Refresh() {
 	c.Time = time.Now()
 }
+
+// Heartbeat pings the peer of the channel every WSPingPeriod, reads from the channel fail once the peer (such as a
+// crashed browser) doesn't answer a ping within WSPongWait. Pongs are handled while reading so the caller must keep
+// reading the channel, see KeepAlive.
+//
+// Returns the function stopping the pings.
+func (c *WSChannel) Heartbeat() (stop func()) {
+	if nil == c.Conn {
+		return func() {}
+	}
+
+	c.Conn.SetReadDeadline(time.Now().Add(WSPongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(WSPongWait))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(WSPingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WSWriteWait)); nil != err {
+					c.Close()
+
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// KeepAlive keeps the heartbeat of the channel (see Heartbeat) and discards messages sent by the peer, it blocks until
+// the channel is closed or dead. Used by the handlers of channels only written by the server, such as output channels.
+func (c *WSChannel) KeepAlive() {
+	if nil == c.Conn {
+		return
+	}
+
+	stop := c.Heartbeat()
+	defer stop()
+
+	for {
+		if _, _, err := c.Conn.NextReader(); nil != err {
+			return
+		}
+	}
+}